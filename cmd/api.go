@@ -2,13 +2,18 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/lib"
+	"tidbyt.dev/pixlet/logging"
 	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
 	"tidbyt.dev/pixlet/server/loader"
 )
 
@@ -17,6 +22,7 @@ func init() {
 	ApiCmd.Flags().IntVarP(&port, "port", "p", 8080, "Port for serving rendered images")
 	ApiCmd.Flags().BoolVarP(&renderGif, "gif", "", false, "Generate GIF instead of WebP")
 	ApiCmd.Flags().BoolVarP(&silenceOutput, "silent", "", false, "Silence print statements when rendering app")
+	ApiCmd.Flags().StringVarP(&cacheURL, "cache", "", runtime.DefaultCacheURL, cacheFlagUsage())
 }
 
 var ApiCmd = &cobra.Command{
@@ -34,6 +40,10 @@ type renderRequest struct {
 	Width   int               `json:"width"`
 	Height  int               `json:"height"`
 	Magnify int               `json:"magnify"`
+
+	// RenderAt is the time the app sees as now, for rendering ahead of
+	// when the image is shown.
+	RenderAt time.Time `json:"render_at"`
 }
 
 func validatePath(path string) bool {
@@ -46,7 +56,8 @@ func validatePath(path string) bool {
 //	   "path": "/workspaces/pixlet/examples/clock",
 //	   "config": {
 //	       "timezone": "America/New_York"
-//	   }
+//	   },
+//	   "render_at": "2024-01-01T12:00:00-05:00"
 //	}
 func renderHandler(w http.ResponseWriter, req *http.Request) {
 	var r renderRequest
@@ -61,8 +72,26 @@ func renderHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	buf, err := loader.RenderApplet(r.Path, r.Config, r.Width, r.Height, r.Magnify, maxDuration, timeout, renderGif, silenceOutput)
+	format := lib.FormatWebP
+	if renderGif {
+		format = lib.FormatGIF
+	}
+	img, err := loader.RenderAppletImage(r.Path, r.Config, lib.LoadOptions{SilencePrint: silenceOutput}, lib.RenderOptions{
+		Width:       r.Width,
+		Height:      r.Height,
+		Magnify:     r.Magnify,
+		Format:      format,
+		MaxDuration: time.Duration(maxDuration) * time.Millisecond,
+		Timeout:     time.Duration(timeout) * time.Millisecond,
+		RenderAt:    r.RenderAt,
+	})
+	var configErr *schema.ConfigError
+	if errors.As(err, &configErr) {
+		http.Error(w, configErr.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
+		logging.FromContext(req.Context()).Error("error rendering", logging.AppKey, r.Path, "error", err)
 		http.Error(w, fmt.Sprintf("error rendering: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -72,17 +101,24 @@ func renderHandler(w http.ResponseWriter, req *http.Request) {
 	} else {
 		w.Header().Set("Content-Type", "image/webp")
 	}
-	w.Write(buf)
+	w.Write(img.Data)
+}
+
+// usageHandler reports the resources used by each app rendered so far.
+func usageHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runtime.DefaultUsageReport.Apps())
 }
 
 func api(cmd *cobra.Command, args []string) error {
-	cache := runtime.NewInMemoryCache()
-	runtime.InitHTTP(cache)
-	runtime.InitCache(cache)
+	if _, err := initCache(); err != nil {
+		return err
+	}
 
 	addr := fmt.Sprintf("%s:%d", host, port)
-	log.Printf("listening at http://%s\n", addr)
+	slog.Info("listening", "url", "http://"+addr)
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /api/render", renderHandler)
-	return http.ListenAndServe(addr, mux)
+	mux.HandleFunc("GET /api/usage", usageHandler)
+	return http.ListenAndServe(addr, logging.Middleware(mux))
 }
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/tools"
+	"tidbyt.dev/pixlet/tools/bench"
+)
+
+var (
+	benchRuns       int
+	benchBaseline   string
+	benchSave       string
+	benchFailOn     string
+	benchFixtures   string
+	benchTimeout    time.Duration
+	benchConfigPair []string
+)
+
+func init() {
+	BenchCmd.Flags().IntVar(&benchRuns, "runs", 10, "how many times to render each app")
+	BenchCmd.Flags().StringVar(&benchBaseline, "baseline", "", "JSON file of earlier results to compare with")
+	BenchCmd.Flags().StringVar(&benchSave, "save", "", "write the results to a JSON file, to use as a baseline later")
+	BenchCmd.Flags().StringVar(&benchFailOn, "fail-on-regress", "", "exit with an error if an app got worse than the baseline by more than this percentage, such as 10%")
+	BenchCmd.Flags().StringVar(&benchFixtures, "fixtures", "", "directory of HTTP fixtures (default testdata/http in each app's directory)")
+	BenchCmd.Flags().DurationVar(&benchTimeout, "timeout", 30*time.Second, "how long each render may take")
+	BenchCmd.Flags().IntVarP(&maxDuration, "max_duration", "d", 15000, "Maximum allowed animation duration (ms)")
+	BenchCmd.Flags().StringArrayVar(&benchConfigPair, "config", nil, "config to render with, as key=value (default the schema's defaults)")
+}
+
+var BenchCmd = &cobra.Command{
+	Use:     "bench <path>...",
+	Example: `pixlet bench apps/* --baseline baseline.json --fail-on-regress 10%`,
+	Short:   "Measure how long apps take to render and how big their output is",
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    benchApps,
+	Long: `Render apps a number of times and report what a render costs: the
+median time taken to run, paint and encode it, the Starlark execution
+steps it takes, and the size of the WebP it produces.
+
+With --baseline, results are compared with those of an earlier run saved
+with --save, and --fail-on-regress fails if any app got worse by more
+than the given percentage. Time depends on the machine, so compare it
+only with baselines from the same one; execution steps and size don't.
+
+Apps are rendered deterministically, with nothing cached and their HTTP
+requests replayed from the fixtures recorded by pixlet test --record.`,
+}
+
+func benchApps(cmd *cobra.Command, args []string) error {
+	threshold := -1.0
+	if benchFailOn != "" {
+		if benchBaseline == "" {
+			return fmt.Errorf("--fail-on-regress needs a --baseline to compare with")
+		}
+		var err error
+		if threshold, err = parsePercent(benchFailOn); err != nil {
+			return fmt.Errorf("invalid --fail-on-regress: %w", err)
+		}
+	}
+
+	var config map[string]string
+	for _, pair := range benchConfigPair {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("config must be on form <key>=<value>, found %s", pair)
+		}
+		if config == nil {
+			config = map[string]string{}
+		}
+		config[key] = value
+	}
+
+	baseline := &bench.Baseline{Apps: map[string]*bench.Result{}}
+	if benchBaseline != "" {
+		var err error
+		if baseline, err = bench.LoadBaseline(benchBaseline); err != nil {
+			return fmt.Errorf("failed to load baseline: %w", err)
+		}
+	}
+
+	// results are merged into the saved file, so apps can be benchmarked
+	// a few at a time
+	results := &bench.Baseline{Apps: map[string]*bench.Result{}}
+	if benchSave != "" {
+		var err error
+		if results, err = bench.LoadBaselineIfExists(benchSave); err != nil {
+			return fmt.Errorf("failed to load %s: %w", benchSave, err)
+		}
+	}
+
+	regressions := 0
+	for _, path := range args {
+		key := filepath.ToSlash(filepath.Clean(path))
+		result, err := benchApp(cmd, path, config)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		results.Apps[key] = result
+
+		old, ok := baseline.Apps[key]
+		if !ok {
+			fmt.Printf("%s\t%s\t%d steps\t%d bytes\n", key, result.Time.Round(time.Microsecond), result.ExecutionSteps, result.Size)
+			continue
+		}
+
+		changes := bench.Compare(old, result, threshold)
+		fmt.Printf("%s\t%s (%s)\t%d steps (%s)\t%d bytes (%s)\n", key,
+			result.Time.Round(time.Microsecond), formatChange(changes[0]),
+			result.ExecutionSteps, formatChange(changes[1]),
+			result.Size, formatChange(changes[2]))
+		if threshold < 0 {
+			continue
+		}
+		for _, c := range changes {
+			if c.Regressed {
+				regressions++
+				fmt.Printf("    %s regressed by %s, more than %s\n", c.Metric, formatChange(c), benchFailOn)
+			}
+		}
+	}
+
+	if benchSave != "" {
+		if err := results.Save(benchSave); err != nil {
+			return fmt.Errorf("failed to save results: %w", err)
+		}
+	}
+
+	if regressions > 0 {
+		return fmt.Errorf("%d metrics regressed by more than %s", regressions, benchFailOn)
+	}
+	return nil
+}
+
+// benchApp measures one app.
+func benchApp(cmd *cobra.Command, path string, config map[string]string) (*bench.Result, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	var fsys fs.FS
+	var baseDir string
+	if info.IsDir() {
+		fsys = os.DirFS(path)
+		baseDir = path
+	} else {
+		if !strings.HasSuffix(path, ".star") {
+			return nil, fmt.Errorf("script file must have suffix .star: %s", path)
+		}
+		fsys = tools.NewSingleFileFS(path)
+		baseDir = filepath.Dir(path)
+	}
+
+	applet, err := runtime.NewAppletFromFS(filepath.Base(path), fsys, runtime.WithDeterministic(), runtime.WithPrintDisabled())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applet: %w", err)
+	}
+	if config == nil && applet.Schema != nil {
+		config = applet.Schema.DefaultConfig()
+	}
+
+	fixtures := benchFixtures
+	if fixtures == "" {
+		fixtures = filepath.Join(baseDir, "testdata", "http")
+	}
+
+	return bench.Measure(cmd.Context(), applet, bench.Options{
+		Runs:        benchRuns,
+		Config:      config,
+		Timeout:     benchTimeout,
+		MaxDuration: maxDuration,
+		Fixtures:    fixtures,
+	})
+}
+
+// parsePercent parses a percentage such as "10%", or "10", as a fraction.
+func parsePercent(s string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if f < 0 {
+		return 0, fmt.Errorf("%s is negative", s)
+	}
+	return f / 100, nil
+}
+
+// formatChange formats how much a metric changed, such as +12.5%.
+func formatChange(c bench.Change) string {
+	return fmt.Sprintf("%+.1f%%", c.Fraction()*100)
+}
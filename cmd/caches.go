@@ -0,0 +1,14 @@
+//go:build !js && !wasm
+
+package cmd
+
+// Cache backends that register themselves with the runtime. They are linked
+// in here so that they can be selected with the --cache flag.
+import (
+	_ "tidbyt.dev/pixlet/runtime/cache/bbolt"
+	_ "tidbyt.dev/pixlet/runtime/cache/filesystem"
+	_ "tidbyt.dev/pixlet/runtime/cache/memcached"
+	_ "tidbyt.dev/pixlet/runtime/cache/s3"
+	_ "tidbyt.dev/pixlet/runtime/cache/sqlite"
+	_ "tidbyt.dev/pixlet/runtime/cache/tiered"
+)
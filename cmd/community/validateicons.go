@@ -66,13 +66,23 @@ func ValidateIcons(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load schema: %w", err)
 	}
 
-	for _, field := range s.Fields {
+	return validateFieldIcons(applet.ID, s.Fields)
+}
+
+// validateFieldIcons checks the icons of fields, including fields nested in
+// groups and lists.
+func validateFieldIcons(appID string, fields []schema.SchemaField) error {
+	for _, field := range fields {
+		if err := validateFieldIcons(appID, field.Fields); err != nil {
+			return err
+		}
+
 		if field.Icon == "" {
 			continue
 		}
 
 		if _, ok := icons.IconsMap[field.Icon]; !ok {
-			return fmt.Errorf("app '%s' contains unknown icon: '%s'", applet.ID, field.Icon)
+			return fmt.Errorf("app '%s' contains unknown icon: '%s'", appID, field.Icon)
 		}
 	}
 
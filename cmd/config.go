@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/schema"
+)
+
+var (
+	configURL          string
+	configAPIToken     string
+	configDevice       string
+	configInstallation string
+	configOutput       string
+)
+
+func init() {
+	for _, c := range []*cobra.Command{ConfigExportCmd, ConfigImportCmd} {
+		c.Flags().StringVarP(&configURL, "url", "u", "http://localhost:8080", "base URL of pixlet serve, or of the server")
+		c.Flags().StringVarP(&configAPIToken, "api-token", "t", "", "API token of the server")
+		c.Flags().StringVarP(&configDevice, "device", "", "", "ID of the device on the server the installation is on")
+		c.Flags().StringVarP(&configInstallation, "installation-id", "i", "", "ID of the installation on the server")
+	}
+	ConfigExportCmd.Flags().StringVarP(&configOutput, "output", "o", "", "Path to write the config to, instead of stdout")
+
+	ConfigCmd.AddCommand(ConfigExportCmd)
+	ConfigCmd.AddCommand(ConfigImportCmd)
+}
+
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Move the config of a Pixlet app between pixlet serve and servers",
+	Long: `Export the config of an app as a portable JSON document, and import it
+elsewhere, such as from the preview of pixlet serve to an installation on a
+Tronbyt server or pixlet hub.
+
+Without --device, config is exported from and imported into pixlet serve at
+--url. With --device and --installation-id, it's that installation's config
+on the server at --url.
+
+Documents record the app's config version, so config exported from an older
+version of an app is migrated when it's imported into a newer one.`,
+}
+
+var ConfigExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the config of an app",
+	Example: `  pixlet config export -o config.json
+  pixlet config export -u https://tronbyt.example.com -t $TOKEN --device kitchen -i clock`,
+	Args: cobra.NoArgs,
+	RunE: configExport,
+}
+
+var ConfigImportCmd = &cobra.Command{
+	Use:     "import <path>",
+	Short:   "Import config exported from another pixlet or server",
+	Example: `  pixlet config import config.json -u https://tronbyt.example.com -t $TOKEN --device kitchen -i clock`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    configImport,
+}
+
+// configEndpoint returns the URL config is exported from or imported to,
+// and the method to import it with.
+func configEndpoint(export bool) (string, string, error) {
+	base := strings.TrimSuffix(configURL, "/")
+
+	if configDevice == "" && configInstallation == "" {
+		if export {
+			return base + "/api/v1/config/export", http.MethodGet, nil
+		}
+		return base + "/api/v1/config/import", http.MethodPost, nil
+	}
+	if configDevice == "" || configInstallation == "" {
+		return "", "", fmt.Errorf("--device and --installation-id must be given together")
+	}
+
+	return fmt.Sprintf(
+		"%s/v0/devices/%s/installations/%s/config",
+		base, url.PathEscape(configDevice), url.PathEscape(configInstallation),
+	), http.MethodPut, nil
+}
+
+func configRequest(method, endpoint string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if configAPIToken != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", configAPIToken))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(buf)))
+	}
+	return buf, nil
+}
+
+func configExport(cmd *cobra.Command, args []string) error {
+	endpoint, _, err := configEndpoint(true)
+	if err != nil {
+		return err
+	}
+
+	buf, err := configRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("exporting config: %w", err)
+	}
+
+	// check it's a document that can be imported before saving it
+	if _, err := schema.DecodeConfigExport(bytes.NewReader(buf)); err != nil {
+		return err
+	}
+
+	if configOutput == "" {
+		_, err = os.Stdout.Write(buf)
+		return err
+	}
+	if err := os.WriteFile(configOutput, buf, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", configOutput, err)
+	}
+	return nil
+}
+
+func configImport(cmd *cobra.Command, args []string) error {
+	endpoint, method, err := configEndpoint(false)
+	if err != nil {
+		return err
+	}
+
+	buf, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", args[0], err)
+	}
+	if _, err := schema.DecodeConfigExport(bytes.NewReader(buf)); err != nil {
+		return fmt.Errorf("reading %s: %w", args[0], err)
+	}
+
+	if _, err := configRequest(method, endpoint, bytes.NewReader(buf)); err != nil {
+		return fmt.Errorf("importing config: %w", err)
+	}
+	return nil
+}
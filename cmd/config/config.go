@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/viper"
 	"golang.org/x/oauth2"
+	"tidbyt.dev/pixlet/device"
 )
 
 const (
@@ -72,3 +73,35 @@ func OAuthTokenFromConfig(ctx context.Context) string {
 
 	return tok.AccessToken
 }
+
+// Devices returns the devices registered with `pixlet devices add`.
+func Devices() ([]device.Device, error) {
+	var devices []device.Device
+	if err := PrivateConfig.UnmarshalKey("devices", &devices); err != nil {
+		return nil, fmt.Errorf("reading registered devices: %w", err)
+	}
+	return devices, nil
+}
+
+// LookupDevice returns the registered device with the given name.
+func LookupDevice(name string) (device.Device, bool, error) {
+	devices, err := Devices()
+	if err != nil {
+		return device.Device{}, false, err
+	}
+	for _, d := range devices {
+		if d.Name == name {
+			return d, true, nil
+		}
+	}
+	return device.Device{}, false, nil
+}
+
+// SaveDevices replaces the registered devices.
+func SaveDevices(devices []device.Device) error {
+	PrivateConfig.Set("devices", devices)
+	if err := PrivateConfig.WriteConfig(); err != nil {
+		return fmt.Errorf("saving registered devices: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/render/imagediff"
+)
+
+var (
+	convertFormat  string
+	convertOutput  string
+	convertMagnify int
+	convertQuality int
+	convertColors  int
+)
+
+// convertExtensions are the extensions of converted files, by format. PNG
+// sequences are written to a directory instead.
+var convertExtensions = map[string]string{
+	"webp":   ".webp",
+	"gif":    ".gif",
+	"mp4":    ".mp4",
+	"rgb565": ".rgb565",
+	"raw":    ".raw",
+}
+
+func init() {
+	ConvertCmd.Flags().StringVarP(&convertFormat, "format", "f", "gif", "Format to convert to: webp, gif, mp4, png-seq, rgb565 or raw")
+	ConvertCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "Path to write to, or the directory for png-seq")
+	ConvertCmd.Flags().IntVarP(&convertMagnify, "magnify", "m", 1, "Increase image dimension by a factor")
+	ConvertCmd.Flags().IntVarP(&convertQuality, "quality", "q", 100, "Quality of WebP and MP4 output, from 0 to 100; 100 is lossless")
+	ConvertCmd.Flags().IntVarP(&convertColors, "colors", "", 256, "Maximum colors in each frame of GIF output, from 2 to 256")
+}
+
+var ConvertCmd = &cobra.Command{
+	Use:   "convert <image>",
+	Short: "Convert a rendered WebP, GIF or PNG to another format",
+	Args:  cobra.ExactArgs(1),
+	RunE:  convert,
+	Long: `Convert a rendered WebP, GIF or PNG to another format, keeping how long
+each frame is shown, for testing firmware and sharing renders.
+
+Formats are:
+
+  webp     animated WebP, lossless unless --quality is under 100
+  gif      animated GIF, with up to --colors colors in each frame
+  mp4      H.264 video, for sharing; needs ffmpeg on the PATH
+  png-seq  a PNG of each frame, numbered, in the --output directory
+  rgb565   each frame's pixels as little-endian 16-bit RGB565, back to back
+  raw      each frame's delay in milliseconds as a big-endian uint16,
+           followed by its pixels as 8-bit RGB
+
+The output is written next to the input, with the format's extension,
+unless --output says otherwise.`,
+}
+
+func convert(cmd *cobra.Command, args []string) error {
+	input := args[0]
+	a, err := imagediff.Load(input)
+	if err != nil {
+		return err
+	}
+
+	outPath := convertOutput
+	if outPath == "" {
+		base := strings.TrimSuffix(input, filepath.Ext(input))
+		if convertFormat == "png-seq" {
+			outPath = base + "_frames"
+		} else {
+			outPath = base + convertExtensions[convertFormat]
+		}
+	}
+
+	if outPath == input {
+		return fmt.Errorf("%s is already %s, pass --output to write it elsewhere", input, convertFormat)
+	}
+
+	if convertQuality < 0 || convertQuality > 100 {
+		return fmt.Errorf("--quality must be from 0 to 100, not %d", convertQuality)
+	}
+
+	screens := encode.ScreensFromFrames(a.Frames, a.Delays)
+	filters := []encode.ImageFilter{encode.Magnify(convertMagnify)}
+
+	var buf []byte
+	switch convertFormat {
+	case "webp":
+		if convertQuality == 100 {
+			buf, err = screens.EncodeWebP(0, filters...)
+		} else {
+			buf, err = screens.EncodeLossyWebP(float32(convertQuality), 0, filters...)
+		}
+	case "gif":
+		buf, err = screens.EncodeGIFColors(convertColors, 0, filters...)
+	case "rgb565":
+		buf, err = screens.EncodeRGB565(0, filters...)
+	case "raw":
+		buf, err = screens.EncodeRaw(0, filters...)
+	case "mp4":
+		return convertToMP4(screens, filters, outPath)
+	case "png-seq":
+		return convertToPNGs(screens, filters, outPath)
+	default:
+		return fmt.Errorf("unknown format %q, expected webp, gif, mp4, png-seq, rgb565 or raw", convertFormat)
+	}
+	if err != nil {
+		return fmt.Errorf("converting %s: %w", input, err)
+	}
+
+	if err := os.WriteFile(outPath, buf, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", outPath, err)
+	}
+	return nil
+}
+
+// convertToPNGs writes each frame to dir as a PNG, numbered from 0.
+func convertToPNGs(screens *encode.Screens, filters []encode.ImageFilter, dir string) error {
+	frames, _, err := screens.Frames(0, filters...)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, im := range frames {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, im); err != nil {
+			return fmt.Errorf("encoding frame %d: %w", i, err)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("frame_%04d.png", i))
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// convertToMP4 encodes the frames as H.264 with ffmpeg. Video has a fixed
+// frame rate, so frames are repeated to be shown for as long as their delays,
+// at the finest rate that fits all of them.
+func convertToMP4(screens *encode.Screens, filters []encode.ImageFilter, outPath string) error {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return fmt.Errorf("converting to mp4 needs ffmpeg: %w", err)
+	}
+
+	frames, delays, err := screens.Frames(0, filters...)
+	if err != nil {
+		return err
+	}
+	if len(frames) == 0 {
+		return fmt.Errorf("image has no frames")
+	}
+
+	step := time.Duration(0)
+	for _, d := range delays {
+		step = gcd(step, d.Round(time.Millisecond))
+	}
+	if step <= 0 {
+		step = time.Second
+	}
+
+	var stdin bytes.Buffer
+	for i, im := range frames {
+		for n := max(delays[i]/step, 1); n > 0; n-- {
+			writeRGB24(&stdin, im)
+		}
+	}
+
+	bounds := frames[0].Bounds()
+	// 51 is the worst quality H.264 offers, and 0 lossless
+	crf := (100 - convertQuality) * 51 / 100
+	c := exec.Command(
+		ffmpeg, "-y", "-loglevel", "error",
+		"-f", "rawvideo", "-pix_fmt", "rgb24",
+		"-s", fmt.Sprintf("%dx%d", bounds.Dx(), bounds.Dy()),
+		"-framerate", fmt.Sprintf("1000/%d", step.Milliseconds()),
+		"-i", "-",
+		// players only take even dimensions
+		"-vf", "pad=ceil(iw/2)*2:ceil(ih/2)*2",
+		"-c:v", "libx264", "-pix_fmt", "yuv420p", "-crf", fmt.Sprint(crf),
+		"-movflags", "+faststart",
+		outPath,
+	)
+	c.Stdin = &stdin
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("running ffmpeg: %w", err)
+	}
+	return nil
+}
+
+func gcd(a, b time.Duration) time.Duration {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func writeRGB24(buf *bytes.Buffer, im image.Image) {
+	bounds := im.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBAModel.Convert(im.At(x, y)).(color.RGBA)
+			buf.Write([]byte{c.R, c.G, c.B})
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/server"
+)
+
+var (
+	debugPort    int
+	debugTimeout int
+)
+
+func init() {
+	DebugCmd.Flags().StringVarP(&host, "host", "i", "127.0.0.1", "Host interface for serving rendered images and the debugger")
+	DebugCmd.Flags().IntVarP(&port, "port", "p", 8080, "Port for serving rendered images")
+	DebugCmd.Flags().IntVarP(&debugPort, "dap_port", "", 4711, "Port for debuggers to connect to with the Debug Adapter Protocol")
+	DebugCmd.Flags().BoolVarP(&watch, "watch", "w", true, "Reload scripts on change. Does not recurse sub-directories.")
+	DebugCmd.Flags().IntVarP(&maxDuration, "max_duration", "d", 15000, "Maximum allowed animation duration (ms)")
+	// renders are paused at breakpoints for as long as it takes to step
+	// through them, so they get much longer than usual
+	DebugCmd.Flags().IntVarP(&debugTimeout, "timeout", "", 3600000, "Timeout for execution (ms)")
+	DebugCmd.Flags().StringVarP(&cacheURL, "cache", "", runtime.DefaultCacheURL, cacheFlagUsage())
+}
+
+var DebugCmd = &cobra.Command{
+	Use:   "debug [path]",
+	Short: "Serve a Pixlet app with a debugger attached",
+	Args:  cobra.ExactArgs(1),
+	RunE:  debug,
+	Long: `Serve a Pixlet app in a web server, like serve, with a Debug Adapter
+Protocol server that editors such as VS Code connect to for setting
+breakpoints, stepping through renders and inspecting variables.
+
+A preview render that hits a breakpoint pauses until it's resumed from
+the editor.`,
+}
+
+func debug(cmd *cobra.Command, args []string) error {
+	cache, err := runtime.OpenCache(cacheURL)
+	if err != nil {
+		return err
+	}
+
+	s, err := server.NewServer(host, port, "/", watch, args[0], maxDuration, debugTimeout, false, "", 1, cache)
+	if err != nil {
+		return err
+	}
+
+	addr := fmt.Sprintf("%s:%d", host, debugPort)
+	if err := s.EnableDebugger(addr); err != nil {
+		return err
+	}
+	slog.Info("debugger listening", "addr", addr)
+
+	return s.Run()
+}
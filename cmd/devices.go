@@ -6,26 +6,140 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"tidbyt.dev/pixlet/cmd/config"
+	"tidbyt.dev/pixlet/device"
 )
 
-var devicesURL string
+var (
+	devicesURL    string
+	deviceProfile string
+	deviceTarget  string
+	deviceURL     string
+)
 
 func init() {
 	DevicesCmd.Flags().StringVarP(&devicesURL, "url", "u", "https://api.tidbyt.com", "base URL of Tidbyt API")
+
+	DevicesAddCmd.Flags().StringVar(&deviceProfile, "profile", device.DefaultProfile, "what the device can show: "+strings.Join(device.ProfileNames(), ", "))
+	DevicesAddCmd.Flags().StringVar(&deviceTarget, "target", "tidbyt", "where the device is pushed to: tidbyt or tronbyt")
+	DevicesAddCmd.Flags().StringVarP(&deviceURL, "url", "u", "", "base URL of the Tronbyt device or server")
+
+	DevicesCmd.AddCommand(DevicesAddCmd)
+	DevicesCmd.AddCommand(DevicesRemoveCmd)
+	DevicesCmd.AddCommand(DevicesProfilesCmd)
 }
 
 var DevicesCmd = &cobra.Command{
 	Use:   "devices",
-	Short: "List devices in your Tidbyt account",
+	Short: "List registered devices and devices in your Tidbyt account",
 	Run:   devices,
 }
 
+var DevicesAddCmd = &cobra.Command{
+	Use:     "add [name] [device ID]",
+	Short:   "Register a device, so it can be rendered for and pushed to by name",
+	Example: `pixlet devices add kitchen abc123 --target tronbyt --url http://tronbyt.local:8000 --profile tronbyt-s3-wide`,
+	Args:    cobra.ExactArgs(2),
+	RunE:    devicesAdd,
+	Long: `Register a device under a name. Pass the name to pixlet render --device
+to render at the device's resolution and in a format it accepts, and to
+pixlet push in place of the device ID to push to it.
+
+The device's profile is copied into pixlet's config, where it can be edited
+to describe displays the built-in profiles don't cover.`,
+}
+
+var DevicesRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Forget a registered device",
+	Args:  cobra.ExactArgs(1),
+	RunE:  devicesRemove,
+}
+
+var DevicesProfilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List the built-in device profiles",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, name := range device.ProfileNames() {
+			fmt.Printf("%s: %s\n", name, describeProfile(device.Profiles[name]))
+		}
+	},
+}
+
+func describeProfile(p device.Profile) string {
+	desc := fmt.Sprintf("%dx%d, %d-bit color, %s", p.Width, p.Height, p.ColorDepth, strings.Join(p.Formats, "/"))
+	if p.MaxPayload > 0 {
+		desc += fmt.Sprintf(", up to %d bytes", p.MaxPayload)
+	}
+	return desc
+}
+
+func devicesAdd(cmd *cobra.Command, args []string) error {
+	profile, err := device.LookupProfile(deviceProfile)
+	if err != nil {
+		return err
+	}
+	if deviceTarget != "tidbyt" && deviceTarget != "tronbyt" {
+		return fmt.Errorf("unknown target %q, expected tidbyt or tronbyt", deviceTarget)
+	}
+	if deviceTarget == "tronbyt" && deviceURL == "" {
+		return fmt.Errorf("pass the --url of the Tronbyt device or server")
+	}
+
+	return registerDevice(device.Device{
+		Name:    args[0],
+		ID:      args[1],
+		Target:  deviceTarget,
+		URL:     deviceURL,
+		Profile: profile,
+	})
+}
+
+// registerDevice saves d in pixlet's config, replacing any device with the
+// same name.
+func registerDevice(d device.Device) error {
+	devices, err := config.Devices()
+	if err != nil {
+		return err
+	}
+	devices = slices.DeleteFunc(devices, func(old device.Device) bool { return old.Name == d.Name })
+	devices = append(devices, d)
+	return config.SaveDevices(devices)
+}
+
+func devicesRemove(cmd *cobra.Command, args []string) error {
+	devices, err := config.Devices()
+	if err != nil {
+		return err
+	}
+	n := len(devices)
+	devices = slices.DeleteFunc(devices, func(d device.Device) bool { return d.Name == args[0] })
+	if len(devices) == n {
+		return fmt.Errorf("no device named %q", args[0])
+	}
+	return config.SaveDevices(devices)
+}
+
 func devices(cmd *cobra.Command, args []string) {
+	registered, err := config.Devices()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for _, d := range registered {
+		fmt.Printf("%s: %s on %s (%s)\n", d.Name, d.ID, d.Target, describeProfile(d.Profile))
+	}
+
 	apiToken = config.OAuthTokenFromConfig(cmd.Context())
 	if apiToken == "" {
+		if len(registered) > 0 {
+			return
+		}
 		fmt.Println("login with `pixlet login`")
 		os.Exit(1)
 	}
@@ -67,3 +181,16 @@ func devices(cmd *cobra.Command, args []string) {
 		fmt.Printf("%s (%s)\n", d.ID, d.DisplayName)
 	}
 }
+
+// lookupProfile returns the profile of the registered device with the given
+// name, or else the built-in profile with that name.
+func lookupProfile(name string) (device.Profile, error) {
+	d, ok, err := config.LookupDevice(name)
+	if err != nil {
+		return device.Profile{}, err
+	}
+	if ok {
+		return d.Profile, nil
+	}
+	return device.LookupProfile(name)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/render/imagediff"
+)
+
+var (
+	diffOutput  string
+	diffMagnify int
+	diffFailAt  float64
+)
+
+func init() {
+	DiffCmd.Flags().StringVarP(&diffOutput, "output", "o", "", "Path to write the old render, new render and highlighted changes side by side to, as a PNG or animated GIF")
+	DiffCmd.Flags().IntVarP(&diffMagnify, "magnify", "m", 4, "Increase the dimensions of the --output image by a factor")
+	DiffCmd.Flags().Float64VarP(&diffFailAt, "fail_over", "", -1, "Exit with an error if the score is over this, for checks in CI. Negative to never fail.")
+}
+
+var DiffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Compare two renders of an app",
+	Args:  cobra.ExactArgs(2),
+	RunE:  diffRenders,
+	Long: `Compare two renders of an app, as WebP, GIF or PNG images, for reviewing
+changes to widgets and fonts.
+
+Frames are aligned by when they're shown, so renders with different
+frame rates are compared by what's on screen. Pixels whose colors differ
+noticeably are counted as changed, and the score is the mean perceptual
+difference (ΔE) between the renders' pixels: 0 for renders that look the
+same, up to about 100 between black and white.`,
+}
+
+func diffRenders(cmd *cobra.Command, args []string) error {
+	old, err := imagediff.Load(args[0])
+	if err != nil {
+		return err
+	}
+	new, err := imagediff.Load(args[1])
+	if err != nil {
+		return err
+	}
+
+	result, err := imagediff.Compare(old, new)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("old: %d frames, %s\n", len(old.Frames), old.Duration())
+	fmt.Printf("new: %d frames, %s\n", len(new.Frames), new.Duration())
+	for _, span := range result.Spans {
+		if span.Changed == 0 {
+			continue
+		}
+		fmt.Printf(
+			"%s-%s: frame %d vs %d, %d pixels changed, max ΔE %.1f\n",
+			span.Start, span.Start+span.Duration, span.Old, span.New, span.Changed, span.MaxDelta,
+		)
+	}
+
+	if result.Identical() {
+		fmt.Println("renders are identical")
+	} else {
+		fmt.Printf("score %.2f, %.1f%% of pixels changed, max ΔE %.1f\n", result.Score, result.Changed*100, result.MaxDelta)
+	}
+
+	if diffOutput != "" {
+		img, _, err := result.EncodeHighlights(diffMagnify)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(diffOutput, img, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", diffOutput, err)
+		}
+	}
+
+	if diffFailAt >= 0 && result.Score > diffFailAt {
+		return fmt.Errorf("score %.2f is over %.2f", result.Score, diffFailAt)
+	}
+	return nil
+}
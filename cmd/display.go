@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/lib"
+	"tidbyt.dev/pixlet/matrix"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+var (
+	displayWidth    int
+	displayHeight   int
+	displayInterval time.Duration
+)
+
+func init() {
+	DisplayCmd.Flags().IntVarP(&displayWidth, "width", "w", 64, "Width of the display")
+	DisplayCmd.Flags().IntVarP(&displayHeight, "height", "t", 32, "Height of the display")
+	DisplayCmd.Flags().DurationVar(&displayInterval, "interval", time.Minute, "How often to render the app again; 0 shows it once")
+	DisplayCmd.Flags().BoolVarP(&silenceOutput, "silent", "", false, "Silence print statements when rendering app")
+	DisplayCmd.Flags().StringVarP(&cacheURL, "cache", "", runtime.DefaultCacheURL, cacheFlagUsage())
+	addNightModeFlags(DisplayCmd)
+}
+
+var DisplayCmd = &cobra.Command{
+	Use:     "display [path] [display URL] [<key>=value>]...",
+	Short:   "Run a Pixlet app on a directly attached LED matrix",
+	Example: `pixlet display examples/clock serial:///dev/ttyACM0?baud=921600`,
+	Args:    cobra.MinimumNArgs(2),
+	RunE:    display,
+	Long: `Run a Pixlet app and draw its frames on an LED matrix attached to this
+machine, looping its animation and rendering it again every --interval.
+
+Displays are given as URLs. Supported schemes: ` + strings.Join(matrix.Schemes, ", ") + `.
+
+  serial:///dev/ttyACM0?baud=921600
+      Sends frames with the Adalight protocol to a microcontroller on a
+      serial port.
+
+  ft://matrix.local:1337?x=0&y=0&z=0
+      Sends frames with the Flaschen-Taschen protocol, as served by ft-server
+      for panels driven by rpi-rgb-led-matrix. x, y and z place the image on
+      the server's canvas.`,
+}
+
+func display(cmd *cobra.Command, args []string) error {
+	path, target := args[0], args[1]
+
+	config := map[string]string{}
+	for _, param := range args[2:] {
+		k, v, ok := strings.Cut(param, "=")
+		if !ok {
+			return fmt.Errorf("parameters must be on form <key>=<value>, found %s", param)
+		}
+		config[k] = v
+	}
+
+	if _, err := initCache(); err != nil {
+		return err
+	}
+
+	applet, err := lib.LoadAppletFromPath(path, lib.LoadOptions{SilencePrint: silenceOutput})
+	if err != nil {
+		return err
+	}
+	if len(config) == 0 {
+		config = applet.DefaultConfig()
+	}
+
+	d, err := matrix.Open(target)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	ctx := cmd.Context()
+	for {
+		filters, err := nightModeFilters(time.Now())
+		if err != nil {
+			return err
+		}
+
+		frames, err := applet.RenderFrames(ctx, config, lib.RenderOptions{
+			Width:   displayWidth,
+			Height:  displayHeight,
+			Timeout: 30 * time.Second,
+			Filters: filters,
+		})
+		if err != nil {
+			return err
+		}
+
+		next := time.Now().Add(displayInterval)
+		for {
+			if err := matrix.Play(ctx, d, frames.Images, frames.Delays); err != nil {
+				return err
+			}
+			// keep looping the animation until it's time to render again,
+			// but don't spin on apps that have nothing to show
+			if displayInterval <= 0 || !time.Now().Before(next) {
+				break
+			}
+			if len(frames.Images) <= 1 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Until(next)):
+				}
+				break
+			}
+		}
+
+		if displayInterval <= 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/lib"
+	"tidbyt.dev/pixlet/manifest"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/tools/appdocs"
+)
+
+var (
+	docsOutput   string
+	docsExamples []string
+	docsMagnify  int
+	docsCheck    bool
+	docsForce    bool
+	docsFixtures string
+)
+
+func init() {
+	DocsCmd.Flags().StringVarP(&docsOutput, "output", "o", "", "path to write the docs to, as Markdown or, if it ends in .html, HTML (default README.md in the app's directory)")
+	DocsCmd.Flags().StringArrayVar(&docsExamples, "example", nil, "config to render an example with, as a query string such as units=f&location=home")
+	DocsCmd.Flags().IntVarP(&docsMagnify, "magnify", "m", 4, "Increase the image dimensions by a factor (useful for previews)")
+	DocsCmd.Flags().BoolVar(&docsCheck, "check", false, "don't write anything, and fail if the docs or their images are out of date")
+	DocsCmd.Flags().BoolVar(&docsForce, "force", false, "overwrite docs that weren't generated by pixlet docs")
+	DocsCmd.Flags().StringVar(&docsFixtures, "fixtures", "", "directory of HTTP fixtures to replay requests from (default testdata/http in the app's directory, if there is one)")
+}
+
+var DocsCmd = &cobra.Command{
+	Use:     "docs <path>",
+	Example: `pixlet docs apps/fuzzyclock --example "color=red"`,
+	Short:   "Generate an app's README from its manifest, schema and renders",
+	Args:    cobra.ExactArgs(1),
+	RunE:    generateDocs,
+	Long: `Generate documentation for an app: its name, description and the rest
+of its manifest, a table of its settings from its schema, and renders of
+it with its default settings and with each --example.
+
+Markdown docs link to the renders, which are written as GIFs next to
+them. HTML docs have the renders embedded. Apps are rendered
+deterministically, with HTTP requests replayed from the fixtures
+recorded by pixlet test --record if there are any, so docs only change
+when the app does. Run with --check in CI to make sure they're kept up
+to date.`,
+}
+
+func generateDocs(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	baseDir := path
+	if !info.IsDir() {
+		baseDir = filepath.Dir(path)
+	}
+
+	output := docsOutput
+	if output == "" {
+		output = filepath.Join(baseDir, "README.md")
+	}
+	outDir := filepath.Dir(output)
+	html := strings.EqualFold(filepath.Ext(output), ".html")
+
+	fixtures := docsFixtures
+	if fixtures == "" {
+		fixtures = filepath.Join(baseDir, "testdata", "http")
+	}
+	cache := runtime.NewInMemoryCache()
+	if _, err := os.Stat(fixtures); err == nil {
+		runtime.InitHTTPFixtures(cache, &runtime.HTTPFixtures{Dir: fixtures, Mode: runtime.FixturesReplay})
+	} else {
+		runtime.InitHTTP(cache)
+	}
+	runtime.InitCache(cache)
+
+	applet, err := lib.LoadAppletFromPath(path, lib.LoadOptions{SilencePrint: true, Deterministic: true})
+	if err != nil {
+		return err
+	}
+
+	doc := &appdocs.Doc{
+		ID:     strings.TrimSuffix(applet.ID(), ".star"),
+		Schema: applet.Schema(),
+	}
+	if f, err := os.Open(filepath.Join(baseDir, manifest.ManifestFileName)); err == nil {
+		doc.Manifest, err = manifest.LoadManifest(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if doc.Manifest.ID != "" {
+			doc.ID = doc.Manifest.ID
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	examples := append([]string{""}, docsExamples...)
+	files := map[string][]byte{}
+	for i, query := range examples {
+		example, err := appdocs.ParseExample(query)
+		if err != nil {
+			return err
+		}
+
+		config := applet.DefaultConfig()
+		for key, value := range example {
+			config[key] = value
+		}
+
+		img, err := applet.Render(cmd.Context(), config, lib.RenderOptions{
+			Magnify:     docsMagnify,
+			Format:      lib.FormatGIF,
+			MaxDuration: 15 * time.Second,
+			Timeout:     30 * time.Second,
+		})
+		if err != nil {
+			return fmt.Errorf("rendering %s: %w", appdocs.ExampleTitle(example), err)
+		}
+		if len(img.Data) == 0 {
+			return fmt.Errorf("rendering %s: app has nothing to show", appdocs.ExampleTitle(example))
+		}
+
+		file := doc.ID + ".gif"
+		if i > 0 {
+			file = fmt.Sprintf("%s_%d.gif", doc.ID, i+1)
+		}
+		doc.Examples = append(doc.Examples, appdocs.Example{
+			Title:     appdocs.ExampleTitle(example),
+			Config:    config,
+			Image:     img.Data,
+			ImageType: "image/gif",
+			File:      file,
+		})
+		if !html {
+			files[filepath.Join(outDir, file)] = img.Data
+		}
+	}
+
+	var buf bytes.Buffer
+	if html {
+		err = doc.WriteHTML(&buf)
+	} else {
+		err = doc.WriteMarkdown(&buf)
+	}
+	if err != nil {
+		return err
+	}
+	files[output] = buf.Bytes()
+
+	if docsCheck {
+		return checkDocs(files)
+	}
+
+	existing, err := os.ReadFile(output)
+	if err == nil && !docsForce && !bytes.Contains(existing, []byte(appdocs.GeneratedMarker)) {
+		return fmt.Errorf("%s wasn't generated by pixlet docs, pass --force to overwrite it", output)
+	}
+	for file, data := range files {
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		fmt.Println(file)
+	}
+	return nil
+}
+
+// checkDocs fails if any of the files differs from what's on disk.
+func checkDocs(files map[string][]byte) error {
+	var stale []string
+	for file, data := range files {
+		existing, err := os.ReadFile(file)
+		if err != nil || !bytes.Equal(existing, data) {
+			stale = append(stale, file)
+		}
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("docs are out of date, run pixlet docs again: %s", strings.Join(stale, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/tools/fontimport"
+)
+
+var (
+	fontSize      int
+	fontChars     string
+	fontRanges    []string
+	fontName      string
+	fontOutput    string
+	fontPreview   string
+	fontSample    string
+	fontThreshold int
+	fontMagnify   int
+)
+
+func init() {
+	FontImportCmd.Flags().IntVar(&fontSize, "size", 0, "size of an em in pixels, to rasterize TrueType and OpenType fonts at")
+	FontImportCmd.Flags().StringVar(&fontChars, "chars", "", "characters to import (default Basic Latin and Latin-1 Supplement)")
+	FontImportCmd.Flags().StringSliceVar(&fontRanges, "range", nil, "ranges of characters to import, such as U+0400-U+04FF")
+	FontImportCmd.Flags().StringVar(&fontName, "name", "", "name of the font, as apps refer to it (default the file's name)")
+	FontImportCmd.Flags().StringVarP(&fontOutput, "output", "o", "", "path to write the BDF to (default <name>.bdf)")
+	FontImportCmd.Flags().StringVar(&fontPreview, "preview", "", "path to write a preview sheet of the glyphs to (default <output>.png)")
+	FontImportCmd.Flags().StringVar(&fontSample, "sample", "", "text to draw under the glyphs on the preview sheet")
+	FontImportCmd.Flags().IntVar(&fontThreshold, "threshold", fontimport.DefaultThreshold, "how opaque, out of 255, a rasterized pixel must be to be drawn")
+	FontImportCmd.Flags().IntVarP(&fontMagnify, "magnify", "m", 4, "Increase the dimensions of the preview sheet by a factor")
+
+	FontCmd.AddCommand(FontImportCmd)
+}
+
+var FontCmd = &cobra.Command{
+	Use:   "font",
+	Short: "Tools for pixlet's fonts",
+}
+
+var FontImportCmd = &cobra.Command{
+	Use:     "import <font>",
+	Example: `pixlet font import mydisplay.ttf --size 8 --range U+0400-U+04FF`,
+	Short:   "Convert a font to a bitmap font pixlet can render",
+	Args:    cobra.ExactArgs(1),
+	RunE:    importFont,
+	Long: `Convert a TrueType or OpenType font to a BDF bitmap font, keeping only
+the characters that are needed, and draw a preview sheet of its glyphs.
+
+Fonts are rasterized with an em of --size pixels, and pixels that are
+more than --threshold opaque are drawn. BDF fonts are subset without
+rasterizing them again.
+
+To make the font available to apps, copy the BDF to pixlet's fonts
+directory and run go generate ./render.`,
+}
+
+func importFont(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if fontThreshold < 1 || fontThreshold > 255 {
+		return fmt.Errorf("--threshold must be between 1 and 255")
+	}
+
+	var chars []rune
+	if fontChars != "" || len(fontRanges) > 0 {
+		chars = []rune(fontChars)
+	}
+	for _, r := range fontRanges {
+		runes, err := fontimport.ParseRange(r)
+		if err != nil {
+			return err
+		}
+		chars = append(chars, runes...)
+	}
+
+	name := fontName
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	output := fontOutput
+	if output == "" {
+		output = name + ".bdf"
+	}
+	preview := fontPreview
+	if preview == "" {
+		preview = strings.TrimSuffix(output, filepath.Ext(output)) + ".png"
+	}
+
+	font, err := fontimport.Import(data, fontimport.Options{
+		Name:      name,
+		Size:      fontSize,
+		Chars:     chars,
+		Threshold: uint8(fontThreshold),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", path, err)
+	}
+
+	var buf bytes.Buffer
+	if err := font.WriteBDF(&buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+
+	sheet, err := font.Preview(fontSample, fontMagnify)
+	if err != nil {
+		return fmt.Errorf("failed to draw preview: %w", err)
+	}
+	buf.Reset()
+	if err := png.Encode(&buf, sheet); err != nil {
+		return err
+	}
+	if err := os.WriteFile(preview, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", preview, err)
+	}
+
+	fmt.Printf("%s: %d glyphs, %d pixels high (ascent %d, descent %d)\n", output, len(font.Glyphs), font.Ascent+font.Descent, font.Ascent, font.Descent)
+	fmt.Printf("%s: preview\n", preview)
+	if len(font.Missing) > 0 {
+		missing := make([]string, 0, len(font.Missing))
+		for _, r := range font.Missing {
+			missing = append(missing, fmt.Sprintf("U+%04X", r))
+		}
+		fmt.Printf("%d characters aren't in the font: %s\n", len(missing), strings.Join(missing, " "))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/tools"
+	"tidbyt.dev/pixlet/tools/fuzz"
+)
+
+var (
+	fuzzRuns     int
+	fuzzSeed     int64
+	fuzzTimeout  time.Duration
+	fuzzFixtures string
+)
+
+func init() {
+	FuzzCmd.Flags().IntVar(&fuzzRuns, "runs", 200, "how many times to run the app")
+	FuzzCmd.Flags().Int64Var(&fuzzSeed, "seed", 0, "seed for the generated inputs, to reproduce a session (default random)")
+	FuzzCmd.Flags().DurationVar(&fuzzTimeout, "timeout", 10*time.Second, "how long each run may take before it's reported as a timeout")
+	FuzzCmd.Flags().StringVar(&fuzzFixtures, "fixtures", "", "directory of HTTP fixtures to mutate (default testdata/http in the app's directory)")
+}
+
+var FuzzCmd = &cobra.Command{
+	Use:     "fuzz <path>",
+	Example: `pixlet fuzz examples/clock --runs 1000`,
+	Short:   "Run an app with unexpected inputs to find crashes and failures",
+	Args:    cobra.ExactArgs(1),
+	RunE:    fuzzApp,
+	Long: `Run a Pixlet app over and over with generated inputs, to find the ones
+that make it crash, time out or fail() before users do.
+
+Config is generated from the app's schema, and is always valid for it:
+long and unusual text, every option, far off places and times, and so
+on. Responses to HTTP requests are replayed from the fixtures recorded by
+pixlet test --record, and some are mutated: APIs fail, return nothing or
+half a response, or return JSON with values missing or of the wrong type.
+
+Runs that go wrong the same way are reported once, with the inputs of the
+first. The app runs deterministically, so a session can be reproduced
+with its --seed.`,
+}
+
+func fuzzApp(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	var fsys fs.FS
+	var baseDir string
+	if info.IsDir() {
+		fsys = os.DirFS(path)
+		baseDir = path
+	} else {
+		if !strings.HasSuffix(path, ".star") {
+			return fmt.Errorf("script file must have suffix .star: %s", path)
+		}
+		fsys = tools.NewSingleFileFS(path)
+		baseDir = filepath.Dir(path)
+	}
+
+	if !cmd.Flags().Changed("seed") {
+		fuzzSeed = time.Now().UnixNano()
+	}
+	if fuzzFixtures == "" {
+		fuzzFixtures = filepath.Join(baseDir, "testdata", "http")
+	}
+
+	findings, err := fuzz.Fuzz(cmd.Context(), func() (*runtime.Applet, error) {
+		return runtime.NewAppletFromFS(filepath.Base(path), fsys, runtime.WithDeterministic(), runtime.WithPrintDisabled())
+	}, fuzz.Options{
+		Runs:     fuzzRuns,
+		Seed:     fuzzSeed,
+		Timeout:  fuzzTimeout,
+		Fixtures: fuzzFixtures,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, f := range findings {
+		fmt.Printf("--- %s in %d of %d runs, first in run %d\n", f.Kind, f.Count, fuzzRuns, f.Run)
+		fmt.Printf("    %s\n", strings.ReplaceAll(strings.TrimSpace(f.Err), "\n", "\n    "))
+		config, _ := json.Marshal(f.Config)
+		fmt.Printf("    config: %s\n", config)
+		for _, m := range f.Mutations {
+			fmt.Printf("    http: %s\n", m)
+		}
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("%d findings in %d runs of %s (--seed %d)", len(findings), fuzzRuns, path, fuzzSeed)
+	}
+	fmt.Printf("ok\t%s\t%d runs (--seed %d)\n", path, fuzzRuns, fuzzSeed)
+	return nil
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/lib"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/server/hub"
+)
+
+// HubAPIKeyEnv is the environment variable the hub's API key is read from if
+// --api_key isn't given.
+const HubAPIKeyEnv = "PIXLET_HUB_API_KEY"
+
+var (
+	hubState  string
+	hubAPIKey string
+	hubDwell  time.Duration
+
+	hubErrorFrames bool
+)
+
+func init() {
+	HubCmd.Flags().StringVarP(&host, "host", "i", "127.0.0.1", "Host interface for serving the hub")
+	HubCmd.Flags().IntVarP(&port, "port", "p", 8080, "Port for serving the hub")
+	HubCmd.Flags().StringVarP(&hubState, "state", "", "pixlet-hub.json", "File to save devices and their installations to")
+	HubCmd.Flags().StringVarP(&hubAPIKey, "api_key", "", "", fmt.Sprintf("API key clients must send as a bearer token (default $%s)", HubAPIKeyEnv))
+	HubCmd.Flags().DurationVarP(&hubDwell, "dwell", "", hub.DefaultDwell, "How long devices show each app for")
+	HubCmd.Flags().IntVarP(&maxDuration, "max_duration", "d", 15000, "Maximum allowed animation duration (ms)")
+	HubCmd.Flags().IntVarP(&timeout, "timeout", "", 30000, "Timeout for execution (ms)")
+	HubCmd.Flags().StringVarP(&cacheURL, "cache", "", runtime.DefaultCacheURL, cacheFlagUsage())
+	HubCmd.Flags().BoolVarP(&hubErrorFrames, "error_frames", "", false, "Show an error frame for apps whose render fails, rather than their last image")
+}
+
+var HubCmd = &cobra.Command{
+	Use:   "hub [apps directory]",
+	Short: "Serve devices with a Tidbyt compatible API",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runHub,
+	Long: `Serve many devices from one host, with the parts of Tidbyt's device API
+that its mobile apps and push scripts use.
+
+Point existing integrations at http://host:port instead of
+https://api.tidbyt.com, for example with pixlet push --url. Devices poll
+/v0/devices/{device ID}/next for the image to show, and are created the
+first time something is pushed to them. They can report their firmware
+version, uptime, Wi-Fi signal and temperature to
+/v0/devices/{device ID}/telemetry, which /metrics serves for Prometheus.
+
+Apps in the apps directory, .star files or directories of Starlark files,
+can be installed on devices with POST /v0/devices/{device ID}/installations,
+and the hub renders them itself.
+
+Each device's installations are its playlist, shown in turn for their own
+dwell time or --dwell. Installations can be reordered, disabled, limited to
+certain days, times of day or the events of an iCalendar feed, and are
+skipped while they have nothing to show. Installations with a refresh
+interval or cron expression are rendered in the background on that
+schedule, rather than as they come up. POST /api/v1/hooks/{app ID}
+renders an app's installations right away, with any JSON posted to it
+merged into their config, and shows them next. Each hook takes its own
+token, from GET /v0/apps/{app ID}/hook, and hooks are off without an API
+key. Devices with a push target are pushed to as the playlist advances,
+instead of polling.`,
+}
+
+func runHub(cmd *cobra.Command, args []string) error {
+	cache, err := runtime.OpenCache(cacheURL)
+	if err != nil {
+		return err
+	}
+	runtime.InitCache(cache)
+
+	apiKey := hubAPIKey
+	if apiKey == "" {
+		apiKey = os.Getenv(HubAPIKeyEnv)
+	}
+	if apiKey == "" {
+		slog.Warn("no API key set, so anyone who can reach the hub can push to it, and webhooks are turned off; pass --api_key or set the environment variable", "env", HubAPIKeyEnv)
+	}
+
+	opts := hub.Options{
+		StatePath:   hubState,
+		APIKey:      apiKey,
+		Dwell:       hubDwell,
+		ErrorFrames: hubErrorFrames,
+		Render: lib.RenderOptions{
+			MaxDuration: time.Duration(maxDuration) * time.Millisecond,
+			Timeout:     time.Duration(timeout) * time.Millisecond,
+		},
+	}
+	if len(args) > 0 {
+		opts.AppsDir = args[0]
+	}
+
+	h, err := hub.New(opts)
+	if err != nil {
+		return err
+	}
+	return h.Run(fmt.Sprintf("%s:%d", host, port))
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/logging"
+	"tidbyt.dev/pixlet/tools/lsp"
+)
+
+func init() {
+	// editors pass --stdio to say how to talk to the server, which is the
+	// only way it talks
+	LspCmd.Flags().Bool("stdio", true, "talk to the editor over stdin and stdout")
+	LspCmd.Flags().MarkHidden("stdio")
+}
+
+var LspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server for editing apps",
+	Args:  cobra.NoArgs,
+	RunE:  runLsp,
+	Long: `Run a Language Server Protocol server over stdin and stdout, for
+editors such as VS Code to complete pixlet's modules and widget
+parameters, go to definitions across load(), and show problems as apps
+are edited.
+
+Configure your editor to run "pixlet lsp" for Starlark files.`,
+}
+
+func runLsp(cmd *cobra.Command, args []string) error {
+	logger := logging.FromContext(cmd.Context()).With("component", "lsp")
+	return lsp.Serve(cmd.Context(), os.Stdin, os.Stdout, logger)
+}
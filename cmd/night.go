@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/encode"
+)
+
+var (
+	nightSchedule    string
+	nightBrightness  int
+	nightTemperature int
+)
+
+// addNightModeFlags adds the flags that dim and warm renders at night.
+func addNightModeFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVarP(&nightSchedule, "night", "", "", "When to dim the display: a range of times such as 22:00-07:00, or a location such as 40.71,-74.01 for sunset to sunrise there")
+	flags.IntVarP(&nightBrightness, "night_brightness", "", 30, "Brightness at night, in percent")
+	flags.IntVarP(&nightTemperature, "night_temperature", "", 3000, "Color temperature at night, in kelvin, or 0 to leave colors alone")
+}
+
+// nightModeFilters returns the filters that dim renders at time t, per the
+// night mode flags.
+func nightModeFilters(t time.Time) ([]encode.ImageFilter, error) {
+	if nightSchedule == "" {
+		return nil, nil
+	}
+
+	n, err := encode.ParseNightSchedule(nightSchedule)
+	if err != nil {
+		return nil, err
+	}
+	n.Brightness = nightBrightness
+	n.Temperature = nightTemperature
+	return n.Filters(t), nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/device"
+	"tidbyt.dev/pixlet/tronbyt"
+)
+
+// WiFiPasswordEnv is the environment variable the Wi-Fi password is read
+// from if --password isn't given, to keep it out of shell history.
+const WiFiPasswordEnv = "PIXLET_WIFI_PASSWORD"
+
+var (
+	provisionSSID     string
+	provisionPassword string
+	provisionServer   string
+	provisionImageURL string
+	provisionSetupURL string
+	provisionID       string
+	provisionProfile  string
+)
+
+func init() {
+	ProvisionCmd.Flags().StringVar(&provisionSSID, "ssid", "", "Wi-Fi network for the device to join")
+	ProvisionCmd.Flags().StringVar(&provisionPassword, "password", "", fmt.Sprintf("password of the Wi-Fi network (default $%s)", WiFiPasswordEnv))
+	ProvisionCmd.Flags().StringVar(&provisionServer, "server", "", "base URL of the pixlet hub or Tronbyt server the device gets images from")
+	ProvisionCmd.Flags().StringVar(&provisionImageURL, "image_url", "", "URL the device polls for images, instead of the --server's")
+	ProvisionCmd.Flags().StringVar(&provisionSetupURL, "setup_url", tronbyt.SetupURL, "URL of the device's setup page")
+	ProvisionCmd.Flags().StringVar(&provisionID, "id", "", "ID of the device on the server (default the name)")
+	ProvisionCmd.Flags().StringVar(&provisionProfile, "profile", "tronbyt-s3", "what the device can show: "+strings.Join(device.ProfileNames(), ", "))
+	ProvisionCmd.MarkFlagRequired("ssid")
+}
+
+var ProvisionCmd = &cobra.Command{
+	Use:     "provision [name]",
+	Short:   "Set up a new Tronbyt device and register it",
+	Example: `pixlet provision kitchen --ssid Home --server http://hub.local:8080 --profile tronbyt-s3-wide`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    provision,
+	Long: `Set up a factory-fresh Tronbyt device: tell it which Wi-Fi network to join
+and where to get images from, and register it under a name, as
+pixlet devices add does.
+
+Until it's set up, the device creates its own Wi-Fi network. Connect to it
+first, then run this command. With --server, the device polls the server's
+/v0/devices/{ID}/next, which is what pixlet hub serves. Provisioning over
+Bluetooth isn't supported.`,
+}
+
+func provision(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	id := provisionID
+	if id == "" {
+		id = name
+	}
+
+	profile, err := device.LookupProfile(provisionProfile)
+	if err != nil {
+		return err
+	}
+
+	imageURL := provisionImageURL
+	if imageURL == "" {
+		if provisionServer == "" {
+			return fmt.Errorf("pass the --server the device gets images from, or its --image_url")
+		}
+		imageURL = strings.TrimSuffix(provisionServer, "/") + "/v0/devices/" + url.PathEscape(id) + "/next"
+	}
+
+	password := provisionPassword
+	if password == "" {
+		password = os.Getenv(WiFiPasswordEnv)
+	}
+
+	err = tronbyt.Provision(cmd.Context(), nil, provisionSetupURL, tronbyt.WiFiConfig{
+		SSID:     provisionSSID,
+		Password: password,
+		ImageURL: imageURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := registerDevice(device.Device{
+		Name:    name,
+		ID:      id,
+		Target:  "tronbyt",
+		URL:     provisionServer,
+		Profile: profile,
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Provisioned %s. It will restart, join %s and show images from %s\n", name, provisionSSID, imageURL)
+	return nil
+}
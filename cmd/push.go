@@ -11,10 +11,13 @@ import (
 
 	"github.com/spf13/cobra"
 	"tidbyt.dev/pixlet/cmd/config"
+	"tidbyt.dev/pixlet/tronbyt"
 )
 
 const (
 	APITokenEnv = "TIDBYT_API_TOKEN"
+
+	tidbytAPIURL = "https://api.tidbyt.com"
 )
 
 var (
@@ -22,6 +25,9 @@ var (
 	installationID string
 	background     bool
 	pushURL        string
+	pushTarget     string
+	pushBrightness int
+	pushPin        bool
 )
 
 type TidbytPushJSON struct {
@@ -35,14 +41,29 @@ func init() {
 	PushCmd.Flags().StringVarP(&apiToken, "api-token", "t", "", "Tidbyt API token")
 	PushCmd.Flags().StringVarP(&installationID, "installation-id", "i", "", "Give your installation an ID to keep it in the rotation")
 	PushCmd.Flags().BoolVarP(&background, "background", "b", false, "Don't immediately show the image on the device")
-	PushCmd.Flags().StringVarP(&pushURL, "url", "u", "https://api.tidbyt.com", "base URL of Tidbyt API")
+	PushCmd.Flags().StringVarP(&pushURL, "url", "u", tidbytAPIURL, "base URL of Tidbyt API, or of the Tronbyt device or server")
+	PushCmd.Flags().StringVar(&pushTarget, "target", "tidbyt", "where to push: tidbyt or tronbyt")
+	PushCmd.Flags().IntVar(&pushBrightness, "brightness", -1, "set the brightness of a Tronbyt device, from 0 to 100")
+	PushCmd.Flags().BoolVar(&pushPin, "pin", false, "pin the installation on a Tronbyt device so it stays on screen")
 }
 
 var PushCmd = &cobra.Command{
-	Use:   "push [device ID] [webp image]",
+	Use:   "push [device ID or name] [webp image]",
 	Short: "Render a Pixlet script and push the WebP output to a Tidbyt",
 	Args:  cobra.MinimumNArgs(2),
 	RunE:  push,
+	Long: `Push a WebP image to a device.
+
+By default, images are pushed through Tidbyt's cloud API. Pass --target
+tronbyt and the --url of a self-hosted Tronbyt device or server to push there
+instead, authenticating with the device's API key from --api-token or
+$TRONBYT_API_KEY. Tronbyt devices can also have their brightness set and an
+installation pinned as part of the push.
+
+Devices registered with pixlet devices add can be pushed to by name. Images
+in a format the device's profile doesn't list are transcoded to one it does,
+so a WebP render can be pushed to a device that only shows GIFs, and the
+image is checked to be one the device can show.`,
 }
 
 func push(cmd *cobra.Command, args []string) error {
@@ -60,6 +81,44 @@ func push(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("background push won't do anything unless you also specify an installation ID")
 	}
 
+	imageData, err := os.ReadFile(image)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", image, err)
+	}
+
+	// the device may be one registered with `pixlet devices add`
+	d, registered, err := config.LookupDevice(deviceID)
+	if err != nil {
+		return err
+	}
+	if registered {
+		deviceID = d.ID
+		if !cmd.Flags().Changed("target") {
+			pushTarget = d.Target
+		}
+		if !cmd.Flags().Changed("url") && d.URL != "" {
+			pushURL = d.URL
+		}
+
+		if imageData, err = d.Profile.Transcode(imageData); err != nil {
+			return fmt.Errorf("can't push %s to %s: %w", image, d.Name, err)
+		}
+		if err := d.Profile.Check(imageData); err != nil {
+			return fmt.Errorf("can't push %s to %s: %w", image, d.Name, err)
+		}
+	}
+
+	switch pushTarget {
+	case "tidbyt":
+		if pushBrightness >= 0 || pushPin {
+			return fmt.Errorf("--brightness and --pin are only supported with --target tronbyt")
+		}
+	case "tronbyt":
+		return pushToTronbyt(cmd, deviceID, imageData)
+	default:
+		return fmt.Errorf("unknown push target %q, expected tidbyt or tronbyt", pushTarget)
+	}
+
 	if apiToken == "" {
 		apiToken = os.Getenv(APITokenEnv)
 	}
@@ -72,11 +131,6 @@ func push(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("blank Tidbyt API token (use `pixlet login`, set $%s or pass with --api-token)", APITokenEnv)
 	}
 
-	imageData, err := os.ReadFile(image)
-	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", image, err)
-	}
-
 	payload, err := json.Marshal(
 		TidbytPushJSON{
 			DeviceID:       deviceID,
@@ -115,3 +169,40 @@ func push(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func pushToTronbyt(cmd *cobra.Command, deviceID string, imageData []byte) error {
+	if pushURL == tidbytAPIURL {
+		return fmt.Errorf("pass the --url of your Tronbyt device or server")
+	}
+	if pushPin && installationID == "" {
+		return fmt.Errorf("--pin needs an installation ID")
+	}
+
+	if apiToken == "" {
+		apiToken = os.Getenv(tronbyt.APIKeyEnv)
+	}
+	if apiToken == "" {
+		return fmt.Errorf("blank Tronbyt API key (set $%s or pass with --api-token)", tronbyt.APIKeyEnv)
+	}
+
+	ctx := cmd.Context()
+	client := &tronbyt.Client{URL: pushURL, APIKey: apiToken}
+
+	if pushBrightness >= 0 {
+		if err := client.SetBrightness(ctx, deviceID, pushBrightness); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Push(ctx, deviceID, imageData, tronbyt.PushOptions{
+		InstallationID: installationID,
+		Background:     background,
+	}); err != nil {
+		return err
+	}
+
+	if pushPin {
+		return client.PinInstallation(ctx, deviceID, installationID)
+	}
+	return nil
+}
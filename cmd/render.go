@@ -7,8 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/device"
+	"tidbyt.dev/pixlet/lib"
 	"tidbyt.dev/pixlet/runtime"
 	"tidbyt.dev/pixlet/server/loader"
 )
@@ -23,6 +26,11 @@ var (
 	width         int
 	height        int
 	timeout       int
+	cacheURL      string
+	renderDevice  string
+	deterministic bool
+	interpolate   int
+	debugTree     bool
 )
 
 func init() {
@@ -65,6 +73,37 @@ func init() {
 		30000,
 		"Timeout for execution (ms)",
 	)
+	RenderCmd.Flags().StringVarP(
+		&cacheURL,
+		"cache",
+		"",
+		runtime.DefaultCacheURL,
+		cacheFlagUsage(),
+	)
+	RenderCmd.Flags().BoolVar(&deterministic, "deterministic", false, "Render the same output for the same config and HTTP responses, by pinning the time and seeding random numbers")
+	RenderCmd.Flags().StringVarP(&renderDevice, "device", "", "", "Render for a registered device, or a device profile, picking its size and format unless they're given")
+	RenderCmd.Flags().IntVar(&interpolate, "interpolate", 0, "Insert up to this many frames per frame where parts of the display move, for smoother scrolling (e.g. 2)")
+	RenderCmd.Flags().BoolVar(&debugTree, "debug-tree", false, "Write where each widget was laid out in the first frame as JSON, next to the image or to stderr if the image goes to stdout")
+	RenderCmd.Flags().BoolVarP(&vflag, "verbose", "v", false, "Write how long painting each frame took, and which widgets the time went to, to stderr as JSON")
+	addNightModeFlags(RenderCmd)
+}
+
+func cacheFlagUsage() string {
+	return fmt.Sprintf("Cache backend URL (schemes: %s)", strings.Join(runtime.CacheSchemes(), ", "))
+}
+
+// initCache opens the cache selected by the --cache flag and installs it as
+// the cache for both HTTP requests and the cache module.
+func initCache() (runtime.Cache, error) {
+	cache, err := runtime.OpenCache(cacheURL)
+	if err != nil {
+		return nil, err
+	}
+
+	runtime.InitHTTP(cache)
+	runtime.InitCache(cache)
+
+	return cache, nil
 }
 
 var RenderCmd = &cobra.Command{
@@ -77,6 +116,9 @@ var RenderCmd = &cobra.Command{
 The path argument should be the path to the Pixlet app to run. The
 app can be a single file with the .star extension, or a directory
 containing multiple Starlark files and resources.
+
+When no config is given, the app is rendered with the defaults of its
+schema.
 	`,
 }
 
@@ -89,6 +131,26 @@ func render(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to stat %s: %w", path, err)
 	}
 
+	var profile *device.Profile
+	if renderDevice != "" {
+		p, err := lookupProfile(renderDevice)
+		if err != nil {
+			return err
+		}
+		profile = &p
+
+		flags := cmd.Flags()
+		if !flags.Changed("width") {
+			width = p.Width
+		}
+		if !flags.Changed("height") {
+			height = p.Height
+		}
+		if !flags.Changed("gif") {
+			renderGif = p.Format() == "gif"
+		}
+	}
+
 	var outPath string
 	if info.IsDir() {
 		outPath = filepath.Join(path, filepath.Base(path))
@@ -138,14 +200,45 @@ func render(cmd *cobra.Command, args []string) error {
 		config[split[0]] = strings.Join(split[1:len(split)], "=")
 	}
 
-	cache := runtime.NewInMemoryCache()
-	runtime.InitHTTP(cache)
-	runtime.InitCache(cache)
+	if _, err := initCache(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if deterministic {
+		now = runtime.DeterministicTime()
+	}
+	filters, err := nightModeFilters(now)
+	if err != nil {
+		return err
+	}
+	if profile != nil {
+		filters = append(filters, profile.Filters()...)
+	}
 
-	buf, err := loader.RenderApplet(path, config, width, height, magnify, maxDuration, timeout, renderGif, silenceOutput)
+	format := lib.FormatWebP
+	if renderGif {
+		format = lib.FormatGIF
+	}
+	img, err := loader.RenderAppletImage(path, config, lib.LoadOptions{
+		SilencePrint:  silenceOutput,
+		Deterministic: deterministic,
+	}, lib.RenderOptions{
+		Width:       width,
+		Height:      height,
+		Magnify:     magnify,
+		Format:      format,
+		MaxDuration: time.Duration(maxDuration) * time.Millisecond,
+		Timeout:     time.Duration(timeout) * time.Millisecond,
+		Filters:     filters,
+		Interpolate: interpolate,
+		Inspect:     debugTree,
+		Diagnose:    vflag,
+	})
 	if err != nil {
 		return fmt.Errorf("error rendering: %w", err)
 	}
+	buf := img.Data
 
 	if outPath == "-" {
 		_, err = os.Stdout.Write(buf)
@@ -157,5 +250,55 @@ func render(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("writing %s: %s", outPath, err)
 	}
 
+	if debugTree {
+		if err := writeTree(outPath, img); err != nil {
+			return err
+		}
+	}
+
+	if vflag {
+		if err := writeDiagnostics(img.Diagnostics); err != nil {
+			return err
+		}
+	}
+
+	if profile != nil && len(buf) > 0 {
+		if err := profile.Check(buf); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s won't be able to show %s: %v\n", renderDevice, outPath, err)
+		}
+	}
+
 	return nil
 }
+
+// writeTree writes the widget tree of an image written to outPath next to
+// it, as outPath.tree.json, or to stderr if the image went to stdout.
+func writeTree(outPath string, img *lib.EncodedImage) error {
+	buf, err := json.MarshalIndent(img.Tree, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding widget tree: %w", err)
+	}
+	buf = append(buf, '\n')
+
+	if outPath == "-" {
+		_, err = os.Stderr.Write(buf)
+		return err
+	}
+	treePath := outPath + ".tree.json"
+	if err := os.WriteFile(treePath, buf, 0644); err != nil {
+		return fmt.Errorf("writing %s: %s", treePath, err)
+	}
+	return nil
+}
+
+// writeDiagnostics writes where the time rendering an image went to stderr.
+func writeDiagnostics(d *lib.Diagnostics) error {
+	buf, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding diagnostics: %w", err)
+	}
+	buf = append(buf, '\n')
+
+	_, err = os.Stderr.Write(buf)
+	return err
+}
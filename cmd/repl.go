@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/chzyer/readline"
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/tools/repl"
+)
+
+var replPreview string
+
+func init() {
+	ReplCmd.Flags().StringVarP(&replPreview, "preview", "", "auto", "How to preview widgets: ascii, kitty, none, or auto to use kitty in terminals that support it")
+	ReplCmd.Flags().StringVarP(&cacheURL, "cache", "", runtime.DefaultCacheURL, cacheFlagUsage())
+}
+
+var ReplCmd = &cobra.Command{
+	Use:   "repl",
+	Short: "Run an interactive Starlark prompt with pixlet's modules loaded",
+	Args:  cobra.NoArgs,
+	RunE:  runRepl,
+	Long: `Run an interactive Starlark prompt for prototyping apps, with the
+render, animation, schema, http and time modules already loaded. Other
+modules can be loaded with load().
+
+Expressions that evaluate to widgets are previewed in the terminal, with
+the kitty graphics protocol in terminals that support it, or with colored
+characters in others.`,
+}
+
+func runRepl(cmd *cobra.Command, args []string) error {
+	preview, err := repl.ParsePreview(replPreview)
+	if err != nil {
+		return err
+	}
+
+	cache, err := runtime.OpenCache(cacheURL)
+	if err != nil {
+		return err
+	}
+	runtime.InitHTTP(cache)
+	runtime.InitCache(cache)
+
+	session, err := repl.NewSession(os.Stdout, preview)
+	if err != nil {
+		return err
+	}
+
+	config := &readline.Config{}
+	if home, err := os.UserHomeDir(); err == nil {
+		config.HistoryFile = filepath.Join(home, ".pixlet_history")
+	}
+	rl, err := readline.NewEx(config)
+	if err != nil {
+		return fmt.Errorf("starting prompt: %w", err)
+	}
+	defer rl.Close()
+
+	return session.Run(cmd.Context(), func(prompt string) (string, error) {
+		rl.SetPrompt(prompt)
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			return "", repl.ErrInterrupt
+		}
+		return line, err
+	})
+}
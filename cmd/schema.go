@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/lib"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func init() {
+	SchemaCmd.AddCommand(SchemaDefaultsCmd)
+	SchemaCmd.AddCommand(SchemaJSONCmd)
+}
+
+var SchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Inspect the config schema of a Pixlet app",
+}
+
+var SchemaDefaultsCmd = &cobra.Command{
+	Use:     "defaults <path>",
+	Short:   "Print the default config of a Pixlet app",
+	Example: `pixlet schema defaults examples/clock > config.json`,
+	Long: `Print the config an app gets when every field of its schema is left at its
+default, as JSON. The output can be passed to pixlet render with --config.`,
+	Args: cobra.ExactArgs(1),
+	RunE: schemaDefaults,
+}
+
+var SchemaJSONCmd = &cobra.Command{
+	Use:     "jsonschema <path>",
+	Short:   "Print the config schema of a Pixlet app as JSON Schema",
+	Example: `pixlet schema jsonschema examples/schema_hello_world`,
+	Long: `Print a JSON Schema (draft 2020-12) describing the config of an app, so that
+generic form libraries and other tools can configure it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: schemaJSON,
+}
+
+// loadAppletSchema loads the applet at path just to read its schema, with
+// print output silenced and an in-memory cache.
+func loadAppletSchema(path string) (*lib.Applet, error) {
+	cache := runtime.NewInMemoryCache()
+	runtime.InitHTTP(cache)
+	runtime.InitCache(cache)
+
+	return lib.LoadAppletFromPath(path, lib.LoadOptions{SilencePrint: true})
+}
+
+func schemaDefaults(cmd *cobra.Command, args []string) error {
+	applet, err := loadAppletSchema(args[0])
+	if err != nil {
+		return err
+	}
+
+	config := applet.DefaultConfig()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(config); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
+
+func schemaJSON(cmd *cobra.Command, args []string) error {
+	applet, err := loadAppletSchema(args[0])
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(applet.JSONSchema()); err != nil {
+		return fmt.Errorf("writing schema: %w", err)
+	}
+	return nil
+}
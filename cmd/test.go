@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/tools"
+)
+
+var (
+	testRun      string
+	testRecord   bool
+	testFixtures string
+	testRedact   []string
+	testTimeout  time.Duration
+	testCoverage bool
+	testProfile  string
+)
+
+func init() {
+	TestCmd.Flags().StringVar(&testRun, "run", "", "only run tests whose names match this regular expression")
+	TestCmd.Flags().BoolVar(&testRecord, "record", false, "make HTTP requests for real and record their responses as fixtures")
+	TestCmd.Flags().StringVar(&testFixtures, "fixtures", "", "directory of HTTP fixtures (default testdata/http in the app's directory)")
+	TestCmd.Flags().StringSliceVar(&testRedact, "redact", nil, "more query parameters to keep out of recorded fixtures")
+	TestCmd.Flags().DurationVar(&testTimeout, "timeout", 30*time.Second, "timeout for each test")
+	TestCmd.Flags().BoolVar(&testCoverage, "coverage", false, "report the share of the app's lines the tests execute")
+	TestCmd.Flags().StringVar(&testProfile, "coverprofile", "", "write coverage to this file in lcov format (implies --coverage)")
+	TestCmd.Flags().BoolVar(&deterministic, "deterministic", true, "pin the time and seed random numbers, as render --deterministic does")
+}
+
+var TestCmd = &cobra.Command{
+	Use:     "test <path>",
+	Example: `pixlet test examples/clock`,
+	Short:   "Run an app's tests",
+	Args:    cobra.ExactArgs(1),
+	RunE:    test,
+	Long: `Run the test_ functions of a Pixlet app.
+
+Tests fail if they call fail(), or if they fail an assertion of the
+assert.star or golden.star modules.
+
+Tests run deterministically by default: time.now() returns the same time
+every run, and random numbers repeat.
+
+HTTP requests made by tests are replayed from fixtures, so tests don't
+depend on live APIs. Run with --record to make the requests for real and
+record the responses. Values of query parameters that commonly carry API
+keys, such as key and token, are redacted from fixtures.
+
+With --coverage, the share of the app's lines the tests execute is
+reported for each file. --coverprofile writes it in lcov format, which
+CI services read.`,
+}
+
+// testReporter collects the assertions a test fails.
+type testReporter struct {
+	errors []string
+}
+
+func (r *testReporter) Error(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func test(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	var fsys fs.FS
+	var baseDir string
+	if info.IsDir() {
+		fsys = os.DirFS(path)
+		baseDir = path
+	} else {
+		if !strings.HasSuffix(path, ".star") {
+			return fmt.Errorf("script file must have suffix .star: %s", path)
+		}
+		fsys = tools.NewSingleFileFS(path)
+		baseDir = filepath.Dir(path)
+	}
+
+	var run *regexp.Regexp
+	if testRun != "" {
+		if run, err = regexp.Compile(testRun); err != nil {
+			return fmt.Errorf("invalid --run: %w", err)
+		}
+	}
+
+	fixtures := &runtime.HTTPFixtures{
+		Dir:    testFixtures,
+		Redact: append(append([]string{}, runtime.DefaultRedactedParams...), testRedact...),
+	}
+	if fixtures.Dir == "" {
+		fixtures.Dir = filepath.Join(baseDir, "testdata", "http")
+	}
+	if testRecord {
+		fixtures.Mode = runtime.FixturesRecord
+	}
+
+	// tests don't share a cache with renders, so they're repeatable
+	cache := runtime.NewInMemoryCache()
+	runtime.InitHTTPFixtures(cache, fixtures)
+	runtime.InitCache(cache)
+
+	var opts []runtime.AppletOption
+	if deterministic {
+		opts = append(opts, runtime.WithDeterministic())
+	}
+	var coverage *runtime.Coverage
+	if testCoverage || testProfile != "" {
+		coverage = runtime.NewCoverage()
+		opts = append(opts, runtime.WithCoverage(coverage))
+	}
+	applet, err := runtime.NewAppletFromFS(filepath.Base(path), fsys, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to load applet: %w", err)
+	}
+
+	start := time.Now()
+	ran, failed := 0, 0
+	for _, name := range applet.Tests() {
+		if run != nil && !run.MatchString(name) {
+			continue
+		}
+		ran++
+
+		r := &testReporter{}
+		testStart := time.Now()
+		ctx, cancel := context.WithTimeout(cmd.Context(), testTimeout)
+		if err := applet.RunTest(ctx, name, r); err != nil {
+			r.errors = append(r.errors, err.Error())
+		}
+		cancel()
+
+		result := "PASS"
+		if len(r.errors) > 0 {
+			result = "FAIL"
+			failed++
+		}
+		fmt.Printf("--- %s: %s (%.2fs)\n", result, name, time.Since(testStart).Seconds())
+		for _, msg := range r.errors {
+			fmt.Printf("    %s\n", strings.ReplaceAll(msg, "\n", "\n    "))
+		}
+	}
+
+	if coverage != nil {
+		if err := reportCoverage(coverage, baseDir); err != nil {
+			return err
+		}
+	}
+
+	if ran == 0 {
+		fmt.Printf("ok\t%s\t%.2fs [no tests to run]\n", path, time.Since(start).Seconds())
+		return nil
+	}
+	if failed > 0 {
+		fmt.Printf("FAIL\t%s\t%.2fs\n", path, time.Since(start).Seconds())
+		return fmt.Errorf("%d of %d tests failed", failed, ran)
+	}
+	fmt.Printf("ok\t%s\t%.2fs\n", path, time.Since(start).Seconds())
+	return nil
+}
+
+// reportCoverage prints the coverage of each of the app's files, and
+// writes it to --coverprofile if set.
+func reportCoverage(coverage *runtime.Coverage, baseDir string) error {
+	files := coverage.Files()
+	covered, total := 0, 0
+	for i, fc := range files {
+		fmt.Printf("coverage: %s\t%s\n", fc.Path, percent(fc.Covered(), len(fc.Lines)))
+		covered += fc.Covered()
+		total += len(fc.Lines)
+
+		// lcov paths are relative to where pixlet was run, so CI finds them
+		files[i].Path = filepath.Join(baseDir, filepath.FromSlash(fc.Path))
+	}
+	fmt.Printf("coverage: %s of statements\n", percent(covered, total))
+
+	if testProfile == "" {
+		return nil
+	}
+	f, err := os.Create(testProfile)
+	if err != nil {
+		return fmt.Errorf("failed to create coverage profile: %w", err)
+	}
+	defer f.Close()
+	if err := runtime.WriteLCOV(f, files); err != nil {
+		return fmt.Errorf("failed to write coverage profile: %w", err)
+	}
+	return f.Close()
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
@@ -0,0 +1,151 @@
+// Package device describes the displays pixlet renders for, so that renders
+// and pushes can pick the right size and format for each one.
+package device
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"tidbyt.dev/pixlet/encode"
+)
+
+// Profile describes what a display can show.
+type Profile struct {
+	// Width and Height of the display in pixels.
+	Width  int `json:"width" yaml:"width" mapstructure:"width"`
+	Height int `json:"height" yaml:"height" mapstructure:"height"`
+
+	// ColorDepth is the number of bits per color channel the display can
+	// show, up to 8.
+	ColorDepth int `json:"color_depth" yaml:"color_depth" mapstructure:"color_depth"`
+
+	// Formats are the image formats the display accepts, "webp", "gif" or
+	// "raw", in order of preference. Raw images are frames of 8-bit RGB
+	// pixels, for displays that can't decode images; see encode.EncodeRaw.
+	Formats []string `json:"formats" yaml:"formats" mapstructure:"formats"`
+
+	// MaxPayload is the largest image, in bytes, that the display accepts.
+	// Zero means there's no limit.
+	MaxPayload int `json:"max_payload,omitempty" yaml:"max_payload,omitempty" mapstructure:"max_payload"`
+
+	// Panel describes how the display's LEDs look, for simulating it.
+	Panel Panel `json:"panel,omitzero" yaml:"panel,omitempty" mapstructure:"panel"`
+}
+
+// Profiles are the built-in profiles, by name.
+var Profiles = map[string]Profile{
+	"tidbyt": {
+		Width: 64, Height: 32, ColorDepth: 8,
+		Formats: []string{"webp"},
+		Panel:   Panel{DotSize: 0.8, Bloom: 0.35, Gamma: 2.2},
+	},
+	"tidbyt-gen2": {
+		Width: 64, Height: 32, ColorDepth: 8,
+		Formats: []string{"webp"},
+		Panel:   Panel{DotSize: 0.85, Bloom: 0.4, Gamma: 2.2},
+	},
+	"tronbyt-s3": {
+		Width: 64, Height: 32, ColorDepth: 8,
+		Formats: []string{"webp", "gif"},
+		Panel:   Panel{DotSize: 0.65, Bloom: 0.2, Gamma: 2.2},
+	},
+	"tronbyt-s3-wide": {
+		Width: 128, Height: 64, ColorDepth: 8,
+		Formats: []string{"webp", "gif"},
+		Panel:   Panel{DotSize: 0.6, Bloom: 0.2, Gamma: 2.2},
+	},
+	"matrixportal-s3": {
+		Width: 64, Height: 32, ColorDepth: 5,
+		Formats:    []string{"webp"},
+		MaxPayload: 64 << 10,
+		Panel:      Panel{DotSize: 0.6, Bloom: 0.15, Gamma: 1},
+	},
+}
+
+// DefaultProfile is the profile of devices that don't name one.
+const DefaultProfile = "tidbyt"
+
+// ProfileNames returns the names of the built-in profiles, sorted.
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupProfile returns the built-in profile with the given name.
+func LookupProfile(name string) (Profile, error) {
+	p, ok := Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown device profile %q, expected one of %s", name, strings.Join(ProfileNames(), ", "))
+	}
+	return p, nil
+}
+
+// Format returns the image format renders for the display should use.
+func (p Profile) Format() string {
+	if len(p.Formats) == 0 {
+		return "webp"
+	}
+	return p.Formats[0]
+}
+
+// Filters returns the filters that adapt rendered frames to the display.
+func (p Profile) Filters() []encode.ImageFilter {
+	if p.ColorDepth > 0 && p.ColorDepth < 8 {
+		return []encode.ImageFilter{encode.ColorDepth(p.ColorDepth)}
+	}
+	return nil
+}
+
+// Check returns an error if the display can't show image, because it's in a
+// format the display doesn't accept or it's too large.
+func (p Profile) Check(image []byte) error {
+	format := ImageFormat(image)
+	if len(p.Formats) > 0 && !slices.Contains(p.Formats, format) {
+		return fmt.Errorf("device accepts %s images, not %s", strings.Join(p.Formats, " or "), format)
+	}
+	if p.MaxPayload > 0 && len(image) > p.MaxPayload {
+		return fmt.Errorf("image is %d bytes, but the device accepts at most %d; try a shorter --max_duration", len(image), p.MaxPayload)
+	}
+	return nil
+}
+
+// ImageFormat returns the format of an encoded image: "webp", "gif", or
+// its media type if it's neither.
+func ImageFormat(image []byte) string {
+	switch {
+	case bytes.HasPrefix(image, []byte("GIF8")):
+		return "gif"
+	case len(image) >= 12 && string(image[:4]) == "RIFF" && string(image[8:12]) == "WEBP":
+		return "webp"
+	default:
+		return http.DetectContentType(image)
+	}
+}
+
+// Device is a display registered with pixlet, so that it can be rendered
+// for and pushed to by name.
+type Device struct {
+	// Name is what the device is called on the command line.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// ID is the device's ID on the service it's pushed through.
+	ID string `json:"id" yaml:"id" mapstructure:"id"`
+
+	// Target is the service the device is pushed through: "tidbyt" or
+	// "tronbyt".
+	Target string `json:"target" yaml:"target" mapstructure:"target"`
+
+	// URL is the base URL of the service. Empty means Tidbyt's API.
+	URL string `json:"url,omitempty" yaml:"url,omitempty" mapstructure:"url"`
+
+	// Profile describes what the device can show.
+	Profile Profile `json:"profile" yaml:"profile" mapstructure:"profile"`
+}
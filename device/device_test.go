@@ -0,0 +1,39 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupProfile(t *testing.T) {
+	p, err := LookupProfile("tronbyt-s3-wide")
+	require.NoError(t, err)
+	assert.Equal(t, 128, p.Width)
+	assert.Equal(t, "webp", p.Format())
+	assert.Empty(t, p.Filters())
+
+	p, err = LookupProfile("matrixportal-s3")
+	require.NoError(t, err)
+	assert.Len(t, p.Filters(), 1)
+
+	_, err = LookupProfile("toaster")
+	assert.ErrorContains(t, err, "tidbyt, tidbyt-gen2")
+}
+
+func TestCheck(t *testing.T) {
+	webp := []byte("RIFF\x00\x00\x00\x00WEBPVP8L")
+	gif := []byte("GIF89a\x01\x00\x01\x00")
+
+	assert.Equal(t, "webp", ImageFormat(webp))
+	assert.Equal(t, "gif", ImageFormat(gif))
+	assert.Equal(t, "image/png", ImageFormat([]byte("\x89PNG\r\n\x1a\n")))
+
+	p := Profile{Formats: []string{"webp"}, MaxPayload: 16}
+	assert.NoError(t, p.Check(webp))
+	assert.ErrorContains(t, p.Check(gif), "accepts webp images, not gif")
+	assert.ErrorContains(t, p.Check(append(webp, make([]byte, 16)...)), "at most 16")
+
+	assert.NoError(t, Profile{}.Check(gif))
+}
@@ -0,0 +1,207 @@
+package device
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"tidbyt.dev/pixlet/encode"
+)
+
+// Panel describes how a display's LEDs look, so that previews can show
+// renders as they'll look on the display rather than as smoothly scaled
+// pixels.
+type Panel struct {
+	// DotSize is the diameter of an LED as a fraction of the distance
+	// between LEDs. Zero means DefaultPanel's.
+	DotSize float64 `json:"dot_size,omitempty" yaml:"dot_size,omitempty" mapstructure:"dot_size"`
+
+	// Bloom is how much of an LED's light spreads onto its neighbors, from
+	// 0 for none to 1 for as much as it shines itself.
+	Bloom float64 `json:"bloom,omitempty" yaml:"bloom,omitempty" mapstructure:"bloom"`
+
+	// Gamma is the exponent that color values are raised to to get the
+	// brightness of an LED. 2.2 matches a monitor; smaller values make dark
+	// colors brighter, as on panels without gamma correction. Zero means
+	// DefaultPanel's.
+	Gamma float64 `json:"gamma,omitempty" yaml:"gamma,omitempty" mapstructure:"gamma"`
+}
+
+// DefaultPanel is how LEDs look on displays whose profiles don't say.
+var DefaultPanel = Panel{DotSize: 0.75, Bloom: 0.25, Gamma: 2.2}
+
+const (
+	// monitorGamma is the gamma of the monitors that simulations are
+	// viewed on.
+	monitorGamma = 2.2
+
+	// unlit is the brightness of an LED that's off, relative to one that's
+	// fully on, so the grid of LEDs shows on black.
+	unlit = 0.004
+
+	// bloomSigma is how far light blooms, in LEDs.
+	bloomSigma = 0.7
+)
+
+// Simulate returns the filters that show frames as they'd look on the
+// display, scaled up by scale.
+func (p Profile) Simulate(scale int) []encode.ImageFilter {
+	return append(p.Filters(), p.Panel.Simulate(scale))
+}
+
+// Simulate returns a filter that draws frames the way the panel shows them,
+// scaled up by scale: each pixel is a round LED with dark gaps around it,
+// light blooms onto its neighbors, and colors are as bright as the panel's
+// gamma makes them. Scales of one or less leave frames unchanged.
+func (p Panel) Simulate(scale int) encode.ImageFilter {
+	if p.DotSize <= 0 {
+		p.DotSize = DefaultPanel.DotSize
+	}
+	if p.Gamma <= 0 {
+		p.Gamma = DefaultPanel.Gamma
+	}
+	bloom := min(max(p.Bloom, 0), 1)
+
+	// color values to LED brightness
+	var toLinear [256]float64
+	for v := range toLinear {
+		toLinear[v] = math.Pow(float64(v)/255, p.Gamma)
+	}
+
+	// brightness to color values on a monitor
+	var toMonitor [4096]uint8
+	for i := range toMonitor {
+		toMonitor[i] = uint8(math.Round(math.Pow(float64(i)/float64(len(toMonitor)-1), 1/monitorGamma) * 255))
+	}
+
+	dot := dotMask(scale, p.DotSize)
+
+	return func(input image.Image) (image.Image, error) {
+		if scale <= 1 {
+			return input, nil
+		}
+
+		bounds := input.Bounds()
+		w, h := bounds.Dx(), bounds.Dy()
+
+		// brightness of each LED, composited onto black, one slice per
+		// channel
+		var leds [3][]float64
+		for c := range leds {
+			leds[c] = make([]float64, w*h)
+		}
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				r, g, b, _ := input.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				leds[0][y*w+x] = toLinear[r>>8]
+				leds[1][y*w+x] = toLinear[g>>8]
+				leds[2][y*w+x] = toLinear[b>>8]
+			}
+		}
+
+		var glow [3][]float64
+		if bloom > 0 {
+			for c := range glow {
+				glow[c] = blur(leds[c], w, h)
+			}
+		}
+
+		out := image.NewRGBA(image.Rect(0, 0, w*scale, h*scale))
+		for oy := 0; oy < h*scale; oy++ {
+			for ox := 0; ox < w*scale; ox++ {
+				x, y := ox/scale, oy/scale
+				cover := dot[(oy%scale)*scale+ox%scale]
+
+				var rgb [3]uint8
+				for c := range rgb {
+					v := cover * (leds[c][y*w+x] + unlit)
+					if bloom > 0 {
+						v += bloom * sample(glow[c], w, h, (float64(ox)+0.5)/float64(scale)-0.5, (float64(oy)+0.5)/float64(scale)-0.5)
+					}
+					v = min(v, 1)
+					rgb[c] = toMonitor[int(v*float64(len(toMonitor)-1)+0.5)]
+				}
+				out.SetRGBA(ox, oy, color.RGBA{rgb[0], rgb[1], rgb[2], 0xff})
+			}
+		}
+		return out, nil
+	}
+}
+
+// dotMask returns how much of each pixel of a scale by scale cell is
+// covered by an LED of the given size, with antialiased edges.
+func dotMask(scale int, size float64) []float64 {
+	if scale <= 1 {
+		return nil
+	}
+
+	mask := make([]float64, scale*scale)
+	center := float64(scale) / 2
+	radius := size * float64(scale) / 2
+	for y := range scale {
+		for x := range scale {
+			d := math.Hypot(float64(x)+0.5-center, float64(y)+0.5-center)
+			mask[y*scale+x] = min(max(radius-d+0.5, 0), 1)
+		}
+	}
+	return mask
+}
+
+// blur returns a Gaussian blur of a w by h channel. Light blurred past the
+// edges is lost, as it is on a panel.
+func blur(in []float64, w, h int) []float64 {
+	const radius = 2
+	var kernel [2*radius + 1]float64
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * bloomSigma * bloomSigma))
+	}
+	var sum float64
+	for _, k := range kernel {
+		sum += k
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	tmp := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v float64
+			for i, k := range kernel {
+				if xx := x + i - radius; xx >= 0 && xx < w {
+					v += k * in[y*w+xx]
+				}
+			}
+			tmp[y*w+x] = v
+		}
+	}
+
+	out := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v float64
+			for i, k := range kernel {
+				if yy := y + i - radius; yy >= 0 && yy < h {
+					v += k * tmp[yy*w+x]
+				}
+			}
+			out[y*w+x] = v
+		}
+	}
+	return out
+}
+
+// sample interpolates a w by h channel bilinearly at (x, y), where integers
+// are the centers of pixels. Points past the edges take the nearest pixel.
+func sample(ch []float64, w, h int, x, y float64) float64 {
+	x = min(max(x, 0), float64(w-1))
+	y = min(max(y, 0), float64(h-1))
+	x0, y0 := int(x), int(y)
+	x1, y1 := min(x0+1, w-1), min(y0+1, h-1)
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	top := ch[y0*w+x0]*(1-fx) + ch[y0*w+x1]*fx
+	bottom := ch[y1*w+x0]*(1-fx) + ch[y1*w+x1]*fx
+	return top*(1-fy) + bottom*fy
+}
@@ -0,0 +1,64 @@
+package device
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulate(t *testing.T) {
+	in := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	in.SetRGBA(1, 0, color.RGBA{0xff, 0xff, 0xff, 0xff})
+
+	p := Panel{DotSize: 0.6, Gamma: 2.2}
+	out, err := p.Simulate(10)(in)
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 30, 20), out.Bounds())
+
+	// the middle of the lit LED is as bright as the pixel, its corners are
+	// gaps, and unlit LEDs are dim
+	assert.Equal(t, color.RGBA{0xff, 0xff, 0xff, 0xff}, out.At(15, 5))
+	assert.Equal(t, color.RGBA{0, 0, 0, 0xff}, out.At(10, 0))
+	r, _, _, _ := out.At(5, 5).RGBA()
+	assert.Greater(t, r>>8, uint32(0))
+	assert.Less(t, r>>8, uint32(0x20))
+
+	// bloom lights the gaps around a lit LED
+	p.Bloom = 0.5
+	out, err = p.Simulate(10)(in)
+	require.NoError(t, err)
+	near, _, _, _ := out.At(10, 0).RGBA()
+	far, _, _, _ := out.At(29, 19).RGBA()
+	assert.Greater(t, near>>8, uint32(0x20))
+	assert.Less(t, far, near)
+}
+
+func TestSimulateGamma(t *testing.T) {
+	in := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	in.SetRGBA(0, 0, color.RGBA{0x40, 0x40, 0x40, 0xff})
+
+	center := func(p Panel) uint8 {
+		out, err := p.Simulate(4)(in)
+		require.NoError(t, err)
+		return out.(*image.RGBA).RGBAAt(2, 2).R
+	}
+
+	// a panel without gamma correction shows dark colors brighter than a
+	// monitor would
+	assert.InDelta(t, 0x40, center(Panel{DotSize: 1, Gamma: 2.2}), 2)
+	assert.Greater(t, center(Panel{DotSize: 1, Gamma: 1}), uint8(0x80))
+}
+
+func TestSimulateScale(t *testing.T) {
+	in := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	out, err := DefaultPanel.Simulate(1)(in)
+	require.NoError(t, err)
+	assert.Same(t, in, out)
+
+	p, err := LookupProfile("matrixportal-s3")
+	require.NoError(t, err)
+	assert.Len(t, p.Simulate(4), 2)
+}
@@ -0,0 +1,36 @@
+package device
+
+import (
+	"fmt"
+	"slices"
+
+	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/render/imagediff"
+)
+
+// Transcode returns image in a format the display accepts. Images already in
+// one are returned as they are, and others are decoded and encoded again in
+// the display's preferred format, keeping each frame's delay, so that a
+// render can be pushed to any display whatever format it was made in.
+func (p Profile) Transcode(image []byte) ([]byte, error) {
+	if len(p.Formats) == 0 || slices.Contains(p.Formats, ImageFormat(image)) {
+		return image, nil
+	}
+
+	a, err := imagediff.Decode(image)
+	if err != nil {
+		return nil, fmt.Errorf("transcoding to %s: %w", p.Format(), err)
+	}
+	screens := encode.ScreensFromFrames(a.Frames, a.Delays)
+
+	switch p.Format() {
+	case "webp":
+		return screens.EncodeWebP(0)
+	case "gif":
+		return screens.EncodeGIF(0)
+	case "raw":
+		return screens.EncodeRaw(0)
+	default:
+		return nil, fmt.Errorf("can't transcode to %s", p.Format())
+	}
+}
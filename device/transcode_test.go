@@ -0,0 +1,54 @@
+package device
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/render/imagediff"
+)
+
+func TestTranscode(t *testing.T) {
+	red := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	blue := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for i := 0; i < len(red.Pix); i += 4 {
+		copy(red.Pix[i:], []byte{255, 0, 0, 255})
+		copy(blue.Pix[i:], []byte{0, 0, 255, 255})
+	}
+	screens := encode.ScreensFromFrames(
+		[]image.Image{red, blue},
+		[]time.Duration{500 * time.Millisecond, 100 * time.Millisecond},
+	)
+	webp, err := screens.EncodeWebP(0)
+	require.NoError(t, err)
+
+	// images the display accepts are left alone
+	same, err := Profile{Formats: []string{"gif", "webp"}}.Transcode(webp)
+	require.NoError(t, err)
+	assert.Equal(t, webp, same)
+
+	// others are encoded again, with the same frames and delays
+	gif, err := Profile{Formats: []string{"gif"}}.Transcode(webp)
+	require.NoError(t, err)
+	assert.Equal(t, "gif", ImageFormat(gif))
+	a, err := imagediff.Decode(gif)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, 100 * time.Millisecond}, a.Delays)
+	assert.Equal(t, color.RGBA{0, 0, 255, 255}, color.RGBAModel.Convert(a.Frames[1].At(3, 1)))
+
+	webp, err = Profile{Formats: []string{"webp"}}.Transcode(gif)
+	require.NoError(t, err)
+	assert.Equal(t, "webp", ImageFormat(webp))
+
+	raw, err := Profile{Formats: []string{"raw"}}.Transcode(webp)
+	require.NoError(t, err)
+	assert.Len(t, raw, 2*(2+4*2*3))
+	assert.Equal(t, []byte{0x01, 0xf4, 255, 0, 0}, raw[:5])
+
+	_, err = Profile{Formats: []string{"gif"}}.Transcode([]byte("not an image"))
+	assert.ErrorContains(t, err, "transcoding to gif")
+}
@@ -63,6 +63,7 @@ func main() {
 	for name, snippet := range examples {
 		src := fmt.Sprintf(`
 load("render.star", "render")
+load("time.star", "time")
 def main():
     w = %s
     return render.Root(child=w)
@@ -1,9 +1,12 @@
 package encode
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"image"
+	"image/draw"
+	"time"
 
 	"github.com/vmihailenco/msgpack/v5"
 
@@ -21,8 +24,27 @@ type Screens struct {
 	roots             []render.Root
 	images            []image.Image
 	delay             int32
+	delays            []time.Duration
 	MaxAge            int32
 	ShowFullAnimation bool
+
+	// ShowFor and RefreshIn are what the roots suggest, in seconds, for
+	// how long the screens are shown and when the app is rendered again.
+	// ShowFor is the total of the roots' suggestions, and RefreshIn the
+	// soonest. Zero means the roots don't say.
+	ShowFor   int32
+	RefreshIn int32
+
+	// Truncated is set after encoding if the context passed to
+	// WithContext was done before all frames had been painted.
+	Truncated bool
+
+	ctx context.Context
+
+	// interpolate is the factor set with Interpolate, and interpolated
+	// whether images have been interpolated yet.
+	interpolate  int
+	interpolated bool
 }
 
 type ImageFilter func(image.Image) (image.Image, error)
@@ -42,6 +64,14 @@ func ScreensFromRoots(roots []render.Root) *Screens {
 		}
 		screens.ShowFullAnimation = roots[0].ShowFullAnimation
 	}
+	for _, r := range roots {
+		if r.ShowFor > 0 {
+			screens.ShowFor += r.ShowFor
+		}
+		if r.RefreshIn > 0 && (screens.RefreshIn == 0 || r.RefreshIn < screens.RefreshIn) {
+			screens.RefreshIn = r.RefreshIn
+		}
+	}
 	return &screens
 }
 
@@ -54,6 +84,80 @@ func ScreensFromImages(images ...image.Image) *Screens {
 	return &screens
 }
 
+// ScreensFromFrames makes screens of decoded frames, each shown for its own
+// delay, such as to encode an image again in another format.
+func ScreensFromFrames(frames []image.Image, delays []time.Duration) *Screens {
+	images := make([]image.Image, len(frames))
+	for i, frame := range frames {
+		rgba, ok := frame.(*image.RGBA)
+		if !ok {
+			rgba = image.NewRGBA(frame.Bounds())
+			draw.Draw(rgba, rgba.Bounds(), frame, frame.Bounds().Min, draw.Src)
+		}
+		images[i] = rgba
+	}
+
+	screens := ScreensFromImages(images...)
+	screens.delays = delays
+	return screens
+}
+
+// frameDelay returns how many milliseconds frame i is shown for.
+func (s *Screens) frameDelay(i int) int {
+	if i < len(s.delays) {
+		return int(s.delays[i] / time.Millisecond)
+	}
+	return int(s.delay)
+}
+
+// Split divides the screens into the separate screens the app asked for,
+// each starting at a root that's Separate, so that they can be encoded and
+// shown on their own. Screens made from images aren't split.
+func (s *Screens) Split() []*Screens {
+	if len(s.roots) == 0 {
+		return []*Screens{s}
+	}
+
+	var split []*Screens
+	start := 0
+	for i := 1; i <= len(s.roots); i++ {
+		if i == len(s.roots) || s.roots[i].Separate {
+			screens := ScreensFromRoots(s.roots[start:i])
+			screens.ctx = s.ctx
+			screens.interpolate = s.interpolate
+			split = append(split, screens)
+			start = i
+		}
+	}
+	return split
+}
+
+// WithContext makes encoding stop painting frames once ctx is done.
+// Whatever was painted up to that point is still encoded, so a slow
+// app produces a shortened animation rather than no image at all.
+func (s *Screens) WithContext(ctx context.Context) *Screens {
+	s.ctx = ctx
+	return s
+}
+
+// Inspect returns where the widgets of each of the render roots are laid
+// out in frame frameIdx of the root, see render.Root.Inspect. It's empty
+// for screens made from images.
+func (s *Screens) Inspect(frameIdx int) []*render.Node {
+	var trees []*render.Node
+	for _, r := range s.roots {
+		trees = append(trees, r.Inspect(frameIdx))
+	}
+	return trees
+}
+
+// Profile paints the frames of the render roots again, one at a time, and
+// reports how long each took and which widgets the time went to, see
+// render.ProfileRoots. It's empty for screens made from images.
+func (s *Screens) Profile() *render.Profile {
+	return render.ProfileRoots(s.roots...)
+}
+
 // Empty returns true if there are no render roots or images in this screen.
 func (s *Screens) Empty() bool {
 	return len(s.roots) == 0 && len(s.images) == 0
@@ -89,15 +193,61 @@ func (s *Screens) Hash() ([]byte, error) {
 	return h[:], nil
 }
 
+// Frames paints the screens and returns each frame along with how long it's
+// shown, for displays that are driven directly instead of being sent an
+// encoded image. Like the encoders, it stops once maxDuration milliseconds
+// are used up, unless maxDuration is 0.
+func (s *Screens) Frames(maxDuration int, filters ...ImageFilter) ([]image.Image, []time.Duration, error) {
+	images, err := s.render(filters...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var frames []image.Image
+	var delays []time.Duration
+	remainingDuration := maxDuration
+	for i, im := range images {
+		frameDelay := s.frameDelay(i)
+		if maxDuration > 0 {
+			if frameDelay > remainingDuration {
+				frameDelay = remainingDuration
+			}
+			remainingDuration -= frameDelay
+		}
+
+		frames = append(frames, im)
+		delays = append(delays, time.Duration(frameDelay)*time.Millisecond)
+
+		if maxDuration > 0 && remainingDuration <= 0 {
+			break
+		}
+	}
+
+	return frames, delays, nil
+}
+
 func (s *Screens) render(filters ...ImageFilter) ([]image.Image, error) {
 	if s.images == nil {
-		s.images = render.PaintRoots(true, s.roots...)
+		if s.ctx != nil {
+			s.images, s.Truncated = render.PaintRootsContext(s.ctx, true, s.roots...)
+		} else {
+			s.images = render.PaintRoots(true, s.roots...)
+		}
 	}
 
 	if len(s.images) == 0 {
 		return nil, nil
 	}
 
+	if s.interpolate > 1 && !s.interpolated {
+		delays := make([]int, len(s.images))
+		for i := range delays {
+			delays[i] = s.frameDelay(i)
+		}
+		s.images, s.delays = interpolateFrames(s.images, delays, s.interpolate)
+		s.interpolated = true
+	}
+
 	images := s.images
 
 	if len(filters) > 0 {
@@ -116,3 +266,30 @@ func (s *Screens) render(filters ...ImageFilter) ([]image.Image, error) {
 
 	return images, nil
 }
+
+// FrameAt paints the screens and returns the frame shown once elapsed time
+// has passed since the animation started, looping it as displays do. It's
+// for clients that poll for a still image.
+func (s *Screens) FrameAt(elapsed time.Duration, maxDuration int, filters ...ImageFilter) (image.Image, error) {
+	frames, delays, err := s.Frames(maxDuration, filters...)
+	if err != nil || len(frames) == 0 {
+		return nil, err
+	}
+
+	var total time.Duration
+	for _, d := range delays {
+		total += d
+	}
+	if total <= 0 {
+		return frames[0], nil
+	}
+
+	elapsed %= total
+	for i, d := range delays {
+		if elapsed < d {
+			return frames[i], nil
+		}
+		elapsed -= d
+	}
+	return frames[len(frames)-1], nil
+}
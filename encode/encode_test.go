@@ -3,9 +3,13 @@ package encode
 import (
 	"bytes"
 	"context"
+	"image"
+	"image/color"
+	"image/draw"
 	"image/gif"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -252,6 +256,45 @@ func TestScreensFromRoots(t *testing.T) {
 	assert.Equal(t, int32(42), s.MaxAge)
 }
 
+func TestSplit(t *testing.T) {
+	s := ScreensFromRoots([]render.Root{
+		{Child: &render.Text{Content: "tree 0"}, Delay: 100},
+		{Child: &render.Text{Content: "tree 1"}},
+		{Child: &render.Text{Content: "tree 2"}, Separate: true, Delay: 200},
+		{Child: &render.Text{Content: "tree 3"}, Separate: true},
+	})
+
+	split := s.Split()
+	require.Len(t, split, 3)
+	assert.Len(t, split[0].roots, 2)
+	assert.Equal(t, int32(100), split[0].delay)
+	assert.Len(t, split[1].roots, 1)
+	assert.Equal(t, int32(200), split[1].delay)
+	assert.Equal(t, "tree 3", split[2].roots[0].Child.(*render.Text).Content)
+	assert.Equal(t, int32(DefaultScreenDelayMillis), split[2].delay)
+
+	// screens made from images, or of nothing, are left whole
+	images := ScreensFromImages(image.NewRGBA(image.Rect(0, 0, 64, 32)))
+	assert.Equal(t, []*Screens{images}, images.Split())
+	assert.Len(t, ScreensFromRoots(nil).Split(), 1)
+}
+
+func TestScreensHints(t *testing.T) {
+	// the roots are shown one after another, and the app is refreshed when
+	// the first of them asks
+	s := ScreensFromRoots([]render.Root{
+		{Child: &render.Text{Content: "tree 0"}, ShowFor: 10, RefreshIn: 300},
+		{Child: &render.Text{Content: "tree 1"}},
+		{Child: &render.Text{Content: "tree 2"}, ShowFor: 5, RefreshIn: 120},
+	})
+	assert.Equal(t, int32(15), s.ShowFor)
+	assert.Equal(t, int32(120), s.RefreshIn)
+
+	s = ScreensFromRoots([]render.Root{{Child: &render.Text{Content: "tree 0"}}})
+	assert.Zero(t, s.ShowFor)
+	assert.Zero(t, s.RefreshIn)
+}
+
 func TestShowFullAnimation(t *testing.T) {
 	requestFull := `
 load("render.star", "render")
@@ -381,3 +424,139 @@ def main():
 	}
 
 }
+
+func TestTruncatedOnContextDone(t *testing.T) {
+	src := []byte(`
+load("render.star", "render")
+
+def main():
+    return render.Root(
+        child = render.Marquee(
+            width = 64,
+            child = render.Box(width = 100, height = 1, color = "#f00"),
+        ),
+    )
+`)
+
+	app, err := runtime.NewApplet("test.star", src)
+	require.NoError(t, err)
+
+	roots, err := app.Run(context.Background())
+	require.NoError(t, err)
+	require.Greater(t, roots[0].Child.FrameCount(), 1)
+
+	// A live context paints every frame.
+	screens := ScreensFromRoots(roots).WithContext(context.Background())
+	webpData, err := screens.EncodeWebP(0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, webpData)
+	assert.False(t, screens.Truncated)
+
+	// Once the context is done, only the first frame is painted, but
+	// it's still encoded rather than failing.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	screens = ScreensFromRoots(roots).WithContext(ctx)
+	gifData, err := screens.EncodeGIF(0)
+	assert.NoError(t, err)
+	assert.True(t, screens.Truncated)
+
+	im, err := gif.DecodeAll(bytes.NewBuffer(gifData))
+	assert.NoError(t, err)
+	assert.Len(t, im.Image, 1)
+}
+
+func TestBrightness(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 200, G: 100, B: 0, A: 255})
+
+	out, err := Brightness(50)(img)
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 100, G: 50, B: 0, A: 255}, out.At(0, 0))
+
+	out, err = Brightness(100)(img)
+	require.NoError(t, err)
+	assert.Same(t, img, out)
+}
+
+func TestFrameAt(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	b := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	screens := ScreensFromImages(a, b)
+	delay := time.Duration(DefaultScreenDelayMillis) * time.Millisecond
+
+	for _, tc := range []struct {
+		elapsed  time.Duration
+		expected image.Image
+	}{
+		{0, a},
+		{delay - time.Millisecond, a},
+		{delay, b},
+		{2 * delay, a},
+		{5*delay + 1, b},
+	} {
+		frame, err := screens.FrameAt(tc.elapsed, 0)
+		require.NoError(t, err)
+		assert.Same(t, tc.expected, frame, "at %s", tc.elapsed)
+	}
+}
+
+func TestColorDepth(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 255, G: 0x87, B: 0x07, A: 255})
+
+	out, err := ColorDepth(5)(img)
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 255, G: 0x83, B: 0, A: 255}, out.At(0, 0))
+
+	out, err = ColorDepth(8)(img)
+	require.NoError(t, err)
+	assert.Same(t, img, out)
+}
+
+func TestScreensFromFrames(t *testing.T) {
+	red := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	draw.Draw(red, red.Bounds(), image.NewUniform(color.NRGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	blue := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	draw.Draw(blue, blue.Bounds(), image.NewUniform(color.RGBA{0, 0, 255, 255}), image.Point{}, draw.Src)
+
+	// each frame keeps its own delay
+	s := ScreensFromFrames([]image.Image{red, blue}, []time.Duration{300 * time.Millisecond, 20 * time.Millisecond})
+	frames, delays, err := s.Frames(0)
+	require.NoError(t, err)
+	assert.Len(t, frames, 2)
+	assert.Equal(t, []time.Duration{300 * time.Millisecond, 20 * time.Millisecond}, delays)
+
+	g, err := s.EncodeGIF(0)
+	require.NoError(t, err)
+	decoded, err := gif.DecodeAll(bytes.NewReader(g))
+	require.NoError(t, err)
+	assert.Equal(t, []int{30, 2}, decoded.Delay)
+
+	raw, err := s.EncodeRaw(0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x01, 0x2c, 255, 0, 0, 255, 0, 0,
+		0x00, 0x14, 0, 0, 255, 0, 0, 255,
+	}, raw)
+
+	rgb565, err := s.EncodeRGB565(0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x00, 0xf8, 0x00, 0xf8,
+		0x1f, 0x00, 0x1f, 0x00,
+	}, rgb565)
+
+	// fewer colors make smaller GIFs
+	few, err := ScreensFromImages(image.NewRGBA(image.Rect(0, 0, 2, 1))).EncodeGIFColors(2, 0)
+	require.NoError(t, err)
+	decoded, err = gif.DecodeAll(bytes.NewReader(few))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(decoded.Image[0].Palette), 2)
+	_, err = s.EncodeGIFColors(1, 0)
+	assert.Error(t, err)
+
+	lossy, err := s.EncodeLossyWebP(50, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "WEBP", string(lossy[8:12]))
+}
@@ -0,0 +1,102 @@
+package encode
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Magnify returns a filter that scales images up by an integer factor using
+// nearest-neighbor sampling, which keeps pixels crisp. Factors of one or less
+// leave images unchanged.
+func Magnify(factor int) ImageFilter {
+	return func(input image.Image) (image.Image, error) {
+		if factor <= 1 {
+			return input, nil
+		}
+		in, ok := input.(*image.RGBA)
+		if !ok {
+			return nil, fmt.Errorf("image not RGBA, very weird")
+		}
+
+		out := image.NewRGBA(
+			image.Rect(
+				0, 0,
+				in.Bounds().Dx()*factor,
+				in.Bounds().Dy()*factor),
+		)
+		for x := range in.Bounds().Dx() {
+			for y := range in.Bounds().Dy() {
+				for xx := range factor {
+					for yy := range factor {
+						out.SetRGBA(
+							x*factor+xx,
+							y*factor+yy,
+							in.RGBAAt(x, y),
+						)
+					}
+				}
+			}
+		}
+
+		return out, nil
+	}
+}
+
+// Brightness returns a filter that scales the color of every pixel by
+// percent, so 0 blanks the image and 100 leaves it unchanged.
+func Brightness(percent int) ImageFilter {
+	percent = max(percent, 0)
+	return func(input image.Image) (image.Image, error) {
+		if percent >= 100 {
+			return input, nil
+		}
+		f := float64(percent) / 100
+		return scaleColors(input, f, f, f), nil
+	}
+}
+
+// scaleColors returns a copy of input with each color channel multiplied by
+// a factor between 0 and 1.
+func scaleColors(input image.Image, r, g, b float64) image.Image {
+	bounds := input.Bounds()
+	out := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cr, cg, cb, ca := input.At(x, y).RGBA()
+			out.SetRGBA(x, y, color.RGBA{
+				R: uint8(float64(cr>>8) * r),
+				G: uint8(float64(cg>>8) * g),
+				B: uint8(float64(cb>>8) * b),
+				A: uint8(ca >> 8),
+			})
+		}
+	}
+	return out
+}
+
+// ColorDepth returns a filter that reduces every color channel to bits bits,
+// the way a display with that color depth would show it.
+func ColorDepth(bits int) ImageFilter {
+	bits = min(max(bits, 1), 8)
+	levels := uint32(1)<<bits - 1
+	return func(input image.Image) (image.Image, error) {
+		if bits == 8 {
+			return input, nil
+		}
+
+		quantize := func(v uint32) uint8 {
+			return uint8((v >> (16 - bits)) * 255 / levels)
+		}
+
+		bounds := input.Bounds()
+		out := image.NewRGBA(bounds)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, a := input.At(x, y).RGBA()
+				out.SetRGBA(x, y, color.RGBA{R: quantize(r), G: quantize(g), B: quantize(b), A: uint8(a >> 8)})
+			}
+		}
+		return out, nil
+	}
+}
@@ -14,6 +14,17 @@ import (
 // Renders a screen to GIF. Optionally pass filters for postprocessing
 // each individual frame.
 func (s *Screens) EncodeGIF(maxDuration int, filters ...ImageFilter) ([]byte, error) {
+	return s.EncodeGIFColors(256, maxDuration, filters...)
+}
+
+// EncodeGIFColors renders a screen to GIF with at most the given number of
+// colors in each frame's palette, up to 256. Fewer colors make for smaller
+// GIFs with banding in gradients.
+func (s *Screens) EncodeGIFColors(colors int, maxDuration int, filters ...ImageFilter) ([]byte, error) {
+	if colors < 2 || colors > 256 {
+		return nil, fmt.Errorf("GIFs have from 2 to 256 colors, not %d", colors)
+	}
+
 	images, err := s.render(filters...)
 	if err != nil {
 		return nil, err
@@ -32,11 +43,11 @@ func (s *Screens) EncodeGIF(maxDuration int, filters ...ImageFilter) ([]byte, er
 			return nil, fmt.Errorf("image %d is %T, require RGBA", imIdx, im)
 		}
 
-		palette := quantize.MedianCutQuantizer{}.Quantize(make([]color.Color, 0, 256), im)
+		palette := quantize.MedianCutQuantizer{}.Quantize(make([]color.Color, 0, colors), im)
 		imPaletted := image.NewPaletted(imRGBA.Bounds(), palette)
 		draw.Draw(imPaletted, imRGBA.Bounds(), imRGBA, image.Point{0, 0}, draw.Src)
 
-		frameDelay := int(s.delay)
+		frameDelay := s.frameDelay(imIdx)
 		if maxDuration > 0 {
 			if frameDelay > remainingDuration {
 				frameDelay = remainingDuration
@@ -0,0 +1,218 @@
+package encode
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"time"
+)
+
+const (
+	// interpolationBlock is the size of the squares frames are divided into
+	// to find what moves between them.
+	interpolationBlock = 8
+
+	// maxInterpolationShift is how many pixels a block may move between two
+	// frames and still be interpolated.
+	maxInterpolationShift = 4
+
+	// MinInterpolatedDelay is the shortest time, in milliseconds, that
+	// frames inserted by interpolation are shown for, which is about as
+	// fast as GIFs play.
+	MinInterpolatedDelay = 20
+)
+
+// Interpolate makes encoding insert up to factor-1 frames between each
+// frame and the next, where parts of the frame move, such as a Marquee
+// scrolling or an Animation translating a widget. The parts that move are
+// drawn part of the way along, so that they move smoothly on displays that
+// can show more frames per second than the app painted. The animation takes
+// as long as before. Frames aren't split into frames shorter than
+// MinInterpolatedDelay, and frames where nothing moves aren't split at all.
+func (s *Screens) Interpolate(factor int) *Screens {
+	s.interpolate = factor
+	return s
+}
+
+// interpolateFrames returns frames with frames interpolated between them,
+// along with how long each is shown. The last frame is interpolated towards
+// the first, since displays loop animations.
+func interpolateFrames(frames []image.Image, delays []int, factor int) ([]image.Image, []time.Duration) {
+	var out []image.Image
+	var outDelays []time.Duration
+
+	for i, frame := range frames {
+		n := factor
+		for n > 1 && delays[i]/n < MinInterpolatedDelay {
+			n--
+		}
+
+		var field *motionField
+		if n > 1 && len(frames) > 1 {
+			field = findMotion(toRGBA(frame), toRGBA(frames[(i+1)%len(frames)]))
+		}
+		if field == nil {
+			out = append(out, frame)
+			outDelays = append(outDelays, time.Duration(delays[i])*time.Millisecond)
+			continue
+		}
+
+		each := delays[i] / n
+		for k := range n {
+			img := frame
+			if k > 0 {
+				img = field.at(float64(k) / float64(n))
+			}
+			delay := each
+			if k == n-1 {
+				delay = delays[i] - each*(n-1)
+			}
+			out = append(out, img)
+			outDelays = append(outDelays, time.Duration(delay)*time.Millisecond)
+		}
+	}
+	return out, outDelays
+}
+
+// motionField is how each block of a frame moves to get to the next frame.
+type motionField struct {
+	from, to *image.RGBA
+
+	// shifts are how far each block moves, by row and column of blocks.
+	// Blocks that stay put, or change in ways other than moving, don't
+	// move.
+	shifts [][]image.Point
+}
+
+// findMotion finds how the blocks of from move to get to to, or returns
+// nil if none of them do.
+func findMotion(from, to *image.RGBA) *motionField {
+	bounds := from.Bounds()
+	if to.Bounds() != bounds {
+		return nil
+	}
+
+	field := &motionField{from: from, to: to}
+	moving := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += interpolationBlock {
+		var row []image.Point
+		for x := bounds.Min.X; x < bounds.Max.X; x += interpolationBlock {
+			block := image.Rect(x, y, x+interpolationBlock, y+interpolationBlock).Intersect(bounds)
+			shift := findShift(from, to, block)
+			moving = moving || shift != image.Point{}
+			row = append(row, shift)
+		}
+		field.shifts = append(field.shifts, row)
+	}
+
+	if !moving {
+		return nil
+	}
+	return field
+}
+
+// findShift finds how far the block of to moved from where it was in from.
+// It's the shift that explains the most of the block, preferring shorter
+// ones, or none if the block doesn't match closely anywhere.
+func findShift(from, to *image.RGBA, block image.Rectangle) image.Point {
+	bounds := from.Bounds()
+	size := block.Dx() * block.Dy()
+
+	best, bestMismatches := image.Point{}, size+1
+	for dy := -maxInterpolationShift; dy <= maxInterpolationShift; dy++ {
+		for dx := -maxInterpolationShift; dx <= maxInterpolationShift; dx++ {
+			shift := image.Pt(dx, dy)
+			mismatches := 0
+			for y := block.Min.Y; y < block.Max.Y && mismatches <= bestMismatches; y++ {
+				for x := block.Min.X; x < block.Max.X; x++ {
+					src := image.Pt(x, y).Sub(shift)
+					if !src.In(bounds) || to.RGBAAt(x, y) != from.RGBAAt(src.X, src.Y) {
+						mismatches++
+					}
+				}
+			}
+
+			if mismatches < bestMismatches || mismatches == bestMismatches && manhattan(shift) < manhattan(best) {
+				best, bestMismatches = shift, mismatches
+			}
+		}
+	}
+
+	// blocks that change in other ways, such as text being replaced,
+	// are left alone rather than smeared
+	if bestMismatches > size/8 {
+		return image.Point{}
+	}
+	return best
+}
+
+// at returns the frame a fraction t of the way from one frame to the next.
+// Pixels of moving blocks are sampled t of the way back along their shift
+// from the first frame, and the rest of the way forward in the next, and
+// blended; other pixels are as in the first frame.
+func (f *motionField) at(t float64) image.Image {
+	bounds := f.from.Bounds()
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, f.from, bounds.Min, draw.Src)
+
+	for row, shifts := range f.shifts {
+		for col, shift := range shifts {
+			if shift == (image.Point{}) {
+				continue
+			}
+
+			origin := bounds.Min.Add(image.Pt(col, row).Mul(interpolationBlock))
+			block := image.Rectangle{origin, origin.Add(image.Pt(interpolationBlock, interpolationBlock))}.Intersect(bounds)
+			dx, dy := float64(shift.X), float64(shift.Y)
+			for y := block.Min.Y; y < block.Max.Y; y++ {
+				for x := block.Min.X; x < block.Max.X; x++ {
+					a := sample(f.from, float64(x)-t*dx, float64(y)-t*dy)
+					b := sample(f.to, float64(x)+(1-t)*dx, float64(y)+(1-t)*dy)
+					i := out.PixOffset(x, y)
+					for c := range 4 {
+						out.Pix[i+c] = uint8(math.Round((1-t)*a[c] + t*b[c]))
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// sample returns the color of img at a point between pixels, interpolated
+// between the four around it. Points outside img take the nearest pixel.
+func sample(img *image.RGBA, x, y float64) [4]float64 {
+	bounds := img.Bounds()
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+
+	pixel := func(x, y int) []uint8 {
+		x = max(bounds.Min.X, min(bounds.Max.X-1, x))
+		y = max(bounds.Min.Y, min(bounds.Max.Y-1, y))
+		i := img.PixOffset(x, y)
+		return img.Pix[i : i+4]
+	}
+
+	var c [4]float64
+	tl, tr := pixel(int(x0), int(y0)), pixel(int(x0)+1, int(y0))
+	bl, br := pixel(int(x0), int(y0)+1), pixel(int(x0)+1, int(y0)+1)
+	for i := range c {
+		top := (1-fx)*float64(tl[i]) + fx*float64(tr[i])
+		bottom := (1-fx)*float64(bl[i]) + fx*float64(br[i])
+		c[i] = (1-fy)*top + fy*bottom
+	}
+	return c
+}
+
+func manhattan(p image.Point) int {
+	return max(p.X, -p.X) + max(p.Y, -p.Y)
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,83 @@
+package encode
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// square returns a black frame with a white square at x.
+func square(x int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := range 16 {
+		for x := range 32 {
+			img.SetRGBA(x, y, color.RGBA{A: 255})
+		}
+	}
+	for y := 6; y < 10; y++ {
+		for dx := range 4 {
+			img.SetRGBA(x+dx, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+	return img
+}
+
+func TestInterpolate(t *testing.T) {
+	screens := ScreensFromFrames(
+		[]image.Image{square(10), square(12)},
+		[]time.Duration{100 * time.Millisecond, 100 * time.Millisecond},
+	).Interpolate(2)
+
+	frames, delays, err := screens.Frames(0)
+	require.NoError(t, err)
+	require.Len(t, frames, 4)
+	assert.Equal(t, []time.Duration{
+		50 * time.Millisecond, 50 * time.Millisecond,
+		50 * time.Millisecond, 50 * time.Millisecond,
+	}, delays)
+
+	// halfway between, the square is a pixel along
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	black := color.RGBA{A: 255}
+	mid := frames[1].(*image.RGBA)
+	assert.Equal(t, black, mid.RGBAAt(10, 8))
+	assert.Equal(t, white, mid.RGBAAt(11, 8))
+	assert.Equal(t, white, mid.RGBAAt(14, 8))
+	assert.Equal(t, black, mid.RGBAAt(15, 8))
+
+	// and on the way back to the first frame, it's a pixel back
+	back := frames[3].(*image.RGBA)
+	assert.Equal(t, white, back.RGBAAt(11, 8))
+	assert.Equal(t, black, back.RGBAAt(15, 8))
+
+	// encoding uses the interpolated frames too
+	_, err = screens.EncodeWebP(0)
+	require.NoError(t, err)
+}
+
+func TestInterpolateOnlySplitsMotion(t *testing.T) {
+	// frames where nothing moves are left alone
+	frames, delays, err := ScreensFromFrames(
+		[]image.Image{square(10), square(10)},
+		[]time.Duration{100 * time.Millisecond, 100 * time.Millisecond},
+	).Interpolate(4).Frames(0)
+	require.NoError(t, err)
+	assert.Len(t, frames, 2)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond}, delays)
+
+	// and frames aren't split into frames shorter than the minimum
+	frames, delays, err = ScreensFromFrames(
+		[]image.Image{square(10), square(12)},
+		[]time.Duration{50 * time.Millisecond, 50 * time.Millisecond},
+	).Interpolate(4).Frames(0)
+	require.NoError(t, err)
+	assert.Len(t, frames, 4)
+	assert.Equal(t, []time.Duration{
+		25 * time.Millisecond, 25 * time.Millisecond,
+		25 * time.Millisecond, 25 * time.Millisecond,
+	}, delays)
+}
@@ -0,0 +1,149 @@
+package encode
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathan-osman/go-sunrise"
+)
+
+// NeutralTemperature is the color temperature, in kelvin, that leaves colors
+// unchanged.
+const NeutralTemperature = 6600
+
+// ColorTemperature returns a filter that shifts white towards the color of
+// light at kelvin, so that temperatures below NeutralTemperature give
+// warmer, more orange images, which are easier on the eyes at night.
+func ColorTemperature(kelvin int) ImageFilter {
+	r, g, b := whitePoint(kelvin)
+	return func(input image.Image) (image.Image, error) {
+		if kelvin >= NeutralTemperature {
+			return input, nil
+		}
+		return scaleColors(input, r, g, b), nil
+	}
+}
+
+// whitePoint approximates the color of a black body at kelvin, with each
+// channel between 0 and 1. It's Tanner Helland's fit of the blackbody
+// spectrum, which is close enough for tinting a display.
+func whitePoint(kelvin int) (r, g, b float64) {
+	t := float64(min(max(kelvin, 1000), 40000)) / 100
+
+	r, g, b = 255, 255, 255
+	if t > 66 {
+		r = 329.698727446 * math.Pow(t-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(t-60, -0.0755148492)
+	} else {
+		g = 99.4708025861*math.Log(t) - 161.1195681661
+		switch {
+		case t <= 19:
+			b = 0
+		case t < 66:
+			b = 138.5177312231*math.Log(t-10) - 305.0447927307
+		}
+	}
+
+	clamp := func(v float64) float64 { return min(max(v, 0), 255) / 255 }
+	return clamp(r), clamp(g), clamp(b)
+}
+
+// NightMode dims displays and warms their colors at night, so that apps
+// don't each have to.
+type NightMode struct {
+	// Brightness is the percentage colors are scaled by at night.
+	Brightness int
+
+	// Temperature is the color temperature, in kelvin, that white is
+	// shifted to at night. Zero leaves colors alone.
+	Temperature int
+
+	// Start and End are the times of day, as offsets from midnight, that
+	// night starts and ends. Night wraps past midnight if End is before
+	// Start.
+	Start, End time.Duration
+
+	// Sun makes night last from sunset to sunrise at Latitude and
+	// Longitude, instead of from Start to End.
+	Sun                 bool
+	Latitude, Longitude float64
+}
+
+// ParseNightSchedule parses when night is, either as a range of times of
+// day such as "22:00-07:00", or as a location such as "40.71,-74.01" for
+// night to last from sunset to sunrise there.
+func ParseNightSchedule(schedule string) (NightMode, error) {
+	var n NightMode
+
+	if start, end, ok := strings.Cut(schedule, "-"); ok && strings.Contains(schedule, ":") {
+		var err error
+		if n.Start, err = parseTimeOfDay(start); err != nil {
+			return n, err
+		}
+		if n.End, err = parseTimeOfDay(end); err != nil {
+			return n, err
+		}
+		return n, nil
+	}
+
+	lat, lng, ok := strings.Cut(schedule, ",")
+	if !ok {
+		return n, fmt.Errorf("night schedule %q is neither a range of times such as 22:00-07:00 nor a location such as 40.71,-74.01", schedule)
+	}
+	var err error
+	if n.Latitude, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil || math.Abs(n.Latitude) > 90 {
+		return n, fmt.Errorf("invalid latitude %q", lat)
+	}
+	if n.Longitude, err = strconv.ParseFloat(strings.TrimSpace(lng), 64); err != nil || math.Abs(n.Longitude) > 180 {
+		return n, fmt.Errorf("invalid longitude %q", lng)
+	}
+	n.Sun = true
+	return n, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsNight reports whether it's night at t. Times of day are in t's location.
+func (n NightMode) IsNight(t time.Time) bool {
+	if n.Sun {
+		// the solar day at the location, which may not be the day in UTC
+		day := t.UTC().Add(time.Duration(n.Longitude / 15 * float64(time.Hour)))
+		rise, set := sunrise.SunriseSunset(n.Latitude, n.Longitude, day.Year(), day.Month(), day.Day())
+		if rise.IsZero() || set.IsZero() {
+			// polar day or night; assume day rather than dimming for months
+			return false
+		}
+		return t.Before(rise) || t.After(set)
+	}
+
+	y, m, d := t.Date()
+	sinceMidnight := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+	if n.Start <= n.End {
+		return sinceMidnight >= n.Start && sinceMidnight < n.End
+	}
+	return sinceMidnight >= n.Start || sinceMidnight < n.End
+}
+
+// Filters returns the filters to apply to images shown at t, which are none
+// during the day.
+func (n NightMode) Filters(t time.Time) []ImageFilter {
+	if !n.IsNight(t) {
+		return nil
+	}
+
+	var filters []ImageFilter
+	if n.Temperature > 0 {
+		filters = append(filters, ColorTemperature(n.Temperature))
+	}
+	return append(filters, Brightness(n.Brightness))
+}
@@ -0,0 +1,78 @@
+package encode
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNightSchedule(t *testing.T) {
+	n, err := ParseNightSchedule("22:30-07:00")
+	require.NoError(t, err)
+	assert.Equal(t, NightMode{Start: 22*time.Hour + 30*time.Minute, End: 7 * time.Hour}, n)
+
+	n, err = ParseNightSchedule("-33.87,151.21")
+	require.NoError(t, err)
+	assert.Equal(t, NightMode{Sun: true, Latitude: -33.87, Longitude: 151.21}, n)
+
+	for _, bad := range []string{"", "tonight", "25:00-07:00", "91,0", "0,181"} {
+		_, err := ParseNightSchedule(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestIsNight(t *testing.T) {
+	oslo, err := time.LoadLocation("Europe/Oslo")
+	require.NoError(t, err)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 6, 21, hour, minute, 0, 0, oslo)
+	}
+
+	n := NightMode{Start: 22 * time.Hour, End: 7 * time.Hour}
+	assert.True(t, n.IsNight(at(23, 0)))
+	assert.True(t, n.IsNight(at(2, 0)))
+	assert.False(t, n.IsNight(at(7, 0)))
+	assert.False(t, n.IsNight(at(12, 0)))
+
+	n = NightMode{Start: 1 * time.Hour, End: 5 * time.Hour}
+	assert.True(t, n.IsNight(at(3, 0)))
+	assert.False(t, n.IsNight(at(23, 0)))
+
+	// New York, where the sun sets around 20:30 local time at midsummer,
+	// which is the next day in UTC
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	n = NightMode{Sun: true, Latitude: 40.71, Longitude: -74.01}
+	assert.False(t, n.IsNight(time.Date(2024, 6, 21, 12, 0, 0, 0, ny)))
+	assert.False(t, n.IsNight(time.Date(2024, 6, 21, 20, 0, 0, 0, ny)))
+	assert.True(t, n.IsNight(time.Date(2024, 6, 21, 21, 30, 0, 0, ny)))
+	assert.True(t, n.IsNight(time.Date(2024, 6, 22, 4, 0, 0, 0, ny)))
+}
+
+func TestNightModeFilters(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	n := NightMode{Start: 22 * time.Hour, End: 7 * time.Hour, Brightness: 50, Temperature: 2700}
+	assert.Empty(t, n.Filters(time.Date(2024, 6, 21, 12, 0, 0, 0, time.UTC)))
+
+	filters := n.Filters(time.Date(2024, 6, 21, 23, 0, 0, 0, time.UTC))
+	require.Len(t, filters, 2)
+
+	var out image.Image = img
+	for _, f := range filters {
+		var err error
+		out, err = f(out)
+		require.NoError(t, err)
+	}
+
+	c := out.At(0, 0).(color.RGBA)
+	assert.Equal(t, uint8(127), c.R)
+	assert.Less(t, c.B, c.G)
+	assert.Less(t, c.G, c.R)
+	assert.Equal(t, uint8(255), c.A)
+}
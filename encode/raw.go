@@ -0,0 +1,60 @@
+package encode
+
+import (
+	"encoding/binary"
+	"image/color"
+	"math"
+	"time"
+)
+
+// EncodeRaw renders a screen to raw frames, for displays that can't decode
+// images. Each frame is its delay in milliseconds, as a big-endian uint16,
+// followed by its pixels as 8-bit RGB triples, row by row. Optionally pass
+// filters for postprocessing each individual frame.
+func (s *Screens) EncodeRaw(maxDuration int, filters ...ImageFilter) ([]byte, error) {
+	frames, delays, err := s.Frames(maxDuration, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	for i, im := range frames {
+		delay := min(delays[i]/time.Millisecond, math.MaxUint16)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(delay))
+
+		bounds := im.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.RGBAModel.Convert(im.At(x, y)).(color.RGBA)
+				buf = append(buf, c.R, c.G, c.B)
+			}
+		}
+	}
+
+	return buf, nil
+}
+
+// EncodeRGB565 renders a screen to raw RGB565 frames, the framebuffer format
+// of many microcontroller displays. Each frame is its pixels as little-endian
+// 16-bit values, row by row, with no delay or header. Optionally pass filters
+// for postprocessing each individual frame.
+func (s *Screens) EncodeRGB565(maxDuration int, filters ...ImageFilter) ([]byte, error) {
+	frames, _, err := s.Frames(maxDuration, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	for _, im := range frames {
+		bounds := im.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.RGBAModel.Convert(im.At(x, y)).(color.RGBA)
+				v := uint16(c.R>>3)<<11 | uint16(c.G>>2)<<5 | uint16(c.B>>3)
+				buf = binary.LittleEndian.AppendUint16(buf, v)
+			}
+		}
+	}
+
+	return buf, nil
+}
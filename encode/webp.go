@@ -12,6 +12,25 @@ import (
 // Renders a screen to WebP. Optionally pass filters for
 // postprocessing each individual frame.
 func (s *Screens) EncodeWebP(maxDuration int, filters ...ImageFilter) ([]byte, error) {
+	config, err := webp.ConfigLosslessPreset(9)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", "configuring encoder", err)
+	}
+	return s.encodeWebP(config, maxDuration, filters...)
+}
+
+// EncodeLossyWebP renders a screen to a lossy WebP of the given quality,
+// from 0 to 100, which is smaller than the lossless WebP of EncodeWebP but
+// blurs the hard edges of pixel art.
+func (s *Screens) EncodeLossyWebP(quality float32, maxDuration int, filters ...ImageFilter) ([]byte, error) {
+	config, err := webp.ConfigPreset(webp.PresetDefault, quality)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", "configuring encoder", err)
+	}
+	return s.encodeWebP(config, maxDuration, filters...)
+}
+
+func (s *Screens) encodeWebP(config *webp.Config, maxDuration int, filters ...ImageFilter) ([]byte, error) {
 	images, err := s.render(filters...)
 	if err != nil {
 		return nil, err
@@ -34,12 +53,8 @@ func (s *Screens) EncodeWebP(maxDuration int, filters ...ImageFilter) ([]byte, e
 	defer anim.Close()
 
 	remainingDuration := time.Duration(maxDuration) * time.Millisecond
-	config, err := webp.ConfigLosslessPreset(9)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", "configuring encoder", err)
-	}
-	for _, im := range images {
-		frameDuration := time.Duration(s.delay) * time.Millisecond
+	for i, im := range images {
+		frameDuration := time.Duration(s.frameDelay(i)) * time.Millisecond
 
 		if maxDuration > 0 {
 			if frameDuration > remainingDuration {
@@ -8,3 +8,8 @@ func (s *Screens) EncodeWebP(maxDuration int, filters ...ImageFilter) ([]byte, e
 	// lol you gullible sucker, you thought you could use webp in wasm?
 	return s.EncodeGIF(maxDuration, filters...)
 }
+
+// Renders a screen to lossy WebP, or rather GIF, see EncodeWebP.
+func (s *Screens) EncodeLossyWebP(quality float32, maxDuration int, filters ...ImageFilter) ([]byte, error) {
+	return s.EncodeGIF(maxDuration, filters...)
+}
@@ -0,0 +1,167 @@
+package icons
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Icon is an image in an icon pack.
+type Icon struct {
+	// Pack and Name identify the icon. Its reference, which apps look it
+	// up by, is "<pack>/<name>".
+	Pack string
+	Name string
+
+	// Format is "png" or "svg", and Data the contents of the file.
+	Format string
+	Data   []byte
+}
+
+// Ref returns the reference apps look the icon up by.
+func (i *Icon) Ref() string {
+	return i.Pack + "/" + i.Name
+}
+
+// Pack is a named set of icons, such as a directory of PNGs and SVGs.
+type Pack struct {
+	Name  string
+	Icons map[string]*Icon
+}
+
+// Names returns the names of the pack's icons, sorted.
+func (p *Pack) Names() []string {
+	names := make([]string, 0, len(p.Icons))
+	for name := range p.Icons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadPack loads the PNGs and SVGs in fsys as an icon pack. Icons are
+// named by their path without its extension, so icons/weather/rain.png in
+// a pack named "acme" is "acme/weather/rain". Other files are ignored.
+func LoadPack(name string, fsys fs.FS) (*Pack, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid icon pack name %q", name)
+	}
+
+	p := &Pack{Name: name, Icons: map[string]*Icon{}}
+	err := fs.WalkDir(fsys, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		ext := path.Ext(file)
+		format := strings.ToLower(strings.TrimPrefix(ext, "."))
+		if format != "png" && format != "svg" {
+			return nil
+		}
+
+		iconName := strings.TrimSuffix(file, ext)
+		if other, ok := p.Icons[iconName]; ok {
+			return fmt.Errorf("icon %s is both a %s and a %s", iconName, other.Format, format)
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		p.Icons[iconName] = &Icon{Pack: name, Name: iconName, Format: format, Data: data}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading icon pack %s: %w", name, err)
+	}
+	if len(p.Icons) == 0 {
+		return nil, fmt.Errorf("icon pack %s has no PNG or SVG icons", name)
+	}
+	return p, nil
+}
+
+// OpenPack loads an icon pack from a directory or a zip file. The pack is
+// named after it, without the extension.
+func OpenPack(pathname string) (*Pack, error) {
+	info, err := os.Stat(pathname)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(pathname), filepath.Ext(pathname))
+
+	if info.IsDir() {
+		return LoadPack(name, os.DirFS(pathname))
+	}
+
+	if !strings.EqualFold(filepath.Ext(pathname), ".zip") {
+		return nil, fmt.Errorf("icon pack must be a directory or a .zip file: %s", pathname)
+	}
+	r, err := zip.OpenReader(pathname)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return LoadPack(name, r)
+}
+
+var (
+	packsMutex sync.RWMutex
+	packs      = map[string]*Pack{}
+)
+
+// Register makes an icon pack available to apps, replacing any pack
+// registered with the same name.
+func Register(p *Pack) {
+	packsMutex.Lock()
+	defer packsMutex.Unlock()
+	packs[p.Name] = p
+}
+
+// RegisterPaths opens and registers icon packs, see OpenPack.
+func RegisterPaths(paths ...string) error {
+	for _, pathname := range paths {
+		p, err := OpenPack(pathname)
+		if err != nil {
+			return err
+		}
+		Register(p)
+	}
+	return nil
+}
+
+// Packs returns the registered icon packs, sorted by name.
+func Packs() []*Pack {
+	packsMutex.RLock()
+	defer packsMutex.RUnlock()
+
+	list := make([]*Pack, 0, len(packs))
+	for _, p := range packs {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Lookup returns the icon with a reference, "<pack>/<name>", or nil if no
+// registered pack has it.
+func Lookup(ref string) *Icon {
+	pack, name, ok := strings.Cut(ref, "/")
+	if !ok {
+		return nil
+	}
+
+	packsMutex.RLock()
+	defer packsMutex.RUnlock()
+
+	p, ok := packs[pack]
+	if !ok {
+		return nil
+	}
+	return p.Icons[name]
+}
@@ -0,0 +1,79 @@
+package icons
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var svg = []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 8 8"><rect width="8" height="8" fill="#f00"/></svg>`)
+
+func TestLoadPack(t *testing.T) {
+	p, err := LoadPack("acme", fstest.MapFS{
+		"logo.svg":         {Data: svg},
+		"weather/rain.PNG": {Data: []byte("png")},
+		"README.md":        {Data: []byte("hello")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logo", "weather/rain"}, p.Names())
+	assert.Equal(t, "svg", p.Icons["logo"].Format)
+	assert.Equal(t, "png", p.Icons["weather/rain"].Format)
+	assert.Equal(t, "acme/weather/rain", p.Icons["weather/rain"].Ref())
+
+	_, err = LoadPack("acme", fstest.MapFS{"logo.svg": {Data: svg}, "logo.png": {Data: []byte("png")}})
+	assert.ErrorContains(t, err, "icon logo is both")
+
+	_, err = LoadPack("acme", fstest.MapFS{"README.md": {Data: []byte("hello")}})
+	assert.ErrorContains(t, err, "has no PNG or SVG icons")
+
+	_, err = LoadPack("a/b", fstest.MapFS{})
+	assert.Error(t, err)
+}
+
+func TestOpenPack(t *testing.T) {
+	dir := t.TempDir()
+
+	packDir := filepath.Join(dir, "lovelace")
+	require.NoError(t, os.Mkdir(packDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(packDir, "engine.svg"), svg, 0644))
+
+	f, err := os.Create(filepath.Join(dir, "babbage.zip"))
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("gears/small.svg")
+	require.NoError(t, err)
+	w.Write(svg)
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	require.NoError(t, RegisterPaths(packDir, filepath.Join(dir, "babbage.zip")))
+	t.Cleanup(func() {
+		packsMutex.Lock()
+		defer packsMutex.Unlock()
+		delete(packs, "lovelace")
+		delete(packs, "babbage")
+	})
+
+	var names []string
+	for _, p := range Packs() {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"babbage", "lovelace"}, names)
+
+	icon := Lookup("babbage/gears/small")
+	require.NotNil(t, icon)
+	assert.Equal(t, svg, icon.Data)
+	assert.NotNil(t, Lookup("lovelace/engine"))
+	assert.Nil(t, Lookup("lovelace/nope"))
+	assert.Nil(t, Lookup("nope/engine"))
+	assert.Nil(t, Lookup("engine"))
+
+	assert.Error(t, RegisterPaths(filepath.Join(dir, "missing")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "icons.txt"), nil, 0644))
+	assert.ErrorContains(t, RegisterPaths(filepath.Join(dir, "icons.txt")), "must be a directory or a .zip file")
+}
@@ -0,0 +1,119 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+
+	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/render"
+)
+
+// maxErrorLength is how much of an error is shown on an error frame, which
+// only fits a few short lines anyway.
+const maxErrorLength = 120
+
+var (
+	errorHeaderColor = color.RGBA{0xa0, 0x10, 0x10, 0xff}
+	errorTextColor   = color.RGBA{0xff, 0x90, 0x90, 0xff}
+	errorTimeColor   = color.RGBA{0x80, 0x80, 0x80, 0xff}
+)
+
+// RenderError renders a frame reporting that a render of the applet with ID
+// appID failed with err at time at: the applet's ID, the error, cut short,
+// and the time. Hosts can show it in place of the applet, rather than its
+// previous image or a blank screen. opts sets the size, format and filters
+// of the image, as for Render.
+func RenderError(appID string, err error, at time.Time, opts RenderOptions) (*EncodedImage, error) {
+	format := opts.Format
+	if format == "" {
+		format = FormatWebP
+	}
+	if format != FormatWebP && format != FormatGIF {
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	width, height := opts.Width, opts.Height
+	if width <= 0 {
+		width = render.DefaultFrameWidth
+	}
+	if height <= 0 {
+		height = render.DefaultFrameHeight
+	}
+
+	img := &EncodedImage{Format: format}
+	err = withFrameSize(width, height, func() error {
+		root, err := errorRoot(appID, err, at)
+		if err != nil {
+			return err
+		}
+
+		screens := encode.ScreensFromRoots([]render.Root{*root})
+		if format == FormatGIF {
+			img.Data, err = screens.EncodeGIF(0, opts.filters()...)
+		} else {
+			img.Data, err = screens.EncodeWebP(0, opts.filters()...)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rendering error frame: %w", err)
+	}
+	return img, nil
+}
+
+// errorRoot lays out an error frame: a header with the applet's ID, the
+// error below it, and the time at the bottom.
+func errorRoot(appID string, renderErr error, at time.Time) (*render.Root, error) {
+	header := &render.Text{Content: appID, Font: "tom-thumb", Color: color.White}
+	message := &render.WrappedText{
+		Content: errorMessage(renderErr),
+		Font:    "tom-thumb",
+		Width:   render.FrameWidth - 2,
+		Height:  render.FrameHeight - 14,
+		Color:   errorTextColor,
+	}
+	timestamp := &render.Text{Content: at.Format("15:04:05"), Font: "tom-thumb", Color: errorTimeColor}
+	for _, w := range []interface{ Init() error }{header, message, timestamp} {
+		if err := w.Init(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &render.Root{
+		Child: render.Column{
+			Expanded:  true,
+			MainAlign: "space_between",
+			Children: []render.Widget{
+				render.Box{
+					Height: 7,
+					Color:  errorHeaderColor,
+					Child:  render.Padding{Pad: render.Insets{Left: 1}, Child: header},
+				},
+				render.Padding{Pad: render.Insets{Left: 1}, Child: message},
+				render.Padding{Pad: render.Insets{Left: 1}, Child: timestamp},
+			},
+		},
+	}, nil
+}
+
+// errorMessage returns what's shown of err: the innermost error it wraps,
+// which says what went wrong without the layers of context around it, cut
+// short if it's long.
+func errorMessage(err error) string {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			break
+		}
+		err = inner
+	}
+
+	msg := strings.Join(strings.Fields(err.Error()), " ")
+	if len(msg) > maxErrorLength {
+		msg = msg[:maxErrorLength-3] + "..."
+	}
+	return msg
+}
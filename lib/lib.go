@@ -0,0 +1,785 @@
+// Package lib is the supported API for embedding pixlet in other Go
+// programs, such as the Tronbyt server.
+//
+// It deliberately exposes a small surface: load an applet, render it with a
+// config, and inspect its schema. Everything is configured through option
+// structs, so callers don't need to reach into pixlet's internal packages or
+// mutate package-level variables. Packages other than lib and schema may
+// change without notice.
+//
+// A typical embedding looks like:
+//
+//	app, err := lib.LoadAppletFromPath("apps/clock", lib.LoadOptions{})
+//	if err != nil {
+//		return err
+//	}
+//
+//	img, err := app.Render(ctx, map[string]string{"timezone": "Europe/Oslo"}, lib.RenderOptions{
+//		Timeout: 30 * time.Second,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//
+//	os.WriteFile("clock.webp", img.Data, 0644)
+//
+// Hosts can extend every render, to add watermarks or overlays or enforce
+// policies, by registering a Plugin.
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/globals"
+	"tidbyt.dev/pixlet/logging"
+	"tidbyt.dev/pixlet/manifest"
+	"tidbyt.dev/pixlet/render"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+	"tidbyt.dev/pixlet/tools"
+	"tidbyt.dev/pixlet/tracing"
+)
+
+// Format is an output image format.
+type Format string
+
+const (
+	FormatWebP Format = "webp"
+	FormatGIF  Format = "gif"
+)
+
+// Cache stores data for applets: both HTTP responses and values set with the
+// cache module.
+type Cache = runtime.Cache
+
+// SecretDecryptionKey decrypts secrets embedded in applets.
+type SecretDecryptionKey = runtime.SecretDecryptionKey
+
+// State is the durable state of one installation of an applet, which it
+// keeps with the state module. Create one with NewState.
+type State = runtime.AppState
+
+// NewState returns the state of an installation, starting with values saved
+// from a previous render. Save State.Values after rendering if it Changed.
+func NewState(values map[string]string) *State {
+	return runtime.NewAppState(values)
+}
+
+// RequestInfo is what the host knows about the render an applet is
+// running for, such as the device it's for and its user's locale, which
+// the applet reads with the request module.
+type RequestInfo = runtime.RequestInfo
+
+// Capabilities is what the host offers applets, which applets that declare
+// what they require with a REQUIRES constant or the capabilities in their
+// manifest are checked against. See runtime.RequiresConstantName.
+type Capabilities = runtime.Capabilities
+
+// CapabilityError is returned when the host lacks capabilities an applet
+// requires.
+type CapabilityError = runtime.CapabilityError
+
+// LoadOptions configure how an applet is loaded.
+type LoadOptions struct {
+	// Print receives the output of print() calls in the applet. When nil,
+	// output is written to stdout prefixed with the applet ID.
+	Print func(msg string)
+
+	// SilencePrint discards the output of print() calls.
+	SilencePrint bool
+
+	// SecretKey, if set, is used to decrypt the applet's secrets.
+	SecretKey *SecretDecryptionKey
+
+	// PoolSize is the number of instances of the applet to preload. It's
+	// the maximum number of renders of the applet that run concurrently;
+	// further renders wait for an instance to free up. Zero means 1.
+	PoolSize int
+
+	// Deterministic makes renders depend only on config and the responses
+	// to HTTP requests, so the same inputs give byte-identical output: the
+	// applet sees runtime.DeterministicTime() as the time, random numbers
+	// repeat, and animations are painted in full even if the render's
+	// deadline passes.
+	Deterministic bool
+
+	// Capabilities is what the host offers applets. Applets that require
+	// more fail to load with a *CapabilityError, and the display size
+	// they require is checked against the size of each render. Its Width
+	// and Height are ignored. Nil means only the display size is checked.
+	Capabilities *Capabilities
+}
+
+// RenderOptions configure a single render.
+type RenderOptions struct {
+	// Width and Height of the display in pixels. Zero means 64x32.
+	Width  int
+	Height int
+
+	// Magnify scales the output up by an integer factor. Zero means 1.
+	Magnify int
+
+	// Format of the output image. Empty means WebP.
+	Format Format
+
+	// MaxDuration caps the length of animations. Zero means no limit.
+	// Applets that request their full animation be shown ignore it.
+	MaxDuration time.Duration
+
+	// Timeout bounds how long the applet may run. Zero means no timeout
+	// beyond the deadline of the context passed to Render.
+	Timeout time.Duration
+
+	// Filters are applied to every frame, in order, before it's
+	// magnified, for example to dim it at night with encode.NightMode.
+	Filters []encode.ImageFilter
+
+	// State is the state of the installation being rendered. Nil means
+	// the applet shares a state with other renders of it in the process.
+	State *State
+
+	// Request is what's known about the render, for the applet to adapt
+	// to. Nil means nothing is.
+	Request *RequestInfo
+
+	// Interpolate inserts up to Interpolate-1 frames between frames where
+	// parts of the display move, such as scrolling text, so that they move
+	// more smoothly. Zero or one means frames are left as they were
+	// painted. See encode.Screens.Interpolate.
+	Interpolate int
+
+	// Inspect sets EncodedImage.Tree, for debugging layouts.
+	Inspect bool
+
+	// Diagnose sets EncodedImage.Diagnostics, for finding out why an
+	// applet is slow to render. It paints the frames a second time.
+	Diagnose bool
+
+	// RenderAt is the time the applet sees as now, for rendering ahead of
+	// when the image is shown. Zero means now. See runtime.WithRenderTime.
+	RenderAt time.Time
+}
+
+// context returns ctx with the timeout, state, request and render time of
+// opts.
+func (opts RenderOptions) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(
+			ctx,
+			opts.Timeout,
+			fmt.Errorf("timeout after %d ms", opts.Timeout.Milliseconds()),
+		)
+	}
+
+	if opts.State != nil {
+		ctx = runtime.WithAppState(ctx, opts.State)
+	}
+	if opts.Request != nil {
+		ctx = runtime.WithRequestInfo(ctx, opts.Request)
+	}
+	if !opts.RenderAt.IsZero() {
+		ctx = runtime.WithRenderTime(ctx, opts.RenderAt)
+	}
+	return ctx, cancel
+}
+
+// size returns the size to render at with opts.
+func (opts RenderOptions) size() (int, int) {
+	width, height := opts.Width, opts.Height
+	if width <= 0 {
+		width = render.DefaultFrameWidth
+	}
+	if height <= 0 {
+		height = render.DefaultFrameHeight
+	}
+	return width, height
+}
+
+// filters returns the filters to apply to frames rendered with opts.
+func (opts RenderOptions) filters() []encode.ImageFilter {
+	return append(slices.Clip(opts.Filters), encode.Magnify(opts.Magnify))
+}
+
+// EncodedImage is the output of a render.
+type EncodedImage struct {
+	// Data holds the encoded image. It's empty if the applet returned no
+	// frames, which signals that it has nothing to show right now.
+	Data   []byte
+	Format Format
+
+	// MaxAge is how long the image may be displayed before it's stale.
+	// Zero means forever.
+	MaxAge time.Duration
+
+	// ShowFullAnimation is set when the applet asks for its whole animation
+	// to be displayed, regardless of how long the device usually shows apps.
+	ShowFullAnimation bool
+
+	// ShowFor and RefreshIn are what the applet suggests for how long the
+	// image is shown and when it's rendered again. Zero means it doesn't
+	// say.
+	ShowFor   time.Duration
+	RefreshIn time.Duration
+
+	// Truncated is set when the render's deadline passed while frames were
+	// being painted. Data then holds only the frames painted in time.
+	Truncated bool
+
+	// Tree is where the widgets of each of the image's roots are laid out
+	// in their first frame, if RenderOptions.Inspect is set.
+	Tree []*render.Node
+
+	// Diagnostics is where the time painting and encoding the image went,
+	// if RenderOptions.Diagnose is set.
+	Diagnostics *Diagnostics
+}
+
+// Diagnostics is where the time painting and encoding an image went.
+type Diagnostics struct {
+	// FrameCount is how many frames the image has.
+	FrameCount int
+
+	// EncodeTime is how long painting the frames and encoding them took.
+	EncodeTime time.Duration
+
+	// Paint is how long painting each frame took, when they're painted
+	// one at a time, and which widgets the time went to.
+	Paint *render.Profile
+}
+
+// MarshalJSON encodes the diagnostics with times as strings such as
+// "1.5ms".
+func (d *Diagnostics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		FrameCount int             `json:"frameCount"`
+		EncodeTime string          `json:"encodeTime"`
+		Paint      *render.Profile `json:"paint"`
+	}{d.FrameCount, d.EncodeTime.String(), d.Paint})
+}
+
+// Applet is a loaded pixlet applet. It's safe to render an Applet from
+// multiple goroutines at once.
+type Applet struct {
+	pool          *runtime.AppletPool
+	app           *runtime.Applet
+	deterministic bool
+	capabilities  *Capabilities
+}
+
+// LoadApplet loads an applet from a filesystem containing one or more
+// Starlark files and their resources.
+func LoadApplet(id string, fsys fs.FS, opts LoadOptions) (*Applet, error) {
+	var appletOpts []runtime.AppletOption
+
+	if opts.SilencePrint {
+		appletOpts = append(appletOpts, runtime.WithPrintDisabled())
+	} else if opts.Print != nil {
+		appletOpts = append(appletOpts, runtime.WithPrintFunc(func(_ *starlark.Thread, msg string) {
+			opts.Print(msg)
+		}))
+	}
+
+	if opts.SecretKey != nil {
+		appletOpts = append(appletOpts, runtime.WithSecretDecryptionKey(opts.SecretKey))
+	}
+
+	if opts.Deterministic {
+		appletOpts = append(appletOpts, runtime.WithDeterministic())
+	}
+
+	_, span := tracing.Start(context.Background(), "applet.load", tracing.AppIDKey.String(id))
+	pool, err := runtime.NewAppletPoolFromFS(opts.PoolSize, id, fsys, appletOpts...)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applet: %w", err)
+	}
+
+	a := &Applet{pool: pool, app: pool.Applet(), deterministic: opts.Deterministic}
+	if opts.Capabilities != nil {
+		caps := *opts.Capabilities
+		caps.Width, caps.Height = 0, 0
+		if err := a.app.CheckCapabilities(caps); err != nil {
+			return nil, err
+		}
+		a.capabilities = &caps
+	}
+	return a, nil
+}
+
+// LoadAppletFromPath loads an applet from a .star file or a directory. The
+// applet ID is the base name of the path.
+func LoadAppletFromPath(path string, opts LoadOptions) (*Applet, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	var fsys fs.FS
+	if info.IsDir() {
+		fsys = os.DirFS(path)
+	} else {
+		if !strings.HasSuffix(path, ".star") {
+			return nil, fmt.Errorf("script file must have suffix .star: %s", path)
+		}
+
+		fsys = tools.NewSingleFileFS(path)
+	}
+
+	return LoadApplet(filepath.Base(path), fsys, opts)
+}
+
+// Requires returns the capabilities the applet declares it requires, such
+// as "network" or "128x64", in its REQUIRES constant or its manifest.
+func (a *Applet) Requires() []string {
+	return a.app.Requires()
+}
+
+// Manifest returns the applet's manifest, or nil if it doesn't have one.
+func (a *Applet) Manifest() *manifest.Manifest {
+	return a.app.Manifest()
+}
+
+// checkCapabilities returns a *CapabilityError if the applet requires more
+// than the host offers, or a bigger display than width by height.
+func (a *Applet) checkCapabilities(width, height int) error {
+	caps := Capabilities{Network: true, Secrets: true}
+	if a.capabilities != nil {
+		caps = *a.capabilities
+	}
+	caps.Width, caps.Height = width, height
+	return a.app.CheckCapabilities(caps)
+}
+
+// ID returns the ID the applet was loaded with.
+func (a *Applet) ID() string {
+	return a.app.ID
+}
+
+// Schema returns the applet's configuration schema, or nil if it has none.
+func (a *Applet) Schema() *schema.Schema {
+	return a.app.Schema
+}
+
+// SchemaJSON returns the applet's configuration schema serialized as JSON,
+// or nil if it has none.
+func (a *Applet) SchemaJSON() []byte {
+	return a.app.SchemaJSON
+}
+
+// LocalizedSchemaJSON returns the applet's configuration schema translated
+// to locale and serialized as JSON, or nil if it has none.
+func (a *Applet) LocalizedSchemaJSON(locale string) ([]byte, error) {
+	if a.app.Schema == nil {
+		return nil, nil
+	}
+
+	localized, err := a.app.Schema.Localize(locale)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(localized)
+}
+
+// JSONSchema returns the applet's configuration schema converted to a JSON
+// Schema describing its config, for driving generic config forms.
+func (a *Applet) JSONSchema() *schema.JSONSchema {
+	if a.app.Schema == nil {
+		return (&schema.Schema{}).JSONSchema()
+	}
+	return a.app.Schema.JSONSchema()
+}
+
+// DefaultConfig returns the config the applet gets when every field of its
+// schema is left at its default. Hosts can save it as the config of a new
+// install.
+func (a *Applet) DefaultConfig() map[string]string {
+	if a.app.Schema == nil {
+		return map[string]string{}
+	}
+	return a.app.Schema.DefaultConfig()
+}
+
+// MigrateConfig updates config saved with an older version of the applet's
+// schema, see runtime.Applet.MigrateConfig. Render migrates config on its
+// own, but hosts should call this to save the migrated config.
+func (a *Applet) MigrateConfig(ctx context.Context, config map[string]string) (map[string]string, error) {
+	return a.pool.MigrateConfig(ctx, config)
+}
+
+// PrepareUploads checks the files uploaded to the FileUpload fields in config
+// and scales the images among them down to the size opts render at, see
+// schema.PrepareUpload. Render doesn't, so hosts should call this once to
+// save the prepared config.
+func (a *Applet) PrepareUploads(config map[string]string, opts RenderOptions) (map[string]string, error) {
+	if a.app.Schema == nil {
+		return config, nil
+	}
+	width, height := opts.size()
+	return a.app.Schema.PrepareUploads(config, width, height)
+}
+
+// HasPrefetch reports whether the applet defines a prefetch function, see
+// Prefetch.
+func (a *Applet) HasPrefetch() bool {
+	return a.app.HasPrefetch()
+}
+
+// Prefetch calls the applet's prefetch function with config, if it has one,
+// with the timeout, state and request of opts. Hosts call it ahead of
+// rendering the applet with the same config, for example while the app
+// before it in a playlist is shown, so that the HTTP responses the applet
+// needs are cached by the time it's rendered and it doesn't wait on the
+// network.
+func (a *Applet) Prefetch(ctx context.Context, config map[string]string, opts RenderOptions) (err error) {
+	if !a.app.HasPrefetch() {
+		return nil
+	}
+
+	ctx, span := tracing.Start(ctx, "pixlet.prefetch", tracing.AppIDKey.String(a.app.ID))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+
+	width, height := opts.size()
+	if err := a.checkCapabilities(width, height); err != nil {
+		return err
+	}
+	return withFrameSize(width, height, func() error {
+		return a.pool.Prefetch(ctx, config)
+	})
+}
+
+// CallHandler calls one of the applet's schema handlers, such as a typeahead
+// search or a generated field.
+func (a *Applet) CallHandler(ctx context.Context, handler, parameter string) (string, error) {
+	return a.pool.CallSchemaHandler(ctx, handler, parameter)
+}
+
+// CallHandlerPage calls a paginated Typeahead handler for the page of options
+// at cursor, which is empty for the first page and the next_cursor of the
+// previous page after that.
+func (a *Applet) CallHandlerPage(ctx context.Context, handler, parameter, cursor string) (string, error) {
+	return a.pool.CallSchemaHandlerPage(ctx, handler, parameter, cursor)
+}
+
+// ResolveSchema returns the applet's schema with Generated fields replaced by
+// the fields they generate for config, see runtime.Applet.ResolveSchema.
+func (a *Applet) ResolveSchema(ctx context.Context, config map[string]string) (*schema.Schema, error) {
+	return a.pool.ResolveSchema(ctx, config)
+}
+
+// StartDeviceAuthorization requests a device code for one of the applet's
+// OAuth2 fields. Show the user code to the user, then call the field's
+// handler with the device code until it returns a token, see
+// schema.OAuth2Params.
+func (a *Applet) StartDeviceAuthorization(ctx context.Context, fieldID string) (*schema.DeviceAuthorization, error) {
+	if a.app.Schema == nil {
+		return nil, fmt.Errorf("applet has no schema")
+	}
+	field, ok := a.app.Schema.Field(fieldID)
+	if !ok {
+		return nil, fmt.Errorf("no field with ID %q", fieldID)
+	}
+	return schema.StartDeviceAuthorization(ctx, field)
+}
+
+// Render runs the applet with config and encodes the result.
+func (a *Applet) Render(ctx context.Context, config map[string]string, opts RenderOptions) (img *EncodedImage, err error) {
+	format := opts.Format
+	if format == "" {
+		format = FormatWebP
+	}
+	if format != FormatWebP && format != FormatGIF {
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	err = a.run(ctx, "pixlet.render", config, opts, func(ctx context.Context, screens *encode.Screens, maxDuration int) error {
+		var err error
+		img, err = a.encode(ctx, screens, format, maxDuration, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// RenderScreens runs the applet with config like Render does, but encodes
+// each separate screen the applet returns as its own image, for hosts that
+// show them in turns; see render.Root's Separate. There's always at least
+// one image, which is empty if the applet has nothing to show.
+func (a *Applet) RenderScreens(ctx context.Context, config map[string]string, opts RenderOptions) (imgs []*EncodedImage, err error) {
+	format := opts.Format
+	if format == "" {
+		format = FormatWebP
+	}
+	if format != FormatWebP && format != FormatGIF {
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	err = a.run(ctx, "pixlet.render_screens", config, opts, func(ctx context.Context, all *encode.Screens, _ int) error {
+		for _, screens := range all.Split() {
+			maxDuration := int(opts.MaxDuration.Milliseconds())
+			if screens.ShowFullAnimation {
+				maxDuration = 0
+			}
+
+			img, err := a.encode(ctx, screens, format, maxDuration, opts)
+			if err != nil {
+				return err
+			}
+			imgs = append(imgs, img)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return imgs, nil
+}
+
+// encode encodes screens as an image in format.
+func (a *Applet) encode(ctx context.Context, screens *encode.Screens, format Format, maxDuration int, opts RenderOptions) (*EncodedImage, error) {
+	img := &EncodedImage{
+		Format:            format,
+		MaxAge:            time.Duration(screens.MaxAge) * time.Second,
+		ShowFullAnimation: screens.ShowFullAnimation,
+		ShowFor:           time.Duration(screens.ShowFor) * time.Second,
+		RefreshIn:         time.Duration(screens.RefreshIn) * time.Second,
+	}
+
+	if screens.Empty() {
+		return img, nil
+	}
+	if opts.Inspect {
+		img.Tree = screens.Inspect(0)
+	}
+
+	var err error
+	start := time.Now()
+	_, encodeSpan := tracing.Start(ctx, "encode", tracing.FormatKey.String(string(format)))
+	if format == FormatGIF {
+		img.Data, err = screens.EncodeGIF(maxDuration, opts.filters()...)
+	} else {
+		img.Data, err = screens.EncodeWebP(maxDuration, opts.filters()...)
+	}
+	encodeSpan.SetAttributes(tracing.BytesKey.Int(len(img.Data)))
+	tracing.End(encodeSpan, err)
+	runtime.UsageMeterFromContext(ctx).AddEncodeTime(time.Since(start))
+	if err != nil {
+		return nil, fmt.Errorf("error rendering: %w", err)
+	}
+
+	if opts.Diagnose {
+		img.Diagnostics = &Diagnostics{EncodeTime: time.Since(start), Paint: screens.Profile()}
+		img.Diagnostics.FrameCount = len(img.Diagnostics.Paint.Frames)
+	}
+
+	img.Truncated = screens.Truncated
+	if img.Truncated {
+		logging.ForApp(a.app.ID).Warn("render timed out, animation truncated", "error", context.Cause(ctx))
+	}
+
+	if err := outputWithPlugins(ctx, img); err != nil {
+		return nil, err
+	}
+
+	runtime.UsageMeterFromContext(ctx).AddOutputBytes(len(img.Data))
+	return img, nil
+}
+
+// Frames are the unencoded output of a render, for displays that are driven
+// frame by frame rather than sent an image.
+type Frames struct {
+	// Images holds the frames, and Delays how long each is shown. They're
+	// empty if the applet has nothing to show right now.
+	Images []image.Image
+	Delays []time.Duration
+
+	// MaxAge, ShowFullAnimation, ShowFor and RefreshIn are as for
+	// EncodedImage.
+	MaxAge            time.Duration
+	ShowFullAnimation bool
+	ShowFor           time.Duration
+	RefreshIn         time.Duration
+}
+
+// RenderFrames runs the applet with config like Render does, but returns the
+// painted frames instead of encoding them. opts.Format is ignored.
+func (a *Applet) RenderFrames(ctx context.Context, config map[string]string, opts RenderOptions) (frames *Frames, err error) {
+	err = a.run(ctx, "pixlet.render_frames", config, opts, func(ctx context.Context, screens *encode.Screens, maxDuration int) error {
+		frames = &Frames{
+			MaxAge:            time.Duration(screens.MaxAge) * time.Second,
+			ShowFullAnimation: screens.ShowFullAnimation,
+			ShowFor:           time.Duration(screens.ShowFor) * time.Second,
+			RefreshIn:         time.Duration(screens.RefreshIn) * time.Second,
+		}
+		if screens.Empty() {
+			return nil
+		}
+
+		var err error
+		start := time.Now()
+		frames.Images, frames.Delays, err = screens.Frames(maxDuration, opts.filters()...)
+		runtime.UsageMeterFromContext(ctx).AddEncodeTime(time.Since(start))
+		if err != nil {
+			return fmt.Errorf("error rendering: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return frames, nil
+}
+
+// run runs the applet with config at the size given in opts, and passes the
+// resulting screens to fn along with the animation length to cap them at.
+func (a *Applet) run(
+	ctx context.Context,
+	spanName string,
+	config map[string]string,
+	opts RenderOptions,
+	fn func(ctx context.Context, screens *encode.Screens, maxDuration int) error,
+) (err error) {
+	ctx, span := tracing.Start(ctx, spanName, tracing.AppIDKey.String(a.app.ID))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+
+	ctx, meter := runtime.MeterUsage(ctx)
+	defer func() { runtime.DefaultUsageReport.Record(a.app.ID, meter.Stats(), err) }()
+
+	width, height := opts.size()
+	if err := a.checkCapabilities(width, height); err != nil {
+		return err
+	}
+
+	info := &PluginInfo{AppID: a.app.ID, Width: width, Height: height, Request: opts.Request}
+	return withFrameSize(width, height, func() error {
+		ctx, roots, err := runWithPlugins(ctx, info, config, func(ctx context.Context, config map[string]string) ([]render.Root, error) {
+			roots, err := a.pool.RunWithConfig(ctx, config)
+			if err != nil {
+				return nil, fmt.Errorf("error running script: %w", err)
+			}
+			return roots, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		screens := encode.ScreensFromRoots(roots).Interpolate(opts.Interpolate)
+		if !a.deterministic {
+			// stop painting frames once the deadline passes
+			screens = screens.WithContext(ctx)
+		}
+
+		maxDuration := int(opts.MaxDuration.Milliseconds())
+		if screens.ShowFullAnimation {
+			maxDuration = 0
+		}
+
+		return fn(ctx, screens, maxDuration)
+	})
+}
+
+// SetCache sets the cache used by all applets in the process. Without a
+// cache, HTTP responses aren't cached and the cache module stores nothing.
+func SetCache(c Cache) {
+	runtime.InitHTTP(c)
+	runtime.InitCache(c)
+}
+
+// OpenCache opens a cache by URL, such as "memory://" or
+// "redis://localhost:6379". See runtime.CacheSchemes for the backends that
+// are available.
+func OpenCache(url string) (Cache, error) {
+	return runtime.OpenCache(url)
+}
+
+// The frame size is still process-wide state inside the renderer, so renders
+// sharing a size run concurrently, while renders of another size wait for
+// them to finish. Renders wait their turn in the order they arrive, so that
+// a steady stream of renders of one size doesn't starve the others.
+var frameSize struct {
+	sync.Mutex
+	cond    sync.Cond
+	running int
+	waiting []*frameSizeWaiter
+}
+
+func init() {
+	frameSize.cond.L = &frameSize.Mutex
+}
+
+// frameSizeWaiter is a render waiting for its turn to use a frame size.
+type frameSizeWaiter struct {
+	width, height int
+}
+
+func withFrameSize(width, height int, fn func() error) error {
+	w := &frameSizeWaiter{width, height}
+
+	frameSize.Lock()
+	frameSize.waiting = append(frameSize.waiting, w)
+	for !frameSizeReady(w) {
+		frameSize.cond.Wait()
+	}
+	frameSize.waiting = slices.DeleteFunc(frameSize.waiting, func(o *frameSizeWaiter) bool { return o == w })
+	if frameSize.running == 0 {
+		globals.Width, globals.Height = width, height
+		render.FrameWidth, render.FrameHeight = width, height
+	}
+	frameSize.running++
+	if len(frameSize.waiting) > 0 {
+		// renders of the same size queued behind this one can start too
+		frameSize.cond.Broadcast()
+	}
+	frameSize.Unlock()
+
+	defer func() {
+		frameSize.Lock()
+		frameSize.running--
+		if frameSize.running == 0 {
+			frameSize.cond.Broadcast()
+		}
+		frameSize.Unlock()
+	}()
+	return fn()
+}
+
+// frameSizeReady reports whether w can start, which it can if no render of
+// another size is waiting ahead of it, and renders running, if there are any,
+// are of its size. frameSize must be locked.
+func frameSizeReady(w *frameSizeWaiter) bool {
+	for _, o := range frameSize.waiting {
+		if o == w {
+			break
+		}
+		if o.width != w.width || o.height != w.height {
+			return false
+		}
+	}
+	return frameSize.running == 0 ||
+		(globals.Width == w.width && globals.Height == w.height &&
+			render.FrameWidth == w.width && render.FrameHeight == w.height)
+}
@@ -0,0 +1,531 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/gif"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"tidbyt.dev/pixlet/globals"
+	"tidbyt.dev/pixlet/render"
+	"tidbyt.dev/pixlet/tracing"
+)
+
+const testApp = `
+load("render.star", "render")
+load("schema.star", "schema")
+
+def main(config):
+    print("hello " + config.str("who", "world"))
+    return render.Root(
+        max_age = 120,
+        child = render.Box(color = config.str("color", "#f00")),
+    )
+
+def get_schema():
+    return schema.Schema(
+        version = "1",
+        fields = [
+            schema.Text(id = "who", name = "Who", desc = "Who to greet", icon = "user"),
+        ],
+    )
+`
+
+func loadTestApp(t *testing.T, src string, opts LoadOptions) *Applet {
+	app, err := LoadApplet("test_app", fstest.MapFS{
+		"test_app.star": {Data: []byte(src)},
+	}, opts)
+	require.NoError(t, err)
+	return app
+}
+
+func TestRenderWebP(t *testing.T) {
+	var printed []string
+	app := loadTestApp(t, testApp, LoadOptions{
+		Print: func(msg string) { printed = append(printed, msg) },
+	})
+	assert.Equal(t, "test_app", app.ID())
+
+	img, err := app.Render(context.Background(), map[string]string{"who": "pixlet"}, RenderOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, FormatWebP, img.Format)
+	assert.Equal(t, "RIFF", string(img.Data[:4]))
+	assert.Equal(t, 120*time.Second, img.MaxAge)
+	assert.Equal(t, []string{"hello pixlet"}, printed)
+}
+
+func TestRenderGIFWithSize(t *testing.T) {
+	app := loadTestApp(t, testApp, LoadOptions{SilencePrint: true})
+
+	img, err := app.Render(context.Background(), nil, RenderOptions{
+		Width:   128,
+		Height:  64,
+		Magnify: 2,
+		Format:  FormatGIF,
+	})
+	require.NoError(t, err)
+
+	g, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	require.NoError(t, err)
+	assert.Equal(t, 256, g.Config.Width)
+	assert.Equal(t, 128, g.Config.Height)
+
+	// rendering at the default size again must not keep the previous size
+	img, err = app.Render(context.Background(), nil, RenderOptions{Format: FormatGIF})
+	require.NoError(t, err)
+	g, err = gif.DecodeAll(bytes.NewReader(img.Data))
+	require.NoError(t, err)
+	assert.Equal(t, 64, g.Config.Width)
+	assert.Equal(t, 32, g.Config.Height)
+}
+
+func TestRenderEmpty(t *testing.T) {
+	app := loadTestApp(t, `
+def main():
+    return []
+`, LoadOptions{})
+
+	img, err := app.Render(context.Background(), nil, RenderOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, img.Data)
+}
+
+func TestRenderScreens(t *testing.T) {
+	app := loadTestApp(t, `
+load("render.star", "render")
+
+def main():
+    return [
+        render.Root(child = render.Box(color = "#f00"), show_for = 5),
+        render.Root(child = render.Box(color = "#0f0")),
+        render.Root(child = render.Box(color = "#00f"), separate = True, refresh_in = 60),
+    ]
+`, LoadOptions{})
+
+	imgs, err := app.RenderScreens(context.Background(), nil, RenderOptions{Format: FormatGIF})
+	require.NoError(t, err)
+	require.Len(t, imgs, 2)
+
+	first, err := gif.DecodeAll(bytes.NewReader(imgs[0].Data))
+	require.NoError(t, err)
+	assert.Len(t, first.Image, 2)
+	assert.Equal(t, 5*time.Second, imgs[0].ShowFor)
+
+	second, err := gif.DecodeAll(bytes.NewReader(imgs[1].Data))
+	require.NoError(t, err)
+	assert.Len(t, second.Image, 1)
+	assert.Equal(t, time.Minute, imgs[1].RefreshIn)
+
+	// hosts that don't show screens separately show them one after another
+	img, err := app.Render(context.Background(), nil, RenderOptions{Format: FormatGIF})
+	require.NoError(t, err)
+	all, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	require.NoError(t, err)
+	assert.Len(t, all.Image, 3)
+
+	// apps with nothing to show render one empty screen
+	app = loadTestApp(t, "def main():\n    return []\n", LoadOptions{})
+	imgs, err = app.RenderScreens(context.Background(), nil, RenderOptions{})
+	require.NoError(t, err)
+	require.Len(t, imgs, 1)
+	assert.Empty(t, imgs[0].Data)
+}
+
+func TestRenderTimeout(t *testing.T) {
+	app := loadTestApp(t, `
+def main():
+    for i in range(1000000000):
+        pass
+    return []
+`, LoadOptions{})
+
+	_, err := app.Render(context.Background(), nil, RenderOptions{Timeout: 10 * time.Millisecond})
+	assert.ErrorContains(t, err, "timeout after 10 ms")
+}
+
+func TestRenderDeterministic(t *testing.T) {
+	src := `
+load("random.star", "random")
+load("render.star", "render")
+load("time.star", "time")
+
+def main():
+    return render.Root(
+        child = render.Column(children = [
+            render.Text(time.now().format("15:04:05.000")),
+            render.Text(str(random.number(0, 1000000))),
+        ]),
+    )
+`
+	render := func(opts LoadOptions) []byte {
+		img, err := loadTestApp(t, src, opts).Render(context.Background(), nil, RenderOptions{})
+		require.NoError(t, err)
+		return img.Data
+	}
+
+	first := render(LoadOptions{Deterministic: true})
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, first, render(LoadOptions{Deterministic: true}))
+	assert.NotEqual(t, first, render(LoadOptions{}))
+}
+
+func TestRenderAt(t *testing.T) {
+	var printed []string
+	app := loadTestApp(t, `
+load("render.star", "render")
+load("time.star", "time")
+
+def main():
+    print(time.now().in_location("UTC").format("2006-01-02 15:04"))
+    return render.Root(child = render.Box())
+`, LoadOptions{Print: func(msg string) { printed = append(printed, msg) }})
+
+	at := time.Date(2030, time.June, 1, 9, 41, 0, 0, time.UTC)
+	_, err := app.Render(context.Background(), nil, RenderOptions{RenderAt: at})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2030-06-01 09:41"}, printed)
+
+	// and it's only for that render
+	_, err = app.Render(context.Background(), nil, RenderOptions{})
+	require.NoError(t, err)
+	assert.NotEqual(t, printed[0], printed[1])
+}
+
+func TestDiagnose(t *testing.T) {
+	app := loadTestApp(t, `
+load("render.star", "render")
+
+def main():
+    return render.Root(child = render.Column(children = [
+        render.Marquee(width = 64, child = render.Text("Grace Hopper found the first actual bug")),
+        render.Box(width = 1, height = 1),
+    ]))
+`, LoadOptions{})
+
+	img, err := app.Render(context.Background(), nil, RenderOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, img.Diagnostics)
+
+	img, err = app.Render(context.Background(), nil, RenderOptions{Diagnose: true})
+	require.NoError(t, err)
+	require.NotNil(t, img.Diagnostics)
+	assert.Greater(t, img.Diagnostics.FrameCount, 1)
+	assert.Len(t, img.Diagnostics.Paint.Frames, img.Diagnostics.FrameCount)
+	assert.Equal(t, "Column", img.Diagnostics.Paint.Roots[0].Children[0].Type)
+
+	buf, err := json.Marshal(img.Diagnostics)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), `"encodeTime":"`)
+}
+
+func TestRenderBadFormat(t *testing.T) {
+	app := loadTestApp(t, testApp, LoadOptions{SilencePrint: true})
+
+	_, err := app.Render(context.Background(), nil, RenderOptions{Format: "bmp"})
+	assert.Error(t, err)
+}
+
+func TestSchema(t *testing.T) {
+	app := loadTestApp(t, testApp, LoadOptions{})
+
+	require.NotNil(t, app.Schema())
+	assert.Equal(t, "who", app.Schema().Fields[0].ID)
+	assert.Contains(t, string(app.SchemaJSON()), `"id":"who"`)
+
+	_, err := app.CallHandler(context.Background(), "nope", "")
+	assert.Error(t, err)
+}
+
+func TestLoadAppletFromPath(t *testing.T) {
+	_, err := LoadAppletFromPath("testdata/does_not_exist.star", LoadOptions{})
+	assert.Error(t, err)
+
+	app, err := LoadAppletFromPath("../examples/hello_world/hello_world.star", LoadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "hello_world.star", app.ID())
+}
+
+func TestLoadAppletTraced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(tp)
+	defer tp.Shutdown(context.Background())
+
+	app := loadTestApp(t, testApp, LoadOptions{})
+	_, err := app.Render(context.Background(), nil, RenderOptions{})
+	require.NoError(t, err)
+
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		names = append(names, span.Name)
+		if span.Name == "applet.load" {
+			assert.Contains(t, span.Attributes, tracing.AppIDKey.String(app.ID()))
+		}
+	}
+	assert.Contains(t, names, "applet.load")
+	assert.Contains(t, names, "pixlet.render")
+}
+
+func TestCapabilities(t *testing.T) {
+	src := `
+load("render.star", "render")
+
+REQUIRES = ["network", "128x64"]
+
+def main():
+    return render.Root(child = render.Box())
+`
+	fsys := fstest.MapFS{"test_app.star": {Data: []byte(src)}}
+
+	// hosts that don't say what they offer only have the size checked
+	app := loadTestApp(t, src, LoadOptions{})
+	assert.Equal(t, []string{"network", "128x64"}, app.Requires())
+	_, err := app.Render(context.Background(), nil, RenderOptions{Width: 128, Height: 64})
+	require.NoError(t, err)
+
+	_, err = app.Render(context.Background(), nil, RenderOptions{})
+	var capErr *CapabilityError
+	require.ErrorAs(t, err, &capErr)
+	assert.Equal(t, []string{"128x64"}, capErr.Missing)
+	assert.EqualError(t, err, "test_app requires 128x64, which this host doesn't offer")
+
+	// hosts that do refuse to load apps that need more
+	_, err = LoadApplet("test_app", fsys, LoadOptions{Capabilities: &Capabilities{Secrets: true}})
+	require.ErrorAs(t, err, &capErr)
+	assert.Equal(t, []string{"network"}, capErr.Missing)
+
+	_, err = LoadApplet("test_app", fsys, LoadOptions{Capabilities: &Capabilities{Network: true}})
+	require.NoError(t, err)
+
+	// and apps can only require capabilities that exist
+	_, err = LoadApplet("test_app", fstest.MapFS{"test_app.star": {Data: []byte(`
+REQUIRES = ["teleportation"]
+
+def main():
+    return []
+`)}}, LoadOptions{})
+	assert.ErrorContains(t, err, `REQUIRES: unknown capability "teleportation"`)
+}
+
+func TestManifestCapabilities(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Lovelace"))
+	}))
+	defer ts.Close()
+
+	src := `
+load("http.star", "http")
+load("render.star", "render")
+
+def main(config):
+    return render.Root(child = render.Text(http.get(config.get("url")).body()))
+`
+	fsys := fstest.MapFS{
+		"test_app.star": {Data: []byte(src)},
+		"manifest.yaml": {Data: []byte(`
+id: test-app
+name: Test App
+version: 1.2.0
+capabilities:
+  network:
+    - 127.0.0.1
+  min_display_size:
+    width: 128
+    height: 64
+`)},
+	}
+
+	app, err := LoadApplet("test_app", fsys, LoadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", app.Manifest().Version)
+	assert.Equal(t, []string{"network", "128x64"}, app.Requires())
+
+	// the minimum display size is a requirement like any other
+	config := map[string]string{"url": ts.URL}
+	_, err = app.Render(context.Background(), config, RenderOptions{})
+	var capErr *CapabilityError
+	require.ErrorAs(t, err, &capErr)
+	assert.Equal(t, []string{"128x64"}, capErr.Missing)
+
+	_, err = app.Render(context.Background(), config, RenderOptions{Width: 128, Height: 64})
+	require.NoError(t, err)
+
+	// and requests can only go to the hosts it declares
+	config["url"] = strings.Replace(ts.URL, "127.0.0.1", "localhost", 1)
+	_, err = app.Render(context.Background(), config, RenderOptions{Width: 128, Height: 64})
+	assert.ErrorContains(t, err, "isn't one of the network hosts the app declares")
+
+	// apps that don't list any hosts aren't held to them
+	fsys["manifest.yaml"] = &fstest.MapFile{Data: []byte(`
+id: test-app
+name: Test App
+capabilities:
+  min_display_size:
+    width: 128
+    height: 64
+`)}
+	app, err = LoadApplet("test_app", fsys, LoadOptions{})
+	require.NoError(t, err)
+	_, err = app.Render(context.Background(), config, RenderOptions{Width: 128, Height: 64})
+	assert.NoError(t, err)
+}
+
+func TestWithFrameSizeDoesNotStarve(t *testing.T) {
+	// renders of one size keep overlapping, so there's never a moment
+	// without one running
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				withFrameSize(64, 32, func() error {
+					if render.FrameWidth != 64 || globals.Width != 64 {
+						t.Error("rendering at the wrong size")
+					}
+					time.Sleep(time.Millisecond)
+					return nil
+				})
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		withFrameSize(128, 64, func() error {
+			assert.Equal(t, 128, render.FrameWidth)
+			assert.Equal(t, 64, globals.Height)
+			return nil
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a render of another size never got its turn")
+	}
+}
+
+func TestRenderConcurrently(t *testing.T) {
+	app := loadTestApp(t, testApp, LoadOptions{SilencePrint: true, PoolSize: 3})
+
+	errs := make(chan error, 10)
+	for range 10 {
+		go func() {
+			_, err := app.Render(context.Background(), nil, RenderOptions{})
+			errs <- err
+		}()
+	}
+	for range 10 {
+		assert.NoError(t, <-errs)
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	app := loadTestApp(t, `
+def main():
+    fail("the engine is out of punched cards")
+`, LoadOptions{})
+
+	_, renderErr := app.Render(context.Background(), nil, RenderOptions{})
+	require.Error(t, renderErr)
+
+	at := time.Date(2026, 10, 17, 9, 41, 0, 0, time.UTC)
+	img, err := RenderError("test_app", renderErr, at, RenderOptions{Format: FormatGIF, Width: 128, Height: 64})
+	require.NoError(t, err)
+
+	g, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	require.NoError(t, err)
+	assert.Equal(t, 128, g.Config.Width)
+	assert.Len(t, g.Image, 1)
+
+	// the header is drawn across the top
+	assert.Equal(t, errorHeaderColor, color.RGBAModel.Convert(g.Image[0].At(127, 0)))
+
+	// only the innermost error is shown, cut short
+	assert.Equal(t, "boom", errorMessage(fmt.Errorf("error running script: %w", errors.New("boom"))))
+	assert.Len(t, errorMessage(errors.New(strings.Repeat("a", 500))), maxErrorLength)
+}
+
+func TestPlugins(t *testing.T) {
+	t.Cleanup(func() { plugins = nil })
+
+	var calls []string
+	RegisterPlugin(Plugin{
+		Name: "policy",
+		Run: func(ctx context.Context, info *PluginInfo, config map[string]string, next RunFunc) ([]render.Root, error) {
+			calls = append(calls, "policy "+info.AppID)
+			if config["who"] == "mallory" {
+				return nil, fmt.Errorf("not allowed")
+			}
+			return next(ctx, map[string]string{"who": config["who"], "color": "#00f"})
+		},
+	})
+	RegisterPlugin(Plugin{
+		Name: "watermark",
+		Run: func(ctx context.Context, info *PluginInfo, config map[string]string, next RunFunc) ([]render.Root, error) {
+			calls = append(calls, "watermark "+config["color"])
+			return next(ctx, config)
+		},
+		Roots: func(ctx context.Context, info *PluginInfo, roots []render.Root) ([]render.Root, error) {
+			for i := range roots {
+				roots[i].Child = render.Stack{Children: []render.Widget{
+					roots[i].Child,
+					render.Box{Width: 1, Height: 1, Color: color.White},
+				}}
+			}
+			return roots, nil
+		},
+		Output: func(ctx context.Context, info *PluginInfo, img *EncodedImage) error {
+			calls = append(calls, fmt.Sprintf("output %dx%d", info.Width, info.Height))
+			img.MaxAge = time.Minute
+			return nil
+		},
+	})
+
+	app := loadTestApp(t, testApp, LoadOptions{SilencePrint: true})
+
+	// the first plugin registered runs first, and sees the config first
+	img, err := app.Render(context.Background(), map[string]string{"who": "ada"}, RenderOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"policy test_app", "watermark #00f", "output 64x32"}, calls)
+	assert.Equal(t, time.Minute, img.MaxAge)
+
+	// roots are changed before they're painted
+	frames, err := app.RenderFrames(context.Background(), nil, RenderOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{0xff, 0xff, 0xff, 0xff}, frames.Images[0].At(0, 0))
+	assert.Equal(t, color.RGBA{0, 0, 0xff, 0xff}, frames.Images[0].At(1, 0))
+
+	_, err = app.Render(context.Background(), map[string]string{"who": "mallory"}, RenderOptions{})
+	assert.ErrorContains(t, err, "not allowed")
+}
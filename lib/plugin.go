@@ -0,0 +1,131 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"tidbyt.dev/pixlet/render"
+)
+
+// Plugin extends every render made through lib, for hosts that add to what
+// applets show or check it, such as with watermarks, overlays or content
+// policies, without changing pixlet. Each of its hooks is optional.
+//
+// Register plugins with RegisterPlugin before rendering. Plugins run in the
+// order they're registered: the first wraps the others' Run, and its Roots
+// and Output see the applet's output first.
+type Plugin struct {
+	// Name identifies the plugin in errors.
+	Name string
+
+	// Run wraps running the applet. It's passed the next step, which runs
+	// the applet with a config and returns the roots it rendered. It can
+	// change the config, check it before running the applet, replace the
+	// roots, or not run the applet at all. Errors it returns fail the
+	// render as they are.
+	Run func(ctx context.Context, info *PluginInfo, config map[string]string, next RunFunc) ([]render.Root, error)
+
+	// Roots changes the widget trees the applet rendered before they're
+	// painted, for example to draw an overlay on top of them.
+	Roots func(ctx context.Context, info *PluginInfo, roots []render.Root) ([]render.Root, error)
+
+	// Output post-processes each encoded image, changing it in place. It's
+	// only called for images with data, and not for RenderFrames.
+	Output func(ctx context.Context, info *PluginInfo, img *EncodedImage) error
+}
+
+// RunFunc runs an applet with config, and returns the roots it rendered.
+type RunFunc func(ctx context.Context, config map[string]string) ([]render.Root, error)
+
+// PluginInfo describes the render plugins are called for.
+type PluginInfo struct {
+	// AppID is the ID of the applet being rendered.
+	AppID string
+
+	// Width and Height are the size being rendered at.
+	Width  int
+	Height int
+
+	// Request is what the host said about the render, if anything.
+	Request *RequestInfo
+}
+
+var (
+	pluginsMutex sync.RWMutex
+	plugins      []Plugin
+)
+
+// RegisterPlugin adds a plugin to every render from now on.
+func RegisterPlugin(p Plugin) {
+	pluginsMutex.Lock()
+	defer pluginsMutex.Unlock()
+	plugins = append(plugins, p)
+}
+
+func registeredPlugins() []Plugin {
+	pluginsMutex.RLock()
+	defer pluginsMutex.RUnlock()
+	return slices.Clip(plugins)
+}
+
+// pluginRun is the plugins of a render in progress, which is attached to
+// its context so that they see its encoded images.
+type pluginRun struct {
+	plugins []Plugin
+	info    *PluginInfo
+}
+
+type pluginRunKey struct{}
+
+// runWithPlugins runs the applet through the plugins' Run hooks, and then
+// passes what it rendered through their Roots hooks. It returns ctx with
+// the plugins attached for outputWithPlugins.
+func runWithPlugins(ctx context.Context, info *PluginInfo, config map[string]string, run RunFunc) (context.Context, []render.Root, error) {
+	ps := registeredPlugins()
+	ctx = context.WithValue(ctx, pluginRunKey{}, &pluginRun{plugins: ps, info: info})
+
+	for i := len(ps) - 1; i >= 0; i-- {
+		p, next := ps[i], run
+		if p.Run == nil {
+			continue
+		}
+		run = func(ctx context.Context, config map[string]string) ([]render.Root, error) {
+			return p.Run(ctx, info, config, next)
+		}
+	}
+
+	roots, err := run(ctx, config)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	for _, p := range ps {
+		if p.Roots == nil {
+			continue
+		}
+		if roots, err = p.Roots(ctx, info, roots); err != nil {
+			return ctx, nil, fmt.Errorf("plugin %s: %w", p.Name, err)
+		}
+	}
+	return ctx, roots, nil
+}
+
+// outputWithPlugins passes an encoded image through the Output hooks of
+// the plugins attached to ctx.
+func outputWithPlugins(ctx context.Context, img *EncodedImage) error {
+	r, ok := ctx.Value(pluginRunKey{}).(*pluginRun)
+	if !ok || len(img.Data) == 0 {
+		return nil
+	}
+	for _, p := range r.plugins {
+		if p.Output == nil {
+			continue
+		}
+		if err := p.Output(ctx, r.info, img); err != nil {
+			return fmt.Errorf("plugin %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
@@ -13,6 +13,12 @@ import (
 	"unsafe"
 
 	"tidbyt.dev/pixlet/runtime"
+	_ "tidbyt.dev/pixlet/runtime/cache/bbolt"
+	_ "tidbyt.dev/pixlet/runtime/cache/filesystem"
+	_ "tidbyt.dev/pixlet/runtime/cache/memcached"
+	_ "tidbyt.dev/pixlet/runtime/cache/s3"
+	_ "tidbyt.dev/pixlet/runtime/cache/sqlite"
+	_ "tidbyt.dev/pixlet/runtime/cache/tiered"
 	"tidbyt.dev/pixlet/server/loader"
 )
 
@@ -28,7 +34,7 @@ func render_app(namePtr *C.char, configPtr *C.char, width, height, magnify, maxD
 		return nil, -1
 	}
 
-	result, err := loader.RenderApplet(name, config, int(width), int(height), int(magnify), int(maxDuration), int(timeout), renderGif != 0, silenceOutput != 0)
+	result, err := loader.RenderApplet(name, config, int(width), int(height), int(magnify), int(maxDuration), int(timeout), 0, renderGif != 0, silenceOutput != 0, false)
 	if err != nil {
 		fmt.Printf("error rendering: %v\n", err)
 		return nil, -2
@@ -55,4 +61,16 @@ func init_redis_cache(redisURL *C.char) {
 	runtime.InitCache(cache)
 }
 
+//export init_cache_url
+func init_cache_url(cacheURL *C.char) C.int {
+	cache, err := runtime.OpenCache(C.GoString(cacheURL))
+	if err != nil {
+		fmt.Printf("error opening cache: %v\n", err)
+		return -1
+	}
+	runtime.InitHTTP(cache)
+	runtime.InitCache(cache)
+	return 0
+}
+
 func main() {}
@@ -0,0 +1,112 @@
+// Package logging configures structured logging for pixlet using log/slog.
+//
+// Log records carry an "app" attribute naming the applet they relate to and,
+// when emitted while serving an HTTP request, a "request_id" attribute, so
+// that logs from a server hosting many apps can be filtered.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const (
+	// AppKey is the attribute key for the ID of the applet a record is about.
+	AppKey = "app"
+
+	// RequestIDKey is the attribute key for the ID of the HTTP request being
+	// served.
+	RequestIDKey = "request_id"
+
+	// RequestIDHeader is the header used to read and echo request IDs.
+	RequestIDHeader = "X-Request-ID"
+)
+
+// Formats supported by Setup.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+type contextKey struct{}
+
+// ParseLevel parses a level name such as "debug" or "warn".
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return l, fmt.Errorf("invalid log level %q (use debug, info, warn or error)", level)
+	}
+	return l, nil
+}
+
+// Setup installs a default slog logger writing records at or above level to
+// w, in either text or JSON format. Output from the standard log package is
+// routed through the same logger.
+func Setup(w io.Writer, level, format string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	opts := &slog.HandlerOptions{Level: l}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatText, "":
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q (use %s or %s)", format, FormatText, FormatJSON)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// ForApp returns a logger that tags records with the given applet ID.
+func ForApp(appID string) *slog.Logger {
+	return slog.Default().With(AppKey, appID)
+}
+
+// FromContext returns the logger attached to ctx by WithLogger or
+// Middleware, falling back to the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// Middleware assigns every request an ID, taken from the X-Request-ID header
+// when present, echoes it in the response, and attaches a logger tagged with
+// it to the request context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		logger := FromContext(r.Context()).With(RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(WithLogger(r.Context(), logger)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupJSON(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	require.NoError(t, Setup(&buf, "warn", FormatJSON))
+
+	ForApp("clock").Info("dropped")
+	ForApp("clock").Warn("kept", "error", "boom")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "kept", record["msg"])
+	assert.Equal(t, "clock", record[AppKey])
+	assert.Equal(t, "boom", record["error"])
+}
+
+func TestSetupRoutesStandardLog(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	require.NoError(t, Setup(&buf, "info", FormatText))
+
+	log.Printf("hello from log")
+	assert.Contains(t, buf.String(), `msg="hello from log"`)
+}
+
+func TestSetupInvalid(t *testing.T) {
+	assert.Error(t, Setup(&bytes.Buffer{}, "loud", FormatText))
+	assert.Error(t, Setup(&bytes.Buffer{}, "info", "xml"))
+}
+
+func TestMiddleware(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	require.NoError(t, Setup(&buf, "info", FormatJSON))
+
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handling")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, "abc123", rec.Header().Get(RequestIDHeader))
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "abc123", record[RequestIDKey])
+
+	// a fresh ID is generated when the client doesn't send one
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Len(t, rec.Header().Get(RequestIDHeader), 16)
+}
@@ -1,36 +1,98 @@
 package main
 
 import (
+	"context"
 	"os"
 
 	"github.com/spf13/cobra"
 	"tidbyt.dev/pixlet/cmd"
 	"tidbyt.dev/pixlet/cmd/community"
+	"tidbyt.dev/pixlet/icons"
+	"tidbyt.dev/pixlet/logging"
+	"tidbyt.dev/pixlet/tracing"
 )
 
 var (
 	rootCmd = &cobra.Command{
-		Use:          "pixlet",
-		Short:        "pixel graphics rendering",
-		Long:         "Pixlet renders graphics for pixel devices, like Tidbyt",
-		SilenceUsage: true,
+		Use:                "pixlet",
+		Short:              "pixel graphics rendering",
+		Long:               "Pixlet renders graphics for pixel devices, like Tidbyt",
+		SilenceUsage:       true,
+		PersistentPreRunE:  preRun,
+		PersistentPostRunE: postRun,
 	}
+
+	otlpEndpoint    string
+	logLevel        string
+	logFormat       string
+	iconPacks       []string
+	shutdownTracing tracing.ShutdownFunc = func(context.Context) error { return nil }
 )
 
+// preRun sets up logging and tracing before any command runs.
+func preRun(cmd *cobra.Command, args []string) error {
+	if err := logging.Setup(os.Stderr, logLevel, logFormat); err != nil {
+		return err
+	}
+
+	endpoint := otlpEndpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+
+	shutdown, err := tracing.Init(context.Background(), endpoint)
+	if err != nil {
+		return err
+	}
+	shutdownTracing = shutdown
+
+	return icons.RegisterPaths(iconPacks...)
+}
+
+// postRun flushes any pending traces.
+func postRun(cmd *cobra.Command, args []string) error {
+	return shutdownTracing(context.Background())
+}
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Minimum level of log messages (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "Format of log messages (text, json)")
+	rootCmd.PersistentFlags().StringVar(
+		&otlpEndpoint,
+		"otlp-endpoint",
+		"",
+		"Export OpenTelemetry traces to this OTLP/HTTP endpoint (e.g. http://localhost:4318)",
+	)
+	rootCmd.PersistentFlags().StringSliceVar(&iconPacks, "icons", nil, "Icon packs for apps to use, as directories or zip files of PNGs and SVGs")
+
 	rootCmd.AddCommand(cmd.ApiCmd)
 	rootCmd.AddCommand(cmd.RenderCmd)
+	rootCmd.AddCommand(cmd.DiffCmd)
+	rootCmd.AddCommand(cmd.ConvertCmd)
+	rootCmd.AddCommand(cmd.FontCmd)
+	rootCmd.AddCommand(cmd.SchemaCmd)
+	rootCmd.AddCommand(cmd.ConfigCmd)
+	rootCmd.AddCommand(cmd.DocsCmd)
 	rootCmd.AddCommand(cmd.PushCmd)
+	rootCmd.AddCommand(cmd.DisplayCmd)
 	rootCmd.AddCommand(cmd.EncryptCmd)
 	rootCmd.AddCommand(cmd.VersionCmd)
 	rootCmd.AddCommand(cmd.ProfileCmd)
 	rootCmd.AddCommand(cmd.LoginCmd)
 	rootCmd.AddCommand(cmd.DevicesCmd)
+	rootCmd.AddCommand(cmd.HubCmd)
+	rootCmd.AddCommand(cmd.ProvisionCmd)
 	rootCmd.AddCommand(cmd.ListCmd)
 	rootCmd.AddCommand(cmd.DeleteCmd)
 	rootCmd.AddCommand(cmd.FormatCmd)
 	rootCmd.AddCommand(cmd.LintCmd)
 	rootCmd.AddCommand(cmd.CheckCmd)
+	rootCmd.AddCommand(cmd.LspCmd)
+	rootCmd.AddCommand(cmd.DebugCmd)
+	rootCmd.AddCommand(cmd.ReplCmd)
+	rootCmd.AddCommand(cmd.TestCmd)
+	rootCmd.AddCommand(cmd.FuzzCmd)
+	rootCmd.AddCommand(cmd.BenchCmd)
 	rootCmd.AddCommand(cmd.SetAuthCmd)
 	rootCmd.AddCommand(community.CommunityCmd)
 }
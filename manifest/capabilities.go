@@ -0,0 +1,147 @@
+package manifest
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Capabilities are what an applet needs to work.
+type Capabilities struct {
+	// Network is the hosts the applet makes requests to, optionally with a
+	// port. A leading "*." matches any subdomain. Ex. "api.weather.gov",
+	// "*.example.com" or "localhost:8080". Applets that list none aren't
+	// limited to any hosts.
+	Network []string `json:"network,omitempty" yaml:"network,omitempty"`
+
+	// Secrets are the names of the secrets the applet decrypts, such as API
+	// keys.
+	Secrets []string `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+
+	// MinDisplaySize is the smallest display the applet renders properly
+	// on.
+	MinDisplaySize *DisplaySize `json:"min_display_size,omitempty" yaml:"min_display_size,omitempty"`
+}
+
+// DisplaySize is the size of a display in pixels.
+type DisplaySize struct {
+	Width  int `json:"width" yaml:"width"`
+	Height int `json:"height" yaml:"height"`
+}
+
+// Validate ensures the capabilities are valid and returns an error if they
+// are not.
+func (c Capabilities) Validate() error {
+	for _, host := range c.Network {
+		if err := ValidateNetworkHost(host); err != nil {
+			return err
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, secret := range c.Secrets {
+		if err := ValidateSecretName(secret); err != nil {
+			return err
+		}
+		if seen[secret] {
+			return fmt.Errorf("secret '%s' is listed more than once", secret)
+		}
+		seen[secret] = true
+	}
+
+	if c.MinDisplaySize != nil {
+		if c.MinDisplaySize.Width <= 0 || c.MinDisplaySize.Height <= 0 {
+			return fmt.Errorf("min display size must be positive, not %dx%d", c.MinDisplaySize.Width, c.MinDisplaySize.Height)
+		}
+	}
+
+	return nil
+}
+
+// AllowsHost reports whether the applet declared it makes requests to host,
+// which may include a port. Hosts declared without a port match any port.
+func (c Capabilities) AllowsHost(host string) bool {
+	name, port := splitHostPort(host)
+	name = strings.ToLower(name)
+
+	for _, allowed := range c.Network {
+		allowedName, allowedPort := splitHostPort(allowed)
+		allowedName = strings.ToLower(allowedName)
+		if allowedPort != "" && allowedPort != port {
+			continue
+		}
+
+		if suffix, ok := strings.CutPrefix(allowedName, "*."); ok {
+			if strings.HasSuffix(name, "."+suffix) {
+				return true
+			}
+		} else if name == allowedName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateNetworkHost ensures a host an applet declares it makes requests to
+// is a host name or IP address, with an optional port, and not a URL.
+func ValidateNetworkHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("network hosts cannot be empty")
+	}
+
+	if strings.Contains(host, "/") {
+		return fmt.Errorf("network host '%s' should be a host name, not a URL", host)
+	}
+
+	name, port := splitHostPort(host)
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return fmt.Errorf("network host '%s' has an invalid port", host)
+		}
+	}
+
+	if net.ParseIP(name) != nil {
+		return nil
+	}
+
+	name = strings.TrimPrefix(name, "*.")
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == dash || label[len(label)-1] == dash {
+			return fmt.Errorf("network host '%s' is not a valid host name", host)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == dash) {
+				return fmt.Errorf("network host '%s' is not a valid host name", host)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateSecretName ensures the name of a secret an applet uses is an
+// identifier, like the names of config fields.
+func ValidateSecretName(name string) error {
+	if name == "" {
+		return fmt.Errorf("secret names cannot be empty")
+	}
+
+	for i, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == underscore || i > 0 && r >= '0' && r <= '9') {
+			return fmt.Errorf("secret names can only contain letters, numbers, or an underscore character, and can't start with a number")
+		}
+	}
+
+	return nil
+}
+
+// splitHostPort splits host into a host name and port, if it has one.
+func splitHostPort(host string) (string, string) {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return strings.Trim(host, "[]"), ""
+	}
+	return name, port
+}
@@ -0,0 +1,97 @@
+package manifest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"tidbyt.dev/pixlet/manifest"
+)
+
+func TestValidateNetworkHost(t *testing.T) {
+	type test struct {
+		input     string
+		shouldErr bool
+	}
+
+	tests := []test{
+		{input: "api.weather.gov", shouldErr: false},
+		{input: "*.example.com", shouldErr: false},
+		{input: "localhost:8080", shouldErr: false},
+		{input: "192.168.1.10", shouldErr: false},
+		{input: "[::1]:8080", shouldErr: false},
+		{input: "https://api.weather.gov", shouldErr: true},
+		{input: "api.weather.gov/points", shouldErr: true},
+		{input: "api..weather.gov", shouldErr: true},
+		{input: "-api.weather.gov", shouldErr: true},
+		{input: "api.weather.gov:http", shouldErr: true},
+		{input: "", shouldErr: true},
+	}
+
+	for _, tc := range tests {
+		err := manifest.ValidateNetworkHost(tc.input)
+
+		if tc.shouldErr {
+			assert.Error(t, err, tc.input)
+		} else {
+			assert.NoError(t, err, tc.input)
+		}
+	}
+}
+
+func TestValidateSecretName(t *testing.T) {
+	type test struct {
+		input     string
+		shouldErr bool
+	}
+
+	tests := []test{
+		{input: "api_key", shouldErr: false},
+		{input: "TOKEN2", shouldErr: false},
+		{input: "2fa", shouldErr: true},
+		{input: "api-key", shouldErr: true},
+		{input: "", shouldErr: true},
+	}
+
+	for _, tc := range tests {
+		err := manifest.ValidateSecretName(tc.input)
+
+		if tc.shouldErr {
+			assert.Error(t, err, tc.input)
+		} else {
+			assert.NoError(t, err, tc.input)
+		}
+	}
+}
+
+func TestCapabilitiesValidate(t *testing.T) {
+	c := manifest.Capabilities{
+		Network:        []string{"api.weather.gov"},
+		Secrets:        []string{"api_key"},
+		MinDisplaySize: &manifest.DisplaySize{Width: 64, Height: 32},
+	}
+	assert.NoError(t, c.Validate())
+
+	c.Secrets = []string{"api_key", "api_key"}
+	assert.Error(t, c.Validate())
+
+	c.Secrets = nil
+	c.MinDisplaySize = &manifest.DisplaySize{Width: 0, Height: 32}
+	assert.Error(t, c.Validate())
+}
+
+func TestAllowsHost(t *testing.T) {
+	c := manifest.Capabilities{
+		Network: []string{"api.weather.gov", "*.example.com", "localhost:8080"},
+	}
+
+	assert.True(t, c.AllowsHost("api.weather.gov"))
+	assert.True(t, c.AllowsHost("API.Weather.gov:443"))
+	assert.True(t, c.AllowsHost("images.example.com"))
+	assert.True(t, c.AllowsHost("a.b.example.com"))
+	assert.True(t, c.AllowsHost("localhost:8080"))
+
+	assert.False(t, c.AllowsHost("example.com"))
+	assert.False(t, c.AllowsHost("weather.gov"))
+	assert.False(t, c.AllowsHost("localhost:9090"))
+	assert.False(t, c.AllowsHost("localhost"))
+}
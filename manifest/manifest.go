@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
+	"golang.org/x/mod/semver"
 	"gopkg.in/yaml.v3"
 )
 
@@ -32,6 +34,19 @@ type Manifest struct {
 	// "Max Timkovich"
 	Author string `json:"author" yaml:"author"`
 
+	// Version is the semantic version of this applet, Ex. "1.4.0". Servers
+	// compare it to the version installed to tell users about updates.
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+
+	// RefreshInterval is how often this applet should be rendered, as a
+	// duration. Ex. "15m". Servers may render it less often, but shouldn't
+	// render it more often.
+	RefreshInterval string `json:"refresh_interval,omitempty" yaml:"refresh_interval,omitempty"`
+
+	// Capabilities are what this applet needs to work, which servers can
+	// enforce and show to users before they install it.
+	Capabilities *Capabilities `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+
 	// Source is the starlark source code for this applet using the go `embed`
 	// module.
 	Source []byte `json:"-" yaml:"-"`
@@ -101,9 +116,49 @@ func (m Manifest) Validate() error {
 		return err
 	}
 
+	if m.Version != "" {
+		err = ValidateVersion(m.Version)
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.RefreshInterval != "" {
+		err = ValidateRefreshInterval(m.RefreshInterval)
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.Capabilities != nil {
+		err = m.Capabilities.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// Refresh returns the refresh interval of the applet, or zero if it doesn't
+// have one.
+func (m Manifest) Refresh() time.Duration {
+	d, err := time.ParseDuration(m.RefreshInterval)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// IsUpdateOf reports whether the applet is a newer version than installed.
+// Versions that aren't valid are never updates or updated.
+func (m Manifest) IsUpdateOf(installed string) bool {
+	if ValidateVersion(m.Version) != nil || ValidateVersion(installed) != nil {
+		return false
+	}
+	return semver.Compare("v"+m.Version, "v"+installed) > 0
+}
+
 // GenerateDirName creates a suitable directory name from an app name.
 func GenerateDirName(name string) string {
 	dir := strings.ReplaceAll(name, "-", "")
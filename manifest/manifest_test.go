@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"tidbyt.dev/pixlet/manifest"
@@ -54,6 +55,28 @@ func TestLoadManifest(t *testing.T) {
 	assert.Equal(t, m.Desc, "Display the time in a groovy, human-readable way.")
 }
 
+func TestLoadExtendedManifest(t *testing.T) {
+	p := filepath.Join("testdata", "manifest_extended.yaml")
+	f, err := os.Open(p)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	m, err := manifest.LoadManifest(f)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Validate())
+
+	assert.Equal(t, "1.2.0", m.Version)
+	assert.Equal(t, 15*time.Minute, m.Refresh())
+	assert.Equal(t, &manifest.Capabilities{
+		Network:        []string{"api.weather.gov", "*.weather.gov"},
+		Secrets:        []string{"api_key"},
+		MinDisplaySize: &manifest.DisplaySize{Width: 64, Height: 32},
+	}, m.Capabilities)
+
+	m.RefreshInterval = "10s"
+	assert.Error(t, m.Validate())
+}
+
 func TestWriteManifest(t *testing.T) {
 	m := manifest.Manifest{
 		ID:      "foo-tracker",
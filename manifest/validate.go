@@ -3,6 +3,7 @@ package manifest
 import (
 	"fmt"
 	"strings"
+	"time"
 	"unicode"
 
 	"golang.org/x/text/cases"
@@ -18,6 +19,13 @@ const (
 	// be tested in the mobile app.
 	MaxSummaryLength = 27
 
+	// The fastest an app can ask to be refreshed. Devices don't show an app
+	// for less than this anyway.
+	MinRefreshInterval = time.Minute
+
+	// The slowest an app can ask to be refreshed.
+	MaxRefreshInterval = 24 * time.Hour
+
 	dash       = '-'
 	underscore = '_'
 )
@@ -139,6 +147,21 @@ func ValidateID(id string) error {
 	return nil
 }
 
+// ValidateRefreshInterval ensures the refresh interval provided is a
+// duration, like "15m", that apps can be refreshed at.
+func ValidateRefreshInterval(interval string) error {
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return fmt.Errorf("'%s' is not a duration, '15m' for example", interval)
+	}
+
+	if d < MinRefreshInterval || d > MaxRefreshInterval {
+		return fmt.Errorf("refresh intervals need to be between %s and %s", MinRefreshInterval, MaxRefreshInterval)
+	}
+
+	return nil
+}
+
 func titleCase(input string) string {
 	words := strings.Split(input, " ")
 	smallwords := " a an on the to of "
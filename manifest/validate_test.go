@@ -111,3 +111,60 @@ func TestValidateID(t *testing.T) {
 	}
 
 }
+
+func TestValidateVersion(t *testing.T) {
+	type test struct {
+		input     string
+		shouldErr bool
+	}
+
+	tests := []test{
+		{input: "1.4.0", shouldErr: false},
+		{input: "0.0.1", shouldErr: false},
+		{input: "2.0.0-beta.1", shouldErr: false},
+		{input: "1.0.0+20240101", shouldErr: false},
+		{input: "v1.4.0", shouldErr: true},
+		{input: "1.4", shouldErr: true},
+		{input: "01.4.0", shouldErr: true},
+		{input: "1.4.0-", shouldErr: true},
+		{input: "1.4.x", shouldErr: true},
+		{input: "", shouldErr: true},
+	}
+
+	for _, tc := range tests {
+		err := manifest.ValidateVersion(tc.input)
+
+		if tc.shouldErr {
+			assert.Error(t, err, tc.input)
+		} else {
+			assert.NoError(t, err, tc.input)
+		}
+	}
+}
+
+func TestValidateRefreshInterval(t *testing.T) {
+	type test struct {
+		input     string
+		shouldErr bool
+	}
+
+	tests := []test{
+		{input: "15m", shouldErr: false},
+		{input: "1h30m", shouldErr: false},
+		{input: "1m", shouldErr: false},
+		{input: "30s", shouldErr: true},
+		{input: "48h", shouldErr: true},
+		{input: "15", shouldErr: true},
+		{input: "", shouldErr: true},
+	}
+
+	for _, tc := range tests {
+		err := manifest.ValidateRefreshInterval(tc.input)
+
+		if tc.shouldErr {
+			assert.Error(t, err, tc.input)
+		} else {
+			assert.NoError(t, err, tc.input)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// ValidateVersion ensures the version provided is a semantic version, like
+// "1.4.0" or "2.0.0-beta.1". See https://semver.org.
+func ValidateVersion(version string) error {
+	if version == "" {
+		return fmt.Errorf("version cannot be empty")
+	}
+
+	if strings.HasPrefix(version, "v") {
+		return fmt.Errorf("versions should not start with 'v', %s != %s", version, version[1:])
+	}
+
+	// semver accepts shorthands like "v1.4", which the canonical form
+	// expands, but manifests have to spell out all three numbers
+	v, _, _ := strings.Cut("v"+version, "+")
+	if !semver.IsValid(v) || semver.Canonical(v) != v {
+		return fmt.Errorf("'%s' is not a semantic version, '1.4.0' for example", version)
+	}
+
+	return nil
+}
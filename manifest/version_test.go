@@ -0,0 +1,29 @@
+package manifest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"tidbyt.dev/pixlet/manifest"
+)
+
+func TestIsUpdateOf(t *testing.T) {
+	m := manifest.Manifest{Version: "1.5.0"}
+	assert.True(t, m.IsUpdateOf("1.4.2"))
+	assert.False(t, m.IsUpdateOf("1.5.0"))
+	assert.False(t, m.IsUpdateOf("2.0.0"))
+	assert.False(t, m.IsUpdateOf(""))
+
+	m.Version = "1.10.0"
+	assert.True(t, m.IsUpdateOf("1.9.0"))
+
+	m.Version = "2.0.0-beta.10"
+	assert.True(t, m.IsUpdateOf("2.0.0-beta.2"))
+	assert.False(t, m.IsUpdateOf("2.0.0"))
+
+	m.Version = "1.0.0+build.2"
+	assert.False(t, m.IsUpdateOf("1.0.0+build.1"))
+
+	m.Version = ""
+	assert.False(t, m.IsUpdateOf("1.4.2"))
+}
@@ -0,0 +1,74 @@
+package matrix
+
+import (
+	"fmt"
+	"image"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// DefaultFlaschenTaschenPort is the UDP port ft-server listens on.
+const DefaultFlaschenTaschenPort = "1337"
+
+// ftDisplay sends frames to a Flaschen-Taschen server as binary PPM images,
+// one per UDP packet. The image can be placed at an offset and on a layer of
+// the server's display with the x, y and z query parameters.
+type ftDisplay struct {
+	conn    net.Conn
+	x, y, z int
+	buf     []byte
+}
+
+func openFlaschenTaschen(u *url.URL) (Display, error) {
+	host := u.Host
+	if host == "" {
+		return nil, fmt.Errorf("ft display needs a host, like ft://matrix.local")
+	}
+	if u.Port() == "" {
+		host = net.JoinHostPort(host, DefaultFlaschenTaschenPort)
+	}
+
+	d := &ftDisplay{}
+	for name, v := range map[string]*int{"x": &d.x, "y": &d.y, "z": &d.z} {
+		if s := u.Query().Get(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s offset %q", name, s)
+			}
+			*v = n
+		}
+	}
+
+	conn, err := net.Dial("udp", host)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", host, err)
+	}
+	d.conn = conn
+
+	return d, nil
+}
+
+func (d *ftDisplay) Draw(img image.Image) error {
+	d.buf = appendPPM(d.buf[:0], img)
+	d.buf = fmt.Appendf(d.buf, "\n%d\n%d\n%d\n", d.x, d.y, d.z)
+	_, err := d.conn.Write(d.buf)
+	return err
+}
+
+func (d *ftDisplay) Close() error {
+	return d.conn.Close()
+}
+
+// appendPPM appends img to buf as a binary (P6) PPM image.
+func appendPPM(buf []byte, img image.Image) []byte {
+	b := img.Bounds()
+	buf = fmt.Appendf(buf, "P6\n%d %d\n255\n", b.Dx(), b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			buf = append(buf, byte(r>>8), byte(g>>8), byte(bl>>8))
+		}
+	}
+	return buf
+}
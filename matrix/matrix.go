@@ -0,0 +1,71 @@
+// Package matrix drives LED matrices that are attached directly to the
+// machine running pixlet, such as a HUB75 panel on a Raspberry Pi, without
+// any firmware or HTTP hop in between.
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Display is a directly attached matrix that frames are drawn on.
+type Display interface {
+	// Draw shows a frame until the next one is drawn.
+	Draw(img image.Image) error
+
+	Close() error
+}
+
+// Schemes are the URL schemes Open supports.
+var Schemes = []string{"serial", "ft"}
+
+// Open opens the display at target, a URL such as:
+//
+//	serial:///dev/ttyACM0?baud=921600
+//	ft://matrix.local:1337
+//
+// serial displays are sent frames with the Adalight protocol, which is
+// spoken by most microcontroller sketches for driving LED strips and panels.
+// ft displays are sent frames with the Flaschen-Taschen protocol, which is
+// served by ft-server on top of rpi-rgb-led-matrix.
+func Open(target string) (Display, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("parsing display URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "serial":
+		return openSerial(u)
+	case "ft":
+		return openFlaschenTaschen(u)
+	default:
+		return nil, fmt.Errorf("unsupported display %q (schemes: %s)", target, strings.Join(Schemes, ", "))
+	}
+}
+
+// Play draws frames on d, showing each for its delay. It returns early if ctx
+// is done.
+func Play(ctx context.Context, d Display, frames []image.Image, delays []time.Duration) error {
+	if len(frames) != len(delays) {
+		return fmt.Errorf("got %d frames but %d delays", len(frames), len(delays))
+	}
+
+	for i, frame := range frames {
+		if err := d.Draw(frame); err != nil {
+			return fmt.Errorf("drawing frame %d: %w", i, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delays[i]):
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package matrix
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 0xff, A: 0xff})
+	img.Set(1, 0, color.RGBA{G: 0x80, B: 0x10, A: 0xff})
+	return img
+}
+
+func TestSerial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tty")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	d, err := Open("serial://" + path + "?baud=921600")
+	require.NoError(t, err)
+	require.NoError(t, d.Draw(testImage()))
+	require.NoError(t, d.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		'A', 'd', 'a', 0x00, 0x01, 0x54,
+		0xff, 0x00, 0x00,
+		0x00, 0x80, 0x10,
+	}, data)
+
+	_, err = Open("serial://" + path + "?baud=fast")
+	assert.Error(t, err)
+}
+
+func TestFlaschenTaschen(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	d, err := Open("ft://" + conn.LocalAddr().String() + "?x=3&z=2")
+	require.NoError(t, err)
+	defer d.Close()
+	require.NoError(t, d.Draw(testImage()))
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	want := append([]byte("P6\n2 1\n255\n"), 0xff, 0x00, 0x00, 0x00, 0x80, 0x10)
+	want = append(want, "\n3\n0\n2\n"...)
+	assert.Equal(t, want, buf[:n])
+}
+
+func TestOpenUnsupported(t *testing.T) {
+	_, err := Open("hdmi://0")
+	assert.ErrorContains(t, err, "unsupported display")
+}
+
+type recordingDisplay struct {
+	frames []image.Image
+}
+
+func (d *recordingDisplay) Draw(img image.Image) error {
+	d.frames = append(d.frames, img)
+	return nil
+}
+
+func (d *recordingDisplay) Close() error { return nil }
+
+func TestPlay(t *testing.T) {
+	d := &recordingDisplay{}
+	frames := []image.Image{testImage(), testImage()}
+
+	require.NoError(t, Play(context.Background(), d, frames, []time.Duration{time.Millisecond, time.Millisecond}))
+	assert.Len(t, d.frames, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d = &recordingDisplay{}
+	assert.ErrorIs(t, Play(ctx, d, frames, []time.Duration{time.Hour, time.Hour}), context.Canceled)
+	assert.Len(t, d.frames, 1)
+
+	assert.Error(t, Play(context.Background(), d, frames, nil))
+}
@@ -0,0 +1,78 @@
+package matrix
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// DefaultBaudRate is the baud rate serial displays are opened at unless the
+// URL sets one.
+const DefaultBaudRate = 115200
+
+// serialDisplay sends frames over a serial port as Adalight packets: a
+// header of "Ada", the number of pixels minus one as a big-endian uint16 and
+// a checksum, followed by the pixels in row-major order as RGB triplets.
+type serialDisplay struct {
+	w   io.WriteCloser
+	buf []byte
+}
+
+func openSerial(u *url.URL) (Display, error) {
+	path := u.Path
+	if path == "" {
+		path = u.Opaque
+	}
+	if path == "" {
+		return nil, fmt.Errorf("serial display needs a device path, like serial:///dev/ttyACM0")
+	}
+
+	baud := DefaultBaudRate
+	if b := u.Query().Get("baud"); b != "" {
+		var err error
+		if baud, err = strconv.Atoi(b); err != nil || baud <= 0 {
+			return nil, fmt.Errorf("invalid baud rate %q", b)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	if err := configureSerial(f, baud); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("configuring %s: %w", path, err)
+	}
+
+	return &serialDisplay{w: f}, nil
+}
+
+func (d *serialDisplay) Draw(img image.Image) error {
+	d.buf = appendAdalight(d.buf[:0], img)
+	_, err := d.w.Write(d.buf)
+	return err
+}
+
+func (d *serialDisplay) Close() error {
+	return d.w.Close()
+}
+
+// appendAdalight appends an Adalight packet holding img to buf.
+func appendAdalight(buf []byte, img image.Image) []byte {
+	b := img.Bounds()
+	n := b.Dx()*b.Dy() - 1
+	hi, lo := byte(n>>8), byte(n)
+
+	buf = append(buf, 'A', 'd', 'a', hi, lo, hi^lo^0x55)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			buf = append(buf, byte(r>>8), byte(g>>8), byte(bl>>8))
+		}
+	}
+	return buf
+}
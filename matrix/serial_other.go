@@ -0,0 +1,11 @@
+//go:build !linux
+
+package matrix
+
+import "os"
+
+// configureSerial leaves the port as it is on platforms other than Linux, so
+// it needs to be set up beforehand, for example with stty.
+func configureSerial(f *os.File, baud int) error {
+	return nil
+}
@@ -0,0 +1,392 @@
+// Package mqtt is a small MQTT 3.1.1 client, just enough to publish renders
+// and state to a broker and to receive commands. All messages are sent and
+// received at QoS 0.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultKeepAlive is how often the client pings the broker if Options
+// doesn't say otherwise.
+const DefaultKeepAlive = 30 * time.Second
+
+// maxPacketSize bounds packets read from the broker, which is the largest
+// size MQTT's remaining length encoding can express.
+const maxPacketSize = 268435455
+
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+	protocolLevel311  = 4
+	flagCleanSession  = 0x02
+	flagWill          = 0x04
+	flagWillRetain    = 0x20
+	flagPassword      = 0x40
+	flagUsername      = 0x80
+	publishFlagRetain = 0x01
+)
+
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Message is a message published to a topic.
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Options configure a connection to a broker.
+type Options struct {
+	// ClientID identifies the client to the broker. Brokers assign one if
+	// it's empty.
+	ClientID string
+
+	// Username and Password authenticate with the broker. They default to
+	// the user info of the broker URL.
+	Username string
+	Password string
+
+	// KeepAlive is how often the client pings the broker, so that the
+	// broker notices when the client goes away. DefaultKeepAlive is used if
+	// it's zero.
+	KeepAlive time.Duration
+
+	// Will is published by the broker if the client disconnects without
+	// calling Close.
+	Will *Message
+
+	// OnMessage is called, one message at a time, for messages published to
+	// topics the client subscribed to.
+	OnMessage func(Message)
+}
+
+// Client is a connection to an MQTT broker. It's safe for concurrent use.
+type Client struct {
+	conn      net.Conn
+	onMessage func(Message)
+
+	mu       sync.Mutex // guards writes to conn and nextID
+	nextID   uint16
+	done     chan struct{}
+	closeErr error
+	once     sync.Once
+}
+
+// Dial connects to the broker at u, an mqtt:// URL, or mqtts:// for TLS.
+// The URL's path isn't used, so callers are free to use it for a topic.
+func Dial(ctx context.Context, u *url.URL, opts Options) (*Client, error) {
+	host := u.Host
+	var tlsConfig *tls.Config
+	switch u.Scheme {
+	case "mqtt", "tcp":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "1883")
+		}
+	case "mqtts", "ssl":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "8883")
+		}
+		tlsConfig = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("unsupported MQTT scheme %q, expected mqtt or mqtts", u.Scheme)
+	}
+
+	if opts.Username == "" && u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+	if opts.KeepAlive == 0 {
+		opts.KeepAlive = DefaultKeepAlive
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to MQTT broker: %w", err)
+	}
+	if tlsConfig != nil {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c, err := handshake(ctx, conn, opts)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to MQTT broker %s: %w", host, err)
+	}
+	return c, nil
+}
+
+func handshake(ctx context.Context, conn net.Conn, opts Options) (*Client, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	var flags byte = flagCleanSession
+	var payload []byte
+	payload = appendString(payload, opts.ClientID)
+	if opts.Will != nil {
+		flags |= flagWill
+		if opts.Will.Retain {
+			flags |= flagWillRetain
+		}
+		payload = appendString(payload, opts.Will.Topic)
+		payload = appendBytes(payload, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		flags |= flagUsername
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= flagPassword
+			payload = appendString(payload, opts.Password)
+		}
+	}
+
+	var body []byte
+	body = appendString(body, "MQTT")
+	body = append(body, protocolLevel311, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive/time.Second))
+	body = append(body, payload...)
+	if _, err := conn.Write(appendPacket(nil, packetConnect<<4, body)); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	header, ack, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	if header>>4 != packetConnack || len(ack) != 2 {
+		return nil, fmt.Errorf("expected CONNACK from broker")
+	}
+	if ack[1] != 0 {
+		msg, ok := connackErrors[ack[1]]
+		if !ok {
+			msg = fmt.Sprintf("code %d", ack[1])
+		}
+		return nil, fmt.Errorf("broker refused connection: %s", msg)
+	}
+
+	c := &Client{
+		conn:      conn,
+		onMessage: opts.OnMessage,
+		done:      make(chan struct{}),
+	}
+	go c.readLoop(r)
+	go c.keepAlive(opts.KeepAlive)
+	return c, nil
+}
+
+// Publish publishes a message. Retained messages are kept by the broker and
+// sent to clients that subscribe later.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	var header byte = packetPublish << 4
+	if retain {
+		header |= publishFlagRetain
+	}
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	return c.write(appendPacket(nil, header, body))
+}
+
+// Subscribe subscribes to topic filters, which may contain the + and #
+// wildcards. Messages are passed to Options.OnMessage.
+func (c *Client) Subscribe(filters ...string) error {
+	c.mu.Lock()
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+	c.mu.Unlock()
+
+	body := binary.BigEndian.AppendUint16(nil, id)
+	for _, f := range filters {
+		body = appendString(body, f)
+		body = append(body, 0) // QoS 0
+	}
+	return c.write(appendPacket(nil, packetSubscribe<<4|0x02, body))
+}
+
+// Done is closed when the connection to the broker is lost or closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection was lost, once Done is closed.
+func (c *Client) Err() error {
+	<-c.done
+	return c.closeErr
+}
+
+// Close disconnects from the broker. The will message isn't published.
+func (c *Client) Close() error {
+	c.write(appendPacket(nil, packetDisconnect<<4, nil))
+	c.shutdown(nil)
+	return nil
+}
+
+func (c *Client) write(packet []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return fmt.Errorf("MQTT connection closed: %w", c.closeErr)
+	default:
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.conn.Write(packet); err != nil {
+		go c.shutdown(err)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) shutdown(err error) {
+	c.once.Do(func() {
+		if err == nil {
+			err = net.ErrClosed
+		}
+		c.closeErr = err
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *Client) readLoop(r *bufio.Reader) {
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		if header>>4 != packetPublish || c.onMessage == nil {
+			// PINGRESP and SUBACK need no reply
+			continue
+		}
+
+		if len(body) < 2 {
+			c.shutdown(fmt.Errorf("malformed PUBLISH"))
+			return
+		}
+		n := int(binary.BigEndian.Uint16(body))
+		if len(body) < 2+n {
+			c.shutdown(fmt.Errorf("malformed PUBLISH"))
+			return
+		}
+		msg := Message{
+			Topic:  string(body[2 : 2+n]),
+			Retain: header&publishFlagRetain != 0,
+		}
+		rest := body[2+n:]
+		if (header>>1)&0x03 > 0 {
+			// a packet ID; we subscribe at QoS 0, so brokers shouldn't
+			// send these
+			if len(rest) < 2 {
+				c.shutdown(fmt.Errorf("malformed PUBLISH"))
+				return
+			}
+			rest = rest[2:]
+		}
+		msg.Payload = rest
+		c.onMessage(msg)
+	}
+}
+
+func (c *Client) keepAlive(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-t.C:
+			c.write(appendPacket(nil, packetPingreq<<4, nil))
+		}
+	}
+}
+
+// readPacket reads one packet, returning its first header byte and body.
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, shift := 0, 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+		if shift > 21 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+	}
+	if length > maxPacketSize {
+		return 0, nil, errors.New("packet too large")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func appendPacket(b []byte, header byte, body []byte) []byte {
+	b = append(b, header)
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, body...)
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b []byte, s []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,164 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBroker accepts one client, and sends publishes to topics the client
+// subscribed to back to it.
+type fakeBroker struct {
+	ln        net.Listener
+	connect   chan []byte
+	published chan Message
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	b := &fakeBroker{
+		ln:        ln,
+		connect:   make(chan []byte, 1),
+		published: make(chan Message, 10),
+	}
+	go b.serve()
+	return b
+}
+
+func (b *fakeBroker) url(user string) *url.URL {
+	u := &url.URL{Scheme: "mqtt", Host: b.ln.Addr().String()}
+	if user != "" {
+		u.User = url.UserPassword(user, "hunter2")
+	}
+	return u
+}
+
+func (b *fakeBroker) serve() {
+	conn, err := b.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	subscribed := map[string]bool{}
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case packetConnect:
+			b.connect <- body
+			conn.Write(appendPacket(nil, packetConnack<<4, []byte{0, 0}))
+
+		case packetSubscribe:
+			rest := body[2:]
+			for len(rest) > 0 {
+				n := int(binary.BigEndian.Uint16(rest))
+				subscribed[string(rest[2:2+n])] = true
+				rest = rest[3+n:]
+			}
+			conn.Write(appendPacket(nil, packetSuback<<4, append(body[:2:2], 0)))
+
+		case packetPublish:
+			n := int(binary.BigEndian.Uint16(body))
+			msg := Message{
+				Topic:   string(body[2 : 2+n]),
+				Payload: body[2+n:],
+				Retain:  header&publishFlagRetain != 0,
+			}
+			b.published <- msg
+			if subscribed[msg.Topic] {
+				conn.Write(appendPacket(nil, packetPublish<<4, body))
+			}
+
+		case packetPingreq:
+			conn.Write(appendPacket(nil, packetPingresp<<4, nil))
+
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	b := newFakeBroker(t)
+
+	received := make(chan Message, 1)
+	c, err := Dial(context.Background(), b.url(""), Options{
+		ClientID:  "pixlet-test",
+		OnMessage: func(m Message) { received <- m },
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Subscribe("pixlet/cmd"))
+	require.NoError(t, c.Publish("pixlet/state", []byte("on"), true))
+	require.NoError(t, c.Publish("pixlet/cmd", []byte("render"), false))
+
+	assert.Equal(t, Message{Topic: "pixlet/state", Payload: []byte("on"), Retain: true}, <-b.published)
+	assert.Equal(t, Message{Topic: "pixlet/cmd", Payload: []byte("render")}, <-b.published)
+
+	select {
+	case m := <-received:
+		assert.Equal(t, "pixlet/cmd", m.Topic)
+		assert.Equal(t, "render", string(m.Payload))
+	case <-time.After(5 * time.Second):
+		t.Fatal("message wasn't delivered")
+	}
+}
+
+func TestConnectCredentialsAndWill(t *testing.T) {
+	b := newFakeBroker(t)
+
+	c, err := Dial(context.Background(), b.url("grace"), Options{
+		ClientID: "pixlet-test",
+		Will:     &Message{Topic: "pixlet/status", Payload: []byte("offline"), Retain: true},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	body := <-b.connect
+	expected := appendString(nil, "MQTT")
+	expected = append(expected, protocolLevel311, flagCleanSession|flagWill|flagWillRetain|flagUsername|flagPassword, 0, 30)
+	expected = appendString(expected, "pixlet-test")
+	expected = appendString(expected, "pixlet/status")
+	expected = appendString(expected, "offline")
+	expected = appendString(expected, "grace")
+	expected = appendString(expected, "hunter2")
+	assert.Equal(t, expected, body)
+}
+
+func TestClose(t *testing.T) {
+	b := newFakeBroker(t)
+
+	c, err := Dial(context.Background(), b.url(""), Options{})
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+
+	<-c.Done()
+	assert.Error(t, c.Publish("pixlet/state", nil, false))
+}
+
+func TestRemainingLength(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097152} {
+		packet := appendPacket(nil, packetPublish<<4, make([]byte, n))
+		header, body, err := readPacket(bufio.NewReader(bytes.NewReader(packet)))
+		require.NoError(t, err)
+		assert.Equal(t, byte(packetPublish<<4), header)
+		assert.Len(t, body, n)
+	}
+}
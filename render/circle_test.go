@@ -0,0 +1,23 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"tidbyt.dev/pixlet/render/golden"
+)
+
+func TestCircleGolden(t *testing.T) {
+	r := Root{Child: Row{
+		Children: []Widget{
+			Circle{Color: color.RGBA{0xff, 0, 0, 0xff}, Diameter: 10},
+			Circle{
+				Color:    color.RGBA{0, 0, 0xff, 0xff},
+				Diameter: 21,
+				Child:    Circle{Color: color.RGBA{0xff, 0xff, 0xff, 0xff}, Diameter: 7},
+			},
+		},
+	}}
+
+	golden.Assert(t, "testdata/circles.png", r.Paint(true), golden.Options{})
+}
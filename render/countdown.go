@@ -0,0 +1,152 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+
+	"github.com/tidbyt/gg"
+)
+
+var (
+	// DefaultCountdownDelay is the frame delay Countdown assumes when
+	// none is given. It's the delay a Root has by default.
+	DefaultCountdownDelay = 50
+
+	// DefaultCountdownDuration is how many seconds of the countdown
+	// are animated when no duration is given.
+	DefaultCountdownDuration = 15
+)
+
+// Countdown shows the time left until `target_time`, as `H:MM:SS`, or
+// `M:SS` when there's less than an hour to go.
+//
+// Rather than freeze at the moment the app was rendered, the time left
+// is worked out for every frame, from when the animation starts and
+// how long each frame is shown for. For the seconds to tick at the
+// right pace, `delay` must be the same as the frame delay of the Root.
+//
+// The animation starts at `now`, which is the time the app is rendered
+// if it's not given, and runs for `duration` seconds or until the
+// target time is reached, whichever is first. After the target time,
+// the countdown stays at zero.
+//
+// DOC(TargetTime): Time to count down to
+// DOC(Now): Time the animation starts at, the render time by default
+// DOC(Delay): Frame delay of the Root, in milliseconds
+// DOC(Duration): Seconds of the countdown to animate
+// DOC(Font): Desired font face
+// DOC(Color): Desired font color
+//
+// EXAMPLE BEGIN
+// render.Countdown(target_time=time.now() + time.minute * 5, duration=3, font="6x13", color="#f80")
+// EXAMPLE END
+type Countdown struct {
+	Widget
+	TargetTime time.Time `starlark:"target_time,required"`
+	Now        time.Time
+	Delay      int
+	Duration   int
+	Font       string
+	Color      color.Color
+
+	start  time.Time
+	delay  time.Duration
+	frames []*Text
+}
+
+func (c *Countdown) Init() error {
+	delay := c.Delay
+	if delay <= 0 {
+		delay = DefaultCountdownDelay
+	}
+
+	duration := c.Duration
+	if duration <= 0 {
+		duration = DefaultCountdownDuration
+	}
+
+	now := c.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	c.start, c.delay = now, time.Duration(delay)*time.Millisecond
+
+	// the text of each frame is made up front, with frames that show the
+	// same time sharing it
+	count := max((duration*1000+delay-1)/delay, 1)
+	texts := map[string]*Text{}
+	c.frames = make([]*Text, 0, count)
+
+	for i := 0; i < count; i++ {
+		left := c.left(i)
+		content := FormatCountdown(left)
+
+		t, ok := texts[content]
+		if !ok {
+			t = &Text{Content: content, Font: c.Font, Color: c.Color}
+			if err := t.Init(); err != nil {
+				return err
+			}
+			texts[content] = t
+		}
+		c.frames = append(c.frames, t)
+
+		// there's nothing left to animate once it's reached zero
+		if left <= 0 {
+			break
+		}
+	}
+
+	return nil
+}
+
+// FormatCountdown formats the time left as a countdown shows it. The
+// seconds are rounded up, so that it only shows zero once the time is
+// up.
+func FormatCountdown(left time.Duration) string {
+	secs := int64(0)
+	if left > 0 {
+		secs = int64((left + time.Second - 1) / time.Second)
+	}
+
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+// left returns the time left in frame frameIdx.
+func (c *Countdown) left(frameIdx int) time.Duration {
+	return c.TargetTime.Sub(c.start.Add(time.Duration(frameIdx) * c.delay))
+}
+
+// frame returns the text shown in frame frameIdx. Frames past the end of
+// the countdown's own animation, when something else animates for longer,
+// keep counting down.
+func (c *Countdown) frame(frameIdx int) *Text {
+	if frameIdx < len(c.frames) {
+		return c.frames[max(frameIdx, 0)]
+	}
+
+	t := &Text{Content: FormatCountdown(c.left(frameIdx)), Font: c.Font, Color: c.Color}
+	if err := t.Init(); err != nil {
+		// the font was loaded fine in Init
+		return c.frames[len(c.frames)-1]
+	}
+	return t
+}
+
+func (c *Countdown) PaintBounds(bounds image.Rectangle, frameIdx int) image.Rectangle {
+	return c.frame(frameIdx).PaintBounds(bounds, frameIdx)
+}
+
+func (c *Countdown) Paint(dc *gg.Context, bounds image.Rectangle, frameIdx int) {
+	c.frame(frameIdx).Paint(dc, bounds, frameIdx)
+}
+
+func (c *Countdown) FrameCount() int {
+	return len(c.frames)
+}
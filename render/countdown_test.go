@@ -0,0 +1,92 @@
+package render
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatCountdown(t *testing.T) {
+	for _, tt := range []struct {
+		left time.Duration
+		text string
+	}{
+		{-time.Hour, "0:00"},
+		{0, "0:00"},
+		{time.Millisecond, "0:01"},
+		{59 * time.Second, "0:59"},
+		{59*time.Second + time.Millisecond, "1:00"},
+		{5 * time.Minute, "5:00"},
+		{time.Hour - time.Second, "59:59"},
+		{time.Hour, "1:00:00"},
+		{26*time.Hour + 3*time.Minute + 4*time.Second, "26:03:04"},
+	} {
+		assert.Equal(t, tt.text, FormatCountdown(tt.left), "%s", tt.left)
+	}
+}
+
+// countdownText returns the text a countdown shows in a frame.
+func countdownText(c *Countdown, frameIdx int) string {
+	return c.frame(frameIdx).Content
+}
+
+func TestCountdownTicksWithFrames(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	c := &Countdown{
+		TargetTime: now.Add(90*time.Second + 10*time.Millisecond),
+		Now:        now,
+		Delay:      100,
+		Duration:   3,
+	}
+	require.NoError(t, c.Init())
+
+	assert.Equal(t, 30, c.FrameCount())
+	assert.Equal(t, "1:31", countdownText(c, 0))
+	assert.Equal(t, "1:30", countdownText(c, 1))
+	assert.Equal(t, "1:30", countdownText(c, 10))
+	assert.Equal(t, "1:29", countdownText(c, 11))
+	assert.Equal(t, "1:28", countdownText(c, 29))
+
+	// frames that show the same time share their text
+	assert.Same(t, c.frame(1), c.frame(10))
+
+	// past its own frames, it keeps counting
+	assert.Equal(t, "1:27", countdownText(c, 31))
+	assert.Equal(t, "0:00", countdownText(c, 1000))
+
+	b := c.PaintBounds(image.Rect(0, 0, 64, 32), 0)
+	assert.Equal(t, 8, b.Dy())
+}
+
+func TestCountdownStopsAtZero(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	// the animation ends once it's reached zero
+	c := &Countdown{TargetTime: now.Add(time.Second), Now: now}
+	require.NoError(t, c.Init())
+	assert.Equal(t, 21, c.FrameCount())
+	assert.Equal(t, "0:01", countdownText(c, 0))
+	assert.Equal(t, "0:00", countdownText(c, 20))
+
+	// and is a single frame when the time is already up
+	c = &Countdown{TargetTime: now.Add(-time.Minute), Now: now}
+	require.NoError(t, c.Init())
+	assert.Equal(t, 1, c.FrameCount())
+	assert.Equal(t, "0:00", countdownText(c, 0))
+
+	// without a duration, 15 seconds are animated
+	c = &Countdown{TargetTime: now.Add(time.Hour), Now: now}
+	require.NoError(t, c.Init())
+	assert.Equal(t, 300, c.FrameCount())
+	assert.Equal(t, "1:00:00", countdownText(c, 0))
+	assert.Equal(t, "59:46", countdownText(c, 299))
+}
+
+func TestCountdownBadFont(t *testing.T) {
+	c := &Countdown{TargetTime: time.Now(), Font: "comic-sans"}
+	assert.Error(t, c.Init())
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/tidbyt/gg"
+)
+
+// FitTextFonts are the fonts FitText tries when none are given, largest
+// first. They're the built-in fonts that stay legible at their size.
+var FitTextFonts = []string{"10x20", "6x13", "6x10", "tb-8", "tom-thumb"}
+
+// FitText draws a string of text in the largest font it fits in.
+//
+// Fonts are tried in the order of the `fonts` list, which should go
+// from largest to smallest, and the first the text fits in, within
+// `width` and `height`, is used. Without a list, the built-in fonts
+// are tried, from `10x20` down to `tom-thumb`. The `height` is that
+// of the display if it's not given.
+//
+// Text that doesn't fit in any of the fonts is drawn in the smallest
+// and clipped, or, if `marquee` is set, scrolled in the largest font
+// that fits the height.
+//
+// DOC(Content): The text string to draw
+// DOC(Width): Width the text has to fit in
+// DOC(Height): Height the text has to fit in
+// DOC(Fonts): Fonts to try, largest first
+// DOC(Color): Desired font color
+// DOC(Marquee): Scroll text that doesn't fit, rather than clip it
+//
+// EXAMPLE BEGIN
+// render.FitText(content="Tidbyt!", width=64, height=16, color="#099")
+// EXAMPLE END
+type FitText struct {
+	Widget
+	Content string `starlark:"content,required"`
+	Width   int    `starlark:"width,required"`
+	Height  int
+	Fonts   []string
+	Color   color.Color
+	Marquee bool
+
+	font  string
+	child Widget
+}
+
+func (ft *FitText) Init() error {
+	fonts := ft.Fonts
+	if len(fonts) == 0 {
+		fonts = FitTextFonts
+	}
+
+	height := ft.Height
+	if height <= 0 {
+		height = FrameHeight
+	}
+
+	// the largest and smallest fonts that fit the height, for when the
+	// text doesn't fit in any
+	var largest, smallest *Text
+
+	for _, name := range fonts {
+		t := &Text{Content: ft.Content, Font: name, Color: ft.Color}
+		if err := t.Init(); err != nil {
+			return err
+		}
+
+		w, h := t.Size()
+		if h > height {
+			continue
+		}
+		if w <= ft.Width {
+			ft.font, ft.child = name, t
+			return nil
+		}
+
+		if largest == nil {
+			largest = t
+		}
+		smallest = t
+	}
+
+	switch {
+	case smallest == nil:
+		// nothing fits the height either, so it's clipped in the last font
+		t := &Text{Content: ft.Content, Font: fonts[len(fonts)-1], Color: ft.Color}
+		if err := t.Init(); err != nil {
+			return err
+		}
+		ft.font, ft.child = t.Font, t
+	case ft.Marquee:
+		ft.font, ft.child = largest.Font, &Marquee{Child: largest, Width: ft.Width}
+	default:
+		ft.font, ft.child = smallest.Font, smallest
+	}
+
+	return nil
+}
+
+// Font returns the name of the font the text is drawn in.
+func (ft *FitText) Font() string {
+	return ft.font
+}
+
+func (ft *FitText) PaintBounds(bounds image.Rectangle, frameIdx int) image.Rectangle {
+	cb := ft.child.PaintBounds(bounds, frameIdx)
+	return image.Rect(0, 0, min(cb.Dx(), ft.Width), cb.Dy())
+}
+
+func (ft *FitText) Paint(dc *gg.Context, bounds image.Rectangle, frameIdx int) {
+	pb := ft.PaintBounds(bounds, frameIdx)
+
+	dc.Push()
+	dc.DrawRectangle(0, 0, float64(pb.Dx()), float64(pb.Dy()))
+	dc.Clip()
+	ft.child.Paint(dc, bounds, frameIdx)
+	dc.Pop()
+}
+
+func (ft *FitText) FrameCount() int {
+	return ft.child.FrameCount()
+}
@@ -0,0 +1,68 @@
+package render
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFitTextPicksLargestFont(t *testing.T) {
+	for _, tt := range []struct {
+		content string
+		width   int
+		height  int
+		font    string
+	}{
+		{"Hi", 64, 0, "10x20"},
+		{"Tidbyt!", 64, 16, "6x13"},
+		{"Tidbyt!", 40, 16, "tb-8"},
+		{"Ada Lovelace", 64, 0, "tb-8"},
+		{"Ada Lovelace", 50, 8, "tom-thumb"},
+	} {
+		ft := &FitText{Content: tt.content, Width: tt.width, Height: tt.height}
+		require.NoError(t, ft.Init())
+		assert.Equal(t, tt.font, ft.Font(), "%q in %dx%d", tt.content, tt.width, tt.height)
+
+		b := ft.PaintBounds(image.Rect(0, 0, 64, 32), 0)
+		assert.LessOrEqual(t, b.Dx(), tt.width)
+		assert.Equal(t, 1, ft.FrameCount())
+	}
+}
+
+func TestFitTextDoesNotFit(t *testing.T) {
+	content := "Grace Hopper and Ada Lovelace"
+
+	// clipped in the smallest font
+	ft := &FitText{Content: content, Width: 64}
+	require.NoError(t, ft.Init())
+	assert.Equal(t, "tom-thumb", ft.Font())
+	assert.Equal(t, image.Rect(0, 0, 64, 6), ft.PaintBounds(image.Rect(0, 0, 64, 32), 0))
+	assert.Equal(t, 1, ft.FrameCount())
+
+	im := PaintWidget(ft, image.Rect(0, 0, 64, 32), 0)
+	assert.Equal(t, 64, im.Bounds().Dx())
+
+	// or scrolled in the largest that fits the height
+	ft = &FitText{Content: content, Width: 64, Height: 10, Marquee: true}
+	require.NoError(t, ft.Init())
+	assert.Equal(t, "6x10", ft.Font())
+	assert.Equal(t, image.Rect(0, 0, 64, 10), ft.PaintBounds(image.Rect(0, 0, 64, 32), 0))
+	assert.Greater(t, ft.FrameCount(), 1)
+
+	// fonts too tall for the box are used anyway, rather than drawing
+	// nothing
+	ft = &FitText{Content: "Hi", Width: 64, Height: 4, Fonts: []string{"6x13", "5x8"}}
+	require.NoError(t, ft.Init())
+	assert.Equal(t, "5x8", ft.Font())
+}
+
+func TestFitTextFonts(t *testing.T) {
+	ft := &FitText{Content: "Mallory", Width: 64, Fonts: []string{"tb-8", "10x20"}}
+	require.NoError(t, ft.Init())
+	assert.Equal(t, "tb-8", ft.Font())
+
+	ft = &FitText{Content: "Mallory", Width: 64, Fonts: []string{"comic-sans"}}
+	assert.Error(t, ft.Init())
+}
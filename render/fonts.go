@@ -5,6 +5,7 @@ package render
 import (
 	"encoding/base64"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/zachomedia/go-bdf"
@@ -19,6 +20,7 @@ func GetFontList() []string {
 	for key := range fontDataRaw {
 		fontNames = append(fontNames, key)
 	}
+	sort.Strings(fontNames)
 	return fontNames
 }
 
@@ -0,0 +1,313 @@
+// Package golden compares rendered frames against golden images, for
+// testing widgets and apps.
+//
+// Goldens are PNGs for single frames and GIFs for animations. Set
+// PIXLET_UPDATE_GOLDEN=1 when running tests that use Assert to write the
+// frames they got as the new goldens instead of comparing.
+package golden
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// UpdateEnv is the environment variable that makes Assert write goldens
+// instead of comparing against them.
+const UpdateEnv = "PIXLET_UPDATE_GOLDEN"
+
+// Options are how different frames can be from their goldens and still
+// match. The zero value only matches identical frames.
+type Options struct {
+	// Tolerance is how much each of a pixel's channels, from 0 to 255, may
+	// differ for the pixel to match.
+	Tolerance uint8
+
+	// Perceptual, if set, compares pixels by how different they look
+	// instead: their distance in CIELAB (ΔE*76), where about 2.3 is
+	// barely noticeable, must be at most Perceptual. Tolerance is ignored.
+	Perceptual float64
+
+	// MaxDiffPixels is how many pixels of each frame may not match.
+	MaxDiffPixels int
+}
+
+// Diff is how frames differ from their goldens.
+type Diff struct {
+	// Frame is the index of the first frame that doesn't match.
+	Frame int
+
+	// Pixels is how many of its pixels don't match, and First is the
+	// first of them.
+	Pixels int
+	First  image.Point
+
+	// MaxDelta is the biggest difference between any of its pixels and
+	// the golden's, in channel values or ΔE if comparing perceptually.
+	MaxDelta float64
+
+	// Reason is set if the frames couldn't be compared pixel by pixel,
+	// such as if there are more of them than goldens.
+	Reason string
+}
+
+func (d *Diff) Error() string {
+	if d.Reason != "" {
+		return d.Reason
+	}
+	return fmt.Sprintf(
+		"frame %d: %d pixels differ from the golden, first at %d,%d, by up to %.4g",
+		d.Frame, d.Pixels, d.First.X, d.First.Y, d.MaxDelta,
+	)
+}
+
+// CompareFrames compares frames against their goldens, returning how the
+// first one that doesn't match differs, or nil if they all match.
+func CompareFrames(want, got []image.Image, opts Options) *Diff {
+	if len(want) != len(got) {
+		return &Diff{Reason: fmt.Sprintf("expected %d frames, got %d", len(want), len(got))}
+	}
+
+	for i := range want {
+		wb, gb := want[i].Bounds(), got[i].Bounds()
+		if wb.Size() != gb.Size() {
+			return &Diff{Frame: i, Reason: fmt.Sprintf("frame %d: expected %dx%d, got %dx%d", i, wb.Dx(), wb.Dy(), gb.Dx(), gb.Dy())}
+		}
+
+		d := Diff{Frame: i}
+		for y := 0; y < wb.Dy(); y++ {
+			for x := 0; x < wb.Dx(); x++ {
+				w := color.NRGBAModel.Convert(want[i].At(wb.Min.X+x, wb.Min.Y+y)).(color.NRGBA)
+				g := color.NRGBAModel.Convert(got[i].At(gb.Min.X+x, gb.Min.Y+y)).(color.NRGBA)
+
+				var delta float64
+				var differs bool
+				if opts.Perceptual > 0 {
+					delta = DeltaE(w, g)
+					differs = delta > opts.Perceptual
+				} else {
+					delta = channelDelta(w, g)
+					differs = delta > float64(opts.Tolerance)
+				}
+
+				if differs {
+					if d.Pixels == 0 {
+						d.First = image.Pt(x, y)
+					}
+					d.Pixels++
+				}
+				d.MaxDelta = math.Max(d.MaxDelta, delta)
+			}
+		}
+		if d.Pixels > opts.MaxDiffPixels {
+			return &d
+		}
+	}
+	return nil
+}
+
+func channelDelta(a, b color.NRGBA) float64 {
+	abs := func(a, b uint8) float64 {
+		return math.Abs(float64(a) - float64(b))
+	}
+	return max(abs(a.R, b.R), abs(a.G, b.G), abs(a.B, b.B), abs(a.A, b.A))
+}
+
+// DeltaE is the CIE76 color difference between a and b, once they're
+// composited onto black, as they are on a device.
+func DeltaE(a, b color.NRGBA) float64 {
+	l1, a1, b1 := lab(a)
+	l2, a2, b2 := lab(b)
+	return math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (b1-b2)*(b1-b2))
+}
+
+// lab converts an sRGB color to CIELAB, with a D65 white point.
+func lab(c color.NRGBA) (l, a, b float64) {
+	linear := func(v uint8) float64 {
+		f := float64(v) / 255 * float64(c.A) / 255
+		if f <= 0.04045 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	r, g, bl := linear(c.R), linear(c.G), linear(c.B)
+
+	x := (0.4124*r + 0.3576*g + 0.1805*bl) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*bl
+	z := (0.0193*r + 0.1192*g + 0.9505*bl) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// LoadGolden reads the frames of the golden at path.
+func LoadGolden(path string) ([]image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	frames, err := DecodeGolden(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading golden %s: %w", path, err)
+	}
+	return frames, nil
+}
+
+// DecodeGolden reads the frames of a golden PNG or GIF.
+func DecodeGolden(r io.Reader) ([]image.Image, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("reading image: %w", err)
+	}
+
+	switch {
+	case bytes.Equal(magic, []byte("\x89PNG")):
+		im, err := png.Decode(br)
+		if err != nil {
+			return nil, err
+		}
+		return []image.Image{im}, nil
+
+	case bytes.Equal(magic[:3], []byte("GIF")):
+		g, err := gif.DecodeAll(br)
+		if err != nil {
+			return nil, err
+		}
+		return gifFrames(g), nil
+
+	default:
+		return nil, fmt.Errorf("goldens must be PNGs or GIFs")
+	}
+}
+
+// gifFrames composites each frame of an animated GIF onto what was shown
+// before it, since frames can be smaller than the image.
+func gifFrames(g *gif.GIF) []image.Image {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	canvas := image.NewNRGBA(bounds)
+
+	frames := make([]image.Image, 0, len(g.Image))
+	for i, frame := range g.Image {
+		var previous *image.NRGBA
+		if i < len(g.Disposal) && g.Disposal[i] == gif.DisposalPrevious {
+			previous = image.NewNRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, image.Point{}, draw.Src)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		shown := image.NewNRGBA(bounds)
+		draw.Draw(shown, bounds, canvas, image.Point{}, draw.Src)
+		frames = append(frames, shown)
+
+		if i < len(g.Disposal) {
+			switch g.Disposal[i] {
+			case gif.DisposalBackground:
+				draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+			case gif.DisposalPrevious:
+				canvas = previous
+			}
+		}
+	}
+	return frames
+}
+
+// WriteGolden writes frames as the golden at path: a PNG if there's one
+// frame, or a GIF if there are more. Frames of a GIF can't have more than
+// 256 colors, but they're kept exactly.
+func WriteGolden(path string, frames []image.Image) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to write")
+	}
+
+	var buf bytes.Buffer
+	if len(frames) == 1 {
+		if err := png.Encode(&buf, frames[0]); err != nil {
+			return err
+		}
+	} else {
+		g := &gif.GIF{}
+		for i, frame := range frames {
+			paletted, err := exactPaletted(frame)
+			if err != nil {
+				return fmt.Errorf("frame %d: %w", i, err)
+			}
+			g.Image = append(g.Image, paletted)
+			g.Delay = append(g.Delay, 0)
+			g.Disposal = append(g.Disposal, gif.DisposalBackground)
+		}
+		if err := gif.EncodeAll(&buf, g); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// exactPaletted converts im to a paletted image with exactly its colors.
+func exactPaletted(im image.Image) (*image.Paletted, error) {
+	b := im.Bounds()
+	out := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), nil)
+	index := map[color.NRGBA]uint8{}
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := color.NRGBAModel.Convert(im.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			if c.A == 0 {
+				c = color.NRGBA{}
+			}
+			i, ok := index[c]
+			if !ok {
+				if len(out.Palette) == 256 {
+					return nil, fmt.Errorf("more than 256 colors can't be kept in a GIF")
+				}
+				i = uint8(len(out.Palette))
+				index[c] = i
+				out.Palette = append(out.Palette, c)
+			}
+			out.SetColorIndex(x, y, i)
+		}
+	}
+	return out, nil
+}
+
+// Assert fails t if frames don't match the golden at path. With
+// PIXLET_UPDATE_GOLDEN set, it writes them as the golden instead.
+func Assert(t testing.TB, path string, frames []image.Image, opts Options) {
+	t.Helper()
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := WriteGolden(path, frames); err != nil {
+			t.Fatalf("writing golden: %v", err)
+		}
+		return
+	}
+
+	want, err := LoadGolden(path)
+	if err != nil {
+		t.Fatalf("%v (set %s=1 to write it)", err, UpdateEnv)
+	}
+	if diff := CompareFrames(want, frames, opts); diff != nil {
+		t.Errorf("%s: %v", path, diff)
+	}
+}
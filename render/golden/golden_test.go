@@ -0,0 +1,104 @@
+package golden
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func solid(c color.Color) image.Image {
+	im := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			im.Set(x, y, c)
+		}
+	}
+	return im
+}
+
+func TestCompareFrames(t *testing.T) {
+	red := solid(color.NRGBA{0xff, 0, 0, 0xff})
+	redder := solid(color.NRGBA{0xfc, 0, 0, 0xff})
+	blue := solid(color.NRGBA{0, 0, 0xff, 0xff})
+
+	assert.Nil(t, CompareFrames([]image.Image{red}, []image.Image{red}, Options{}))
+
+	diff := CompareFrames([]image.Image{red, red}, []image.Image{red, redder}, Options{})
+	require.NotNil(t, diff)
+	assert.Equal(t, 1, diff.Frame)
+	assert.Equal(t, 8, diff.Pixels)
+	assert.Equal(t, image.Pt(0, 0), diff.First)
+	assert.Equal(t, 3.0, diff.MaxDelta)
+	assert.Nil(t, CompareFrames([]image.Image{red}, []image.Image{redder}, Options{Tolerance: 3}))
+
+	// a slightly different red looks the same, but blue doesn't
+	assert.Nil(t, CompareFrames([]image.Image{red}, []image.Image{redder}, Options{Perceptual: 2.3}))
+	assert.NotNil(t, CompareFrames([]image.Image{red}, []image.Image{blue}, Options{Perceptual: 2.3}))
+
+	// a few pixels can be allowed to differ
+	speck := image.NewNRGBA(red.Bounds())
+	copy(speck.Pix, red.(*image.NRGBA).Pix)
+	speck.Set(2, 1, color.White)
+	diff = CompareFrames([]image.Image{red}, []image.Image{speck}, Options{})
+	require.NotNil(t, diff)
+	assert.Equal(t, image.Pt(2, 1), diff.First)
+	assert.Equal(t, "frame 0: 1 pixels differ from the golden, first at 2,1, by up to 255", diff.Error())
+	assert.Nil(t, CompareFrames([]image.Image{red}, []image.Image{speck}, Options{MaxDiffPixels: 1}))
+
+	diff = CompareFrames([]image.Image{red}, []image.Image{red, red}, Options{})
+	require.NotNil(t, diff)
+	assert.Equal(t, "expected 1 frames, got 2", diff.Error())
+
+	diff = CompareFrames([]image.Image{red}, []image.Image{image.NewNRGBA(image.Rect(0, 0, 2, 2))}, Options{})
+	require.NotNil(t, diff)
+	assert.Equal(t, "frame 0: expected 4x2, got 2x2", diff.Error())
+}
+
+func TestWriteAndLoadGolden(t *testing.T) {
+	dir := t.TempDir()
+	red := solid(color.NRGBA{0xff, 0, 0, 0xff})
+	blue := solid(color.NRGBA{0, 0, 0xff, 0xff})
+	half := solid(color.NRGBA{0x12, 0x34, 0x56, 0x80})
+
+	// a single frame is a PNG
+	path := filepath.Join(dir, "single.png")
+	require.NoError(t, WriteGolden(path, []image.Image{half}))
+	frames, err := LoadGolden(path)
+	require.NoError(t, err)
+	assert.Nil(t, CompareFrames([]image.Image{half}, frames, Options{}))
+
+	// animations are GIFs, with their colors kept exactly
+	path = filepath.Join(dir, "nested", "animation.gif")
+	require.NoError(t, WriteGolden(path, []image.Image{red, blue, red}))
+	frames, err = LoadGolden(path)
+	require.NoError(t, err)
+	assert.Nil(t, CompareFrames([]image.Image{red, blue, red}, frames, Options{}))
+
+	// unless there are too many of them
+	many := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for i := 0; i < 32*32; i++ {
+		many.Pix[i*4], many.Pix[i*4+1], many.Pix[i*4+3] = uint8(i), uint8(i>>8), 0xff
+	}
+	assert.Error(t, WriteGolden(filepath.Join(dir, "many.gif"), []image.Image{many, many}))
+
+	_, err = DecodeGolden(bytes.NewReader([]byte("RIFF....WEBP")))
+	assert.Error(t, err)
+	_, err = LoadGolden(filepath.Join(dir, "missing.png"))
+	assert.Error(t, err)
+}
+
+func TestAssert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.png")
+	red := solid(color.NRGBA{0xff, 0, 0, 0xff})
+
+	t.Setenv(UpdateEnv, "1")
+	Assert(t, path, []image.Image{red}, Options{})
+
+	t.Setenv(UpdateEnv, "")
+	Assert(t, path, []image.Image{red}, Options{})
+}
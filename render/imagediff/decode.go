@@ -0,0 +1,95 @@
+package imagediff
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"os"
+	"time"
+
+	"tidbyt.dev/pixlet/render/golden"
+)
+
+// defaultDelay is how long frames of animations with no delay are shown,
+// as browsers do.
+const defaultDelay = 100 * time.Millisecond
+
+// Animation is the frames of a render, and how long each is shown. A still
+// image has a single frame, shown for no time.
+type Animation struct {
+	Frames []image.Image
+	Delays []time.Duration
+}
+
+// Duration is how long the animation lasts.
+func (a *Animation) Duration() time.Duration {
+	var d time.Duration
+	for _, delay := range a.Delays {
+		d += delay
+	}
+	return d
+}
+
+// Load reads a WebP, GIF or PNG render.
+func Load(path string) (*Animation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return a, nil
+}
+
+// Decode decodes a WebP, GIF or PNG render.
+func Decode(data []byte) (*Animation, error) {
+	var a *Animation
+	switch {
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		var err error
+		if a, err = decodeWebP(data); err != nil {
+			return nil, err
+		}
+
+	case bytes.HasPrefix(data, []byte("GIF")):
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		// goldens composite frames the way GIFs are shown
+		frames, err := golden.DecodeGolden(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		a = &Animation{Frames: frames}
+		for _, delay := range g.Delay {
+			a.Delays = append(a.Delays, time.Duration(delay)*10*time.Millisecond)
+		}
+
+	case bytes.HasPrefix(data, []byte("\x89PNG")):
+		frames, err := golden.DecodeGolden(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		a = &Animation{Frames: frames, Delays: []time.Duration{0}}
+
+	default:
+		return nil, fmt.Errorf("not a WebP, GIF or PNG image")
+	}
+
+	if len(a.Frames) == 0 {
+		return nil, fmt.Errorf("image has no frames")
+	}
+	if len(a.Frames) > 1 {
+		for i, delay := range a.Delays {
+			if delay <= 0 {
+				a.Delays[i] = defaultDelay
+			}
+		}
+	}
+	return a, nil
+}
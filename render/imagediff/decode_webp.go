@@ -0,0 +1,33 @@
+//go:build !js && !wasm
+
+package imagediff
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/tronbyt/go-libwebp/webp"
+)
+
+func decodeWebP(data []byte) (*Animation, error) {
+	decoder, err := webp.NewAnimationDecoder(data)
+	if err != nil {
+		return nil, fmt.Errorf("creating animation decoder: %v", err)
+	}
+
+	img, err := decoder.Decode()
+	if err != nil {
+		return nil, fmt.Errorf("decoding image data: %v", err)
+	}
+
+	// timestamps are when each frame ends
+	a := &Animation{}
+	previous := 0
+	for i, im := range img.Image {
+		a.Frames = append(a.Frames, image.Image(im))
+		a.Delays = append(a.Delays, time.Duration(img.Timestamp[i]-previous)*time.Millisecond)
+		previous = img.Timestamp[i]
+	}
+	return a, nil
+}
@@ -0,0 +1,11 @@
+//go:build js && wasm
+
+package imagediff
+
+import (
+	"fmt"
+)
+
+func decodeWebP(data []byte) (*Animation, error) {
+	return nil, fmt.Errorf("WebP not supported in WASM")
+}
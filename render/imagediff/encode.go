@@ -0,0 +1,50 @@
+package imagediff
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+
+	"github.com/ericpauley/go-quantize/quantize"
+	"tidbyt.dev/pixlet/encode"
+)
+
+// EncodeHighlights encodes the highlights of the result's spans, scaled up
+// by magnify: as a PNG if there's only one, or an animated GIF otherwise.
+// It returns the image and its MIME type.
+func (r *Result) EncodeHighlights(magnify int) ([]byte, string, error) {
+	var frames []image.Image
+	for _, span := range r.Spans {
+		frame, err := encode.Magnify(magnify)(span.Highlight)
+		if err != nil {
+			return nil, "", err
+		}
+		frames = append(frames, frame)
+	}
+
+	var buf bytes.Buffer
+	if len(frames) == 1 {
+		if err := png.Encode(&buf, frames[0]); err != nil {
+			return nil, "", fmt.Errorf("encoding: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+
+	g := &gif.GIF{}
+	for i, frame := range frames {
+		palette := quantize.MedianCutQuantizer{}.Quantize(make([]color.Color, 0, 256), frame)
+		paletted := image.NewPaletted(frame.Bounds(), palette)
+		draw.Draw(paletted, frame.Bounds(), frame, image.Point{}, draw.Src)
+
+		g.Image = append(g.Image, paletted)
+		g.Delay = append(g.Delay, int(r.Spans[i].Duration.Milliseconds()/10)) // in 100ths of a second
+	}
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		return nil, "", fmt.Errorf("encoding: %w", err)
+	}
+	return buf.Bytes(), "image/gif", nil
+}
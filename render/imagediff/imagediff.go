@@ -0,0 +1,184 @@
+// Package imagediff compares renders of apps, for reviewing changes to
+// widgets and fonts. Frames are aligned by when they're shown, changed
+// pixels are highlighted, and the difference is scored by how different it
+// looks.
+package imagediff
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"tidbyt.dev/pixlet/render/golden"
+)
+
+// JustNoticeable is the color difference, in ΔE, that people can only just
+// tell apart. Pixels that differ by more are counted as changed.
+const JustNoticeable = 2.3
+
+// Span is a time during which the same frames of both renders are shown,
+// and how they differ.
+type Span struct {
+	// Old and New are the indexes of the frames shown.
+	Old, New int
+
+	Start, Duration time.Duration
+
+	// Changed is how many pixels differ noticeably, and MaxDelta the
+	// biggest difference between any pixels, in ΔE.
+	Changed  int
+	MaxDelta float64
+
+	// Highlight shows the old frame, the new one, and the changes between
+	// them side by side.
+	Highlight image.Image
+}
+
+// Result is how two renders differ.
+type Result struct {
+	Spans []Span
+
+	// Score is the mean ΔE between the renders' pixels, weighted by how
+	// long they're shown: zero for renders that look the same, and about
+	// 100 between black and white.
+	Score float64
+
+	// Changed is the fraction of pixels, weighted by how long they're
+	// shown, that differ noticeably.
+	Changed float64
+
+	// MaxDelta is the biggest difference between any pixels, in ΔE.
+	MaxDelta float64
+}
+
+// Identical reports whether the renders have exactly the same pixels.
+func (r *Result) Identical() bool {
+	return r.MaxDelta == 0
+}
+
+// Compare compares two renders of the same size. Their frames are aligned
+// by time, so that renders with different frame rates are compared by
+// what's on screen. If one render is shorter than the other, its last frame
+// is held, and a still image is shown throughout the other's animation.
+func Compare(old, new *Animation) (*Result, error) {
+	ob, nb := old.Frames[0].Bounds(), new.Frames[0].Bounds()
+	if ob.Size() != nb.Size() {
+		return nil, fmt.Errorf("old render is %dx%d but new one is %dx%d", ob.Dx(), ob.Dy(), nb.Dx(), nb.Dy())
+	}
+
+	spans := align(old, new)
+	var total time.Duration
+	for _, span := range spans {
+		total += span.Duration
+	}
+
+	result := &Result{}
+	pixels := float64(ob.Dx() * ob.Dy())
+	for _, span := range spans {
+		mean := compareFrames(old.Frames[span.Old], new.Frames[span.New], &span)
+
+		// weight each span by how long it's shown, or equally if both
+		// renders are still images
+		weight := 1.0
+		if total > 0 {
+			weight = float64(span.Duration) / float64(total)
+		}
+		result.Score += mean * weight
+		result.Changed += float64(span.Changed) / pixels * weight
+		result.MaxDelta = math.Max(result.MaxDelta, span.MaxDelta)
+		result.Spans = append(result.Spans, span)
+	}
+	return result, nil
+}
+
+// align returns the spans of time in which neither render changes frames.
+func align(old, new *Animation) []Span {
+	oldEnds, newEnds := ends(old), ends(new)
+	total := max(old.Duration(), new.Duration())
+
+	var spans []Span
+	var start time.Duration
+	i, j := 0, 0
+	for {
+		end := min(oldEnds[i], newEnds[j], total)
+		spans = append(spans, Span{Old: i, New: j, Start: start, Duration: end - start})
+		if end >= total {
+			return spans
+		}
+
+		start = end
+		if oldEnds[i] == end {
+			i++
+		}
+		if newEnds[j] == end {
+			j++
+		}
+	}
+}
+
+// ends returns when each frame of an animation stops being shown. The last
+// frame is held indefinitely.
+func ends(a *Animation) []time.Duration {
+	ends := make([]time.Duration, len(a.Frames))
+	var t time.Duration
+	for i := range a.Frames {
+		t += a.Delays[i]
+		ends[i] = t
+	}
+	ends[len(ends)-1] = math.MaxInt64
+	return ends
+}
+
+// compareFrames compares two frames shown during span, setting how they
+// differ, and returns the mean difference between their pixels.
+//
+// The highlight shows the frames and the noticeable changes between them
+// side by side, separated by gray lines. Changes are drawn in magenta over
+// a dimmed copy of the new frame, brighter the more noticeable they are.
+func compareFrames(old, new image.Image, span *Span) float64 {
+	ob, nb := old.Bounds(), new.Bounds()
+	w, h := ob.Dx(), ob.Dy()
+
+	out := image.NewRGBA(image.Rect(0, 0, 3*w+2, h))
+	gray := color.RGBA{0x80, 0x80, 0x80, 0xff}
+	for y := 0; y < h; y++ {
+		out.SetRGBA(w, y, gray)
+		out.SetRGBA(2*w+1, y, gray)
+	}
+
+	var sum float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			o := opaque(old.At(ob.Min.X+x, ob.Min.Y+y))
+			n := opaque(new.At(nb.Min.X+x, nb.Min.Y+y))
+			out.Set(x, y, o)
+			out.Set(w+1+x, y, n)
+
+			delta := golden.DeltaE(o, n)
+			sum += delta
+			span.MaxDelta = math.Max(span.MaxDelta, delta)
+
+			var c color.RGBA
+			if delta > JustNoticeable {
+				span.Changed++
+				level := uint8(0x60 + min(delta/50, 1)*0x9f)
+				c = color.RGBA{level, 0, level, 0xff}
+			} else {
+				luma := uint8((299*uint32(n.R) + 587*uint32(n.G) + 114*uint32(n.B)) / 1000 / 4)
+				c = color.RGBA{luma, luma, luma, 0xff}
+			}
+			out.SetRGBA(2*w+2+x, y, c)
+		}
+	}
+
+	span.Highlight = out
+	return sum / float64(w*h)
+}
+
+// opaque returns a color as it's shown on a device: composited onto black.
+func opaque(c color.Color) color.NRGBA {
+	r, g, b, _ := c.RGBA()
+	return color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xff}
+}
@@ -0,0 +1,155 @@
+package imagediff
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/encode"
+)
+
+var (
+	red   = color.RGBA{0xff, 0, 0, 0xff}
+	blue  = color.RGBA{0, 0, 0xff, 0xff}
+	green = color.RGBA{0, 0xff, 0, 0xff}
+)
+
+func solid(c color.RGBA) *image.RGBA {
+	im := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			im.SetRGBA(x, y, c)
+		}
+	}
+	return im
+}
+
+func animation(delay time.Duration, colors ...color.RGBA) *Animation {
+	a := &Animation{}
+	for _, c := range colors {
+		a.Frames = append(a.Frames, solid(c))
+		a.Delays = append(a.Delays, delay)
+	}
+	return a
+}
+
+func TestCompareIdentical(t *testing.T) {
+	r, err := Compare(animation(50*time.Millisecond, red, blue), animation(50*time.Millisecond, red, blue))
+	require.NoError(t, err)
+	assert.True(t, r.Identical())
+	assert.Equal(t, 0.0, r.Score)
+	assert.Len(t, r.Spans, 2)
+}
+
+func TestCompareAlignsFrames(t *testing.T) {
+	old := animation(100*time.Millisecond, red, blue)
+	new := animation(50*time.Millisecond, red, red, blue, green)
+
+	r, err := Compare(old, new)
+	require.NoError(t, err)
+	require.Len(t, r.Spans, 4)
+
+	for i, want := range []Span{
+		{Old: 0, New: 0, Start: 0, Duration: 50 * time.Millisecond},
+		{Old: 0, New: 1, Start: 50 * time.Millisecond, Duration: 50 * time.Millisecond},
+		{Old: 1, New: 2, Start: 100 * time.Millisecond, Duration: 50 * time.Millisecond},
+		{Old: 1, New: 3, Start: 150 * time.Millisecond, Duration: 50 * time.Millisecond, Changed: 8},
+	} {
+		got := r.Spans[i]
+		assert.Equal(t, want.Old, got.Old, "span %d", i)
+		assert.Equal(t, want.New, got.New, "span %d", i)
+		assert.Equal(t, want.Start, got.Start, "span %d", i)
+		assert.Equal(t, want.Duration, got.Duration, "span %d", i)
+		assert.Equal(t, want.Changed, got.Changed, "span %d", i)
+	}
+
+	// every pixel changed for a quarter of the time
+	assert.False(t, r.Identical())
+	assert.InDelta(t, 0.25, r.Changed, 1e-9)
+	assert.InDelta(t, r.MaxDelta/4, r.Score, 1e-9)
+	assert.Greater(t, r.MaxDelta, 100.0)
+}
+
+func TestCompareStillImage(t *testing.T) {
+	still := &Animation{Frames: []image.Image{solid(red)}, Delays: []time.Duration{0}}
+
+	r, err := Compare(still, animation(100*time.Millisecond, red, blue))
+	require.NoError(t, err)
+	require.Len(t, r.Spans, 2)
+	assert.Equal(t, 0, r.Spans[0].Changed)
+	assert.Equal(t, 8, r.Spans[1].Changed)
+	assert.InDelta(t, 0.5, r.Changed, 1e-9)
+
+	r, err = Compare(still, still)
+	require.NoError(t, err)
+	require.Len(t, r.Spans, 1)
+	assert.True(t, r.Identical())
+}
+
+func TestCompareSizes(t *testing.T) {
+	big := &Animation{Frames: []image.Image{image.NewRGBA(image.Rect(0, 0, 64, 32))}, Delays: []time.Duration{0}}
+	_, err := Compare(animation(0, red), big)
+	assert.EqualError(t, err, "old render is 4x2 but new one is 64x32")
+}
+
+func TestHighlight(t *testing.T) {
+	r, err := Compare(animation(0, red), animation(0, blue))
+	require.NoError(t, err)
+
+	h := r.Spans[0].Highlight
+	assert.Equal(t, image.Rect(0, 0, 14, 2), h.Bounds())
+	assert.Equal(t, red, h.At(0, 0))
+	assert.Equal(t, color.RGBA{0x80, 0x80, 0x80, 0xff}, h.At(4, 0))
+	assert.Equal(t, blue, h.At(5, 0))
+	assert.Equal(t, color.RGBA{0xff, 0, 0xff, 0xff}, h.At(10, 1))
+
+	img, mime, err := r.EncodeHighlights(2)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", mime)
+	decoded, err := png.Decode(bytes.NewReader(img))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 28, 4), decoded.Bounds())
+
+	r, err = Compare(animation(50*time.Millisecond, red, blue), animation(50*time.Millisecond, red, green))
+	require.NoError(t, err)
+	_, mime, err = r.EncodeHighlights(1)
+	require.NoError(t, err)
+	assert.Equal(t, "image/gif", mime)
+}
+
+func TestDecode(t *testing.T) {
+	screens := func() *encode.Screens {
+		return encode.ScreensFromImages(solid(red), solid(blue), solid(green))
+	}
+
+	webp, err := screens().EncodeWebP(0)
+	require.NoError(t, err)
+	gif, err := screens().EncodeGIF(0)
+	require.NoError(t, err)
+
+	for name, data := range map[string][]byte{"webp": webp, "gif": gif} {
+		a, err := Decode(data)
+		require.NoError(t, err, name)
+		require.Len(t, a.Frames, 3, name)
+		assert.Equal(t, []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}, a.Delays, name)
+
+		r, err := Compare(animation(50*time.Millisecond, red, blue, green), a)
+		require.NoError(t, err, name)
+		assert.Equal(t, 0, r.Spans[0].Changed+r.Spans[1].Changed+r.Spans[2].Changed, name)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, solid(red)))
+	a, err := Decode(buf.Bytes())
+	require.NoError(t, err)
+	assert.Len(t, a.Frames, 1)
+	assert.Equal(t, time.Duration(0), a.Duration())
+
+	_, err = Decode([]byte("hello"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,202 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"reflect"
+
+	"github.com/tidbyt/gg"
+)
+
+// Node is a widget as it's laid out in a frame, for debugging layouts and
+// building inspectors. Nodes form a tree like the widgets do, but only hold
+// the widgets that were painted in the frame: of an Animation or Sequence,
+// only the child being shown.
+type Node struct {
+	// Type is the widget's type, such as "Text" or "Row".
+	Type string `json:"type"`
+
+	// Bounds is where the widget painted in the frame, in pixels from its
+	// top left corner.
+	Bounds Bounds `json:"bounds"`
+
+	// Color is the widget's color, for widgets that have one, as #rrggbb
+	// or #rrggbbaa.
+	Color string `json:"color,omitempty"`
+
+	// Content is the text of Text and WrappedText widgets.
+	Content string `json:"content,omitempty"`
+
+	Children []*Node `json:"children,omitempty"`
+
+	painted bool
+}
+
+// Bounds is a rectangle in a frame.
+type Bounds struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Inspect lays out the root's widgets in frame frameIdx, and returns the
+// tree of where each was painted.
+func (r Root) Inspect(frameIdx int) *Node {
+	child, node := inspectable(r.Child)
+
+	dc := gg.NewContext(FrameWidth, FrameHeight)
+	dc.Push()
+	child.Paint(dc, image.Rect(0, 0, FrameWidth, FrameHeight), frameIdx)
+	dc.Pop()
+
+	root := &Node{
+		Type:     "Root",
+		Bounds:   Bounds{Width: FrameWidth, Height: FrameHeight},
+		Children: []*Node{node},
+		painted:  true,
+	}
+	root.prune()
+	return root
+}
+
+// inspected wraps a widget to record where it's painted.
+type inspected struct {
+	Widget
+	node *Node
+}
+
+func (w *inspected) Paint(dc *gg.Context, bounds image.Rectangle, frameIdx int) {
+	if !w.node.painted {
+		// widgets painted more than once, such as the child of a Marquee
+		// that's wrapping around, are where they're first painted
+		w.node.painted = true
+		w.node.Bounds = transformedBounds(dc, w.Widget.PaintBounds(bounds, frameIdx))
+	}
+	w.Widget.Paint(dc, bounds, frameIdx)
+}
+
+var (
+	widgetType      = reflect.TypeFor[Widget]()
+	widgetSliceType = reflect.TypeFor[[]Widget]()
+	colorType       = reflect.TypeFor[color.Color]()
+)
+
+// inspectable returns a copy of w, with it and its descendants wrapped to
+// record where they're painted in the returned node.
+func inspectable(w Widget) (Widget, *Node) {
+	return wrapTree(w, func(w Widget, children []*Node) (Widget, *Node) {
+		node := &Node{Type: widgetTypeName(w), Children: children}
+
+		if s := reflect.Indirect(reflect.ValueOf(w)); s.Kind() == reflect.Struct {
+			for i := range s.NumField() {
+				field, f := s.Type().Field(i), s.Field(i)
+				switch {
+				case !field.IsExported():
+				case field.Name == "Color" && field.Type == colorType && !f.IsNil():
+					node.Color = colorHex(f.Interface().(color.Color))
+				case field.Name == "Content" && field.Type.Kind() == reflect.String:
+					node.Content = f.String()
+				}
+			}
+		}
+
+		return &inspected{Widget: w, node: node}, node
+	})
+}
+
+// wrapTree returns a copy of the tree of widgets under w, with each widget
+// replaced by what wrap returns for its copy, bottom up, along with what
+// wrap returns for w. wrap is passed what it returned for the widget's
+// children. The tree is copied so that it can be wrapped without changing
+// it for other renders.
+func wrapTree[N any](w Widget, wrap func(w Widget, children []N) (Widget, N)) (Widget, N) {
+	v := reflect.ValueOf(w)
+
+	var cp, s reflect.Value
+	switch {
+	case v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct:
+		cp = reflect.New(v.Elem().Type())
+		cp.Elem().Set(v.Elem())
+		s = cp.Elem()
+	case v.Kind() == reflect.Struct:
+		cp = reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		s = cp
+	default:
+		return wrap(w, nil)
+	}
+
+	var children []N
+	for i := range s.NumField() {
+		field, f := s.Type().Field(i), s.Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		switch {
+		case field.Type == widgetType && !f.IsNil():
+			child, childNode := wrapTree(f.Interface().(Widget), wrap)
+			f.Set(reflect.ValueOf(child))
+			children = append(children, childNode)
+
+		case field.Type == widgetSliceType:
+			wrapped := make([]Widget, f.Len())
+			for j := range wrapped {
+				var childNode N
+				wrapped[j], childNode = wrapTree(f.Index(j).Interface().(Widget), wrap)
+				children = append(children, childNode)
+			}
+			f.Set(reflect.ValueOf(wrapped))
+		}
+	}
+
+	return wrap(cp.Interface().(Widget), children)
+}
+
+// widgetTypeName returns the name of w's type, such as "Text".
+func widgetTypeName(w Widget) string {
+	return reflect.Indirect(reflect.ValueOf(w)).Type().Name()
+}
+
+// transformedBounds returns where r ends up in the frame, after dc's
+// transformations.
+func transformedBounds(dc *gg.Context, r image.Rectangle) Bounds {
+	x0, y0 := dc.TransformPoint(float64(r.Min.X), float64(r.Min.Y))
+	x1, y1 := dc.TransformPoint(float64(r.Max.X), float64(r.Max.Y))
+	x2, y2 := dc.TransformPoint(float64(r.Min.X), float64(r.Max.Y))
+	x3, y3 := dc.TransformPoint(float64(r.Max.X), float64(r.Min.Y))
+
+	minX, maxX := min(x0, x1, x2, x3), max(x0, x1, x2, x3)
+	minY, maxY := min(y0, y1, y2, y3), max(y0, y1, y2, y3)
+	return Bounds{
+		X:      int(minX),
+		Y:      int(minY),
+		Width:  int(maxX - minX),
+		Height: int(maxY - minY),
+	}
+}
+
+// prune removes the nodes of widgets that weren't painted.
+func (n *Node) prune() {
+	children := n.Children[:0]
+	for _, c := range n.Children {
+		if c.painted {
+			c.prune()
+			children = append(children, c)
+		}
+	}
+	n.Children = children
+	if len(n.Children) == 0 {
+		n.Children = nil
+	}
+}
+
+func colorHex(c color.Color) string {
+	nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if nc.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", nc.R, nc.G, nc.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", nc.R, nc.G, nc.B, nc.A)
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	text := &Text{Content: "hi"}
+	require.NoError(t, text.Init())
+
+	root := Root{
+		Child: Row{
+			Children: []Widget{
+				Box{Width: 10, Height: 5, Color: color.RGBA{0xff, 0, 0, 0xff}},
+				Padding{Pad: Insets{Left: 2, Top: 1}, Child: text},
+				Sequence{Children: []Widget{
+					Box{Width: 3, Height: 3, Color: color.NRGBA{0, 0xff, 0, 0x80}},
+					Box{Width: 6, Height: 3},
+				}},
+			},
+		},
+	}
+
+	tree := root.Inspect(0)
+	assert.Equal(t, "Root", tree.Type)
+	assert.Equal(t, Bounds{Width: 64, Height: 32}, tree.Bounds)
+
+	row := tree.Children[0]
+	assert.Equal(t, "Row", row.Type)
+	require.Len(t, row.Children, 3)
+
+	box := row.Children[0]
+	assert.Equal(t, "Box", box.Type)
+	assert.Equal(t, Bounds{Width: 10, Height: 5}, box.Bounds)
+	assert.Equal(t, "#ff0000", box.Color)
+
+	// children are where they're painted in the frame
+	padded := row.Children[1].Children[0]
+	assert.Equal(t, "Text", padded.Type)
+	assert.Equal(t, "hi", padded.Content)
+	w, h := text.Size()
+	assert.Equal(t, Bounds{X: 12, Y: 1, Width: w, Height: h}, padded.Bounds)
+
+	// only the children painted in the frame are included
+	seq := row.Children[2]
+	require.Len(t, seq.Children, 1)
+	assert.Equal(t, "#00ff0080", seq.Children[0].Color)
+	assert.Equal(t, 3, seq.Children[0].Bounds.Width)
+
+	seq = root.Inspect(1).Children[0].Children[2]
+	require.Len(t, seq.Children, 1)
+	assert.Equal(t, 6, seq.Children[0].Bounds.Width)
+
+	// the tree inspected isn't changed
+	_, ok := root.Child.(Row).Children[1].(Padding).Child.(*Text)
+	assert.True(t, ok)
+}
@@ -0,0 +1,144 @@
+package render
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"time"
+
+	"github.com/tidbyt/gg"
+)
+
+// Profile is how long painting the frames of roots took, and which widgets
+// the time went to, for finding out why an animation is slow to render.
+type Profile struct {
+	// Frames is how long each frame took to paint, in order.
+	Frames []time.Duration `json:"frames"`
+
+	// PaintTime is how long painting all the frames took.
+	PaintTime time.Duration `json:"paintTime"`
+
+	// Roots are the widgets of each root, with the time spent painting
+	// them.
+	Roots []*ProfileNode `json:"roots"`
+
+	// Slowest is the path from a root to the widget that painting took the
+	// longest in, such as ["Root", "Column", "Marquee", "Text"]. It follows
+	// the child that took the most time for as long as that child took most
+	// of its parent's time.
+	Slowest []string `json:"slowest"`
+}
+
+// ProfileNode is a widget and the time spent painting it, in all frames.
+type ProfileNode struct {
+	Type string `json:"type"`
+
+	// PaintTime is the time spent painting the widget, its children
+	// included.
+	PaintTime time.Duration `json:"paintTime"`
+
+	// Paints is how many times the widget was painted.
+	Paints int `json:"paints"`
+
+	Children []*ProfileNode `json:"children,omitempty"`
+}
+
+// MarshalJSON encodes the profile with times as strings such as "1.5ms".
+func (p *Profile) MarshalJSON() ([]byte, error) {
+	frames := make([]string, len(p.Frames))
+	for i, d := range p.Frames {
+		frames[i] = d.String()
+	}
+	return json.Marshal(struct {
+		PaintTime string         `json:"paintTime"`
+		Slowest   []string       `json:"slowest"`
+		Frames    []string       `json:"frames"`
+		Roots     []*ProfileNode `json:"roots"`
+	}{p.PaintTime.String(), p.Slowest, frames, p.Roots})
+}
+
+// MarshalJSON encodes the node with its time as a string such as "1.5ms".
+func (n *ProfileNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type      string         `json:"type"`
+		PaintTime string         `json:"paintTime"`
+		Paints    int            `json:"paints"`
+		Children  []*ProfileNode `json:"children,omitempty"`
+	}{n.Type, n.PaintTime.String(), n.Paints, n.Children})
+}
+
+// ProfileRoots paints the frames of roots, one at a time, and reports how
+// long each took and which widgets the time went to. Painting is slower
+// while it's profiled, so times are best compared with each other.
+func ProfileRoots(roots ...Root) *Profile {
+	p := &Profile{Frames: []time.Duration{}}
+
+	for _, r := range roots {
+		child, node := profiled(r.Child)
+		root := &ProfileNode{Type: "Root", Children: []*ProfileNode{node}}
+		p.Roots = append(p.Roots, root)
+
+		numFrames := min(child.FrameCount(), DefaultMaxFrameCount)
+		if r.maxFrameCount > 0 {
+			numFrames = min(child.FrameCount(), r.maxFrameCount)
+		}
+		for i := range numFrames {
+			dc := gg.NewContext(FrameWidth, FrameHeight)
+			dc.SetColor(color.Black)
+			dc.Clear()
+
+			start := time.Now()
+			dc.Push()
+			child.Paint(dc, image.Rect(0, 0, FrameWidth, FrameHeight), i)
+			dc.Pop()
+			elapsed := time.Since(start)
+
+			p.Frames = append(p.Frames, elapsed)
+			p.PaintTime += elapsed
+			root.PaintTime += elapsed
+			root.Paints++
+		}
+	}
+
+	var slowest *ProfileNode
+	for _, root := range p.Roots {
+		if slowest == nil || root.PaintTime > slowest.PaintTime {
+			slowest = root
+		}
+	}
+	for n := slowest; n != nil; {
+		p.Slowest = append(p.Slowest, n.Type)
+
+		var next *ProfileNode
+		for _, c := range n.Children {
+			if c.PaintTime*2 > n.PaintTime && (next == nil || c.PaintTime > next.PaintTime) {
+				next = c
+			}
+		}
+		n = next
+	}
+
+	return p
+}
+
+// profiling wraps a widget to time its paints.
+type profiling struct {
+	Widget
+	node *ProfileNode
+}
+
+func (w *profiling) Paint(dc *gg.Context, bounds image.Rectangle, frameIdx int) {
+	start := time.Now()
+	w.Widget.Paint(dc, bounds, frameIdx)
+	w.node.PaintTime += time.Since(start)
+	w.node.Paints++
+}
+
+// profiled returns a copy of w, with it and its descendants wrapped to time
+// their paints in the returned node.
+func profiled(w Widget) (Widget, *ProfileNode) {
+	return wrapTree(w, func(w Widget, children []*ProfileNode) (Widget, *ProfileNode) {
+		node := &ProfileNode{Type: widgetTypeName(w), Children: children}
+		return &profiling{Widget: w, node: node}, node
+	})
+}
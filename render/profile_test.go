@@ -0,0 +1,50 @@
+package render
+
+import (
+	"encoding/json"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileRoots(t *testing.T) {
+	circles := make([]Widget, 40)
+	for i := range circles {
+		circles[i] = Circle{Diameter: 30, Color: color.White}
+	}
+
+	root := Root{
+		Child: Row{
+			Children: []Widget{
+				Box{Width: 2, Height: 2},
+				Animation{Children: []Widget{
+					Stack{Children: circles},
+					Stack{Children: circles},
+				}},
+			},
+		},
+	}
+
+	p := ProfileRoots(root)
+	require.Len(t, p.Frames, 2)
+	assert.Equal(t, p.Frames[0]+p.Frames[1], p.PaintTime)
+
+	row := p.Roots[0].Children[0]
+	assert.Equal(t, "Row", row.Type)
+	assert.Equal(t, 2, row.Paints)
+
+	// each child of the animation is painted in one frame
+	anim := row.Children[1]
+	assert.Equal(t, 1, anim.Children[0].Paints)
+	assert.Equal(t, 1, anim.Children[1].Paints)
+
+	// the circles take up the time
+	assert.Equal(t, []string{"Root", "Row", "Animation"}, p.Slowest[:3])
+
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"paintTime":"`)
+	assert.Contains(t, string(b), `"type":"Animation"`)
+}
@@ -1,6 +1,7 @@
 package render
 
 import (
+	"context"
 	"image"
 	"image/color"
 	"runtime"
@@ -38,18 +39,34 @@ var FrameHeight = DefaultFrameHeight
 // an expiration time in seconds. Display devices use this to avoid
 // displaying stale data in the event of e.g. connectivity issues.
 //
+// Apps can also suggest how long the root is shown for with _ShowFor_,
+// and when they should be rendered again with _RefreshIn_, both in
+// seconds. Hosts that rotate through apps use these in place of their
+// defaults, unless the user has set their own.
+//
+// When an app returns a list of roots, they're shown one after another as
+// a single animation. A root that's _Separate_ instead starts a new screen,
+// which hosts that rotate through apps show in its own turn.
+//
 // DOC(Child): Widget to render
 // DOC(Delay): Frame delay in milliseconds
 // DOC(MaxAge): Expiration time in seconds
 // DOC(ShowFullAnimation): Request animation is shown in full, regardless of app cycle speed
+// DOC(ShowFor): Suggested time to show the root for, in seconds
+// DOC(RefreshIn): Suggested time until the app is rendered again, in seconds
+// DOC(Separate): Show as a separate screen, rather than as more frames of the previous root
 type Root struct {
 	Child             Widget `starlark:"child,required"`
 	Delay             int32  `starlark:"delay"`
 	MaxAge            int32  `starlark:"max_age"`
 	ShowFullAnimation bool   `starlark:"show_full_animation"`
+	ShowFor           int32  `starlark:"show_for"`
+	RefreshIn         int32  `starlark:"refresh_in"`
+	Separate          bool   `starlark:"separate"`
 
 	maxParallelFrames int
 	maxFrameCount     int
+	ctx               context.Context
 }
 
 type RootPaintOption func(*Root)
@@ -74,9 +91,25 @@ func WithMaxFrameCount(max int) RootPaintOption {
 	}
 }
 
+// WithContext stops `Paint` from starting new frames once ctx is
+// done. The first frame is always painted, so the result is never
+// empty.
+func WithContext(ctx context.Context) RootPaintOption {
+	return func(r *Root) {
+		r.ctx = ctx
+	}
+}
+
 // Paint renders the child widget onto the frame. It doesn't do
 // any resizing or alignment.
 func (r Root) Paint(solidBackground bool, opts ...RootPaintOption) []image.Image {
+	frames, _ := r.paint(solidBackground, opts...)
+	return frames
+}
+
+// paint renders frames like Paint, and also reports whether painting
+// was cut short by the context set with WithContext.
+func (r Root) paint(solidBackground bool, opts ...RootPaintOption) ([]image.Image, bool) {
 	for _, opt := range opts {
 		opt(&r)
 	}
@@ -106,7 +139,12 @@ func (r Root) Paint(solidBackground bool, opts ...RootPaintOption) []image.Image
 
 	var wg sync.WaitGroup
 	sem := make(chan bool, parallelism)
-	for i := 0; i < numFrames; i++ {
+	painted := 0
+	for ; painted < numFrames; painted++ {
+		if painted > 0 && r.ctx != nil && r.ctx.Err() != nil {
+			break
+		}
+
 		wg.Add(1)
 		sem <- true
 
@@ -126,11 +164,11 @@ func (r Root) Paint(solidBackground bool, opts ...RootPaintOption) []image.Image
 			r.Child.Paint(dc, image.Rect(0, 0, FrameWidth, FrameHeight), i)
 			dc.Pop()
 			frames[i] = dc.Image()
-		}(i)
+		}(painted)
 	}
 
 	wg.Wait()
-	return frames
+	return frames[:painted], painted < numFrames
 }
 
 // PaintRoots draws >=1 Roots which must all have the same dimensions.
@@ -142,3 +180,23 @@ func PaintRoots(solidBackground bool, roots ...Root) []image.Image {
 
 	return images
 }
+
+// PaintRootsContext is like PaintRoots, but stops painting once ctx
+// is done and returns the frames painted up to that point. The
+// returned bool reports whether any frames were left out.
+func PaintRootsContext(ctx context.Context, solidBackground bool, roots ...Root) ([]image.Image, bool) {
+	var images []image.Image
+	for i, r := range roots {
+		if i > 0 && ctx.Err() != nil {
+			return images, true
+		}
+
+		frames, truncated := r.paint(solidBackground, WithContext(ctx))
+		images = append(images, frames...)
+		if truncated {
+			return images, true
+		}
+	}
+
+	return images, false
+}
@@ -8,9 +8,11 @@ import (
 	"path"
 	"runtime/debug"
 	"slices"
+	"sort"
 	"strings"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	starlibbsoup "github.com/qri-io/starlib/bsoup"
 	starlibgzip "github.com/qri-io/starlib/compress/gzip"
@@ -28,19 +30,29 @@ import (
 	"go.starlark.net/starlarktest"
 	"go.starlark.net/syntax"
 
+	"tidbyt.dev/pixlet/manifest"
 	"tidbyt.dev/pixlet/render"
+	"tidbyt.dev/pixlet/runtime/modules/airquality"
 	"tidbyt.dev/pixlet/runtime/modules/animation_runtime"
 	"tidbyt.dev/pixlet/runtime/modules/file"
+	"tidbyt.dev/pixlet/runtime/modules/golden"
 	"tidbyt.dev/pixlet/runtime/modules/hmac"
 	"tidbyt.dev/pixlet/runtime/modules/humanize"
+	"tidbyt.dev/pixlet/runtime/modules/icons_runtime"
+	"tidbyt.dev/pixlet/runtime/modules/location"
 	"tidbyt.dev/pixlet/runtime/modules/qrcode"
+	"tidbyt.dev/pixlet/runtime/modules/quotes"
 	"tidbyt.dev/pixlet/runtime/modules/random"
 	"tidbyt.dev/pixlet/runtime/modules/render_runtime"
+	"tidbyt.dev/pixlet/runtime/modules/sports"
 	"tidbyt.dev/pixlet/runtime/modules/starlarkhttp"
 	"tidbyt.dev/pixlet/runtime/modules/sunrise"
+	"tidbyt.dev/pixlet/runtime/modules/transit"
+	"tidbyt.dev/pixlet/runtime/modules/weather"
 	"tidbyt.dev/pixlet/runtime/modules/xpath"
 	"tidbyt.dev/pixlet/schema"
 	"tidbyt.dev/pixlet/starlarkutil"
+	"tidbyt.dev/pixlet/tracing"
 )
 
 type ModuleLoader func(*starlark.Thread, string) (starlark.StringDict, error)
@@ -62,9 +74,13 @@ type Applet struct {
 	loader       ModuleLoader
 	initializers []ThreadInitializer
 	loadedPaths  map[string]bool
+	probes       []Probe
 
-	mainFun    *starlark.Function
-	schemaFile string
+	mainFun     *starlark.Function
+	prefetchFun *starlark.Function
+	requires    []string
+	schemaFile  string
+	manifest    *manifest.Manifest
 
 	Schema     *schema.Schema
 	SchemaJSON []byte
@@ -98,6 +114,33 @@ func WithSecretDecryptionKey(key *SecretDecryptionKey) AppletOption {
 	}
 }
 
+// deterministicUnix is the Unix time applets loaded with WithDeterministic
+// see, 2024-01-01 12:00 UTC.
+const deterministicUnix = 1704110400
+
+// DeterministicTime returns the time applets loaded with WithDeterministic
+// see.
+func DeterministicTime() time.Time {
+	return time.Unix(deterministicUnix, 0).UTC()
+}
+
+// WithDeterministic makes the applet's output depend only on its config
+// and the responses to its HTTP requests: time.now() always returns
+// DeterministicTime(), and the random module is seeded the same way on every
+// run.
+func WithDeterministic() AppletOption {
+	return func(a *Applet) error {
+		a.initializers = append(a.initializers, func(t *starlark.Thread) *starlark.Thread {
+			starlibtime.SetNow(t, func() (time.Time, error) {
+				return DeterministicTime(), nil
+			})
+			random.AttachToThreadAt(t, DeterministicTime())
+			return t
+		})
+		return nil
+	}
+}
+
 func WithPrintFunc(print PrintFunc) AppletOption {
 	return func(a *Applet) error {
 		a.initializers = append(a.initializers, func(t *starlark.Thread) *starlark.Thread {
@@ -214,23 +257,35 @@ func (a *Applet) RunWithConfig(ctx context.Context, config map[string]string) (r
 // CallSchemaHandler calls a schema handler, passing it a single
 // string parameter and returning a single string value.
 func (app *Applet) CallSchemaHandler(ctx context.Context, handlerName, parameter string) (result string, err error) {
+	return app.CallSchemaHandlerPage(ctx, handlerName, parameter, "")
+}
+
+// CallSchemaHandlerPage is like CallSchemaHandler, but also passes cursor to
+// paginated Typeahead handlers, which take it as a second parameter. Pass the
+// next_cursor of the previous page to fetch the following one.
+func (app *Applet) CallSchemaHandlerPage(ctx context.Context, handlerName, parameter, cursor string) (result string, err error) {
 	handler, found := app.Schema.Handlers[handlerName]
 	if !found {
 		return "", fmt.Errorf("no exported handler named '%s'", handlerName)
 	}
 
-	resultVal, err := app.Call(
-		ctx,
-		handler.Function,
-		starlark.String(parameter),
-	)
+	if len([]rune(parameter)) < handler.MinQueryLength {
+		return "[]", nil
+	}
+
+	args := starlark.Tuple{starlark.String(parameter)}
+	if handler.Function.NumParams() >= 2 {
+		args = append(args, starlark.String(cursor))
+	}
+
+	resultVal, err := app.Call(ctx, handler.Function, args...)
 	if err != nil {
 		return "", fmt.Errorf("calling schema handler %s: %v", handlerName, err)
 	}
 
 	switch handler.ReturnType {
 	case schema.ReturnOptions:
-		options, err := schema.EncodeOptions(resultVal)
+		options, err := schema.EncodeHandlerOptions(resultVal)
 		if err != nil {
 			return "", err
 		}
@@ -263,33 +318,59 @@ func (app *Applet) CallSchemaHandler(ctx context.Context, handlerName, parameter
 	return "", fmt.Errorf("a very unexpected error happened for handler \"%s\"", handlerName)
 }
 
-// RunTests runs all test functions that are defined in the applet source.
-func (app *Applet) RunTests(t *testing.T) {
-	app.initializers = append(app.initializers, func(thread *starlark.Thread) *starlark.Thread {
-		starlarktest.SetReporter(thread, t)
-		return thread
-	})
-
-	for file, globals := range app.Globals {
+// Tests returns the names of the test functions that are defined in the
+// applet source, as file/function, sorted.
+func (a *Applet) Tests() []string {
+	var tests []string
+	for file, globals := range a.Globals {
 		for name, global := range globals {
-			if !strings.HasPrefix(name, "test_") {
-				continue
+			if _, ok := global.(*starlark.Function); ok && strings.HasPrefix(name, "test_") {
+				tests = append(tests, fmt.Sprintf("%s/%s", file, name))
 			}
+		}
+	}
+	sort.Strings(tests)
+	return tests
+}
+
+type testReporterKey struct{}
+
+// RunTest runs one of the applet's Tests. Failed assertions are reported to
+// r, and the test stops if it returns an error.
+func (a *Applet) RunTest(ctx context.Context, test string, r starlarktest.Reporter) error {
+	i := strings.LastIndex(test, "/")
+	if i < 0 {
+		return fmt.Errorf("no such test: %s", test)
+	}
+	fun, ok := a.Globals[test[:i]][test[i+1:]].(*starlark.Function)
+	if !ok || !strings.HasPrefix(test[i+1:], "test_") {
+		return fmt.Errorf("no such test: %s", test)
+	}
+
+	_, err := a.Call(context.WithValue(ctx, testReporterKey{}, r), fun)
+	return err
+}
 
-			if fun, ok := global.(*starlark.Function); ok {
-				t.Run(fmt.Sprintf("%s/%s", file, name), func(t *testing.T) {
-					if _, err := app.Call(context.Background(), fun); err != nil {
-						t.Error(err)
-					}
-				})
+// RunTests runs all test functions that are defined in the applet source.
+func (app *Applet) RunTests(t *testing.T) {
+	for _, test := range app.Tests() {
+		t.Run(test, func(t *testing.T) {
+			if err := app.RunTest(context.Background(), test, t); err != nil {
+				t.Error(err)
 			}
-		}
+		})
 	}
 }
 
 // Calls any callable from Applet.Globals. Pass args and receive a
 // starlark Value, or an error if you're unlucky.
 func (a *Applet) Call(ctx context.Context, callable *starlark.Function, args ...starlark.Value) (val starlark.Value, err error) {
+	ctx, span := tracing.Start(ctx, "starlark.call",
+		tracing.AppIDKey.String(a.ID),
+		tracing.FunctionKey.String(callable.Name()),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("panic while running %s: %v\n%s", a.ID, r, debug.Stack())
@@ -299,6 +380,14 @@ func (a *Applet) Call(ctx context.Context, callable *starlark.Function, args ...
 	t := a.newThread(ctx)
 	defer starlarkutil.RunOnExitFuncs(t)
 
+	start := time.Now()
+	defer func() {
+		UsageMeterFromContext(ctx).update(func(s *UsageStats) {
+			s.WallTime += time.Since(start)
+			s.ExecutionSteps += t.ExecutionSteps()
+		})
+	}()
+
 	context.AfterFunc(ctx, func() {
 		t.Cancel(context.Cause(ctx).Error())
 	})
@@ -331,6 +420,10 @@ func (a *Applet) PathsForBundle() []string {
 }
 
 func (a *Applet) load(fsys fs.FS) (err error) {
+	if err := a.loadManifest(fsys); err != nil {
+		return err
+	}
+
 	// list files in the root directory of fsys
 	rootDir, err := fs.ReadDir(fsys, ".")
 	if err != nil {
@@ -420,6 +513,8 @@ func (a *Applet) ensureLoaded(fsys fs.FS, pathToLoad string, currentlyLoading ..
 
 	switch path.Ext(pathToLoad) {
 	case ".star":
+		src = a.instrument(pathToLoad, src, predeclared)
+
 		globals, err := starlark.ExecFileOptions(
 			&syntax.FileOptions{
 				Set:       true,
@@ -445,6 +540,12 @@ func (a *Applet) ensureLoaded(fsys fs.FS, pathToLoad string, currentlyLoading ..
 
 			a.MainFile = pathToLoad
 			a.mainFun = mainFun
+			a.prefetchFun, _ = globals[PrefetchFunctionName].(*starlark.Function)
+			if requires, ok := globals[RequiresConstantName]; ok {
+				if a.requires, err = parseRequires(requires); err != nil {
+					return err
+				}
+			}
 		}
 
 		schemaFun, _ := globals[schema.SchemaFunctionName].(*starlark.Function)
@@ -494,13 +595,71 @@ func (a *Applet) newThread(ctx context.Context) *starlark.Thread {
 	starlarkutil.AttachThreadContext(ctx, t)
 	random.AttachToThread(t)
 
+	// apps that don't list any network hosts can make requests anywhere,
+	// even if they declare other capabilities
+	if a.manifest != nil && a.manifest.Capabilities != nil && len(a.manifest.Capabilities.Network) > 0 {
+		starlarkhttp.AllowHosts(t, a.manifest.Capabilities.AllowsHost)
+	}
+
+	if r, ok := ctx.Value(testReporterKey{}).(starlarktest.Reporter); ok {
+		starlarktest.SetReporter(t, r)
+	}
+
 	for _, init := range a.initializers {
 		t = init(t)
 	}
 
+	// a time to render at overrides the time deterministic applets see
+	attachRenderTime(ctx, t)
+
 	return t
 }
 
+// BuiltinModules are the names of the modules pixlet provides, which apps
+// load with load().
+var BuiltinModules = []string{
+	"airquality.star",
+	"animation.star",
+	"assert.star",
+	"bsoup.star",
+	"cache.star",
+	"compress/gzip.star",
+	"compress/zipfile.star",
+	"encoding/base64.star",
+	"encoding/csv.star",
+	"encoding/json.star",
+	"golden.star",
+	"hash.star",
+	"hmac.star",
+	"html.star",
+	"http.star",
+	"humanize.star",
+	"icons.star",
+	"location.star",
+	"math.star",
+	"qrcode.star",
+	"quotes.star",
+	"random.star",
+	"re.star",
+	"render.star",
+	"request.star",
+	"schema.star",
+	"secret.star",
+	"sports.star",
+	"state.star",
+	"sunrise.star",
+	"time.star",
+	"transit.star",
+	"weather.star",
+	"xpath.star",
+}
+
+// LoadBuiltinModule loads one of the BuiltinModules, for tools that inspect
+// them, such as editors.
+func LoadBuiltinModule(module string) (starlark.StringDict, error) {
+	return (&Applet{}).loadModule(nil, module)
+}
+
 func (a *Applet) loadModule(thread *starlark.Thread, module string) (starlark.StringDict, error) {
 	if a.loader != nil {
 		mod, err := a.loader(thread, module)
@@ -525,6 +684,12 @@ func (a *Applet) loadModule(thread *starlark.Thread, module string) (starlark.St
 	case "secret.star":
 		return LoadSecretModule()
 
+	case "state.star":
+		return LoadStateModule()
+
+	case "request.star":
+		return LoadRequestModule()
+
 	case "xpath.star":
 		return xpath.LoadXPathModule()
 
@@ -560,6 +725,9 @@ func (a *Applet) loadModule(thread *starlark.Thread, module string) (starlark.St
 			starlibjson.Module.Name: starlibjson.Module,
 		}, nil
 
+	case "golden.star":
+		return golden.LoadModule()
+
 	case "hash.star":
 		return starlibhash.LoadModule()
 
@@ -575,6 +743,12 @@ func (a *Applet) loadModule(thread *starlark.Thread, module string) (starlark.St
 	case "humanize.star":
 		return humanize.LoadModule()
 
+	case "icons.star":
+		return icons_runtime.LoadModule()
+
+	case "location.star":
+		return location.LoadModule()
+
 	case "math.star":
 		return starlark.StringDict{
 			starlibmath.Module.Name: starlibmath.Module,
@@ -583,6 +757,9 @@ func (a *Applet) loadModule(thread *starlark.Thread, module string) (starlark.St
 	case "re.star":
 		return starlibre.LoadModule()
 
+	case "sports.star":
+		return sports.LoadModule()
+
 	case "sunrise.star":
 		return sunrise.LoadModule()
 
@@ -591,6 +768,18 @@ func (a *Applet) loadModule(thread *starlark.Thread, module string) (starlark.St
 			starlibtime.Module.Name: starlibtime.Module,
 		}, nil
 
+	case "transit.star":
+		return transit.LoadModule()
+
+	case "quotes.star":
+		return quotes.LoadModule()
+
+	case "weather.star":
+		return weather.LoadModule()
+
+	case "airquality.star":
+		return airquality.LoadModule()
+
 	case "random.star":
 		return random.LoadModule()
 
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	starlibbase64 "github.com/qri-io/starlib/encoding/base64"
 	"github.com/stretchr/testify/assert"
@@ -132,6 +133,7 @@ func TestRunMainAcceptsConfig(t *testing.T) {
 		"two":     "2",
 		"toggle1": "true",
 		"toggle2": "false",
+		"lines":   `["red", "blue"]`,
 	}
 
 	// It's ok for main() to accept no args at all
@@ -167,6 +169,9 @@ def main(config):
 	assert_eq("config.bool('toggle1')", config.bool("toggle1"), True)
 	assert_eq("config.bool('toggle2')", config.bool("toggle2"), False)
 
+	assert_eq("config.list with fallback", config.list("doesnt_exist", []), [])
+	assert_eq("config.list('lines')", config.list("lines"), ["red", "blue"])
+
 	return [render.Root(child=render.Box()) for _ in range(int(config["one"]) + int(config["two"]))]
 `
 	app, err = NewApplet("test.star", []byte(src))
@@ -453,4 +458,135 @@ def main():
 	app.RunTests(t)
 }
 
+type collectingReporter struct {
+	errors []string
+}
+
+func (r *collectingReporter) Error(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func TestRunTest(t *testing.T) {
+	src := `
+load("assert.star", "assert")
+
+def test_passes():
+	assert.eq(1 + 1, 2)
+
+def test_asserts():
+	assert.eq(1 + 1, 3)
+	assert.true(False)
+
+def test_fails():
+	fail("oh no")
+
+def helper_test():
+	pass
+
+def main():
+	return []
+`
+
+	app, err := NewApplet("tests.star", []byte(src))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tests.star/test_asserts", "tests.star/test_fails", "tests.star/test_passes"}, app.Tests())
+
+	r := &collectingReporter{}
+	assert.NoError(t, app.RunTest(context.Background(), "tests.star/test_passes", r))
+	assert.Empty(t, r.errors)
+
+	assert.NoError(t, app.RunTest(context.Background(), "tests.star/test_asserts", r))
+	assert.Len(t, r.errors, 2)
+
+	assert.ErrorContains(t, app.RunTest(context.Background(), "tests.star/test_fails", r), "oh no")
+	assert.Error(t, app.RunTest(context.Background(), "tests.star/helper_test", r))
+	assert.Error(t, app.RunTest(context.Background(), "tests.star/test_missing", r))
+}
+
+func TestDeterministic(t *testing.T) {
+	src := `
+load("random.star", "random")
+load("time.star", "time")
+
+def main():
+    print(time.now().unix, random.number(0, 1 << 30), random.number(0, 1 << 30))
+    return []
+`
+
+	var printed []string
+	for i := 0; i < 2; i++ {
+		app, err := NewApplet("deterministic.star", []byte(src), WithDeterministic(), WithPrintFunc(func(_ *starlark.Thread, msg string) {
+			printed = append(printed, msg)
+		}))
+		require.NoError(t, err)
+		_, err = app.Run(context.Background())
+		require.NoError(t, err)
+	}
+
+	require.Len(t, printed, 2)
+	assert.Equal(t, printed[0], printed[1])
+	assert.Contains(t, printed[0], fmt.Sprint(DeterministicTime().Unix()))
+}
+
 // TODO: test Screens, especially Screens.Render()
+
+func TestUsageMeter(t *testing.T) {
+	src := `
+load("cache.star", "cache")
+
+def main():
+    for i in range(100):
+        pass
+    cache.set("key", "value")
+    cache.get("key")
+    cache.get("other")
+    return []
+`
+	InitCache(NewInMemoryCache())
+	defer InitCache(nil)
+
+	app, err := NewApplet("usage.star", []byte(src))
+	require.NoError(t, err)
+
+	ctx, meter := MeterUsage(context.Background())
+	_, err = app.Run(ctx)
+	require.NoError(t, err)
+	meter.AddOutputBytes(42)
+	meter.AddEncodeTime(time.Millisecond)
+
+	stats := meter.Stats()
+	assert.Greater(t, stats.WallTime, time.Duration(0))
+	assert.Greater(t, stats.ExecutionSteps, uint64(100))
+	assert.Equal(t, int64(2), stats.CacheReads)
+	assert.Equal(t, int64(1), stats.CacheWrites)
+	assert.Equal(t, int64(5), stats.CacheBytesWritten)
+	assert.Equal(t, int64(42), stats.OutputBytes)
+	assert.Equal(t, time.Millisecond, stats.EncodeTime)
+
+	report := NewUsageReport()
+	report.Record("usage", stats, nil)
+	report.Record("usage", stats, fmt.Errorf("failed"))
+
+	apps := report.Apps()
+	assert.Equal(t, int64(2), apps["usage"].Executions)
+	assert.Equal(t, int64(1), apps["usage"].Errors)
+	assert.Equal(t, int64(84), apps["usage"].Total.OutputBytes)
+	assert.Equal(t, 2*time.Millisecond, apps["usage"].Total.EncodeTime)
+	assert.Equal(t, stats.WallTime, apps["usage"].MaxWallTime)
+
+	// a nil meter records nothing, and doesn't panic
+	var nilMeter *UsageMeter
+	nilMeter.AddOutputBytes(1)
+	assert.Equal(t, UsageStats{}, nilMeter.Stats())
+}
+
+func TestLoadBuiltinModule(t *testing.T) {
+	for _, name := range BuiltinModules {
+		m, err := LoadBuiltinModule(name)
+		require.NoError(t, err, name)
+		assert.NotEmpty(t, m, name)
+	}
+
+	_, err := LoadBuiltinModule("nope.star")
+	assert.Error(t, err)
+}
@@ -3,13 +3,17 @@ package runtime
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/url"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go.starlark.net/starlark"
 	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/logging"
+	"tidbyt.dev/pixlet/starlarkutil"
 )
 
 const DefaultExpirationSeconds = 60
@@ -19,47 +23,85 @@ type Cache interface {
 	Get(thread *starlark.Thread, key string) ([]byte, bool, error)
 }
 
-type InMemoryCacheRecord struct {
-	data       []byte
-	expiration time.Time
-}
+// CacheFactory creates a Cache from a URL. The URL's scheme has already been
+// matched against the scheme the factory was registered under, so factories
+// only need to interpret the remainder of the URL.
+type CacheFactory func(u *url.URL) (Cache, error)
+
+// DefaultCacheURL is the cache used when no cache URL is provided.
+const DefaultCacheURL = "memory://"
+
+var (
+	cacheFactoriesMutex sync.RWMutex
+	cacheFactories      = map[string]CacheFactory{}
+)
 
-type InMemoryCache struct {
-	records map[string]*InMemoryCacheRecord
-	mutex   sync.RWMutex
+func init() {
+	RegisterCache("memory", newInMemoryCacheFromURL)
+	RegisterCache("redis", newRedisCacheFromURL)
+	RegisterCache("rediss", newRedisCacheFromURL)
 }
 
-func NewInMemoryCache() *InMemoryCache {
-	return &InMemoryCache{records: map[string]*InMemoryCacheRecord{}}
+// RegisterCache makes a cache backend available under the given URL scheme,
+// so that it can be selected with OpenCache. It is intended to be called from
+// the init function of the package implementing the backend. Registering the
+// same scheme twice replaces the previous factory.
+func RegisterCache(scheme string, factory CacheFactory) {
+	if factory == nil {
+		panic("runtime: RegisterCache factory is nil")
+	}
+
+	cacheFactoriesMutex.Lock()
+	defer cacheFactoriesMutex.Unlock()
+
+	cacheFactories[scheme] = factory
 }
 
-func (c *InMemoryCache) Get(_ *starlark.Thread, key string) (value []byte, found bool, err error) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+// CacheSchemes returns the sorted list of registered cache URL schemes.
+func CacheSchemes() []string {
+	cacheFactoriesMutex.RLock()
+	defer cacheFactoriesMutex.RUnlock()
 
-	r, found := c.records[key]
+	schemes := make([]string, 0, len(cacheFactories))
+	for scheme := range cacheFactories {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
 
-	if !found {
-		return nil, false, nil
+	return schemes
+}
+
+// OpenCache creates a Cache from a URL such as "memory://" or
+// "redis://localhost:6379/0", using the backend registered for the URL's
+// scheme. An empty URL opens DefaultCacheURL.
+func OpenCache(rawURL string) (Cache, error) {
+	if rawURL == "" {
+		rawURL = DefaultCacheURL
 	}
 
-	if time.Now().After(r.expiration) {
-		return nil, false, nil
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing cache URL: %w", err)
 	}
 
-	return r.data, true, nil
-}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("cache URL %q has no scheme (supported: %v)", rawURL, CacheSchemes())
+	}
 
-func (c *InMemoryCache) Set(_ *starlark.Thread, key string, value []byte, ttl int64) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	cacheFactoriesMutex.RLock()
+	factory, ok := cacheFactories[u.Scheme]
+	cacheFactoriesMutex.RUnlock()
 
-	c.records[key] = &InMemoryCacheRecord{
-		data:       value,
-		expiration: time.Now().Add(time.Duration(ttl) * time.Second),
+	if !ok {
+		return nil, fmt.Errorf("unknown cache scheme %q (supported: %v)", u.Scheme, CacheSchemes())
+	}
+
+	c, err := factory(u)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s cache: %w", u.Scheme, err)
 	}
 
-	return nil
+	return c, nil
 }
 
 type RedisCache struct {
@@ -77,6 +119,17 @@ func NewRedisCache(url string) *RedisCache {
 	}
 }
 
+func newRedisCacheFromURL(u *url.URL) (Cache, error) {
+	opts, err := redis.ParseURL(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisCache{
+		client: redis.NewClient(opts),
+	}, nil
+}
+
 func (c *RedisCache) Get(_ *starlark.Thread, key string) (value []byte, found bool, err error) {
 	ctx := context.Background()
 	val, err := c.client.Get(ctx, key).Bytes()
@@ -89,11 +142,41 @@ func (c *RedisCache) Get(_ *starlark.Thread, key string) (value []byte, found bo
 	}
 }
 
+// GetWithExpiration reads key and its remaining TTL in a single round trip.
+func (c *RedisCache) GetWithExpiration(_ *starlark.Thread, key string) ([]byte, time.Time, bool, error) {
+	ctx := context.Background()
+	pipe := c.client.Pipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, time.Time{}, false, err
+	}
+
+	val, err := get.Bytes()
+	if err == redis.Nil {
+		return nil, time.Time{}, false, nil
+	} else if err != nil {
+		return nil, time.Time{}, false, err
+	}
+
+	// keys without a TTL report a negative one
+	var expiration time.Time
+	if ttl := pttl.Val(); ttl > 0 {
+		expiration = time.Now().Add(ttl)
+	}
+	return val, expiration, true, nil
+}
+
 func (c *RedisCache) Set(_ *starlark.Thread, key string, value []byte, ttl int64) error {
 	ctx := context.Background()
 	return c.client.Set(ctx, key, value, time.Duration(ttl)*time.Second).Err()
 }
 
+func (c *RedisCache) Delete(key string) error {
+	ctx := context.Background()
+	return c.client.Del(ctx, key).Err()
+}
+
 var (
 	cacheOnce   sync.Once
 	cacheModule starlark.StringDict
@@ -120,22 +203,36 @@ func LoadCacheModule() (starlark.StringDict, error) {
 	return cacheModule, nil
 }
 
-func scopedCacheKey(thread *starlark.Thread, key starlark.String) string {
-	return fmt.Sprintf("pixlet:%s:%s", thread.Name, key.GoString())
+// SharedCacheKeyPrefix is the prefix of keys the cache module shares
+// between apps, which apps ask for with shared = True.
+const SharedCacheKeyPrefix = "pixlet-shared:"
+
+// scopedCacheKey returns the key a cache module key is stored under, which
+// is scoped to the app running on thread unless it's shared. The app's ID is
+// escaped, so that no app can write to another's keys, whatever their IDs.
+func scopedCacheKey(thread *starlark.Thread, key starlark.String, shared bool) string {
+	if shared {
+		return SharedCacheKeyPrefix + key.GoString()
+	}
+	return fmt.Sprintf("pixlet:%s:%s", url.QueryEscape(starlarkutil.AppID(thread)), key.GoString())
 }
 
 func cacheGet(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
-	var key starlark.String
+	var (
+		key    starlark.String
+		shared bool
+	)
 
 	if err := starlark.UnpackArgs(
 		"get",
 		args, kwargs,
 		"key", &key,
+		"shared?", &shared,
 	); err != nil {
 		return nil, fmt.Errorf("unpacking arguments for cache.get: %v", err)
 	}
 
-	cacheKey := scopedCacheKey(thread, key)
+	cacheKey := scopedCacheKey(thread, key, shared)
 
 	if cache == nil {
 		// no cache configured
@@ -143,10 +240,11 @@ func cacheGet(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple,
 	}
 
 	val, found, err := cache.Get(thread, cacheKey)
+	UsageMeterFromContext(starlarkutil.ThreadContext(thread)).update(func(s *UsageStats) { s.CacheReads++ })
 
 	if err != nil {
 		// don't fail just because cache is misbehaving
-		log.Printf("getting %s from cache: %v", cacheKey, err)
+		logging.ForApp(starlarkutil.AppID(thread)).Warn("getting value from cache", "key", cacheKey, "error", err)
 		return starlark.None, nil
 	}
 
@@ -159,9 +257,10 @@ func cacheGet(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple,
 
 func cacheSet(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var (
-		key starlark.String
-		val starlark.String
-		ttl starlark.Int
+		key    starlark.String
+		val    starlark.String
+		ttl    starlark.Int
+		shared bool
 	)
 
 	if err := starlark.UnpackArgs(
@@ -170,11 +269,12 @@ func cacheSet(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple,
 		"key", &key,
 		"value", &val,
 		"ttl_seconds?", &ttl,
+		"shared?", &shared,
 	); err != nil {
 		return nil, fmt.Errorf("unpacking arguments for cache.set: %v", err)
 	}
 
-	cacheKey := scopedCacheKey(thread, key)
+	cacheKey := scopedCacheKey(thread, key, shared)
 
 	ttl64, ok := ttl.Int64()
 	if !ok {
@@ -195,8 +295,12 @@ func cacheSet(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple,
 	}
 
 	err := cache.Set(thread, cacheKey, []byte(val.GoString()), ttl64)
+	UsageMeterFromContext(starlarkutil.ThreadContext(thread)).update(func(s *UsageStats) {
+		s.CacheWrites++
+		s.CacheBytesWritten += int64(len(val))
+	})
 	if err != nil {
-		log.Printf("setting %s in cache: %v", cacheKey, err)
+		logging.ForApp(starlarkutil.AppID(thread)).Warn("setting value in cache", "key", cacheKey, "error", err)
 	}
 
 	return starlark.None, nil
@@ -0,0 +1,300 @@
+//go:build !js && !wasm
+
+// Package bbolt provides a cache backend that persists entries to a single
+// bbolt database file. It's pure Go and needs no server, which suits single
+// binary deployments on small boards.
+//
+// Entries are kept in a bucket per app, and those that don't belong to an
+// app in a shared bucket, so that an app's entries can be found without
+// reading everyone else's.
+//
+// Importing this package registers the "bolt" cache URL scheme:
+//
+//	bolt:///var/lib/pixlet/cache.bolt
+//	bolt://cache.bolt
+package bbolt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+const (
+	// Scheme is the cache URL scheme handled by this package.
+	Scheme = "bolt"
+
+	// PurgeInterval is how often expired entries are deleted from the
+	// database. The space they took up is reused for new entries.
+	PurgeInterval = 5 * time.Minute
+
+	// OpenTimeout is how long Open waits for another process to let go
+	// of the database file.
+	OpenTimeout = 5 * time.Second
+
+	// headerSize is the size of the expiration time stored before each
+	// value.
+	headerSize = 8
+)
+
+var (
+	// appsBucket holds a bucket for each app, named by its ID.
+	appsBucket = []byte("apps")
+
+	// sharedBucket holds the entries that don't belong to an app.
+	sharedBucket = []byte("shared")
+)
+
+func init() {
+	runtime.RegisterCache(Scheme, func(u *url.URL) (runtime.Cache, error) {
+		return Open(PathFromURL(u))
+	})
+}
+
+// Cache is a runtime.Cache that stores entries in bbolt.
+type Cache struct {
+	db   *bolt.DB
+	done chan struct{}
+	once sync.Once
+}
+
+// PathFromURL extracts the database path from a bolt:// URL. Both
+// bolt:///abs/path.bolt and bolt://relative/path.bolt are accepted.
+func PathFromURL(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+	return u.Host + u.Path
+}
+
+// Open opens (creating if necessary) the bbolt cache database at path.
+func Open(path string) (*Cache, error) {
+	if path == "" {
+		return nil, fmt.Errorf("bolt cache requires a database path")
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: OpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(appsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(sharedBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing %s: %w", path, err)
+	}
+
+	c := &Cache{
+		db:   db,
+		done: make(chan struct{}),
+	}
+
+	if err := c.Purge(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	go c.purgeLoop()
+
+	return c, nil
+}
+
+// bucket returns the bucket key belongs in, creating it if create is set.
+// It returns nil if the bucket doesn't exist.
+func bucket(tx *bolt.Tx, key string, create bool) (*bolt.Bucket, error) {
+	app := runtime.CacheKeyApp(key)
+	if app == "" {
+		return tx.Bucket(sharedBucket), nil
+	}
+
+	apps := tx.Bucket(appsBucket)
+	if create {
+		return apps.CreateBucketIfNotExists([]byte(app))
+	}
+	return apps.Bucket([]byte(app)), nil
+}
+
+func (c *Cache) Get(thread *starlark.Thread, key string) ([]byte, bool, error) {
+	value, _, found, err := c.GetWithExpiration(thread, key)
+	return value, found, err
+}
+
+func (c *Cache) GetWithExpiration(_ *starlark.Thread, key string) ([]byte, time.Time, bool, error) {
+	var value []byte
+	var exp time.Time
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b, err := bucket(tx, key, false)
+		if err != nil || b == nil {
+			return err
+		}
+
+		v := b.Get([]byte(key))
+		if v == nil || !expiration(v).After(time.Now()) {
+			return nil
+		}
+
+		// v is only valid for the life of the transaction
+		value = append([]byte{}, v[headerSize:]...)
+		exp = expiration(v)
+		return nil
+	})
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+
+	return value, exp, value != nil, nil
+}
+
+func (c *Cache) Set(_ *starlark.Thread, key string, value []byte, ttl int64) error {
+	expiresAt := time.Now().Add(time.Duration(ttl) * time.Second)
+
+	v := make([]byte, headerSize+len(value))
+	binary.BigEndian.PutUint64(v, uint64(expiresAt.UnixMilli()))
+	copy(v[headerSize:], value)
+
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b, err := bucket(tx, key, true)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), v)
+	})
+}
+
+func (c *Cache) Delete(key string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b, err := bucket(tx, key, false)
+		if err != nil || b == nil {
+			return err
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+func (c *Cache) Entries() ([]runtime.CacheEntry, error) {
+	now := time.Now()
+
+	var entries []runtime.CacheEntry
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return forEachBucket(tx, func(_ []byte, b *bolt.Bucket) error {
+			return b.ForEach(func(k, v []byte) error {
+				if exp := expiration(v); exp.After(now) {
+					entries = append(entries, runtime.CacheEntry{
+						Key:        string(k),
+						Size:       int64(len(v) - headerSize),
+						Expiration: exp,
+					})
+				}
+				return nil
+			})
+		})
+	})
+
+	return entries, err
+}
+
+// Purge deletes all expired entries from the database, and the buckets of
+// apps that have none left.
+func (c *Cache) Purge() error {
+	now := time.Now()
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		var empty [][]byte
+
+		err := forEachBucket(tx, func(app []byte, b *bolt.Bucket) error {
+			// deleting while iterating with a cursor skips entries, so
+			// they're collected first
+			var expired [][]byte
+			err := b.ForEach(func(k, v []byte) error {
+				if !expiration(v).After(now) {
+					expired = append(expired, append([]byte{}, k...))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, k := range expired {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+
+			if k, _ := b.Cursor().First(); app != nil && k == nil {
+				empty = append(empty, append([]byte{}, app...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		apps := tx.Bucket(appsBucket)
+		for _, app := range empty {
+			if err := apps.DeleteBucket(app); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("purging expired entries: %w", err)
+	}
+
+	return nil
+}
+
+// Close stops the background purge and closes the database.
+func (c *Cache) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return c.db.Close()
+}
+
+func (c *Cache) purgeLoop() {
+	ticker := time.NewTicker(PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.Purge()
+		}
+	}
+}
+
+// forEachBucket calls fn with the shared bucket, with a nil app, and then
+// with each app's bucket.
+func forEachBucket(tx *bolt.Tx, fn func(app []byte, b *bolt.Bucket) error) error {
+	if err := fn(nil, tx.Bucket(sharedBucket)); err != nil {
+		return err
+	}
+
+	apps := tx.Bucket(appsBucket)
+	return apps.ForEachBucket(func(app []byte) error {
+		return fn(app, apps.Bucket(app))
+	})
+}
+
+// expiration returns when the entry stored as v expires. Values too short
+// to have been written by Set have already expired.
+func expiration(v []byte) time.Time {
+	if len(v) < headerSize {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(v)))
+}
@@ -0,0 +1,186 @@
+package bbolt
+
+import (
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func open(t *testing.T) *Cache {
+	c, err := Open(filepath.Join(t.TempDir(), "cache.bolt"))
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// apps returns the names of the apps that have a bucket.
+func apps(t *testing.T, c *Cache) []string {
+	var names []string
+	require.NoError(t, c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(appsBucket).ForEachBucket(func(app []byte) error {
+			names = append(names, string(app))
+			return nil
+		})
+	}))
+	return names
+}
+
+func TestGetAndSet(t *testing.T) {
+	c := open(t)
+
+	_, found, err := c.Get(nil, "missing")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, c.Set(nil, "key", []byte("one"), 60))
+	assert.NoError(t, c.Set(nil, "key", []byte("two"), 60))
+
+	val, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("two"), val)
+
+	// an empty value is still found
+	assert.NoError(t, c.Set(nil, "empty", []byte{}, 60))
+	val, found, err = c.Get(nil, "empty")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Empty(t, val)
+}
+
+func TestBucketPerApp(t *testing.T) {
+	c := open(t)
+
+	assert.NoError(t, c.Set(nil, "pixlet:clock:ada", []byte("one"), 60))
+	assert.NoError(t, c.Set(nil, "pixlet:weather:ada", []byte("two"), 60))
+	assert.NoError(t, c.Set(nil, "shared", []byte("three"), 60))
+	assert.Equal(t, []string{"clock", "weather"}, apps(t, c))
+
+	// an app's bucket doesn't get in the way of another's keys
+	_, found, err := c.Get(nil, "pixlet:lovelace:ada")
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.NoError(t, c.Delete("pixlet:lovelace:ada"))
+
+	val, found, err := c.Get(nil, "pixlet:clock:ada")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("one"), val)
+
+	val, found, err = c.Get(nil, "shared")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("three"), val)
+}
+
+func TestExpiration(t *testing.T) {
+	c := open(t)
+
+	assert.NoError(t, c.Set(nil, "expired", []byte("gone"), -1))
+	assert.NoError(t, c.Set(nil, "pixlet:clock:expired", []byte("gone"), -1))
+	assert.NoError(t, c.Set(nil, "pixlet:weather:expired", []byte("gone"), -1))
+	assert.NoError(t, c.Set(nil, "pixlet:weather:live", []byte("here"), 60))
+
+	_, found, err := c.Get(nil, "expired")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// sweeping deletes the expired entries, and the buckets of apps that
+	// have nothing left
+	assert.NoError(t, c.Purge())
+	assert.Equal(t, []string{"weather"}, apps(t, c))
+
+	var keys []string
+	require.NoError(t, c.db.View(func(tx *bolt.Tx) error {
+		return forEachBucket(tx, func(_ []byte, b *bolt.Bucket) error {
+			return b.ForEach(func(k, _ []byte) error {
+				keys = append(keys, string(k))
+				return nil
+			})
+		})
+	}))
+	assert.Equal(t, []string{"pixlet:weather:live"}, keys)
+}
+
+func TestSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bolt")
+
+	c, err := Open(path)
+	require.NoError(t, err)
+	assert.NoError(t, c.Set(nil, "pixlet:clock:key", []byte("value"), 60))
+	assert.NoError(t, c.Close())
+
+	c, err = Open(path)
+	require.NoError(t, err)
+	defer c.Close()
+
+	val, found, err := c.Get(nil, "pixlet:clock:key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+}
+
+func TestOpenCacheURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bolt")
+
+	c, err := runtime.OpenCache("bolt://" + path)
+	require.NoError(t, err)
+	defer c.(*Cache).Close()
+
+	u, _ := url.Parse("bolt://relative/cache.bolt")
+	assert.Equal(t, "relative/cache.bolt", PathFromURL(u))
+
+	u, _ = url.Parse("bolt:cache.bolt")
+	assert.Equal(t, "cache.bolt", PathFromURL(u))
+
+	_, err = Open("")
+	assert.Error(t, err)
+}
+
+func TestEntries(t *testing.T) {
+	c := open(t)
+
+	assert.NoError(t, c.Set(nil, "pixlet:clock:live", []byte("value"), 60))
+	assert.NoError(t, c.Set(nil, "pixlet:clock:expired", []byte("value"), -1))
+	assert.NoError(t, c.Set(nil, "shared", []byte("va"), 60))
+
+	entries, err := c.Entries()
+	assert.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "shared", entries[0].Key)
+	assert.Equal(t, int64(2), entries[0].Size)
+	assert.Equal(t, "pixlet:clock:live", entries[1].Key)
+	assert.Equal(t, int64(5), entries[1].Size)
+	assert.InDelta(t, 60, entries[1].TTL().Seconds(), 1)
+}
+
+func TestDelete(t *testing.T) {
+	c := open(t)
+
+	assert.NoError(t, c.Set(nil, "pixlet:clock:key", []byte("value"), 60))
+	assert.NoError(t, c.Delete("pixlet:clock:key"))
+	assert.NoError(t, c.Delete("missing"))
+
+	_, found, err := c.Get(nil, "pixlet:clock:key")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// flushing an app goes through its bucket
+	stats := runtime.NewStatsCache(c)
+	assert.NoError(t, c.Set(nil, "pixlet:clock:a", []byte("value"), 60))
+	assert.NoError(t, c.Set(nil, "pixlet:weather:b", []byte("value"), 60))
+	n, err := stats.Flush("clock")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	entries, err := c.Entries()
+	assert.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "pixlet:weather:b", entries[0].Key)
+}
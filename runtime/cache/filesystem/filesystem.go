@@ -0,0 +1,420 @@
+// Package filesystem provides a cache backend that stores each entry as a
+// file in a directory. It has no dependencies beyond a writable directory,
+// which makes it a good fit for small deployments such as a Raspberry Pi.
+//
+// Importing this package registers the "file" cache URL scheme, and "fs" as
+// a shorter alias for it. The optional max_bytes query parameter bounds the
+// total size of the cache directory:
+//
+//	file:///var/cache/pixlet?max_bytes=52428800
+//	fs:/var/cache/pixlet
+package filesystem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+const (
+	// Scheme is the cache URL scheme handled by this package.
+	Scheme = "file"
+
+	// ShortScheme is an alias for Scheme, for --cache=fs:/path.
+	ShortScheme = "fs"
+
+	// DefaultMaxBytes is the size limit used when none is configured.
+	DefaultMaxBytes = 64 * 1024 * 1024 // 64MB
+
+	// LowWaterPercent is how full, as a percentage of its size limit, the
+	// cache is left when it's gone over the limit. Leaving room means the
+	// directory isn't scanned again on the very next write.
+	LowWaterPercent = 90
+
+	// StaleTempAge is how old a temporary file has to be for GC to take it
+	// as left behind by a write that crashed, rather than one in progress.
+	StaleTempAge = 10 * time.Minute
+
+	entrySuffix = ".entry"
+	tempPattern = "tmp-*"
+	headerSize  = 8 + 4
+)
+
+var errCorruptEntry = errors.New("corrupt cache entry")
+
+func init() {
+	runtime.RegisterCache(Scheme, openURL)
+	runtime.RegisterCache(ShortScheme, openURL)
+}
+
+// openURL opens the cache in the directory a file or fs URL names.
+func openURL(u *url.URL) (runtime.Cache, error) {
+	maxBytes := int64(DefaultMaxBytes)
+	if v := u.Query().Get("max_bytes"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid max_bytes: %q", v)
+		}
+		maxBytes = n
+	}
+
+	dir := u.Path
+	if u.Opaque != "" {
+		dir = u.Opaque
+	} else if u.Host != "" {
+		dir = u.Host + u.Path
+	}
+
+	return Open(dir, maxBytes)
+}
+
+// Cache is a runtime.Cache that stores one file per key. Each file starts
+// with a small header holding the expiration time and the original key,
+// followed by the cached value.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mutex   sync.Mutex
+	size    int64
+	onEvict func(key string)
+}
+
+// Open opens the cache rooted at dir, creating the directory if needed. Once
+// the total size of all entries exceeds maxBytes, expired entries and then
+// the entries closest to expiring are removed, until it's down to
+// LowWaterPercent of maxBytes.
+func Open(dir string, maxBytes int64) (*Cache, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("filesystem cache requires a directory")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	c := &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+	}
+
+	if err := c.GC(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Cache) Get(thread *starlark.Thread, key string) ([]byte, bool, error) {
+	value, _, found, err := c.GetWithExpiration(thread, key)
+	return value, found, err
+}
+
+func (c *Cache) GetWithExpiration(_ *starlark.Thread, key string) ([]byte, time.Time, bool, error) {
+	b, err := os.ReadFile(c.pathFor(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, time.Time{}, false, nil
+	} else if err != nil {
+		return nil, time.Time{}, false, err
+	}
+
+	e, err := decodeEntry(b)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+
+	if e.key != key || time.Now().After(e.expiration) {
+		return nil, time.Time{}, false, nil
+	}
+
+	return e.value, e.expiration, true, nil
+}
+
+func (c *Cache) Set(_ *starlark.Thread, key string, value []byte, ttl int64) error {
+	path := c.pathFor(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	b := encodeEntry(entry{
+		key:        key,
+		value:      value,
+		expiration: time.Now().Add(time.Duration(ttl) * time.Second),
+	})
+
+	// write to a temporary file and rename it into place so that readers
+	// never observe a partially written entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempPattern)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var previous int64
+	if info, err := os.Stat(path); err == nil {
+		previous = info.Size()
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.size += int64(len(b)) - previous
+	if c.size > c.maxBytes {
+		return c.gcLocked()
+	}
+
+	return nil
+}
+
+// OnEvict sets a function to call with the key of each entry removed to
+// keep the directory within its size limit.
+func (c *Cache) OnEvict(fn func(key string)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.onEvict = fn
+}
+
+func (c *Cache) Delete(key string) error {
+	path := c.pathFor(key)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	c.size -= info.Size()
+	return nil
+}
+
+func (c *Cache) Entries() ([]runtime.CacheEntry, error) {
+	now := time.Now()
+	var entries []runtime.CacheEntry
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != entrySuffix {
+			return nil
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			// removed by a concurrent GC
+			return nil
+		}
+
+		e, err := decodeEntry(b)
+		if err != nil || now.After(e.expiration) {
+			return nil
+		}
+
+		entries = append(entries, runtime.CacheEntry{
+			Key:        e.key,
+			Size:       int64(len(e.value)),
+			Expiration: e.expiration,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning cache directory: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GC removes expired entries and stale temporary files. If the cache is
+// still over its size limit, it then removes the entries closest to expiring
+// until it's down to LowWaterPercent of the limit.
+func (c *Cache) GC() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.gcLocked()
+}
+
+func (c *Cache) gcLocked() error {
+	type file struct {
+		path       string
+		key        string
+		size       int64
+		expiration time.Time
+	}
+
+	now := time.Now()
+	var files []file
+	var total int64
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		if ok, _ := filepath.Match(tempPattern, d.Name()); ok {
+			if now.Sub(info.ModTime()) > StaleTempAge {
+				os.Remove(path)
+			}
+			return nil
+		}
+		if filepath.Ext(path) != entrySuffix {
+			return nil
+		}
+
+		key, expiration, err := readHeader(path)
+		if err != nil || now.After(expiration) {
+			os.Remove(path)
+			return nil
+		}
+
+		files = append(files, file{path, key, info.Size(), expiration})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("scanning cache directory: %w", err)
+	}
+
+	if total > c.maxBytes {
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].expiration.Before(files[j].expiration)
+		})
+
+		lowWater := c.maxBytes * LowWaterPercent / 100
+		for _, f := range files {
+			if total <= lowWater {
+				break
+			}
+			if err := os.Remove(f.path); err == nil {
+				total -= f.size
+				if c.onEvict != nil {
+					c.onEvict(f.key)
+				}
+			}
+		}
+	}
+
+	c.size = total
+	return nil
+}
+
+func (c *Cache) pathFor(key string) string {
+	h := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(h[:])
+	return filepath.Join(c.dir, name[:2], name+entrySuffix)
+}
+
+type entry struct {
+	key        string
+	value      []byte
+	expiration time.Time
+}
+
+func encodeEntry(e entry) []byte {
+	var buf bytes.Buffer
+	buf.Grow(headerSize + len(e.key) + len(e.value))
+	binary.Write(&buf, binary.BigEndian, e.expiration.UnixNano())
+	binary.Write(&buf, binary.BigEndian, uint32(len(e.key)))
+	buf.WriteString(e.key)
+	buf.Write(e.value)
+	return buf.Bytes()
+}
+
+func decodeEntry(b []byte) (entry, error) {
+	if len(b) < headerSize {
+		return entry{}, errCorruptEntry
+	}
+
+	expiration := int64(binary.BigEndian.Uint64(b[0:8]))
+	keyLen := int(binary.BigEndian.Uint32(b[8:12]))
+	if len(b) < headerSize+keyLen {
+		return entry{}, errCorruptEntry
+	}
+
+	return entry{
+		key:        string(b[headerSize : headerSize+keyLen]),
+		value:      b[headerSize+keyLen:],
+		expiration: time.Unix(0, expiration),
+	}, nil
+}
+
+// readHeader reads the key and expiration time of the entry at path,
+// without reading its value.
+func readHeader(path string) (string, time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var header struct {
+		Expiration int64
+		KeyLen     uint32
+	}
+	if err := binary.Read(f, binary.BigEndian, &header); err != nil {
+		return "", time.Time{}, errCorruptEntry
+	}
+
+	// the length comes from disk, so it's checked before it's allocated
+	if int64(header.KeyLen) > info.Size()-headerSize {
+		return "", time.Time{}, errCorruptEntry
+	}
+
+	key := make([]byte, header.KeyLen)
+	if _, err := io.ReadFull(f, key); err != nil {
+		return "", time.Time{}, errCorruptEntry
+	}
+
+	return string(key), time.Unix(0, header.Expiration), nil
+}
@@ -0,0 +1,194 @@
+package filesystem
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func TestGetAndSet(t *testing.T) {
+	c, err := Open(t.TempDir(), DefaultMaxBytes)
+	require.NoError(t, err)
+
+	_, found, err := c.Get(nil, "missing")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, c.Set(nil, "key", []byte("one"), 60))
+	assert.NoError(t, c.Set(nil, "key", []byte("two"), 60))
+
+	val, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("two"), val)
+}
+
+func TestExpiration(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, DefaultMaxBytes)
+	require.NoError(t, err)
+
+	assert.NoError(t, c.Set(nil, "expired", []byte("gone"), -1))
+	_, found, err := c.Get(nil, "expired")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, c.GC())
+	_, err = os.Stat(c.pathFor("expired"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSizeLimit(t *testing.T) {
+	// each entry takes up 113 bytes
+	value := []byte(strings.Repeat("x", 100))
+	c, err := Open(t.TempDir(), 350)
+	require.NoError(t, err)
+
+	var evicted []string
+	c.OnEvict(func(key string) { evicted = append(evicted, key) })
+
+	// entries expiring sooner are evicted first, until the cache is down
+	// to 90% of its limit
+	assert.NoError(t, c.Set(nil, "a", value, 10))
+	assert.NoError(t, c.Set(nil, "b", value, 20))
+	assert.NoError(t, c.Set(nil, "c", value, 30))
+	assert.NoError(t, c.Set(nil, "d", value, 40))
+
+	for _, key := range []string{"a", "b"} {
+		_, found, _ := c.Get(nil, key)
+		assert.False(t, found, key)
+	}
+	for _, key := range []string{"c", "d"} {
+		_, found, _ := c.Get(nil, key)
+		assert.True(t, found, key)
+	}
+	assert.LessOrEqual(t, c.size, int64(315))
+	assert.Equal(t, []string{"a", "b"}, evicted)
+
+	// which leaves room for the next entry
+	assert.NoError(t, c.Set(nil, "e", value, 50))
+	assert.Equal(t, []string{"a", "b"}, evicted)
+	assert.Equal(t, int64(339), c.size)
+}
+
+func TestGCRemovesStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, DefaultMaxBytes)
+	require.NoError(t, err)
+
+	stale := filepath.Join(dir, "ab", "tmp-1")
+	fresh := filepath.Join(dir, "ab", "tmp-2")
+	require.NoError(t, os.MkdirAll(filepath.Dir(stale), 0755))
+	require.NoError(t, os.WriteFile(stale, []byte("ada"), 0644))
+	require.NoError(t, os.WriteFile(fresh, []byte("ada"), 0644))
+	old := time.Now().Add(-2 * StaleTempAge)
+	require.NoError(t, os.Chtimes(stale, old, old))
+
+	require.NoError(t, c.GC())
+
+	assert.NoFileExists(t, stale)
+
+	// it may belong to a write that's still going
+	assert.FileExists(t, fresh)
+}
+
+func TestCorruptEntries(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, DefaultMaxBytes)
+	require.NoError(t, err)
+	require.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+
+	// a key length far past the end of the file
+	path := c.pathFor("key")
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	binary.BigEndian.PutUint32(b[8:12], 0xffffffff)
+	require.NoError(t, os.WriteFile(path, b, 0644))
+
+	_, _, err = readHeader(path)
+	assert.ErrorIs(t, err, errCorruptEntry)
+
+	_, found, err := c.Get(nil, "key")
+	assert.ErrorIs(t, err, errCorruptEntry)
+	assert.False(t, found)
+
+	// and GC gets rid of it
+	require.NoError(t, c.GC())
+	assert.NoFileExists(t, path)
+}
+
+func TestSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := Open(dir, DefaultMaxBytes)
+	require.NoError(t, err)
+	assert.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+
+	c, err = Open(dir, DefaultMaxBytes)
+	require.NoError(t, err)
+
+	val, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+}
+
+func TestOpenCacheURL(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+
+	c, err := runtime.OpenCache("file://" + dir + "?max_bytes=1024")
+	require.NoError(t, err)
+	assert.Equal(t, dir, c.(*Cache).dir)
+	assert.Equal(t, int64(1024), c.(*Cache).maxBytes)
+
+	_, err = runtime.OpenCache("file://" + dir + "?max_bytes=lots")
+	assert.Error(t, err)
+
+	// fs: is short for file://
+	c, err = runtime.OpenCache("fs:" + dir)
+	require.NoError(t, err)
+	assert.Equal(t, dir, c.(*Cache).dir)
+	assert.Equal(t, int64(DefaultMaxBytes), c.(*Cache).maxBytes)
+
+	t.Chdir(t.TempDir())
+	c, err = runtime.OpenCache("fs:cache")
+	require.NoError(t, err)
+	assert.Equal(t, "cache", c.(*Cache).dir)
+}
+
+func TestEntries(t *testing.T) {
+	c, err := Open(t.TempDir(), DefaultMaxBytes)
+	require.NoError(t, err)
+
+	assert.NoError(t, c.Set(nil, "live", []byte("value"), 60))
+	assert.NoError(t, c.Set(nil, "expired", []byte("value"), -1))
+
+	entries, err := c.Entries()
+	assert.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "live", entries[0].Key)
+	assert.Equal(t, int64(5), entries[0].Size)
+	assert.InDelta(t, 60, entries[0].TTL().Seconds(), 1)
+}
+
+func TestDelete(t *testing.T) {
+	c, err := Open(t.TempDir(), DefaultMaxBytes)
+	require.NoError(t, err)
+
+	assert.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+	assert.NoError(t, c.Delete("key"))
+	assert.NoError(t, c.Delete("missing"))
+
+	_, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, int64(0), c.size)
+}
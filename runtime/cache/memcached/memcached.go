@@ -0,0 +1,354 @@
+// Package memcached provides a cache backend that stores entries in one or
+// more memcached servers, for deployments that already run memcached.
+//
+// Importing this package registers the "memcached" cache URL scheme. Servers
+// are given as a comma separated list of addresses, with 11211 as the default
+// port, and the optional prefix query parameter is prepended to every key so
+// that several deployments can share the servers:
+//
+//	memcached://localhost
+//	memcached://cache1:11211,cache2:11211?prefix=pixlet:
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+const (
+	// Scheme is the cache URL scheme handled by this package.
+	Scheme = "memcached"
+
+	// DefaultPort is the port of servers given without one.
+	DefaultPort = "11211"
+
+	// DefaultTimeout bounds connecting to a server and each request to it.
+	DefaultTimeout = time.Second
+
+	// maxKeyLength is the longest key memcached accepts.
+	maxKeyLength = 250
+
+	// maxRelativeTTL is the longest expiration memcached takes as relative
+	// to now. Longer ones are taken as Unix times.
+	maxRelativeTTL = 30 * 24 * 60 * 60
+
+	// maxIdleConns is how many connections to each server are kept open
+	// for reuse.
+	maxIdleConns = 4
+)
+
+func init() {
+	runtime.RegisterCache(Scheme, openURL)
+}
+
+// openURL opens the cache on the servers a memcached URL names.
+func openURL(u *url.URL) (runtime.Cache, error) {
+	var servers []string
+	if u.Host != "" {
+		servers = strings.Split(u.Host, ",")
+	}
+
+	opts := Options{Prefix: u.Query().Get("prefix")}
+	if v := u.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout: %q", v)
+		}
+		opts.Timeout = d
+	}
+
+	return New(servers, opts)
+}
+
+// Options configure a Cache.
+type Options struct {
+	// Prefix is prepended to every key.
+	Prefix string
+
+	// Timeout bounds connecting to a server and each request to it. Zero
+	// means DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Cache is a runtime.Cache that stores entries in memcached. Keys are
+// spread over the servers by their hash, so each key lives on one server.
+type Cache struct {
+	servers []*server
+	prefix  string
+	timeout time.Duration
+}
+
+// New returns a cache that stores entries in the memcached servers at
+// addrs. It doesn't connect to them until it's used.
+func New(addrs []string, opts Options) (*Cache, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("memcached cache requires at least one server")
+	}
+
+	c := &Cache{
+		prefix:  opts.Prefix,
+		timeout: opts.Timeout,
+	}
+	if c.timeout <= 0 {
+		c.timeout = DefaultTimeout
+	}
+
+	for _, addr := range addrs {
+		if addr == "" {
+			return nil, fmt.Errorf("empty server address")
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, DefaultPort)
+		}
+		c.servers = append(c.servers, &server{addr: addr})
+	}
+
+	return c, nil
+}
+
+func (c *Cache) Get(_ *starlark.Thread, key string) ([]byte, bool, error) {
+	key = c.key(key)
+
+	var value []byte
+	var found bool
+	err := c.do(key, func(rw *bufio.ReadWriter) error {
+		fmt.Fprintf(rw, "get %s\r\n", key)
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+
+		line, err := readLine(rw.Reader)
+		if err != nil {
+			return err
+		}
+		if line == "END" {
+			return nil
+		}
+
+		// VALUE <key> <flags> <bytes>
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[0] != "VALUE" {
+			return fmt.Errorf("unexpected response: %q", line)
+		}
+		size, err := strconv.Atoi(fields[3])
+		if err != nil || size < 0 {
+			return fmt.Errorf("unexpected response: %q", line)
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rw, buf); err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(buf, []byte("\r\n")) {
+			return fmt.Errorf("corrupt value for %s", key)
+		}
+		if line, err = readLine(rw.Reader); err != nil {
+			return err
+		} else if line != "END" {
+			return fmt.Errorf("unexpected response: %q", line)
+		}
+
+		value, found = buf[:size], true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, found, nil
+}
+
+func (c *Cache) Set(_ *starlark.Thread, key string, value []byte, ttl int64) error {
+	key = c.key(key)
+
+	if ttl <= 0 {
+		// memcached takes 0 to mean never expiring, whereas the entry
+		// should be gone already
+		return c.delete(key)
+	}
+	if ttl > maxRelativeTTL {
+		ttl += time.Now().Unix()
+	}
+
+	return c.do(key, func(rw *bufio.ReadWriter) error {
+		fmt.Fprintf(rw, "set %s 0 %d %d\r\n", key, ttl, len(value))
+		rw.Write(value)
+		rw.WriteString("\r\n")
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+
+		line, err := readLine(rw.Reader)
+		if err != nil {
+			return err
+		}
+		if line != "STORED" {
+			return fmt.Errorf("storing %s: %s", key, line)
+		}
+		return nil
+	})
+}
+
+// Delete removes key from the server that holds it.
+func (c *Cache) Delete(key string) error {
+	return c.delete(c.key(key))
+}
+
+func (c *Cache) delete(key string) error {
+	return c.do(key, func(rw *bufio.ReadWriter) error {
+		fmt.Fprintf(rw, "delete %s\r\n", key)
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+
+		line, err := readLine(rw.Reader)
+		if err != nil {
+			return err
+		}
+		if line != "DELETED" && line != "NOT_FOUND" {
+			return fmt.Errorf("deleting %s: %s", key, line)
+		}
+		return nil
+	})
+}
+
+// Close closes the connections kept open to the servers.
+func (c *Cache) Close() error {
+	for _, s := range c.servers {
+		s.close()
+	}
+	return nil
+}
+
+// key returns the memcached key for key. Keys that memcached can't take,
+// because they're too long or have spaces or control characters in them,
+// are hashed.
+func (c *Cache) key(key string) string {
+	key = c.prefix + key
+	if len(key) <= maxKeyLength && !strings.ContainsFunc(key, func(r rune) bool { return r <= ' ' || r == 0x7f }) {
+		return key
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// do runs fn with a connection to the server that holds key. Connections
+// that fail are closed, and the others are kept for the next request.
+func (c *Cache) do(key string, fn func(rw *bufio.ReadWriter) error) error {
+	s := c.servers[crc32.ChecksumIEEE([]byte(key))%uint32(len(c.servers))]
+
+	conn, err := s.conn(c.timeout)
+	if err != nil {
+		return fmt.Errorf("connecting to memcached at %s: %w", s.addr, err)
+	}
+
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	err = fn(conn.rw)
+
+	var protoErr *protocolError
+	if err != nil && !errors.As(err, &protoErr) {
+		conn.Close()
+	} else {
+		s.release(conn)
+	}
+	if err != nil {
+		return fmt.Errorf("memcached at %s: %w", s.addr, err)
+	}
+	return nil
+}
+
+// server is a memcached server, with the connections to it that are idle.
+type server struct {
+	addr string
+
+	mu   sync.Mutex
+	idle []*conn
+}
+
+type conn struct {
+	net.Conn
+	rw *bufio.ReadWriter
+}
+
+func (s *server) conn(timeout time.Duration) (*conn, error) {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		c := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return c, nil
+	}
+	s.mu.Unlock()
+
+	nc, err := net.DialTimeout("tcp", s.addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{
+		Conn: nc,
+		rw:   bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc)),
+	}, nil
+}
+
+func (s *server) release(c *conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.idle) >= maxIdleConns {
+		c.Close()
+		return
+	}
+	s.idle = append(s.idle, c)
+}
+
+func (s *server) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.idle {
+		c.Close()
+	}
+	s.idle = nil
+}
+
+// protocolError is an error the server responded with, after which the
+// connection can still be used.
+type protocolError struct {
+	msg string
+}
+
+func (e *protocolError) Error() string {
+	return e.msg
+}
+
+// readLine reads a line of a response, and returns the errors the server
+// responds with as a *protocolError.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+
+	if line == "ERROR" || strings.HasPrefix(line, "CLIENT_ERROR ") || strings.HasPrefix(line, "SERVER_ERROR ") {
+		return "", &protocolError{msg: line}
+	}
+	return line, nil
+}
@@ -0,0 +1,178 @@
+package memcached
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+// fakeServer speaks enough of the memcached protocol to test against, and
+// records the keys and expirations it's sent.
+type fakeServer struct {
+	addr string
+
+	mu      sync.Mutex
+	values  map[string][]byte
+	expires map[string]int64
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeServer{
+		addr:    l.Addr().String(),
+		values:  map[string][]byte{},
+		expires: map[string]int64{},
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+
+		s.mu.Lock()
+		switch fields[0] {
+		case "get":
+			if v, ok := s.values[fields[1]]; ok {
+				fmt.Fprintf(c, "VALUE %s 0 %d\r\n%s\r\n", fields[1], len(v), v)
+			}
+			io.WriteString(c, "END\r\n")
+		case "set":
+			exp, _ := strconv.ParseInt(fields[3], 10, 64)
+			size, _ := strconv.Atoi(fields[4])
+			buf := make([]byte, size+2)
+			io.ReadFull(r, buf)
+			if size > 16 {
+				io.WriteString(c, "SERVER_ERROR object too large for cache\r\n")
+				break
+			}
+			s.values[fields[1]] = buf[:size]
+			s.expires[fields[1]] = exp
+			io.WriteString(c, "STORED\r\n")
+		case "delete":
+			delete(s.values, fields[1])
+			io.WriteString(c, "DELETED\r\n")
+		default:
+			io.WriteString(c, "ERROR\r\n")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestGetAndSet(t *testing.T) {
+	s := newFakeServer(t)
+	c, err := New([]string{s.addr}, Options{Prefix: "pixlet:"})
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, found, err := c.Get(nil, "missing")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, c.Set(nil, "key", []byte("one"), 60))
+	assert.NoError(t, c.Set(nil, "key", []byte("two"), 60))
+
+	val, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("two"), val)
+	assert.Equal(t, int64(60), s.expires["pixlet:key"])
+
+	// errors from the server are returned, and the connection is reused
+	assert.ErrorContains(t, c.Set(nil, "big", make([]byte, 32), 60), "object too large")
+	_, found, err = c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	// entries that have expired already are removed
+	assert.NoError(t, c.Set(nil, "key", []byte("gone"), -1))
+	_, found, err = c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, c.Set(nil, "key", []byte("three"), 60))
+	assert.NoError(t, c.Delete("key"))
+	assert.NoError(t, c.Delete("key"))
+	_, found, err = c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestKeys(t *testing.T) {
+	s := newFakeServer(t)
+	c, err := New([]string{s.addr}, Options{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// keys memcached can't take are hashed
+	long := strings.Repeat("a", 300)
+	for _, key := range []string{"https://example.com/a b", long} {
+		require.NoError(t, c.Set(nil, key, []byte("v"), 60))
+		val, found, err := c.Get(nil, key)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []byte("v"), val)
+	}
+	for key := range s.values {
+		assert.True(t, strings.HasPrefix(key, "sha256:"), key)
+	}
+
+	// long expirations are sent as Unix times
+	require.NoError(t, c.Set(nil, "year", []byte("v"), 365*24*60*60))
+	assert.Greater(t, s.expires["year"], int64(maxRelativeTTL))
+}
+
+func TestOpenCacheURL(t *testing.T) {
+	s := newFakeServer(t)
+	other := newFakeServer(t)
+
+	c, err := runtime.OpenCache(fmt.Sprintf("memcached://%s,%s?prefix=ada:&timeout=2s", s.addr, other.addr))
+	require.NoError(t, err)
+	mc := c.(*Cache)
+	defer mc.Close()
+	assert.Len(t, mc.servers, 2)
+	assert.Equal(t, "ada:", mc.prefix)
+
+	// keys are spread over the servers
+	for i := range 20 {
+		require.NoError(t, c.Set(nil, strconv.Itoa(i), []byte("v"), 60))
+	}
+	assert.NotEmpty(t, s.values)
+	assert.NotEmpty(t, other.values)
+
+	c, err = runtime.OpenCache("memcached://localhost")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:11211", c.(*Cache).servers[0].addr)
+
+	_, err = runtime.OpenCache("memcached://")
+	assert.ErrorContains(t, err, "at least one server")
+}
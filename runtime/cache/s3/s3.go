@@ -0,0 +1,415 @@
+// Package s3 provides a cache backend that stores entries as objects in an
+// S3-compatible bucket, such as on AWS, MinIO or Cloudflare R2, so that the
+// replicas of a render fleet share the API responses they cache.
+//
+// Importing this package registers the "s3" cache URL scheme. The bucket
+// comes first, followed by an optional prefix for every object's key. The
+// endpoint and region are query parameters, and credentials are taken from
+// the URL or from the usual AWS environment variables:
+//
+//	s3://pixlet-cache?region=eu-west-1
+//	s3://pixlet-cache/render/?endpoint=http://minio:9000
+//	s3://access:secret@pixlet-cache?endpoint=https://account.r2.cloudflarestorage.com&region=auto
+//
+// Objects don't expire on their own, so expired entries are only treated as
+// missing. A lifecycle rule on the bucket should delete them, a day or so
+// after they were written.
+package s3
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+const (
+	// Scheme is the cache URL scheme handled by this package.
+	Scheme = "s3"
+
+	// DefaultRegion is the region of buckets whose region isn't given.
+	DefaultRegion = "us-east-1"
+
+	// DefaultTimeout bounds each request to the object store.
+	DefaultTimeout = 5 * time.Second
+
+	// expiresHeader is the object metadata that holds when an entry
+	// expires, in Unix milliseconds.
+	expiresHeader = "X-Amz-Meta-Pixlet-Expires"
+
+	// maxKeyLength is the longest object key S3 accepts.
+	maxKeyLength = 1024
+
+	// emptyPayloadHash is the SHA-256 of an empty body.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+func init() {
+	runtime.RegisterCache(Scheme, openURL)
+}
+
+// openURL opens the cache in the bucket an s3 URL names.
+func openURL(u *url.URL) (runtime.Cache, error) {
+	q := u.Query()
+	opts := Options{
+		Bucket:       u.Host,
+		Prefix:       strings.TrimPrefix(u.Path, "/"),
+		Endpoint:     q.Get("endpoint"),
+		Region:       q.Get("region"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if opts.Region == "" {
+		opts.Region = os.Getenv("AWS_REGION")
+	}
+	if u.User != nil {
+		opts.AccessKey = u.User.Username()
+		opts.SecretKey, _ = u.User.Password()
+		opts.SessionToken = ""
+	}
+	if v := q.Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout: %q", v)
+		}
+		opts.Timeout = d
+	}
+
+	return New(opts)
+}
+
+// Options configure a Cache.
+type Options struct {
+	// Endpoint is the URL of the object store. Empty means AWS, in
+	// Region.
+	Endpoint string
+
+	// Region is the bucket's region. Empty means DefaultRegion. Stores
+	// that don't have regions, such as R2, take "auto".
+	Region string
+
+	// Bucket is the name of the bucket entries are kept in.
+	Bucket string
+
+	// Prefix is prepended to the key of every object.
+	Prefix string
+
+	// AccessKey, SecretKey and the optional SessionToken are the
+	// credentials requests are signed with.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	// Timeout bounds each request to the object store. Zero means
+	// DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Cache is a runtime.Cache that stores each entry as an object in an
+// S3-compatible bucket. Buckets are addressed by path, which every store
+// understands.
+type Cache struct {
+	client       *http.Client
+	endpoint     *url.URL
+	region       string
+	bucket       string
+	prefix       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+
+	// now is when requests are signed. Tests set it.
+	now func() time.Time
+}
+
+// New returns a cache that stores entries in the bucket opts names. It
+// doesn't connect to the object store until it's used.
+func New(opts Options) (*Cache, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("s3 cache requires a bucket")
+	}
+	if opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, fmt.Errorf("s3 cache requires credentials, in the URL or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	c := &Cache{
+		client:       &http.Client{Timeout: opts.Timeout},
+		region:       opts.Region,
+		bucket:       opts.Bucket,
+		prefix:       opts.Prefix,
+		accessKey:    opts.AccessKey,
+		secretKey:    opts.SecretKey,
+		sessionToken: opts.SessionToken,
+		now:          time.Now,
+	}
+	if c.client.Timeout <= 0 {
+		c.client.Timeout = DefaultTimeout
+	}
+	if c.region == "" {
+		c.region = DefaultRegion
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + c.region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid endpoint: %q", endpoint)
+	}
+	c.endpoint = u
+
+	return c, nil
+}
+
+func (c *Cache) Get(thread *starlark.Thread, key string) ([]byte, bool, error) {
+	value, _, found, err := c.GetWithExpiration(thread, key)
+	return value, found, err
+}
+
+func (c *Cache) GetWithExpiration(_ *starlark.Thread, key string) ([]byte, time.Time, bool, error) {
+	resp, err := c.do(http.MethodGet, c.key(key), nil, nil, nil)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := responseError(resp)
+		if errors.Is(err, errNoSuchKey) {
+			return nil, time.Time{}, false, nil
+		}
+		return nil, time.Time{}, false, fmt.Errorf("getting %s: %w", key, err)
+	}
+
+	exp := expiration(resp.Header)
+	if !exp.After(time.Now()) {
+		return nil, time.Time{}, false, nil
+	}
+
+	value, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("getting %s: %w", key, err)
+	}
+
+	return value, exp, true, nil
+}
+
+func (c *Cache) Set(_ *starlark.Thread, key string, value []byte, ttl int64) error {
+	if ttl <= 0 {
+		// the entry should be gone already
+		return c.Delete(key)
+	}
+
+	expiresAt := time.Now().Add(time.Duration(ttl) * time.Second)
+	header := http.Header{}
+	header.Set(expiresHeader, strconv.FormatInt(expiresAt.UnixMilli(), 10))
+
+	resp, err := c.do(http.MethodPut, c.key(key), nil, header, value)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("storing %s: %w", key, responseError(resp))
+	}
+	return nil
+}
+
+// Delete removes the object key is stored as. Deleting a key that isn't
+// stored succeeds.
+func (c *Cache) Delete(key string) error {
+	resp, err := c.do(http.MethodDelete, c.key(key), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		if err := responseError(resp); !errors.Is(err, errNoSuchKey) {
+			return fmt.Errorf("deleting %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Entries lists the live entries under the prefix. Listing doesn't say when
+// objects expire, so each one is looked up as well, which makes it slow on
+// a large cache. Keys that were hashed to be stored are listed hashed.
+func (c *Cache) Entries() ([]runtime.CacheEntry, error) {
+	now := time.Now()
+
+	var entries []runtime.CacheEntry
+	token := ""
+	for {
+		page, err := c.list(token)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range page.Contents {
+			exp, err := c.head(obj.Key)
+			if err != nil {
+				return nil, err
+			}
+			if !exp.After(now) {
+				continue
+			}
+
+			entries = append(entries, runtime.CacheEntry{
+				Key:        strings.TrimPrefix(obj.Key, c.prefix),
+				Size:       obj.Size,
+				Expiration: exp,
+			})
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return entries, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// listResult is the part of a ListObjectsV2 response the cache needs.
+type listResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key  string
+		Size int64
+	}
+}
+
+// list lists a page of the objects under the prefix, starting at token.
+func (c *Cache) list(token string) (*listResult, error) {
+	query := url.Values{"list-type": {"2"}, "prefix": {c.prefix}}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+
+	resp, err := c.do(http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing %s: %w", c.bucket, responseError(resp))
+	}
+
+	var result listResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("listing %s: %w", c.bucket, err)
+	}
+	return &result, nil
+}
+
+// head returns when the object at objectKey expires. Objects that are gone
+// have already expired.
+func (c *Cache) head(objectKey string) (time.Time, error) {
+	resp, err := c.do(http.MethodHead, objectKey, nil, nil, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return expiration(resp.Header), nil
+	case http.StatusNotFound:
+		return time.Time{}, nil
+	default:
+		return time.Time{}, fmt.Errorf("looking up %s: %s", objectKey, resp.Status)
+	}
+}
+
+// key returns the object key for key. Keys that S3 can't take, because
+// they're too long or aren't UTF-8, are hashed.
+func (c *Cache) key(key string) string {
+	key = c.prefix + key
+	if len(key) <= maxKeyLength && utf8.ValidString(key) {
+		return key
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return c.prefix + "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// do sends a signed request for the object at objectKey, or for the bucket
+// if objectKey is empty.
+func (c *Cache) do(method, objectKey string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.bucket + "/" + objectKey
+	u.RawPath = escape(u.Path, false)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		payloadHash = hashHex(body)
+	}
+	c.sign(req, payloadHash, c.now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %s from %s: %w", objectKey, c.endpoint.Host, err)
+	}
+	return resp, nil
+}
+
+// errNoSuchKey is what responseError returns for objects that don't exist.
+var errNoSuchKey = errors.New("no such key")
+
+// responseError reads the error in resp, which S3 describes in XML. A 404
+// without a description, as for HEAD requests, is taken as a missing key.
+func responseError(resp *http.Response) error {
+	var e struct {
+		Code    string
+		Message string
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := xml.Unmarshal(body, &e); err != nil || e.Code == "" {
+		if resp.StatusCode == http.StatusNotFound {
+			return errNoSuchKey
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+
+	if e.Code == "NoSuchKey" {
+		return errNoSuchKey
+	}
+	return fmt.Errorf("%s: %s: %s", resp.Status, e.Code, e.Message)
+}
+
+// expiration returns when an object expires, from its metadata. Objects
+// without it weren't written by the cache, and have already expired.
+func expiration(header http.Header) time.Time {
+	ms, err := strconv.ParseInt(header.Get(expiresHeader), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,251 @@
+//go:build !js && !wasm
+
+// Package sqlite provides a cache backend that persists entries to a single
+// SQLite database file, so that cached data survives restarts.
+//
+// Importing this package registers the "sqlite" cache URL scheme:
+//
+//	sqlite:///var/lib/pixlet/cache.db
+//	sqlite://cache.db
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+	_ "modernc.org/sqlite"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+const (
+	// Scheme is the cache URL scheme handled by this package.
+	Scheme = "sqlite"
+
+	// PurgeInterval is how often expired entries are deleted from the
+	// database, and the space they took up is given back.
+	PurgeInterval = 5 * time.Minute
+
+	// autoVacuumIncremental is the value of the auto_vacuum pragma that lets
+	// incremental_vacuum shrink the file.
+	autoVacuumIncremental = 2
+)
+
+func init() {
+	runtime.RegisterCache(Scheme, func(u *url.URL) (runtime.Cache, error) {
+		return Open(PathFromURL(u))
+	})
+}
+
+// Cache is a runtime.Cache that stores entries in SQLite.
+type Cache struct {
+	db   *sql.DB
+	done chan struct{}
+	once sync.Once
+}
+
+// PathFromURL extracts the database path from a sqlite:// URL. Both
+// sqlite:///abs/path.db and sqlite://relative/path.db are accepted.
+func PathFromURL(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+	return u.Host + u.Path
+}
+
+// NewSQLiteCache opens (creating if necessary) a cache that persists entries
+// to the SQLite database at path, so that they survive restarts. Expired
+// entries are deleted, and the file vacuumed, every few minutes.
+func NewSQLiteCache(path string) (runtime.Cache, error) {
+	c, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Open opens (creating if necessary) the SQLite cache database at path.
+func Open(path string) (*Cache, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sqlite cache requires a database path")
+	}
+
+	// the path is escaped, so that ? and # in it aren't taken for the start
+	// of the query
+	dsn := (&url.URL{
+		Scheme:   "file",
+		Opaque:   (&url.URL{Path: path}).EscapedPath(),
+		RawQuery: "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+	}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	// SQLite only supports a single writer, so serialize access rather than
+	// having connections fight over the lock.
+	db.SetMaxOpenConns(1)
+
+	if err := enableIncrementalVacuum(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing %s: %w", path, err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS cache (
+			key        TEXT PRIMARY KEY,
+			value      BLOB NOT NULL,
+			expires_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS cache_expires_at ON cache (expires_at);
+	`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing %s: %w", path, err)
+	}
+
+	c := &Cache{
+		db:   db,
+		done: make(chan struct{}),
+	}
+
+	if err := c.Purge(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	go c.purgeLoop()
+
+	return c, nil
+}
+
+func (c *Cache) Get(thread *starlark.Thread, key string) ([]byte, bool, error) {
+	value, _, found, err := c.GetWithExpiration(thread, key)
+	return value, found, err
+}
+
+func (c *Cache) GetWithExpiration(_ *starlark.Thread, key string) ([]byte, time.Time, bool, error) {
+	var value []byte
+	var expiresAt int64
+	err := c.db.QueryRow(
+		`SELECT value, expires_at FROM cache WHERE key = ? AND expires_at > ?`,
+		key, time.Now().UnixMilli(),
+	).Scan(&value, &expiresAt)
+
+	if err == sql.ErrNoRows {
+		return nil, time.Time{}, false, nil
+	} else if err != nil {
+		return nil, time.Time{}, false, err
+	}
+
+	return value, time.UnixMilli(expiresAt), true, nil
+}
+
+func (c *Cache) Set(_ *starlark.Thread, key string, value []byte, ttl int64) error {
+	expiresAt := time.Now().Add(time.Duration(ttl) * time.Second).UnixMilli()
+	_, err := c.db.Exec(
+		`INSERT INTO cache (key, value, expires_at) VALUES (?, ?, ?)
+		 ON CONFLICT (key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`,
+		key, value, expiresAt,
+	)
+	return err
+}
+
+func (c *Cache) Delete(key string) error {
+	_, err := c.db.Exec(`DELETE FROM cache WHERE key = ?`, key)
+	return err
+}
+
+func (c *Cache) Entries() ([]runtime.CacheEntry, error) {
+	rows, err := c.db.Query(
+		`SELECT key, length(value), expires_at FROM cache WHERE expires_at > ?`,
+		time.Now().UnixMilli(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []runtime.CacheEntry
+	for rows.Next() {
+		var e runtime.CacheEntry
+		var expiresAt int64
+		if err := rows.Scan(&e.Key, &e.Size, &expiresAt); err != nil {
+			return nil, err
+		}
+		e.Expiration = time.UnixMilli(expiresAt)
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// enableIncrementalVacuum makes the database able to give back the space
+// of deleted entries, so that it doesn't only ever grow. That has to be set
+// before any tables are created, so databases created without it are
+// vacuumed once to convert them.
+func enableIncrementalVacuum(db *sql.DB) error {
+	if _, err := db.Exec(`PRAGMA auto_vacuum = INCREMENTAL`); err != nil {
+		return err
+	}
+
+	var mode int
+	if err := db.QueryRow(`PRAGMA auto_vacuum`).Scan(&mode); err != nil {
+		return err
+	}
+	if mode != autoVacuumIncremental {
+		if _, err := db.Exec(`VACUUM`); err != nil {
+			return fmt.Errorf("vacuuming: %w", err)
+		}
+	}
+	return nil
+}
+
+// Purge deletes all expired entries from the database, and shrinks the file
+// by the space they took up.
+func (c *Cache) Purge() error {
+	res, err := c.db.Exec(`DELETE FROM cache WHERE expires_at <= ?`, time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("purging expired entries: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+
+	// incremental_vacuum frees a page each time it's stepped, so it has to
+	// be read to the end
+	rows, err := c.db.Query(`PRAGMA incremental_vacuum`)
+	if err != nil {
+		return fmt.Errorf("vacuuming: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("vacuuming: %w", err)
+	}
+	return nil
+}
+
+// Close stops the background purge and closes the database.
+func (c *Cache) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return c.db.Close()
+}
+
+func (c *Cache) purgeLoop() {
+	ticker := time.NewTicker(PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.Purge()
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func TestGetAndSet(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, found, err := c.Get(nil, "missing")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, c.Set(nil, "key", []byte("one"), 60))
+	assert.NoError(t, c.Set(nil, "key", []byte("two"), 60))
+
+	val, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("two"), val)
+}
+
+func TestExpiration(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer c.Close()
+
+	assert.NoError(t, c.Set(nil, "expired", []byte("gone"), -1))
+	_, found, err := c.Get(nil, "expired")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, c.Purge())
+	var count int
+	assert.NoError(t, c.db.QueryRow(`SELECT COUNT(*) FROM cache`).Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestPurgeShrinksDatabase(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer c.Close()
+
+	pages := func() int {
+		var n int
+		require.NoError(t, c.db.QueryRow(`PRAGMA page_count`).Scan(&n))
+		return n
+	}
+	empty := pages()
+
+	value := make([]byte, 64*1024)
+	for i := range 16 {
+		require.NoError(t, c.Set(nil, fmt.Sprintf("expired-%d", i), value, -1))
+	}
+	require.Greater(t, pages(), empty)
+
+	require.NoError(t, c.Purge())
+	assert.Equal(t, empty, pages())
+
+	var free int
+	require.NoError(t, c.db.QueryRow(`PRAGMA freelist_count`).Scan(&free))
+	assert.Equal(t, 0, free)
+}
+
+func TestOpenEnablesVacuumOnOldDatabases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE cache (key TEXT PRIMARY KEY, value BLOB NOT NULL, expires_at INTEGER NOT NULL)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	c, err := Open(path)
+	require.NoError(t, err)
+	defer c.Close()
+
+	var mode int
+	require.NoError(t, c.db.QueryRow(`PRAGMA auto_vacuum`).Scan(&mode))
+	assert.Equal(t, autoVacuumIncremental, mode)
+}
+
+func TestSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	c, err := Open(path)
+	require.NoError(t, err)
+	assert.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+	assert.NoError(t, c.Close())
+
+	c, err = Open(path)
+	require.NoError(t, err)
+	defer c.Close()
+
+	val, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+}
+
+func TestOpenCacheURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	c, err := runtime.OpenCache("sqlite://" + path)
+	require.NoError(t, err)
+	defer c.(*Cache).Close()
+
+	u, _ := url.Parse("sqlite://relative/cache.db")
+	assert.Equal(t, "relative/cache.db", PathFromURL(u))
+
+	u, _ = url.Parse("sqlite:cache.db")
+	assert.Equal(t, "cache.db", PathFromURL(u))
+}
+
+func TestNewSQLiteCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache?v=1#.db")
+
+	c, err := NewSQLiteCache(path)
+	require.NoError(t, err)
+	require.IsType(t, &Cache{}, c)
+	assert.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+	assert.NoError(t, c.(*Cache).Close())
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	val, found, err := reopened.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+
+	_, err = NewSQLiteCache("")
+	assert.Error(t, err)
+}
+
+func TestOpenEscapesPath(t *testing.T) {
+	for _, name := range []string{"cache?v=1#.db", "100%.db", "my cache.db"} {
+		path := filepath.Join(t.TempDir(), name)
+		c, err := Open(path)
+		require.NoError(t, err, name)
+
+		_, err = os.Stat(path)
+		assert.NoError(t, err, name)
+
+		// the pragmas after the path still apply
+		var mode string
+		require.NoError(t, c.db.QueryRow("PRAGMA journal_mode").Scan(&mode))
+		assert.Equal(t, "wal", mode, name)
+		assert.NoError(t, c.Close())
+	}
+}
+
+func TestEntries(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer c.Close()
+
+	assert.NoError(t, c.Set(nil, "live", []byte("value"), 60))
+	assert.NoError(t, c.Set(nil, "expired", []byte("value"), -1))
+
+	entries, err := c.Entries()
+	assert.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "live", entries[0].Key)
+	assert.Equal(t, int64(5), entries[0].Size)
+	assert.InDelta(t, 60, entries[0].TTL().Seconds(), 1)
+}
+
+func TestDelete(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer c.Close()
+
+	assert.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+	assert.NoError(t, c.Delete("key"))
+	assert.NoError(t, c.Delete("missing"))
+
+	_, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,221 @@
+// Package tiered provides a cache that layers a fast front cache, usually in
+// memory, over a slower persistent back cache. Reads are served from the
+// front whenever possible and writes go through to both layers, so hot keys
+// stay fast while everything survives a restart. Values read from the back
+// are copied to the front if the back implements runtime.ExpiringCache.
+//
+// Importing this package registers the "tiered" cache URL scheme. The back
+// cache is given as a URL-escaped cache URL, and the front defaults to memory:
+//
+//	tiered://?back=sqlite%3A%2F%2F%2Fvar%2Fcache.db&front_ttl=30
+package tiered
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+const (
+	// Scheme is the cache URL scheme handled by this package.
+	Scheme = "tiered"
+
+	// DefaultFrontTTL caps how long entries live in the front layer.
+	DefaultFrontTTL = 30 * time.Second
+)
+
+func init() {
+	runtime.RegisterCache(Scheme, func(u *url.URL) (runtime.Cache, error) {
+		q := u.Query()
+
+		if q.Get("back") == "" {
+			return nil, fmt.Errorf("tiered cache requires a back cache URL")
+		}
+
+		front, err := runtime.OpenCache(q.Get("front"))
+		if err != nil {
+			return nil, fmt.Errorf("front: %w", err)
+		}
+
+		back, err := runtime.OpenCache(q.Get("back"))
+		if err != nil {
+			return nil, fmt.Errorf("back: %w", err)
+		}
+
+		var opts Options
+		if opts.FrontTTL, err = parseSeconds(q.Get("front_ttl"), DefaultFrontTTL); err != nil {
+			return nil, fmt.Errorf("front_ttl: %w", err)
+		}
+		if opts.FrontTTL == 0 {
+			// front_ttl=0 turns the front layer off
+			opts.FrontTTL = -1
+		}
+		if opts.BackTTL, err = parseSeconds(q.Get("back_ttl"), 0); err != nil {
+			return nil, fmt.Errorf("back_ttl: %w", err)
+		}
+
+		return New(front, back, opts), nil
+	})
+}
+
+// Options configures the TTLs used by each layer.
+type Options struct {
+	// FrontTTL caps how long entries live in the front layer, including
+	// values read from the back layer and copied to the front. Front
+	// entries never outlive their back layer entry. Zero means
+	// DefaultFrontTTL, and a negative TTL turns the front layer off, so
+	// that every read goes to the back. Caches keep entries for whole
+	// seconds, so it's rounded up to one.
+	FrontTTL time.Duration
+
+	// BackTTL caps how long entries live in the back layer. Zero means
+	// entries keep the TTL they were set with. Like FrontTTL, it's rounded
+	// up to whole seconds.
+	BackTTL time.Duration
+}
+
+// Cache is a two-layer write-through cache.
+type Cache struct {
+	front runtime.Cache
+	back  runtime.Cache
+	opts  Options
+}
+
+// New creates a tiered cache reading from front before back.
+func New(front, back runtime.Cache, opts Options) *Cache {
+	if opts.FrontTTL == 0 {
+		opts.FrontTTL = DefaultFrontTTL
+	}
+	opts.FrontTTL = ceilSeconds(opts.FrontTTL)
+	opts.BackTTL = ceilSeconds(opts.BackTTL)
+
+	return &Cache{
+		front: front,
+		back:  back,
+		opts:  opts,
+	}
+}
+
+// frontDisabled reports whether the front layer is turned off, see
+// Options.FrontTTL.
+func (c *Cache) frontDisabled() bool {
+	return c.opts.FrontTTL < 0
+}
+
+func (c *Cache) Get(thread *starlark.Thread, key string) ([]byte, bool, error) {
+	if c.frontDisabled() {
+		return c.back.Get(thread, key)
+	}
+	if val, found, err := c.front.Get(thread, key); err == nil && found {
+		return val, true, nil
+	}
+
+	// a value is only promoted to the front layer if the back layer can
+	// tell how long it has left, so that it doesn't outlive its original
+	back, ok := c.back.(runtime.ExpiringCache)
+	if !ok {
+		return c.back.Get(thread, key)
+	}
+
+	val, exp, found, err := back.GetWithExpiration(thread, key)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	ttl := c.opts.FrontTTL
+	if !exp.IsZero() {
+		ttl = min(ttl, time.Until(exp))
+	}
+
+	// failing to promote only costs us speed
+	if seconds := int64(ttl / time.Second); seconds > 0 {
+		c.front.Set(thread, key, val, seconds)
+	}
+
+	return val, true, nil
+}
+
+func (c *Cache) Set(thread *starlark.Thread, key string, value []byte, ttl int64) error {
+	ttl = capTTL(ttl, c.opts.BackTTL)
+	if err := c.back.Set(thread, key, value, ttl); err != nil {
+		return err
+	}
+	if c.frontDisabled() {
+		return nil
+	}
+
+	// capped by the back layer's TTL too, so it doesn't outlive it
+	return c.front.Set(thread, key, value, capTTL(ttl, c.opts.FrontTTL))
+}
+
+// Entries lists the entries of the back layer, which holds a superset of the
+// front layer.
+func (c *Cache) Entries() ([]runtime.CacheEntry, error) {
+	if ic, ok := c.back.(runtime.InspectableCache); ok {
+		return ic.Entries()
+	}
+	return nil, runtime.ErrCacheNotInspectable
+}
+
+// Delete removes key from both layers, starting with the back, so that it
+// can't be promoted to the front again while it's being deleted.
+func (c *Cache) Delete(key string) error {
+	back, ok := c.back.(runtime.DeletableCache)
+	if !ok {
+		return runtime.ErrCacheNotDeletable
+	}
+	if c.frontDisabled() {
+		return back.Delete(key)
+	}
+	front, ok := c.front.(runtime.DeletableCache)
+	if !ok {
+		return runtime.ErrCacheNotDeletable
+	}
+
+	if err := back.Delete(key); err != nil {
+		return err
+	}
+	return front.Delete(key)
+}
+
+// OnEvict reports the entries the back layer evicts. Entries evicted from
+// the front are still in the back, so they aren't reported.
+func (c *Cache) OnEvict(fn func(key string)) {
+	if ec, ok := c.back.(runtime.EvictingCache); ok {
+		ec.OnEvict(fn)
+	}
+}
+
+// ceilSeconds rounds a positive d up to whole seconds, so that a TTL under
+// a second isn't truncated to none at all.
+func ceilSeconds(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	return (d + time.Second - 1).Truncate(time.Second)
+}
+
+func capTTL(ttl int64, max time.Duration) int64 {
+	if max > 0 && ttl > int64(max.Seconds()) {
+		return int64(max.Seconds())
+	}
+	return ttl
+}
+
+func parseSeconds(v string, def time.Duration) (time.Duration, error) {
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number of seconds: %q", v)
+	}
+
+	return time.Duration(n) * time.Second, nil
+}
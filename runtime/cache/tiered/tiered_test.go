@@ -0,0 +1,212 @@
+package tiered
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime"
+	_ "tidbyt.dev/pixlet/runtime/cache/filesystem"
+)
+
+// recordingCache wraps an in-memory cache and remembers the TTLs it was
+// asked to store.
+type recordingCache struct {
+	*runtime.InMemoryCache
+	ttls map[string]int64
+	err  error
+}
+
+func newRecordingCache() *recordingCache {
+	return &recordingCache{
+		InMemoryCache: runtime.NewInMemoryCache(),
+		ttls:          map[string]int64{},
+	}
+}
+
+func (c *recordingCache) Set(thread *starlark.Thread, key string, value []byte, ttl int64) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.ttls[key] = ttl
+	return c.InMemoryCache.Set(thread, key, value, ttl)
+}
+
+func TestWriteThrough(t *testing.T) {
+	front, back := newRecordingCache(), newRecordingCache()
+	c := New(front, back, Options{FrontTTL: 10 * time.Second, BackTTL: time.Hour})
+
+	assert.NoError(t, c.Set(nil, "short", []byte("1"), 5))
+	assert.NoError(t, c.Set(nil, "long", []byte("2"), 7200))
+
+	assert.Equal(t, int64(5), front.ttls["short"])
+	assert.Equal(t, int64(5), back.ttls["short"])
+	assert.Equal(t, int64(10), front.ttls["long"])
+	assert.Equal(t, int64(3600), back.ttls["long"])
+}
+
+func TestFrontNeverOutlivesBack(t *testing.T) {
+	front, back := newRecordingCache(), newRecordingCache()
+	c := New(front, back, Options{FrontTTL: 30 * time.Second, BackTTL: 10 * time.Second})
+
+	assert.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+	assert.Equal(t, int64(10), back.ttls["key"])
+	assert.Equal(t, int64(10), front.ttls["key"])
+}
+
+func TestSubSecondTTLs(t *testing.T) {
+	front, back := newRecordingCache(), newRecordingCache()
+	c := New(front, back, Options{FrontTTL: 500 * time.Millisecond, BackTTL: 1500 * time.Millisecond})
+
+	// rounded up, rather than truncated to no cap at all
+	assert.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+	assert.Equal(t, int64(2), back.ttls["key"])
+	assert.Equal(t, int64(1), front.ttls["key"])
+}
+
+func TestFrontDisabled(t *testing.T) {
+	front, back := newRecordingCache(), newRecordingCache()
+	c := New(front, back, Options{FrontTTL: -1})
+
+	assert.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+	val, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+	assert.Empty(t, front.ttls)
+
+	assert.NoError(t, c.Delete("key"))
+	_, found, _ = back.Get(nil, "key")
+	assert.False(t, found)
+}
+
+func TestPromotion(t *testing.T) {
+	front, back := newRecordingCache(), newRecordingCache()
+	c := New(front, back, Options{FrontTTL: 10 * time.Second})
+
+	// simulate a restart: the value only exists in the back layer
+	assert.NoError(t, back.Set(nil, "key", []byte("value"), 60))
+
+	val, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+
+	val, found, _ = front.Get(nil, "key")
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+	assert.Equal(t, int64(10), front.ttls["key"])
+
+	_, found, err = c.Get(nil, "missing")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestPromotionKeepsExpiration(t *testing.T) {
+	front, back := newRecordingCache(), newRecordingCache()
+	c := New(front, back, Options{FrontTTL: 30 * time.Second})
+
+	// the back entry expires well before FrontTTL
+	assert.NoError(t, back.Set(nil, "key", []byte("value"), 2))
+
+	_, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(1), front.ttls["key"])
+
+	time.Sleep(1100 * time.Millisecond)
+	_, found, _ = front.Get(nil, "key")
+	assert.False(t, found)
+
+	// with under a second left, it isn't promoted at all
+	delete(front.ttls, "key")
+	_, found, err = c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.NotContains(t, front.ttls, "key")
+}
+
+func TestNoPromotionWithoutExpiration(t *testing.T) {
+	front, back := newRecordingCache(), newRecordingCache()
+	c := New(front, struct{ runtime.Cache }{back}, Options{FrontTTL: 30 * time.Second})
+
+	assert.NoError(t, back.Set(nil, "key", []byte("value"), 60))
+
+	val, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+
+	_, found, _ = front.Get(nil, "key")
+	assert.False(t, found)
+}
+
+func TestBackError(t *testing.T) {
+	front, back := newRecordingCache(), newRecordingCache()
+	back.err = errors.New("disk full")
+	c := New(front, back, Options{})
+
+	assert.Error(t, c.Set(nil, "key", []byte("value"), 60))
+	_, found, _ := front.Get(nil, "key")
+	assert.False(t, found)
+}
+
+func TestDelete(t *testing.T) {
+	front, back := newRecordingCache(), newRecordingCache()
+	c := New(front, back, Options{})
+
+	assert.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+	assert.NoError(t, c.Delete("key"))
+
+	_, found, _ := front.Get(nil, "key")
+	assert.False(t, found)
+	_, found, _ = back.Get(nil, "key")
+	assert.False(t, found)
+
+	// the back layer has to be able to delete too
+	c = New(front, struct{ runtime.Cache }{back}, Options{})
+	assert.ErrorIs(t, c.Delete("key"), runtime.ErrCacheNotDeletable)
+}
+
+func TestOpenCacheURL(t *testing.T) {
+	c, err := runtime.OpenCache("tiered://?back=" + url.QueryEscape("memory://") + "&front_ttl=5&back_ttl=60")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, c.(*Cache).opts.FrontTTL)
+	assert.Equal(t, 60*time.Second, c.(*Cache).opts.BackTTL)
+
+	_, err = runtime.OpenCache("tiered://")
+	assert.Error(t, err)
+
+	c, err = runtime.OpenCache("tiered://?back=" + url.QueryEscape("memory://") + "&front_ttl=0")
+	require.NoError(t, err)
+	assert.True(t, c.(*Cache).frontDisabled())
+
+	_, err = runtime.OpenCache("tiered://?back=memory%3A%2F%2F&front_ttl=soon")
+	assert.Error(t, err)
+}
+
+func TestSurvivesRestart(t *testing.T) {
+	back := "fs:" + t.TempDir()
+	open := func() runtime.Cache {
+		c, err := runtime.OpenCache("tiered://?front=" + url.QueryEscape("memory://?max_entries=10") + "&back=" + url.QueryEscape(back))
+		require.NoError(t, err)
+		return c
+	}
+
+	c := open()
+	require.NoError(t, c.Set(nil, "key", []byte("Lovelace"), 60))
+
+	// a new front layer starts out empty, and is filled from the back
+	c = open()
+	assert.Equal(t, 0, c.(*Cache).front.(*runtime.InMemoryCache).Len())
+	val, found, err := c.Get(nil, "key")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("Lovelace"), val)
+	assert.Equal(t, 1, c.(*Cache).front.(*runtime.InMemoryCache).Len())
+}
@@ -2,9 +2,13 @@ package runtime
 
 import (
 	"context"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.starlark.net/starlark"
 )
 
 func TestCacheGetAndSet(t *testing.T) {
@@ -87,6 +91,54 @@ def main():
 
 }
 
+func TestCacheNamespacedByApp(t *testing.T) {
+	src := `
+load("render.star", "render")
+load("cache.star", "cache")
+
+def main(config):
+    latest = cache.get("latest")
+    cache.set("latest", config["who"])
+    if config.get("share"):
+        cache.set("latest", config["who"], shared = True)
+    print("%s %s" % (latest, cache.get("latest", shared = True)))
+    return render.Root(child = render.Box())
+`
+	c := NewInMemoryCache()
+	InitCache(c)
+
+	var printed []string
+	print := WithPrintFunc(func(thread *starlark.Thread, msg string) { printed = append(printed, msg) })
+
+	// IDs that would give the same key if they weren't escaped
+	ada, err := NewApplet("a:b", []byte(src), print)
+	assert.NoError(t, err)
+	grace, err := NewApplet("a", []byte(src), print)
+	assert.NoError(t, err)
+
+	_, err = ada.RunWithConfig(context.Background(), map[string]string{"who": "Ada", "share": "1"})
+	assert.NoError(t, err)
+	_, err = grace.RunWithConfig(context.Background(), map[string]string{"who": "Grace"})
+	assert.NoError(t, err)
+	_, err = ada.RunWithConfig(context.Background(), map[string]string{"who": "Ada"})
+	assert.NoError(t, err)
+
+	// neither sees what the other set, unless it's shared
+	assert.Equal(t, []string{"None Ada", "None Ada", "Ada Ada"}, printed)
+
+	_, found, _ := c.Get(nil, "pixlet:a%3Ab:latest")
+	assert.True(t, found)
+	_, found, _ = c.Get(nil, "pixlet-shared:latest")
+	assert.True(t, found)
+	assert.Equal(t, "a:b", CacheKeyApp("pixlet:a%3Ab:latest"))
+	assert.Equal(t, "", CacheKeyApp("pixlet-shared:latest"))
+
+	// installations of an app share its cache
+	key := scopedCacheKey(&starlark.Thread{Name: "clock/abc123"}, "latest", false)
+	assert.Equal(t, "pixlet:clock:latest", key)
+	assert.Equal(t, "clock", CacheKeyApp(key))
+}
+
 func TestCacheNoInit(t *testing.T) {
 	src := `
 load("render.star", "render")
@@ -128,3 +180,228 @@ def main():
 	assert.Error(t, err)
 	assert.Nil(t, screens)
 }
+
+type testCache struct {
+	*InMemoryCache
+	url string
+}
+
+func TestOpenCache(t *testing.T) {
+	c, err := OpenCache("")
+	assert.NoError(t, err)
+	assert.IsType(t, &InMemoryCache{}, c)
+
+	c, err = OpenCache("memory://")
+	assert.NoError(t, err)
+	assert.IsType(t, &InMemoryCache{}, c)
+
+	c, err = OpenCache("redis://localhost:6379/0")
+	assert.NoError(t, err)
+	assert.IsType(t, &RedisCache{}, c)
+
+	_, err = OpenCache("nope://whatever")
+	assert.ErrorContains(t, err, "unknown cache scheme")
+
+	_, err = OpenCache("localhost:6379")
+	assert.Error(t, err)
+}
+
+func TestRegisterCache(t *testing.T) {
+	RegisterCache("test", func(u *url.URL) (Cache, error) {
+		return &testCache{
+			InMemoryCache: NewInMemoryCache(),
+			url:           u.String(),
+		}, nil
+	})
+	assert.Contains(t, CacheSchemes(), "test")
+
+	c, err := OpenCache("test://somewhere/else?opt=1")
+	assert.NoError(t, err)
+	assert.Equal(t, "test://somewhere/else?opt=1", c.(*testCache).url)
+
+	assert.NoError(t, c.Set(nil, "key", []byte("value"), 60))
+	val, found, err := c.Get(nil, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+}
+
+func TestInMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewBoundedInMemoryCache(0, 2)
+
+	assert.NoError(t, c.Set(nil, "a", []byte("1"), 60))
+	assert.NoError(t, c.Set(nil, "b", []byte("2"), 60))
+
+	// touch a so that b becomes the least recently used
+	_, found, _ := c.Get(nil, "a")
+	assert.True(t, found)
+
+	assert.NoError(t, c.Set(nil, "c", []byte("3"), 60))
+	assert.Equal(t, 2, c.Len())
+
+	_, found, _ = c.Get(nil, "b")
+	assert.False(t, found)
+	_, found, _ = c.Get(nil, "a")
+	assert.True(t, found)
+	_, found, _ = c.Get(nil, "c")
+	assert.True(t, found)
+}
+
+func TestInMemoryCacheMaxBytes(t *testing.T) {
+	// each record is one byte of key and nine bytes of value
+	c := NewBoundedInMemoryCache(25, 0)
+
+	assert.NoError(t, c.Set(nil, "a", []byte("123456789"), 60))
+	assert.NoError(t, c.Set(nil, "b", []byte("123456789"), 60))
+	assert.Equal(t, int64(20), c.Size())
+
+	assert.NoError(t, c.Set(nil, "c", []byte("123456789"), 60))
+	assert.Equal(t, int64(20), c.Size())
+	_, found, _ := c.Get(nil, "a")
+	assert.False(t, found)
+
+	// overwriting a key replaces its size rather than adding to it
+	assert.NoError(t, c.Set(nil, "c", []byte("1"), 60))
+	assert.Equal(t, int64(12), c.Size())
+
+	// values larger than the whole cache are not stored
+	assert.NoError(t, c.Set(nil, "d", make([]byte, 100), 60))
+	_, found, _ = c.Get(nil, "d")
+	assert.False(t, found)
+}
+
+func TestInMemoryCacheExpiresActively(t *testing.T) {
+	c := NewInMemoryCache()
+
+	assert.NoError(t, c.Set(nil, "expired", []byte("gone"), -1))
+	assert.NoError(t, c.Set(nil, "live", []byte("here"), 60))
+
+	assert.Equal(t, 1, c.Len())
+	assert.Equal(t, int64(len("live")+len("here")), c.Size())
+}
+
+func TestInMemoryCacheSweepsExpired(t *testing.T) {
+	c := NewInMemoryCache()
+	c.sweepInterval = 10 * time.Millisecond
+	var evicted []string
+	c.OnEvict(func(key string) { evicted = append(evicted, key) })
+
+	// nothing touches the cache after this, so only the sweep removes it
+	assert.NoError(t, c.Set(nil, "expired", []byte("gone"), -1))
+
+	assert.Eventually(t, func() bool {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return len(c.records) == 0 && c.sweep == nil
+	}, time.Second, 5*time.Millisecond)
+
+	// expiring isn't being evicted to stay within the limits
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	assert.Empty(t, evicted)
+}
+
+func TestOpenInMemoryCacheWithLimits(t *testing.T) {
+	c, err := OpenCache("memory://?max_bytes=1024&max_entries=10")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024), c.(*InMemoryCache).maxBytes)
+	assert.Equal(t, 10, c.(*InMemoryCache).maxEntries)
+
+	_, err = OpenCache("memory://?max_entries=many")
+	assert.Error(t, err)
+}
+
+func TestStatsCache(t *testing.T) {
+	c := NewStatsCache(NewInMemoryCache())
+
+	assert.NoError(t, c.Set(nil, "pixlet:app1:key", []byte("value"), 60))
+	assert.NoError(t, c.Set(nil, "httpcache:app2:abcdef", []byte("response"), 60))
+	assert.NoError(t, c.Set(nil, "abcdef", []byte("x"), 60))
+
+	c.Get(nil, "pixlet:app1:key")
+	c.Get(nil, "pixlet:app1:key")
+	c.Get(nil, "pixlet:app1:missing")
+	c.Get(nil, "httpcache:app2:abcdef")
+
+	stats := c.Stats()
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1, Sets: 1, BytesRead: 10, BytesWritten: 5}, stats["app1"])
+	assert.Equal(t, CacheStats{Hits: 1, Sets: 1, BytesRead: 8, BytesWritten: 8}, stats["app2"])
+	assert.Equal(t, CacheStats{Sets: 1, BytesWritten: 1}, stats[""])
+
+	entries, err := c.Entries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "abcdef", entries[0].Key)
+	assert.Equal(t, "", entries[0].App)
+	assert.Equal(t, "httpcache:app2:abcdef", entries[1].Key)
+	assert.Equal(t, "app2", entries[1].App)
+	assert.Equal(t, int64(8), entries[1].Size)
+	assert.Equal(t, "pixlet:app1:key", entries[2].Key)
+	assert.Equal(t, "app1", entries[2].App)
+	assert.InDelta(t, 60, entries[2].TTL().Seconds(), 1)
+
+	_, err = NewStatsCache(&RedisCache{}).Entries()
+	assert.ErrorIs(t, err, ErrCacheNotInspectable)
+}
+
+func TestStatsCacheEvictions(t *testing.T) {
+	c := NewStatsCache(NewBoundedInMemoryCache(0, 2))
+
+	// entries that expire aren't counted
+	assert.NoError(t, c.Set(nil, "pixlet:ada:0", []byte("0"), -1))
+	assert.NoError(t, c.Set(nil, "pixlet:ada:1", []byte("1"), 60))
+	assert.NoError(t, c.Set(nil, "pixlet:grace:1", []byte("2"), 60))
+	c.Get(nil, "pixlet:ada:1")
+
+	// grace's is the least recently used, so it's evicted
+	assert.NoError(t, c.Set(nil, "pixlet:ada:2", []byte("3"), 60))
+	c.Get(nil, "pixlet:ada:0")
+
+	stats := c.Stats()
+	assert.Equal(t, int64(0), stats["ada"].Evictions)
+	assert.Equal(t, int64(1), stats["grace"].Evictions)
+
+	total := c.Total()
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Sets: 4, Evictions: 1, BytesRead: 1, BytesWritten: 4}, total)
+	assert.Equal(t, 0.5, total.HitRate())
+	assert.Equal(t, 0.0, CacheStats{}.HitRate())
+}
+
+func TestStatsCacheDeleteAndFlush(t *testing.T) {
+	c := NewStatsCache(NewInMemoryCache())
+
+	assert.NoError(t, c.Set(nil, "pixlet:ada:key", []byte("1"), 60))
+	assert.NoError(t, c.Set(nil, "pixlet:ada:other", []byte("2"), 60))
+	assert.NoError(t, c.Set(nil, "httpcache:grace:abcdef", []byte("3"), 60))
+	assert.NoError(t, c.Set(nil, "pixlet-shared:key", []byte("4"), 60))
+
+	assert.NoError(t, c.Delete("pixlet:ada:key"))
+	assert.NoError(t, c.Delete("pixlet:ada:missing"))
+	_, found, _ := c.Get(nil, "pixlet:ada:key")
+	assert.False(t, found)
+
+	n, err := c.Flush("ada")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	entries, _ := c.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "httpcache:grace:abcdef", entries[0].Key)
+	assert.Equal(t, "pixlet-shared:key", entries[1].Key)
+
+	n, err = c.Flush("")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	entries, _ = c.Entries()
+	assert.Empty(t, entries)
+
+	// a cache that can delete but not list entries can't be flushed
+	_, err = NewStatsCache(&RedisCache{}).Flush("")
+	assert.ErrorIs(t, err, ErrCacheNotInspectable)
+	assert.ErrorIs(t, NewStatsCache(opaqueCache{NewInMemoryCache()}).Delete("key"), ErrCacheNotDeletable)
+}
+
+// opaqueCache hides everything but the Cache interface of the cache it wraps.
+type opaqueCache struct {
+	Cache
+}
@@ -0,0 +1,275 @@
+package runtime
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// ErrCacheNotInspectable is returned when listing the entries of a cache
+// that doesn't support it.
+var ErrCacheNotInspectable = errors.New("cache does not support listing entries")
+
+// ErrCacheNotDeletable is returned when deleting entries from a cache that
+// doesn't support it.
+var ErrCacheNotDeletable = errors.New("cache does not support deleting entries")
+
+// CacheEntry describes a single record held by a cache.
+type CacheEntry struct {
+	Key        string    `json:"key"`
+	App        string    `json:"app,omitempty"`
+	Size       int64     `json:"size"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// TTL returns how long the entry has left to live.
+func (e CacheEntry) TTL() time.Duration {
+	return time.Until(e.Expiration)
+}
+
+// InspectableCache is implemented by caches that can enumerate their live
+// entries, for debugging and operational tooling.
+type InspectableCache interface {
+	Cache
+	Entries() ([]CacheEntry, error)
+}
+
+// DeletableCache is implemented by caches that can remove entries before
+// they expire. Deleting a key that isn't there is not an error.
+type DeletableCache interface {
+	Cache
+	Delete(key string) error
+}
+
+// EvictingCache is implemented by caches that evict live entries to stay
+// within their limits, and can report them. Entries that expire aren't
+// evicted. fn is called with the key of each entry evicted, while the cache
+// may be locked, so it mustn't use the cache.
+type EvictingCache interface {
+	Cache
+	OnEvict(fn func(key string))
+}
+
+// ExpiringCache is implemented by caches that can report when a value
+// expires as they read it, so that it can be copied elsewhere without
+// outliving the original. A zero expiration means the value doesn't expire.
+type ExpiringCache interface {
+	Cache
+	GetWithExpiration(thread *starlark.Thread, key string) ([]byte, time.Time, bool, error)
+}
+
+// CacheStats holds usage counters for a single app.
+type CacheStats struct {
+	Hits         int64 `json:"hits"`
+	Misses       int64 `json:"misses"`
+	Sets         int64 `json:"sets"`
+	Evictions    int64 `json:"evictions"`
+	Errors       int64 `json:"errors"`
+	BytesRead    int64 `json:"bytes_read"`
+	BytesWritten int64 `json:"bytes_written"`
+}
+
+// HitRate returns the share of reads that were hits, from 0 to 1, or 0 if
+// there were none.
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (s *CacheStats) add(o CacheStats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Sets += o.Sets
+	s.Evictions += o.Evictions
+	s.Errors += o.Errors
+	s.BytesRead += o.BytesRead
+	s.BytesWritten += o.BytesWritten
+}
+
+// StatsCache wraps a Cache and counts hits, misses, writes and, if the cache
+// reports them, evictions per app.
+type StatsCache struct {
+	Cache
+
+	mutex sync.Mutex
+	stats map[string]*CacheStats
+}
+
+// NewStatsCache returns a cache that records usage statistics for c.
+func NewStatsCache(c Cache) *StatsCache {
+	sc := &StatsCache{
+		Cache: c,
+		stats: map[string]*CacheStats{},
+	}
+
+	if ec, ok := c.(EvictingCache); ok {
+		ec.OnEvict(func(key string) {
+			sc.record(key, func(s *CacheStats) { s.Evictions++ })
+		})
+	}
+
+	return sc
+}
+
+func (c *StatsCache) Get(thread *starlark.Thread, key string) ([]byte, bool, error) {
+	val, found, err := c.Cache.Get(thread, key)
+
+	c.record(key, func(s *CacheStats) {
+		switch {
+		case err != nil:
+			s.Errors++
+		case found:
+			s.Hits++
+			s.BytesRead += int64(len(val))
+		default:
+			s.Misses++
+		}
+	})
+
+	return val, found, err
+}
+
+func (c *StatsCache) Set(thread *starlark.Thread, key string, value []byte, ttl int64) error {
+	err := c.Cache.Set(thread, key, value, ttl)
+
+	c.record(key, func(s *CacheStats) {
+		if err != nil {
+			s.Errors++
+		} else {
+			s.Sets++
+			s.BytesWritten += int64(len(value))
+		}
+	})
+
+	return err
+}
+
+// Stats returns a snapshot of the counters, keyed by app ID. Keys that don't
+// belong to an app are counted under the empty string.
+func (c *StatsCache) Stats() map[string]CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := make(map[string]CacheStats, len(c.stats))
+	for app, s := range c.stats {
+		stats[app] = *s
+	}
+
+	return stats
+}
+
+// Total returns the sum of the counters of every app.
+func (c *StatsCache) Total() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var total CacheStats
+	for _, s := range c.stats {
+		total.add(*s)
+	}
+
+	return total
+}
+
+// Entries lists the live entries of the wrapped cache, sorted by key, with
+// each entry's app filled in.
+func (c *StatsCache) Entries() ([]CacheEntry, error) {
+	ic, ok := c.Cache.(InspectableCache)
+	if !ok {
+		return nil, ErrCacheNotInspectable
+	}
+
+	entries, err := ic.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		entries[i].App = CacheKeyApp(entries[i].Key)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries, nil
+}
+
+// Delete removes key from the wrapped cache.
+func (c *StatsCache) Delete(key string) error {
+	dc, ok := c.Cache.(DeletableCache)
+	if !ok {
+		return ErrCacheNotDeletable
+	}
+
+	return dc.Delete(key)
+}
+
+// Flush removes every live entry of the wrapped cache, or only those of app
+// if it isn't empty, and returns how many it removed. The cache has to be
+// able to list its entries as well as delete them.
+func (c *StatsCache) Flush(app string) (int, error) {
+	if _, ok := c.Cache.(DeletableCache); !ok {
+		return 0, ErrCacheNotDeletable
+	}
+
+	entries, err := c.Entries()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, e := range entries {
+		if app != "" && e.App != app {
+			continue
+		}
+		if err := c.Delete(e.Key); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+func (c *StatsCache) record(key string, update func(*CacheStats)) {
+	app := CacheKeyApp(key)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	s, ok := c.stats[app]
+	if !ok {
+		s = &CacheStats{}
+		c.stats[app] = s
+	}
+	update(s)
+}
+
+// CacheKeyApp returns the ID of the app a cache key belongs to, or the empty
+// string if the key isn't scoped to an app, such as keys apps share. It
+// understands keys written by the cache module and by the HTTP cache.
+func CacheKeyApp(key string) string {
+	if rest, ok := strings.CutPrefix(key, "pixlet:"); ok {
+		if app, _, ok := strings.Cut(rest, ":"); ok {
+			if id, err := url.QueryUnescape(app); err == nil {
+				return id
+			}
+			return app
+		}
+	}
+	if rest, ok := strings.CutPrefix(key, HTTPCachePrefix+":"); ok {
+		// the hash of the request comes last, after the app's ID
+		if i := strings.LastIndex(rest, ":"); i >= 0 {
+			return rest[:i]
+		}
+	}
+
+	return ""
+}
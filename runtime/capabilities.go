@@ -0,0 +1,179 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/manifest"
+)
+
+// RequiresConstantName is the name of the constant apps can define next to
+// main to declare what they need of the host that runs them, such as
+//
+//	REQUIRES = ["network", "secrets", "128x64"]
+//
+// so that hosts that can't offer it refuse to run them with a clear error,
+// rather than the app failing halfway through a render.
+const RequiresConstantName = "REQUIRES"
+
+// Capabilities apps can require, besides a display size such as "128x64",
+// which requires a display at least that wide and tall.
+const (
+	// CapabilityNetwork requires that the app can make HTTP requests.
+	CapabilityNetwork = "network"
+
+	// CapabilitySecrets requires that the app's secrets can be decrypted.
+	CapabilitySecrets = "secrets"
+)
+
+// Capabilities is what a host offers the apps it runs.
+type Capabilities struct {
+	// Network is whether apps can make HTTP requests.
+	Network bool
+
+	// Secrets is whether apps' secrets can be decrypted.
+	Secrets bool
+
+	// Width and Height are the size of the display apps render for. Zero
+	// means the size isn't known yet, and size requirements aren't checked.
+	Width  int
+	Height int
+}
+
+// CapabilityError is returned when a host lacks capabilities an applet
+// requires.
+type CapabilityError struct {
+	AppID   string
+	Missing []string
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("%s requires %s, which this host doesn't offer", e.AppID, strings.Join(e.Missing, ", "))
+}
+
+// Manifest returns the manifest the applet was loaded with, or nil if it
+// doesn't have one.
+func (a *Applet) Manifest() *manifest.Manifest {
+	return a.manifest
+}
+
+// Requires returns the capabilities the applet declares it requires, in its
+// REQUIRES constant or the capabilities in its manifest: apps that list
+// network hosts require the network, those that list secrets require
+// secrets, and the minimum display size is a size requirement.
+func (a *Applet) Requires() []string {
+	requires := slices.Clone(a.requires)
+	if a.manifest == nil || a.manifest.Capabilities == nil {
+		return requires
+	}
+
+	caps := a.manifest.Capabilities
+	var fromManifest []string
+	if len(caps.Network) > 0 {
+		fromManifest = append(fromManifest, CapabilityNetwork)
+	}
+	if len(caps.Secrets) > 0 {
+		fromManifest = append(fromManifest, CapabilitySecrets)
+	}
+	if size := caps.MinDisplaySize; size != nil {
+		fromManifest = append(fromManifest, fmt.Sprintf("%dx%d", size.Width, size.Height))
+	}
+
+	for _, req := range fromManifest {
+		if !slices.Contains(requires, req) {
+			requires = append(requires, req)
+		}
+	}
+	return requires
+}
+
+// CheckCapabilities returns a *CapabilityError listing the capabilities the
+// applet requires that host doesn't offer, if there are any.
+func (a *Applet) CheckCapabilities(host Capabilities) error {
+	var missing []string
+	for _, req := range a.Requires() {
+		var ok bool
+		switch req {
+		case CapabilityNetwork:
+			ok = host.Network
+		case CapabilitySecrets:
+			ok = host.Secrets
+		default:
+			width, height, _ := parseSize(req)
+			ok = host.Width == 0 || host.Height == 0 || (host.Width >= width && host.Height >= height)
+		}
+		if !ok {
+			missing = append(missing, req)
+		}
+	}
+
+	if len(missing) > 0 {
+		return &CapabilityError{AppID: a.ID, Missing: missing}
+	}
+	return nil
+}
+
+// loadManifest reads the applet's manifest, if fsys has one. The network
+// hosts its capabilities list are the only ones the applet can make
+// requests to.
+func (a *Applet) loadManifest(fsys fs.FS) error {
+	f, err := fsys.Open(manifest.ManifestFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("opening %s: %w", manifest.ManifestFileName, err)
+	}
+	defer f.Close()
+
+	m, err := manifest.LoadManifest(f)
+	if err != nil {
+		return err
+	}
+	if m.Capabilities != nil {
+		if err := m.Capabilities.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", manifest.ManifestFileName, err)
+		}
+	}
+
+	a.manifest = m
+	return nil
+}
+
+// parseRequires parses the value of an applet's REQUIRES constant.
+func parseRequires(v starlark.Value) ([]string, error) {
+	iter, ok := v.(starlark.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of strings, not %s", RequiresConstantName, v.Type())
+	}
+
+	var requires []string
+	it := iter.Iterate()
+	defer it.Done()
+	var x starlark.Value
+	for it.Next(&x) {
+		req, ok := starlark.AsString(x)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of strings, not one with %s", RequiresConstantName, x.Type())
+		}
+		if req != CapabilityNetwork && req != CapabilitySecrets {
+			if _, _, err := parseSize(req); err != nil {
+				return nil, fmt.Errorf("%s: unknown capability %q", RequiresConstantName, req)
+			}
+		}
+		requires = append(requires, req)
+	}
+	return requires, nil
+}
+
+// parseSize parses a display size such as "128x64".
+func parseSize(s string) (width, height int, err error) {
+	if _, err := fmt.Sscanf(s, "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 || fmt.Sprintf("%dx%d", width, height) != s {
+		return 0, 0, fmt.Errorf("invalid size %q", s)
+	}
+	return width, height, nil
+}
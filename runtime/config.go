@@ -5,7 +5,10 @@ import (
 	"strconv"
 
 	"github.com/mitchellh/hashstructure/v2"
+	starlibtime "go.starlark.net/lib/time"
 	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/schema"
 )
 
 type AppletConfig map[string]string
@@ -15,6 +18,9 @@ func (a AppletConfig) AttrNames() []string {
 		"get",
 		"str",
 		"bool",
+		"list",
+		"time_range",
+		"duration",
 	}
 }
 
@@ -27,6 +33,15 @@ func (a AppletConfig) Attr(name string) (starlark.Value, error) {
 	case "bool":
 		return starlark.NewBuiltin("bool", a.getBoolean), nil
 
+	case "list":
+		return starlark.NewBuiltin("list", a.getList), nil
+
+	case "time_range":
+		return starlark.NewBuiltin("time_range", a.getTimeRange), nil
+
+	case "duration":
+		return starlark.NewBuiltin("duration", a.getDuration), nil
+
 	default:
 		return nil, nil
 	}
@@ -92,3 +107,90 @@ func (a AppletConfig) getBoolean(thread *starlark.Thread, _ *starlark.Builtin, a
 		return starlark.Bool(b), nil
 	}
 }
+
+func (a AppletConfig) getList(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key starlark.String
+	var def starlark.Value
+	def = starlark.None
+
+	if err := starlark.UnpackPositionalArgs(
+		"list", args, kwargs, 1,
+		&key, &def,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for config.list: %v", err)
+	}
+
+	val, ok := a[key.GoString()]
+	if !ok {
+		return def, nil
+	}
+
+	// multi-selects hold a list of strings, while lists hold one object of
+	// child field values per entry, which is exposed as a config of its own.
+	if values, err := schema.DecodeMultiSelect(val); err == nil {
+		list := make([]starlark.Value, 0, len(values))
+		for _, v := range values {
+			list = append(list, starlark.String(v))
+		}
+		return starlark.NewList(list), nil
+	}
+
+	items, err := schema.DecodeList(val)
+	if err != nil {
+		return nil, fmt.Errorf("config.list: %q: %v", key.GoString(), err)
+	}
+
+	list := make([]starlark.Value, 0, len(items))
+	for _, item := range items {
+		list = append(list, AppletConfig(item))
+	}
+	return starlark.NewList(list), nil
+}
+
+func (a AppletConfig) getTimeRange(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key starlark.String
+	var def starlark.Value
+	def = starlark.None
+
+	if err := starlark.UnpackPositionalArgs(
+		"time_range", args, kwargs, 1,
+		&key, &def,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for config.time_range: %v", err)
+	}
+
+	val, ok := a[key.GoString()]
+	if !ok || val == "" {
+		return def, nil
+	}
+
+	tr, err := schema.ParseTimeRange(val)
+	if err != nil {
+		return nil, fmt.Errorf("config.time_range: %q: %v", key.GoString(), err)
+	}
+	return tr, nil
+}
+
+func (a AppletConfig) getDuration(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key starlark.String
+	var def starlark.Value
+	def = starlark.None
+
+	if err := starlark.UnpackPositionalArgs(
+		"duration", args, kwargs, 1,
+		&key, &def,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for config.duration: %v", err)
+	}
+
+	val, ok := a[key.GoString()]
+	if !ok || val == "" {
+		return def, nil
+	}
+
+	d, err := schema.ParseDuration(val)
+	if err != nil {
+		return nil, fmt.Errorf("config.duration: %q: %v", key.GoString(), err)
+	}
+	return starlibtime.Duration(d), nil
+}
@@ -0,0 +1,116 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+
+	"go.starlark.net/starlark"
+)
+
+// Coverage records which lines of applets' source are executed, for
+// reporting the coverage of tests. It's a Probe, and safe to share between
+// applets and threads.
+type Coverage struct {
+	mu    sync.Mutex
+	files map[string]*probes
+}
+
+// probes are the probes in a file: the line of each, and the times it ran.
+type probes struct {
+	lines []int
+	hits  []int
+}
+
+// FileCoverage is the coverage of a source file: the times each of its
+// lines with a statement was executed.
+type FileCoverage struct {
+	Path  string
+	Lines map[int]int
+}
+
+// Covered returns how many of the file's lines with a statement were
+// executed.
+func (fc FileCoverage) Covered() int {
+	n := 0
+	for _, hits := range fc.Lines {
+		if hits > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{files: map[string]*probes{}}
+}
+
+// WithCoverage records the lines the applet executes, including those run
+// when it's loaded, to c.
+func WithCoverage(c *Coverage) AppletOption {
+	return WithProbe(c)
+}
+
+// Instrumented implements Probe.
+func (c *Coverage) Instrumented(file string, lines []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.files[file]; ok && slices.Equal(old.lines, lines) {
+		// the file's been loaded before, by another applet
+		return
+	}
+	c.files[file] = &probes{lines: lines, hits: make([]int, len(lines))}
+}
+
+// Hit implements Probe.
+func (c *Coverage) Hit(thread *starlark.Thread, file string, probe int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.files[file]; ok && probe < len(p.hits) {
+		p.hits[probe]++
+	}
+}
+
+// Files returns the coverage of the files loaded with coverage, sorted by
+// path.
+func (c *Coverage) Files() []FileCoverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var files []FileCoverage
+	for file, p := range c.files {
+		fc := FileCoverage{Path: file, Lines: map[int]int{}}
+		for i, line := range p.lines {
+			// statements sharing a line count as one
+			fc.Lines[line] = max(fc.Lines[line], p.hits[i])
+		}
+		files = append(files, fc)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// WriteLCOV writes coverage in the lcov tracefile format, which CI services
+// and coverage badges read.
+func WriteLCOV(w io.Writer, files []FileCoverage) error {
+	var buf bytes.Buffer
+	for _, fc := range files {
+		lines := make([]int, 0, len(fc.Lines))
+		for line := range fc.Lines {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+
+		fmt.Fprintf(&buf, "TN:\nSF:%s\n", fc.Path)
+		for _, line := range lines {
+			fmt.Fprintf(&buf, "DA:%d,%d\n", line, fc.Lines[line])
+		}
+		fmt.Fprintf(&buf, "LF:%d\nLH:%d\nend_of_record\n", len(lines), fc.Covered())
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
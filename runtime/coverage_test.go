@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverage(t *testing.T) {
+	src := `
+load("render.star", "render")
+
+def greet(name):
+    if name == "Ada":
+        return "Hi Ada"
+    elif name:
+        return "Hello " + name
+    return "Hello"
+
+def count(n):
+    total = 0
+    for i in range(n):
+        total += i
+    return total
+
+def main(config):
+    message = greet(config.get("name", "Ada"))
+    return render.Root(
+        child = render.Text(message),
+    )
+`
+	vfs := fstest.MapFS{"coverage.star": {Data: []byte(src)}}
+
+	coverage := NewCoverage()
+	app, err := NewAppletFromFS("coverage", vfs, WithCoverage(coverage))
+	require.NoError(t, err)
+
+	_, err = app.RunWithConfig(context.Background(), map[string]string{"name": "Grace"})
+	require.NoError(t, err)
+	_, err = app.RunWithConfig(context.Background(), map[string]string{"name": "Grace"})
+	require.NoError(t, err)
+
+	files := coverage.Files()
+	require.Len(t, files, 1)
+
+	assert.Equal(t, "coverage.star", files[0].Path)
+	assert.Equal(t, map[int]int{
+		5:  2, // if name == "Ada"
+		6:  0,
+		7:  2, // elif name
+		8:  2,
+		9:  0,
+		12: 0,
+		13: 0,
+		14: 0,
+		15: 0,
+		18: 2,
+		19: 2,
+	}, files[0].Lines)
+	assert.Equal(t, 5, files[0].Covered())
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteLCOV(&buf, files))
+	assert.Contains(t, buf.String(), "SF:coverage.star\nDA:5,2\nDA:6,0\nDA:7,2\n")
+	assert.Contains(t, buf.String(), "LF:11\nLH:5\nend_of_record\n")
+}
+
+func TestCoverageKeepsLines(t *testing.T) {
+	src := `
+def main(config):
+    total = 0
+    for i in range(3):
+        total += i
+    if total == 3: fail("total is %d" % total)
+`
+	coverage := NewCoverage()
+	app, err := NewApplet("lines", []byte(src), WithCoverage(coverage))
+	require.NoError(t, err)
+
+	_, err = app.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "lines/lines.star:6:")
+
+	files := coverage.Files()
+	require.Len(t, files, 1)
+	assert.Equal(t, map[int]int{3: 1, 4: 1, 5: 3, 6: 1}, files[0].Lines)
+}
@@ -20,6 +20,7 @@ import (
 	"sort"
 	"strings"
 	"text/template"
+	"time"
 
 	"tidbyt.dev/pixlet/render"
 	"tidbyt.dev/pixlet/render/animation"
@@ -71,6 +72,8 @@ var Packages = []Package{
 			reflect.ValueOf(new(render.Box)),
 			reflect.ValueOf(new(render.Circle)),
 			reflect.ValueOf(new(render.Column)),
+			reflect.ValueOf(new(render.Countdown)),
+			reflect.ValueOf(new(render.FitText)),
 			reflect.ValueOf(new(render.Image)),
 			reflect.ValueOf(new(render.Marquee)),
 			reflect.ValueOf(new(render.Padding)),
@@ -145,6 +148,16 @@ var TypeMap = map[reflect.Type]Type{
 		DocType:      "bool",
 		TemplatePath: "./runtime/gen/attr/bool.tmpl",
 	},
+	toDecayedType(new([]string)): {
+		GoType:       "starlark.Value",
+		DocType:      "[str]",
+		TemplatePath: "./runtime/gen/attr/strings.tmpl",
+	},
+	toDecayedType(new(time.Time)): {
+		GoType:       "starlark.Value",
+		DocType:      "time.time",
+		TemplatePath: "./runtime/gen/attr/time.tmpl",
+	},
 
 	// Render types
 	toDecayedType(new(render.Insets)): {
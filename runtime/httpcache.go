@@ -14,7 +14,10 @@ import (
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"tidbyt.dev/pixlet/runtime/modules/starlarkhttp"
+	"tidbyt.dev/pixlet/tracing"
 )
 
 const (
@@ -66,12 +69,33 @@ func InitHTTP(cache Cache) {
 
 // RoundTrip is an approximation of what our internal HTTP proxy does. It should
 // behave the same way, and any discrepancy should be considered a bug.
-func (c *cacheClient) RoundTrip(req *http.Request) (*http.Response, error) {
-	ctx := req.Context()
+func (c *cacheClient) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	ctx, span := tracing.Start(req.Context(), "http.request",
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+		attribute.String("url.path", req.URL.Path),
+		tracing.AppIDKey.String(req.Header.Get("X-Tidbyt-App")),
+	)
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(
+				attribute.Int("http.response.status_code", resp.StatusCode),
+				tracing.CacheStatusKey.String(resp.Header.Get("tidbyt-cache-status")),
+			)
+		}
+		tracing.End(span, err)
+	}()
 
 	ctx, cancel := context.WithTimeout(ctx, HTTPTimeout)
 	defer cancel() // need to do this to not leak a goroutine
 
+	meter := UsageMeterFromContext(ctx)
+	meter.update(func(s *UsageStats) { s.HTTPRequests++ })
+
+	if mock, ok := HTTPMocksFromContext(ctx).match(req); ok {
+		return mock.response(req)
+	}
+
 	key, err := cacheKey(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate cache key: %w", err)
@@ -82,12 +106,13 @@ func (c *cacheClient) RoundTrip(req *http.Request) (*http.Response, error) {
 		if exists && err == nil {
 			if res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), req); err == nil {
 				res.Header.Set("tidbyt-cache-status", "HIT")
+				meter.update(func(s *UsageStats) { s.HTTPCacheHits++ })
 				return res, nil
 			}
 		}
 	}
 
-	resp, err := c.transport.RoundTrip(req.WithContext(ctx))
+	resp, err = c.transport.RoundTrip(req.WithContext(ctx))
 	if err == nil {
 		resp.Body = http.MaxBytesReader(nil, resp.Body, MaxResponseBytes)
 	}
@@ -100,6 +125,8 @@ func (c *cacheClient) RoundTrip(req *http.Request) (*http.Response, error) {
 			return nil, fmt.Errorf("failed to serialize response for cache: %v (%s)", err, resp.Status)
 		}
 
+		meter.update(func(s *UsageStats) { s.BytesFetched += int64(len(ser)) })
+
 		ttl := DetermineTTL(req, resp)
 		c.cache.Set(nil, key, ser, int64(ttl.Seconds()))
 		resp.Header.Set("tidbyt-cache-status", "MISS")
@@ -0,0 +1,237 @@
+package runtime
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"tidbyt.dev/pixlet/runtime/modules/starlarkhttp"
+)
+
+// FixtureMode is whether HTTPFixtures replays responses from fixture files
+// or records them.
+type FixtureMode int
+
+const (
+	// FixturesReplay serves every request from a fixture, and fails
+	// requests there's no fixture for.
+	FixturesReplay FixtureMode = iota
+
+	// FixturesRecord makes requests for real, and saves their responses as
+	// fixtures.
+	FixturesRecord
+)
+
+// Redacted is what the values of redacted query parameters are replaced
+// with in fixtures.
+const Redacted = "REDACTED"
+
+// DefaultRedactedParams are query parameters that commonly carry API keys,
+// which are kept out of fixtures.
+var DefaultRedactedParams = []string{
+	"access_token", "api_key", "apikey", "appid", "auth", "client_secret",
+	"key", "password", "secret", "token",
+}
+
+// ErrNoFixture is returned when replaying a request there's no fixture for.
+var ErrNoFixture = errors.New("no HTTP fixture for request")
+
+// HTTPFixtures is a transport that records the HTTP requests apps make to
+// fixture files, and replays them, so tests of apps don't depend on live
+// APIs. There's one JSON file per request, in Dir.
+//
+// Requests are matched by method, URL and body. Headers aren't recorded
+// or matched, since they carry credentials.
+type HTTPFixtures struct {
+	Dir  string
+	Mode FixtureMode
+
+	// Redact are query parameters whose values are replaced with Redacted
+	// before requests are recorded or matched. Nil means
+	// DefaultRedactedParams.
+	Redact []string
+
+	// Transport makes requests when recording. Nil means
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+type httpFixture struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body,omitempty"`
+	} `json:"request"`
+
+	Response struct {
+		Status     int         `json:"status"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       string      `json:"body,omitempty"`
+		BodyBase64 string      `json:"bodyBase64,omitempty"`
+	} `json:"response"`
+}
+
+// InitHTTPFixtures installs the HTTP client apps make requests with, like
+// InitHTTP, but with requests recorded to or replayed from fixtures.
+func InitHTTPFixtures(cache Cache, fixtures *HTTPFixtures) {
+	starlarkhttp.StarlarkHTTPClient = &http.Client{
+		Transport: &cacheClient{cache: cache, transport: fixtures},
+		Timeout:   HTTPTimeout * 2,
+	}
+}
+
+func (f *HTTPFixtures) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	u := f.redact(req.URL)
+	path := filepath.Join(f.Dir, fixtureName(req.Method, u, body))
+
+	if f.Mode == FixturesRecord {
+		return f.record(req, u, body, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w %s %s", ErrNoFixture, req.Method, u)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture httpFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("reading fixture %s: %w", path, err)
+	}
+	return fixture.response(req)
+}
+
+func (f *HTTPFixtures) record(req *http.Request, u string, body []byte, path string) (*http.Response, error) {
+	transport := f.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, MaxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("reading response to record: %w", err)
+	}
+
+	var fixture httpFixture
+	fixture.Request.Method = req.Method
+	fixture.Request.URL = u
+	fixture.Request.Body = string(body)
+	fixture.Response.Status = resp.StatusCode
+	fixture.Response.Header = resp.Header.Clone()
+	fixture.Response.Header.Del("Set-Cookie")
+	if utf8.Valid(respBody) {
+		fixture.Response.Body = string(respBody)
+	} else {
+		fixture.Response.BodyBase64 = base64.StdEncoding.EncodeToString(respBody)
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+
+	return fixture.response(req)
+}
+
+func (fixture *httpFixture) response(req *http.Request) (*http.Response, error) {
+	body := []byte(fixture.Response.Body)
+	if fixture.Response.BodyBase64 != "" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(fixture.Response.BodyBase64); err != nil {
+			return nil, fmt.Errorf("decoding fixture body: %w", err)
+		}
+	}
+
+	header := fixture.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	// the body's stored decoded
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Response.Status, http.StatusText(fixture.Response.Status)),
+		StatusCode:    fixture.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// redact returns u with the values of redacted query parameters replaced.
+func (f *HTTPFixtures) redact(u *url.URL) string {
+	params := f.Redact
+	if params == nil {
+		params = DefaultRedactedParams
+	}
+
+	redacted := *u
+	query := redacted.Query()
+	for name, values := range query {
+		if slices.Contains(params, strings.ToLower(name)) {
+			for i := range values {
+				values[i] = Redacted
+			}
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	redacted.User = nil
+	return redacted.String()
+}
+
+// fixtureName names the fixture of a request after its method and host,
+// so fixtures are easy to tell apart, and a hash of the rest.
+func fixtureName(method, u string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, u)
+	h.Write(body)
+
+	host := "request"
+	if parsed, err := url.Parse(u); err == nil && parsed.Host != "" {
+		host = strings.NewReplacer(":", "_", ".", "_").Replace(parsed.Host)
+	}
+	return fmt.Sprintf("%s-%s-%s.json", strings.ToLower(method), host, hex.EncodeToString(h.Sum(nil))[:12])
+}
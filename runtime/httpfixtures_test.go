@@ -0,0 +1,117 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/runtime/modules/starlarkhttp"
+)
+
+var fixturesSrc = `
+load("http.star", "http")
+
+def main(config):
+    resp = http.get(config.get("url") + "/forecast", params = {"city": "Lovelace", "key": "hunter2"})
+    if resp.status_code != 200:
+        fail("status", resp.status_code)
+    if resp.json()["temp"] != 21:
+        fail("unexpected forecast", resp.body())
+
+    resp = http.post(config.get("url") + "/report", body = "sunny")
+    if resp.body() != "thanks for sunny":
+        fail("unexpected report", resp.body())
+    return []
+`
+
+func TestHTTPFixtures(t *testing.T) {
+	defer func(c *http.Client) { starlarkhttp.StarlarkHTTPClient = c }(starlarkhttp.StarlarkHTTPClient)
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/forecast":
+			assert.Equal(t, "hunter2", r.URL.Query().Get("key"))
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Set-Cookie", "session=grace")
+			fmt.Fprint(w, `{"temp": 21}`)
+		case "/report":
+			body := make([]byte, 5)
+			r.Body.Read(body)
+			fmt.Fprintf(w, "thanks for %s", body)
+		}
+	}))
+	defer srv.Close()
+
+	config := map[string]string{"url": srv.URL}
+
+	// the client is picked when the app's loaded
+	run := func(fixtures *HTTPFixtures) error {
+		InitHTTPFixtures(NewInMemoryCache(), fixtures)
+		app, err := NewApplet("fixtures.star", []byte(fixturesSrc))
+		require.NoError(t, err)
+		_, err = app.RunWithConfig(context.Background(), config)
+		return err
+	}
+
+	// record the requests for real
+	dir := t.TempDir()
+	err := run(&HTTPFixtures{Dir: dir, Mode: FixturesRecord})
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "hunter2")
+		assert.NotContains(t, string(data), "grace")
+	}
+	forecast, err := filepath.Glob(filepath.Join(dir, "get-127_0_0_1_*.json"))
+	require.NoError(t, err)
+	require.Len(t, forecast, 1)
+	data, err := os.ReadFile(forecast[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "key=REDACTED")
+
+	// and replay them, without the server
+	require.NoError(t, run(&HTTPFixtures{Dir: dir}))
+	assert.Equal(t, 2, requests)
+
+	// requests without fixtures fail
+	err = run(&HTTPFixtures{Dir: t.TempDir()})
+	assert.ErrorContains(t, err, "no HTTP fixture for request GET "+srv.URL+"/forecast?city=Lovelace&key=REDACTED")
+	assert.Equal(t, 2, requests)
+}
+
+func TestHTTPFixturesBinary(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\xff")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(png)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	client := &http.Client{Transport: &HTTPFixtures{Dir: dir, Mode: FixturesRecord}}
+	resp, err := client.Get(srv.URL + "/logo.png")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	client = &http.Client{Transport: &HTTPFixtures{Dir: dir}}
+	resp, err = client.Get(srv.URL + "/logo.png")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body := make([]byte, 20)
+	n, _ := resp.Body.Read(body)
+	assert.Equal(t, png, body[:n])
+}
@@ -0,0 +1,115 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// HTTPMock is a canned response to the HTTP requests an app makes to a URL,
+// to see how it copes with an API that's down, empty or rate limiting it.
+type HTTPMock struct {
+	// Status is the response's status code. Zero means 200.
+	Status int `json:"status,omitempty"`
+
+	Header map[string]string `json:"header,omitempty"`
+	Body   string            `json:"body,omitempty"`
+
+	// Error fails the request with this error instead of responding, as if
+	// the server couldn't be reached.
+	Error string `json:"error,omitempty"`
+}
+
+// HTTPMocks are canned responses by URL. A URL ending in * matches every
+// URL it's a prefix of, and one without a query matches the URL with any
+// query. The most specific match wins.
+type HTTPMocks map[string]HTTPMock
+
+type httpMocksKey struct{}
+
+// WithHTTPMocks attaches mocks to ctx, so that requests apps make while
+// running with it get the mocked responses instead of being made, or
+// served from the cache. Requests that don't match a mock are made as
+// usual.
+func WithHTTPMocks(ctx context.Context, mocks HTTPMocks) context.Context {
+	return context.WithValue(ctx, httpMocksKey{}, mocks)
+}
+
+// HTTPMocksFromContext returns the mocks attached to ctx, or nil.
+func HTTPMocksFromContext(ctx context.Context) HTTPMocks {
+	m, _ := ctx.Value(httpMocksKey{}).(HTTPMocks)
+	return m
+}
+
+// Validate checks that the mocks' status codes are valid.
+func (m HTTPMocks) Validate() error {
+	for u, mock := range m {
+		if mock.Status != 0 && (mock.Status < 100 || mock.Status > 999) {
+			return fmt.Errorf("mock for %s: invalid status %d", u, mock.Status)
+		}
+	}
+	return nil
+}
+
+// match returns the mock for req.
+func (m HTTPMocks) match(req *http.Request) (HTTPMock, bool) {
+	if len(m) == 0 {
+		return HTTPMock{}, false
+	}
+
+	u := req.URL.String()
+	if mock, ok := m[u]; ok {
+		return mock, true
+	}
+
+	withoutQuery := *req.URL
+	withoutQuery.RawQuery = ""
+	withoutQuery.Fragment = ""
+	if mock, ok := m[withoutQuery.String()]; ok {
+		return mock, true
+	}
+
+	var best string
+	for pattern := range m {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && strings.HasPrefix(u, prefix) && len(prefix) >= len(best) {
+			best = prefix
+		}
+	}
+	if mock, ok := m[best+"*"]; ok {
+		return mock, true
+	}
+	return HTTPMock{}, false
+}
+
+func (mock HTTPMock) response(req *http.Request) (*http.Response, error) {
+	if mock.Error != "" {
+		return nil, errors.New(mock.Error)
+	}
+
+	status := mock.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := http.Header{}
+	for k, v := range mock.Header {
+		header.Set(k, v)
+	}
+	header.Set("tidbyt-cache-status", "MOCK")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(mock.Body))),
+		ContentLength: int64(len(mock.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/runtime/modules/starlarkhttp"
+)
+
+var mocksSrc = `
+load("http.star", "http")
+
+def main(config):
+    resp = http.get(config.get("url") + "/forecast", params = {"city": "Lovelace"}, ttl_seconds = 60)
+    got = "%d %s %s" % (resp.status_code, resp.headers.get("Retry-After", ""), resp.body())
+    if got != config.get("expect"):
+        fail("got", got)
+    return []
+`
+
+func TestHTTPMocks(t *testing.T) {
+	defer func(c *http.Client) { starlarkhttp.StarlarkHTTPClient = c }(starlarkhttp.StarlarkHTTPClient)
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"temp": 21}`))
+	}))
+	defer srv.Close()
+
+	InitHTTP(NewInMemoryCache())
+	app, err := NewApplet("mocks.star", []byte(mocksSrc))
+	require.NoError(t, err)
+	run := func(ctx context.Context, expect string) error {
+		_, err := app.RunWithConfig(ctx, map[string]string{"url": srv.URL, "expect": expect})
+		return err
+	}
+
+	// without mocks, the request is made, and cached
+	require.NoError(t, run(context.Background(), `200  {"temp": 21}`))
+	assert.Equal(t, 1, requests)
+
+	// mocks win over the cache, and aren't cached themselves
+	ctx := WithHTTPMocks(context.Background(), HTTPMocks{
+		srv.URL + "/forecast": {Status: 429, Header: map[string]string{"Retry-After": "30"}},
+	})
+	require.NoError(t, run(ctx, "429 30 "))
+	assert.Equal(t, 1, requests)
+
+	ctx = WithHTTPMocks(context.Background(), HTTPMocks{
+		srv.URL + "/*": {Error: "connection refused"},
+	})
+	assert.ErrorContains(t, run(ctx, ""), "connection refused")
+
+	ctx = WithHTTPMocks(context.Background(), HTTPMocks{
+		srv.URL + "/other": {Status: 500},
+	})
+	require.NoError(t, run(ctx, `200  {"temp": 21}`))
+	assert.Equal(t, 1, requests)
+}
+
+func TestHTTPMocksMatch(t *testing.T) {
+	mocks := HTTPMocks{
+		"https://api.example.com/*":                  {Status: 503},
+		"https://api.example.com/v1/*":               {Status: 429},
+		"https://api.example.com/v1/scores":          {Body: "[]"},
+		"https://api.example.com/v1/scores?team=ada": {Body: "{}"},
+	}
+
+	match := func(u string) HTTPMock {
+		req := httptest.NewRequest("GET", u, nil)
+		mock, ok := mocks.match(req)
+		require.True(t, ok, u)
+		return mock
+	}
+	assert.Equal(t, "{}", match("https://api.example.com/v1/scores?team=ada").Body)
+	assert.Equal(t, "[]", match("https://api.example.com/v1/scores?team=grace").Body)
+	assert.Equal(t, 429, match("https://api.example.com/v1/standings").Status)
+	assert.Equal(t, 503, match("https://api.example.com/v2/scores").Status)
+
+	_, ok := mocks.match(httptest.NewRequest("GET", "https://example.com/", nil))
+	assert.False(t, ok)
+
+	assert.NoError(t, mocks.Validate())
+	assert.Error(t, HTTPMocks{"https://example.com": {Status: 42}}.Validate())
+}
@@ -0,0 +1,289 @@
+package runtime
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+const (
+	// DefaultInMemoryCacheMaxBytes is the default size limit of an
+	// InMemoryCache, counting both keys and values.
+	DefaultInMemoryCacheMaxBytes = 128 * 1024 * 1024 // 128MB
+
+	// DefaultInMemoryCacheMaxEntries is the default entry limit of an
+	// InMemoryCache. Zero means no limit.
+	DefaultInMemoryCacheMaxEntries = 0
+
+	// InMemoryCacheSweepInterval is how often an InMemoryCache holding
+	// records removes those that have expired.
+	InMemoryCacheSweepInterval = time.Minute
+)
+
+type InMemoryCacheRecord struct {
+	key        string
+	data       []byte
+	expiration time.Time
+
+	element   *list.Element // position in the LRU list
+	heapIndex int           // position in the expiration heap
+}
+
+func (r *InMemoryCacheRecord) size() int64 {
+	return int64(len(r.key) + len(r.data))
+}
+
+// InMemoryCache is a size-bounded LRU cache. Once either limit is exceeded,
+// the least recently used records are evicted. Expired records are removed
+// on every access, and swept every InMemoryCacheSweepInterval while there
+// are any, so they don't hold on to memory in a cache that's gone idle.
+type InMemoryCache struct {
+	records       map[string]*InMemoryCacheRecord
+	lru           *list.List // front is most recently used
+	expirations   expirationHeap
+	size          int64
+	maxBytes      int64
+	maxEntries    int
+	onEvict       func(key string)
+	sweepInterval time.Duration
+	sweep         *time.Timer // nil when there's nothing to sweep
+	mutex         sync.Mutex
+}
+
+// NewInMemoryCache creates an in-memory cache with the default limits.
+func NewInMemoryCache() *InMemoryCache {
+	return NewBoundedInMemoryCache(DefaultInMemoryCacheMaxBytes, DefaultInMemoryCacheMaxEntries)
+}
+
+// NewBoundedInMemoryCache creates an in-memory cache holding at most maxBytes
+// of keys and values and at most maxEntries records. A limit of zero or less
+// disables that limit.
+func NewBoundedInMemoryCache(maxBytes int64, maxEntries int) *InMemoryCache {
+	return &InMemoryCache{
+		records:       map[string]*InMemoryCacheRecord{},
+		lru:           list.New(),
+		maxBytes:      maxBytes,
+		maxEntries:    maxEntries,
+		sweepInterval: InMemoryCacheSweepInterval,
+	}
+}
+
+// newInMemoryCacheFromURL creates an in-memory cache from a memory:// URL,
+// which accepts max_bytes and max_entries query parameters.
+func newInMemoryCacheFromURL(u *url.URL) (Cache, error) {
+	q := u.Query()
+
+	maxBytes := int64(DefaultInMemoryCacheMaxBytes)
+	if v := q.Get("max_bytes"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_bytes: %q", v)
+		}
+		maxBytes = n
+	}
+
+	maxEntries := DefaultInMemoryCacheMaxEntries
+	if v := q.Get("max_entries"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_entries: %q", v)
+		}
+		maxEntries = n
+	}
+
+	return NewBoundedInMemoryCache(maxBytes, maxEntries), nil
+}
+
+func (c *InMemoryCache) Get(thread *starlark.Thread, key string) (value []byte, found bool, err error) {
+	value, _, found, err = c.GetWithExpiration(thread, key)
+	return value, found, err
+}
+
+func (c *InMemoryCache) GetWithExpiration(_ *starlark.Thread, key string) ([]byte, time.Time, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.expireLocked(time.Now())
+
+	r, found := c.records[key]
+	if !found {
+		return nil, time.Time{}, false, nil
+	}
+
+	c.lru.MoveToFront(r.element)
+	return r.data, r.expiration, true, nil
+}
+
+func (c *InMemoryCache) Set(_ *starlark.Thread, key string, value []byte, ttl int64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	c.expireLocked(now)
+
+	if r, found := c.records[key]; found {
+		c.removeLocked(r)
+	}
+
+	r := &InMemoryCacheRecord{
+		key:        key,
+		data:       value,
+		expiration: now.Add(time.Duration(ttl) * time.Second),
+	}
+
+	if c.maxBytes > 0 && r.size() > c.maxBytes {
+		// would evict everything else and still not fit
+		return nil
+	}
+
+	r.element = c.lru.PushFront(r)
+	heap.Push(&c.expirations, r)
+	c.records[key] = r
+	c.size += r.size()
+	c.scheduleSweepLocked()
+
+	for c.overLimitLocked() {
+		evicted := c.lru.Back().Value.(*InMemoryCacheRecord)
+		c.removeLocked(evicted)
+		if c.onEvict != nil {
+			c.onEvict(evicted.key)
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of live records in the cache.
+func (c *InMemoryCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.expireLocked(time.Now())
+	return len(c.records)
+}
+
+// Size returns the number of bytes used by live keys and values.
+func (c *InMemoryCache) Size() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.expireLocked(time.Now())
+	return c.size
+}
+
+func (c *InMemoryCache) Entries() ([]CacheEntry, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.expireLocked(time.Now())
+
+	entries := make([]CacheEntry, 0, len(c.records))
+	for _, r := range c.records {
+		entries = append(entries, CacheEntry{
+			Key:        r.key,
+			Size:       int64(len(r.data)),
+			Expiration: r.expiration,
+		})
+	}
+
+	return entries, nil
+}
+
+// OnEvict sets a function to call with the key of each record evicted to
+// stay within the cache's limits.
+func (c *InMemoryCache) OnEvict(fn func(key string)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.onEvict = fn
+}
+
+// Delete removes the record for key, if there is one.
+func (c *InMemoryCache) Delete(key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if r, found := c.records[key]; found {
+		c.removeLocked(r)
+	}
+
+	return nil
+}
+
+func (c *InMemoryCache) overLimitLocked() bool {
+	if c.maxEntries > 0 && len(c.records) > c.maxEntries {
+		return true
+	}
+	return c.maxBytes > 0 && c.size > c.maxBytes
+}
+
+// expireLocked removes all records that have expired by now.
+func (c *InMemoryCache) expireLocked(now time.Time) {
+	for len(c.expirations) > 0 && !now.Before(c.expirations[0].expiration) {
+		c.removeLocked(c.expirations[0])
+	}
+}
+
+// scheduleSweepLocked arranges for expired records to be swept, unless a
+// sweep is already due. There's no timer while the cache is empty, so
+// caches that are no longer used don't need closing.
+func (c *InMemoryCache) scheduleSweepLocked() {
+	if c.sweep == nil && len(c.records) > 0 {
+		c.sweep = time.AfterFunc(c.sweepInterval, c.sweepExpired)
+	}
+}
+
+// sweepExpired removes expired records, and schedules the next sweep if
+// any are left. Expired records aren't reported to OnEvict, which is only
+// for those evicted to stay within the limits.
+func (c *InMemoryCache) sweepExpired() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.sweep = nil
+	c.expireLocked(time.Now())
+	c.scheduleSweepLocked()
+}
+
+func (c *InMemoryCache) removeLocked(r *InMemoryCacheRecord) {
+	c.lru.Remove(r.element)
+	heap.Remove(&c.expirations, r.heapIndex)
+	delete(c.records, r.key)
+	c.size -= r.size()
+}
+
+// expirationHeap is a min-heap of records ordered by expiration time.
+type expirationHeap []*InMemoryCacheRecord
+
+func (h expirationHeap) Len() int { return len(h) }
+
+func (h expirationHeap) Less(i, j int) bool {
+	return h[i].expiration.Before(h[j].expiration)
+}
+
+func (h expirationHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *expirationHeap) Push(x any) {
+	r := x.(*InMemoryCacheRecord)
+	r.heapIndex = len(*h)
+	*h = append(*h, r)
+}
+
+func (h *expirationHeap) Pop() any {
+	old := *h
+	n := len(old)
+	r := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return r
+}
@@ -0,0 +1,78 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/schema"
+)
+
+// MigrateConfig brings config saved with an older config version of the
+// applet's schema up to date, by calling the schema's migrate function with a
+// copy of the config and the version it was saved with. Config that's
+// current, or that belongs to an applet without a versioned schema, is
+// returned as is. Migrated config has schema.ConfigVersionKey set, and hosts
+// should save it in place of the old config.
+func (a *Applet) MigrateConfig(ctx context.Context, config map[string]string) (map[string]string, error) {
+	s := a.Schema
+	if s == nil || s.ConfigVersion == 0 {
+		return config, nil
+	}
+
+	from, err := schema.ConfigVersion(config)
+	if err != nil {
+		return nil, err
+	}
+	if from == s.ConfigVersion {
+		return config, nil
+	}
+	if from > s.ConfigVersion {
+		return nil, fmt.Errorf("config version %d is newer than the schema's version %d", from, s.ConfigVersion)
+	}
+
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	dict := starlark.NewDict(len(config))
+	for _, k := range keys {
+		dict.SetKey(starlark.String(k), starlark.String(config[k]))
+	}
+
+	migrated := make(map[string]string, len(config))
+	if s.Migrate == nil {
+		// nothing to migrate, just record the new version
+		for k, v := range config {
+			migrated[k] = v
+		}
+	} else {
+		val, err := a.Call(ctx, s.Migrate, dict, starlark.MakeInt(from))
+		if err != nil {
+			return nil, fmt.Errorf("migrating config from version %d: %w", from, err)
+		}
+
+		result, ok := val.(*starlark.Dict)
+		if !ok {
+			return nil, fmt.Errorf("expected %s to return a dict but found: %s", s.Migrate.Name(), val.Type())
+		}
+		for _, item := range result.Items() {
+			k, kok := starlark.AsString(item[0])
+			v, vok := starlark.AsString(item[1])
+			if !kok || !vok {
+				return nil, fmt.Errorf(
+					"expected %s to return a dict of strings but found: %s: %s",
+					s.Migrate.Name(), item[0].Type(), item[1].Type(),
+				)
+			}
+			migrated[k] = v
+		}
+	}
+
+	migrated[schema.ConfigVersionKey] = strconv.Itoa(s.ConfigVersion)
+	return migrated, nil
+}
@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/schema"
+)
+
+var migrateSource = `
+load("render.star", "render")
+load("schema.star", "schema")
+
+def migrate(config, from_version):
+    if from_version < 1 and "station" in config:
+        config["stop"] = config.pop("station")
+    if from_version < 2:
+        config["units"] = "metric" if config.get("metric") == "true" else "imperial"
+        config.pop("metric", None)
+    return config
+
+def get_schema():
+    return schema.Schema(
+        version = "1",
+        config_version = 2,
+        migrate = migrate,
+        fields = [
+            schema.Text(id = "stop", name = "Stop", desc = "Stop.", icon = "train"),
+            schema.Dropdown(
+                id = "units",
+                name = "Units",
+                desc = "Units.",
+                icon = "ruler",
+                default = "imperial",
+                options = [
+                    schema.Option(display = "Metric", value = "metric"),
+                    schema.Option(display = "Imperial", value = "imperial"),
+                ],
+            ),
+        ],
+    )
+
+def main(config):
+    if config.get("stop") != "Central" or config.get("units") != "metric":
+        fail("config wasn't migrated: %s, %s" % (config.get("stop"), config.get("units")))
+    return []
+`
+
+func TestMigrateConfig(t *testing.T) {
+	app, err := NewApplet("migrate.star", []byte(migrateSource))
+	require.NoError(t, err)
+	assert.Equal(t, 2, app.Schema.ConfigVersion)
+
+	old := map[string]string{"station": "Central", "metric": "true"}
+	migrated, err := app.MigrateConfig(context.Background(), old)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"stop":                  "Central",
+		"units":                 "metric",
+		schema.ConfigVersionKey: "2",
+	}, migrated)
+	assert.Equal(t, map[string]string{"station": "Central", "metric": "true"}, old)
+
+	// only the steps after the saved version run
+	migrated, err = app.MigrateConfig(context.Background(), map[string]string{
+		"stop":                  "Central",
+		"metric":                "false",
+		schema.ConfigVersionKey: "1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "imperial", migrated["units"])
+
+	current := map[string]string{"stop": "Central", "units": "metric", schema.ConfigVersionKey: "2"}
+	migrated, err = app.MigrateConfig(context.Background(), current)
+	require.NoError(t, err)
+	assert.Equal(t, current, migrated)
+
+	_, err = app.MigrateConfig(context.Background(), map[string]string{schema.ConfigVersionKey: "3"})
+	assert.ErrorContains(t, err, "newer")
+
+	_, err = app.MigrateConfig(context.Background(), map[string]string{schema.ConfigVersionKey: "two"})
+	assert.Error(t, err)
+}
+
+func TestAppletPoolMigratesConfig(t *testing.T) {
+	pool, err := NewAppletPool(1, func() (*Applet, error) {
+		return NewApplet("migrate.star", []byte(migrateSource))
+	})
+	require.NoError(t, err)
+
+	_, err = pool.RunWithConfig(context.Background(), map[string]string{"station": "Central", "metric": "true"})
+	assert.NoError(t, err)
+}
+
+func TestMigrateRequiresConfigVersion(t *testing.T) {
+	src := `
+load("schema.star", "schema")
+
+def migrate(config, from_version):
+    return config
+
+def get_schema():
+    return schema.Schema(version = "1", migrate = migrate)
+
+def main():
+    return []
+`
+	_, err := NewApplet("migrate.star", []byte(src))
+	assert.ErrorContains(t, err, "config_version")
+}
@@ -0,0 +1,95 @@
+package airquality
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/runtime/modules/location"
+)
+
+var airNowURL = "https://www.airnowapi.org/aq/observation/latLong/current/"
+
+// airNowPollutants are the names AirNow gives pollutants.
+var airNowPollutants = map[string]Pollutant{
+	"PM2.5": PM25,
+	"PM10":  PM10,
+	"O3":    O3,
+	"NO2":   NO2,
+	"SO2":   SO2,
+	"CO":    CO,
+}
+
+// airNowDistance is how far from a place, in miles, AirNow looks for a
+// reporting area.
+const airNowDistance = 50
+
+type airNowObservation struct {
+	DateObserved  string `json:"DateObserved"`
+	HourObserved  int    `json:"HourObserved"`
+	ReportingArea string `json:"ReportingArea"`
+	StateCode     string `json:"StateCode"`
+	ParameterName string `json:"ParameterName"`
+	AQI           int    `json:"AQI"`
+}
+
+// airNow fetches from AirNow, the official air quality of the United
+// States, Canada and Mexico, which needs a free API key. It reports each
+// pollutant's US AQI rather than its concentration, so the concentrations
+// and the CAQI are worked back from them, and are approximate.
+func airNow(ctx context.Context, f *fetch.Fetcher, place location.Place, apiKey string) (*Report, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("airnow requires an api_key, from https://docs.airnowapi.org")
+	}
+
+	q := url.Values{}
+	q.Set("format", "application/json")
+	q.Set("latitude", strconv.FormatFloat(place.Lat, 'f', -1, 64))
+	q.Set("longitude", strconv.FormatFloat(place.Lng, 'f', -1, 64))
+	q.Set("distance", strconv.Itoa(airNowDistance))
+	q.Set("API_KEY", apiKey)
+
+	var observations []airNowObservation
+	if err := f.Get(ctx, airNowURL+"?"+q.Encode(), &observations); err != nil {
+		return nil, err
+	}
+	if len(observations) == 0 {
+		return nil, fmt.Errorf("no reporting area within %d miles", airNowDistance)
+	}
+
+	loc, err := time.LoadLocation(place.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	r := &Report{
+		Concentrations: Concentrations{},
+		AQI:            map[Pollutant]int{},
+		Pollen:         map[Pollen]float64{},
+	}
+	for _, o := range observations {
+		p, ok := airNowPollutants[o.ParameterName]
+		if !ok || o.AQI < 0 {
+			continue
+		}
+		r.AQI[p] = o.AQI
+		r.Concentrations[p] = usAQI.concentration(p, o.AQI)
+
+		if r.Area == "" {
+			r.Area = o.ReportingArea
+			if o.StateCode != "" {
+				r.Area += ", " + o.StateCode
+			}
+		}
+		if day, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(o.DateObserved), loc); err == nil {
+			if t := day.Add(time.Duration(o.HourObserved) * time.Hour); t.After(r.Time) {
+				r.Time = t
+			}
+		}
+	}
+	return r, nil
+}
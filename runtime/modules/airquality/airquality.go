@@ -0,0 +1,278 @@
+// Package airquality fetches air quality and pollen counts from open data
+// services, and reports them the same way whichever is used, with both the
+// US Air Quality Index and Europe's Common Air Quality Index computed from
+// the same tables, so apps don't each need to carry their own.
+//
+// Open-Meteo needs no account, while AirNow, which only covers North
+// America, needs an API key that apps pass along. Both update hourly, so
+// reports are cached for half an hour by default.
+package airquality
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	startime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/runtime/modules/location"
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+const (
+	ModuleName = "airquality"
+
+	// DefaultProvider is the provider used when apps don't name one. It
+	// covers the whole world without an API key.
+	DefaultProvider = "open-meteo"
+
+	// DefaultTTL is how long responses are cached for by default. The
+	// providers update hourly.
+	DefaultTTL = 30 * time.Minute
+)
+
+// Pollen is a kind of pollen that's counted.
+type Pollen string
+
+const (
+	Alder   Pollen = "alder"
+	Birch   Pollen = "birch"
+	Grass   Pollen = "grass"
+	Mugwort Pollen = "mugwort"
+	Olive   Pollen = "olive"
+	Ragweed Pollen = "ragweed"
+)
+
+// Pollens are the kinds of pollen that are counted.
+var Pollens = []Pollen{Alder, Birch, Grass, Mugwort, Olive, Ragweed}
+
+// Report is the air quality at a place.
+type Report struct {
+	// Time is when the air was measured, or modeled.
+	Time time.Time
+
+	// Area is the name of the area the measurements are from, if the
+	// provider names it.
+	Area string
+
+	Concentrations Concentrations
+
+	// AQI are the US Air Quality Index of pollutants, for providers that
+	// report them rather than concentrations. They take precedence over
+	// those computed from concentrations.
+	AQI map[Pollutant]int
+
+	// Pollen are the pollen counts, in grains/m³. Kinds of pollen that
+	// weren't counted are missing.
+	Pollen map[Pollen]float64
+}
+
+// USAQI returns the report's US Air Quality Index, or false if it has
+// none of the pollutants it's computed from.
+func (r *Report) USAQI() (Index, bool) {
+	sub := usAQI.subIndices(r.Concentrations, false)
+	maps.Copy(sub, r.AQI)
+	return usAQI.index(sub)
+}
+
+// CAQI returns the report's Common Air Quality Index, or false if it has
+// none of the pollutants it's computed from.
+func (r *Report) CAQI() (Index, bool) {
+	return CAQI(r.Concentrations)
+}
+
+// provider fetches the air quality at place, with apiKey if the provider
+// needs one.
+type provider func(ctx context.Context, f *fetch.Fetcher, place location.Place, apiKey string) (*Report, error)
+
+var providers = map[string]provider{
+	"open-meteo": openMeteo,
+	"airnow":     airNow,
+}
+
+// Providers returns the names of the supported providers, sorted.
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+var (
+	once   sync.Once
+	module starlark.StringDict
+)
+
+func LoadModule() (starlark.StringDict, error) {
+	once.Do(func() {
+		module = starlark.StringDict{
+			ModuleName: &starlarkstruct.Module{
+				Name: ModuleName,
+				Members: starlark.StringDict{
+					"current":   starlark.NewBuiltin("current", current),
+					"us_aqi":    starlark.NewBuiltin("us_aqi", indexBuiltin(USAQI)),
+					"caqi":      starlark.NewBuiltin("caqi", indexBuiltin(CAQI)),
+					"providers": starlark.NewBuiltin("providers", listProviders),
+				},
+			},
+		}
+	})
+
+	return module, nil
+}
+
+// Fetch returns the air quality at place from the named provider, with
+// apiKey if it needs one. Responses are cached for ttl.
+func Fetch(ctx context.Context, providerName string, place location.Place, apiKey string, ttl time.Duration, appID string) (*Report, error) {
+	p, ok := providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q, expected one of %s", providerName, strings.Join(Providers(), ", "))
+	}
+
+	// nearby places share cached responses, and the service isn't told
+	// exactly where the device is
+	place.Lat = math.Round(place.Lat*100) / 100
+	place.Lng = math.Round(place.Lng*100) / 100
+
+	r, err := p(ctx, &fetch.Fetcher{TTL: ttl, AppID: appID}, place, apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("fetching air quality from %s: %w", providerName, err)
+	}
+	return r, nil
+}
+
+func current(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		loc          starlark.Value
+		providerName = DefaultProvider
+		apiKey       string
+		ttl          = int(DefaultTTL.Seconds())
+	)
+
+	if err := starlark.UnpackArgs(
+		"current",
+		args, kwargs,
+		"location", &loc,
+		"provider?", &providerName,
+		"api_key?", &apiKey,
+		"ttl_seconds?", &ttl,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for current: %s", err)
+	}
+
+	place, err := location.FromValue(loc)
+	if err != nil {
+		return nil, fmt.Errorf("current: %w", err)
+	}
+
+	appID := starlarkutil.AppID(thread)
+	r, err := Fetch(
+		fetch.Context(thread),
+		providerName, place, apiKey,
+		time.Duration(ttl)*time.Second,
+		appID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("current: %w", err)
+	}
+
+	return reportValue(r, providerName), nil
+}
+
+// indexBuiltin returns a builtin that computes an index from concentrations
+// given as keyword arguments, such as pm2_5 = 12.
+func indexBuiltin(index func(Concentrations) (Index, bool)) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		values := make([]starlark.Value, len(Pollutants))
+		var pairs []any
+		for i, p := range Pollutants {
+			pairs = append(pairs, string(p)+"?", &values[i])
+		}
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, pairs...); err != nil {
+			return nil, fmt.Errorf("unpacking arguments for %s: %s", b.Name(), err)
+		}
+
+		c := Concentrations{}
+		for i, p := range Pollutants {
+			if values[i] == nil || values[i] == starlark.None {
+				continue
+			}
+			v, ok := starlark.AsFloat(values[i])
+			if !ok {
+				return nil, fmt.Errorf("%s: %s must be a number, not %s", b.Name(), p, values[i].Type())
+			}
+			c[p] = v
+		}
+
+		i, ok := index(c)
+		return indexValue(i, ok), nil
+	}
+}
+
+func listProviders(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs("providers", args, kwargs); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for providers: %s", err)
+	}
+
+	var names []starlark.Value
+	for _, name := range Providers() {
+		names = append(names, starlark.String(name))
+	}
+	return starlark.NewList(names), nil
+}
+
+func reportValue(r *Report, providerName string) starlark.Value {
+	pollutants := starlark.StringDict{}
+	for _, p := range Pollutants {
+		pollutants[string(p)] = number(r.Concentrations, p)
+	}
+	pollen := starlark.StringDict{}
+	for _, p := range Pollens {
+		pollen[string(p)] = number(r.Pollen, p)
+	}
+
+	us, usOK := r.USAQI()
+	eu, euOK := r.CAQI()
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"provider":   starlark.String(providerName),
+		"time":       startime.Time(r.Time),
+		"area":       starlark.String(r.Area),
+		"us_aqi":     indexValue(us, usOK),
+		"caqi":       indexValue(eu, euOK),
+		"pollutants": starlarkstruct.FromStringDict(starlarkstruct.Default, pollutants),
+		"pollen":     starlarkstruct.FromStringDict(starlarkstruct.Default, pollen),
+	})
+}
+
+// indexValue converts i, or returns None if it isn't known.
+func indexValue(i Index, ok bool) starlark.Value {
+	if !ok {
+		return starlark.None
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"value":    starlark.MakeInt(i.Value),
+		"category": starlark.String(i.Category),
+		"color":    starlark.String(i.Color),
+		"dominant": starlark.String(i.Dominant),
+	})
+}
+
+// number returns m[k], or None if it isn't there.
+func number[K comparable](m map[K]float64, k K) starlark.Value {
+	v, ok := m[k]
+	if !ok || math.IsNaN(v) {
+		return starlark.None
+	}
+	return starlark.Float(v)
+}
@@ -0,0 +1,221 @@
+package airquality
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch/fetchtest"
+	"tidbyt.dev/pixlet/runtime/modules/location"
+)
+
+const openMeteoJSON = `{
+	"utc_offset_seconds": 7200,
+	"current": {
+		"time": "2026-10-17T14:00",
+		"interval": 3600,
+		"pm2_5": 12.0,
+		"pm10": 35.0,
+		"ozone": 117.8,
+		"nitrogen_dioxide": 150.0,
+		"sulphur_dioxide": 2.1,
+		"carbon_monoxide": 210.0,
+		"alder_pollen": 0.0,
+		"birch_pollen": 1.5,
+		"grass_pollen": 12.0,
+		"mugwort_pollen": null,
+		"olive_pollen": null,
+		"ragweed_pollen": null
+	}
+}`
+
+const airNowJSON = `[
+	{"DateObserved": "2026-10-17 ", "HourObserved": 9, "LocalTimeZone": "EST", "ReportingArea": "Brooklyn", "StateCode": "NY", "ParameterName": "O3", "AQI": 45, "Category": {"Number": 1, "Name": "Good"}},
+	{"DateObserved": "2026-10-17 ", "HourObserved": 10, "LocalTimeZone": "EST", "ReportingArea": "Brooklyn", "StateCode": "NY", "ParameterName": "PM2.5", "AQI": 62, "Category": {"Number": 2, "Name": "Moderate"}}
+]`
+
+// serve points every provider at a server that responds with the given
+// bodies by path, and records the requests it gets.
+func serve(t *testing.T, bodies map[string]string) *[]*http.Request {
+	return fetchtest.Serve(t, bodies, map[*string]string{
+		&openMeteoURL: "/open-meteo",
+		&airNowURL:    "/airnow",
+	})
+}
+
+func TestUSAQI(t *testing.T) {
+	for _, tt := range []struct {
+		c        Concentrations
+		value    int
+		category string
+	}{
+		{Concentrations{PM25: 0}, 0, "good"},
+		{Concentrations{PM25: 9.0}, 50, "good"},
+		{Concentrations{PM25: 9.1}, 51, "moderate"},
+		{Concentrations{PM25: 12.0}, 56, "moderate"},
+		{Concentrations{PM25: 12.09}, 56, "moderate"},
+		{Concentrations{PM25: 35.4}, 100, "moderate"},
+		{Concentrations{PM25: 60}, 154, "unhealthy"},
+		{Concentrations{PM25: 400}, 500, "hazardous"},
+		{Concentrations{PM10: 100}, 73, "moderate"},
+		// 60 ppb
+		{Concentrations{O3: 117.8}, 67, "moderate"},
+		// 100 ppb
+		{Concentrations{NO2: 188.18}, 100, "moderate"},
+		// 10 ppm
+		{Concentrations{CO: 11460}, 109, "unhealthy_for_sensitive_groups"},
+	} {
+		i, ok := USAQI(tt.c)
+		require.True(t, ok, "%v", tt.c)
+		assert.Equal(t, tt.value, i.Value, "%v", tt.c)
+		assert.Equal(t, tt.category, i.Category, "%v", tt.c)
+	}
+
+	i, ok := USAQI(Concentrations{PM25: 12.0, O3: 117.8, SO2: 1})
+	require.True(t, ok)
+	assert.Equal(t, Index{Value: 67, Category: "moderate", Color: "#ffff00", Dominant: O3}, i)
+
+	_, ok = USAQI(Concentrations{})
+	assert.False(t, ok)
+}
+
+func TestCAQI(t *testing.T) {
+	i, ok := CAQI(Concentrations{PM10: 35, NO2: 150, O3: 30})
+	require.True(t, ok)
+	assert.Equal(t, Index{Value: 63, Category: "medium", Color: "#eec20b", Dominant: NO2}, i)
+
+	// past the top of the grid, it's extrapolated
+	i, ok = CAQI(Concentrations{PM25: 165})
+	require.True(t, ok)
+	assert.Equal(t, 125, i.Value)
+	assert.Equal(t, "very_high", i.Category)
+
+	i, _ = CAQI(Concentrations{CO: 1000})
+	assert.Equal(t, "very_low", i.Category)
+}
+
+func TestConcentration(t *testing.T) {
+	// working back from an index gives a concentration with that index, to
+	// within what truncating it to the precision of the tables loses
+	for _, p := range Pollutants {
+		for _, index := range []int{0, 25, 50, 51, 99, 151, 250} {
+			c := usAQI.concentration(p, index)
+			i, ok := USAQI(Concentrations{p: c * 1.0001})
+			require.True(t, ok)
+			assert.InDelta(t, index, i.Value, 3, "%s %d", p, index)
+		}
+	}
+}
+
+func TestOpenMeteo(t *testing.T) {
+	requests := serve(t, map[string]string{"/open-meteo": openMeteoJSON})
+
+	place := location.Resolve(52.520008, 13.404954, "")
+	r, err := Fetch(context.Background(), "open-meteo", place, "", DefaultTTL, "air")
+	require.NoError(t, err)
+
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	assert.Equal(t, "52.52", req.URL.Query().Get("latitude"))
+	assert.Equal(t, "13.4", req.URL.Query().Get("longitude"))
+	assert.Equal(t, "Europe/Berlin", req.URL.Query().Get("timezone"))
+	assert.Contains(t, req.URL.Query().Get("current"), "birch_pollen")
+	assert.Equal(t, "1800", req.Header.Get("X-Tidbyt-Cache-Seconds"))
+	assert.Equal(t, "air", req.Header.Get("X-Tidbyt-App"))
+
+	assert.Equal(t, time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), r.Time.UTC())
+	assert.Equal(t, 12.0, r.Concentrations[PM25])
+	assert.Equal(t, 210.0, r.Concentrations[CO])
+	assert.Equal(t, map[Pollen]float64{Alder: 0, Birch: 1.5, Grass: 12}, r.Pollen)
+
+	us, ok := r.USAQI()
+	require.True(t, ok)
+	assert.Equal(t, 78, us.Value)
+	assert.Equal(t, NO2, us.Dominant)
+	eu, ok := r.CAQI()
+	require.True(t, ok)
+	assert.Equal(t, 63, eu.Value)
+}
+
+func TestAirNow(t *testing.T) {
+	requests := serve(t, map[string]string{"/airnow": airNowJSON})
+
+	place := location.Resolve(40.6781784, -73.9441579, "America/New_York")
+	_, err := Fetch(context.Background(), "airnow", place, "", DefaultTTL, "air")
+	assert.ErrorContains(t, err, "requires an api_key")
+
+	r, err := Fetch(context.Background(), "airnow", place, "secret", DefaultTTL, "air")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", (*requests)[0].URL.Query().Get("API_KEY"))
+
+	assert.Equal(t, "Brooklyn, NY", r.Area)
+	assert.Equal(t, time.Date(2026, 10, 17, 14, 0, 0, 0, time.UTC), r.Time.UTC())
+
+	// its indices are kept as they are
+	us, ok := r.USAQI()
+	require.True(t, ok)
+	assert.Equal(t, Index{Value: 62, Category: "moderate", Color: "#ffff00", Dominant: PM25}, us)
+	assert.InDelta(t, 15.0, r.Concentrations[PM25], 0.1)
+
+	_, ok = r.CAQI()
+	assert.True(t, ok)
+	assert.Empty(t, r.Pollen)
+
+	// nowhere near a reporting area
+	serve(t, map[string]string{"/airnow": `[]`})
+	_, err = Fetch(context.Background(), "airnow", place, "secret", DefaultTTL, "air")
+	assert.ErrorContains(t, err, "no reporting area")
+}
+
+func TestFetchInvalid(t *testing.T) {
+	_, err := Fetch(context.Background(), "purpleair", location.Resolve(0, 0, ""), "", DefaultTTL, "air")
+	assert.ErrorContains(t, err, "airnow, open-meteo")
+}
+
+func TestAirQualityModule(t *testing.T) {
+	serve(t, map[string]string{"/open-meteo": openMeteoJSON})
+
+	src := `
+load("airquality.star", "airquality")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+assert(airquality.providers() == ["airnow", "open-meteo"])
+
+berlin = airquality.current({"lat": 52.52, "lng": 13.40})
+assert(berlin.provider == "open-meteo")
+assert(berlin.us_aqi.value == 78)
+assert(berlin.us_aqi.category == "moderate")
+assert(berlin.us_aqi.dominant == "no2")
+assert(berlin.caqi.value == 63)
+assert(berlin.caqi.color == "#eec20b")
+assert(berlin.pollutants.pm2_5 == 12.0)
+assert(berlin.pollen.grass == 12.0)
+assert(berlin.pollen.ragweed == None)
+
+assert(airquality.us_aqi(pm2_5 = 12).value == 56)
+assert(airquality.caqi(pm10 = 35).category == "low")
+assert(airquality.us_aqi() == None)
+`
+	thread := &starlark.Thread{
+		Name: "air",
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return LoadModule()
+		},
+	}
+	_, err := starlark.ExecFile(thread, "air.star", src, nil)
+	require.NoError(t, err)
+
+	_, err = starlark.ExecFile(thread, "air.star", `
+load("airquality.star", "airquality")
+airquality.us_aqi(pm2_5 = "lots")
+`, nil)
+	assert.ErrorContains(t, err, "pm2_5 must be a number")
+}
@@ -0,0 +1,290 @@
+package airquality
+
+import (
+	"math"
+)
+
+// Pollutant is a pollutant that air quality indices are computed from.
+type Pollutant string
+
+const (
+	PM25 Pollutant = "pm2_5"
+	PM10 Pollutant = "pm10"
+	O3   Pollutant = "o3"
+	NO2  Pollutant = "no2"
+	SO2  Pollutant = "so2"
+	CO   Pollutant = "co"
+)
+
+// Pollutants are the pollutants indices are computed from, in the order
+// they're reported in.
+var Pollutants = []Pollutant{PM25, PM10, O3, NO2, SO2, CO}
+
+// Concentrations are the concentrations of pollutants, in µg/m³. Pollutants
+// that weren't measured are missing.
+type Concentrations map[Pollutant]float64
+
+// molecularWeight is the molecular weight of the gases, in g/mol, for
+// converting between µg/m³ and ppb.
+var molecularWeight = map[Pollutant]float64{
+	O3:  48.00,
+	NO2: 46.01,
+	SO2: 64.07,
+	CO:  28.01,
+}
+
+// molarVolume is the volume of a mole of gas at 25°C and 1 atm, in liters,
+// which the US AQI's ppb and ppm are measured at.
+const molarVolume = 24.45
+
+// Index is an air quality index, and what it says about the air.
+type Index struct {
+	// Value is the index, such as 42.
+	Value int
+
+	// Category is how good the air is, such as "good" or "very_low".
+	Category string
+
+	// Color is the color the index's scale shows the category in, as
+	// "#rrggbb".
+	Color string
+
+	// Dominant is the pollutant with the highest index, which the index is.
+	Dominant Pollutant
+}
+
+// band is where a pollutant's concentrations map to a range of an index.
+type band struct {
+	cLow, cHigh float64
+	iLow, iHigh float64
+}
+
+// category is a range of an index's values.
+type category struct {
+	max   int
+	name  string
+	color string
+}
+
+// scale is an air quality index, computed from the concentrations of
+// pollutants.
+type scale struct {
+	// bands are the concentrations of each pollutant, in the units the
+	// index is defined in, that map to each range of the index.
+	bands map[Pollutant][]band
+
+	// unit converts a pollutant's concentration from µg/m³ to the unit its
+	// bands are in, and truncates it as the index says to.
+	unit func(p Pollutant, c float64) float64
+
+	// categories name the ranges of the index, in order.
+	categories []category
+}
+
+// usAQI is the United States EPA's Air Quality Index, with the PM2.5
+// breakpoints revised in 2024. Ozone uses the 8-hour table, which ends at
+// 300, and the others their usual averaging times, which hourly values
+// stand in for.
+var usAQI = scale{
+	bands: map[Pollutant][]band{
+		PM25: {
+			{0, 9.0, 0, 50},
+			{9.1, 35.4, 51, 100},
+			{35.5, 55.4, 101, 150},
+			{55.5, 125.4, 151, 200},
+			{125.5, 225.4, 201, 300},
+			{225.5, 325.4, 301, 500},
+		},
+		PM10: {
+			{0, 54, 0, 50},
+			{55, 154, 51, 100},
+			{155, 254, 101, 150},
+			{255, 354, 151, 200},
+			{355, 424, 201, 300},
+			{425, 604, 301, 500},
+		},
+		// ppm
+		O3: {
+			{0, 0.054, 0, 50},
+			{0.055, 0.070, 51, 100},
+			{0.071, 0.085, 101, 150},
+			{0.086, 0.105, 151, 200},
+			{0.106, 0.200, 201, 300},
+		},
+		// ppb
+		NO2: {
+			{0, 53, 0, 50},
+			{54, 100, 51, 100},
+			{101, 360, 101, 150},
+			{361, 649, 151, 200},
+			{650, 1249, 201, 300},
+			{1250, 2049, 301, 500},
+		},
+		// ppb
+		SO2: {
+			{0, 35, 0, 50},
+			{36, 75, 51, 100},
+			{76, 185, 101, 150},
+			{186, 304, 151, 200},
+			{305, 604, 201, 300},
+			{605, 1004, 301, 500},
+		},
+		// ppm
+		CO: {
+			{0, 4.4, 0, 50},
+			{4.5, 9.4, 51, 100},
+			{9.5, 12.4, 101, 150},
+			{12.5, 15.4, 151, 200},
+			{15.5, 30.4, 201, 300},
+			{30.5, 50.4, 301, 500},
+		},
+	},
+	unit: func(p Pollutant, c float64) float64 {
+		// truncated to the precision the EPA's tables are in
+		switch p {
+		case PM25:
+			return truncate(c, 0.1)
+		case PM10:
+			return truncate(c, 1)
+		case O3:
+			return truncate(toPPB(p, c)/1000, 0.001)
+		case NO2, SO2:
+			return truncate(toPPB(p, c), 1)
+		case CO:
+			return truncate(toPPB(p, c)/1000, 0.1)
+		}
+		return c
+	},
+	categories: []category{
+		{50, "good", "#00e400"},
+		{100, "moderate", "#ffff00"},
+		{150, "unhealthy_for_sensitive_groups", "#ff7e00"},
+		{200, "unhealthy", "#ff0000"},
+		{300, "very_unhealthy", "#8f3f97"},
+		{math.MaxInt, "hazardous", "#7e0023"},
+	},
+}
+
+// caqi is the Common Air Quality Index used across Europe, from the hourly
+// grid for background stations. Values above 100 are extrapolated from the
+// top band, as the grid says.
+var caqi = scale{
+	bands: map[Pollutant][]band{
+		PM25: {{0, 15, 0, 25}, {15, 30, 25, 50}, {30, 55, 50, 75}, {55, 110, 75, 100}},
+		PM10: {{0, 25, 0, 25}, {25, 50, 25, 50}, {50, 90, 50, 75}, {90, 180, 75, 100}},
+		O3:   {{0, 60, 0, 25}, {60, 120, 25, 50}, {120, 180, 50, 75}, {180, 240, 75, 100}},
+		NO2:  {{0, 50, 0, 25}, {50, 100, 25, 50}, {100, 200, 50, 75}, {200, 400, 75, 100}},
+		SO2:  {{0, 50, 0, 25}, {50, 100, 25, 50}, {100, 350, 50, 75}, {350, 500, 75, 100}},
+		CO:   {{0, 5000, 0, 25}, {5000, 7500, 25, 50}, {7500, 10000, 50, 75}, {10000, 20000, 75, 100}},
+	},
+	unit: func(_ Pollutant, c float64) float64 { return c },
+	categories: []category{
+		{25, "very_low", "#79bc6a"},
+		{50, "low", "#bbcf4c"},
+		{75, "medium", "#eec20b"},
+		{100, "high", "#f29305"},
+		{math.MaxInt, "very_high", "#e8416f"},
+	},
+}
+
+// USAQI returns the US Air Quality Index of concentrations, or false if
+// none of the pollutants it's computed from were measured. Concentrations
+// above the top of a pollutant's table give the top of the index.
+func USAQI(c Concentrations) (Index, bool) {
+	return usAQI.index(usAQI.subIndices(c, false))
+}
+
+// CAQI returns the Common Air Quality Index of concentrations, or false if
+// none of the pollutants it's computed from were measured.
+func CAQI(c Concentrations) (Index, bool) {
+	return caqi.index(caqi.subIndices(c, true))
+}
+
+// subIndices returns the index of each pollutant in c. If extrapolate is
+// set, concentrations above the top band continue it, rather than giving
+// the top of the index.
+func (s scale) subIndices(c Concentrations, extrapolate bool) map[Pollutant]int {
+	sub := map[Pollutant]int{}
+	for p, v := range c {
+		if _, ok := s.bands[p]; !ok || math.IsNaN(v) || v < 0 {
+			continue
+		}
+		sub[p] = s.subIndex(p, s.unit(p, v), extrapolate)
+	}
+	return sub
+}
+
+// index returns the highest of the pollutants' indices, or false if there
+// are none.
+func (s scale) index(sub map[Pollutant]int) (Index, bool) {
+	best, found := Index{}, false
+	for _, p := range Pollutants {
+		if i, ok := sub[p]; ok && (!found || i > best.Value) {
+			best.Value, best.Dominant, found = i, p, true
+		}
+	}
+	if !found {
+		return Index{}, false
+	}
+
+	for _, cat := range s.categories {
+		if best.Value <= cat.max {
+			best.Category, best.Color = cat.name, cat.color
+			break
+		}
+	}
+	return best, true
+}
+
+func (s scale) subIndex(p Pollutant, c float64, extrapolate bool) int {
+	bands := s.bands[p]
+	for i, b := range bands {
+		// concentrations between two bands' ends, which truncating them
+		// avoids, count towards the upper band
+		if c <= b.cHigh || (i == len(bands)-1 && extrapolate) {
+			return int(math.Round((b.iHigh-b.iLow)/(b.cHigh-b.cLow)*(max(c, b.cLow)-b.cLow) + b.iLow))
+		}
+	}
+	return int(bands[len(bands)-1].iHigh)
+}
+
+// concentration is the inverse of subIndex, for providers that only report
+// a pollutant's index. It returns the concentration the index is computed
+// from, in µg/m³, before truncating it.
+func (s scale) concentration(p Pollutant, index int) float64 {
+	bands := s.bands[p]
+	b := bands[len(bands)-1]
+	for _, b2 := range bands {
+		if float64(index) <= b2.iHigh {
+			b = b2
+			break
+		}
+	}
+	c := b.cLow + (float64(index)-b.iLow)*(b.cHigh-b.cLow)/(b.iHigh-b.iLow)
+
+	switch p {
+	case O3:
+		return fromPPB(p, c*1000)
+	case NO2, SO2:
+		return fromPPB(p, c)
+	case CO:
+		return fromPPB(p, c*1000)
+	}
+	return c
+}
+
+// toPPB converts a gas's concentration from µg/m³ to ppb.
+func toPPB(p Pollutant, c float64) float64 {
+	return c * molarVolume / molecularWeight[p]
+}
+
+// fromPPB converts a gas's concentration from ppb to µg/m³.
+func fromPPB(p Pollutant, ppb float64) float64 {
+	return ppb * molecularWeight[p] / molarVolume
+}
+
+// truncate truncates v to a multiple of precision. Values a hair under a
+// multiple, as floating point arithmetic leaves them, are rounded up to it.
+func truncate(v, precision float64) float64 {
+	return math.Floor(v/precision+1e-9) * precision
+}
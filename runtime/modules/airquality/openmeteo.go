@@ -0,0 +1,76 @@
+package airquality
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/runtime/modules/location"
+)
+
+var openMeteoURL = "https://air-quality-api.open-meteo.com/v1/air-quality"
+
+// openMeteoPollutants are the names Open-Meteo gives pollutants.
+var openMeteoPollutants = map[string]Pollutant{
+	"pm2_5":            PM25,
+	"pm10":             PM10,
+	"ozone":            O3,
+	"nitrogen_dioxide": NO2,
+	"sulphur_dioxide":  SO2,
+	"carbon_monoxide":  CO,
+}
+
+// openMeteo fetches from Open-Meteo, which models the air of the whole
+// world, and pollen in Europe during the pollen season. Its concentrations
+// are hourly, so the US AQI computed from them can differ from official
+// figures, which average over 8 or 24 hours.
+func openMeteo(ctx context.Context, f *fetch.Fetcher, place location.Place, _ string) (*Report, error) {
+	var fields []string
+	for name := range openMeteoPollutants {
+		fields = append(fields, name)
+	}
+	for _, p := range Pollens {
+		fields = append(fields, string(p)+"_pollen")
+	}
+	// in the same order every time, so that responses are cached
+	slices.Sort(fields)
+
+	q := url.Values{}
+	q.Set("latitude", strconv.FormatFloat(place.Lat, 'f', -1, 64))
+	q.Set("longitude", strconv.FormatFloat(place.Lng, 'f', -1, 64))
+	q.Set("timezone", place.Timezone)
+	q.Set("current", strings.Join(fields, ","))
+
+	// values it doesn't have, such as pollen outside Europe, are null
+	var resp struct {
+		UTCOffsetSeconds int            `json:"utc_offset_seconds"`
+		Current          map[string]any `json:"current"`
+	}
+	if err := f.Get(ctx, openMeteoURL+"?"+q.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	at, _ := resp.Current["time"].(string)
+	now, err := time.ParseInLocation("2006-01-02T15:04", at, time.FixedZone("", resp.UTCOffsetSeconds))
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q", at)
+	}
+
+	r := &Report{Time: now, Concentrations: Concentrations{}, Pollen: map[Pollen]float64{}}
+	for name, p := range openMeteoPollutants {
+		if v, ok := resp.Current[name].(float64); ok {
+			r.Concentrations[p] = v
+		}
+	}
+	for _, p := range Pollens {
+		if v, ok := resp.Current[string(p)+"_pollen"].(float64); ok {
+			r.Pollen[p] = v
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,169 @@
+package golden
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/render"
+	"tidbyt.dev/pixlet/render/golden"
+	"tidbyt.dev/pixlet/runtime/modules/file"
+	"tidbyt.dev/pixlet/runtime/modules/render_runtime"
+)
+
+const (
+	ModuleName = "golden"
+)
+
+var (
+	once   sync.Once
+	module starlark.StringDict
+)
+
+func LoadModule() (starlark.StringDict, error) {
+	once.Do(func() {
+		module = starlark.StringDict{
+			ModuleName: &starlarkstruct.Module{
+				Name: ModuleName,
+				Members: starlark.StringDict{
+					"compare":        starlark.NewBuiltin("compare", compare),
+					"assert_matches": starlark.NewBuiltin("assert_matches", assertMatches),
+				},
+			},
+		}
+	})
+
+	return module, nil
+}
+
+// compare returns a struct describing how rendered output differs from a
+// golden.
+func compare(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	diff, err := diffArgs(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := starlark.StringDict{
+		"match":     starlark.Bool(diff == nil),
+		"frame":     starlark.None,
+		"pixels":    starlark.MakeInt(0),
+		"max_delta": starlark.Float(0),
+		"message":   starlark.String(""),
+	}
+	if diff != nil {
+		fields["frame"] = starlark.MakeInt(diff.Frame)
+		fields["pixels"] = starlark.MakeInt(diff.Pixels)
+		fields["max_delta"] = starlark.Float(diff.MaxDelta)
+		fields["message"] = starlark.String(diff.Error())
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields), nil
+}
+
+// assertMatches fails if rendered output differs from a golden.
+func assertMatches(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	diff, err := diffArgs(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	if diff != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), diff)
+	}
+	return starlark.None, nil
+}
+
+func diffArgs(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (*golden.Diff, error) {
+	var (
+		got, want     starlark.Value
+		tolerance     starlark.Int
+		perceptual    starlark.Float
+		maxDiffPixels starlark.Int
+	)
+	if err := starlark.UnpackArgs(
+		b.Name(),
+		args, kwargs,
+		"got", &got,
+		"golden", &want,
+		"tolerance?", &tolerance,
+		"perceptual?", &perceptual,
+		"max_diff_pixels?", &maxDiffPixels,
+	); err != nil {
+		return nil, err
+	}
+
+	var opts golden.Options
+	t, ok := tolerance.Int64()
+	if !ok || t < 0 || t > 255 {
+		return nil, fmt.Errorf("%s: tolerance must be between 0 and 255", b.Name())
+	}
+	opts.Tolerance = uint8(t)
+	opts.Perceptual = float64(perceptual)
+	if err := starlark.AsInt(maxDiffPixels, &opts.MaxDiffPixels); err != nil {
+		return nil, fmt.Errorf("%s: max_diff_pixels: %w", b.Name(), err)
+	}
+
+	gotFrames, err := frames(got)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	wantFrames, err := goldenFrames(want)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	return golden.CompareFrames(wantFrames, gotFrames, opts), nil
+}
+
+// frames renders a root, a list of roots or a widget, which is rendered as
+// the child of a root.
+func frames(v starlark.Value) ([]image.Image, error) {
+	switch v := v.(type) {
+	case render_runtime.Rootable:
+		return v.AsRenderRoot().Paint(true), nil
+
+	case render_runtime.Widget:
+		return render.Root{Child: v.AsRenderWidget()}.Paint(true), nil
+
+	case *starlark.List:
+		var roots []render.Root
+		for i := 0; i < v.Len(); i++ {
+			r, ok := v.Index(i).(render_runtime.Rootable)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of roots, found %s", v.Index(i).Type())
+			}
+			roots = append(roots, r.AsRenderRoot())
+		}
+		return render.PaintRoots(true, roots...), nil
+
+	default:
+		return nil, fmt.Errorf("expected a root, a list of roots or a widget, found %s", v.Type())
+	}
+}
+
+// goldenFrames reads a golden from one of the app's files, loaded with
+// load("testdata/clock.png", clock_png = "file"), or from bytes.
+func goldenFrames(v starlark.Value) ([]image.Image, error) {
+	var r io.Reader
+	switch v := v.(type) {
+	case *file.File:
+		f, err := v.FS.Open(v.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+
+	case starlark.Bytes:
+		r = bytes.NewReader([]byte(v))
+
+	default:
+		return nil, fmt.Errorf("expected golden to be a file or bytes, found %s", v.Type())
+	}
+
+	return golden.DecodeGolden(r)
+}
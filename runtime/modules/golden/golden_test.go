@@ -0,0 +1,82 @@
+package golden_test
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"image/png"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/render"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+var goldenSrc = `
+load("golden.star", "golden")
+load("render.star", "render")
+load("testdata/box.png", box_png = "file")
+
+def box(color):
+    return render.Root(child = render.Box(width = 8, height = 4, color = color))
+
+def test_match():
+    golden.assert_matches(box("#f00"), box_png)
+    golden.assert_matches([box("#f00")], box_png.readall("rb"))
+
+    result = golden.compare(box("#f00"), box_png)
+    if not result.match or result.frame != None:
+        fail("expected a match, got", result)
+
+def test_mismatch():
+    result = golden.compare(box("#00f"), box_png)
+    if result.match or result.frame != 0 or result.pixels != 8 * 4:
+        fail("expected every pixel of the box to differ, got", result)
+
+    result = golden.compare(box("#fc0000"), box_png)
+    if result.match or result.max_delta != 3:
+        fail("expected a slightly different red, got", result)
+
+def test_thresholds():
+    golden.assert_matches(box("#fc0000"), box_png, tolerance = 3)
+    golden.assert_matches(box("#fc0000"), box_png, perceptual = 2.3)
+    golden.assert_matches(render.Box(width = 8, height = 4, color = "#f00"), box_png)
+
+    result = golden.compare(render.Root(child = render.Box(width = 7, height = 4, color = "#f00")), box_png, max_diff_pixels = 4)
+    if not result.match:
+        fail("expected the 4 pixels to be allowed, got", result)
+
+def main():
+    return box("#f00")
+`
+
+func TestGolden(t *testing.T) {
+	frames := render.Root{Child: render.Box{Width: 8, Height: 4, Color: color.RGBA{0xff, 0, 0, 0xff}}}.Paint(true)
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, frames[0]))
+
+	app, err := runtime.NewAppletFromFS("golden_test", fstest.MapFS{
+		"golden_test.star": {Data: []byte(goldenSrc)},
+		"testdata/box.png": {Data: buf.Bytes()},
+	})
+	require.NoError(t, err)
+	app.RunTests(t)
+}
+
+func TestGoldenMismatchFails(t *testing.T) {
+	src := `
+load("golden.star", "golden")
+load("render.star", "render")
+
+def main():
+    golden.assert_matches(render.Box(color = "#00f"), b"not an image")
+    return []
+`
+	app, err := runtime.NewApplet("golden_mismatch.star", []byte(src))
+	require.NoError(t, err)
+
+	_, err = app.Run(context.Background())
+	assert.ErrorContains(t, err, "goldens must be PNGs or GIFs")
+}
@@ -0,0 +1,80 @@
+// Package icons_runtime provides the icons module, which looks up icons in
+// the icon packs registered with pixlet.
+package icons_runtime
+
+import (
+	"fmt"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/icons"
+)
+
+const (
+	ModuleName = "icons"
+)
+
+var (
+	once   sync.Once
+	module starlark.StringDict
+)
+
+func LoadModule() (starlark.StringDict, error) {
+	once.Do(func() {
+		module = starlark.StringDict{
+			ModuleName: &starlarkstruct.Module{
+				Name: ModuleName,
+				Members: starlark.StringDict{
+					"get":    starlark.NewBuiltin("get", get),
+					"exists": starlark.NewBuiltin("exists", exists),
+					"list":   starlark.NewBuiltin("list", list),
+				},
+			},
+		}
+	})
+
+	return module, nil
+}
+
+// get returns the contents of an icon's file, for render.Image.
+func get(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var ref string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "ref", &ref); err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	icon := icons.Lookup(ref)
+	if icon == nil {
+		return nil, fmt.Errorf("%s: no icon %q in the registered icon packs", b.Name(), ref)
+	}
+	return starlark.String(icon.Data), nil
+}
+
+func exists(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var ref string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "ref", &ref); err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	return starlark.Bool(icons.Lookup(ref) != nil), nil
+}
+
+// list returns the references of the icons in a pack, or in every pack.
+func list(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pack string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "pack?", &pack); err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	refs := []starlark.Value{}
+	for _, p := range icons.Packs() {
+		if pack != "" && p.Name != pack {
+			continue
+		}
+		for _, name := range p.Names() {
+			refs = append(refs, starlark.String(p.Icons[name].Ref()))
+		}
+	}
+	return starlark.NewList(refs), nil
+}
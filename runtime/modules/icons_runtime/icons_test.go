@@ -0,0 +1,59 @@
+package icons_runtime_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/icons"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+var iconsSource = `
+load("icons.star", "icons")
+load("render.star", "render")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+assert(icons.exists("grace/cobol"))
+assert(not icons.exists("grace/fortran"))
+assert(icons.list() == ["grace/cobol", "grace/ships/harvard"])
+assert(icons.list("grace") == icons.list())
+assert(icons.list("nope") == [])
+
+def main():
+    return render.Root(child = render.Image(src = icons.get("grace/cobol")))
+`
+
+var svg = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 8 8"><rect width="8" height="8" fill="#f00"/></svg>`
+
+func TestIcons(t *testing.T) {
+	pack, err := icons.LoadPack("grace", fstest.MapFS{
+		"cobol.svg":         {Data: []byte(svg)},
+		"ships/harvard.svg": {Data: []byte(svg)},
+	})
+	require.NoError(t, err)
+	icons.Register(pack)
+
+	app, err := runtime.NewApplet("pack.star", []byte(iconsSource))
+	require.NoError(t, err)
+
+	roots, err := app.Run(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, roots, 1)
+
+	app, err = runtime.NewApplet("missing.star", []byte(`
+load("icons.star", "icons")
+
+def main():
+    return icons.get("grace/fortran")
+`))
+	require.NoError(t, err)
+	_, err = app.Run(context.Background())
+	assert.ErrorContains(t, err, `no icon "grace/fortran"`)
+}
@@ -0,0 +1,108 @@
+// Package fetch gets JSON from the web services that modules such as
+// weather and sports wrap. Requests go through the same client as the http
+// module, so responses are cached like an app's own requests, and are held
+// to the same network hosts.
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime/modules/starlarkhttp"
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+// UserAgent identifies pixlet to the services, some of which turn away
+// requests without one.
+const UserAgent = "pixlet (+https://github.com/tidbyt/pixlet)"
+
+type allowedHostsKey struct{}
+
+// Context returns the context of thread, for fetching on behalf of the app
+// running on it. Like the app's own requests, fetches made with it are
+// limited to the network hosts the app declares, see
+// starlarkhttp.AllowHosts.
+func Context(thread *starlark.Thread) context.Context {
+	ctx := starlarkutil.ThreadContext(thread)
+	if allowed := starlarkhttp.AllowedHosts(thread); allowed != nil {
+		ctx = context.WithValue(ctx, allowedHostsKey{}, allowed)
+	}
+	return ctx
+}
+
+// CheckHost returns an error if ctx came from Context, and req isn't to one
+// of the network hosts the app declares.
+func CheckHost(ctx context.Context, req *http.Request) error {
+	if allowed := allowedHosts(ctx); allowed != nil && !allowed(req.URL.Host) {
+		return fmt.Errorf("%s isn't one of the network hosts the app declares", req.URL.Host)
+	}
+	return nil
+}
+
+// Client returns the http module's client, which if ctx came from Context
+// only follows redirects to the network hosts the app declares.
+func Client(ctx context.Context) *http.Client {
+	return starlarkhttp.RestrictRedirects(starlarkhttp.StarlarkHTTPClient, allowedHosts(ctx))
+}
+
+func allowedHosts(ctx context.Context) func(string) bool {
+	allowed, _ := ctx.Value(allowedHostsKey{}).(func(string) bool)
+	return allowed
+}
+
+// Fetcher gets JSON on behalf of an app.
+type Fetcher struct {
+	// TTL is how long responses are cached for.
+	TTL time.Duration
+
+	// AppID is the app responses are cached for.
+	AppID string
+
+	// Accept is sent as the Accept header. Empty means application/json.
+	Accept string
+}
+
+// Get fetches url and decodes its JSON response into v. Responses other
+// than 200 OK are errors, which include the start of the response body. If
+// ctx came from Context, url must be on one of the app's network hosts.
+func (f *Fetcher) Get(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if err := CheckHost(ctx, req); err != nil {
+		return err
+	}
+
+	accept := f.Accept
+	if accept == "" {
+		accept = "application/json"
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-Tidbyt-App", f.AppID)
+	req.Header.Set("X-Tidbyt-Cache-Seconds", strconv.Itoa(int(f.TTL.Seconds())))
+
+	resp, err := Client(ctx).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
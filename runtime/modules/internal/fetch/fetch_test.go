@@ -0,0 +1,89 @@
+package fetch_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch/fetchtest"
+	"tidbyt.dev/pixlet/runtime/modules/starlarkhttp"
+)
+
+func TestGet(t *testing.T) {
+	var baseURL string
+	requests := fetchtest.Serve(t, map[string]string{
+		"/ada": `{"name": "Ada", "next": "$SERVER/grace"}`,
+	}, map[*string]string{&baseURL: ""})
+
+	f := &fetch.Fetcher{TTL: time.Minute, AppID: "clock"}
+
+	var v struct{ Name, Next string }
+	require.NoError(t, f.Get(context.Background(), baseURL+"/ada", &v))
+	assert.Equal(t, "Ada", v.Name)
+	assert.Equal(t, baseURL+"/grace", v.Next)
+
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	assert.Equal(t, "clock", req.Header.Get("X-Tidbyt-App"))
+	assert.Equal(t, "60", req.Header.Get("X-Tidbyt-Cache-Seconds"))
+	assert.Equal(t, "application/json", req.Header.Get("Accept"))
+	assert.Equal(t, fetch.UserAgent, req.Header.Get("User-Agent"))
+
+	err := f.Get(context.Background(), baseURL+"/grace", &v)
+	assert.ErrorContains(t, err, "404 Not Found: 404 page not found")
+
+	f.Accept = "application/geo+json"
+	f.Get(context.Background(), baseURL+"/ada", &v)
+	assert.Equal(t, "application/geo+json", (*requests)[2].Header.Get("Accept"))
+}
+
+func TestGetChecksHosts(t *testing.T) {
+	var baseURL string
+	requests := fetchtest.Serve(t, map[string]string{
+		"/ada": `{"name": "Ada"}`,
+	}, map[*string]string{&baseURL: ""})
+
+	f := &fetch.Fetcher{TTL: time.Minute, AppID: "clock"}
+	thread := &starlark.Thread{Name: "clock"}
+	var v struct{ Name string }
+
+	// apps without declared hosts can fetch from anywhere
+	require.NoError(t, f.Get(fetch.Context(thread), baseURL+"/ada", &v))
+
+	starlarkhttp.AllowHosts(thread, func(host string) bool { return host == "example.com" })
+	err := f.Get(fetch.Context(thread), baseURL+"/ada", &v)
+	assert.ErrorContains(t, err, "isn't one of the network hosts the app declares")
+	assert.Len(t, *requests, 1)
+}
+
+func TestGetChecksRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "Ada"}`))
+	}))
+	defer target.Close()
+
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	f := &fetch.Fetcher{TTL: time.Minute, AppID: "clock"}
+	thread := &starlark.Thread{Name: "clock"}
+	var v struct{ Name string }
+
+	redirectHost := strings.TrimPrefix(redirect.URL, "http://")
+	starlarkhttp.AllowHosts(thread, func(host string) bool { return host == redirectHost })
+	err := f.Get(fetch.Context(thread), redirect.URL, &v)
+	assert.ErrorContains(t, err, "isn't one of the network hosts the app declares")
+	assert.Empty(t, v.Name)
+
+	// without an allowlist, redirects are followed as usual
+	require.NoError(t, f.Get(context.Background(), redirect.URL, &v))
+	assert.Equal(t, "Ada", v.Name)
+}
@@ -0,0 +1,37 @@
+// Package fetchtest serves canned responses to modules that use
+// fetch.Fetcher, for their tests.
+package fetchtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Serve starts a server that answers requests for the paths in bodies with
+// their body, in which $SERVER is replaced by the server's URL, and anything
+// else with a 404. Each of urls is pointed at the server, at the path it
+// maps to, until the test ends. It returns the requests the server gets.
+func Serve(t *testing.T, bodies map[string]string, urls map[*string]string) *[]*http.Request {
+	var requests []*http.Request
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(strings.ReplaceAll(body, "$SERVER", server.URL)))
+	}))
+	t.Cleanup(server.Close)
+
+	for u, path := range urls {
+		old := *u
+		t.Cleanup(func() { *u = old })
+		*u = server.URL + path
+	}
+
+	return &requests
+}
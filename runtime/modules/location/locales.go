@@ -0,0 +1,35 @@
+package location
+
+// languages are the main languages of countries, as ISO 639 codes, for
+// guessing a locale. Countries that aren't listed are assumed to use
+// English.
+var languages = map[string]string{
+	"AD": "ca", "AE": "ar", "AR": "es", "AT": "de", "BA": "bs", "BE": "nl",
+	"BG": "bg", "BH": "ar", "BO": "es", "BR": "pt", "BY": "be", "CH": "de",
+	"CL": "es", "CN": "zh", "CO": "es", "CR": "es", "CU": "es", "CY": "el",
+	"CZ": "cs", "DE": "de", "DK": "da", "DO": "es", "DZ": "ar", "EC": "es",
+	"EE": "et", "EG": "ar", "ES": "es", "FI": "fi", "FO": "fo", "FR": "fr",
+	"GE": "ka", "GR": "el", "GT": "es", "HK": "zh", "HN": "es", "HR": "hr",
+	"HU": "hu", "ID": "id", "IL": "he", "IQ": "ar", "IR": "fa", "IS": "is",
+	"IT": "it", "JO": "ar", "JP": "ja", "KR": "ko", "KW": "ar", "KZ": "kk",
+	"LB": "ar", "LI": "de", "LT": "lt", "LU": "fr", "LV": "lv", "LY": "ar",
+	"MA": "ar", "MC": "fr", "MD": "ro", "ME": "sr", "MK": "mk", "MO": "zh",
+	"MX": "es", "MY": "ms", "NI": "es", "NL": "nl", "NO": "nb", "OM": "ar",
+	"PA": "es", "PE": "es", "PL": "pl", "PR": "es", "PT": "pt", "PY": "es",
+	"QA": "ar", "RO": "ro", "RS": "sr", "RU": "ru", "SA": "ar", "SE": "sv",
+	"SI": "sl", "SK": "sk", "SM": "it", "SV": "es", "SY": "ar", "TH": "th",
+	"TN": "ar", "TR": "tr", "TW": "zh", "UA": "uk", "UY": "es", "UZ": "uz",
+	"VA": "it", "VE": "es", "VN": "vi", "YE": "ar",
+}
+
+// imperial are the countries that mostly use imperial units.
+var imperial = map[string]bool{
+	"LR": true, "MM": true, "US": true,
+}
+
+// hour12 are the countries where times are usually written with a 12-hour
+// clock.
+var hour12 = map[string]bool{
+	"AU": true, "BD": true, "CA": true, "EG": true, "IN": true, "JO": true,
+	"MY": true, "NZ": true, "PH": true, "PK": true, "SA": true, "US": true,
+}
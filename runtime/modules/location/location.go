@@ -0,0 +1,326 @@
+// Package location resolves the timezone, country and locale of a place
+// offline, so apps don't need to call geocoding APIs to show local time.
+//
+// Places are matched to the nearest of the representative locations listed
+// in the tz database's zone.tab, which is embedded along with iso3166.tab,
+// both from tzdata 2025b and in the public domain. That's exact for most
+// places, though it can pick a neighbouring zone near a border, so the
+// timezone of a location value is used when it has one.
+package location
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+const (
+	ModuleName = "location"
+)
+
+//go:embed zone.tab
+var zoneTab []byte
+
+//go:embed iso3166.tab
+var iso3166Tab []byte
+
+// zone is a row of zone.tab: a timezone, and the country and place it's
+// named for.
+type zone struct {
+	country  string
+	lat, lng float64
+	timezone string
+}
+
+var (
+	once   sync.Once
+	module starlark.StringDict
+
+	tablesOnce sync.Once
+	zones      []zone
+	countries  map[string]string
+)
+
+func LoadModule() (starlark.StringDict, error) {
+	once.Do(func() {
+		module = starlark.StringDict{
+			ModuleName: &starlarkstruct.Module{
+				Name: ModuleName,
+				Members: starlark.StringDict{
+					"resolve": starlark.NewBuiltin("resolve", resolve),
+				},
+			},
+		}
+	})
+
+	return module, nil
+}
+
+// Place is what's known about a location.
+type Place struct {
+	Lat, Lng float64
+
+	// Locality is the name of the place, if the location value has one.
+	Locality string
+
+	// Timezone is the IANA name of the place's timezone.
+	Timezone string
+
+	// Country is the ISO 3166 code of the country the place is in, and
+	// CountryName its English name.
+	Country     string
+	CountryName string
+
+	// Locale is a BCP 47 tag for the main language of the country, such
+	// as "de-AT".
+	Locale string
+
+	// Units is "imperial" in the few countries that use them, and "metric"
+	// elsewhere.
+	Units string
+
+	// Hour12 is set if the country usually writes times with a 12-hour
+	// clock.
+	Hour12 bool
+}
+
+// Resolve returns what's known about the place at lat and lng. timezone is
+// the place's timezone if it's already known, and empty otherwise.
+func Resolve(lat, lng float64, timezone string) Place {
+	tablesOnce.Do(func() {
+		zones = parseZones(zoneTab)
+		countries = parseCountries(iso3166Tab)
+	})
+
+	p := Place{Lat: lat, Lng: lng, Timezone: timezone}
+
+	nearest := zones[0]
+	best := math.Inf(1)
+	for _, z := range zones {
+		if d := distance(lat, lng, z.lat, z.lng); d < best {
+			nearest, best = z, d
+		}
+	}
+
+	if _, err := time.LoadLocation(p.Timezone); p.Timezone == "" || err != nil {
+		p.Timezone = nearest.timezone
+	}
+
+	// the country of the timezone is more reliable near borders, when the
+	// location value said what it is
+	p.Country = nearest.country
+	if p.Timezone != nearest.timezone {
+		for _, z := range zones {
+			if z.timezone == p.Timezone {
+				p.Country = z.country
+				break
+			}
+		}
+	}
+	p.CountryName = countries[p.Country]
+
+	lang, ok := languages[p.Country]
+	if !ok {
+		lang = "en"
+	}
+	p.Locale = lang + "-" + p.Country
+
+	p.Units = "metric"
+	if imperial[p.Country] {
+		p.Units = "imperial"
+	}
+	p.Hour12 = hour12[p.Country]
+
+	return p
+}
+
+func resolve(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var loc starlark.Value
+
+	if err := starlark.UnpackArgs(
+		"resolve",
+		args, kwargs,
+		"location", &loc,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for resolve: %s", err)
+	}
+
+	p, err := FromValue(loc)
+	if err != nil {
+		return nil, fmt.Errorf("resolve: %w", err)
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"lat":          starlark.Float(p.Lat),
+		"lng":          starlark.Float(p.Lng),
+		"locality":     starlark.String(p.Locality),
+		"timezone":     starlark.String(p.Timezone),
+		"country":      starlark.String(p.Country),
+		"country_name": starlark.String(p.CountryName),
+		"locale":       starlark.String(p.Locale),
+		"units":        starlark.String(p.Units),
+		"hour12":       starlark.Bool(p.Hour12),
+	}), nil
+}
+
+// FromValue resolves a location value, which is the JSON of a
+// schema.Location config value, or a dict with at least lat and lng, such as
+// other modules take.
+func FromValue(v starlark.Value) (Place, error) {
+	fields, err := locationFields(v)
+	if err != nil {
+		return Place{}, err
+	}
+
+	lat, err := strconv.ParseFloat(fields["lat"], 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return Place{}, fmt.Errorf("invalid latitude %q", fields["lat"])
+	}
+	lng, err := strconv.ParseFloat(fields["lng"], 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return Place{}, fmt.Errorf("invalid longitude %q", fields["lng"])
+	}
+
+	p := Resolve(lat, lng, fields["timezone"])
+	p.Locality = fields["locality"]
+	return p, nil
+}
+
+// locationFields returns the fields of a location value, which is the JSON
+// of a schema.Location config value, or a dict of the same. Coordinates are
+// strings in config values, but may be numbers in dicts apps make.
+func locationFields(v starlark.Value) (map[string]string, error) {
+	fields := map[string]string{}
+
+	switch v := v.(type) {
+	case starlark.String:
+		var raw map[string]any
+		if err := json.Unmarshal([]byte(v.GoString()), &raw); err != nil {
+			return nil, fmt.Errorf("decoding location: %w", err)
+		}
+		for k, val := range raw {
+			switch val.(type) {
+			case string, float64:
+				fields[k] = fmt.Sprint(val)
+			}
+		}
+
+	case *starlark.Dict:
+		for _, item := range v.Items() {
+			k, ok := starlark.AsString(item[0])
+			if !ok {
+				continue
+			}
+			switch val := item[1].(type) {
+			case starlark.String:
+				fields[k] = val.GoString()
+			case starlark.Int, starlark.Float:
+				fields[k] = val.String()
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("location must be a string or dict, not %s", v.Type())
+	}
+
+	return fields, nil
+}
+
+// distance returns the angle between two points on a sphere, in radians.
+func distance(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * math.Asin(math.Sqrt(a))
+}
+
+func parseZones(tab []byte) []zone {
+	var zs []zone
+	s := bufio.NewScanner(bytes.NewReader(tab))
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		if len(cols) < 3 {
+			continue
+		}
+		lat, lng, ok := parseCoordinates(cols[1])
+		if !ok {
+			continue
+		}
+		zs = append(zs, zone{country: cols[0], lat: lat, lng: lng, timezone: cols[2]})
+	}
+	return zs
+}
+
+// parseCoordinates parses ISO 6709 coordinates as zone.tab writes them:
+// ±DDMM±DDDMM or ±DDMMSS±DDDMMSS.
+func parseCoordinates(s string) (lat, lng float64, ok bool) {
+	i := strings.IndexAny(s[1:], "+-") + 1
+	if i <= 0 {
+		return 0, 0, false
+	}
+	lat, ok1 := parseAngle(s[:i], 2)
+	lng, ok2 := parseAngle(s[i:], 3)
+	return lat, lng, ok1 && ok2
+}
+
+func parseAngle(s string, degreeDigits int) (float64, bool) {
+	if len(s) < 1+degreeDigits+2 {
+		return 0, false
+	}
+	sign := 1.0
+	if s[0] == '-' {
+		sign = -1
+	}
+	digits := s[1:]
+
+	var angle float64
+	scale := 1.0
+	for len(digits) > 0 {
+		n := 2
+		if scale == 1 {
+			n = degreeDigits
+		}
+		if len(digits) < n {
+			return 0, false
+		}
+		v, err := strconv.Atoi(digits[:n])
+		if err != nil {
+			return 0, false
+		}
+		angle += float64(v) / scale
+		digits = digits[n:]
+		scale *= 60
+	}
+	return sign * angle, true
+}
+
+func parseCountries(tab []byte) map[string]string {
+	cs := map[string]string{}
+	s := bufio.NewScanner(bytes.NewReader(tab))
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if code, name, ok := strings.Cut(line, "\t"); ok {
+			cs[code] = name
+		}
+	}
+	return cs
+}
@@ -0,0 +1,104 @@
+package location_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/runtime/modules/location"
+)
+
+var locationSource = `
+load("location.star", "location")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+# a config value from schema.Location
+brooklyn = location.resolve("""
+{
+	"lat": "40.6781784",
+	"lng": "-73.9441579",
+	"description": "Brooklyn, NY, USA",
+	"locality": "Brooklyn",
+	"timezone": "America/New_York"
+}
+""")
+assert(brooklyn.timezone == "America/New_York")
+assert(brooklyn.country == "US")
+assert(brooklyn.country_name == "United States")
+assert(brooklyn.locale == "en-US")
+assert(brooklyn.units == "imperial")
+assert(brooklyn.hour12)
+assert(brooklyn.locality == "Brooklyn")
+
+# the timezone is found when the value doesn't have one
+vienna = location.resolve({"lat": 48.2082, "lng": 16.3738})
+assert(vienna.timezone == "Europe/Vienna")
+assert(vienna.locale == "de-AT")
+assert(vienna.units == "metric")
+assert(not vienna.hour12)
+
+def main():
+    return []
+`
+
+func TestLocation(t *testing.T) {
+	app, err := runtime.NewApplet("location_test.star", []byte(locationSource))
+	require.NoError(t, err)
+	_, err = app.Run(context.Background())
+	require.NoError(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		lat, lng float64
+		timezone string
+		country  string
+	}{
+		{"Chicago", 41.8781, -87.6298, "America/Chicago", "US"},
+		{"Denver", 39.7392, -104.9903, "America/Denver", "US"},
+		{"Sydney", -33.8688, 151.2093, "Australia/Sydney", "AU"},
+		{"Tokyo", 35.6762, 139.6503, "Asia/Tokyo", "JP"},
+		{"Oslo", 59.9139, 10.7522, "Europe/Oslo", "NO"},
+		{"São Paulo", -23.5558, -46.6396, "America/Sao_Paulo", "BR"},
+	} {
+		p := location.Resolve(tc.lat, tc.lng, "")
+		assert.Equal(t, tc.timezone, p.Timezone, tc.name)
+		assert.Equal(t, tc.country, p.Country, tc.name)
+	}
+
+	// near a border, the timezone the location has is trusted, and its
+	// country used
+	p := location.Resolve(42.3149, -83.0364, "America/Toronto")
+	assert.Equal(t, "America/Toronto", p.Timezone)
+	assert.Equal(t, "CA", p.Country)
+	assert.Equal(t, "en-CA", p.Locale)
+
+	// as long as it's a real one
+	p = location.Resolve(35.6762, 139.6503, "Mars/Olympus_Mons")
+	assert.Equal(t, "Asia/Tokyo", p.Timezone)
+}
+
+func TestResolveErrors(t *testing.T) {
+	for _, src := range []string{
+		`location.resolve("not json")`,
+		`location.resolve({"lat": 91, "lng": 0})`,
+		`location.resolve({"lat": "0"})`,
+		`location.resolve(42)`,
+	} {
+		app, err := runtime.NewApplet("errors.star", []byte(`
+load("location.star", "location")
+def main():
+    `+src+`
+    return []
+`))
+		require.NoError(t, err)
+		_, err = app.Run(context.Background())
+		assert.Error(t, err, src)
+	}
+}
@@ -0,0 +1,91 @@
+package quotes
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+)
+
+var coinGeckoURL = "https://api.coingecko.com/api/v3"
+
+var coinGecko = provider{
+	quote:  coinGeckoQuote,
+	search: coinGeckoSearch,
+}
+
+// coinGeckoQuote fetches from CoinGecko, which covers cryptocurrencies by
+// their CoinGecko ID, such as "bitcoin" rather than "BTC", in any currency.
+// It doesn't report the names of coins, and the previous close is the price
+// 24 hours ago, since the market never closes.
+func coinGeckoQuote(ctx context.Context, f *fetch.Fetcher, symbol, currency string) (*Quote, error) {
+	id := strings.ToLower(symbol)
+
+	q := url.Values{}
+	q.Set("ids", id)
+	q.Set("vs_currencies", currency)
+	q.Set("include_24hr_change", "true")
+	q.Set("include_last_updated_at", "true")
+
+	var resp map[string]map[string]*float64
+	if err := f.Get(ctx, coinGeckoURL+"/simple/price?"+q.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	prices := resp[id]
+	price := prices[currency]
+	if price == nil {
+		return nil, fmt.Errorf("no quote for %s in %s", symbol, currency)
+	}
+
+	quote := &Quote{
+		Symbol:        id,
+		Price:         *price,
+		PreviousClose: orNaN(nil),
+		Currency:      strings.ToUpper(currency),
+	}
+	if change := prices[currency+"_24h_change"]; change != nil {
+		quote.PreviousClose = *price / (1 + *change/100)
+	}
+	if updated := prices["last_updated_at"]; updated != nil {
+		quote.Time = time.Unix(int64(*updated), 0)
+	}
+	return quote, nil
+}
+
+type coinGeckoSearchResponse struct {
+	Coins []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Symbol string `json:"symbol"`
+	} `json:"coins"`
+}
+
+// coinGeckoSearch searches coins by their name or ticker symbol, and
+// returns their CoinGecko IDs to quote them by.
+func coinGeckoSearch(ctx context.Context, f *fetch.Fetcher, query string, limit int) ([]Match, error) {
+	var resp coinGeckoSearchResponse
+	if err := f.Get(ctx, coinGeckoURL+"/search?"+url.Values{"query": {query}}.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, c := range resp.Coins {
+		name := c.Name
+		if c.Symbol != "" {
+			name += " (" + strings.ToUpper(c.Symbol) + ")"
+		}
+		matches = append(matches, Match{
+			Symbol: c.ID,
+			Name:   name,
+			Type:   "crypto",
+		})
+		if len(matches) == limit {
+			break
+		}
+	}
+	return matches, nil
+}
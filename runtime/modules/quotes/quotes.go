@@ -0,0 +1,370 @@
+// Package quotes fetches stock and cryptocurrency quotes from several free
+// market data services, and reports them the same way whichever is used, so
+// ticker apps don't each need to parse a provider's API.
+//
+// Each symbol is fetched and cached on its own, so an app that quotes
+// several lists of symbols, one per screen say, only fetches a symbol they
+// share once.
+package quotes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	startime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+const (
+	ModuleName = "quotes"
+
+	// DefaultProvider is the provider used when apps don't name one.
+	DefaultProvider = "yahoo"
+
+	// DefaultCurrency is the currency quotes are in, for providers that
+	// quote in any currency.
+	DefaultCurrency = "usd"
+
+	// DefaultTTL is how long quotes are cached for by default.
+	DefaultTTL = time.Minute
+
+	// DefaultSearchTTL is how long search results are cached for by
+	// default. The symbols that match a query rarely change.
+	DefaultSearchTTL = 24 * time.Hour
+
+	// MaxSymbols is how many symbols can be quoted at once.
+	MaxSymbols = 20
+
+	// MaxResults is how many search results can be asked for.
+	MaxResults = 25
+)
+
+// Quote is the latest price of a symbol.
+type Quote struct {
+	Symbol string
+
+	// Name is the name of the company or coin, if the provider says.
+	Name string
+
+	// Price is the latest price, and PreviousClose the price a day
+	// earlier, or at the last close for markets that close. PreviousClose
+	// is NaN if the provider doesn't report it.
+	Price         float64
+	PreviousClose float64
+
+	// Currency is the ISO 4217 code of the currency prices are in, in upper
+	// case.
+	Currency string
+
+	// Exchange is where the symbol is traded, if the provider says.
+	Exchange string
+
+	// Time is when the price was last updated.
+	Time time.Time
+}
+
+// Change is how much the price changed since the previous close, or NaN if
+// it isn't known.
+func (q *Quote) Change() float64 {
+	return q.Price - q.PreviousClose
+}
+
+// ChangePercent is the change since the previous close, in percent, or NaN
+// if it isn't known.
+func (q *Quote) ChangePercent() float64 {
+	if q.PreviousClose == 0 {
+		return math.NaN()
+	}
+	return q.Change() / q.PreviousClose * 100
+}
+
+// Match is a symbol that matches a search.
+type Match struct {
+	// Symbol is what to quote the match by.
+	Symbol string
+
+	Name     string
+	Exchange string
+
+	// Type is what's traded, such as "equity", "etf" or "crypto", in lower
+	// case.
+	Type string
+}
+
+// provider fetches quotes from a market data service.
+type provider struct {
+	// quote fetches the quote of symbol, in currency for providers that
+	// quote in any currency.
+	quote func(ctx context.Context, f *fetch.Fetcher, symbol, currency string) (*Quote, error)
+
+	// search returns up to limit symbols that match query, best first.
+	search func(ctx context.Context, f *fetch.Fetcher, query string, limit int) ([]Match, error)
+}
+
+var providers = map[string]provider{
+	"yahoo":     yahoo,
+	"coingecko": coinGecko,
+}
+
+// Providers returns the names of the supported providers, sorted.
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+var (
+	once   sync.Once
+	module starlark.StringDict
+)
+
+func LoadModule() (starlark.StringDict, error) {
+	once.Do(func() {
+		module = starlark.StringDict{
+			ModuleName: &starlarkstruct.Module{
+				Name: ModuleName,
+				Members: starlark.StringDict{
+					"get":       starlark.NewBuiltin("get", get),
+					"search":    starlark.NewBuiltin("search", search),
+					"providers": starlark.NewBuiltin("providers", listProviders),
+				},
+			},
+		}
+	})
+
+	return module, nil
+}
+
+func lookup(providerName string) (provider, error) {
+	p, ok := providers[providerName]
+	if !ok {
+		return provider{}, fmt.Errorf("unknown provider %q, expected one of %s", providerName, strings.Join(Providers(), ", "))
+	}
+	return p, nil
+}
+
+// Fetch returns the quotes of symbols from the named provider, in the same
+// order, in currency for providers that quote in any currency. Responses are
+// cached for ttl.
+func Fetch(ctx context.Context, providerName string, symbols []string, currency string, ttl time.Duration, appID string) ([]*Quote, error) {
+	p, err := lookup(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if len(symbols) > MaxSymbols {
+		return nil, fmt.Errorf("at most %d symbols can be quoted at once, not %d", MaxSymbols, len(symbols))
+	}
+
+	// each symbol is fetched on its own, so that different lists of
+	// symbols still share cached responses
+	f := &fetch.Fetcher{TTL: ttl, AppID: appID}
+	quotes := make([]*Quote, len(symbols))
+	for i, symbol := range symbols {
+		symbol = strings.TrimSpace(symbol)
+		if symbol == "" {
+			return nil, fmt.Errorf("empty symbol")
+		}
+
+		q, err := p.quote(ctx, f, symbol, strings.ToLower(currency))
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s from %s: %w", symbol, providerName, err)
+		}
+		quotes[i] = q
+	}
+	return quotes, nil
+}
+
+// Search returns up to limit symbols of the named provider that match
+// query, best first. Responses are cached for ttl.
+func Search(ctx context.Context, providerName, query string, limit int, ttl time.Duration, appID string) ([]Match, error) {
+	p, err := lookup(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > MaxResults {
+		return nil, fmt.Errorf("limit must be from 1 to %d, not %d", MaxResults, limit)
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+
+	matches, err := p.search(ctx, &fetch.Fetcher{TTL: ttl, AppID: appID}, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("searching %s: %w", providerName, err)
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func get(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		symbolsValue starlark.Value
+		providerName = DefaultProvider
+		currency     = DefaultCurrency
+		ttl          = int(DefaultTTL.Seconds())
+	)
+
+	if err := starlark.UnpackArgs(
+		"get",
+		args, kwargs,
+		"symbols", &symbolsValue,
+		"provider?", &providerName,
+		"currency?", &currency,
+		"ttl_seconds?", &ttl,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for get: %s", err)
+	}
+
+	symbols, err := stringsOf("symbols", symbolsValue)
+	if err != nil {
+		return nil, err
+	}
+
+	appID := starlarkutil.AppID(thread)
+	quotes, err := Fetch(
+		fetch.Context(thread),
+		providerName, symbols, currency,
+		time.Duration(ttl)*time.Second,
+		appID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+
+	values := make([]starlark.Value, len(quotes))
+	for i, q := range quotes {
+		values[i] = quoteValue(q, providerName)
+	}
+	return starlark.NewList(values), nil
+}
+
+func search(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		query        string
+		providerName = DefaultProvider
+		limit        = 10
+		ttl          = int(DefaultSearchTTL.Seconds())
+	)
+
+	if err := starlark.UnpackArgs(
+		"search",
+		args, kwargs,
+		"query", &query,
+		"provider?", &providerName,
+		"limit?", &limit,
+		"ttl_seconds?", &ttl,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for search: %s", err)
+	}
+
+	appID := starlarkutil.AppID(thread)
+	matches, err := Search(
+		fetch.Context(thread),
+		providerName, query, limit,
+		time.Duration(ttl)*time.Second,
+		appID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+
+	values := make([]starlark.Value, len(matches))
+	for i, m := range matches {
+		display := m.Symbol
+		if m.Name != "" {
+			display += " - " + m.Name
+		}
+		values[i] = starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"symbol":   starlark.String(m.Symbol),
+			"name":     starlark.String(m.Name),
+			"exchange": starlark.String(m.Exchange),
+			"type":     starlark.String(m.Type),
+			"display":  starlark.String(display),
+		})
+	}
+	return starlark.NewList(values), nil
+}
+
+func listProviders(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs("providers", args, kwargs); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for providers: %s", err)
+	}
+
+	var names []starlark.Value
+	for _, name := range Providers() {
+		names = append(names, starlark.String(name))
+	}
+	return starlark.NewList(names), nil
+}
+
+func quoteValue(q *Quote, providerName string) starlark.Value {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"provider":       starlark.String(providerName),
+		"symbol":         starlark.String(q.Symbol),
+		"name":           starlark.String(q.Name),
+		"price":          starlark.Float(q.Price),
+		"previous_close": number(q.PreviousClose),
+		"change":         number(q.Change()),
+		"change_percent": number(q.ChangePercent()),
+		"currency":       starlark.String(q.Currency),
+		"exchange":       starlark.String(q.Exchange),
+		"time":           startime.Time(q.Time),
+	})
+}
+
+// stringsOf unpacks an argument that's a string or a list of them.
+func stringsOf(name string, v starlark.Value) ([]string, error) {
+	if s, ok := starlark.AsString(v); ok {
+		return []string{s}, nil
+	}
+
+	iterable, ok := v.(starlark.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("get: %s must be a string or a list of strings, not %s", name, v.Type())
+	}
+	var ss []string
+	iter := iterable.Iterate()
+	defer iter.Done()
+	var x starlark.Value
+	for iter.Next(&x) {
+		s, ok := starlark.AsString(x)
+		if !ok {
+			return nil, fmt.Errorf("get: %s must be a string or a list of strings, not a list of %s", name, x.Type())
+		}
+		ss = append(ss, s)
+	}
+	return ss, nil
+}
+
+// number returns v, or None if it isn't known.
+func number(v float64) starlark.Value {
+	if math.IsNaN(v) {
+		return starlark.None
+	}
+	return starlark.Float(v)
+}
+
+// orNaN returns *v, or NaN if it's nil, for values that services report as
+// null when they aren't known.
+func orNaN(v *float64) float64 {
+	if v == nil {
+		return math.NaN()
+	}
+	return *v
+}
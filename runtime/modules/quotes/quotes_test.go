@@ -0,0 +1,198 @@
+package quotes
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch/fetchtest"
+)
+
+const yahooChartJSON = `{"chart": {"result": [{"meta": {
+	"currency": "USD",
+	"symbol": "AAPL",
+	"fullExchangeName": "NasdaqGS",
+	"regularMarketPrice": 231.3,
+	"regularMarketTime": 1792252800,
+	"chartPreviousClose": 225.0,
+	"longName": "Apple Inc.",
+	"shortName": "Apple Inc."
+}}], "error": null}}`
+
+const yahooSearchJSON = `{"quotes": [
+	{"symbol": "AAPL", "shortname": "Apple Inc.", "longname": "Apple Inc.", "exchDisp": "NASDAQ", "quoteType": "EQUITY"},
+	{"symbol": "APLE", "shortname": "Apple Hospitality REIT, Inc.", "exchDisp": "NYSE", "quoteType": "EQUITY"},
+	{"index": "urn:news", "shortname": "not a symbol"}
+]}`
+
+const coinGeckoPriceJSON = `{"bitcoin": {"eur": 60000, "eur_24h_change": 20, "last_updated_at": 1792252800}}`
+
+const coinGeckoSearchJSON = `{"coins": [
+	{"id": "bitcoin", "name": "Bitcoin", "symbol": "BTC"},
+	{"id": "bitcoin-cash", "name": "Bitcoin Cash", "symbol": "BCH"},
+	{"id": "wrapped-bitcoin", "name": "Wrapped Bitcoin", "symbol": "WBTC"}
+]}`
+
+// serve points every provider at a server that responds with the given
+// bodies by path, and records the requests it gets.
+func serve(t *testing.T, bodies map[string]string) *[]*http.Request {
+	return fetchtest.Serve(t, bodies, map[*string]string{
+		&yahooChartURL:  "/chart/",
+		&yahooSearchURL: "/search",
+		&coinGeckoURL:   "/coingecko",
+	})
+}
+
+func TestYahoo(t *testing.T) {
+	requests := serve(t, map[string]string{"/chart/AAPL": yahooChartJSON})
+
+	qs, err := Fetch(context.Background(), "yahoo", []string{" aapl"}, DefaultCurrency, DefaultTTL, "ticker")
+	require.NoError(t, err)
+
+	// cached for the app
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	assert.Equal(t, "ticker", req.Header.Get("X-Tidbyt-App"))
+	assert.Equal(t, "60", req.Header.Get("X-Tidbyt-Cache-Seconds"))
+
+	require.Len(t, qs, 1)
+	q := qs[0]
+	assert.Equal(t, "AAPL", q.Symbol)
+	assert.Equal(t, "Apple Inc.", q.Name)
+	assert.Equal(t, 231.3, q.Price)
+	assert.Equal(t, "USD", q.Currency)
+	assert.Equal(t, "NasdaqGS", q.Exchange)
+	assert.Equal(t, time.Date(2026, 10, 17, 16, 0, 0, 0, time.UTC), q.Time.UTC())
+	assert.InDelta(t, 6.3, q.Change(), 0.001)
+	assert.InDelta(t, 2.8, q.ChangePercent(), 0.001)
+
+	_, err = Fetch(context.Background(), "yahoo", []string{"AAPL", "NOPE"}, DefaultCurrency, DefaultTTL, "ticker")
+	assert.ErrorContains(t, err, "fetching NOPE from yahoo: 404")
+}
+
+func TestYahooSearch(t *testing.T) {
+	requests := serve(t, map[string]string{"/search": yahooSearchJSON})
+
+	matches, err := Search(context.Background(), "yahoo", "apple", 5, DefaultSearchTTL, "ticker")
+	require.NoError(t, err)
+
+	assert.Equal(t, "apple", (*requests)[0].URL.Query().Get("q"))
+	assert.Equal(t, "5", (*requests)[0].URL.Query().Get("quotesCount"))
+	assert.Equal(t, []Match{
+		{Symbol: "AAPL", Name: "Apple Inc.", Exchange: "NASDAQ", Type: "equity"},
+		{Symbol: "APLE", Name: "Apple Hospitality REIT, Inc.", Exchange: "NYSE", Type: "equity"},
+	}, matches)
+
+	// nothing to search for yet
+	matches, err = Search(context.Background(), "yahoo", " ", 5, DefaultSearchTTL, "ticker")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.Len(t, *requests, 1)
+}
+
+func TestCoinGecko(t *testing.T) {
+	requests := serve(t, map[string]string{
+		"/coingecko/simple/price": coinGeckoPriceJSON,
+		"/coingecko/search":       coinGeckoSearchJSON,
+	})
+
+	qs, err := Fetch(context.Background(), "coingecko", []string{"Bitcoin"}, "EUR", DefaultTTL, "ticker")
+	require.NoError(t, err)
+
+	query := (*requests)[0].URL.Query()
+	assert.Equal(t, "bitcoin", query.Get("ids"))
+	assert.Equal(t, "eur", query.Get("vs_currencies"))
+
+	q := qs[0]
+	assert.Equal(t, "bitcoin", q.Symbol)
+	assert.Equal(t, 60000.0, q.Price)
+	assert.Equal(t, "EUR", q.Currency)
+	assert.InDelta(t, 50000, q.PreviousClose, 0.001)
+	assert.InDelta(t, 20, q.ChangePercent(), 0.001)
+	assert.Equal(t, time.Date(2026, 10, 17, 16, 0, 0, 0, time.UTC), q.Time.UTC())
+
+	// coins it doesn't have a price of
+	_, err = Fetch(context.Background(), "coingecko", []string{"bitcoin"}, "xyz", DefaultTTL, "ticker")
+	assert.ErrorContains(t, err, "no quote for bitcoin in xyz")
+
+	matches, err := Search(context.Background(), "coingecko", "bitcoin", 2, DefaultSearchTTL, "ticker")
+	require.NoError(t, err)
+	assert.Equal(t, []Match{
+		{Symbol: "bitcoin", Name: "Bitcoin (BTC)", Type: "crypto"},
+		{Symbol: "bitcoin-cash", Name: "Bitcoin Cash (BCH)", Type: "crypto"},
+	}, matches)
+}
+
+func TestFetchInvalid(t *testing.T) {
+	_, err := Fetch(context.Background(), "nasdaq", []string{"AAPL"}, DefaultCurrency, DefaultTTL, "ticker")
+	assert.ErrorContains(t, err, "coingecko, yahoo")
+
+	_, err = Fetch(context.Background(), "yahoo", make([]string, MaxSymbols+1), DefaultCurrency, DefaultTTL, "ticker")
+	assert.Error(t, err)
+
+	_, err = Fetch(context.Background(), "yahoo", []string{""}, DefaultCurrency, DefaultTTL, "ticker")
+	assert.ErrorContains(t, err, "empty symbol")
+
+	_, err = Search(context.Background(), "yahoo", "apple", MaxResults+1, DefaultSearchTTL, "ticker")
+	assert.Error(t, err)
+
+	assert.True(t, math.IsNaN((&Quote{Price: 1, PreviousClose: math.NaN()}).ChangePercent()))
+}
+
+func TestQuotesModule(t *testing.T) {
+	requests := serve(t, map[string]string{
+		"/chart/AAPL": yahooChartJSON,
+		"/search":     yahooSearchJSON,
+	})
+
+	src := `
+load("quotes.star", "quotes")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+assert(quotes.providers() == ["coingecko", "yahoo"])
+
+aapl = quotes.get("AAPL")[0]
+assert(aapl.symbol == "AAPL")
+assert(aapl.provider == "yahoo")
+assert(aapl.price == 231.3)
+assert(aapl.previous_close == 225.0)
+assert(aapl.currency == "USD")
+assert(aapl.time.unix == 1792252800)
+
+assert(len(quotes.get(["AAPL", "AAPL"])) == 2)
+
+matches = quotes.search("apple")
+assert(len(matches) == 2)
+assert(matches[0].symbol == "AAPL")
+assert(matches[0].display == "AAPL - Apple Inc.")
+assert(matches[1].exchange == "NYSE")
+`
+	thread := &starlark.Thread{
+		Name: "ticker/ada",
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return LoadModule()
+		},
+	}
+	_, err := starlark.ExecFile(thread, "ticker.star", src, nil)
+	require.NoError(t, err)
+
+	// responses are cached for the app
+	for _, req := range *requests {
+		assert.Equal(t, "ticker", req.Header.Get("X-Tidbyt-App"))
+	}
+
+	_, err = starlark.ExecFile(thread, "ticker.star", `
+load("quotes.star", "quotes")
+quotes.get(1)
+`, nil)
+	assert.ErrorContains(t, err, "symbols must be a string or a list of strings")
+}
@@ -0,0 +1,118 @@
+package quotes
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+)
+
+var (
+	yahooChartURL  = "https://query1.finance.yahoo.com/v8/finance/chart/"
+	yahooSearchURL = "https://query2.finance.yahoo.com/v1/finance/search"
+)
+
+var yahoo = provider{
+	quote:  yahooQuote,
+	search: yahooSearch,
+}
+
+type yahooChartResponse struct {
+	Chart struct {
+		Result []struct {
+			Meta struct {
+				Symbol             string   `json:"symbol"`
+				LongName           string   `json:"longName"`
+				ShortName          string   `json:"shortName"`
+				Currency           string   `json:"currency"`
+				FullExchangeName   string   `json:"fullExchangeName"`
+				RegularMarketPrice *float64 `json:"regularMarketPrice"`
+				RegularMarketTime  int64    `json:"regularMarketTime"`
+				ChartPreviousClose *float64 `json:"chartPreviousClose"`
+				PreviousClose      *float64 `json:"previousClose"`
+			} `json:"meta"`
+		} `json:"result"`
+	} `json:"chart"`
+}
+
+// yahooQuote fetches from Yahoo Finance's charts, which cover stocks, funds,
+// indices, currencies and cryptocurrencies on most exchanges. Symbols on
+// exchanges outside the United States have a suffix, such as "VOD.L", and
+// prices are in the currency the symbol is traded in.
+func yahooQuote(ctx context.Context, f *fetch.Fetcher, symbol, _ string) (*Quote, error) {
+	q := url.Values{}
+	q.Set("range", "1d")
+	q.Set("interval", "1d")
+
+	var resp yahooChartResponse
+	if err := f.Get(ctx, yahooChartURL+url.PathEscape(strings.ToUpper(symbol))+"?"+q.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Chart.Result) == 0 || resp.Chart.Result[0].Meta.RegularMarketPrice == nil {
+		return nil, fmt.Errorf("no quote for %s", symbol)
+	}
+
+	m := resp.Chart.Result[0].Meta
+	previousClose := m.PreviousClose
+	if previousClose == nil {
+		previousClose = m.ChartPreviousClose
+	}
+	name := m.LongName
+	if name == "" {
+		name = m.ShortName
+	}
+
+	return &Quote{
+		Symbol:        m.Symbol,
+		Name:          name,
+		Price:         *m.RegularMarketPrice,
+		PreviousClose: orNaN(previousClose),
+		Currency:      strings.ToUpper(m.Currency),
+		Exchange:      m.FullExchangeName,
+		Time:          time.Unix(m.RegularMarketTime, 0),
+	}, nil
+}
+
+type yahooSearchResponse struct {
+	Quotes []struct {
+		Symbol    string `json:"symbol"`
+		LongName  string `json:"longname"`
+		ShortName string `json:"shortname"`
+		Exchange  string `json:"exchDisp"`
+		QuoteType string `json:"quoteType"`
+	} `json:"quotes"`
+}
+
+func yahooSearch(ctx context.Context, f *fetch.Fetcher, query string, limit int) ([]Match, error) {
+	q := url.Values{}
+	q.Set("q", query)
+	q.Set("quotesCount", strconv.Itoa(limit))
+	q.Set("newsCount", "0")
+
+	var resp yahooSearchResponse
+	if err := f.Get(ctx, yahooSearchURL+"?"+q.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, r := range resp.Quotes {
+		if r.Symbol == "" {
+			continue
+		}
+		name := r.LongName
+		if name == "" {
+			name = r.ShortName
+		}
+		matches = append(matches, Match{
+			Symbol:   r.Symbol,
+			Name:     name,
+			Exchange: r.Exchange,
+			Type:     strings.ToLower(r.QuoteType),
+		})
+	}
+	return matches, nil
+}
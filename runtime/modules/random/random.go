@@ -22,7 +22,13 @@ var (
 )
 
 func AttachToThread(t *starlark.Thread) {
-	nowSeconds := time.Now().UnixMilli() / 1000
+	AttachToThreadAt(t, time.Now())
+}
+
+// AttachToThreadAt seeds the thread's generator as AttachToThread does, as
+// if it were now.
+func AttachToThreadAt(t *starlark.Thread, now time.Time) {
+	nowSeconds := now.UnixMilli() / 1000
 
 	t.SetLocal(
 		threadRandKey,
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"image/color"
 	"math"
+	"time"
 
+	startime "go.starlark.net/lib/time"
 	"go.starlark.net/starlark"
 	"tidbyt.dev/pixlet/render"
 )
@@ -43,6 +45,49 @@ func DataPointFromStarlark(value starlark.Value) ([2]float64, error) {
 	return result, nil
 }
 
+// StringsFromStarlark converts a list or tuple of strings, such as the names
+// of fonts. None is the same as an empty list.
+func StringsFromStarlark(name string, value starlark.Value) ([]string, error) {
+	if value == starlark.None {
+		return nil, nil
+	}
+
+	iterable, ok := value.(starlark.Indexable)
+	if !ok {
+		return nil, fmt.Errorf("expected %s to be a list of str but found: %s", name, value.Type())
+	}
+
+	result := make([]string, 0, iterable.Len())
+	for i := 0; i < iterable.Len(); i++ {
+		s, ok := starlark.AsString(iterable.Index(i))
+		if !ok {
+			return nil, fmt.Errorf("expected %s to be a list of str but found: %s (at index %d)", name, iterable.Index(i).Type(), i)
+		}
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+// TimeFromStarlark converts a time.time. When it's not given, it's the
+// current time as time.now() in thread sees it, which is the render time
+// if the host set one.
+func TimeFromStarlark(thread *starlark.Thread, name string, value starlark.Value) (time.Time, error) {
+	if value == nil || value == starlark.None {
+		if now := startime.Now(thread); now != nil {
+			return now()
+		}
+		return startime.NowFunc(), nil
+	}
+
+	t, ok := value.(startime.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected %s to be a time.time but found: %s", name, value.Type())
+	}
+
+	return time.Time(t), nil
+}
+
 func DataSeriesFromStarlark(list *starlark.List) ([][2]float64, error) {
 	result := make([][2]float64, 0)
 
@@ -33,7 +33,8 @@ func LoadRenderModule() (starlark.StringDict, error) {
 			"render": &starlarkstruct.Module{
 				Name: "render",
 				Members: starlark.StringDict{
-					"fonts": fnt,
+					"fonts":        fnt,
+					"measure_text": starlark.NewBuiltin("measure_text", measureText),
 
 					"Animation": starlark.NewBuiltin("Animation", newAnimation),
 
@@ -43,6 +44,10 @@ func LoadRenderModule() (starlark.StringDict, error) {
 
 					"Column": starlark.NewBuiltin("Column", newColumn),
 
+					"Countdown": starlark.NewBuiltin("Countdown", newCountdown),
+
+					"FitText": starlark.NewBuiltin("FitText", newFitText),
+
 					"Image": starlark.NewBuiltin("Image", newImage),
 
 					"Marquee": starlark.NewBuiltin("Marquee", newMarquee),
@@ -567,6 +572,301 @@ func columnFrameCount(
 	return starlark.MakeInt(count), nil
 }
 
+type Countdown struct {
+	Widget
+
+	render.Countdown
+
+	starlarkTargetTime starlark.Value
+
+	starlarkNow starlark.Value
+
+	starlarkColor starlark.String
+
+	frame_count *starlark.Builtin
+}
+
+func newCountdown(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+
+	var (
+		target_time starlark.Value
+		now         starlark.Value
+		delay       starlark.Int
+		duration    starlark.Int
+		font        starlark.String
+		color       starlark.String
+	)
+
+	if err := starlark.UnpackArgs(
+		"Countdown",
+		args, kwargs,
+		"target_time", &target_time,
+		"now?", &now,
+		"delay?", &delay,
+		"duration?", &duration,
+		"font?", &font,
+		"color?", &color,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for Countdown: %s", err)
+	}
+
+	w := &Countdown{}
+
+	w.starlarkTargetTime = target_time
+	if val, err := TimeFromStarlark(thread, "target_time", target_time); err == nil {
+		w.TargetTime = val
+	} else {
+		return nil, err
+	}
+
+	w.starlarkNow = now
+	if val, err := TimeFromStarlark(thread, "now", now); err == nil {
+		w.Now = val
+	} else {
+		return nil, err
+	}
+
+	w.Delay = int(delay.BigInt().Int64())
+
+	w.Duration = int(duration.BigInt().Int64())
+
+	w.Font = font.GoString()
+
+	w.starlarkColor = color
+	if color.Len() > 0 {
+		c, err := render.ParseColor(color.GoString())
+		if err != nil {
+			return nil, fmt.Errorf("color is not a valid hex string: %s", color.String())
+		}
+		w.Color = c
+	}
+
+	w.frame_count = starlark.NewBuiltin("frame_count", countdownFrameCount)
+
+	if err := w.Init(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *Countdown) AsRenderWidget() render.Widget {
+	return &w.Countdown
+}
+
+func (w *Countdown) AttrNames() []string {
+	return []string{
+		"target_time", "now", "delay", "duration", "font", "color",
+	}
+}
+
+func (w *Countdown) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "target_time":
+
+		return w.starlarkTargetTime, nil
+
+	case "now":
+
+		return w.starlarkNow, nil
+
+	case "delay":
+
+		return starlark.MakeInt(int(w.Delay)), nil
+
+	case "duration":
+
+		return starlark.MakeInt(int(w.Duration)), nil
+
+	case "font":
+
+		return starlark.String(w.Font), nil
+
+	case "color":
+
+		return w.starlarkColor, nil
+
+	case "frame_count":
+		return w.frame_count.BindReceiver(w), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (w *Countdown) String() string       { return "Countdown(...)" }
+func (w *Countdown) Type() string         { return "Countdown" }
+func (w *Countdown) Freeze()              {}
+func (w *Countdown) Truth() starlark.Bool { return true }
+
+func (w *Countdown) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(w, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
+
+func countdownFrameCount(
+	thread *starlark.Thread,
+	b *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+
+	w := b.Receiver().(*Countdown)
+	count := w.FrameCount()
+
+	return starlark.MakeInt(count), nil
+}
+
+type FitText struct {
+	Widget
+
+	render.FitText
+
+	starlarkFonts starlark.Value
+
+	starlarkColor starlark.String
+
+	frame_count *starlark.Builtin
+}
+
+func newFitText(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+
+	var (
+		content starlark.String
+		width   starlark.Int
+		height  starlark.Int
+		fonts   starlark.Value
+		color   starlark.String
+		marquee starlark.Bool
+	)
+
+	if err := starlark.UnpackArgs(
+		"FitText",
+		args, kwargs,
+		"content", &content,
+		"width", &width,
+		"height?", &height,
+		"fonts?", &fonts,
+		"color?", &color,
+		"marquee?", &marquee,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for FitText: %s", err)
+	}
+
+	w := &FitText{}
+
+	w.Content = content.GoString()
+
+	w.Width = int(width.BigInt().Int64())
+
+	w.Height = int(height.BigInt().Int64())
+
+	w.starlarkFonts = fonts
+	if fonts != nil {
+		if val, err := StringsFromStarlark("fonts", fonts); err == nil {
+			w.Fonts = val
+		} else {
+			return nil, err
+		}
+	}
+
+	w.starlarkColor = color
+	if color.Len() > 0 {
+		c, err := render.ParseColor(color.GoString())
+		if err != nil {
+			return nil, fmt.Errorf("color is not a valid hex string: %s", color.String())
+		}
+		w.Color = c
+	}
+
+	w.Marquee = bool(marquee)
+
+	w.frame_count = starlark.NewBuiltin("frame_count", fittextFrameCount)
+
+	if err := w.Init(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *FitText) AsRenderWidget() render.Widget {
+	return &w.FitText
+}
+
+func (w *FitText) AttrNames() []string {
+	return []string{
+		"content", "width", "height", "fonts", "color", "marquee",
+	}
+}
+
+func (w *FitText) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "content":
+
+		return starlark.String(w.Content), nil
+
+	case "width":
+
+		return starlark.MakeInt(int(w.Width)), nil
+
+	case "height":
+
+		return starlark.MakeInt(int(w.Height)), nil
+
+	case "fonts":
+
+		return w.starlarkFonts, nil
+
+	case "color":
+
+		return w.starlarkColor, nil
+
+	case "marquee":
+
+		return starlark.Bool(w.Marquee), nil
+
+	case "frame_count":
+		return w.frame_count.BindReceiver(w), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (w *FitText) String() string       { return "FitText(...)" }
+func (w *FitText) Type() string         { return "FitText" }
+func (w *FitText) Freeze()              {}
+func (w *FitText) Truth() starlark.Bool { return true }
+
+func (w *FitText) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(w, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
+
+func fittextFrameCount(
+	thread *starlark.Thread,
+	b *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+
+	w := b.Receiver().(*FitText)
+	count := w.FrameCount()
+
+	return starlark.MakeInt(count), nil
+}
+
 type Image struct {
 	Widget
 
@@ -1358,6 +1658,9 @@ func newRoot(
 		delay               starlark.Int
 		max_age             starlark.Int
 		show_full_animation starlark.Bool
+		show_for            starlark.Int
+		refresh_in          starlark.Int
+		separate            starlark.Bool
 	)
 
 	if err := starlark.UnpackArgs(
@@ -1367,6 +1670,9 @@ func newRoot(
 		"delay?", &delay,
 		"max_age?", &max_age,
 		"show_full_animation?", &show_full_animation,
+		"show_for?", &show_for,
+		"refresh_in?", &refresh_in,
+		"separate?", &separate,
 	); err != nil {
 		return nil, fmt.Errorf("unpacking arguments for Root: %s", err)
 	}
@@ -1399,6 +1705,20 @@ func newRoot(
 
 	w.ShowFullAnimation = bool(show_full_animation)
 
+	if val, err := starlark.AsInt32(show_for); err == nil {
+		w.ShowFor = int32(val)
+	} else {
+		return nil, err
+	}
+
+	if val, err := starlark.AsInt32(refresh_in); err == nil {
+		w.RefreshIn = int32(val)
+	} else {
+		return nil, err
+	}
+
+	w.Separate = bool(separate)
+
 	return w, nil
 }
 
@@ -1408,7 +1728,7 @@ func (w *Root) AsRenderRoot() render.Root {
 
 func (w *Root) AttrNames() []string {
 	return []string{
-		"child", "delay", "max_age", "show_full_animation",
+		"child", "delay", "max_age", "show_full_animation", "show_for", "refresh_in", "separate",
 	}
 }
 
@@ -1431,6 +1751,18 @@ func (w *Root) Attr(name string) (starlark.Value, error) {
 
 		return starlark.Bool(w.ShowFullAnimation), nil
 
+	case "show_for":
+
+		return starlark.MakeInt(int(w.ShowFor)), nil
+
+	case "refresh_in":
+
+		return starlark.MakeInt(int(w.RefreshIn)), nil
+
+	case "separate":
+
+		return starlark.Bool(w.Separate), nil
+
 	default:
 		return nil, nil
 	}
@@ -0,0 +1,44 @@
+package render_runtime
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/render"
+)
+
+// measureText returns the width and height in pixels of a Text widget with
+// the given content and font, without laying anything out, so that apps can
+// tell whether a string fits before picking how to show it.
+func measureText(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+
+	var (
+		content starlark.String
+		font    starlark.String
+	)
+
+	if err := starlark.UnpackArgs(
+		"measure_text",
+		args, kwargs,
+		"content", &content,
+		"font?", &font,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for measure_text: %s", err)
+	}
+
+	t := &render.Text{Content: content.GoString(), Font: font.GoString()}
+	if err := t.Init(); err != nil {
+		return nil, err
+	}
+	width, height := t.Size()
+
+	return starlark.Tuple([]starlark.Value{
+		starlark.MakeInt(width),
+		starlark.MakeInt(height),
+	}), nil
+}
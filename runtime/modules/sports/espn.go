@@ -0,0 +1,161 @@
+package sports
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+)
+
+var espnURL = "https://site.api.espn.com/apis/site/v2/sports"
+
+// espnLeagues are the paths of the leagues on ESPN's API.
+var espnLeagues = map[string]string{
+	"nfl":        "football/nfl",
+	"ncaaf":      "football/college-football",
+	"nba":        "basketball/nba",
+	"wnba":       "basketball/wnba",
+	"ncaam":      "basketball/mens-college-basketball",
+	"ncaaw":      "basketball/womens-college-basketball",
+	"mlb":        "baseball/mlb",
+	"nhl":        "hockey/nhl",
+	"mls":        "soccer/usa.1",
+	"epl":        "soccer/eng.1",
+	"laliga":     "soccer/esp.1",
+	"bundesliga": "soccer/ger.1",
+	"seriea":     "soccer/ita.1",
+	"ligue1":     "soccer/fra.1",
+	"ucl":        "soccer/uefa.champions",
+}
+
+type espnScoreboard struct {
+	Events []struct {
+		ID           string `json:"id"`
+		Date         string `json:"date"`
+		Competitions []struct {
+			Venue struct {
+				FullName string `json:"fullName"`
+			} `json:"venue"`
+			Competitors []struct {
+				HomeAway string `json:"homeAway"`
+				Winner   bool   `json:"winner"`
+				Score    string `json:"score"`
+				Team     struct {
+					ID               string `json:"id"`
+					Abbreviation     string `json:"abbreviation"`
+					DisplayName      string `json:"displayName"`
+					ShortDisplayName string `json:"shortDisplayName"`
+					Color            string `json:"color"`
+					Logo             string `json:"logo"`
+				} `json:"team"`
+			} `json:"competitors"`
+		} `json:"competitions"`
+		Status struct {
+			Type struct {
+				Name        string `json:"name"`
+				State       string `json:"state"`
+				ShortDetail string `json:"shortDetail"`
+			} `json:"type"`
+		} `json:"status"`
+	} `json:"events"`
+}
+
+// espn fetches from ESPN's scoreboards, which cover the major North
+// American leagues and European soccer. It's the API most scoreboard apps
+// already use, though ESPN doesn't document it.
+var espn = provider{
+	leagues: slices.Collect(maps.Keys(espnLeagues)),
+	fetch: func(ctx context.Context, f *fetch.Fetcher, league, date string) ([]Fixture, error) {
+		url := fmt.Sprintf("%s/%s/scoreboard", espnURL, espnLeagues[league])
+		if date != "" {
+			url += "?dates=" + strings.ReplaceAll(date, "-", "")
+		}
+
+		var resp espnScoreboard
+		if err := f.Get(ctx, url, &resp); err != nil {
+			return nil, err
+		}
+
+		var fixtures []Fixture
+		for _, e := range resp.Events {
+			if len(e.Competitions) == 0 {
+				continue
+			}
+			c := e.Competitions[0]
+
+			start, err := parseESPNTime(e.Date)
+			if err != nil {
+				return nil, err
+			}
+			status := espnStatus(e.Status.Type.Name, e.Status.Type.State)
+			fixture := Fixture{
+				ID:     e.ID,
+				Start:  start,
+				Status: status,
+				Detail: e.Status.Type.ShortDetail,
+				Venue:  c.Venue.FullName,
+			}
+
+			for _, comp := range c.Competitors {
+				side := Competitor{
+					Team: Team{
+						ID:           comp.Team.ID,
+						Abbreviation: comp.Team.Abbreviation,
+						Name:         comp.Team.DisplayName,
+						ShortName:    comp.Team.ShortDisplayName,
+						LogoURL:      comp.Team.Logo,
+					},
+					Winner: comp.Winner,
+				}
+				if comp.Team.Color != "" {
+					side.Team.Color = "#" + comp.Team.Color
+				}
+				// scores are "0" before games start
+				if score, err := strconv.Atoi(comp.Score); err == nil && e.Status.Type.State != "pre" {
+					side.Score = &score
+				}
+
+				if comp.HomeAway == "home" {
+					fixture.Home = side
+				} else {
+					fixture.Away = side
+				}
+			}
+			fixtures = append(fixtures, fixture)
+		}
+		return fixtures, nil
+	},
+}
+
+// parseESPNTime parses ESPN's times, which usually leave out seconds.
+func parseESPNTime(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04Z07:00", time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func espnStatus(name, state string) Status {
+	switch name {
+	case "STATUS_POSTPONED":
+		return Postponed
+	case "STATUS_CANCELED", "STATUS_CANCELLED":
+		return Canceled
+	}
+
+	switch state {
+	case "in":
+		return InProgress
+	case "post":
+		return Final
+	default:
+		return Scheduled
+	}
+}
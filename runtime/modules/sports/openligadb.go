@@ -0,0 +1,128 @@
+package sports
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+)
+
+var openLigaDBURL = "https://api.openligadb.de"
+
+// now returns the current time, which tells matches that are under way
+// from those yet to start.
+var now = time.Now
+
+type openLigaDBTeam struct {
+	TeamID      int    `json:"teamId"`
+	TeamName    string `json:"teamName"`
+	ShortName   string `json:"shortName"`
+	TeamIconURL string `json:"teamIconUrl"`
+}
+
+type openLigaDBMatch struct {
+	MatchID          int            `json:"matchID"`
+	MatchDateTimeUTC time.Time      `json:"matchDateTimeUTC"`
+	Team1            openLigaDBTeam `json:"team1"`
+	Team2            openLigaDBTeam `json:"team2"`
+	MatchIsFinished  bool           `json:"matchIsFinished"`
+	MatchResults     []struct {
+		ResultTypeID int `json:"resultTypeID"`
+		PointsTeam1  int `json:"pointsTeam1"`
+		PointsTeam2  int `json:"pointsTeam2"`
+	} `json:"matchResults"`
+	Goals []struct {
+		ScoreTeam1 int `json:"scoreTeam1"`
+		ScoreTeam2 int `json:"scoreTeam2"`
+	} `json:"goals"`
+	Location *struct {
+		LocationStadium string `json:"locationStadium"`
+	} `json:"location"`
+}
+
+// openLigaDBFinal is the result type of a match's final score, rather
+// than its half time score.
+const openLigaDBFinal = 2
+
+// openLigaDB fetches from OpenLigaDB, a community database of German
+// football. It has no abbreviations or colors for teams, and live scores
+// are as up to date as its volunteers keep them. Without a date, it lists
+// the current matchday.
+var openLigaDB = provider{
+	leagues: []string{"bl1", "bl2", "bl3", "dfb"},
+	fetch: func(ctx context.Context, f *fetch.Fetcher, league, date string) ([]Fixture, error) {
+		var matches []openLigaDBMatch
+		if err := f.Get(ctx, fmt.Sprintf("%s/getmatchdata/%s", openLigaDBURL, league), &matches); err != nil {
+			return nil, err
+		}
+
+		t := now()
+		var fixtures []Fixture
+		for _, m := range matches {
+			// matchdays span several days, and dates are in Germany's
+			// timezone
+			if date != "" && m.MatchDateTimeUTC.In(berlin()).Format(time.DateOnly) != date {
+				continue
+			}
+
+			fixture := Fixture{
+				ID:    strconv.Itoa(m.MatchID),
+				Start: m.MatchDateTimeUTC,
+				Home:  Competitor{Team: openLigaDBTeamOf(m.Team1)},
+				Away:  Competitor{Team: openLigaDBTeamOf(m.Team2)},
+			}
+			if m.Location != nil {
+				fixture.Venue = m.Location.LocationStadium
+			}
+
+			home, away := 0, 0
+			for _, g := range m.Goals {
+				home, away = g.ScoreTeam1, g.ScoreTeam2
+			}
+			switch {
+			case m.MatchIsFinished:
+				fixture.Status = Final
+				fixture.Detail = "FT"
+				for _, r := range m.MatchResults {
+					if r.ResultTypeID == openLigaDBFinal {
+						home, away = r.PointsTeam1, r.PointsTeam2
+					}
+				}
+				fixture.Home.Winner = home > away
+				fixture.Away.Winner = away > home
+			case t.Before(m.MatchDateTimeUTC):
+				fixture.Status = Scheduled
+			default:
+				fixture.Status = InProgress
+				fixture.Detail = "Live"
+			}
+			if fixture.Status != Scheduled {
+				fixture.Home.Score, fixture.Away.Score = &home, &away
+			}
+
+			fixtures = append(fixtures, fixture)
+		}
+		return fixtures, nil
+	},
+}
+
+func openLigaDBTeamOf(t openLigaDBTeam) Team {
+	return Team{
+		ID:        strconv.Itoa(t.TeamID),
+		Name:      t.TeamName,
+		ShortName: t.ShortName,
+		LogoURL:   t.TeamIconURL,
+	}
+}
+
+// berlin returns Germany's timezone, or UTC if the tz database isn't
+// available.
+func berlin() *time.Location {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
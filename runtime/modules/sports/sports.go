@@ -0,0 +1,344 @@
+// Package sports fetches fixtures and scores from sports data providers,
+// and reports them the same way whichever is used, so scoreboard apps don't
+// each need to scrape a league's site or parse a provider's API.
+//
+// Scores are only cached for a minute by default so that live games keep
+// up. Team logos are looked up in the sports icon pack, so apps don't need
+// to download and scale each provider's.
+package sports
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	startime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/icons"
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+const (
+	ModuleName = "sports"
+
+	// DefaultProvider is the provider used when apps don't name one.
+	DefaultProvider = "espn"
+
+	// DefaultTTL is how long responses are cached for by default, short
+	// enough for live scores.
+	DefaultTTL = time.Minute
+
+	// LogoPack is the icon pack team logos are looked up in.
+	LogoPack = "sports"
+)
+
+// Status is where a fixture is at.
+type Status string
+
+const (
+	Scheduled  Status = "scheduled"
+	InProgress Status = "in_progress"
+	Final      Status = "final"
+	Postponed  Status = "postponed"
+	Canceled   Status = "canceled"
+)
+
+// Fixture is a game or match between two teams.
+type Fixture struct {
+	ID     string
+	League string
+	Start  time.Time
+	Status Status
+
+	// Detail is the provider's short description of where the fixture is
+	// at, such as "Q3 4:12", "HT" or "Final/OT".
+	Detail string
+
+	Venue string
+
+	Home, Away Competitor
+}
+
+// Competitor is a team's side of a fixture.
+type Competitor struct {
+	Team Team
+
+	// Score is nil until the fixture starts.
+	Score *int
+
+	// Winner is set once the fixture is over, for the team that won.
+	Winner bool
+}
+
+// Team is a team, as a provider describes it.
+type Team struct {
+	ID string
+
+	// Abbreviation is the team's short code, such as "BOS", if the
+	// provider has one.
+	Abbreviation string
+
+	Name      string
+	ShortName string
+
+	// Color is the team's color, as "#rrggbb", if the provider has one.
+	Color string
+
+	// LogoURL is where the provider hosts the team's logo.
+	LogoURL string
+}
+
+// Logo returns the team's logo from the LogoPack icon pack, or nil if it
+// isn't there. Logos are named after the league and the team's abbreviation
+// in lower case, or its ID if it has none, such as sports/nba/bos.
+func (t Team) Logo(league string) []byte {
+	key := t.Abbreviation
+	if key == "" {
+		key = t.ID
+	}
+	icon := icons.Lookup(LogoPack + "/" + league + "/" + strings.ToLower(key))
+	if icon == nil {
+		return nil
+	}
+	return icon.Data
+}
+
+// Matches reports whether q is the team's ID, abbreviation or one of its
+// names, ignoring case.
+func (t Team) Matches(q string) bool {
+	for _, s := range []string{t.ID, t.Abbreviation, t.Name, t.ShortName} {
+		if s != "" && strings.EqualFold(s, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// provider fetches fixtures from a sports data provider.
+type provider struct {
+	// leagues are the leagues the provider covers, by the names apps use.
+	leagues []string
+
+	// fetch returns the fixtures of a league on a date, as YYYY-MM-DD, or
+	// the provider's current fixtures if date is empty.
+	fetch func(ctx context.Context, f *fetch.Fetcher, league, date string) ([]Fixture, error)
+}
+
+var providers = map[string]provider{
+	"espn":       espn,
+	"openligadb": openLigaDB,
+}
+
+// Providers returns the names of the supported providers, sorted.
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Leagues returns the leagues a provider covers, sorted.
+func Leagues(providerName string) ([]string, error) {
+	p, ok := providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q, expected one of %s", providerName, strings.Join(Providers(), ", "))
+	}
+	return slices.Sorted(slices.Values(p.leagues)), nil
+}
+
+var (
+	once   sync.Once
+	module starlark.StringDict
+)
+
+func LoadModule() (starlark.StringDict, error) {
+	once.Do(func() {
+		module = starlark.StringDict{
+			ModuleName: &starlarkstruct.Module{
+				Name: ModuleName,
+				Members: starlark.StringDict{
+					"fixtures":  starlark.NewBuiltin("fixtures", fixtures),
+					"leagues":   starlark.NewBuiltin("leagues", listLeagues),
+					"providers": starlark.NewBuiltin("providers", listProviders),
+					"logo":      starlark.NewBuiltin("logo", logo),
+				},
+			},
+		}
+	})
+
+	return module, nil
+}
+
+// Fetch returns the fixtures of a league from the named provider, on a
+// date, as YYYY-MM-DD, or the provider's current ones if date is empty.
+// Responses are cached for ttl.
+func Fetch(ctx context.Context, providerName, league, date string, ttl time.Duration, appID string) ([]Fixture, error) {
+	leagues, err := Leagues(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(leagues, league) {
+		return nil, fmt.Errorf("%s doesn't cover %q, expected one of %s", providerName, league, strings.Join(leagues, ", "))
+	}
+	if date != "" {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("date must be YYYY-MM-DD, not %q", date)
+		}
+	}
+
+	fixtures, err := providers[providerName].fetch(ctx, &fetch.Fetcher{TTL: ttl, AppID: appID}, league, date)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s fixtures from %s: %w", league, providerName, err)
+	}
+	for i := range fixtures {
+		fixtures[i].League = league
+	}
+	slices.SortStableFunc(fixtures, func(a, b Fixture) int {
+		return a.Start.Compare(b.Start)
+	})
+	return fixtures, nil
+}
+
+func fixtures(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		league       string
+		providerName = DefaultProvider
+		date         string
+		team         string
+		ttl          = int(DefaultTTL.Seconds())
+	)
+
+	if err := starlark.UnpackArgs(
+		"fixtures",
+		args, kwargs,
+		"league", &league,
+		"provider?", &providerName,
+		"date?", &date,
+		"team?", &team,
+		"ttl_seconds?", &ttl,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for fixtures: %s", err)
+	}
+
+	appID := starlarkutil.AppID(thread)
+	fs, err := Fetch(
+		fetch.Context(thread),
+		providerName, league, date,
+		time.Duration(ttl)*time.Second,
+		appID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+
+	values := []starlark.Value{}
+	for _, f := range fs {
+		if team != "" && !f.Home.Team.Matches(team) && !f.Away.Team.Matches(team) {
+			continue
+		}
+		values = append(values, fixtureValue(f))
+	}
+	return starlark.NewList(values), nil
+}
+
+func listLeagues(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	providerName := DefaultProvider
+	if err := starlark.UnpackArgs("leagues", args, kwargs, "provider?", &providerName); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for leagues: %s", err)
+	}
+
+	leagues, err := Leagues(providerName)
+	if err != nil {
+		return nil, fmt.Errorf("leagues: %w", err)
+	}
+	return stringList(leagues), nil
+}
+
+func listProviders(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs("providers", args, kwargs); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for providers: %s", err)
+	}
+	return stringList(Providers()), nil
+}
+
+// logo returns a team's logo from the sports icon pack, for render.Image,
+// or None if the pack doesn't have it. The team is one from a fixture, or
+// its abbreviation.
+func logo(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		league string
+		team   starlark.Value
+	)
+	if err := starlark.UnpackArgs("logo", args, kwargs, "league", &league, "team", &team); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for logo: %s", err)
+	}
+
+	var t Team
+	switch v := team.(type) {
+	case starlark.String:
+		t.Abbreviation = string(v)
+	case *starlarkstruct.Struct:
+		for attr, field := range map[string]*string{"abbreviation": &t.Abbreviation, "id": &t.ID} {
+			if s, err := v.Attr(attr); err == nil {
+				*field, _ = starlark.AsString(s)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("logo: team must be a team or an abbreviation, not %s", team.Type())
+	}
+
+	data := t.Logo(league)
+	if data == nil {
+		return starlark.None, nil
+	}
+	return starlark.String(data), nil
+}
+
+func stringList(ss []string) *starlark.List {
+	values := make([]starlark.Value, len(ss))
+	for i, s := range ss {
+		values[i] = starlark.String(s)
+	}
+	return starlark.NewList(values)
+}
+
+func fixtureValue(f Fixture) starlark.Value {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"id":     starlark.String(f.ID),
+		"league": starlark.String(f.League),
+		"start":  startime.Time(f.Start),
+		"status": starlark.String(f.Status),
+		"detail": starlark.String(f.Detail),
+		"venue":  starlark.String(f.Venue),
+		"home":   competitorValue(f.Home),
+		"away":   competitorValue(f.Away),
+	})
+}
+
+func competitorValue(c Competitor) starlark.Value {
+	var score starlark.Value = starlark.None
+	if c.Score != nil {
+		score = starlark.MakeInt(*c.Score)
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"team": starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"id":           starlark.String(c.Team.ID),
+			"abbreviation": starlark.String(c.Team.Abbreviation),
+			"name":         starlark.String(c.Team.Name),
+			"short_name":   starlark.String(c.Team.ShortName),
+			"color":        starlark.String(c.Team.Color),
+			"logo_url":     starlark.String(c.Team.LogoURL),
+		}),
+		"score":  score,
+		"winner": starlark.Bool(c.Winner),
+	})
+}
@@ -0,0 +1,224 @@
+package sports
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/icons"
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch/fetchtest"
+)
+
+const espnJSON = `{"events": [
+	{
+		"id": "401",
+		"date": "2026-10-17T23:30Z",
+		"competitions": [{
+			"venue": {"fullName": "TD Garden"},
+			"competitors": [
+				{"homeAway": "home", "score": "88", "team": {"id": "2", "abbreviation": "BOS", "displayName": "Boston Celtics", "shortDisplayName": "Celtics", "color": "008348", "logo": "https://a.espncdn.com/bos.png"}},
+				{"homeAway": "away", "score": "91", "team": {"id": "18", "abbreviation": "NY", "displayName": "New York Knicks", "shortDisplayName": "Knicks", "color": "1d428a"}}
+			]
+		}],
+		"status": {"type": {"name": "STATUS_IN_PROGRESS", "state": "in", "shortDetail": "Q4 2:31"}}
+	},
+	{
+		"id": "400",
+		"date": "2026-10-17T19:00Z",
+		"competitions": [{
+			"competitors": [
+				{"homeAway": "home", "score": "101", "winner": true, "team": {"id": "13", "abbreviation": "LAL", "displayName": "Los Angeles Lakers"}},
+				{"homeAway": "away", "score": "99", "team": {"id": "9", "abbreviation": "GS", "displayName": "Golden State Warriors"}}
+			]
+		}],
+		"status": {"type": {"name": "STATUS_FINAL", "state": "post", "shortDetail": "Final/OT"}}
+	},
+	{
+		"id": "402",
+		"date": "2026-10-18T02:00Z",
+		"competitions": [{
+			"competitors": [
+				{"homeAway": "home", "score": "0", "team": {"id": "25", "abbreviation": "OKC", "displayName": "Oklahoma City Thunder"}},
+				{"homeAway": "away", "score": "0", "team": {"id": "7", "abbreviation": "DEN", "displayName": "Denver Nuggets"}}
+			]
+		}],
+		"status": {"type": {"name": "STATUS_POSTPONED", "state": "pre", "shortDetail": "Postponed"}}
+	}
+]}`
+
+const openLigaDBJSON = `[
+	{
+		"matchID": 72001,
+		"matchDateTimeUTC": "2026-10-17T13:30:00Z",
+		"team1": {"teamId": 40, "teamName": "FC Bayern München", "shortName": "Bayern"},
+		"team2": {"teamId": 7, "teamName": "Borussia Dortmund", "shortName": "Dortmund"},
+		"matchIsFinished": true,
+		"matchResults": [
+			{"resultTypeID": 2, "pointsTeam1": 2, "pointsTeam2": 2},
+			{"resultTypeID": 1, "pointsTeam1": 1, "pointsTeam2": 0}
+		],
+		"location": {"locationStadium": "Allianz Arena"}
+	},
+	{
+		"matchID": 72002,
+		"matchDateTimeUTC": "2026-10-17T16:30:00Z",
+		"team1": {"teamId": 1635, "teamName": "RB Leipzig", "shortName": "Leipzig"},
+		"team2": {"teamId": 6, "teamName": "Bayer 04 Leverkusen", "shortName": "Leverkusen"},
+		"matchIsFinished": false,
+		"goals": [{"scoreTeam1": 0, "scoreTeam2": 1}]
+	},
+	{
+		"matchID": 72003,
+		"matchDateTimeUTC": "2026-10-18T15:30:00Z",
+		"team1": {"teamId": 87, "teamName": "Borussia Mönchengladbach", "shortName": "Gladbach"},
+		"team2": {"teamId": 9, "teamName": "FC Schalke 04", "shortName": "Schalke"},
+		"matchIsFinished": false
+	}
+]`
+
+// serve points every provider at a server that responds with the given
+// bodies by path, and records the requests it gets.
+func serve(t *testing.T, bodies map[string]string) *[]*http.Request {
+	old := now
+	now = func() time.Time { return time.Date(2026, 10, 17, 17, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = old })
+
+	return fetchtest.Serve(t, bodies, map[*string]string{
+		&espnURL:       "/espn",
+		&openLigaDBURL: "/openligadb",
+	})
+}
+
+func TestESPN(t *testing.T) {
+	requests := serve(t, map[string]string{"/espn/basketball/nba/scoreboard": espnJSON})
+
+	fixtures, err := Fetch(context.Background(), "espn", "nba", "2026-10-17", DefaultTTL, "scores")
+	require.NoError(t, err)
+
+	req := (*requests)[0]
+	assert.Equal(t, "20261017", req.URL.Query().Get("dates"))
+	assert.Equal(t, "60", req.Header.Get("X-Tidbyt-Cache-Seconds"))
+	assert.Equal(t, "scores", req.Header.Get("X-Tidbyt-App"))
+
+	// fixtures are sorted by when they start
+	require.Len(t, fixtures, 3)
+	assert.Equal(t, "400", fixtures[0].ID)
+	assert.Equal(t, Final, fixtures[0].Status)
+	assert.Equal(t, "Final/OT", fixtures[0].Detail)
+	assert.True(t, fixtures[0].Home.Winner)
+	assert.False(t, fixtures[0].Away.Winner)
+
+	live := fixtures[1]
+	assert.Equal(t, "nba", live.League)
+	assert.Equal(t, time.Date(2026, 10, 17, 23, 30, 0, 0, time.UTC), live.Start)
+	assert.Equal(t, InProgress, live.Status)
+	assert.Equal(t, "TD Garden", live.Venue)
+	assert.Equal(t, Team{
+		ID:           "2",
+		Abbreviation: "BOS",
+		Name:         "Boston Celtics",
+		ShortName:    "Celtics",
+		Color:        "#008348",
+		LogoURL:      "https://a.espncdn.com/bos.png",
+	}, live.Home.Team)
+	assert.Equal(t, 88, *live.Home.Score)
+	assert.Equal(t, 91, *live.Away.Score)
+
+	// games that haven't started have no score
+	assert.Equal(t, Postponed, fixtures[2].Status)
+	assert.Nil(t, fixtures[2].Home.Score)
+}
+
+func TestOpenLigaDB(t *testing.T) {
+	serve(t, map[string]string{"/openligadb/getmatchdata/bl1": openLigaDBJSON})
+
+	fixtures, err := Fetch(context.Background(), "openligadb", "bl1", "", DefaultTTL, "scores")
+	require.NoError(t, err)
+	require.Len(t, fixtures, 3)
+
+	// the final score is used, not the half time one
+	assert.Equal(t, Final, fixtures[0].Status)
+	assert.Equal(t, 2, *fixtures[0].Home.Score)
+	assert.Equal(t, 2, *fixtures[0].Away.Score)
+	assert.False(t, fixtures[0].Home.Winner || fixtures[0].Away.Winner)
+	assert.Equal(t, "Allianz Arena", fixtures[0].Venue)
+	assert.Equal(t, "Bayern", fixtures[0].Home.Team.ShortName)
+
+	// matches under way are scored by their goals so far
+	assert.Equal(t, InProgress, fixtures[1].Status)
+	assert.Equal(t, 0, *fixtures[1].Home.Score)
+	assert.Equal(t, 1, *fixtures[1].Away.Score)
+
+	assert.Equal(t, Scheduled, fixtures[2].Status)
+	assert.Nil(t, fixtures[2].Home.Score)
+
+	// dates pick from the matchday
+	fixtures, err = Fetch(context.Background(), "openligadb", "bl1", "2026-10-18", DefaultTTL, "scores")
+	require.NoError(t, err)
+	require.Len(t, fixtures, 1)
+	assert.Equal(t, "Gladbach", fixtures[0].Home.Team.ShortName)
+}
+
+func TestFetchInvalid(t *testing.T) {
+	_, err := Fetch(context.Background(), "bbc", "epl", "", DefaultTTL, "scores")
+	assert.ErrorContains(t, err, "expected one of espn, openligadb")
+
+	_, err = Fetch(context.Background(), "openligadb", "nba", "", DefaultTTL, "scores")
+	assert.ErrorContains(t, err, "expected one of bl1, bl2, bl3, dfb")
+
+	_, err = Fetch(context.Background(), "espn", "nba", "17/10/2026", DefaultTTL, "scores")
+	assert.ErrorContains(t, err, "date must be YYYY-MM-DD")
+}
+
+func TestFixturesModule(t *testing.T) {
+	serve(t, map[string]string{"/espn/basketball/nba/scoreboard": espnJSON})
+
+	pack, err := icons.LoadPack(LogoPack, fstest.MapFS{
+		"nba/bos.png": {Data: []byte("celtics")},
+	})
+	require.NoError(t, err)
+	icons.Register(pack)
+
+	src := `
+load("sports.star", "sports")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+assert(sports.providers() == ["espn", "openligadb"])
+assert("nba" in sports.leagues())
+assert(sports.leagues(provider = "openligadb") == ["bl1", "bl2", "bl3", "dfb"])
+
+games = sports.fixtures("nba", team = "celtics")
+assert(len(games) == 1)
+game = games[0]
+assert(game.status == "in_progress")
+assert(game.detail == "Q4 2:31")
+assert(game.home.team.abbreviation == "BOS")
+assert(game.home.score == 88)
+assert(game.away.team.color == "#1d428a")
+assert(game.start.hour == 23)
+
+assert(sports.logo("nba", game.home.team) == "celtics")
+assert(sports.logo("nba", "BOS") == "celtics")
+assert(sports.logo("nba", game.away.team) == None)
+
+postponed = sports.fixtures("nba", team = "DEN")
+assert(postponed[0].home.score == None)
+`
+	thread := &starlark.Thread{
+		Name: "scores",
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return LoadModule()
+		},
+	}
+	_, err = starlark.ExecFile(thread, "scores.star", src, nil)
+	require.NoError(t, err)
+}
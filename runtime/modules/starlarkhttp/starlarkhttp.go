@@ -33,12 +33,15 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 
 	util "github.com/qri-io/starlib/util"
 	"go.starlark.net/starlark"
 	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/starlarkutil"
 )
 
 // AsString unquotes a starlark string value
@@ -59,6 +62,52 @@ var (
 	StarlarkHTTPGuard RequestGuard
 )
 
+// allowedHostsKey is the name of the thread-local holding the check set by
+// AllowHosts.
+const allowedHostsKey = "tidbyt.dev/pixlet/runtime/modules/starlarkhttp/$allowedHosts"
+
+// AllowHosts limits the requests made on thread to the hosts allowed
+// reports true for, such as those an applet declares in its manifest. The
+// host passed to allowed includes the port, if the URL has one.
+func AllowHosts(thread *starlark.Thread, allowed func(host string) bool) {
+	thread.SetLocal(allowedHostsKey, allowed)
+}
+
+// AllowedHosts returns the check AllowHosts set on thread, or nil if
+// requests made on it may go to any host.
+func AllowedHosts(thread *starlark.Thread) func(host string) bool {
+	allowed, _ := thread.Local(allowedHostsKey).(func(string) bool)
+	return allowed
+}
+
+// maxRedirects is how many redirects a client returned by RestrictRedirects
+// follows, the same as the http package's default.
+const maxRedirects = 10
+
+// RestrictRedirects returns cli, or if allowed isn't nil, a copy of it that
+// refuses to follow redirects to hosts allowed reports false for. Checking
+// the first request's host isn't enough, as its server can redirect anywhere.
+func RestrictRedirects(cli *http.Client, allowed func(host string) bool) *http.Client {
+	if allowed == nil {
+		return cli
+	}
+
+	restricted := *cli
+	restricted.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !allowed(req.URL.Host) {
+			return fmt.Errorf("redirected to %s, which isn't one of the network hosts the app declares", req.URL.Host)
+		}
+		if cli.CheckRedirect != nil {
+			return cli.CheckRedirect(req, via)
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+	return &restricted
+}
+
 // Encodings for form data.
 //
 // See: https://developer.mozilla.org/en-US/docs/Web/HTTP/Methods/POST
@@ -136,10 +185,14 @@ func (m *Module) reqMethod(method string) func(thread *starlark.Thread, _ *starl
 			return nil, err
 		}
 
-		req, err := http.NewRequest(strings.ToUpper(method), rawurl, nil)
+		req, err := http.NewRequestWithContext(starlarkutil.ThreadContext(thread), strings.ToUpper(method), rawurl, nil)
 		if err != nil {
 			return nil, err
 		}
+		allowed := AllowedHosts(thread)
+		if allowed != nil && !allowed(req.URL.Host) {
+			return nil, fmt.Errorf("%s: %s isn't one of the network hosts the app declares", method, req.URL.Host)
+		}
 		if m.rg != nil {
 			req, err = m.rg.Allowed(thread, req)
 			if err != nil {
@@ -160,7 +213,7 @@ func (m *Module) reqMethod(method string) func(thread *starlark.Thread, _ *starl
 			return nil, err
 		}
 
-		res, err := m.cli.Do(req)
+		res, err := RestrictRedirects(m.cli, allowed).Do(req)
 		if err != nil {
 			return nil, err
 		}
@@ -228,7 +281,7 @@ func setAuth(req *http.Request, auth starlark.Tuple) error {
 
 func setStandardHeaders(req *http.Request, thread *starlark.Thread, ttl starlark.Int) error {
 	// Set app identifier.
-	req.Header.Set("X-Tidbyt-App", getAppIdentifier(thread))
+	req.Header.Set("X-Tidbyt-App", starlarkutil.AppID(thread))
 
 	// Set ttl for caching client.
 	ttl64, ok := ttl.Int64()
@@ -240,15 +293,6 @@ func setStandardHeaders(req *http.Request, thread *starlark.Thread, ttl starlark
 	return nil
 }
 
-func getAppIdentifier(thread *starlark.Thread) string {
-	parts := strings.Split(thread.Name, "/")
-	if len(parts) > 0 {
-		return parts[0]
-	}
-
-	return thread.Name
-}
-
 func setHeaders(req *http.Request, headers *starlark.Dict) error {
 	keys := headers.Keys()
 	if len(keys) == 0 {
@@ -393,7 +437,14 @@ func (r *Response) Struct() *starlarkstruct.Struct {
 // HeadersDict flops
 func (r *Response) HeadersDict() *starlark.Dict {
 	d := new(starlark.Dict)
-	for key, vals := range r.Header {
+	// sorted, so apps iterating over them always see the same order
+	keys := make([]string, 0, len(r.Header))
+	for key := range r.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		vals := r.Header[key]
 		if err := d.SetKey(starlark.String(key), starlark.String(strings.Join(vals, ","))); err != nil {
 			panic(err)
 		}
@@ -140,3 +140,65 @@ func TestSetBody(t *testing.T) {
 		}
 	}
 }
+
+func TestAllowHosts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	thread := &starlark.Thread{Name: "unittests/abc123", Load: testdata.NewLoader(starlarkhttp.LoadModule, starlarkhttp.ModuleName)}
+	predeclared := starlark.StringDict{"url": starlark.String(ts.URL)}
+	src := `
+load("http.star", "http")
+resp = http.get(url)
+`
+
+	_, err := starlark.ExecFile(thread, "allowed.star", src, predeclared)
+	if err != nil {
+		t.Errorf("unexpected error without an allowlist: %s", err)
+	}
+
+	starlarkhttp.AllowHosts(thread, func(host string) bool { return host == "example.com" })
+	_, err = starlark.ExecFile(thread, "denied.star", src, predeclared)
+	if err == nil || !strings.Contains(err.Error(), "isn't one of the network hosts the app declares") {
+		t.Errorf("expected undeclared host to be rejected, got: %v", err)
+	}
+
+	starlarkhttp.AllowHosts(thread, func(host string) bool { return strings.HasPrefix(host, "127.0.0.1:") })
+	_, err = starlark.ExecFile(thread, "declared.star", src, predeclared)
+	if err != nil {
+		t.Errorf("unexpected error for a declared host: %s", err)
+	}
+}
+
+func TestAllowHostsRedirect(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	thread := &starlark.Thread{Name: "unittests/abc123", Load: testdata.NewLoader(starlarkhttp.LoadModule, starlarkhttp.ModuleName)}
+	predeclared := starlark.StringDict{"url": starlark.String(redirect.URL)}
+	src := `
+load("http.star", "http")
+resp = http.get(url)
+`
+
+	redirectHost := strings.TrimPrefix(redirect.URL, "http://")
+	starlarkhttp.AllowHosts(thread, func(host string) bool { return host == redirectHost })
+	_, err := starlark.ExecFile(thread, "redirect.star", src, predeclared)
+	if err == nil || !strings.Contains(err.Error(), "isn't one of the network hosts the app declares") {
+		t.Errorf("expected redirect to undeclared host to be rejected, got: %v", err)
+	}
+
+	targetHost := strings.TrimPrefix(target.URL, "http://")
+	starlarkhttp.AllowHosts(thread, func(host string) bool { return host == redirectHost || host == targetHost })
+	_, err = starlark.ExecFile(thread, "redirect.star", src, predeclared)
+	if err != nil {
+		t.Errorf("unexpected error for a redirect to a declared host: %s", err)
+	}
+}
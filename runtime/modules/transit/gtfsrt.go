@@ -0,0 +1,268 @@
+package transit
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Feed is what's decoded from a GTFS-realtime feed: its trip updates.
+// Vehicle positions and alerts are skipped.
+type Feed struct {
+	// Timestamp is when the feed was made, or zero if it doesn't say.
+	Timestamp time.Time
+
+	TripUpdates []TripUpdate
+}
+
+// TripUpdate is the realtime progress of a trip.
+type TripUpdate struct {
+	TripID  string
+	RouteID string
+
+	// DirectionID is the direction the trip travels in, 0 or 1 as in the
+	// feed's static GTFS, or -1 if the feed doesn't say.
+	DirectionID int
+
+	// Canceled trips won't run.
+	Canceled bool
+
+	// Vehicle is the label of the vehicle running the trip, or its ID if
+	// it has no label.
+	Vehicle string
+
+	// Delay is how many seconds late the trip is, if the feed says, for
+	// stops that don't say themselves.
+	Delay *int
+
+	Stops []StopTimeUpdate
+}
+
+// StopTimeUpdate is the realtime arrival and departure of a trip at a stop.
+type StopTimeUpdate struct {
+	StopSequence int
+	StopID       string
+
+	Arrival, Departure StopTimeEvent
+
+	// Skipped stops won't be served by the trip.
+	Skipped bool
+}
+
+// StopTimeEvent is when a trip arrives at or departs a stop.
+type StopTimeEvent struct {
+	// Time is when it happens, or zero if the feed only gives a delay.
+	Time time.Time
+
+	// Delay is how many seconds late it is, negative if early, or nil if
+	// the feed doesn't say.
+	Delay *int
+}
+
+// GTFS-realtime field numbers, from gtfs-realtime.proto.
+const (
+	feedMessageHeader = 1
+	feedMessageEntity = 2
+
+	feedHeaderTimestamp = 3
+
+	feedEntityTripUpdate = 3
+
+	tripUpdateTrip           = 1
+	tripUpdateStopTimeUpdate = 2
+	tripUpdateVehicle        = 3
+	tripUpdateDelay          = 5
+
+	tripDescriptorTripID               = 1
+	tripDescriptorScheduleRelationship = 4
+	tripDescriptorRouteID              = 5
+	tripDescriptorDirectionID          = 6
+
+	vehicleDescriptorID    = 1
+	vehicleDescriptorLabel = 2
+
+	stopTimeUpdateStopSequence         = 1
+	stopTimeUpdateArrival              = 2
+	stopTimeUpdateDeparture            = 3
+	stopTimeUpdateStopID               = 4
+	stopTimeUpdateScheduleRelationship = 5
+
+	stopTimeEventDelay = 1
+	stopTimeEventTime  = 2
+
+	// tripCanceled and tripDeleted are the schedule relationships of trips
+	// that won't run, and stopSkipped that of stops that won't be served.
+	tripCanceled = 3
+	tripDeleted  = 7
+	stopSkipped  = 1
+)
+
+// field is a field of a protocol buffer message: its number, and its value,
+// in varint for varint fields, and in bytes for strings and messages.
+type field struct {
+	num    protowire.Number
+	varint uint64
+	bytes  []byte
+}
+
+// fields calls f with each varint and length-delimited field of a message,
+// skipping fields of other types.
+func fields(b []byte, f func(field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		fl := field{num: num}
+		switch typ {
+		case protowire.VarintType:
+			fl.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			fl.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := f(fl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// int32Of returns the value of an int32 field, which negative numbers are
+// sign extended to 64 bits in.
+func int32Of(f field) *int {
+	v := int(int32(f.varint))
+	return &v
+}
+
+// DecodeFeed decodes a GTFS-realtime FeedMessage.
+func DecodeFeed(b []byte) (*Feed, error) {
+	feed := &Feed{}
+	err := fields(b, func(f field) error {
+		switch f.num {
+		case feedMessageHeader:
+			return fields(f.bytes, func(f field) error {
+				if f.num == feedHeaderTimestamp && f.varint > 0 {
+					feed.Timestamp = time.Unix(int64(f.varint), 0)
+				}
+				return nil
+			})
+		case feedMessageEntity:
+			return fields(f.bytes, func(f field) error {
+				if f.num != feedEntityTripUpdate {
+					return nil
+				}
+				tu, err := decodeTripUpdate(f.bytes)
+				if err != nil {
+					return err
+				}
+				feed.TripUpdates = append(feed.TripUpdates, tu)
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decoding GTFS-realtime feed: %w", err)
+	}
+	return feed, nil
+}
+
+func decodeTripUpdate(b []byte) (TripUpdate, error) {
+	tu := TripUpdate{DirectionID: -1}
+	err := fields(b, func(f field) error {
+		switch f.num {
+		case tripUpdateTrip:
+			return fields(f.bytes, func(f field) error {
+				switch f.num {
+				case tripDescriptorTripID:
+					tu.TripID = string(f.bytes)
+				case tripDescriptorRouteID:
+					tu.RouteID = string(f.bytes)
+				case tripDescriptorDirectionID:
+					tu.DirectionID = int(f.varint)
+				case tripDescriptorScheduleRelationship:
+					tu.Canceled = f.varint == tripCanceled || f.varint == tripDeleted
+				}
+				return nil
+			})
+
+		case tripUpdateVehicle:
+			var id, label string
+			err := fields(f.bytes, func(f field) error {
+				switch f.num {
+				case vehicleDescriptorID:
+					id = string(f.bytes)
+				case vehicleDescriptorLabel:
+					label = string(f.bytes)
+				}
+				return nil
+			})
+			tu.Vehicle = label
+			if label == "" {
+				tu.Vehicle = id
+			}
+			return err
+
+		case tripUpdateDelay:
+			tu.Delay = int32Of(f)
+
+		case tripUpdateStopTimeUpdate:
+			stu, err := decodeStopTimeUpdate(f.bytes)
+			if err != nil {
+				return err
+			}
+			tu.Stops = append(tu.Stops, stu)
+		}
+		return nil
+	})
+	return tu, err
+}
+
+func decodeStopTimeUpdate(b []byte) (StopTimeUpdate, error) {
+	var stu StopTimeUpdate
+	err := fields(b, func(f field) error {
+		switch f.num {
+		case stopTimeUpdateStopSequence:
+			stu.StopSequence = int(f.varint)
+		case stopTimeUpdateStopID:
+			stu.StopID = string(f.bytes)
+		case stopTimeUpdateScheduleRelationship:
+			stu.Skipped = f.varint == stopSkipped
+		case stopTimeUpdateArrival:
+			return decodeStopTimeEvent(f.bytes, &stu.Arrival)
+		case stopTimeUpdateDeparture:
+			return decodeStopTimeEvent(f.bytes, &stu.Departure)
+		}
+		return nil
+	})
+	return stu, err
+}
+
+func decodeStopTimeEvent(b []byte, e *StopTimeEvent) error {
+	return fields(b, func(f field) error {
+		switch f.num {
+		case stopTimeEventDelay:
+			e.Delay = int32Of(f)
+		case stopTimeEventTime:
+			if t := int64(f.varint); t > 0 {
+				e.Time = time.Unix(t, 0)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,333 @@
+// Package transit fetches GTFS-realtime trip updates, which most transit
+// agencies publish, and lists the upcoming departures from stops, so transit
+// apps don't each need to decode the feeds.
+//
+// Only times given by the realtime feed are known: departures that a feed
+// only gives a delay for, relative to the agency's static schedule, are
+// left out.
+//
+// Feeds are fetched through the same client as the http module, so they're
+// cached like an app's own requests, and held to the same network hosts.
+package transit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	startime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+const (
+	ModuleName = "transit"
+
+	// DefaultTTL is how long feeds are cached for by default. Feeds are
+	// usually updated every 30 seconds or so.
+	DefaultTTL = 30 * time.Second
+
+	// DefaultLimit is how many departures are listed by default.
+	DefaultLimit = 10
+
+	// maxFeedSize is the largest feed that's decoded. Whole-system feeds
+	// of big agencies run to a few megabytes.
+	maxFeedSize = 32 << 20
+)
+
+// now returns the current time, which departures are listed from.
+var now = time.Now
+
+var (
+	once   sync.Once
+	module starlark.StringDict
+)
+
+func LoadModule() (starlark.StringDict, error) {
+	once.Do(func() {
+		module = starlark.StringDict{
+			ModuleName: &starlarkstruct.Module{
+				Name: ModuleName,
+				Members: starlark.StringDict{
+					"departures": starlark.NewBuiltin("departures", departures),
+				},
+			},
+		}
+	})
+
+	return module, nil
+}
+
+// Fetch fetches and decodes the GTFS-realtime feed at url, with the given
+// headers, such as the API key some agencies require. Responses are cached
+// for ttl. If ctx came from fetch.Context, url must be on one of the app's
+// network hosts.
+func Fetch(ctx context.Context, url string, headers map[string]string, ttl time.Duration, appID string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := fetch.CheckHost(ctx, req); err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Accept", "application/x-protobuf, application/octet-stream")
+	req.Header.Set("X-Tidbyt-App", appID)
+	req.Header.Set("X-Tidbyt-Cache-Seconds", strconv.Itoa(int(ttl.Seconds())))
+
+	resp, err := fetch.Client(ctx).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("fetching feed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching feed: %w", err)
+	}
+	if len(b) > maxFeedSize {
+		return nil, fmt.Errorf("feed is larger than %d MB", maxFeedSize>>20)
+	}
+	return DecodeFeed(b)
+}
+
+// Query picks departures from a feed.
+type Query struct {
+	// Stops are the IDs of the stops to list departures from, as in the
+	// agency's static GTFS.
+	Stops []string
+
+	// Routes, if set, are the only routes whose departures are listed.
+	Routes []string
+
+	// Direction, if 0 or 1, is the only direction whose departures are
+	// listed. -1 means either.
+	Direction int
+
+	// Limit is how many departures are listed at most. Zero means all.
+	Limit int
+
+	// IncludeCanceled lists departures that were canceled, or whose stop
+	// is skipped, rather than leaving them out.
+	IncludeCanceled bool
+}
+
+// Departure is a trip leaving a stop.
+type Departure struct {
+	RouteID string
+	TripID  string
+	StopID  string
+
+	// DirectionID is 0 or 1, or -1 if the feed doesn't say.
+	DirectionID int
+
+	Time time.Time
+
+	// Delay is how many seconds late the departure is, negative if early,
+	// or nil if the feed doesn't say.
+	Delay *int
+
+	Vehicle  string
+	Canceled bool
+}
+
+// Departures returns the departures in feed that match q, from at onwards,
+// soonest first. A departure's delay is the delay of its stop, or else of
+// the trip's last stop before it that has one, or else of the trip, as
+// delays carry on down a trip in GTFS-realtime.
+func (feed *Feed) Departures(q Query, at time.Time) []Departure {
+	var deps []Departure
+	for _, tu := range feed.TripUpdates {
+		if len(q.Routes) > 0 && !slices.Contains(q.Routes, tu.RouteID) {
+			continue
+		}
+		if q.Direction >= 0 && tu.DirectionID != q.Direction {
+			continue
+		}
+
+		delay := tu.Delay
+		for _, stu := range tu.Stops {
+			event := stu.Departure
+			if event.Time.IsZero() {
+				event = stu.Arrival
+			}
+			if d := firstOf(stu.Departure.Delay, stu.Arrival.Delay); d != nil {
+				delay = d
+			}
+
+			if !slices.Contains(q.Stops, stu.StopID) || event.Time.IsZero() || event.Time.Before(at) {
+				continue
+			}
+			canceled := tu.Canceled || stu.Skipped
+			if canceled && !q.IncludeCanceled {
+				continue
+			}
+
+			deps = append(deps, Departure{
+				RouteID:     tu.RouteID,
+				TripID:      tu.TripID,
+				StopID:      stu.StopID,
+				DirectionID: tu.DirectionID,
+				Time:        event.Time,
+				Delay:       delay,
+				Vehicle:     tu.Vehicle,
+				Canceled:    canceled,
+			})
+		}
+	}
+
+	slices.SortStableFunc(deps, func(a, b Departure) int {
+		return a.Time.Compare(b.Time)
+	})
+	if q.Limit > 0 && len(deps) > q.Limit {
+		deps = deps[:q.Limit]
+	}
+	return deps
+}
+
+// firstOf returns the first of values that isn't nil.
+func firstOf[T any](values ...*T) *T {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func departures(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		url             string
+		stops           starlark.Value
+		routes          starlark.Value = starlark.None
+		direction       starlark.Value = starlark.None
+		limit                          = DefaultLimit
+		headers                        = &starlark.Dict{}
+		ttl                            = int(DefaultTTL.Seconds())
+		includeCanceled bool
+	)
+
+	if err := starlark.UnpackArgs(
+		"departures",
+		args, kwargs,
+		"url", &url,
+		"stops", &stops,
+		"routes?", &routes,
+		"direction?", &direction,
+		"limit?", &limit,
+		"headers?", &headers,
+		"ttl_seconds?", &ttl,
+		"include_canceled?", &includeCanceled,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for departures: %s", err)
+	}
+
+	q := Query{Direction: -1, Limit: limit, IncludeCanceled: includeCanceled}
+	var err error
+	if q.Stops, err = stringsOf("stops", stops); err != nil {
+		return nil, err
+	}
+	if len(q.Stops) == 0 {
+		return nil, fmt.Errorf("departures: stops must name at least one stop")
+	}
+	if routes != starlark.None {
+		if q.Routes, err = stringsOf("routes", routes); err != nil {
+			return nil, err
+		}
+	}
+	if direction != starlark.None {
+		d, err := starlark.AsInt32(direction)
+		if err != nil || (d != 0 && d != 1) {
+			return nil, fmt.Errorf("departures: direction must be 0 or 1, not %s", direction)
+		}
+		q.Direction = d
+	}
+
+	hdrs := map[string]string{}
+	for _, item := range headers.Items() {
+		k, kok := starlark.AsString(item[0])
+		v, vok := starlark.AsString(item[1])
+		if !kok || !vok {
+			return nil, fmt.Errorf("departures: headers must be strings")
+		}
+		hdrs[k] = v
+	}
+
+	appID := starlarkutil.AppID(thread)
+	feed, err := Fetch(fetch.Context(thread), url, hdrs, time.Duration(ttl)*time.Second, appID)
+	if err != nil {
+		return nil, fmt.Errorf("departures: %w", err)
+	}
+
+	t := now()
+	var values []starlark.Value
+	for _, d := range feed.Departures(q, t) {
+		values = append(values, departureValue(d, t))
+	}
+	return starlark.NewList(values), nil
+}
+
+// stringsOf unpacks an argument that's a string or a list of them.
+func stringsOf(name string, v starlark.Value) ([]string, error) {
+	if s, ok := starlark.AsString(v); ok {
+		return []string{s}, nil
+	}
+
+	iterable, ok := v.(starlark.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("departures: %s must be a string or a list of strings, not %s", name, v.Type())
+	}
+	var ss []string
+	iter := iterable.Iterate()
+	defer iter.Done()
+	var x starlark.Value
+	for iter.Next(&x) {
+		s, ok := starlark.AsString(x)
+		if !ok {
+			return nil, fmt.Errorf("departures: %s must be a string or a list of strings, not a list of %s", name, x.Type())
+		}
+		ss = append(ss, s)
+	}
+	return ss, nil
+}
+
+func departureValue(d Departure, now time.Time) starlark.Value {
+	var direction, delay, vehicle starlark.Value = starlark.None, starlark.None, starlark.None
+	if d.DirectionID >= 0 {
+		direction = starlark.MakeInt(d.DirectionID)
+	}
+	if d.Delay != nil {
+		delay = starlark.MakeInt(*d.Delay)
+	}
+	if d.Vehicle != "" {
+		vehicle = starlark.String(d.Vehicle)
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"route":     starlark.String(d.RouteID),
+		"trip":      starlark.String(d.TripID),
+		"stop":      starlark.String(d.StopID),
+		"direction": direction,
+		"time":      startime.Time(d.Time),
+		"minutes":   starlark.MakeInt(int(d.Time.Sub(now) / time.Minute)),
+		"delay":     delay,
+		"vehicle":   vehicle,
+		"canceled":  starlark.Bool(d.Canceled),
+	})
+}
@@ -0,0 +1,216 @@
+package transit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.starlark.net/starlark"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"tidbyt.dev/pixlet/runtime/modules/starlarkhttp"
+)
+
+// message encodes a protocol buffer message from its fields, which are
+// strings, messages made with message, or varints.
+func message(fields ...any) []byte {
+	var b []byte
+	for i := 0; i < len(fields); i += 2 {
+		num := protowire.Number(fields[i].(int))
+		switch v := fields[i+1].(type) {
+		case string:
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, v)
+		case []byte:
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendBytes(b, v)
+		case int:
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(v))
+		case int64:
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(v))
+		}
+	}
+	return b
+}
+
+var at = time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+
+func stopTimeUpdate(stop string, departs time.Time, fields ...any) []byte {
+	return message(append([]any{
+		stopTimeUpdateStopID, stop,
+		stopTimeUpdateDeparture, message(stopTimeEventTime, departs.Unix()),
+	}, fields...)...)
+}
+
+// testFeed has three G trains: one running two minutes late, one on time
+// that skips Classon Av, and one canceled.
+func testFeed() []byte {
+	return message(
+		feedMessageHeader, message(1, "2.0", feedHeaderTimestamp, at.Unix()),
+		// a vehicle position, which is skipped
+		feedMessageEntity, message(1, "pos", 4, message(1, message(1, "t0"))),
+		feedMessageEntity, message(1, "a", feedEntityTripUpdate, message(
+			tripUpdateTrip, message(tripDescriptorTripID, "t1", tripDescriptorRouteID, "G", tripDescriptorDirectionID, 0),
+			tripUpdateVehicle, message(vehicleDescriptorID, "1234", vehicleDescriptorLabel, "G 0800"),
+			tripUpdateStopTimeUpdate, stopTimeUpdate("G21N", at.Add(-time.Minute),
+				stopTimeUpdateArrival, message(stopTimeEventDelay, 120)),
+			tripUpdateStopTimeUpdate, stopTimeUpdate("G22N", at.Add(4*time.Minute+30*time.Second)),
+		)),
+		feedMessageEntity, message(1, "b", feedEntityTripUpdate, message(
+			tripUpdateTrip, message(tripDescriptorTripID, "t2", tripDescriptorRouteID, "G", tripDescriptorDirectionID, 1),
+			tripUpdateDelay, -30,
+			tripUpdateStopTimeUpdate, stopTimeUpdate("G22S", at.Add(2*time.Minute)),
+			tripUpdateStopTimeUpdate, stopTimeUpdate("G23S", at.Add(4*time.Minute), stopTimeUpdateScheduleRelationship, stopSkipped),
+		)),
+		feedMessageEntity, message(1, "c", feedEntityTripUpdate, message(
+			tripUpdateTrip, message(tripDescriptorTripID, "t3", tripDescriptorRouteID, "G", tripDescriptorScheduleRelationship, tripCanceled),
+			tripUpdateStopTimeUpdate, stopTimeUpdate("G22N", at.Add(10*time.Minute)),
+		)),
+	)
+}
+
+func TestDecodeFeed(t *testing.T) {
+	feed, err := DecodeFeed(testFeed())
+	require.NoError(t, err)
+
+	assert.Equal(t, at, feed.Timestamp.UTC())
+	require.Len(t, feed.TripUpdates, 3)
+
+	tu := feed.TripUpdates[0]
+	assert.Equal(t, "t1", tu.TripID)
+	assert.Equal(t, "G", tu.RouteID)
+	assert.Equal(t, 0, tu.DirectionID)
+	assert.Equal(t, "G 0800", tu.Vehicle)
+	require.Len(t, tu.Stops, 2)
+	assert.Equal(t, 120, *tu.Stops[0].Arrival.Delay)
+	assert.Equal(t, at.Add(-time.Minute), tu.Stops[0].Departure.Time.UTC())
+
+	assert.Equal(t, -30, *feed.TripUpdates[1].Delay)
+	assert.True(t, feed.TripUpdates[1].Stops[1].Skipped)
+	assert.True(t, feed.TripUpdates[2].Canceled)
+	assert.Equal(t, -1, feed.TripUpdates[2].DirectionID)
+
+	_, err = DecodeFeed([]byte{0x0a, 0x05, 0x01})
+	assert.Error(t, err)
+}
+
+func TestDepartures(t *testing.T) {
+	feed, err := DecodeFeed(testFeed())
+	require.NoError(t, err)
+
+	deps := feed.Departures(Query{Stops: []string{"G22N", "G22S", "G23S"}, Direction: -1}, at)
+	require.Len(t, deps, 2)
+
+	// the trip on time comes first, with the trip's delay
+	assert.Equal(t, "t2", deps[0].TripID)
+	assert.Equal(t, -30, *deps[0].Delay)
+
+	// and the late one carries on the delay of the stop before
+	assert.Equal(t, "t1", deps[1].TripID)
+	assert.Equal(t, "G22N", deps[1].StopID)
+	assert.Equal(t, 120, *deps[1].Delay)
+	assert.Equal(t, "G 0800", deps[1].Vehicle)
+
+	// canceled trips and skipped stops are left out unless asked for
+	deps = feed.Departures(Query{Stops: []string{"G22N", "G23S"}, Direction: -1, IncludeCanceled: true}, at)
+	require.Len(t, deps, 3)
+	assert.True(t, deps[0].Canceled)
+	assert.False(t, deps[1].Canceled)
+	assert.True(t, deps[2].Canceled)
+
+	deps = feed.Departures(Query{Stops: []string{"G22N", "G22S"}, Direction: 1}, at)
+	require.Len(t, deps, 1)
+	assert.Equal(t, "G22S", deps[0].StopID)
+
+	deps = feed.Departures(Query{Stops: []string{"G22N", "G22S"}, Routes: []string{"F"}, Direction: -1}, at)
+	assert.Empty(t, deps)
+
+	deps = feed.Departures(Query{Stops: []string{"G22N", "G22S"}, Direction: -1, Limit: 1}, at)
+	assert.Len(t, deps, 1)
+}
+
+func TestDeparturesModule(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(testFeed())
+	}))
+	defer server.Close()
+
+	old := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = old })
+
+	src := `
+load("transit.star", "transit")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+deps = transit.departures(FEED, stops = "G22N", headers = {"x-api-key": "lovelace"})
+assert(len(deps) == 1)
+assert(deps[0].route == "G")
+assert(deps[0].direction == 0)
+assert(deps[0].minutes == 4)
+assert(deps[0].delay == 120)
+assert(deps[0].vehicle == "G 0800")
+assert(not deps[0].canceled)
+
+southbound = transit.departures(FEED, stops = ["G22N", "G22S"], direction = 1, include_canceled = True)
+assert(len(southbound) == 1)
+assert(southbound[0].delay == -30)
+assert(southbound[0].vehicle == None)
+`
+	thread := &starlark.Thread{
+		Name: "subway/kitchen",
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return LoadModule()
+		},
+	}
+	_, err := starlark.ExecFile(thread, "subway.star", src, starlark.StringDict{"FEED": starlark.String(server.URL)})
+	require.NoError(t, err)
+
+	got := requests[0]
+	assert.Equal(t, "lovelace", got.Header.Get("X-Api-Key"))
+	assert.Equal(t, "subway", got.Header.Get("X-Tidbyt-App"))
+	assert.Equal(t, "30", got.Header.Get("X-Tidbyt-Cache-Seconds"))
+
+	_, err = starlark.ExecFile(thread, "subway.star", `
+load("transit.star", "transit")
+transit.departures(FEED, stops = [])
+`, starlark.StringDict{"FEED": starlark.String(server.URL)})
+	assert.ErrorContains(t, err, "at least one stop")
+
+	_, err = starlark.ExecFile(thread, "subway.star", `
+load("transit.star", "transit")
+transit.departures(FEED, stops = "G22N", direction = 2)
+`, starlark.StringDict{"FEED": starlark.String(server.URL)})
+	assert.ErrorContains(t, err, "direction must be 0 or 1")
+
+	// feeds are held to the hosts the app declares
+	starlarkhttp.AllowHosts(thread, func(host string) bool { return host == "api.mta.info" })
+	_, err = starlark.ExecFile(thread, "subway.star", `
+load("transit.star", "transit")
+transit.departures(FEED, stops = "G22N")
+`, starlark.StringDict{"FEED": starlark.String(server.URL)})
+	assert.ErrorContains(t, err, "isn't one of the network hosts the app declares")
+	assert.Len(t, requests, 2)
+}
+
+func TestFetchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid API key", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := Fetch(context.Background(), server.URL, nil, DefaultTTL, "subway")
+	assert.ErrorContains(t, err, "403 Forbidden: invalid API key")
+}
@@ -0,0 +1,142 @@
+package weather
+
+import "strings"
+
+// Condition is the weather in a few words, the same whichever provider
+// reported it.
+type Condition string
+
+const (
+	Clear        Condition = "clear"
+	PartlyCloudy Condition = "partly_cloudy"
+	Cloudy       Condition = "cloudy"
+	Fog          Condition = "fog"
+	Drizzle      Condition = "drizzle"
+	Rain         Condition = "rain"
+	FreezingRain Condition = "freezing_rain"
+	Sleet        Condition = "sleet"
+	Snow         Condition = "snow"
+	Thunderstorm Condition = "thunderstorm"
+	Wind         Condition = "wind"
+	Unknown      Condition = "unknown"
+)
+
+// Icon returns the name of an icon for the condition, from the set most
+// weather icon packs use: clear-day, clear-night, partly-cloudy-day,
+// partly-cloudy-night, cloudy, fog, rain, sleet, snow, thunderstorm and wind.
+func (c Condition) Icon(isDay bool) string {
+	switch c {
+	case Clear, PartlyCloudy:
+		name := "clear"
+		if c == PartlyCloudy {
+			name = "partly-cloudy"
+		}
+		if isDay {
+			return name + "-day"
+		}
+		return name + "-night"
+	case Drizzle, Rain:
+		return "rain"
+	case FreezingRain, Sleet:
+		return "sleet"
+	case Fog, Snow, Thunderstorm, Wind:
+		return string(c)
+	}
+	return "cloudy"
+}
+
+// wmoCondition returns the condition of a WMO weather interpretation code,
+// as Open-Meteo reports them.
+func wmoCondition(code int) Condition {
+	switch code {
+	case 0, 1:
+		return Clear
+	case 2:
+		return PartlyCloudy
+	case 3:
+		return Cloudy
+	case 45, 48:
+		return Fog
+	case 51, 53, 55:
+		return Drizzle
+	case 56, 57, 66, 67:
+		return FreezingRain
+	case 61, 63, 65, 80, 81, 82:
+		return Rain
+	case 71, 73, 75, 77, 85, 86:
+		return Snow
+	case 95, 96, 99:
+		return Thunderstorm
+	}
+	return Unknown
+}
+
+// metNorwayCondition returns the condition of a MET Norway symbol code, such
+// as "lightrainshowers_day".
+func metNorwayCondition(symbol string) Condition {
+	symbol, _, _ = strings.Cut(symbol, "_")
+	switch {
+	case symbol == "":
+		return Unknown
+	case symbol == "clearsky", symbol == "fair":
+		return Clear
+	case symbol == "partlycloudy":
+		return PartlyCloudy
+	case symbol == "cloudy":
+		return Cloudy
+	case symbol == "fog":
+		return Fog
+	case strings.Contains(symbol, "thunder"):
+		return Thunderstorm
+	case strings.Contains(symbol, "sleet"):
+		return Sleet
+	case strings.Contains(symbol, "snow"):
+		return Snow
+	case symbol == "lightrain":
+		return Drizzle
+	case strings.Contains(symbol, "rain"):
+		return Rain
+	}
+	return Unknown
+}
+
+// nwsCondition returns the condition of the icon URL of a National Weather
+// Service forecast, such as ".../icons/land/day/tsra_sct,40/rain,60", and
+// whether it's for the day.
+func nwsCondition(icon string) (Condition, bool) {
+	_, rest, ok := strings.Cut(icon, "/icons/land/")
+	if !ok {
+		return Unknown, true
+	}
+	rest, _, _ = strings.Cut(rest, "?")
+	parts := strings.Split(rest, "/")
+	if len(parts) < 2 {
+		return Unknown, true
+	}
+	isDay := parts[0] != "night"
+	code, _, _ := strings.Cut(parts[1], ",")
+
+	switch code {
+	case "skc", "few", "hot", "cold":
+		return Clear, isDay
+	case "sct":
+		return PartlyCloudy, isDay
+	case "bkn", "ovc":
+		return Cloudy, isDay
+	case "wind_skc", "wind_few", "wind_sct", "wind_bkn", "wind_ovc":
+		return Wind, isDay
+	case "snow", "blizzard":
+		return Snow, isDay
+	case "rain_snow", "rain_sleet", "snow_sleet", "sleet":
+		return Sleet, isDay
+	case "fzra", "rain_fzra", "snow_fzra":
+		return FreezingRain, isDay
+	case "rain", "rain_showers", "rain_showers_hi":
+		return Rain, isDay
+	case "tsra", "tsra_sct", "tsra_hi", "tornado", "hurricane", "tropical_storm":
+		return Thunderstorm, isDay
+	case "fog", "haze", "smoke", "dust":
+		return Fog, isDay
+	}
+	return Unknown, isDay
+}
@@ -0,0 +1,162 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	gosunrise "github.com/nathan-osman/go-sunrise"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/runtime/modules/location"
+)
+
+var metNorwayURL = "https://api.met.no/weatherapi/locationforecast/2.0/compact"
+
+type metNorwayPeriod struct {
+	Summary struct {
+		SymbolCode string `json:"symbol_code"`
+	} `json:"summary"`
+	Details struct {
+		PrecipitationAmount *float64 `json:"precipitation_amount"`
+	} `json:"details"`
+}
+
+type metNorwayResponse struct {
+	Properties struct {
+		Timeseries []struct {
+			Time time.Time `json:"time"`
+			Data struct {
+				Instant struct {
+					Details struct {
+						AirTemperature    *float64 `json:"air_temperature"`
+						RelativeHumidity  *float64 `json:"relative_humidity"`
+						WindSpeed         *float64 `json:"wind_speed"`
+						WindFromDirection *float64 `json:"wind_from_direction"`
+					} `json:"details"`
+				} `json:"instant"`
+				Next1Hours *metNorwayPeriod `json:"next_1_hours"`
+				Next6Hours *metNorwayPeriod `json:"next_6_hours"`
+			} `json:"data"`
+		} `json:"timeseries"`
+	} `json:"properties"`
+}
+
+// metNorway fetches from MET Norway, which covers the whole world. Its
+// forecast is a series of hourly and, further ahead, six-hourly points, which
+// are summed up into days in the place's timezone. It has no apparent
+// temperatures or chances of precipitation.
+func metNorway(ctx context.Context, f *fetch.Fetcher, place location.Place, days int) (*Report, error) {
+	var resp metNorwayResponse
+	err := f.Get(ctx, fmt.Sprintf("%s?lat=%.2f&lon=%.2f", metNorwayURL, place.Lat, place.Lng), &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	series := resp.Properties.Timeseries
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no forecast")
+	}
+
+	zone, err := time.LoadLocation(place.Timezone)
+	if err != nil {
+		zone = time.UTC
+	}
+
+	now := series[0]
+	details := now.Data.Instant.Details
+	symbol := metNorwaySymbol(now.Data.Next1Hours, now.Data.Next6Hours)
+	r := &Report{
+		Current: Observation{
+			Time:          now.Time.In(zone),
+			Temp:          orNaN(details.AirTemperature),
+			FeelsLike:     math.NaN(),
+			Humidity:      orNaN(details.RelativeHumidity),
+			WindSpeed:     orNaN(details.WindSpeed) * 3.6,
+			WindDirection: orNaN(details.WindFromDirection),
+			Condition:     metNorwayCondition(symbol),
+			IsDay:         metNorwayIsDay(symbol, place, now.Time),
+		},
+	}
+
+	// the condition of each day is the one forecast closest to midday
+	middays := map[string]time.Duration{}
+	for _, point := range series {
+		local := point.Time.In(zone)
+		date := local.Format(time.DateOnly)
+		if n := len(r.Daily); n == 0 || r.Daily[n-1].Date != date {
+			if len(r.Daily) == days {
+				break
+			}
+			r.Daily = append(r.Daily, Day{
+				Date:                date,
+				High:                math.NaN(),
+				Low:                 math.NaN(),
+				PrecipitationChance: math.NaN(),
+				Condition:           Unknown,
+			})
+		}
+
+		d := &r.Daily[len(r.Daily)-1]
+		if t := point.Data.Instant.Details.AirTemperature; t != nil {
+			if math.IsNaN(d.High) || *t > d.High {
+				d.High = *t
+			}
+			if math.IsNaN(d.Low) || *t < d.Low {
+				d.Low = *t
+			}
+		}
+
+		// hourly points also have six hour totals, which overlap the next
+		// points' hours
+		if p := point.Data.Next1Hours; p != nil && p.Details.PrecipitationAmount != nil {
+			d.Precipitation += *p.Details.PrecipitationAmount
+		} else if p := point.Data.Next6Hours; p != nil && p.Details.PrecipitationAmount != nil {
+			d.Precipitation += *p.Details.PrecipitationAmount
+		}
+
+		if symbol := metNorwaySymbol(point.Data.Next1Hours, point.Data.Next6Hours); symbol != "" {
+			fromMidday := local.Sub(time.Date(local.Year(), local.Month(), local.Day(), 12, 0, 0, 0, zone)).Abs()
+			if best, ok := middays[date]; !ok || fromMidday < best {
+				middays[date] = fromMidday
+				d.Condition = metNorwayCondition(symbol)
+			}
+		}
+	}
+	return r, nil
+}
+
+func metNorwaySymbol(periods ...*metNorwayPeriod) string {
+	for _, p := range periods {
+		if p != nil && p.Summary.SymbolCode != "" {
+			return p.Summary.SymbolCode
+		}
+	}
+	return ""
+}
+
+// metNorwayIsDay reports whether it's day at the place at time t. Symbols
+// for clear and partly cloudy skies say, and otherwise it's between sunrise
+// and sunset.
+func metNorwayIsDay(symbol string, place location.Place, t time.Time) bool {
+	switch {
+	case strings.HasSuffix(symbol, "_night"):
+		return false
+	case strings.HasSuffix(symbol, "_day"), strings.HasSuffix(symbol, "_polartwilight"):
+		return true
+	}
+
+	rise, set := gosunrise.SunriseSunset(place.Lat, place.Lng, t.Year(), t.Month(), t.Day())
+	if rise.IsZero() || set.IsZero() {
+		// the sun doesn't rise or set today, so it's day if it's summer
+		summer := t.Month() >= time.April && t.Month() <= time.September
+		return summer == (place.Lat > 0)
+	}
+	if set.Before(rise) {
+		// the day started yesterday, in UTC
+		return t.After(rise) || t.Before(set)
+	}
+	return t.After(rise) && t.Before(set)
+}
@@ -0,0 +1,151 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/runtime/modules/location"
+)
+
+var nwsURL = "https://api.weather.gov"
+
+type nwsPoint struct {
+	Properties struct {
+		Forecast       string `json:"forecast"`
+		ForecastHourly string `json:"forecastHourly"`
+	} `json:"properties"`
+}
+
+type nwsValue struct {
+	Value *float64 `json:"value"`
+}
+
+type nwsForecast struct {
+	Properties struct {
+		Periods []struct {
+			StartTime                  time.Time `json:"startTime"`
+			IsDaytime                  bool      `json:"isDaytime"`
+			Temperature                float64   `json:"temperature"`
+			TemperatureUnit            string    `json:"temperatureUnit"`
+			ProbabilityOfPrecipitation nwsValue  `json:"probabilityOfPrecipitation"`
+			RelativeHumidity           nwsValue  `json:"relativeHumidity"`
+			WindSpeed                  string    `json:"windSpeed"`
+			WindDirection              string    `json:"windDirection"`
+			Icon                       string    `json:"icon"`
+		} `json:"periods"`
+	} `json:"properties"`
+}
+
+// compassPoints are the directions the National Weather Service reports
+// wind as coming from, in order from north.
+var compassPoints = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+var mphPattern = regexp.MustCompile(`(\d+) mph`)
+
+// nws fetches from the National Weather Service, which only covers the
+// United States. Its forecasts come in day and night periods, and have no
+// precipitation amounts or apparent temperatures.
+func nws(ctx context.Context, f *fetch.Fetcher, place location.Place, days int) (*Report, error) {
+	var point nwsPoint
+	err := f.Get(ctx, fmt.Sprintf("%s/points/%.2f,%.2f", nwsURL, place.Lat, place.Lng), &point)
+	if err != nil {
+		return nil, fmt.Errorf("%w (the National Weather Service only covers the United States)", err)
+	}
+
+	var hourly, daily nwsForecast
+	if err := f.Get(ctx, point.Properties.ForecastHourly, &hourly); err != nil {
+		return nil, err
+	}
+	if err := f.Get(ctx, point.Properties.Forecast, &daily); err != nil {
+		return nil, err
+	}
+	if len(hourly.Properties.Periods) == 0 {
+		return nil, fmt.Errorf("no hourly forecast")
+	}
+
+	now := hourly.Properties.Periods[0]
+	condition, isDay := nwsCondition(now.Icon)
+	r := &Report{
+		Current: Observation{
+			Time:          now.StartTime,
+			Temp:          nwsTemp(now.Temperature, now.TemperatureUnit),
+			FeelsLike:     math.NaN(),
+			Humidity:      orNaN(now.RelativeHumidity.Value),
+			WindSpeed:     nwsWindSpeed(now.WindSpeed),
+			WindDirection: nwsWindDirection(now.WindDirection),
+			Condition:     condition,
+			IsDay:         isDay,
+		},
+	}
+
+	for _, p := range daily.Properties.Periods {
+		// periods are in the place's own timezone
+		date := p.StartTime.Format(time.DateOnly)
+		if n := len(r.Daily); n == 0 || r.Daily[n-1].Date != date {
+			if len(r.Daily) == days {
+				break
+			}
+			r.Daily = append(r.Daily, Day{
+				Date:                date,
+				High:                math.NaN(),
+				Low:                 math.NaN(),
+				PrecipitationChance: math.NaN(),
+				Precipitation:       math.NaN(),
+				Condition:           Unknown,
+			})
+		}
+
+		d := &r.Daily[len(r.Daily)-1]
+		temp := nwsTemp(p.Temperature, p.TemperatureUnit)
+		condition, _ := nwsCondition(p.Icon)
+		if p.IsDaytime {
+			d.High = temp
+			d.Condition = condition
+		} else {
+			d.Low = temp
+			if d.Condition == Unknown {
+				// the forecast starts tonight
+				d.Condition = condition
+			}
+		}
+		if chance := orNaN(p.ProbabilityOfPrecipitation.Value); math.IsNaN(d.PrecipitationChance) || chance > d.PrecipitationChance {
+			d.PrecipitationChance = chance
+		}
+	}
+	return r, nil
+}
+
+func nwsTemp(t float64, unit string) float64 {
+	if unit == "F" {
+		return (t - 32) * 5 / 9
+	}
+	return t
+}
+
+// nwsWindSpeed converts wind speeds such as "10 mph" or "5 to 10 mph" to
+// km/h, taking the highest speed of a range.
+func nwsWindSpeed(s string) float64 {
+	m := mphPattern.FindStringSubmatch(s)
+	if m == nil {
+		return math.NaN()
+	}
+	mph, _ := strconv.ParseFloat(m[1], 64)
+	return mph * 1.609344
+}
+
+func nwsWindDirection(s string) float64 {
+	for i, point := range compassPoints {
+		if s == point {
+			return float64(i) * 22.5
+		}
+	}
+	return math.NaN()
+}
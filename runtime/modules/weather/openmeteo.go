@@ -0,0 +1,103 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/runtime/modules/location"
+)
+
+var openMeteoURL = "https://api.open-meteo.com/v1/forecast"
+
+type openMeteoResponse struct {
+	UTCOffsetSeconds int `json:"utc_offset_seconds"`
+
+	Current struct {
+		Time                string   `json:"time"`
+		Temperature         *float64 `json:"temperature_2m"`
+		ApparentTemperature *float64 `json:"apparent_temperature"`
+		Humidity            *float64 `json:"relative_humidity_2m"`
+		WindSpeed           *float64 `json:"wind_speed_10m"`
+		WindDirection       *float64 `json:"wind_direction_10m"`
+		WeatherCode         *int     `json:"weather_code"`
+		IsDay               int      `json:"is_day"`
+	} `json:"current"`
+
+	Daily struct {
+		Time                []string   `json:"time"`
+		WeatherCode         []*int     `json:"weather_code"`
+		High                []*float64 `json:"temperature_2m_max"`
+		Low                 []*float64 `json:"temperature_2m_min"`
+		PrecipitationChance []*float64 `json:"precipitation_probability_max"`
+		Precipitation       []*float64 `json:"precipitation_sum"`
+	} `json:"daily"`
+}
+
+// openMeteo fetches from Open-Meteo, which covers the whole world by
+// combining national weather services' models.
+func openMeteo(ctx context.Context, f *fetch.Fetcher, place location.Place, days int) (*Report, error) {
+	q := url.Values{}
+	q.Set("latitude", strconv.FormatFloat(place.Lat, 'f', -1, 64))
+	q.Set("longitude", strconv.FormatFloat(place.Lng, 'f', -1, 64))
+	q.Set("timezone", place.Timezone)
+	q.Set("forecast_days", strconv.Itoa(days))
+	q.Set("current", "temperature_2m,apparent_temperature,relative_humidity_2m,wind_speed_10m,wind_direction_10m,weather_code,is_day")
+	q.Set("daily", "weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max,precipitation_sum")
+
+	var resp openMeteoResponse
+	if err := f.Get(ctx, openMeteoURL+"?"+q.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	zone := time.FixedZone("", resp.UTCOffsetSeconds)
+	now, err := time.ParseInLocation("2006-01-02T15:04", resp.Current.Time, zone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q", resp.Current.Time)
+	}
+
+	r := &Report{
+		Current: Observation{
+			Time:          now,
+			Temp:          orNaN(resp.Current.Temperature),
+			FeelsLike:     orNaN(resp.Current.ApparentTemperature),
+			Humidity:      orNaN(resp.Current.Humidity),
+			WindSpeed:     orNaN(resp.Current.WindSpeed),
+			WindDirection: orNaN(resp.Current.WindDirection),
+			Condition:     wmoConditionOf(resp.Current.WeatherCode),
+			IsDay:         resp.Current.IsDay != 0,
+		},
+	}
+
+	d := resp.Daily
+	for i, date := range d.Time {
+		r.Daily = append(r.Daily, Day{
+			Date:                date,
+			High:                orNaN(at(d.High, i)),
+			Low:                 orNaN(at(d.Low, i)),
+			PrecipitationChance: orNaN(at(d.PrecipitationChance, i)),
+			Precipitation:       orNaN(at(d.Precipitation, i)),
+			Condition:           wmoConditionOf(at(d.WeatherCode, i)),
+		})
+	}
+	return r, nil
+}
+
+func wmoConditionOf(code *int) Condition {
+	if code == nil {
+		return Unknown
+	}
+	return wmoCondition(*code)
+}
+
+// at returns s[i], or nil if s is too short, for daily values that may be
+// missing.
+func at[T any](s []*T, i int) *T {
+	if i < len(s) {
+		return s[i]
+	}
+	return nil
+}
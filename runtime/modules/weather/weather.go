@@ -0,0 +1,298 @@
+// Package weather fetches current conditions and daily forecasts from
+// several free weather services, and reports them the same way whichever is
+// used, so apps don't each need to parse a provider's API.
+//
+// Places are rounded to two decimal places, about a kilometer, before
+// they're sent, so that devices near each other share cached forecasts and
+// services aren't told exactly where a device is.
+package weather
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	startime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch"
+	"tidbyt.dev/pixlet/runtime/modules/location"
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+const (
+	ModuleName = "weather"
+
+	// DefaultProvider is the provider used when apps don't name one. It
+	// covers the whole world without an API key.
+	DefaultProvider = "open-meteo"
+
+	// DefaultTTL is how long responses are cached for by default.
+	DefaultTTL = 15 * time.Minute
+
+	// MaxDays is how many days of forecast can be asked for.
+	MaxDays = 7
+)
+
+// provider fetches a report for days days at place.
+type provider func(ctx context.Context, f *fetch.Fetcher, place location.Place, days int) (*Report, error)
+
+var providers = map[string]provider{
+	"open-meteo": openMeteo,
+	"nws":        nws,
+	"met.no":     metNorway,
+}
+
+// Providers returns the names of the supported providers, sorted.
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Report is the weather at a place. Temperatures are in degrees Celsius,
+// wind speeds in km/h and precipitation in millimeters. Values a provider
+// doesn't report are NaN.
+type Report struct {
+	Current Observation
+	Daily   []Day
+}
+
+// Observation is the weather at a point in time.
+type Observation struct {
+	Time      time.Time
+	Temp      float64
+	FeelsLike float64
+
+	// Humidity is the relative humidity, in percent.
+	Humidity float64
+
+	// WindSpeed is the sustained wind speed, and WindDirection the direction
+	// in degrees the wind is coming from.
+	WindSpeed     float64
+	WindDirection float64
+
+	Condition Condition
+	IsDay     bool
+}
+
+// Day is the forecast for a day, in the place's timezone.
+type Day struct {
+	// Date is the day, as YYYY-MM-DD.
+	Date string
+
+	High, Low float64
+
+	// PrecipitationChance is the highest chance of precipitation during
+	// the day, in percent, and Precipitation how much is expected.
+	PrecipitationChance float64
+	Precipitation       float64
+
+	Condition Condition
+}
+
+var (
+	once   sync.Once
+	module starlark.StringDict
+)
+
+func LoadModule() (starlark.StringDict, error) {
+	once.Do(func() {
+		module = starlark.StringDict{
+			ModuleName: &starlarkstruct.Module{
+				Name: ModuleName,
+				Members: starlark.StringDict{
+					"forecast":  starlark.NewBuiltin("forecast", forecast),
+					"providers": starlark.NewBuiltin("providers", listProviders),
+				},
+			},
+		}
+	})
+
+	return module, nil
+}
+
+// Fetch returns the weather at place from the named provider, for the next
+// days days including today. Responses are cached for ttl.
+func Fetch(ctx context.Context, providerName string, place location.Place, days int, ttl time.Duration, appID string) (*Report, error) {
+	p, ok := providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q, expected one of %s", providerName, strings.Join(Providers(), ", "))
+	}
+	if days < 1 || days > MaxDays {
+		return nil, fmt.Errorf("days must be from 1 to %d, not %d", MaxDays, days)
+	}
+
+	// nearby places share cached responses, and the service isn't told
+	// exactly where the device is
+	place.Lat = math.Round(place.Lat*100) / 100
+	place.Lng = math.Round(place.Lng*100) / 100
+
+	// the National Weather Service answers with GeoJSON
+	f := &fetch.Fetcher{TTL: ttl, AppID: appID, Accept: "application/geo+json, application/json"}
+	r, err := p(ctx, f, place, days)
+	if err != nil {
+		return nil, fmt.Errorf("fetching weather from %s: %w", providerName, err)
+	}
+	if len(r.Daily) > days {
+		r.Daily = r.Daily[:days]
+	}
+	return r, nil
+}
+
+// unitSystem is what a report's values are converted to for apps.
+type unitSystem struct {
+	Name          string
+	Temp          func(float64) float64
+	Speed         func(float64) float64
+	Precipitation func(float64) float64
+
+	TempUnit, SpeedUnit, PrecipitationUnit string
+}
+
+func same(v float64) float64 { return v }
+
+var units = map[string]unitSystem{
+	"metric": {
+		Name:              "metric",
+		Temp:              same,
+		Speed:             same,
+		Precipitation:     same,
+		TempUnit:          "°C",
+		SpeedUnit:         "km/h",
+		PrecipitationUnit: "mm",
+	},
+	"imperial": {
+		Name:              "imperial",
+		Temp:              func(c float64) float64 { return c*9/5 + 32 },
+		Speed:             func(kmh float64) float64 { return kmh / 1.609344 },
+		Precipitation:     func(mm float64) float64 { return mm / 25.4 },
+		TempUnit:          "°F",
+		SpeedUnit:         "mph",
+		PrecipitationUnit: "in",
+	},
+}
+
+func forecast(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		loc          starlark.Value
+		providerName = DefaultProvider
+		unitsName    string
+		days         = 3
+		ttl          = int(DefaultTTL.Seconds())
+	)
+
+	if err := starlark.UnpackArgs(
+		"forecast",
+		args, kwargs,
+		"location", &loc,
+		"provider?", &providerName,
+		"units?", &unitsName,
+		"days?", &days,
+		"ttl_seconds?", &ttl,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for forecast: %s", err)
+	}
+
+	place, err := location.FromValue(loc)
+	if err != nil {
+		return nil, fmt.Errorf("forecast: %w", err)
+	}
+
+	if unitsName == "" {
+		unitsName = place.Units
+	}
+	u, ok := units[unitsName]
+	if !ok {
+		return nil, fmt.Errorf("forecast: units must be metric or imperial, not %q", unitsName)
+	}
+
+	appID := starlarkutil.AppID(thread)
+	r, err := Fetch(
+		fetch.Context(thread),
+		providerName, place, days,
+		time.Duration(ttl)*time.Second,
+		appID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("forecast: %w", err)
+	}
+
+	return reportValue(r, place, providerName, u), nil
+}
+
+func listProviders(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs("providers", args, kwargs); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for providers: %s", err)
+	}
+
+	var names []starlark.Value
+	for _, name := range Providers() {
+		names = append(names, starlark.String(name))
+	}
+	return starlark.NewList(names), nil
+}
+
+func reportValue(r *Report, place location.Place, providerName string, u unitSystem) starlark.Value {
+	c := r.Current
+	current := starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"time":           startime.Time(c.Time),
+		"temp":           number(c.Temp, u.Temp),
+		"feels_like":     number(c.FeelsLike, u.Temp),
+		"humidity":       number(c.Humidity, same),
+		"wind_speed":     number(c.WindSpeed, u.Speed),
+		"wind_direction": number(c.WindDirection, same),
+		"condition":      starlark.String(c.Condition),
+		"icon":           starlark.String(c.Condition.Icon(c.IsDay)),
+		"is_day":         starlark.Bool(c.IsDay),
+	})
+
+	daily := make([]starlark.Value, len(r.Daily))
+	for i, d := range r.Daily {
+		daily[i] = starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"date":                 starlark.String(d.Date),
+			"high":                 number(d.High, u.Temp),
+			"low":                  number(d.Low, u.Temp),
+			"precipitation_chance": number(d.PrecipitationChance, same),
+			"precipitation":        number(d.Precipitation, u.Precipitation),
+			"condition":            starlark.String(d.Condition),
+			"icon":                 starlark.String(d.Condition.Icon(true)),
+		})
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"provider":           starlark.String(providerName),
+		"timezone":           starlark.String(place.Timezone),
+		"units":              starlark.String(u.Name),
+		"temp_unit":          starlark.String(u.TempUnit),
+		"speed_unit":         starlark.String(u.SpeedUnit),
+		"precipitation_unit": starlark.String(u.PrecipitationUnit),
+		"current":            current,
+		"daily":              starlark.NewList(daily),
+	})
+}
+
+// number converts v, or returns None if it isn't known.
+func number(v float64, convert func(float64) float64) starlark.Value {
+	if math.IsNaN(v) {
+		return starlark.None
+	}
+	return starlark.Float(convert(v))
+}
+
+// orNaN returns *v, or NaN if it's nil, for values that services report as
+// null when they aren't known.
+func orNaN(v *float64) float64 {
+	if v == nil {
+		return math.NaN()
+	}
+	return *v
+}
@@ -0,0 +1,273 @@
+package weather
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/runtime/modules/internal/fetch/fetchtest"
+	"tidbyt.dev/pixlet/runtime/modules/location"
+)
+
+const openMeteoJSON = `{
+	"utc_offset_seconds": 7200,
+	"current": {
+		"time": "2026-10-17T14:15",
+		"temperature_2m": 12.5,
+		"apparent_temperature": 10.1,
+		"relative_humidity_2m": 71,
+		"wind_speed_10m": 18.4,
+		"wind_direction_10m": 240,
+		"weather_code": 61,
+		"is_day": 1
+	},
+	"daily": {
+		"time": ["2026-10-17", "2026-10-18"],
+		"weather_code": [61, 2],
+		"temperature_2m_max": [13.1, 15.0],
+		"temperature_2m_min": [7.2, 6.4],
+		"precipitation_probability_max": [80, null],
+		"precipitation_sum": [4.2, 0]
+	}
+}`
+
+const nwsHourlyJSON = `{"properties": {"periods": [
+	{
+		"startTime": "2026-10-17T14:00:00-04:00",
+		"isDaytime": true,
+		"temperature": 68,
+		"temperatureUnit": "F",
+		"relativeHumidity": {"value": 55},
+		"windSpeed": "10 mph",
+		"windDirection": "NW",
+		"icon": "https://api.weather.gov/icons/land/day/sct?size=small"
+	}
+]}}`
+
+const nwsDailyJSON = `{"properties": {"periods": [
+	{
+		"startTime": "2026-10-17T18:00:00-04:00",
+		"isDaytime": false,
+		"temperature": 50,
+		"temperatureUnit": "F",
+		"probabilityOfPrecipitation": {"value": 20},
+		"icon": "https://api.weather.gov/icons/land/night/rain,20?size=medium"
+	},
+	{
+		"startTime": "2026-10-18T06:00:00-04:00",
+		"isDaytime": true,
+		"temperature": 77,
+		"temperatureUnit": "F",
+		"probabilityOfPrecipitation": {"value": 60},
+		"icon": "https://api.weather.gov/icons/land/day/tsra_sct,60?size=medium"
+	},
+	{
+		"startTime": "2026-10-18T18:00:00-04:00",
+		"isDaytime": false,
+		"temperature": 59,
+		"temperatureUnit": "F",
+		"probabilityOfPrecipitation": {"value": null},
+		"icon": "https://api.weather.gov/icons/land/night/few?size=medium"
+	}
+]}}`
+
+const metNorwayJSON = `{"properties": {"timeseries": [
+	{
+		"time": "2026-10-17T10:00:00Z",
+		"data": {
+			"instant": {"details": {"air_temperature": 8.0, "relative_humidity": 90, "wind_speed": 5.0, "wind_from_direction": 180}},
+			"next_1_hours": {"summary": {"symbol_code": "lightrain"}, "details": {"precipitation_amount": 0.5}},
+			"next_6_hours": {"summary": {"symbol_code": "rain"}, "details": {"precipitation_amount": 3.0}}
+		}
+	},
+	{
+		"time": "2026-10-17T11:00:00Z",
+		"data": {
+			"instant": {"details": {"air_temperature": 9.5}},
+			"next_1_hours": {"summary": {"symbol_code": "rain"}, "details": {"precipitation_amount": 1.0}},
+			"next_6_hours": {"summary": {"symbol_code": "rain"}, "details": {"precipitation_amount": 2.0}}
+		}
+	},
+	{
+		"time": "2026-10-18T12:00:00Z",
+		"data": {
+			"instant": {"details": {"air_temperature": 4.0}},
+			"next_6_hours": {"summary": {"symbol_code": "clearsky_day"}, "details": {"precipitation_amount": 0.0}}
+		}
+	}
+]}}`
+
+// serve points every provider at a server that responds with the given
+// bodies by path, and records the requests it gets.
+func serve(t *testing.T, bodies map[string]string) *[]*http.Request {
+	return fetchtest.Serve(t, bodies, map[*string]string{
+		&openMeteoURL: "/open-meteo",
+		&nwsURL:       "/nws",
+		&metNorwayURL: "/met.no",
+	})
+}
+
+func TestOpenMeteo(t *testing.T) {
+	requests := serve(t, map[string]string{"/open-meteo": openMeteoJSON})
+
+	place := location.Resolve(59.913868, 10.752245, "")
+	r, err := Fetch(context.Background(), "open-meteo", place, 2, DefaultTTL, "forecast")
+	require.NoError(t, err)
+
+	// places are rounded, and responses cached as asked
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	assert.Equal(t, "59.91", req.URL.Query().Get("latitude"))
+	assert.Equal(t, "10.75", req.URL.Query().Get("longitude"))
+	assert.Equal(t, "Europe/Oslo", req.URL.Query().Get("timezone"))
+	assert.Equal(t, "900", req.Header.Get("X-Tidbyt-Cache-Seconds"))
+	assert.Equal(t, "forecast", req.Header.Get("X-Tidbyt-App"))
+
+	assert.Equal(t, time.Date(2026, 10, 17, 12, 15, 0, 0, time.UTC), r.Current.Time.UTC())
+	assert.Equal(t, 12.5, r.Current.Temp)
+	assert.Equal(t, 10.1, r.Current.FeelsLike)
+	assert.Equal(t, Rain, r.Current.Condition)
+	assert.True(t, r.Current.IsDay)
+
+	require.Len(t, r.Daily, 2)
+	assert.Equal(t, Day{
+		Date:                "2026-10-17",
+		High:                13.1,
+		Low:                 7.2,
+		PrecipitationChance: 80,
+		Precipitation:       4.2,
+		Condition:           Rain,
+	}, r.Daily[0])
+	assert.True(t, math.IsNaN(r.Daily[1].PrecipitationChance))
+	assert.Equal(t, PartlyCloudy, r.Daily[1].Condition)
+}
+
+func TestNWS(t *testing.T) {
+	serve(t, map[string]string{
+		"/nws/points/40.68,-73.94": `{"properties": {
+			"forecast": "$SERVER/nws/gridpoints/OKX/35,33/forecast",
+			"forecastHourly": "$SERVER/nws/gridpoints/OKX/35,33/forecast/hourly"
+		}}`,
+		"/nws/gridpoints/OKX/35,33/forecast":        nwsDailyJSON,
+		"/nws/gridpoints/OKX/35,33/forecast/hourly": nwsHourlyJSON,
+	})
+
+	place := location.Resolve(40.6781784, -73.9441579, "America/New_York")
+	r, err := Fetch(context.Background(), "nws", place, 3, DefaultTTL, "forecast")
+	require.NoError(t, err)
+
+	assert.InDelta(t, 20, r.Current.Temp, 0.01)
+	assert.True(t, math.IsNaN(r.Current.FeelsLike))
+	assert.Equal(t, 55.0, r.Current.Humidity)
+	assert.InDelta(t, 16.09, r.Current.WindSpeed, 0.01)
+	assert.Equal(t, 315.0, r.Current.WindDirection)
+	assert.Equal(t, PartlyCloudy, r.Current.Condition)
+
+	// the forecast starts tonight, so today only has a low
+	require.Len(t, r.Daily, 2)
+	assert.Equal(t, "2026-10-17", r.Daily[0].Date)
+	assert.True(t, math.IsNaN(r.Daily[0].High))
+	assert.InDelta(t, 10, r.Daily[0].Low, 0.01)
+	assert.Equal(t, Rain, r.Daily[0].Condition)
+	assert.Equal(t, 20.0, r.Daily[0].PrecipitationChance)
+
+	assert.Equal(t, "2026-10-18", r.Daily[1].Date)
+	assert.InDelta(t, 25, r.Daily[1].High, 0.01)
+	assert.InDelta(t, 15, r.Daily[1].Low, 0.01)
+	assert.Equal(t, Thunderstorm, r.Daily[1].Condition)
+	assert.Equal(t, 60.0, r.Daily[1].PrecipitationChance)
+
+	// places outside the United States aren't covered
+	_, err = Fetch(context.Background(), "nws", location.Resolve(48.2082, 16.3738, ""), 3, DefaultTTL, "forecast")
+	assert.ErrorContains(t, err, "only covers the United States")
+}
+
+func TestMetNorway(t *testing.T) {
+	requests := serve(t, map[string]string{"/met.no": metNorwayJSON})
+
+	place := location.Resolve(59.913868, 10.752245, "")
+	r, err := Fetch(context.Background(), "met.no", place, 3, DefaultTTL, "forecast")
+	require.NoError(t, err)
+
+	assert.Equal(t, "lat=59.91&lon=10.75", (*requests)[0].URL.RawQuery)
+	assert.Contains(t, (*requests)[0].Header.Get("User-Agent"), "pixlet")
+
+	assert.Equal(t, 8.0, r.Current.Temp)
+	assert.Equal(t, 18.0, r.Current.WindSpeed)
+	assert.Equal(t, Drizzle, r.Current.Condition)
+	// rain symbols don't say, but it's day in Oslo at noon
+	assert.True(t, r.Current.IsDay)
+
+	require.Len(t, r.Daily, 2)
+	assert.Equal(t, "2026-10-17", r.Daily[0].Date)
+	assert.Equal(t, 9.5, r.Daily[0].High)
+	assert.Equal(t, 8.0, r.Daily[0].Low)
+	assert.Equal(t, 1.5, r.Daily[0].Precipitation)
+	// 10:00 UTC is midday in Oslo
+	assert.Equal(t, Drizzle, r.Daily[0].Condition)
+	assert.True(t, math.IsNaN(r.Daily[0].PrecipitationChance))
+	assert.Equal(t, Clear, r.Daily[1].Condition)
+}
+
+func TestFetchInvalid(t *testing.T) {
+	place := location.Resolve(59.91, 10.75, "")
+
+	_, err := Fetch(context.Background(), "yr", place, 3, DefaultTTL, "forecast")
+	assert.ErrorContains(t, err, "met.no, nws, open-meteo")
+
+	_, err = Fetch(context.Background(), "open-meteo", place, 8, DefaultTTL, "forecast")
+	assert.Error(t, err)
+}
+
+func TestIcon(t *testing.T) {
+	assert.Equal(t, "clear-day", Clear.Icon(true))
+	assert.Equal(t, "partly-cloudy-night", PartlyCloudy.Icon(false))
+	assert.Equal(t, "rain", Drizzle.Icon(true))
+	assert.Equal(t, "sleet", FreezingRain.Icon(true))
+	assert.Equal(t, "thunderstorm", Thunderstorm.Icon(false))
+	assert.Equal(t, "cloudy", Unknown.Icon(true))
+}
+
+func TestForecastModule(t *testing.T) {
+	serve(t, map[string]string{"/open-meteo": openMeteoJSON})
+
+	src := `
+load("weather.star", "weather")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+assert(weather.providers() == ["met.no", "nws", "open-meteo"])
+
+# units follow the place, unless asked for
+brooklyn = weather.forecast({"lat": 40.6781784, "lng": -73.9441579}, days = 2)
+assert(brooklyn.units == "imperial")
+assert(brooklyn.temp_unit == "°F")
+assert(brooklyn.current.temp == 54.5)
+assert(brooklyn.current.icon == "rain")
+assert(brooklyn.timezone == "America/New_York")
+
+oslo = weather.forecast('{"lat": "59.91", "lng": "10.75"}', units = "metric", days = 2)
+assert(oslo.current.temp == 12.5)
+assert(oslo.current.condition == "rain")
+assert(len(oslo.daily) == 2)
+assert(oslo.daily[0].date == "2026-10-17")
+assert(oslo.daily[1].precipitation_chance == None)
+assert(oslo.daily[1].icon == "partly-cloudy-day")
+`
+	thread := &starlark.Thread{
+		Name: "forecast",
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return LoadModule()
+		},
+	}
+	_, err := starlark.ExecFile(thread, "forecast.star", src, nil)
+	require.NoError(t, err)
+}
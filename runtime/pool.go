@@ -0,0 +1,182 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"tidbyt.dev/pixlet/render"
+	"tidbyt.dev/pixlet/schema"
+)
+
+// AppletPool holds several preloaded instances of the same applet so that it
+// can be run concurrently. Instances are checked out for the duration of a
+// run and checked back in afterwards, which also bounds how many runs of the
+// applet execute at once.
+type AppletPool struct {
+	applets chan *Applet
+	size    int
+
+	// template is the first instance loaded. It's used for read-only
+	// metadata like the ID and schema, which are identical across instances.
+	template *Applet
+}
+
+// NewAppletPool preloads size instances of an applet by calling load
+// repeatedly. Sizes below one are treated as one.
+func NewAppletPool(size int, load func() (*Applet, error)) (*AppletPool, error) {
+	if size < 1 {
+		size = 1
+	}
+
+	p := &AppletPool{
+		applets: make(chan *Applet, size),
+		size:    size,
+	}
+
+	for i := 0; i < size; i++ {
+		app, err := load()
+		if err != nil {
+			return nil, err
+		}
+		if p.template == nil {
+			p.template = app
+		}
+		p.applets <- app
+	}
+
+	return p, nil
+}
+
+// NewAppletPoolFromFS creates a pool of size instances of the applet in fsys.
+func NewAppletPoolFromFS(size int, id string, fsys fs.FS, opts ...AppletOption) (*AppletPool, error) {
+	return NewAppletPool(size, func() (*Applet, error) {
+		return NewAppletFromFS(id, fsys, opts...)
+	})
+}
+
+// Size returns the number of instances in the pool.
+func (p *AppletPool) Size() int {
+	return p.size
+}
+
+// ID returns the ID of the pooled applet.
+func (p *AppletPool) ID() string {
+	return p.template.ID
+}
+
+// Applet returns one of the pooled instances for reading metadata such as
+// the schema. Don't run it; use Checkout for that.
+func (p *AppletPool) Applet() *Applet {
+	return p.template
+}
+
+// Checkout takes an instance out of the pool, waiting for one to be checked
+// in if they're all in use. It fails if ctx is done before an instance
+// becomes available. Every checked out instance must be returned with
+// Checkin.
+func (p *AppletPool) Checkout(ctx context.Context) (*Applet, error) {
+	select {
+	case app := <-p.applets:
+		return app, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for %s to become available: %w", p.template.ID, context.Cause(ctx))
+	}
+}
+
+// Checkin returns an instance to the pool.
+func (p *AppletPool) Checkin(app *Applet) {
+	p.applets <- app
+}
+
+// RunWithConfig checks out an instance, runs it, and checks it back in.
+// Config saved with an older version of the applet's schema is migrated
+// first, and config values are then validated against the schema.
+func (p *AppletPool) RunWithConfig(ctx context.Context, config map[string]string) ([]render.Root, error) {
+	app, err := p.Checkout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Checkin(app)
+
+	if config, err = prepareConfig(ctx, app, config); err != nil {
+		return nil, err
+	}
+	return app.RunWithConfig(ctx, config)
+}
+
+// Prefetch checks out an instance, calls its prefetch function with config
+// the way RunWithConfig calls main, and checks it back in. See
+// Applet.Prefetch.
+func (p *AppletPool) Prefetch(ctx context.Context, config map[string]string) error {
+	app, err := p.Checkout(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Checkin(app)
+
+	if config, err = prepareConfig(ctx, app, config); err != nil {
+		return err
+	}
+	return app.Prefetch(ctx, config)
+}
+
+// prepareConfig migrates and validates config for the applet's schema.
+func prepareConfig(ctx context.Context, app *Applet, config map[string]string) (map[string]string, error) {
+	s := app.Schema
+	if s == nil {
+		return config, nil
+	}
+
+	config, err := app.MigrateConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ValidateConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// MigrateConfig checks out an instance, migrates config with it, and checks
+// it back in. See Applet.MigrateConfig.
+func (p *AppletPool) MigrateConfig(ctx context.Context, config map[string]string) (map[string]string, error) {
+	app, err := p.Checkout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Checkin(app)
+
+	return app.MigrateConfig(ctx, config)
+}
+
+// ResolveSchema checks out an instance, resolves the schema for config, and
+// checks the instance back in. See Applet.ResolveSchema.
+func (p *AppletPool) ResolveSchema(ctx context.Context, config map[string]string) (*schema.Schema, error) {
+	app, err := p.Checkout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Checkin(app)
+
+	return app.ResolveSchema(ctx, config)
+}
+
+// CallSchemaHandler checks out an instance, calls the handler, and checks the
+// instance back in.
+func (p *AppletPool) CallSchemaHandler(ctx context.Context, handlerName, parameter string) (string, error) {
+	return p.CallSchemaHandlerPage(ctx, handlerName, parameter, "")
+}
+
+// CallSchemaHandlerPage is like CallSchemaHandler, but fetches the page of a
+// paginated handler's options at cursor. See Applet.CallSchemaHandlerPage.
+func (p *AppletPool) CallSchemaHandlerPage(ctx context.Context, handlerName, parameter, cursor string) (string, error) {
+	app, err := p.Checkout(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer p.Checkin(app)
+
+	return app.CallSchemaHandlerPage(ctx, handlerName, parameter, cursor)
+}
@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppletPoolConcurrentRuns(t *testing.T) {
+	src := `
+load("render.star", "render")
+
+def main(config):
+    return [render.Root(child = render.Box()) for _ in range(int(config.get("n")))]
+`
+	pool, err := NewAppletPool(4, func() (*Applet, error) {
+		return NewApplet("pool.star", []byte(src))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, pool.Size())
+	assert.Equal(t, "pool.star", pool.ID())
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			roots, err := pool.RunWithConfig(context.Background(), map[string]string{"n": fmt.Sprint(n)})
+			assert.NoError(t, err)
+			assert.Len(t, roots, n)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestAppletPoolCheckoutWaits(t *testing.T) {
+	pool, err := NewAppletPool(1, func() (*Applet, error) {
+		return NewApplet("pool.star", []byte("def main():\n    return []\n"))
+	})
+	require.NoError(t, err)
+
+	app, err := pool.Checkout(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = pool.Checkout(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	pool.Checkin(app)
+	app, err = pool.Checkout(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, app)
+}
+
+func TestAppletPoolLoadError(t *testing.T) {
+	_, err := NewAppletPool(2, func() (*Applet, error) {
+		return NewApplet("broken.star", []byte("this is not starlark"))
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+// PrefetchFunctionName is the name of the function apps can define next to
+// main to fetch their data ahead of being rendered.
+const PrefetchFunctionName = "prefetch"
+
+// HasPrefetch reports whether the applet defines a prefetch function.
+func (a *Applet) HasPrefetch() bool {
+	return a.prefetchFun != nil
+}
+
+// Prefetch calls the applet's prefetch function with config, if it has one.
+// It takes the config main does, and makes the HTTP requests main will, so
+// that hosts can call it ahead of a render and have the responses cached by
+// the time main asks for them. What it returns is ignored.
+func (a *Applet) Prefetch(ctx context.Context, config map[string]string) error {
+	if a.prefetchFun == nil {
+		return nil
+	}
+
+	var args starlark.Tuple
+	if a.prefetchFun.NumParams() > 0 {
+		args = starlark.Tuple{AppletConfig(config)}
+	}
+	if _, err := a.Call(ctx, a.prefetchFun, args...); err != nil {
+		return fmt.Errorf("prefetching: %w", err)
+	}
+	return nil
+}
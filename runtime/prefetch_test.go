@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var prefetchSource = `
+load("render.star", "render")
+
+def prefetch(config):
+    if config.get("who") != "Ada":
+        fail("prefetch didn't get the config: %s" % config.get("who"))
+
+def main(config):
+    return render.Root(child = render.Text(config.get("who")))
+`
+
+func TestPrefetch(t *testing.T) {
+	app, err := NewApplet("prefetch.star", []byte(prefetchSource))
+	require.NoError(t, err)
+	assert.True(t, app.HasPrefetch())
+
+	require.NoError(t, app.Prefetch(context.Background(), map[string]string{"who": "Ada"}))
+	assert.ErrorContains(t, app.Prefetch(context.Background(), map[string]string{"who": "Mallory"}), "prefetch didn't get the config")
+
+	pool, err := NewAppletPool(1, func() (*Applet, error) {
+		return NewApplet("prefetch.star", []byte(prefetchSource))
+	})
+	require.NoError(t, err)
+	require.NoError(t, pool.Prefetch(context.Background(), map[string]string{"who": "Ada"}))
+
+	// apps without a prefetch function have nothing to prefetch
+	app, err = NewApplet("hello.star", []byte(`
+load("render.star", "render")
+
+def main():
+    return render.Root(child = render.Text("hello"))
+`))
+	require.NoError(t, err)
+	assert.False(t, app.HasPrefetch())
+	assert.NoError(t, app.Prefetch(context.Background(), nil))
+}
@@ -0,0 +1,149 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// probeBuiltin is the builtin that instrumented source calls as it runs.
+const probeBuiltin = "__probe__"
+
+// Probe is told as each statement of an applet is about to run, for tools
+// such as coverage and debuggers.
+//
+// Source is instrumented as it's loaded: each simple statement is preceded
+// by a call to a probe, on the same line, and the conditions of if, elif and
+// while statements and the iterables of for loops are wrapped in one. Line
+// numbers in errors are unchanged, but columns on instrumented lines move.
+type Probe interface {
+	// Instrumented is called as a file is loaded, with the line of each of
+	// its probes.
+	Instrumented(file string, lines []int)
+
+	// Hit is called as a probe of a file is reached, in the thread that
+	// reached it. The frame of the statement is at depth 1 of the thread's
+	// call stack, under the probe's.
+	Hit(thread *starlark.Thread, file string, probe int)
+}
+
+// WithProbe instruments the applet's source as it's loaded to call p as
+// each of its statements is about to run.
+func WithProbe(p Probe) AppletOption {
+	return func(a *Applet) error {
+		a.probes = append(a.probes, p)
+		return nil
+	}
+}
+
+// instrument instruments the source of a file with probes, if the applet
+// has any, and declares the probe builtin.
+func (a *Applet) instrument(file string, src []byte, predeclared starlark.StringDict) []byte {
+	if len(a.probes) == 0 {
+		return src
+	}
+
+	src, lines := instrument(file, src)
+	for _, p := range a.probes {
+		p.Instrumented(file, lines)
+	}
+
+	prefix := a.ID + "/"
+	predeclared[probeBuiltin] = starlark.NewBuiltin(probeBuiltin, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var n int
+		var value starlark.Value = starlark.None
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &n, &value); err != nil {
+			return nil, err
+		}
+
+		file := strings.TrimPrefix(thread.CallFrame(1).Pos.Filename(), prefix)
+		for _, p := range a.probes {
+			p.Hit(thread, file, n)
+		}
+		return value, nil
+	})
+
+	return src
+}
+
+// instrument returns src with probes added, and the line of each. Source
+// that doesn't parse is returned as is, for executing it to report the
+// error.
+func instrument(file string, src []byte) ([]byte, []int) {
+	opts := &syntax.FileOptions{Set: true, Recursion: true}
+	f, err := opts.Parse(file, src, 0)
+	if err != nil {
+		return src, nil
+	}
+
+	type edit struct {
+		offset int
+		text   string
+	}
+	var edits []edit
+	var lines []int
+
+	lineStarts := []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	offset := func(pos syntax.Position) int {
+		o := lineStarts[pos.Line-1]
+		for col := int32(1); col < pos.Col; col++ {
+			_, size := utf8.DecodeRune(src[o:])
+			o += size
+		}
+		return o
+	}
+	probe := func(pos syntax.Position) int {
+		lines = append(lines, int(pos.Line))
+		return len(lines) - 1
+	}
+	wrap := func(pos syntax.Position, x syntax.Expr) {
+		start, end := x.Span()
+		edits = append(edits,
+			edit{offset(start), fmt.Sprintf("%s(%d, ", probeBuiltin, probe(pos))},
+			edit{offset(end), ")"},
+		)
+	}
+
+	var walk func(stmts []syntax.Stmt)
+	walk = func(stmts []syntax.Stmt) {
+		for _, stmt := range stmts {
+			switch s := stmt.(type) {
+			case *syntax.DefStmt:
+				walk(s.Body)
+			case *syntax.IfStmt:
+				wrap(s.If, s.Cond)
+				walk(s.True)
+				walk(s.False)
+			case *syntax.ForStmt:
+				wrap(s.For, s.X)
+				walk(s.Body)
+			case *syntax.WhileStmt:
+				wrap(s.While, s.Cond)
+				walk(s.Body)
+			case *syntax.LoadStmt:
+				// loads run before anything else in the file
+			default:
+				start, _ := stmt.Span()
+				edits = append(edits, edit{offset(start), fmt.Sprintf("%s(%d); ", probeBuiltin, probe(start))})
+			}
+		}
+	}
+	walk(f.Stmts)
+
+	// apply the edits from the end, so offsets stay valid
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].offset > edits[j].offset })
+	out := append([]byte{}, src...)
+	for _, e := range edits {
+		out = append(out[:e.offset], append([]byte(e.text), out[e.offset:]...)...)
+	}
+	return out, lines
+}
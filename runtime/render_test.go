@@ -9,6 +9,7 @@ import (
 	"image/color"
 	"image/png"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -63,6 +64,12 @@ assert(0 < t1.size()[0], "0 < t1.size()[0]")
 assert(0 < t1.size()[1], "0 < t1.size()[1]")
 assert(t1.frame_count() == 1, "t1.frame_count() == 1")
 
+# Text metrics
+assert(render.measure_text("foo", font = "6x13") == render.Text("foo", font = "6x13").size(), 'render.measure_text("foo", font = "6x13") == render.Text("foo", font = "6x13").size()')
+assert(render.measure_text("foo") == render.Text("foo").size(), 'render.measure_text("foo") == render.Text("foo").size()')
+assert(render.measure_text("foo bar")[0] > render.measure_text("foo")[0], 'render.measure_text("foo bar")[0] > render.measure_text("foo")[0]')
+assert(render.measure_text("foo", font = "tom-thumb")[1] < render.measure_text("foo", font = "6x13")[1], 'render.measure_text("foo", font = "tom-thumb")[1] < render.measure_text("foo", font = "6x13")[1]')
+
 # WrappedText
 tw = render.WrappedText(
     height = 16,
@@ -247,3 +254,79 @@ def main():
 	assert.Equal(t, bounds, actualIm.Bounds())
 	assert.Equal(t, blue, actualIm.At(12, 12))
 }
+
+func TestFitText(t *testing.T) {
+	const (
+		filename = "test_fit_text.star"
+		src      = `
+load("render.star", "render")
+t = render.FitText(
+	content = "Ada",
+	width = 64,
+	fonts = ["tb-8", "tom-thumb"],
+)
+def main():
+    return render.Root(child=t)
+`
+	)
+
+	app, err := NewApplet(filename, []byte(src))
+	require.NoError(t, err)
+
+	ft := app.Globals[filename]["t"]
+	require.IsType(t, &render_runtime.FitText{}, ft)
+
+	widget := ft.(*render_runtime.FitText).AsRenderWidget()
+	require.IsType(t, &render.FitText{}, widget)
+	assert.Equal(t, []string{"tb-8", "tom-thumb"}, widget.(*render.FitText).Fonts)
+	assert.Equal(t, "tb-8", widget.(*render.FitText).Font())
+
+	_, err = NewApplet("test_fit_text_fonts.star", []byte(`
+load("render.star", "render")
+t = render.FitText(content = "Ada", width = 64, fonts = [1])
+def main():
+    return render.Root(child=t)
+`))
+	assert.ErrorContains(t, err, "expected fonts to be a list of str")
+}
+
+func TestCountdown(t *testing.T) {
+	const (
+		filename = "test_countdown.star"
+		src      = `
+load("render.star", "render")
+load("time.star", "time")
+
+def main():
+    return render.Root(
+        delay = 100,
+        child = render.Countdown(
+            target_time = time.time(year = 2026, month = 10, day = 17, hour = 12, minute = 5),
+            delay = 100,
+            duration = 2,
+        ),
+    )
+`
+	)
+
+	app, err := NewApplet(filename, []byte(src))
+	require.NoError(t, err)
+
+	// without a start time, it counts down from the render time
+	at := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	roots, err := app.Run(WithRenderTime(context.Background(), at))
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+
+	countdown := roots[0].Child.(*render.Countdown)
+	assert.WithinDuration(t, at, countdown.Now, time.Second)
+	assert.Equal(t, 20, countdown.FrameCount())
+
+	_, err = NewApplet("test_countdown_target.star", []byte(`
+load("render.star", "render")
+c = render.Countdown(target_time = "noon")
+def main():
+    return render.Root(child=c)
+`))
+	assert.ErrorContains(t, err, "expected target_time to be a time.time")
+}
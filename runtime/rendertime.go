@@ -0,0 +1,31 @@
+package runtime
+
+import (
+	"context"
+	"time"
+
+	starlibtime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+)
+
+type renderTimeKey struct{}
+
+// WithRenderTime makes applets run with ctx see at as the time they start
+// running: time.now() returns at, plus however long the applet has run for.
+// It lets hosts render ahead of when the image is shown, or make up for a
+// device's clock being off, so that clocks show the minute they're shown in.
+func WithRenderTime(ctx context.Context, at time.Time) context.Context {
+	return context.WithValue(ctx, renderTimeKey{}, time.Until(at))
+}
+
+// attachRenderTime makes time.now() in t return the time set with
+// WithRenderTime, if ctx has one.
+func attachRenderTime(ctx context.Context, t *starlark.Thread) {
+	offset, ok := ctx.Value(renderTimeKey{}).(time.Duration)
+	if !ok {
+		return
+	}
+	starlibtime.SetNow(t, func() (time.Time, error) {
+		return time.Now().Add(offset), nil
+	})
+}
@@ -0,0 +1,167 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/render"
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+// RequestInfo is what a host knows about the render an applet is running
+// for, such as the device it's for, which apps read with the request
+// module. It lets apps adapt to where they're shown without asking users
+// for it in their schema.
+//
+// Hosts attach it to the context an applet is run with using
+// WithRequestInfo. Everything in it is optional.
+type RequestInfo struct {
+	// DeviceID is the ID of the device the render is for, on the host.
+	DeviceID string `json:"device_id,omitempty"`
+
+	// Width and Height are the size of the display in pixels. Zero means
+	// the size the applet is rendered at.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+
+	// Locale is the BCP 47 language tag of the device's user, such as
+	// "en-US" or "nb".
+	Locale string `json:"locale,omitempty"`
+
+	// Tier is the user's plan or tier with the host, such as "free" or
+	// "pro", for hosts that have them.
+	Tier string `json:"tier,omitempty"`
+
+	// Values are other metadata the host passes to apps.
+	Values map[string]string `json:"values,omitempty"`
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo attaches what's known about the render to ctx.
+func WithRequestInfo(ctx context.Context, r *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, r)
+}
+
+// requestInfo returns the request info attached to the thread's context,
+// or an empty one.
+func requestInfo(thread *starlark.Thread) *RequestInfo {
+	if r, ok := starlarkutil.ThreadContext(thread).Value(requestInfoKey{}).(*RequestInfo); ok && r != nil {
+		return r
+	}
+	return &RequestInfo{}
+}
+
+// get returns the value of a field of r by the name apps know it by, or of
+// one of its Values.
+func (r *RequestInfo) get(key string) (starlark.Value, bool) {
+	optional := func(s string) (starlark.Value, bool) {
+		return starlark.String(s), s != ""
+	}
+
+	switch key {
+	case "device_id":
+		return optional(r.DeviceID)
+	case "locale":
+		return optional(r.Locale)
+	case "tier":
+		return optional(r.Tier)
+	case "width":
+		width, _ := r.size()
+		return starlark.MakeInt(width), true
+	case "height":
+		_, height := r.size()
+		return starlark.MakeInt(height), true
+	}
+
+	v, ok := r.Values[key]
+	return starlark.String(v), ok
+}
+
+// size returns the size of the display, or else the size the applet is
+// rendered at.
+func (r *RequestInfo) size() (int, int) {
+	width, height := r.Width, r.Height
+	if width <= 0 {
+		width = render.FrameWidth
+	}
+	if height <= 0 {
+		height = render.FrameHeight
+	}
+	return width, height
+}
+
+var (
+	requestOnce   sync.Once
+	requestModule starlark.StringDict
+)
+
+func LoadRequestModule() (starlark.StringDict, error) {
+	requestOnce.Do(func() {
+		requestModule = starlark.StringDict{
+			"request": &starlarkstruct.Module{
+				Name: "request",
+				Members: starlark.StringDict{
+					"get":       starlark.NewBuiltin("get", requestGet),
+					"device_id": requestField("device_id"),
+					"locale":    requestField("locale"),
+					"tier":      requestField("tier"),
+					"size":      starlark.NewBuiltin("size", requestSize),
+				},
+			},
+		}
+	})
+
+	return requestModule, nil
+}
+
+func requestGet(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		key starlark.String
+		def starlark.Value = starlark.None
+	)
+
+	if err := starlark.UnpackArgs(
+		"get",
+		args, kwargs,
+		"key", &key,
+		"default?", &def,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for request.get: %v", err)
+	}
+
+	val, ok := requestInfo(thread).get(key.GoString())
+	if !ok {
+		return def, nil
+	}
+	return val, nil
+}
+
+// requestField returns a builtin that returns a field of the request info,
+// or None if the host didn't set it.
+func requestField(name string) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackArgs(name, args, kwargs); err != nil {
+			return nil, fmt.Errorf("unpacking arguments for request.%s: %v", name, err)
+		}
+
+		val, ok := requestInfo(thread).get(name)
+		if !ok {
+			return starlark.None, nil
+		}
+		return val, nil
+	})
+}
+
+func requestSize(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs("size", args, kwargs); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for request.size: %v", err)
+	}
+
+	width, height := requestInfo(thread).size()
+	return starlark.Tuple{starlark.MakeInt(width), starlark.MakeInt(height)}, nil
+}
@@ -0,0 +1,55 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestInfo(t *testing.T) {
+	src := `
+load("render.star", "render")
+load("request.star", "request")
+
+def main(config):
+    want = config.get("want")
+    got = "%s %s %s %s %s %s" % (
+        request.device_id(),
+        request.size(),
+        request.locale(),
+        request.tier(),
+        request.get("room", "nowhere"),
+        request.get("width"),
+    )
+    if got != want:
+        fail("got", got, "want", want)
+    return render.Root(child = render.Box())
+`
+	app, err := NewApplet("kitchen.star", []byte(src))
+	require.NoError(t, err)
+
+	// without request info, apps only know the size they're rendered at
+	_, err = app.RunWithConfig(context.Background(), map[string]string{
+		"want": "None (64, 32) None None nowhere 64",
+	})
+	require.NoError(t, err)
+
+	// as hosts such as pixlet serve take it in requests
+	var info RequestInfo
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"device_id": "kitchen",
+		"width": 128,
+		"height": 64,
+		"locale": "nb-NO",
+		"tier": "pro",
+		"values": {"room": "kitchen"}
+	}`), &info))
+
+	ctx := WithRequestInfo(context.Background(), &info)
+	_, err = app.RunWithConfig(ctx, map[string]string{
+		"want": "kitchen (128, 64) nb-NO pro kitchen 128",
+	})
+	require.NoError(t, err)
+}
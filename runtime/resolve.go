@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"go.starlark.net/starlark"
+
+	"tidbyt.dev/pixlet/schema"
+)
+
+// maxGeneratedDepth bounds how deeply Generated fields may generate further
+// Generated fields, so that handlers that generate themselves can't loop.
+const maxGeneratedDepth = 8
+
+// ResolveSchema returns the applet's schema as a config UI would show it for
+// config: every Generated field is replaced by the fields its handler
+// generates for the current value of its source field, or dropped if the
+// source isn't set. Generated fields inside generated fields are resolved
+// too. This saves frontends from calling Generated handlers themselves.
+func (app *Applet) ResolveSchema(ctx context.Context, config map[string]string) (*schema.Schema, error) {
+	if app.Schema == nil {
+		return &schema.Schema{Version: "1"}, nil
+	}
+
+	resolved := *app.Schema
+	resolved.Handlers = maps.Clone(app.Schema.Handlers)
+
+	fields, err := app.resolveFields(ctx, app.Schema.Fields, resolved.Handlers, config, 0)
+	if err != nil {
+		return nil, err
+	}
+	resolved.Fields = fields
+
+	return &resolved, nil
+}
+
+func (app *Applet) resolveFields(
+	ctx context.Context,
+	fields []schema.SchemaField,
+	handlers map[string]schema.SchemaHandler,
+	config map[string]string,
+	depth int,
+) ([]schema.SchemaField, error) {
+	if fields == nil {
+		return nil, nil
+	}
+
+	resolved := make([]schema.SchemaField, 0, len(fields))
+	for _, field := range fields {
+		if field.Type != "generated" {
+			if len(field.Fields) > 0 {
+				children, err := app.resolveFields(ctx, field.Fields, handlers, config, depth)
+				if err != nil {
+					return nil, err
+				}
+				field.Fields = children
+			}
+			resolved = append(resolved, field)
+			continue
+		}
+
+		param := config[field.Source]
+		if param == "" {
+			continue
+		}
+		if depth >= maxGeneratedDepth {
+			return nil, fmt.Errorf("field %q: generated fields are nested more than %d deep", field.ID, maxGeneratedDepth)
+		}
+
+		handler, ok := handlers[field.Handler]
+		if !ok {
+			return nil, fmt.Errorf("field %q: no exported handler named '%s'", field.ID, field.Handler)
+		}
+
+		val, err := app.Call(ctx, handler.Function, starlark.String(param))
+		if err != nil {
+			return nil, fmt.Errorf("calling schema handler %s: %v", field.Handler, err)
+		}
+
+		generated, err := schema.FromStarlark(val, app.Globals[app.schemaFile])
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", field.ID, err)
+		}
+		maps.Copy(handlers, generated.Handlers)
+
+		children, err := app.resolveFields(ctx, generated.Fields, handlers, config, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, children...)
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var resolveSource = `
+load("schema.star", "schema")
+
+def pet_options(kind):
+    if kind == "dog":
+        return [
+            schema.Toggle(id = "walks", name = "Walks", desc = "Show walks.", icon = "dog"),
+            schema.Generated(id = "breed_fields", source = "walks", handler = walk_options),
+        ]
+    return [
+        schema.Text(id = "name", name = "Name", desc = "Your cat's name.", icon = "cat"),
+    ]
+
+def walk_options(walks):
+    if walks != "true":
+        return []
+    return [
+        schema.Text(id = "walk_time", name = "Walk Time", desc = "When to walk.", icon = "clock"),
+    ]
+
+def get_schema():
+    return schema.Schema(
+        version = "1",
+        fields = [
+            schema.Dropdown(
+                id = "kind",
+                name = "Kind",
+                desc = "Your pet.",
+                icon = "paw",
+                default = "cat",
+                options = [
+                    schema.Option(display = "Cat", value = "cat"),
+                    schema.Option(display = "Dog", value = "dog"),
+                ],
+            ),
+            schema.Generated(id = "pet", source = "kind", handler = pet_options),
+        ],
+    )
+
+def main(config):
+    return []
+`
+
+func TestResolveSchema(t *testing.T) {
+	app, err := NewApplet("resolve.star", []byte(resolveSource))
+	require.NoError(t, err)
+
+	ids := func(config map[string]string) []string {
+		s, err := app.ResolveSchema(context.Background(), config)
+		require.NoError(t, err)
+		var ids []string
+		for _, f := range s.Fields {
+			ids = append(ids, f.ID)
+		}
+		return ids
+	}
+
+	// generated fields are dropped while their source isn't set
+	assert.Equal(t, []string{"kind"}, ids(map[string]string{}))
+
+	assert.Equal(t, []string{"kind", "name"}, ids(map[string]string{"kind": "cat"}))
+	assert.Equal(t, []string{"kind", "walks"}, ids(map[string]string{"kind": "dog"}))
+	assert.Equal(t, []string{"kind", "walks", "walk_time"}, ids(map[string]string{"kind": "dog", "walks": "true"}))
+
+	// the applet's own schema is left alone
+	assert.Len(t, app.Schema.Fields, 2)
+}
+
+func TestResolveSchemaLoop(t *testing.T) {
+	src := `
+load("schema.star", "schema")
+
+def again(v):
+    return [schema.Generated(id = "again", source = "seed", handler = again)]
+
+def get_schema():
+    return schema.Schema(
+        version = "1",
+        fields = [
+            schema.Text(id = "seed", name = "Seed", desc = "Seed.", icon = "seedling"),
+            schema.Generated(id = "gen", source = "seed", handler = again),
+        ],
+    )
+
+def main(config):
+    return []
+`
+	app, err := NewApplet("loop.star", []byte(src))
+	require.NoError(t, err)
+
+	_, err = app.ResolveSchema(context.Background(), map[string]string{"seed": "x"})
+	assert.ErrorContains(t, err, "nested")
+}
@@ -0,0 +1,219 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+// StateQuota is how many bytes of keys and values an app may keep in its
+// state.
+const StateQuota = 16 << 10
+
+// AppState is the durable state of one installation of an app, which it
+// reads and writes with the state module. Unlike the cache, state is never
+// evicted, so apps can keep streaks, high scores and what they last showed.
+//
+// Hosts load an installation's state into an AppState, attach it to the
+// context an applet is run with using WithAppState, and save its Values
+// afterwards if it Changed. Applets run without one share a state per app
+// that lasts as long as the process.
+type AppState struct {
+	mutex   sync.Mutex
+	values  map[string]string
+	changed bool
+}
+
+// NewAppState returns the state of an installation, starting with values,
+// which may be nil.
+func NewAppState(values map[string]string) *AppState {
+	return &AppState{values: maps.Clone(values)}
+}
+
+// Values returns a copy of the state's values.
+func (s *AppState) Values() map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return maps.Clone(s.values)
+}
+
+// Changed reports whether the applet has set or deleted values, so that the
+// state needs saving.
+func (s *AppState) Changed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.changed
+}
+
+func (s *AppState) get(key string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+func (s *AppState) set(key, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	size := len(key) + len(value)
+	for k, v := range s.values {
+		if k != key {
+			size += len(k) + len(v)
+		}
+	}
+	if size > StateQuota {
+		return fmt.Errorf("state would be %d bytes, over its quota of %d", size, StateQuota)
+	}
+
+	if s.values == nil {
+		s.values = map[string]string{}
+	}
+	if old, ok := s.values[key]; !ok || old != value {
+		s.values[key] = value
+		s.changed = true
+	}
+	return nil
+}
+
+func (s *AppState) delete(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.changed = true
+	}
+}
+
+func (s *AppState) keys() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return slices.Sorted(maps.Keys(s.values))
+}
+
+type appStateKey struct{}
+
+// WithAppState attaches the state of the installation being run to ctx.
+func WithAppState(ctx context.Context, s *AppState) context.Context {
+	return context.WithValue(ctx, appStateKey{}, s)
+}
+
+var (
+	processStatesMutex sync.Mutex
+	processStates      = map[string]*AppState{}
+)
+
+// appState returns the state attached to the thread's context, or else the
+// process's state for the app.
+func appState(thread *starlark.Thread) *AppState {
+	if s, ok := starlarkutil.ThreadContext(thread).Value(appStateKey{}).(*AppState); ok && s != nil {
+		return s
+	}
+
+	processStatesMutex.Lock()
+	defer processStatesMutex.Unlock()
+	s, ok := processStates[starlarkutil.AppID(thread)]
+	if !ok {
+		s = NewAppState(nil)
+		processStates[starlarkutil.AppID(thread)] = s
+	}
+	return s
+}
+
+var (
+	stateOnce   sync.Once
+	stateModule starlark.StringDict
+)
+
+func LoadStateModule() (starlark.StringDict, error) {
+	stateOnce.Do(func() {
+		stateModule = starlark.StringDict{
+			"state": &starlarkstruct.Module{
+				Name: "state",
+				Members: starlark.StringDict{
+					"get":    starlark.NewBuiltin("get", stateGet),
+					"set":    starlark.NewBuiltin("set", stateSet),
+					"delete": starlark.NewBuiltin("delete", stateDelete),
+					"keys":   starlark.NewBuiltin("keys", stateKeys),
+				},
+			},
+		}
+	})
+
+	return stateModule, nil
+}
+
+func stateGet(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		key starlark.String
+		def starlark.Value = starlark.None
+	)
+
+	if err := starlark.UnpackArgs(
+		"get",
+		args, kwargs,
+		"key", &key,
+		"default?", &def,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for state.get: %v", err)
+	}
+
+	val, ok := appState(thread).get(key.GoString())
+	if !ok {
+		return def, nil
+	}
+	return starlark.String(val), nil
+}
+
+func stateSet(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key, val starlark.String
+
+	if err := starlark.UnpackArgs(
+		"set",
+		args, kwargs,
+		"key", &key,
+		"value", &val,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for state.set: %v", err)
+	}
+
+	if err := appState(thread).set(key.GoString(), val.GoString()); err != nil {
+		return nil, fmt.Errorf("state.set: %w", err)
+	}
+	return starlark.None, nil
+}
+
+func stateDelete(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key starlark.String
+
+	if err := starlark.UnpackArgs(
+		"delete",
+		args, kwargs,
+		"key", &key,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for state.delete: %v", err)
+	}
+
+	appState(thread).delete(key.GoString())
+	return starlark.None, nil
+}
+
+func stateKeys(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs("keys", args, kwargs); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for state.keys: %v", err)
+	}
+
+	keys := appState(thread).keys()
+	list := make([]starlark.Value, len(keys))
+	for i, k := range keys {
+		list[i] = starlark.String(k)
+	}
+	return starlark.NewList(list), nil
+}
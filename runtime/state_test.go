@@ -0,0 +1,107 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	src := `
+load("render.star", "render")
+load("state.star", "state")
+
+def main():
+    if state.get("high_score") != None:
+        fail("state should start empty")
+    if state.get("high_score", "0") != "0":
+        fail("default not returned")
+
+    state.set("high_score", "42")
+    state.set("streak", "3")
+    state.set("last_seen", "")
+    state.delete("last_seen")
+    state.delete("never_set")
+
+    if state.keys() != ["high_score", "streak"]:
+        fail("unexpected keys %s" % state.keys())
+
+    return render.Root(child=render.Text(state.get("high_score")))
+`
+	app, err := NewApplet("scores.star", []byte(src))
+	require.NoError(t, err)
+
+	s := NewAppState(nil)
+	_, err = app.Run(WithAppState(context.Background(), s))
+	require.NoError(t, err)
+	assert.True(t, s.Changed())
+	assert.Equal(t, map[string]string{"high_score": "42", "streak": "3"}, s.Values())
+
+	// setting what's already there isn't a change
+	src = `
+load("render.star", "render")
+load("state.star", "state")
+
+def main():
+    state.set("high_score", state.get("high_score"))
+    return render.Root(child=render.Text(state.get("high_score")))
+`
+	app, err = NewApplet("scores.star", []byte(src))
+	require.NoError(t, err)
+	s = NewAppState(map[string]string{"high_score": "42"})
+	_, err = app.Run(WithAppState(context.Background(), s))
+	require.NoError(t, err)
+	assert.False(t, s.Changed())
+}
+
+func TestStateQuota(t *testing.T) {
+	src := `
+load("render.star", "render")
+load("state.star", "state")
+
+def main(config):
+    state.set(config.get("key"), config.get("value"))
+    return render.Root(child=render.Box())
+`
+	app, err := NewApplet("scores.star", []byte(src))
+	require.NoError(t, err)
+
+	s := NewAppState(nil)
+	ctx := WithAppState(context.Background(), s)
+	half := strings.Repeat("x", StateQuota/2)
+	_, err = app.RunWithConfig(ctx, map[string]string{"key": "a", "value": half})
+	require.NoError(t, err)
+
+	// replacing a value only counts the new one
+	_, err = app.RunWithConfig(ctx, map[string]string{"key": "a", "value": half})
+	require.NoError(t, err)
+
+	_, err = app.RunWithConfig(ctx, map[string]string{"key": "b", "value": half})
+	assert.ErrorContains(t, err, "over its quota")
+	assert.Equal(t, []string{"a"}, s.keys())
+}
+
+func TestStateWithoutHost(t *testing.T) {
+	src := `
+load("render.star", "render")
+load("state.star", "state")
+
+def main():
+    renders = int(state.get("renders", "0")) + 1
+    state.set("renders", str(renders))
+    return [render.Root(child=render.Box()) for _ in range(renders)]
+`
+	app, err := NewApplet("counter.star", []byte(src))
+	require.NoError(t, err)
+
+	// apps run without a state keep one for the life of the process
+	roots, err := app.Run(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, roots, 1)
+	roots, err = app.Run(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, roots, 2)
+}
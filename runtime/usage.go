@@ -0,0 +1,160 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// UsageStats describes the resources used by one or more applet executions.
+//
+// CPU time isn't measured directly, since applets run concurrently in the
+// same process. ExecutionSteps stands in for the CPU spent running
+// Starlark, and EncodeTime for that spent on the image.
+type UsageStats struct {
+	// WallTime is the time spent running Starlark, including time spent
+	// waiting on HTTP requests.
+	WallTime time.Duration `json:"wall_time_ns"`
+
+	// ExecutionSteps counts Starlark computation steps. Unlike wall time it
+	// doesn't depend on host load or network latency, so it's the best
+	// measure of the CPU an applet's code consumes.
+	ExecutionSteps uint64 `json:"execution_steps"`
+
+	// EncodeTime is the time spent painting the frames of the applet's
+	// output and encoding them.
+	EncodeTime time.Duration `json:"encode_time_ns"`
+
+	HTTPRequests      int64 `json:"http_requests"`
+	HTTPCacheHits     int64 `json:"http_cache_hits"`
+	BytesFetched      int64 `json:"bytes_fetched"`
+	CacheReads        int64 `json:"cache_reads"`
+	CacheWrites       int64 `json:"cache_writes"`
+	CacheBytesWritten int64 `json:"cache_bytes_written"`
+	OutputBytes       int64 `json:"output_bytes"`
+}
+
+func (s *UsageStats) add(o UsageStats) {
+	s.WallTime += o.WallTime
+	s.ExecutionSteps += o.ExecutionSteps
+	s.EncodeTime += o.EncodeTime
+	s.HTTPRequests += o.HTTPRequests
+	s.HTTPCacheHits += o.HTTPCacheHits
+	s.BytesFetched += o.BytesFetched
+	s.CacheReads += o.CacheReads
+	s.CacheWrites += o.CacheWrites
+	s.CacheBytesWritten += o.CacheBytesWritten
+	s.OutputBytes += o.OutputBytes
+}
+
+// UsageMeter accumulates the resources used while running an applet. Attach
+// one to the context passed to Applet.Run and friends with MeterUsage. All
+// methods are safe to call on a nil meter, which records nothing.
+type UsageMeter struct {
+	mutex sync.Mutex
+	stats UsageStats
+}
+
+type usageMeterKey struct{}
+
+// MeterUsage attaches a new UsageMeter to ctx.
+func MeterUsage(ctx context.Context) (context.Context, *UsageMeter) {
+	m := &UsageMeter{}
+	return context.WithValue(ctx, usageMeterKey{}, m), m
+}
+
+// UsageMeterFromContext returns the meter attached to ctx, or nil.
+func UsageMeterFromContext(ctx context.Context) *UsageMeter {
+	m, _ := ctx.Value(usageMeterKey{}).(*UsageMeter)
+	return m
+}
+
+// Stats returns a snapshot of the usage recorded so far.
+func (m *UsageMeter) Stats() UsageStats {
+	if m == nil {
+		return UsageStats{}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.stats
+}
+
+// AddOutputBytes records the size of the image an execution produced.
+func (m *UsageMeter) AddOutputBytes(n int) {
+	m.update(func(s *UsageStats) { s.OutputBytes += int64(n) })
+}
+
+// AddEncodeTime records time spent painting and encoding an execution's
+// frames.
+func (m *UsageMeter) AddEncodeTime(d time.Duration) {
+	m.update(func(s *UsageStats) { s.EncodeTime += d })
+}
+
+func (m *UsageMeter) update(fn func(*UsageStats)) {
+	if m == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	fn(&m.stats)
+}
+
+// AppUsage aggregates usage across all executions of an app.
+type AppUsage struct {
+	Executions  int64         `json:"executions"`
+	Errors      int64         `json:"errors"`
+	Total       UsageStats    `json:"total"`
+	MaxWallTime time.Duration `json:"max_wall_time_ns"`
+	LastRun     time.Time     `json:"last_run"`
+}
+
+// UsageReport aggregates usage per app, so hosts can find apps that are
+// expensive or misbehaving.
+type UsageReport struct {
+	mutex sync.Mutex
+	apps  map[string]*AppUsage
+}
+
+// DefaultUsageReport collects usage for applets rendered by pixlet's own
+// commands.
+var DefaultUsageReport = NewUsageReport()
+
+// NewUsageReport creates an empty report.
+func NewUsageReport() *UsageReport {
+	return &UsageReport{apps: map[string]*AppUsage{}}
+}
+
+// Record adds the usage of one execution of an app. Pass the error the
+// execution failed with, if any.
+func (r *UsageReport) Record(appID string, stats UsageStats, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	u, ok := r.apps[appID]
+	if !ok {
+		u = &AppUsage{}
+		r.apps[appID] = u
+	}
+
+	u.Executions++
+	if err != nil {
+		u.Errors++
+	}
+	u.Total.add(stats)
+	u.MaxWallTime = max(u.MaxWallTime, stats.WallTime)
+	u.LastRun = time.Now()
+}
+
+// Apps returns a snapshot of the aggregated usage, keyed by app ID.
+func (r *UsageReport) Apps() map[string]AppUsage {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	apps := make(map[string]AppUsage, len(r.apps))
+	for id, u := range r.apps {
+		apps[id] = *u
+	}
+	return apps
+}
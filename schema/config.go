@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ConfigVersionKey is the config key hosts store the schema's ConfigVersion
+// under when saving config, so that it can be migrated when the schema
+// changes. Field IDs can't contain "$", so it never clashes with a field.
+const ConfigVersionKey = "$config_version"
+
+// ConfigVersion returns the config version that config was saved with. Config
+// saved before the app's schema was versioned has version 0.
+func ConfigVersion(config map[string]string) (int, error) {
+	v, ok := config[ConfigVersionKey]
+	if !ok {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", ConfigVersionKey, v)
+	}
+	return n, nil
+}
+
+// ConfigError reports a config value that isn't valid for its field.
+type ConfigError struct {
+	FieldID string
+	Err     error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config for %q: %v", e.FieldID, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateConfig checks config values against the fields of the schema and
+// their constraints, returning a *ConfigError for the first invalid value.
+// Values for fields the schema doesn't know about are left alone, since apps
+// may read config that isn't exposed in their schema, and so are hidden
+// fields.
+func (s *Schema) ValidateConfig(config map[string]string) error {
+	return validateConfig(s.Fields, config)
+}
+
+func validateConfig(fields []SchemaField, config map[string]string) error {
+	for _, field := range fields {
+		// hidden fields aren't shown to users, so whatever value they
+		// last had is left as is
+		if visible, err := field.IsVisible(config); err != nil || !visible {
+			continue
+		}
+
+		if isFieldGroup(field) {
+			if err := validateConfig(field.Fields, config); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := validateValue(field, config[field.ID]); err != nil {
+			return &ConfigError{FieldID: field.ID, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// validateValue checks the config value of a single field. An empty value
+// means the field wasn't filled in, which is only an error if it's required.
+func validateValue(field SchemaField, value string) error {
+	if err := validateConstraints(field, value); err != nil || value == "" {
+		return err
+	}
+
+	switch field.Type {
+	case "date":
+		_, err := ParseDate(value, time.UTC)
+		return err
+	case "datetime":
+		_, err := ParseDateTime(value)
+		return err
+	case "timerange":
+		_, err := ParseTimeRange(value)
+		return err
+	case "duration":
+		return validateDuration(field, value)
+	case "list":
+		return validateList(field, value)
+	case "locations":
+		return validateLocations(value)
+	case "multiselect":
+		values, err := DecodeMultiSelect(value)
+		if err != nil {
+			return err
+		}
+		return validateMultiSelect(field, values)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"go.starlark.net/starlark"
+)
+
+// Date lets users pick a calendar day. It's serialized in config as
+// YYYY-MM-DD, without a time or timezone, so that the day doesn't shift when
+// the app renders in a different zone than the one it was configured in.
+type Date struct {
+	SchemaField
+}
+
+func newDate(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var (
+		id   starlark.String
+		name starlark.String
+		desc starlark.String
+		icon starlark.String
+		def  starlark.String
+	)
+
+	if err := starlark.UnpackArgs(
+		"Date",
+		args, kwargs,
+		"id", &id,
+		"name", &name,
+		"desc", &desc,
+		"icon", &icon,
+		"default?", &def,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for Date: %s", err)
+	}
+
+	s := &Date{}
+	s.SchemaField.Type = "date"
+	s.ID = id.GoString()
+	s.Name = name.GoString()
+	s.Description = desc.GoString()
+	s.Icon = icon.GoString()
+	s.Default = def.GoString()
+
+	if s.Default != "" {
+		if _, err := ParseDate(s.Default, time.UTC); err != nil {
+			return nil, fmt.Errorf("invalid default for Date: %s", err)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Date) AsSchemaField() SchemaField {
+	return s.SchemaField
+}
+
+func (s *Date) AttrNames() []string {
+	return []string{
+		"id", "name", "desc", "icon", "default",
+	}
+}
+
+func (s *Date) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "id":
+		return starlark.String(s.ID), nil
+
+	case "name":
+		return starlark.String(s.Name), nil
+
+	case "desc":
+		return starlark.String(s.Description), nil
+
+	case "icon":
+		return starlark.String(s.Icon), nil
+
+	case "default":
+		return starlark.String(s.Default), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (s *Date) String() string       { return "Date(...)" }
+func (s *Date) Type() string         { return "Date" }
+func (s *Date) Freeze()              {}
+func (s *Date) Truth() starlark.Bool { return true }
+
+func (s *Date) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(s, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
@@ -0,0 +1,62 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+var dateSource = `
+load("schema.star", "schema")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+d = schema.Date(
+	id = "birthday",
+	name = "Birthday",
+	desc = "The day to count down to.",
+	icon = "cakeCandles",
+	default = "2024-07-04",
+)
+
+assert(d.id == "birthday")
+assert(d.name == "Birthday")
+assert(d.desc == "The day to count down to.")
+assert(d.icon == "cakeCandles")
+assert(d.default == "2024-07-04")
+
+def main():
+	return []
+`
+
+func TestDate(t *testing.T) {
+	app, err := runtime.NewApplet("date.star", []byte(dateSource))
+	assert.NoError(t, err)
+
+	screens, err := app.Run(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, screens)
+}
+
+func TestDateBadDefault(t *testing.T) {
+	src := `
+load("schema.star", "schema")
+
+d = schema.Date(
+	id = "birthday",
+	name = "Birthday",
+	desc = "The day to count down to.",
+	icon = "cakeCandles",
+	default = "July 4th",
+)
+
+def main():
+	return []
+`
+	_, err := runtime.NewApplet("date.star", []byte(src))
+	assert.ErrorContains(t, err, "invalid default for Date")
+}
@@ -2,11 +2,18 @@ package schema
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/mitchellh/hashstructure/v2"
 	"go.starlark.net/starlark"
 )
 
+// DateFormat is how Date fields are serialized in config.
+const DateFormat = time.DateOnly
+
+// DateTime lets users pick a date and time. It's serialized in config as an
+// RFC 3339 timestamp. If the field has a timezone, the picker works in that
+// zone and the timestamp carries its offset.
 type DateTime struct {
 	SchemaField
 }
@@ -18,10 +25,12 @@ func newDateTime(
 	kwargs []starlark.Tuple,
 ) (starlark.Value, error) {
 	var (
-		id   starlark.String
-		name starlark.String
-		desc starlark.String
-		icon starlark.String
+		id       starlark.String
+		name     starlark.String
+		desc     starlark.String
+		icon     starlark.String
+		def      starlark.String
+		timezone starlark.String
 	)
 
 	if err := starlark.UnpackArgs(
@@ -31,6 +40,8 @@ func newDateTime(
 		"name", &name,
 		"desc", &desc,
 		"icon", &icon,
+		"default?", &def,
+		"timezone?", &timezone,
 	); err != nil {
 		return nil, fmt.Errorf("unpacking arguments for DateTime: %s", err)
 	}
@@ -41,17 +52,46 @@ func newDateTime(
 	s.Name = name.GoString()
 	s.Description = desc.GoString()
 	s.Icon = icon.GoString()
+	s.Default = def.GoString()
+	s.Timezone = timezone.GoString()
+
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return nil, fmt.Errorf("invalid timezone for DateTime: %s", err)
+	}
+	if s.Default != "" {
+		if _, err := ParseDateTime(s.Default); err != nil {
+			return nil, fmt.Errorf("invalid default for DateTime: %s", err)
+		}
+	}
 
 	return s, nil
 }
 
+// ParseDateTime parses a DateTime config value.
+func ParseDateTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected an RFC 3339 timestamp: %w", err)
+	}
+	return t, nil
+}
+
+// ParseDate parses a Date config value as midnight in loc.
+func ParseDate(value string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(DateFormat, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date formatted as YYYY-MM-DD: %w", err)
+	}
+	return t, nil
+}
+
 func (s *DateTime) AsSchemaField() SchemaField {
 	return s.SchemaField
 }
 
 func (s *DateTime) AttrNames() []string {
 	return []string{
-		"id", "name", "desc", "icon",
+		"id", "name", "desc", "icon", "default", "timezone",
 	}
 }
 
@@ -70,6 +110,12 @@ func (s *DateTime) Attr(name string) (starlark.Value, error) {
 	case "icon":
 		return starlark.String(s.Icon), nil
 
+	case "default":
+		return starlark.String(s.Default), nil
+
+	case "timezone":
+		return starlark.String(s.Timezone), nil
+
 	default:
 		return nil, nil
 	}
@@ -3,9 +3,12 @@ package schema_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
 )
 
 var dateTimeSource = `
@@ -22,6 +25,18 @@ t = schema.DateTime(
 	icon = "gear",
 )
 
+tz = schema.DateTime(
+	id = "kickoff",
+	name = "Kickoff",
+	desc = "When the game starts.",
+	icon = "futbol",
+	default = "2026-06-11T19:00:00-06:00",
+	timezone = "America/Mexico_City",
+)
+
+assert(tz.default == "2026-06-11T19:00:00-06:00")
+assert(tz.timezone == "America/Mexico_City")
+
 assert(t.id == "event_name")
 assert(t.name == "Event Name")
 assert(t.desc == "The time of the event.")
@@ -39,3 +54,54 @@ func TestDateTime(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, screens)
 }
+
+func TestDateTimeBadTimezone(t *testing.T) {
+	src := `
+load("schema.star", "schema")
+
+t = schema.DateTime(
+	id = "event_name",
+	name = "Event Name",
+	desc = "The time of the event.",
+	icon = "gear",
+	timezone = "Mars/Olympus_Mons",
+)
+
+def main():
+	return []
+`
+	_, err := runtime.NewApplet("date_time.star", []byte(src))
+	assert.ErrorContains(t, err, "invalid timezone")
+}
+
+func TestDateTimeValidateConfig(t *testing.T) {
+	s := &schema.Schema{
+		Version: "1",
+		Fields: []schema.SchemaField{
+			{Type: "datetime", ID: "when", Name: "When"},
+			{Type: "date", ID: "day", Name: "Day"},
+		},
+	}
+
+	assert.NoError(t, s.ValidateConfig(map[string]string{
+		"when": "2024-03-01T12:30:00.000Z",
+		"day":  "2024-03-01",
+	}))
+	assert.NoError(t, s.ValidateConfig(map[string]string{
+		"when": "2024-03-01T12:30:00+01:00",
+		"day":  "",
+	}))
+
+	assert.Error(t, s.ValidateConfig(map[string]string{"when": "next tuesday"}))
+	assert.Error(t, s.ValidateConfig(map[string]string{"day": "2024-03-01T12:30:00Z"}))
+	assert.Error(t, s.ValidateConfig(map[string]string{"day": "03/01/2024"}))
+}
+
+func TestParseDate(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	d, err := schema.ParseDate("2024-03-01", loc)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, loc), d)
+}
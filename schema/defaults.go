@@ -0,0 +1,30 @@
+package schema
+
+import "strconv"
+
+// DefaultConfig returns the config an app gets when every field is left at
+// its default, including fields nested in groups and sections. Fields without
+// a default are left out. If the schema has a config version, it's included
+// under ConfigVersionKey, so that hosts can save the result as the config of
+// a new install.
+func (s *Schema) DefaultConfig() map[string]string {
+	config := map[string]string{}
+	addDefaults(config, s.Fields)
+
+	if s.ConfigVersion > 0 {
+		config[ConfigVersionKey] = strconv.Itoa(s.ConfigVersion)
+	}
+	return config
+}
+
+func addDefaults(config map[string]string, fields []SchemaField) {
+	for _, field := range fields {
+		if isFieldGroup(field) {
+			addDefaults(config, field.Fields)
+			continue
+		}
+		if field.Default != "" {
+			config[field.ID] = field.Default
+		}
+	}
+}
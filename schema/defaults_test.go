@@ -0,0 +1,52 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	src := `
+load("schema.star", "schema")
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		config_version = 3,
+		fields = [
+			schema.Text(id = "who", name = "Who?", desc = "Who to greet.", icon = "user"),
+			schema.Toggle(id = "small", name = "Small", desc = "Small text.", icon = "compress", default = False),
+			schema.Section(
+				id = "display",
+				name = "Display",
+				desc = "Display options.",
+				icon = "display",
+				fields = [
+					schema.Color(id = "color", name = "Color", desc = "Text color.", icon = "brush", default = "#7AB0FF"),
+					schema.Duration(id = "refresh", name = "Refresh", desc = "How often to refresh.", icon = "clock", default = "15m"),
+				],
+			),
+		],
+	)
+
+def main():
+	return []
+`
+	app, err := runtime.NewApplet("defaults.star", []byte(src))
+	require.NoError(t, err)
+
+	config := app.Schema.DefaultConfig()
+	assert.Equal(t, map[string]string{
+		"small":                 "false",
+		"color":                 "#7ab0ff",
+		"refresh":               "15m0s",
+		schema.ConfigVersionKey: "3",
+	}, config)
+
+	assert.NoError(t, app.Schema.ValidateConfig(config))
+	assert.Equal(t, map[string]string{}, (&schema.Schema{Version: "1"}).DefaultConfig())
+}
@@ -0,0 +1,203 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/hashstructure/v2"
+	starlibtime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+)
+
+// DurationUnits are the units a Duration can be entered in, from smallest to
+// largest.
+var DurationUnits = []string{"seconds", "minutes", "hours"}
+
+var durationUnitSizes = map[string]time.Duration{
+	"seconds": time.Second,
+	"minutes": time.Minute,
+	"hours":   time.Hour,
+}
+
+// Duration lets users enter a length of time, such as a refresh interval or
+// the length of a countdown, in one of the field's units. It's serialized in
+// config in Go's canonical duration format, for example "1h30m0s", whichever
+// unit it was entered in.
+type Duration struct {
+	SchemaField
+	starlarkUnits *starlark.List
+}
+
+func newDuration(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var (
+		id    starlark.String
+		name  starlark.String
+		desc  starlark.String
+		icon  starlark.String
+		def   starlark.Value = starlark.None
+		units *starlark.List
+	)
+
+	if err := starlark.UnpackArgs(
+		"Duration",
+		args, kwargs,
+		"id", &id,
+		"name", &name,
+		"desc", &desc,
+		"icon", &icon,
+		"default?", &def,
+		"units?", &units,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for Duration: %s", err)
+	}
+
+	s := &Duration{}
+	s.SchemaField.Type = "duration"
+	s.ID = id.GoString()
+	s.Name = name.GoString()
+	s.Description = desc.GoString()
+	s.Icon = icon.GoString()
+
+	if units == nil {
+		values := make([]starlark.Value, 0, len(DurationUnits))
+		for _, u := range DurationUnits {
+			values = append(values, starlark.String(u))
+		}
+		units = starlark.NewList(values)
+	}
+	for i := 0; i < units.Len(); i++ {
+		u, ok := starlark.AsString(units.Index(i))
+		if !ok || durationUnitSizes[u] == 0 {
+			return nil, fmt.Errorf(
+				"expected units to be a list of %s but found: %s (at index %d)",
+				strings.Join(DurationUnits, ", "),
+				units.Index(i),
+				i,
+			)
+		}
+		if slices.Contains(s.Units, u) {
+			return nil, fmt.Errorf("unit %q listed more than once", u)
+		}
+		s.Units = append(s.Units, u)
+	}
+	if len(s.Units) == 0 {
+		return nil, fmt.Errorf("units must not be empty")
+	}
+	s.starlarkUnits = units
+
+	if def != starlark.None {
+		d, err := durationArg(def)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default for Duration: %s", err)
+		}
+		s.Default = EncodeDuration(d)
+		if err := validateDuration(s.SchemaField, s.Default); err != nil {
+			return nil, fmt.Errorf("invalid default for Duration: %s", err)
+		}
+	}
+
+	return s, nil
+}
+
+// durationArg converts a Starlark time.duration, or a string such as "15m",
+// to a duration.
+func durationArg(v starlark.Value) (time.Duration, error) {
+	switch v := v.(type) {
+	case starlibtime.Duration:
+		return time.Duration(v), nil
+	case starlark.String:
+		return time.ParseDuration(v.GoString())
+	default:
+		return 0, fmt.Errorf("expected a duration or a string, not %s", v.Type())
+	}
+}
+
+// EncodeDuration serializes a duration the way it's stored in config.
+func EncodeDuration(d time.Duration) string {
+	return d.String()
+}
+
+// ParseDuration parses a Duration config value.
+func ParseDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("expected a duration such as 15m0s, got %q", value)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration %s is negative", value)
+	}
+	return d, nil
+}
+
+// validateDuration checks that value is a duration that can be entered in
+// the field's smallest unit.
+func validateDuration(field SchemaField, value string) error {
+	d, err := ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range DurationUnits {
+		if !slices.Contains(field.Units, u) {
+			continue
+		}
+		if d%durationUnitSizes[u] != 0 {
+			return fmt.Errorf("duration %s is not a whole number of %s", value, u)
+		}
+		break
+	}
+	return nil
+}
+
+func (s *Duration) AsSchemaField() SchemaField {
+	return s.SchemaField
+}
+
+func (s *Duration) AttrNames() []string {
+	return []string{
+		"id", "name", "desc", "icon", "default", "units",
+	}
+}
+
+func (s *Duration) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "id":
+		return starlark.String(s.ID), nil
+
+	case "name":
+		return starlark.String(s.Name), nil
+
+	case "desc":
+		return starlark.String(s.Description), nil
+
+	case "icon":
+		return starlark.String(s.Icon), nil
+
+	case "default":
+		return starlark.String(s.Default), nil
+
+	case "units":
+		return s.starlarkUnits, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (s *Duration) String() string       { return "Duration(...)" }
+func (s *Duration) Type() string         { return "Duration" }
+func (s *Duration) Freeze()              {}
+func (s *Duration) Truth() starlark.Bool { return true }
+
+func (s *Duration) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(s, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
@@ -0,0 +1,123 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+)
+
+var durationSource = `
+load("schema.star", "schema")
+load("time.star", "time")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+d = schema.Duration(
+	id = "refresh",
+	name = "Refresh Interval",
+	desc = "How often to refresh.",
+	icon = "clock",
+	default = time.parse_duration("15m"),
+	units = ["minutes", "hours"],
+	min = "5m",
+	max = time.parse_duration("6h"),
+)
+
+assert(d.id == "refresh")
+assert(d.name == "Refresh Interval")
+assert(d.desc == "How often to refresh.")
+assert(d.icon == "clock")
+assert(d.default == "15m0s")
+assert(d.units == ["minutes", "hours"])
+
+countdown = schema.Duration(
+	id = "countdown",
+	name = "Countdown",
+	desc = "How long to count down from.",
+	icon = "hourglass",
+)
+
+assert(countdown.default == "")
+assert(countdown.units == ["seconds", "minutes", "hours"])
+
+def get_schema():
+	return schema.Schema(version = "1", fields = [d, countdown])
+
+def main(config):
+	assert(config.duration("refresh") == time.parse_duration("90m"))
+	assert(config.duration("countdown", time.parse_duration("1m")) == time.parse_duration("1m"))
+	assert(config.duration("missing") == None)
+	return []
+`
+
+func TestDuration(t *testing.T) {
+	app, err := runtime.NewApplet("duration.star", []byte(durationSource))
+	require.NoError(t, err)
+
+	screens, err := app.RunWithConfig(context.Background(), map[string]string{
+		"refresh": "1h30m0s",
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, screens)
+
+	field := app.Schema.Fields[0]
+	assert.Equal(t, "duration", field.Type)
+	assert.Equal(t, []string{"minutes", "hours"}, field.Units)
+	assert.Equal(t, 300.0, *field.Min)
+	assert.Equal(t, 21600.0, *field.Max)
+}
+
+func TestDurationBadArgs(t *testing.T) {
+	for name, args := range map[string]string{
+		"unit":         `units = ["days"]`,
+		"no units":     `units = []`,
+		"default":      `default = "soon"`,
+		"default unit": `default = "90s", units = ["minutes"]`,
+		"bound":        `min = 5`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := `
+load("schema.star", "schema")
+
+schema.Duration(id = "d", name = "D", desc = "D", icon = "clock", ` + args + `)
+
+def main():
+	return []
+`
+			_, err := runtime.NewApplet("duration.star", []byte(src))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDurationValidateConfig(t *testing.T) {
+	app, err := runtime.NewApplet("duration.star", []byte(durationSource))
+	require.NoError(t, err)
+	s := app.Schema
+
+	assert.NoError(t, s.ValidateConfig(map[string]string{"refresh": "15m0s"}))
+	assert.NoError(t, s.ValidateConfig(map[string]string{"refresh": "2h"}))
+	assert.NoError(t, s.ValidateConfig(map[string]string{"countdown": "45s"}))
+
+	assert.ErrorContains(t, s.ValidateConfig(map[string]string{"refresh": "10m30s"}), "whole number of minutes")
+	assert.ErrorContains(t, s.ValidateConfig(map[string]string{"refresh": "1m0s"}), "at least 5m0s")
+	assert.ErrorContains(t, s.ValidateConfig(map[string]string{"refresh": "7h"}), "at most 6h0m0s")
+	assert.Error(t, s.ValidateConfig(map[string]string{"refresh": "15"}))
+	assert.Error(t, s.ValidateConfig(map[string]string{"countdown": "-5s"}))
+}
+
+func TestEncodeDuration(t *testing.T) {
+	assert.Equal(t, "1h30m0s", schema.EncodeDuration(90*time.Minute))
+	assert.Equal(t, "45s", schema.EncodeDuration(45*time.Second))
+
+	d, err := schema.ParseDuration("1h30m0s")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+}
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+)
+
+// ConfigExportFormat is the version of the config export format that
+// NewConfigExport writes and DecodeConfigExport reads.
+const ConfigExportFormat = 1
+
+// ConfigExport is an app's config as a portable document, for moving it
+// from one host to another, such as from pixlet serve to a server.
+type ConfigExport struct {
+	Format int `json:"format"`
+
+	// AppID is the app the config is for, if known. Hosts refuse config
+	// exported from a different app.
+	AppID string `json:"app_id,omitempty"`
+
+	// ConfigVersion is the config version of the schema the config was
+	// saved with, so that the importing host can migrate it if its copy of
+	// the app is newer.
+	ConfigVersion int `json:"config_version"`
+
+	// Config is the config itself, without ConfigVersionKey.
+	Config map[string]string `json:"config"`
+}
+
+// NewConfigExport returns a document for config saved by the app with ID
+// appID, which may be empty if it isn't known.
+func NewConfigExport(appID string, config map[string]string) (*ConfigExport, error) {
+	version, err := ConfigVersion(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := maps.Clone(config)
+	if c == nil {
+		c = map[string]string{}
+	}
+	delete(c, ConfigVersionKey)
+
+	return &ConfigExport{
+		Format:        ConfigExportFormat,
+		AppID:         appID,
+		ConfigVersion: version,
+		Config:        c,
+	}, nil
+}
+
+// DecodeConfigExport reads a document written by NewConfigExport.
+func DecodeConfigExport(r io.Reader) (*ConfigExport, error) {
+	var e ConfigExport
+	if err := json.NewDecoder(r).Decode(&e); err != nil {
+		return nil, fmt.Errorf("decoding config export: %w", err)
+	}
+
+	if e.Format != ConfigExportFormat {
+		return nil, fmt.Errorf("unsupported config export format %d", e.Format)
+	}
+	if e.ConfigVersion < 0 {
+		return nil, fmt.Errorf("invalid config version %d", e.ConfigVersion)
+	}
+	if e.Config == nil {
+		return nil, fmt.Errorf("config export has no config")
+	}
+	return &e, nil
+}
+
+// CheckApp returns an error if the config was exported from an app other
+// than the one with ID appID.
+func (e *ConfigExport) CheckApp(appID string) error {
+	if e.AppID != "" && appID != "" && e.AppID != appID {
+		return fmt.Errorf("config is for app %q, not %q", e.AppID, appID)
+	}
+	return nil
+}
+
+// SavedConfig returns the config as hosts save it, with its config version
+// under ConfigVersionKey, ready to be migrated to the app's current schema.
+func (e *ConfigExport) SavedConfig() map[string]string {
+	config := maps.Clone(e.Config)
+	if config == nil {
+		config = map[string]string{}
+	}
+	delete(config, ConfigVersionKey)
+
+	if e.ConfigVersion > 0 {
+		config[ConfigVersionKey] = strconv.Itoa(e.ConfigVersion)
+	}
+	return config
+}
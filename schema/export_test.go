@@ -0,0 +1,68 @@
+package schema_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/schema"
+)
+
+func TestConfigExportRoundTrip(t *testing.T) {
+	saved := map[string]string{
+		"who":                   "Ada",
+		schema.ConfigVersionKey: "2",
+	}
+
+	e, err := schema.NewConfigExport("greeting", saved)
+	require.NoError(t, err)
+	assert.Equal(t, 2, e.ConfigVersion)
+	assert.Equal(t, map[string]string{"who": "Ada"}, e.Config)
+
+	var buf bytes.Buffer
+	require.NoError(t, json.NewEncoder(&buf).Encode(e))
+
+	imported, err := schema.DecodeConfigExport(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "greeting", imported.AppID)
+	assert.Equal(t, saved, imported.SavedConfig())
+}
+
+func TestConfigExportUnversioned(t *testing.T) {
+	e, err := schema.NewConfigExport("", map[string]string{"who": "Grace"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, e.ConfigVersion)
+
+	// config from before the schema was versioned is saved without one
+	assert.Equal(t, map[string]string{"who": "Grace"}, e.SavedConfig())
+
+	_, err = schema.NewConfigExport("", map[string]string{schema.ConfigVersionKey: "x"})
+	assert.Error(t, err)
+}
+
+func TestDecodeConfigExportInvalid(t *testing.T) {
+	for name, doc := range map[string]string{
+		"not json":       `config`,
+		"unknown format": `{"format": 2, "config": {}}`,
+		"no config":      `{"format": 1}`,
+		"bad version":    `{"format": 1, "config_version": -1, "config": {}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := schema.DecodeConfigExport(strings.NewReader(doc))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConfigExportCheckApp(t *testing.T) {
+	e := &schema.ConfigExport{AppID: "greeting"}
+	assert.NoError(t, e.CheckApp("greeting"))
+	assert.NoError(t, e.CheckApp(""))
+	assert.Error(t, e.CheckApp("clock"))
+
+	e.AppID = ""
+	assert.NoError(t, e.CheckApp("clock"))
+}
@@ -0,0 +1,217 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// asSchemaField is promoted to every field type embedding SchemaField, which
+// lets the schema module set options shared by all of them.
+func (f *SchemaField) asSchemaField() *SchemaField {
+	return f
+}
+
+// withFieldOptions wraps a field constructor so that it accepts the keyword
+// arguments shared by all fields in addition to its own: visible_if, required,
+// pattern, min, max and translations.
+func withFieldOptions(
+	fn func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error),
+) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(
+		thread *starlark.Thread,
+		b *starlark.Builtin,
+		args starlark.Tuple,
+		kwargs []starlark.Tuple,
+	) (starlark.Value, error) {
+		var (
+			visibleIf starlark.String
+			required  starlark.Bool
+			pattern   starlark.String
+			min       starlark.Value = starlark.None
+			max       starlark.Value = starlark.None
+
+			translations *starlark.Dict
+		)
+
+		var own, shared []starlark.Tuple
+		for _, kv := range kwargs {
+			switch string(kv[0].(starlark.String)) {
+			case "visible_if", "required", "pattern", "min", "max", "translations":
+				shared = append(shared, kv)
+			default:
+				own = append(own, kv)
+			}
+		}
+
+		if err := starlark.UnpackArgs(
+			b.Name(),
+			nil, shared,
+			"visible_if?", &visibleIf,
+			"required?", &required,
+			"pattern?", &pattern,
+			"min?", &min,
+			"max?", &max,
+			"translations?", &translations,
+		); err != nil {
+			return nil, fmt.Errorf("unpacking arguments for %s: %s", b.Name(), err)
+		}
+
+		val, err := fn(thread, b, args, own)
+		if err != nil || len(shared) == 0 {
+			return val, err
+		}
+
+		f, ok := val.(interface{ asSchemaField() *SchemaField })
+		if !ok {
+			return nil, fmt.Errorf("%s doesn't support %s", b.Name(), shared[0][0])
+		}
+		field := f.asSchemaField()
+
+		if visibleIf != "" {
+			if _, err := ParseVisibleIf(visibleIf.GoString()); err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+			field.VisibleIf = visibleIf.GoString()
+		}
+
+		field.Required = bool(required)
+
+		if pattern != "" {
+			if field.Type != "text" {
+				return nil, fmt.Errorf("%s: pattern is only supported on Text fields", b.Name())
+			}
+			if _, err := regexp.Compile(pattern.GoString()); err != nil {
+				return nil, fmt.Errorf("%s: invalid pattern: %s", b.Name(), err)
+			}
+			field.Pattern = pattern.GoString()
+		}
+
+		if field.Min, err = boundArg(b.Name(), "min", min, *field); err != nil {
+			return nil, err
+		}
+		if field.Max, err = boundArg(b.Name(), "max", max, *field); err != nil {
+			return nil, err
+		}
+		if field.Min != nil && field.Max != nil && *field.Min > *field.Max {
+			return nil, fmt.Errorf("%s: min is greater than max", b.Name())
+		}
+
+		if translations != nil {
+			if field.Translations, err = fieldTranslationsArg(translations); err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+		}
+
+		return val, nil
+	}
+}
+
+// boundArg converts a min or max argument. They're supported on fields where
+// it's clear what they limit: the number entered in a Text field, the number
+// of options picked in a MultiSelect, the number of items in a List or
+// Locations and the length of a Duration, which is bounded in seconds.
+func boundArg(builtinName, name string, v starlark.Value, field SchemaField) (*float64, error) {
+	if v == starlark.None {
+		return nil, nil
+	}
+
+	switch field.Type {
+	case "text", "multiselect", "list", "locations":
+	case "duration":
+		d, err := durationArg(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %s", builtinName, name, err)
+		}
+		f := d.Seconds()
+		return &f, nil
+	default:
+		return nil, fmt.Errorf("%s: %s is only supported on Text, MultiSelect, List, Locations and Duration fields", builtinName, name)
+	}
+
+	f, ok := starlark.AsFloat(v)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s must be a number, not %s", builtinName, name, v.Type())
+	}
+	return &f, nil
+}
+
+// validateConstraints checks a config value against the required, pattern,
+// min and max constraints of field. Values that are empty only fail if the
+// field is required.
+func validateConstraints(field SchemaField, value string) error {
+	if value == "" {
+		if field.Required {
+			return fmt.Errorf("a value is required")
+		}
+		return nil
+	}
+
+	if field.Pattern != "" {
+		re, err := regexp.Compile(`^(?:` + field.Pattern + `)$`)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("%q doesn't match the pattern %s", value, field.Pattern)
+		}
+	}
+
+	if field.Min == nil && field.Max == nil {
+		return nil
+	}
+
+	var n float64
+	var what string
+	switch field.Type {
+	case "text":
+		var err error
+		if n, err = strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		what = "value"
+	case "multiselect":
+		values, err := DecodeMultiSelect(value)
+		if err != nil {
+			return err
+		}
+		n, what = float64(len(values)), "number of selected options"
+	case "list":
+		items, err := DecodeList(value)
+		if err != nil {
+			return err
+		}
+		n, what = float64(len(items)), "number of items"
+	case "locations":
+		locations, err := DecodeLocations(value)
+		if err != nil {
+			return err
+		}
+		n, what = float64(len(locations)), "number of locations"
+	case "duration":
+		d, err := ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if field.Min != nil && d.Seconds() < *field.Min {
+			return fmt.Errorf("duration is %s, but must be at least %s", d, time.Duration(*field.Min*float64(time.Second)))
+		}
+		if field.Max != nil && d.Seconds() > *field.Max {
+			return fmt.Errorf("duration is %s, but must be at most %s", d, time.Duration(*field.Max*float64(time.Second)))
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	if field.Min != nil && n < *field.Min {
+		return fmt.Errorf("%s is %v, but must be at least %v", what, n, *field.Min)
+	}
+	if field.Max != nil && n > *field.Max {
+		return fmt.Errorf("%s is %v, but must be at most %v", what, n, *field.Max)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package schema_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+)
+
+var constraintsSource = `
+load("schema.star", "schema")
+
+def main(config):
+	return []
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [
+			schema.Text(
+				id = "zip",
+				name = "ZIP Code",
+				desc = "Where you live.",
+				icon = "house",
+				required = True,
+				pattern = "[0-9]{5}",
+			),
+			schema.Text(
+				id = "count",
+				name = "Count",
+				desc = "How many to show.",
+				icon = "hashtag",
+				min = 1,
+				max = 10,
+			),
+			schema.MultiSelect(
+				id = "lines",
+				name = "Lines",
+				desc = "The lines to show.",
+				icon = "train",
+				max = 2,
+				options = [
+					schema.Option(display = "Red", value = "red"),
+					schema.Option(display = "Blue", value = "blue"),
+					schema.Option(display = "Green", value = "green"),
+				],
+			),
+		],
+	)
+`
+
+func TestFieldConstraints(t *testing.T) {
+	app, err := runtime.NewApplet("constraints.star", []byte(constraintsSource))
+	require.NoError(t, err)
+	s := app.Schema
+
+	assert.True(t, s.Fields[0].Required)
+	assert.Equal(t, "[0-9]{5}", s.Fields[0].Pattern)
+	assert.Equal(t, 1.0, *s.Fields[1].Min)
+	assert.Equal(t, 10.0, *s.Fields[1].Max)
+
+	assert.NoError(t, s.ValidateConfig(map[string]string{"zip": "10001"}))
+	assert.NoError(t, s.ValidateConfig(map[string]string{"zip": "10001", "count": "10", "lines": `["red", "blue"]`}))
+
+	for _, config := range []map[string]string{
+		{},
+		{"zip": ""},
+		{"zip": "1000"},
+		{"zip": "100010"},
+		{"zip": "10001", "count": "0"},
+		{"zip": "10001", "count": "11"},
+		{"zip": "10001", "count": "a few"},
+		{"zip": "10001", "lines": `["red", "blue", "green"]`},
+	} {
+		err := s.ValidateConfig(config)
+		assert.Error(t, err, "%v", config)
+
+		var configErr *schema.ConfigError
+		assert.True(t, errors.As(err, &configErr), "%v", config)
+	}
+
+	err = s.ValidateConfig(map[string]string{"zip": "nope"})
+	var configErr *schema.ConfigError
+	require.True(t, errors.As(err, &configErr))
+	assert.Equal(t, "zip", configErr.FieldID)
+}
+
+func TestFieldConstraintsErrors(t *testing.T) {
+	for name, field := range map[string]string{
+		"bad pattern":     `schema.Text(id = "t", name = "T", desc = "T", icon = "t", pattern = "[")`,
+		"pattern on date": `schema.Date(id = "d", name = "D", desc = "D", icon = "d", pattern = ".*")`,
+		"min on toggle":   `schema.Toggle(id = "t", name = "T", desc = "T", icon = "t", min = 1)`,
+		"min over max":    `schema.Text(id = "t", name = "T", desc = "T", icon = "t", min = 2, max = 1)`,
+		"string max":      `schema.Text(id = "t", name = "T", desc = "T", icon = "t", max = "10")`,
+	} {
+		src := `
+load("schema.star", "schema")
+
+f = ` + field + `
+
+def main():
+	return []
+`
+		_, err := runtime.NewApplet("constraints.star", []byte(src))
+		assert.Error(t, err, name)
+	}
+}
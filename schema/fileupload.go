@@ -0,0 +1,278 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"maps"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"go.starlark.net/starlark"
+	"golang.org/x/image/draw"
+)
+
+// DefaultMaxUploadSize is the largest file, in bytes, that a FileUpload field
+// accepts unless it sets max_size.
+const DefaultMaxUploadSize = 256 << 10
+
+// maxUploadPixels bounds the size of uploaded images that are decoded for
+// downscaling, since a small file can still hold a huge image.
+const maxUploadPixels = 4096 * 4096
+
+// FileUpload lets users upload a small file, such as a logo or a CSV of
+// birthdays. The file is passed to the applet as a base64 encoded string.
+// Uploaded images larger than the display are downscaled to fit when they're
+// saved, see PrepareUpload.
+type FileUpload struct {
+	SchemaField
+	starlarkAccept *starlark.List
+}
+
+func newFileUpload(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var (
+		id      starlark.String
+		name    starlark.String
+		desc    starlark.String
+		icon    starlark.String
+		accept  *starlark.List
+		maxSize = starlark.MakeInt(DefaultMaxUploadSize)
+	)
+
+	if err := starlark.UnpackArgs(
+		"FileUpload",
+		args, kwargs,
+		"id", &id,
+		"name", &name,
+		"desc", &desc,
+		"icon", &icon,
+		"accept?", &accept,
+		"max_size?", &maxSize,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for FileUpload: %s", err)
+	}
+
+	s := &FileUpload{}
+	s.SchemaField.Type = "file"
+	s.ID = id.GoString()
+	s.Name = name.GoString()
+	s.Description = desc.GoString()
+	s.Icon = icon.GoString()
+
+	size, ok := maxSize.Int64()
+	if !ok || size <= 0 {
+		return nil, fmt.Errorf("max_size must be a positive integer")
+	}
+	s.MaxSize = int(size)
+
+	if accept == nil {
+		accept = starlark.NewList(nil)
+	}
+	for i := 0; i < accept.Len(); i++ {
+		t, ok := starlark.AsString(accept.Index(i))
+		if !ok {
+			return nil, fmt.Errorf(
+				"expected accept to be a list of strings but found: %s (at index %d)",
+				accept.Index(i).Type(),
+				i,
+			)
+		}
+		if _, _, err := mime.ParseMediaType(t); err != nil || !strings.Contains(t, "/") {
+			return nil, fmt.Errorf("accept: %q is not a media type (at index %d)", t, i)
+		}
+		s.Accept = append(s.Accept, t)
+	}
+	s.starlarkAccept = accept
+
+	return s, nil
+}
+
+// PrepareUpload checks a file uploaded to a FileUpload field and scales it
+// down to fit within width x height if it's a larger image. The file must be
+// base64 encoded, no larger than the field allows, and of a type the field
+// accepts. Types are sniffed from the content rather than trusted from the
+// browser.
+//
+// Uploads are prepared once, as they're saved, rather than before every
+// render.
+func PrepareUpload(field SchemaField, value string, width, height int) (string, error) {
+	if field.Type != "file" {
+		return "", fmt.Errorf("field %q is not a FileUpload", field.ID)
+	}
+	if value == "" {
+		return value, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("expected base64 encoded file: %w", err)
+	}
+	if err := checkUpload(field, data); err != nil {
+		return "", err
+	}
+
+	scaled, err := downscaleImage(data, width, height)
+	if err != nil || scaled == nil {
+		return value, err
+	}
+	return base64.StdEncoding.EncodeToString(scaled), nil
+}
+
+// PrepareUploads returns a copy of config with PrepareUpload applied to the
+// value of every FileUpload field, or the first error as a *ConfigError.
+// Other values are left as they are.
+func (s *Schema) PrepareUploads(config map[string]string, width, height int) (map[string]string, error) {
+	var out map[string]string
+
+	var walk func([]SchemaField) error
+	walk = func(fields []SchemaField) error {
+		for _, field := range fields {
+			if isFieldGroup(field) {
+				if err := walk(field.Fields); err != nil {
+					return err
+				}
+				continue
+			}
+			if field.Type != "file" || config[field.ID] == "" {
+				continue
+			}
+
+			prepared, err := PrepareUpload(field, config[field.ID], width, height)
+			if err != nil {
+				return &ConfigError{FieldID: field.ID, Err: err}
+			}
+			if prepared == config[field.ID] {
+				continue
+			}
+
+			if out == nil {
+				out = maps.Clone(config)
+			}
+			out[field.ID] = prepared
+		}
+		return nil
+	}
+
+	if err := walk(s.Fields); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		return config, nil
+	}
+	return out, nil
+}
+
+// checkUpload checks that data is no larger than field allows, and of a type
+// it accepts.
+func checkUpload(field SchemaField, data []byte) error {
+	maxSize := field.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxUploadSize
+	}
+	if len(data) > maxSize {
+		return fmt.Errorf("file is %d bytes, but at most %d are allowed", len(data), maxSize)
+	}
+
+	if len(field.Accept) == 0 {
+		return nil
+	}
+
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	for _, a := range field.Accept {
+		a, _, _ = mime.ParseMediaType(a)
+		if a == detected || (strings.HasSuffix(a, "/*") && strings.HasPrefix(detected, strings.TrimSuffix(a, "*"))) {
+			return nil
+		}
+	}
+	return fmt.Errorf("file type %s is not one of %s", detected, strings.Join(field.Accept, ", "))
+}
+
+// downscaleImage returns data scaled down to fit within width x height and
+// re-encoded as PNG, or nil if it isn't an image larger than that. GIFs are
+// left alone, since scaling them would drop all but their first frame.
+func downscaleImage(data []byte, width, height int) ([]byte, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format == "gif" || (cfg.Width <= width && cfg.Height <= height) {
+		// not an image we can scale, or small enough already
+		return nil, nil
+	}
+
+	if cfg.Width*cfg.Height > maxUploadPixels {
+		return nil, fmt.Errorf("image is %dx%d, which is too large", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+
+	w, h := width, cfg.Height*width/cfg.Width
+	if h > height {
+		w, h = cfg.Width*height/cfg.Height, height
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("encoding image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *FileUpload) AsSchemaField() SchemaField {
+	return s.SchemaField
+}
+
+func (s *FileUpload) AttrNames() []string {
+	return []string{
+		"id", "name", "desc", "icon", "accept", "max_size",
+	}
+}
+
+func (s *FileUpload) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "id":
+		return starlark.String(s.ID), nil
+
+	case "name":
+		return starlark.String(s.Name), nil
+
+	case "desc":
+		return starlark.String(s.Description), nil
+
+	case "icon":
+		return starlark.String(s.Icon), nil
+
+	case "accept":
+		return s.starlarkAccept, nil
+
+	case "max_size":
+		return starlark.MakeInt(s.MaxSize), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (s *FileUpload) String() string       { return "FileUpload(...)" }
+func (s *FileUpload) Type() string         { return "FileUpload" }
+func (s *FileUpload) Freeze()              {}
+func (s *FileUpload) Truth() starlark.Bool { return true }
+
+func (s *FileUpload) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(s, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
@@ -0,0 +1,187 @@
+package schema_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+)
+
+var fileUploadSource = `
+load("schema.star", "schema")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+f = schema.FileUpload(
+	id = "logo",
+	name = "Logo",
+	desc = "A logo to display.",
+	icon = "image",
+	accept = ["image/*"],
+	max_size = 1024,
+)
+
+assert(f.id == "logo")
+assert(f.name == "Logo")
+assert(f.desc == "A logo to display.")
+assert(f.icon == "image")
+assert(f.accept == ["image/*"])
+assert(f.max_size == 1024)
+
+csv = schema.FileUpload(
+	id = "birthdays",
+	name = "Birthdays",
+	desc = "A CSV of birthdays.",
+	icon = "cakeCandles",
+)
+
+assert(csv.accept == [])
+assert(csv.max_size == 262144)
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [
+			f,
+			schema.PhotoSelect(
+				id = "photo",
+				name = "Photo",
+				desc = "A photo.",
+				icon = "camera",
+			),
+			csv,
+		],
+	)
+
+def main():
+	return []
+`
+
+func encodePNG(t *testing.T, w, h int) string {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, w, h))))
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func loadFileUploadApplet(t *testing.T) *runtime.Applet {
+	app, err := runtime.NewApplet("fileupload.star", []byte(fileUploadSource))
+	require.NoError(t, err)
+	return app
+}
+
+func TestFileUpload(t *testing.T) {
+	app := loadFileUploadApplet(t)
+
+	screens, err := app.Run(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, screens)
+
+	assert.Equal(t, "file", app.Schema.Fields[0].Type)
+	assert.Equal(t, []string{"image/*"}, app.Schema.Fields[0].Accept)
+	assert.Equal(t, schema.DefaultMaxUploadSize, app.Schema.Fields[2].MaxSize)
+}
+
+func TestFileUploadBadArgs(t *testing.T) {
+	for name, args := range map[string]string{
+		"max_size": `max_size = 0`,
+		"accept":   `accept = ["png"]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := `
+load("schema.star", "schema")
+
+schema.FileUpload(id = "f", name = "F", desc = "F", icon = "file", ` + args + `)
+
+def main():
+	return []
+`
+			_, err := runtime.NewApplet("fileupload.star", []byte(src))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPrepareUpload(t *testing.T) {
+	s := loadFileUploadApplet(t).Schema
+	logo, _ := s.Field("logo")
+	photo, _ := s.Field("photo")
+	text := base64.StdEncoding.EncodeToString([]byte("name,date\nGrace,12-09\n"))
+
+	small := encodePNG(t, 4, 4)
+	value, err := schema.PrepareUpload(logo, small, 64, 32)
+	assert.NoError(t, err)
+	assert.Equal(t, small, value)
+
+	_, err = schema.PrepareUpload(logo, "not base64!", 64, 32)
+	assert.ErrorContains(t, err, "base64")
+	_, err = schema.PrepareUpload(logo, text, 64, 32)
+	assert.ErrorContains(t, err, "file type text/plain")
+	_, err = schema.PrepareUpload(logo, encodePNG(t, 512, 512), 64, 32)
+	assert.ErrorContains(t, err, "at most 1024")
+
+	// PhotoSelect fields aren't uploads
+	_, err = schema.PrepareUpload(photo, small, 64, 32)
+	assert.Error(t, err)
+}
+
+func TestPrepareUploads(t *testing.T) {
+	s := loadFileUploadApplet(t).Schema
+	text := base64.StdEncoding.EncodeToString([]byte("hello"))
+	small := encodePNG(t, 16, 16)
+
+	var gifBuf bytes.Buffer
+	require.NoError(t, gif.Encode(&gifBuf, image.NewPaletted(image.Rect(0, 0, 200, 50), palette.Plan9), nil))
+	animated := base64.StdEncoding.EncodeToString(gifBuf.Bytes())
+
+	config := map[string]string{
+		"logo":      encodePNG(t, 200, 50),
+		"birthdays": text,
+		"photo":     encodePNG(t, 200, 50),
+	}
+	out, err := s.PrepareUploads(config, 64, 32)
+	require.NoError(t, err)
+
+	data, err := base64.StdEncoding.DecodeString(out["logo"])
+	require.NoError(t, err)
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 64, cfg.Width)
+	assert.Equal(t, 16, cfg.Height)
+
+	assert.Equal(t, text, out["birthdays"])
+
+	// PhotoSelect values are left as they are
+	assert.Equal(t, config["photo"], out["photo"])
+
+	// the original config is left untouched
+	assert.NotEqual(t, config["logo"], out["logo"])
+
+	// nothing to scale returns config as is
+	out, err = s.PrepareUploads(map[string]string{"logo": small}, 64, 32)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"logo": small}, out)
+
+	// GIFs aren't scaled, which would drop their animation
+	out, err = s.PrepareUploads(map[string]string{"birthdays": animated}, 64, 32)
+	require.NoError(t, err)
+	assert.Equal(t, animated, out["birthdays"])
+
+	_, err = s.PrepareUploads(map[string]string{"logo": text}, 64, 32)
+	var configErr *schema.ConfigError
+	require.ErrorAs(t, err, &configErr)
+	assert.Equal(t, "logo", configErr.FieldID)
+
+	// uploads aren't checked again before each render
+	assert.NoError(t, s.ValidateConfig(map[string]string{"logo": text, "photo": text}))
+}
@@ -0,0 +1,182 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"go.starlark.net/starlark"
+)
+
+// Group organizes related fields under a collapsible heading. Section does the
+// same with a heading that's always expanded. Both are purely presentational:
+// the fields inside them are stored in config under their own IDs, just like
+// top level fields.
+type Group struct {
+	SchemaField
+	starlarkFields *starlark.List
+}
+
+func newGroup(
+	thread *starlark.Thread,
+	b *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	return newFieldGroup("group", b.Name(), args, kwargs)
+}
+
+func newSection(
+	thread *starlark.Thread,
+	b *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	return newFieldGroup("section", b.Name(), args, kwargs)
+}
+
+func newFieldGroup(
+	fieldType string,
+	builtinName string,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var (
+		id        starlark.String
+		name      starlark.String
+		desc      starlark.String
+		icon      starlark.String
+		fields    *starlark.List
+		collapsed starlark.Bool
+	)
+
+	params := []any{
+		"id", &id,
+		"name", &name,
+		"desc?", &desc,
+		"icon?", &icon,
+		"fields", &fields,
+	}
+	if fieldType == "group" {
+		params = append(params, "collapsed?", &collapsed)
+	}
+
+	if err := starlark.UnpackArgs(
+		builtinName,
+		args, kwargs,
+		params...,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for %s: %s", builtinName, err)
+	}
+
+	s := &Group{}
+	s.SchemaField.Type = fieldType
+	s.ID = id.GoString()
+	s.Name = name.GoString()
+	s.Description = desc.GoString()
+	s.Icon = icon.GoString()
+	s.Collapsed = bool(collapsed)
+
+	var fieldVal starlark.Value
+	fieldIter := fields.Iterate()
+	defer fieldIter.Done()
+	for i := 0; fieldIter.Next(&fieldVal); i++ {
+		if _, isNone := fieldVal.(starlark.NoneType); isNone {
+			continue
+		}
+
+		f, ok := fieldVal.(Field)
+		if !ok {
+			return nil, fmt.Errorf(
+				"expected fields to be a list of Field but found: %s (at index %d)",
+				fieldVal.Type(),
+				i,
+			)
+		}
+
+		child := f.AsSchemaField()
+		switch child.Type {
+		case "generated", "notification":
+			return nil, fmt.Errorf(
+				"%s fields can't be used in a %s (at index %d)",
+				fieldVal.Type(),
+				builtinName,
+				i,
+			)
+		}
+
+		s.Fields = append(s.Fields, child)
+	}
+	s.starlarkFields = fields
+
+	return s, nil
+}
+
+// isFieldGroup reports whether field only exists to organize its children.
+func isFieldGroup(field SchemaField) bool {
+	return field.Type == "group" || field.Type == "section"
+}
+
+func (s *Group) AsSchemaField() SchemaField {
+	return s.SchemaField
+}
+
+func (s *Group) AttrNames() []string {
+	names := []string{
+		"id", "name", "desc", "icon", "fields",
+	}
+	if s.SchemaField.Type == "group" {
+		names = append(names, "collapsed")
+	}
+	return names
+}
+
+func (s *Group) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "id":
+		return starlark.String(s.ID), nil
+
+	case "name":
+		return starlark.String(s.Name), nil
+
+	case "desc":
+		return starlark.String(s.Description), nil
+
+	case "icon":
+		return starlark.String(s.Icon), nil
+
+	case "fields":
+		return s.starlarkFields, nil
+
+	case "collapsed":
+		if s.SchemaField.Type != "group" {
+			return nil, nil
+		}
+		return starlark.Bool(s.Collapsed), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (s *Group) String() string {
+	if s.SchemaField.Type == "section" {
+		return "Section(...)"
+	}
+	return "Group(...)"
+}
+
+func (s *Group) Type() string {
+	if s.SchemaField.Type == "section" {
+		return "Section"
+	}
+	return "Group"
+}
+
+func (s *Group) Freeze()              {}
+func (s *Group) Truth() starlark.Bool { return true }
+
+func (s *Group) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(s, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
@@ -0,0 +1,152 @@
+package schema_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+var groupSource = `
+load("schema.star", "schema")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+def search(pattern):
+	return [schema.Option(display = "Main St", value = "main")]
+
+g = schema.Group(
+	id = "appearance",
+	name = "Appearance",
+	desc = "How the app looks.",
+	icon = "brush",
+	collapsed = True,
+	fields = [
+		schema.Toggle(
+			id = "small",
+			name = "Small Text",
+			desc = "Use a smaller font.",
+			icon = "compress",
+			default = False,
+		),
+	],
+)
+
+s = schema.Section(
+	id = "where",
+	name = "Where",
+	fields = [
+		schema.Typeahead(
+			id = "stop",
+			name = "Stop",
+			desc = "The stop to show.",
+			icon = "bus",
+			handler = search,
+		),
+		g,
+	],
+)
+
+assert(g.id == "appearance")
+assert(g.name == "Appearance")
+assert(g.collapsed == True)
+assert(g.fields[0].id == "small")
+assert(s.name == "Where")
+assert(s.fields[1] == g)
+
+def main(config):
+	return []
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [s],
+	)
+`
+
+func TestGroup(t *testing.T) {
+	app, err := runtime.NewApplet("group.star", []byte(groupSource))
+	require.NoError(t, err)
+
+	screens, err := app.Run(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, screens)
+
+	// handlers of fields inside groups are registered
+	options, err := app.CallSchemaHandler(context.Background(), "stop$search", "main")
+	assert.NoError(t, err)
+	assert.Contains(t, options, "Main St")
+
+	var js struct {
+		Schema []struct {
+			Type   string `json:"type"`
+			Fields []struct {
+				Type      string `json:"type"`
+				ID        string `json:"id"`
+				Handler   string `json:"handler"`
+				Collapsed bool   `json:"collapsed"`
+			} `json:"fields"`
+		} `json:"schema"`
+	}
+	require.NoError(t, json.Unmarshal(app.SchemaJSON, &js))
+	require.Len(t, js.Schema, 1)
+	assert.Equal(t, "section", js.Schema[0].Type)
+	require.Len(t, js.Schema[0].Fields, 2)
+	assert.Equal(t, "stop$search", js.Schema[0].Fields[0].Handler)
+	assert.Equal(t, "group", js.Schema[0].Fields[1].Type)
+	assert.True(t, js.Schema[0].Fields[1].Collapsed)
+}
+
+func TestGroupValidateConfig(t *testing.T) {
+	src := `
+load("schema.star", "schema")
+
+def main(config):
+	return []
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [
+			schema.Group(
+				id = "when",
+				name = "When",
+				fields = [
+					schema.Date(id = "day", name = "Day", desc = "A day.", icon = "calendar"),
+				],
+			),
+		],
+	)
+`
+	app, err := runtime.NewApplet("group.star", []byte(src))
+	require.NoError(t, err)
+
+	// fields inside groups are stored at the top level of config
+	assert.NoError(t, app.Schema.ValidateConfig(map[string]string{"day": "2024-01-01"}))
+	assert.Error(t, app.Schema.ValidateConfig(map[string]string{"day": "tomorrow"}))
+}
+
+func TestGroupRejectsGenerated(t *testing.T) {
+	src := `
+load("schema.star", "schema")
+
+def more(value):
+	return []
+
+g = schema.Group(
+	id = "g",
+	name = "G",
+	fields = [schema.Generated(id = "gen", source = "x", handler = more)],
+)
+
+def main():
+	return []
+`
+	_, err := runtime.NewApplet("group.star", []byte(src))
+	assert.ErrorContains(t, err, "can't be used in a Group")
+}
@@ -0,0 +1,276 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+)
+
+// JSONSchemaDialect is the version of JSON Schema that JSONSchema produces.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema document. Only the keywords needed to describe
+// app config are included.
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Default     any    `json:"default,omitempty"`
+
+	Const   *string       `json:"const,omitempty"`
+	Enum    []string      `json:"enum,omitempty"`
+	OneOf   []*JSONSchema `json:"oneOf,omitempty"`
+	Pattern string        `json:"pattern,omitempty"`
+	Format  string        `json:"format,omitempty"`
+
+	ContentEncoding  string      `json:"contentEncoding,omitempty"`
+	ContentMediaType string      `json:"contentMediaType,omitempty"`
+	ContentSchema    *JSONSchema `json:"contentSchema,omitempty"`
+
+	Properties           *JSONSchemaProperties `json:"properties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema           `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema           `json:"items,omitempty"`
+	UniqueItems          bool                  `json:"uniqueItems,omitempty"`
+	MinItems             *int                  `json:"minItems,omitempty"`
+	MaxItems             *int                  `json:"maxItems,omitempty"`
+
+	// Extensions carry schema features JSON Schema can't express, such as
+	// the field type and visible_if expressions.
+	PixletType      string `json:"x-pixlet-type,omitempty"`
+	PixletVisibleIf string `json:"x-pixlet-visible-if,omitempty"`
+	PixletGroup     string `json:"x-pixlet-group,omitempty"`
+}
+
+// JSONSchemaProperties are the properties of an object, serialized in the
+// order the fields appear in the app's schema so that forms show them in
+// the same order.
+type JSONSchemaProperties struct {
+	Names   []string
+	Schemas map[string]*JSONSchema
+}
+
+func (p *JSONSchemaProperties) set(name string, s *JSONSchema) {
+	if p.Schemas == nil {
+		p.Schemas = map[string]*JSONSchema{}
+	}
+	if _, ok := p.Schemas[name]; !ok {
+		p.Names = append(p.Names, name)
+	}
+	p.Schemas[name] = s
+}
+
+func (p *JSONSchemaProperties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range p.Names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.Schemas[name])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// JSONSchema converts the schema to a JSON Schema describing the app's config:
+// an object with one string property per field. Values that pixlet stores as
+// JSON, such as a Location or a List, are described with contentSchema.
+// Groups and sections are flattened, since their fields are stored at the top
+// level of the config.
+func (s *Schema) JSONSchema() *JSONSchema {
+	root := &JSONSchema{
+		Schema:               JSONSchemaDialect,
+		Type:                 "object",
+		Properties:           &JSONSchemaProperties{},
+		AdditionalProperties: &JSONSchema{Type: "string"},
+	}
+	addJSONSchemaFields(root, s.Fields, "")
+	return root
+}
+
+func addJSONSchemaFields(obj *JSONSchema, fields []SchemaField, group string) {
+	for _, field := range fields {
+		if isFieldGroup(field) {
+			addJSONSchemaFields(obj, field.Fields, field.ID)
+			continue
+		}
+
+		prop := fieldJSONSchema(field)
+		prop.PixletGroup = group
+		obj.Properties.set(field.ID, prop)
+		if field.Required {
+			obj.Required = append(obj.Required, field.ID)
+		}
+	}
+}
+
+func fieldJSONSchema(field SchemaField) *JSONSchema {
+	s := &JSONSchema{
+		Type:            "string",
+		Title:           field.Name,
+		Description:     field.Description,
+		PixletType:      field.Type,
+		PixletVisibleIf: field.VisibleIf,
+	}
+	if field.Default != "" {
+		s.Default = field.Default
+	}
+
+	switch field.Type {
+	case "text":
+		if field.Pattern != "" {
+			s.Pattern = `^(?:` + field.Pattern + `)$`
+		}
+
+	case "dropdown", "radio":
+		for _, o := range field.Options {
+			s.OneOf = append(s.OneOf, optionJSONSchema(o))
+		}
+
+	case "onoff":
+		s.Enum = []string{"true", "false"}
+
+	case "color":
+		s.Pattern = `^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`
+
+	case "date":
+		s.Format = "date"
+
+	case "datetime":
+		s.Format = "date-time"
+
+	case "duration":
+		// Go's duration format, not the ISO 8601 one of format: duration
+		s.Pattern = `^([0-9]+h)?([0-9]+m)?([0-9]+s)?$`
+
+	case "file":
+		s.ContentEncoding = "base64"
+		if len(field.Accept) == 1 {
+			s.ContentMediaType = field.Accept[0]
+		}
+
+	case "png":
+		s.ContentEncoding = "base64"
+
+	case "multiselect":
+		options := &JSONSchema{}
+		for _, o := range field.Options {
+			options.OneOf = append(options.OneOf, optionJSONSchema(o))
+		}
+		s.ContentMediaType = "application/json"
+		s.ContentSchema = &JSONSchema{
+			Type:        "array",
+			Items:       options,
+			UniqueItems: true,
+			MinItems:    minItems(field.Min),
+			MaxItems:    maxItems(field.Max),
+		}
+
+	case "list":
+		item := &JSONSchema{
+			Type:                 "object",
+			Properties:           &JSONSchemaProperties{},
+			AdditionalProperties: &JSONSchema{Type: "string"},
+		}
+		addJSONSchemaFields(item, field.Fields, "")
+		s.ContentMediaType = "application/json"
+		s.ContentSchema = &JSONSchema{
+			Type:     "array",
+			Items:    item,
+			MinItems: minItems(field.Min),
+			MaxItems: maxItems(field.Max),
+		}
+		if field.MaxItems > 0 && s.ContentSchema.MaxItems == nil {
+			s.ContentSchema.MaxItems = &field.MaxItems
+		}
+
+	case "timerange":
+		s.ContentMediaType = "application/json"
+		s.ContentSchema = &JSONSchema{
+			Type:     "object",
+			Required: []string{"start", "end"},
+			Properties: &JSONSchemaProperties{
+				Names: []string{"start", "end", "days"},
+				Schemas: map[string]*JSONSchema{
+					"start": {Type: "string", Pattern: timeOfDayPattern},
+					"end":   {Type: "string", Pattern: timeOfDayPattern},
+					"days": {
+						Type:        "array",
+						Items:       &JSONSchema{Type: "string", Enum: Weekdays},
+						UniqueItems: true,
+					},
+				},
+			},
+		}
+
+	case "location":
+		s.ContentMediaType = "application/json"
+		s.ContentSchema = stringObjectJSONSchema("lat", "lng", "description", "locality", "place_id", "timezone")
+
+	case "locations":
+		item := stringObjectJSONSchema("label", "lat", "lng", "description", "locality", "place_id", "timezone")
+		item.Required = []string{"label", "lat", "lng"}
+		s.ContentMediaType = "application/json"
+		s.ContentSchema = &JSONSchema{
+			Type:     "array",
+			Items:    item,
+			MinItems: minItems(field.Min),
+			MaxItems: maxItems(field.Max),
+		}
+
+	case "locationbased", "typeahead":
+		s.ContentMediaType = "application/json"
+		s.ContentSchema = stringObjectJSONSchema("display", "value")
+	}
+
+	return s
+}
+
+const timeOfDayPattern = `^([01][0-9]|2[0-3]):[0-5][0-9]$`
+
+func optionJSONSchema(o SchemaOption) *JSONSchema {
+	value := o.Value
+	return &JSONSchema{Const: &value, Title: o.Display}
+}
+
+// stringObjectJSONSchema describes an object with string properties, the
+// first two of which are required.
+func stringObjectJSONSchema(names ...string) *JSONSchema {
+	s := &JSONSchema{
+		Type:       "object",
+		Properties: &JSONSchemaProperties{},
+		Required:   names[:2],
+	}
+	for _, name := range names {
+		s.Properties.set(name, &JSONSchema{Type: "string"})
+	}
+	return s
+}
+
+func minItems(min *float64) *int {
+	if min == nil {
+		return nil
+	}
+	n := int(math.Ceil(*min))
+	return &n
+}
+
+func maxItems(max *float64) *int {
+	if max == nil {
+		return nil
+	}
+	n := int(math.Floor(*max))
+	return &n
+}
@@ -0,0 +1,111 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+)
+
+var jsonSchemaSource = `
+load("schema.star", "schema")
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [
+			schema.Text(id = "zip", name = "ZIP", desc = "Your ZIP code.", icon = "house", required = True, pattern = "[0-9]{5}"),
+			schema.Dropdown(
+				id = "units",
+				name = "Units",
+				desc = "Units to use.",
+				icon = "ruler",
+				default = "metric",
+				options = [
+					schema.Option(display = "Metric", value = "metric"),
+					schema.Option(display = "Imperial", value = "imperial"),
+				],
+			),
+			schema.Group(
+				id = "advanced",
+				name = "Advanced",
+				desc = "Advanced options.",
+				icon = "gear",
+				fields = [
+					schema.Toggle(id = "wind", name = "Wind", desc = "Show wind.", icon = "wind", default = False, visible_if = "units == 'metric'"),
+				],
+			),
+			schema.MultiSelect(
+				id = "lines",
+				name = "Lines",
+				desc = "Lines to show.",
+				icon = "train",
+				options = [schema.Option(display = "Red", value = "red")],
+				max = 1,
+			),
+			schema.List(
+				id = "stops",
+				name = "Stops",
+				desc = "Stops to show.",
+				icon = "bus",
+				max_items = 3,
+				fields = [
+					schema.Text(id = "name", name = "Name", desc = "Stop name.", icon = "signature"),
+				],
+			),
+		],
+	)
+
+def main():
+	return []
+`
+
+func TestJSONSchema(t *testing.T) {
+	app, err := runtime.NewApplet("jsonschema.star", []byte(jsonSchemaSource))
+	require.NoError(t, err)
+
+	js, err := json.Marshal(app.Schema.JSONSchema())
+	require.NoError(t, err)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(js, &doc))
+
+	assert.Equal(t, schema.JSONSchemaDialect, doc["$schema"])
+	assert.Equal(t, "object", doc["type"])
+	assert.Equal(t, []any{"zip"}, doc["required"])
+
+	// properties keep the order of the schema, with groups flattened
+	assert.Regexp(t, `"properties":\{"zip":.*"units":.*"wind":.*"lines":.*"stops":`, string(js))
+
+	props := doc["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"type":          "string",
+		"title":         "ZIP",
+		"description":   "Your ZIP code.",
+		"pattern":       "^(?:[0-9]{5})$",
+		"x-pixlet-type": "text",
+	}, props["zip"])
+
+	assert.Equal(t, []any{
+		map[string]any{"const": "metric", "title": "Metric"},
+		map[string]any{"const": "imperial", "title": "Imperial"},
+	}, props["units"].(map[string]any)["oneOf"])
+	assert.Equal(t, "metric", props["units"].(map[string]any)["default"])
+
+	wind := props["wind"].(map[string]any)
+	assert.Equal(t, "advanced", wind["x-pixlet-group"])
+	assert.Equal(t, "units == 'metric'", wind["x-pixlet-visible-if"])
+	assert.Equal(t, []any{"true", "false"}, wind["enum"])
+
+	lines := props["lines"].(map[string]any)
+	assert.Equal(t, "application/json", lines["contentMediaType"])
+	assert.Equal(t, float64(1), lines["contentSchema"].(map[string]any)["maxItems"])
+
+	stops := props["stops"].(map[string]any)["contentSchema"].(map[string]any)
+	assert.Equal(t, "array", stops["type"])
+	assert.Equal(t, float64(3), stops["maxItems"])
+	assert.Contains(t, stops["items"].(map[string]any)["properties"], "name")
+}
@@ -0,0 +1,196 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"go.starlark.net/starlark"
+)
+
+// List lets users add a variable number of entries, each made up of the
+// same child fields. It's serialized in config as a JSON list with one object
+// per entry, mapping child field IDs to their values.
+type List struct {
+	SchemaField
+	starlarkFields *starlark.List
+}
+
+// listChildTypes are the field types that may be used inside a List. Fields
+// with handlers aren't supported, since handlers are registered per schema.
+var listChildTypes = map[string]bool{
+	"color":       true,
+	"date":        true,
+	"datetime":    true,
+	"dropdown":    true,
+	"duration":    true,
+	"location":    true,
+	"multiselect": true,
+	"onoff":       true,
+	"text":        true,
+	"timerange":   true,
+}
+
+func newList(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var (
+		id       starlark.String
+		name     starlark.String
+		desc     starlark.String
+		icon     starlark.String
+		fields   *starlark.List
+		maxItems = starlark.MakeInt(0)
+	)
+
+	if err := starlark.UnpackArgs(
+		"List",
+		args, kwargs,
+		"id", &id,
+		"name", &name,
+		"desc", &desc,
+		"icon", &icon,
+		"fields", &fields,
+		"max_items?", &maxItems,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for List: %s", err)
+	}
+
+	s := &List{}
+	s.SchemaField.Type = "list"
+	s.ID = id.GoString()
+	s.Name = name.GoString()
+	s.Description = desc.GoString()
+	s.Icon = icon.GoString()
+
+	max, ok := maxItems.Int64()
+	if !ok || max < 0 {
+		return nil, fmt.Errorf("max_items must be a non-negative integer")
+	}
+	s.MaxItems = int(max)
+
+	var fieldVal starlark.Value
+	fieldIter := fields.Iterate()
+	defer fieldIter.Done()
+	for i := 0; fieldIter.Next(&fieldVal); i++ {
+		if _, isNone := fieldVal.(starlark.NoneType); isNone {
+			continue
+		}
+
+		f, ok := fieldVal.(Field)
+		if !ok {
+			return nil, fmt.Errorf(
+				"expected fields to be a list of Field but found: %s (at index %d)",
+				fieldVal.Type(),
+				i,
+			)
+		}
+
+		child := f.AsSchemaField()
+		if !listChildTypes[child.Type] {
+			return nil, fmt.Errorf(
+				"%s fields can't be used in a List (at index %d)",
+				fieldVal.Type(),
+				i,
+			)
+		}
+
+		s.Fields = append(s.Fields, child)
+	}
+	s.starlarkFields = fields
+
+	return s, nil
+}
+
+// DecodeList parses a List config value into one map of child field values
+// per entry. An empty string is an empty list.
+func DecodeList(value string) ([]map[string]string, error) {
+	items := []map[string]string{}
+	if value == "" {
+		return items, nil
+	}
+	if err := json.Unmarshal([]byte(value), &items); err != nil {
+		return nil, fmt.Errorf("expected a JSON list of objects with string values: %w", err)
+	}
+	return items, nil
+}
+
+// EncodeList serializes List entries the way they're stored in config.
+func EncodeList(items []map[string]string) string {
+	if items == nil {
+		items = []map[string]string{}
+	}
+	b, _ := json.Marshal(items)
+	return string(b)
+}
+
+// validateList checks that value holds at most MaxItems entries, each of
+// which is valid for the list's child fields.
+func validateList(field SchemaField, value string) error {
+	items, err := DecodeList(value)
+	if err != nil {
+		return err
+	}
+
+	if field.MaxItems > 0 && len(items) > field.MaxItems {
+		return fmt.Errorf("has %d items, but at most %d are allowed", len(items), field.MaxItems)
+	}
+
+	children := &Schema{Fields: field.Fields}
+	for i, item := range items {
+		if err := children.ValidateConfig(item); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *List) AsSchemaField() SchemaField {
+	return s.SchemaField
+}
+
+func (s *List) AttrNames() []string {
+	return []string{
+		"id", "name", "desc", "icon", "fields", "max_items",
+	}
+}
+
+func (s *List) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "id":
+		return starlark.String(s.ID), nil
+
+	case "name":
+		return starlark.String(s.Name), nil
+
+	case "desc":
+		return starlark.String(s.Description), nil
+
+	case "icon":
+		return starlark.String(s.Icon), nil
+
+	case "fields":
+		return s.starlarkFields, nil
+
+	case "max_items":
+		return starlark.MakeInt(s.MaxItems), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (s *List) String() string       { return "List(...)" }
+func (s *List) Type() string         { return "List" }
+func (s *List) Freeze()              {}
+func (s *List) Truth() starlark.Bool { return true }
+
+func (s *List) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(s, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
@@ -0,0 +1,127 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+)
+
+var listSource = `
+load("schema.star", "schema")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+l = schema.List(
+	id = "birthdays",
+	name = "Birthdays",
+	desc = "The birthdays to count down to.",
+	icon = "cakeCandles",
+	max_items = 3,
+	fields = [
+		schema.Text(
+			id = "who",
+			name = "Name",
+			desc = "Whose birthday it is.",
+			icon = "user",
+		),
+		schema.Date(
+			id = "day",
+			name = "Day",
+			desc = "The day of the birthday.",
+			icon = "calendar",
+		),
+	],
+)
+
+assert(l.id == "birthdays")
+assert(l.name == "Birthdays")
+assert(l.desc == "The birthdays to count down to.")
+assert(l.icon == "cakeCandles")
+assert(l.max_items == 3)
+assert(l.fields[0].id == "who")
+
+def main(config):
+	birthdays = config.list("birthdays")
+	assert(len(birthdays) == 2)
+	assert(birthdays[0].str("who") == "Ada")
+	assert(birthdays[1]["day"] == "2024-04-30")
+	return []
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [l],
+	)
+`
+
+func TestList(t *testing.T) {
+	app, err := runtime.NewApplet("list.star", []byte(listSource))
+	require.NoError(t, err)
+
+	require.Len(t, app.Schema.Fields, 1)
+	assert.Equal(t, "list", app.Schema.Fields[0].Type)
+	assert.Equal(t, 3, app.Schema.Fields[0].MaxItems)
+	assert.Len(t, app.Schema.Fields[0].Fields, 2)
+
+	screens, err := app.RunWithConfig(context.Background(), map[string]string{
+		"birthdays": schema.EncodeList([]map[string]string{
+			{"who": "Ada", "day": "2024-12-10"},
+			{"who": "Grace", "day": "2024-04-30"},
+		}),
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, screens)
+}
+
+func TestListRejectsHandlerFields(t *testing.T) {
+	src := `
+load("schema.star", "schema")
+
+def search(pattern):
+	return []
+
+l = schema.List(
+	id = "stops",
+	name = "Stops",
+	desc = "The stops to show.",
+	icon = "bus",
+	fields = [
+		schema.Typeahead(
+			id = "stop",
+			name = "Stop",
+			desc = "A stop.",
+			icon = "bus",
+			handler = search,
+		),
+	],
+)
+
+def main():
+	return []
+`
+	_, err := runtime.NewApplet("list.star", []byte(src))
+	assert.ErrorContains(t, err, "can't be used in a List")
+}
+
+func TestListValidateConfig(t *testing.T) {
+	app, err := runtime.NewApplet("list.star", []byte(listSource))
+	require.NoError(t, err)
+	s := app.Schema
+
+	assert.NoError(t, s.ValidateConfig(map[string]string{"birthdays": "[]"}))
+	assert.NoError(t, s.ValidateConfig(map[string]string{"birthdays": `[{"who": "Ada", "day": "2024-12-10"}]`}))
+
+	// too many items
+	assert.Error(t, s.ValidateConfig(map[string]string{"birthdays": `[{}, {}, {}, {}]`}))
+
+	// child fields are validated too
+	assert.ErrorContains(t, s.ValidateConfig(map[string]string{"birthdays": `[{"day": "December 10th"}]`}), "item 0")
+
+	assert.Error(t, s.ValidateConfig(map[string]string{"birthdays": "Ada,Grace"}))
+}
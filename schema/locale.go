@@ -0,0 +1,166 @@
+package schema
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+	"golang.org/x/text/language"
+)
+
+// FieldTranslation holds the name and description of a field in one locale.
+// Either may be left empty to fall back to a less specific locale.
+type FieldTranslation struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// LocaleFallbacks returns the locales to look up translations in for locale,
+// from most to least specific, for example "de-AT" and then "de". Fields fall
+// back to their untranslated text when none of them match.
+func LocaleFallbacks(locale string) ([]string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid locale %q: %w", locale, err)
+	}
+
+	var chain []string
+	for !tag.IsRoot() {
+		chain = append(chain, tag.String())
+		tag = tag.Parent()
+	}
+	return chain, nil
+}
+
+// canonicalLocale normalizes a locale used as a translation key, so that
+// "pt_br" and "pt-BR" are the same.
+func canonicalLocale(locale string) (string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", fmt.Errorf("invalid locale %q: %w", locale, err)
+	}
+	return tag.String(), nil
+}
+
+// Localize returns a copy of the schema with field names, descriptions and
+// option labels translated to locale, falling back to less specific locales
+// and then to the untranslated text. The copy doesn't carry translations, so
+// it's what a host should send to a config UI that asked for one locale.
+func (s *Schema) Localize(locale string) (*Schema, error) {
+	chain, err := LocaleFallbacks(locale)
+	if err != nil {
+		return nil, err
+	}
+
+	localized := *s
+	localized.Fields = localizeFields(s.Fields, chain)
+	return &localized, nil
+}
+
+func localizeFields(fields []SchemaField, chain []string) []SchemaField {
+	if fields == nil {
+		return nil
+	}
+
+	localized := make([]SchemaField, len(fields))
+	for i, f := range fields {
+		name, desc := "", ""
+		for _, l := range chain {
+			t := f.Translations[l]
+			if name == "" {
+				name = t.Name
+			}
+			if desc == "" {
+				desc = t.Description
+			}
+		}
+		if name != "" {
+			f.Name = name
+		}
+		if desc != "" {
+			f.Description = desc
+		}
+		f.Translations = nil
+
+		if f.Options != nil {
+			options := make([]SchemaOption, len(f.Options))
+			for j, o := range f.Options {
+				for _, l := range chain {
+					if display, ok := o.Translations[l]; ok {
+						o.Display, o.Text = display, display
+						break
+					}
+				}
+				o.Translations = nil
+				options[j] = o
+			}
+			f.Options = options
+		}
+
+		f.Fields = localizeFields(f.Fields, chain)
+		localized[i] = f
+	}
+	return localized
+}
+
+// fieldTranslationsArg converts the translations argument of a field, a dict
+// mapping locales to dicts with a name and/or desc.
+func fieldTranslationsArg(v *starlark.Dict) (map[string]FieldTranslation, error) {
+	translations := make(map[string]FieldTranslation, v.Len())
+	for _, item := range v.Items() {
+		locale, ok := starlark.AsString(item[0])
+		if !ok {
+			return nil, fmt.Errorf("translations: expected locale to be a string, not %s", item[0].Type())
+		}
+		locale, err := canonicalLocale(locale)
+		if err != nil {
+			return nil, fmt.Errorf("translations: %w", err)
+		}
+
+		texts, ok := item[1].(*starlark.Dict)
+		if !ok {
+			return nil, fmt.Errorf("translations[%q]: expected a dict, not %s", locale, item[1].Type())
+		}
+
+		var t FieldTranslation
+		for _, text := range texts.Items() {
+			key, _ := starlark.AsString(text[0])
+			value, ok := starlark.AsString(text[1])
+			if !ok {
+				return nil, fmt.Errorf("translations[%q][%s]: expected a string, not %s", locale, text[0], text[1].Type())
+			}
+			switch key {
+			case "name":
+				t.Name = value
+			case "desc":
+				t.Description = value
+			default:
+				return nil, fmt.Errorf("translations[%q]: unexpected key %s, expected name or desc", locale, text[0])
+			}
+		}
+		translations[locale] = t
+	}
+	return translations, nil
+}
+
+// optionTranslationsArg converts the translations argument of an Option, a
+// dict mapping locales to the option's display text.
+func optionTranslationsArg(v *starlark.Dict) (map[string]string, error) {
+	translations := make(map[string]string, v.Len())
+	for _, item := range v.Items() {
+		locale, ok := starlark.AsString(item[0])
+		if !ok {
+			return nil, fmt.Errorf("translations: expected locale to be a string, not %s", item[0].Type())
+		}
+		locale, err := canonicalLocale(locale)
+		if err != nil {
+			return nil, fmt.Errorf("translations: %w", err)
+		}
+
+		display, ok := starlark.AsString(item[1])
+		if !ok {
+			return nil, fmt.Errorf("translations[%q]: expected a string, not %s", locale, item[1].Type())
+		}
+		translations[locale] = display
+	}
+	return translations, nil
+}
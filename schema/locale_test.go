@@ -0,0 +1,126 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+)
+
+var localeSource = `
+load("schema.star", "schema")
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [
+			schema.Dropdown(
+				id = "line",
+				name = "Line",
+				desc = "The train line to show.",
+				icon = "train",
+				default = "red",
+				options = [
+					schema.Option(display = "Red Line", value = "red", translations = {"de": "Rote Linie"}),
+					schema.Option(display = "Blue Line", value = "blue"),
+				],
+				translations = {
+					"de": {"name": "Linie", "desc": "Die anzuzeigende Linie."},
+					"de_AT": {"desc": "Die Linie, die angezeigt wird."},
+				},
+			),
+			schema.Group(
+				id = "display",
+				name = "Display",
+				desc = "How to show departures.",
+				icon = "display",
+				fields = [
+					schema.Toggle(
+						id = "show_delays",
+						name = "Show Delays",
+						desc = "Whether to show delays.",
+						icon = "clock",
+						translations = {"de": {"name": "Verspätungen anzeigen"}},
+					),
+				],
+			),
+		],
+	)
+
+def main():
+	return []
+`
+
+func TestLocalize(t *testing.T) {
+	app, err := runtime.NewApplet("locale.star", []byte(localeSource))
+	require.NoError(t, err)
+
+	screens, err := app.Run(context.Background())
+	require.NoError(t, err)
+	assert.NotNil(t, screens)
+
+	line := app.Schema.Fields[0]
+	assert.Equal(t, "Line", line.Name)
+	assert.Equal(t, map[string]schema.FieldTranslation{
+		"de":    {Name: "Linie", Description: "Die anzuzeigende Linie."},
+		"de-AT": {Description: "Die Linie, die angezeigt wird."},
+	}, line.Translations)
+
+	de, err := app.Schema.Localize("de-DE")
+	require.NoError(t, err)
+	assert.Equal(t, "Linie", de.Fields[0].Name)
+	assert.Equal(t, "Die anzuzeigende Linie.", de.Fields[0].Description)
+	assert.Equal(t, "Rote Linie", de.Fields[0].Options[0].Display)
+	assert.Equal(t, "Blue Line", de.Fields[0].Options[1].Display)
+	assert.Equal(t, "Verspätungen anzeigen", de.Fields[1].Fields[0].Name)
+	assert.Equal(t, "Whether to show delays.", de.Fields[1].Fields[0].Description)
+	assert.Nil(t, de.Fields[0].Translations)
+
+	// the most specific locale wins, falling back per text
+	at, err := app.Schema.Localize("de-AT")
+	require.NoError(t, err)
+	assert.Equal(t, "Linie", at.Fields[0].Name)
+	assert.Equal(t, "Die Linie, die angezeigt wird.", at.Fields[0].Description)
+
+	fr, err := app.Schema.Localize("fr")
+	require.NoError(t, err)
+	assert.Equal(t, "Line", fr.Fields[0].Name)
+	assert.Equal(t, "Red Line", fr.Fields[0].Options[0].Display)
+
+	// the original schema is left alone
+	assert.Equal(t, "Line", app.Schema.Fields[0].Name)
+	assert.Equal(t, "Red Line", app.Schema.Fields[0].Options[0].Display)
+
+	_, err = app.Schema.Localize("not a locale")
+	assert.Error(t, err)
+}
+
+func TestLocaleFallbacks(t *testing.T) {
+	chain, err := schema.LocaleFallbacks("pt_BR")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pt-BR", "pt"}, chain)
+}
+
+func TestBadTranslations(t *testing.T) {
+	for name, translations := range map[string]string{
+		"locale": `{"not a locale": {"name": "x"}}`,
+		"key":    `{"de": {"title": "x"}}`,
+		"value":  `{"de": "x"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := `
+load("schema.star", "schema")
+
+schema.Toggle(id = "t", name = "T", desc = "T", icon = "check", translations = ` + translations + `)
+
+def main():
+	return []
+`
+			_, err := runtime.NewApplet("locale.star", []byte(src))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"go.starlark.net/starlark"
+)
+
+// Locations lets users pick several labeled locations, such as home and work,
+// for apps that compare or connect places. The value is a JSON list with one
+// object per location, holding the same keys as a Location plus a label, so
+// apps can read it with config.list.
+type Locations struct {
+	SchemaField
+	starlarkLabels *starlark.List
+}
+
+func newLocations(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var (
+		id     starlark.String
+		name   starlark.String
+		desc   starlark.String
+		icon   starlark.String
+		labels *starlark.List
+	)
+
+	if err := starlark.UnpackArgs(
+		"Locations",
+		args, kwargs,
+		"id", &id,
+		"name", &name,
+		"desc", &desc,
+		"icon", &icon,
+		"labels?", &labels,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for Locations: %s", err)
+	}
+
+	s := &Locations{}
+	s.SchemaField.Type = "locations"
+	s.ID = id.GoString()
+	s.Name = name.GoString()
+	s.Description = desc.GoString()
+	s.Icon = icon.GoString()
+
+	if labels == nil {
+		labels = starlark.NewList(nil)
+	}
+	for i := 0; i < labels.Len(); i++ {
+		l, ok := starlark.AsString(labels.Index(i))
+		if !ok || l == "" {
+			return nil, fmt.Errorf(
+				"expected labels to be a list of non-empty strings but found: %s (at index %d)",
+				labels.Index(i),
+				i,
+			)
+		}
+		s.Labels = append(s.Labels, l)
+	}
+	s.starlarkLabels = labels
+
+	return s, nil
+}
+
+// DecodeLocations parses a Locations config value. An empty string is an
+// empty list.
+func DecodeLocations(value string) ([]map[string]string, error) {
+	locations, err := DecodeList(value)
+	if err != nil {
+		return nil, fmt.Errorf("expected a JSON list of locations: %w", err)
+	}
+	return locations, nil
+}
+
+// validateLocations checks that every location has a label, unique within
+// the field, and valid coordinates.
+func validateLocations(value string) error {
+	locations, err := DecodeLocations(value)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(locations))
+	for i, l := range locations {
+		label := l["label"]
+		if label == "" {
+			return fmt.Errorf("location %d has no label", i)
+		}
+		if seen[label] {
+			return fmt.Errorf("label %q used more than once", label)
+		}
+		seen[label] = true
+
+		lat, err := strconv.ParseFloat(l["lat"], 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return fmt.Errorf("%s: invalid latitude %q", label, l["lat"])
+		}
+		lng, err := strconv.ParseFloat(l["lng"], 64)
+		if err != nil || lng < -180 || lng > 180 {
+			return fmt.Errorf("%s: invalid longitude %q", label, l["lng"])
+		}
+	}
+	return nil
+}
+
+func (s *Locations) AsSchemaField() SchemaField {
+	return s.SchemaField
+}
+
+func (s *Locations) AttrNames() []string {
+	return []string{
+		"id", "name", "desc", "icon", "labels",
+	}
+}
+
+func (s *Locations) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "id":
+		return starlark.String(s.ID), nil
+
+	case "name":
+		return starlark.String(s.Name), nil
+
+	case "desc":
+		return starlark.String(s.Description), nil
+
+	case "icon":
+		return starlark.String(s.Icon), nil
+
+	case "labels":
+		return s.starlarkLabels, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (s *Locations) String() string       { return "Locations(...)" }
+func (s *Locations) Type() string         { return "Locations" }
+func (s *Locations) Freeze()              {}
+func (s *Locations) Truth() starlark.Bool { return true }
+
+func (s *Locations) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(s, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
@@ -0,0 +1,86 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+var locationsSource = `
+load("schema.star", "schema")
+
+def assert(success, message=None):
+	if not success:
+		fail(message or "assertion failed")
+
+l = schema.Locations(
+	id = "places",
+	name = "Places",
+	desc = "The places to compare.",
+	icon = "mapPin",
+	labels = ["Home", "Work"],
+	max = 2,
+)
+
+assert(l.id == "places")
+assert(l.name == "Places")
+assert(l.desc == "The places to compare.")
+assert(l.icon == "mapPin")
+assert(l.labels == ["Home", "Work"])
+
+def main(config):
+	places = config.list("places")
+	assert(len(places) == 2)
+	assert(places[0].get("label") == "Home")
+	assert(places[1]["lat"] == "42.3601")
+	return []
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [l],
+	)
+`
+
+func TestLocations(t *testing.T) {
+	app, err := runtime.NewApplet("locations.star", []byte(locationsSource))
+	require.NoError(t, err)
+
+	require.Len(t, app.Schema.Fields, 1)
+	assert.Equal(t, "locations", app.Schema.Fields[0].Type)
+	assert.Equal(t, []string{"Home", "Work"}, app.Schema.Fields[0].Labels)
+
+	screens, err := app.RunWithConfig(context.Background(), map[string]string{
+		"places": `[
+			{"label": "Home", "lat": "40.6781784", "lng": "-73.9441579", "locality": "Brooklyn"},
+			{"label": "Work", "lat": "42.3601", "lng": "-71.0589", "locality": "Boston"}
+		]`,
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, screens)
+}
+
+func TestLocationsValidateConfig(t *testing.T) {
+	app, err := runtime.NewApplet("locations.star", []byte(locationsSource))
+	require.NoError(t, err)
+	s := app.Schema
+
+	assert.NoError(t, s.ValidateConfig(map[string]string{"places": "[]"}))
+	assert.NoError(t, s.ValidateConfig(map[string]string{"places": `[{"label": "Gym", "lat": "0", "lng": "0"}]`}))
+
+	for name, value := range map[string]string{
+		"not a list":        `{"label": "Home"}`,
+		"missing label":     `[{"lat": "0", "lng": "0"}]`,
+		"duplicate label":   `[{"label": "Home", "lat": "0", "lng": "0"}, {"label": "Home", "lat": "1", "lng": "1"}]`,
+		"invalid latitude":  `[{"label": "Home", "lat": "91", "lng": "0"}]`,
+		"invalid longitude": `[{"label": "Home", "lat": "0", "lng": "east"}]`,
+		"too many":          `[{"label": "A", "lat": "0", "lng": "0"}, {"label": "B", "lat": "0", "lng": "0"}, {"label": "C", "lat": "0", "lng": "0"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, s.ValidateConfig(map[string]string{"places": value}))
+		})
+	}
+}
@@ -35,20 +35,30 @@ func LoadModule() (starlark.StringDict, error) {
 				Name: ModuleName,
 				Members: starlark.StringDict{
 					"Schema":        starlark.NewBuiltin("Schema", newSchema),
-					"Toggle":        starlark.NewBuiltin("Toggle", newToggle),
+					"Toggle":        starlark.NewBuiltin("Toggle", withFieldOptions(newToggle)),
 					"Option":        starlark.NewBuiltin("Option", newOption),
-					"Dropdown":      starlark.NewBuiltin("Dropdown", newDropdown),
-					"Location":      starlark.NewBuiltin("Location", newLocation),
-					"Text":          starlark.NewBuiltin("Text", newText),
-					"LocationBased": starlark.NewBuiltin("LocationBased", newLocationBased),
-					"DateTime":      starlark.NewBuiltin("DateTime", newDateTime),
-					"OAuth2":        starlark.NewBuiltin("OAuth2", newOAuth2),
-					"PhotoSelect":   starlark.NewBuiltin("PhotoSelect", newPhotoSelect),
-					"Typeahead":     starlark.NewBuiltin("Typeahead", newTypeahead),
+					"Dropdown":      starlark.NewBuiltin("Dropdown", withFieldOptions(newDropdown)),
+					"MultiSelect":   starlark.NewBuiltin("MultiSelect", withFieldOptions(newMultiSelect)),
+					"Duration":      starlark.NewBuiltin("Duration", withFieldOptions(newDuration)),
+					"FileUpload":    starlark.NewBuiltin("FileUpload", withFieldOptions(newFileUpload)),
+					"List":          starlark.NewBuiltin("List", withFieldOptions(newList)),
+					"Location":      starlark.NewBuiltin("Location", withFieldOptions(newLocation)),
+					"Locations":     starlark.NewBuiltin("Locations", withFieldOptions(newLocations)),
+					"Text":          starlark.NewBuiltin("Text", withFieldOptions(newText)),
+					"TimeRange":     starlark.NewBuiltin("TimeRange", withFieldOptions(newTimeRange)),
+					"LocationBased": starlark.NewBuiltin("LocationBased", withFieldOptions(newLocationBased)),
+					"Date":          starlark.NewBuiltin("Date", withFieldOptions(newDate)),
+					"DateTime":      starlark.NewBuiltin("DateTime", withFieldOptions(newDateTime)),
+					"OAuth2":        starlark.NewBuiltin("OAuth2", withFieldOptions(newOAuth2)),
+					"PhotoSelect":   starlark.NewBuiltin("PhotoSelect", withFieldOptions(newPhotoSelect)),
+					"Page":          starlark.NewBuiltin("Page", newPage),
+					"Typeahead":     starlark.NewBuiltin("Typeahead", withFieldOptions(newTypeahead)),
 					"Handler":       starlark.NewBuiltin("Handler", newHandler),
 					"HandlerType":   handlerType,
 					"Generated":     starlark.NewBuiltin("Generated", newGenerated),
-					"Color":         starlark.NewBuiltin("Color", newColor),
+					"Group":         starlark.NewBuiltin("Group", withFieldOptions(newGroup)),
+					"Section":       starlark.NewBuiltin("Section", withFieldOptions(newSection)),
+					"Color":         starlark.NewBuiltin("Color", withFieldOptions(newColor)),
 					"Notification":  starlark.NewBuiltin("Notification", newNotification),
 					"Sound":         starlark.NewBuiltin("Sound", newSound),
 				},
@@ -77,6 +87,8 @@ func newSchema(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple
 		fields        *starlark.List
 		handlers      *starlark.List
 		notifications *starlark.List
+		configVersion = starlark.MakeInt(0)
+		migrate       *starlark.Function
 	)
 
 	if err := starlark.UnpackArgs(
@@ -86,6 +98,8 @@ func newSchema(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple
 		"fields?", &fields,
 		"handlers?", &handlers,
 		"notifications?", &notifications,
+		"config_version?", &configVersion,
+		"migrate?", &migrate,
 	); err != nil {
 		return nil, fmt.Errorf("unpacking arguments for Schema: %s", err)
 	}
@@ -94,9 +108,19 @@ func newSchema(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple
 		return nil, fmt.Errorf("only schema version 1 is supported, not: %s", version.GoString())
 	}
 
+	cv, ok := configVersion.Int64()
+	if !ok || cv < 0 {
+		return nil, fmt.Errorf("config_version must be a non-negative integer")
+	}
+	if migrate != nil && cv == 0 {
+		return nil, fmt.Errorf("migrate requires a config_version")
+	}
+
 	s := &StarlarkSchema{
 		Schema: Schema{
-			Version: version.GoString(),
+			Version:       version.GoString(),
+			ConfigVersion: int(cv),
+			Migrate:       migrate,
 		},
 		Handlers:              map[string]SchemaHandler{},
 		starlarkFields:        fields,
@@ -182,6 +206,7 @@ func (s StarlarkSchema) AttrNames() []string {
 		"version",
 		"fields",
 		"handlers",
+		"config_version",
 	}
 }
 
@@ -196,6 +221,9 @@ func (s StarlarkSchema) Attr(name string) (starlark.Value, error) {
 	case "handlers":
 		return s.starlarkHandlers, nil
 
+	case "config_version":
+		return starlark.MakeInt(s.ConfigVersion), nil
+
 	case "notifications":
 		return s.starlarkNotifications, nil
 
@@ -0,0 +1,193 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"go.starlark.net/starlark"
+)
+
+// MultiSelect lets users pick any number of options. The selected values are
+// passed to the applet as a JSON list of strings.
+type MultiSelect struct {
+	SchemaField
+	starlarkOptions *starlark.List
+	starlarkDefault *starlark.List
+}
+
+func newMultiSelect(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var (
+		id      starlark.String
+		name    starlark.String
+		desc    starlark.String
+		icon    starlark.String
+		options *starlark.List
+		def     *starlark.List
+	)
+
+	if err := starlark.UnpackArgs(
+		"MultiSelect",
+		args, kwargs,
+		"id", &id,
+		"name", &name,
+		"desc", &desc,
+		"icon", &icon,
+		"options", &options,
+		"default?", &def,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for MultiSelect: %s", err)
+	}
+
+	s := &MultiSelect{}
+	s.SchemaField.Type = "multiselect"
+	s.ID = id.GoString()
+	s.Name = name.GoString()
+	s.Description = desc.GoString()
+	s.Icon = icon.GoString()
+
+	var optionVal starlark.Value
+	optionIter := options.Iterate()
+	defer optionIter.Done()
+	for i := 0; optionIter.Next(&optionVal); i++ {
+		if _, isNone := optionVal.(starlark.NoneType); isNone {
+			continue
+		}
+
+		o, ok := optionVal.(*Option)
+		if !ok {
+			return nil, fmt.Errorf(
+				"expected options to be a list of Option but found: %s (at index %d)",
+				optionVal.Type(),
+				i,
+			)
+		}
+
+		s.Options = append(s.Options, o.SchemaOption)
+	}
+	s.starlarkOptions = options
+
+	if def == nil {
+		def = starlark.NewList(nil)
+	}
+
+	values := make([]string, 0, def.Len())
+	for i := 0; i < def.Len(); i++ {
+		v, ok := starlark.AsString(def.Index(i))
+		if !ok {
+			return nil, fmt.Errorf(
+				"expected default to be a list of strings but found: %s (at index %d)",
+				def.Index(i).Type(),
+				i,
+			)
+		}
+		values = append(values, v)
+	}
+	if err := validateMultiSelect(s.SchemaField, values); err != nil {
+		return nil, fmt.Errorf("invalid default for MultiSelect: %w", err)
+	}
+	if len(values) > 0 {
+		s.Default = EncodeMultiSelect(values)
+	}
+	s.starlarkDefault = def
+
+	return s, nil
+}
+
+// validateMultiSelect checks that values only holds option values of field,
+// each at most once.
+func validateMultiSelect(field SchemaField, values []string) error {
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if seen[v] {
+			return fmt.Errorf("%q selected more than once", v)
+		}
+		seen[v] = true
+
+		found := false
+		for _, o := range field.Options {
+			if o.Value == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%q is not one of the options", v)
+		}
+	}
+	return nil
+}
+
+// EncodeMultiSelect serializes selected values the way they're stored in
+// config.
+func EncodeMultiSelect(values []string) string {
+	if values == nil {
+		values = []string{}
+	}
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+// DecodeMultiSelect parses a MultiSelect config value. An empty string is an
+// empty selection.
+func DecodeMultiSelect(value string) ([]string, error) {
+	values := []string{}
+	if value == "" {
+		return values, nil
+	}
+	if err := json.Unmarshal([]byte(value), &values); err != nil {
+		return nil, fmt.Errorf("expected a JSON list of strings: %w", err)
+	}
+	return values, nil
+}
+
+func (s *MultiSelect) AsSchemaField() SchemaField {
+	return s.SchemaField
+}
+
+func (s *MultiSelect) AttrNames() []string {
+	return []string{
+		"id", "name", "desc", "icon", "default", "options",
+	}
+}
+
+func (s *MultiSelect) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "id":
+		return starlark.String(s.ID), nil
+
+	case "name":
+		return starlark.String(s.Name), nil
+
+	case "desc":
+		return starlark.String(s.Description), nil
+
+	case "icon":
+		return starlark.String(s.Icon), nil
+
+	case "default":
+		return s.starlarkDefault, nil
+
+	case "options":
+		return s.starlarkOptions, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (s *MultiSelect) String() string       { return "MultiSelect(...)" }
+func (s *MultiSelect) Type() string         { return "MultiSelect" }
+func (s *MultiSelect) Freeze()              {}
+func (s *MultiSelect) Truth() starlark.Bool { return true }
+
+func (s *MultiSelect) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(s, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
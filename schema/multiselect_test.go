@@ -0,0 +1,124 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+)
+
+var multiSelectSource = `
+load("schema.star", "schema")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+options = [
+	schema.Option(
+		display = "Red Line",
+		value = "red",
+	),
+	schema.Option(
+		display = "Blue Line",
+		value = "blue",
+	),
+	schema.Option(
+		display = "Green Line",
+		value = "green",
+	),
+]
+
+s = schema.MultiSelect(
+	id = "lines",
+	name = "Lines",
+	desc = "The transit lines to show.",
+	icon = "train",
+	options = options,
+	default = ["red", "green"],
+)
+
+assert(s.id == "lines")
+assert(s.name == "Lines")
+assert(s.desc == "The transit lines to show.")
+assert(s.icon == "train")
+assert(s.default == ["red", "green"])
+
+assert(s.options[1].display == "Blue Line")
+assert(s.options[1].value == "blue")
+
+def main():
+	return []
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [s],
+	)
+`
+
+func TestMultiSelect(t *testing.T) {
+	app, err := runtime.NewApplet("multiselect.star", []byte(multiSelectSource))
+	require.NoError(t, err)
+
+	screens, err := app.Run(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, screens)
+
+	require.Len(t, app.Schema.Fields, 1)
+	assert.Equal(t, "multiselect", app.Schema.Fields[0].Type)
+	assert.Equal(t, `["red","green"]`, app.Schema.Fields[0].Default)
+}
+
+func TestMultiSelectBadDefault(t *testing.T) {
+	src := `
+load("schema.star", "schema")
+
+s = schema.MultiSelect(
+	id = "lines",
+	name = "Lines",
+	desc = "The transit lines to show.",
+	icon = "train",
+	options = [schema.Option(display = "Red Line", value = "red")],
+	default = ["purple"],
+)
+
+def main():
+	return []
+`
+	_, err := runtime.NewApplet("multiselect.star", []byte(src))
+	assert.ErrorContains(t, err, "not one of the options")
+}
+
+func TestMultiSelectValidateConfig(t *testing.T) {
+	app, err := runtime.NewApplet("multiselect.star", []byte(multiSelectSource))
+	require.NoError(t, err)
+
+	s := app.Schema
+	assert.NoError(t, s.ValidateConfig(map[string]string{}))
+	assert.NoError(t, s.ValidateConfig(map[string]string{"lines": ""}))
+	assert.NoError(t, s.ValidateConfig(map[string]string{"lines": `["blue"]`}))
+	assert.NoError(t, s.ValidateConfig(map[string]string{"lines": schema.EncodeMultiSelect([]string{"red", "blue", "green"})}))
+
+	assert.Error(t, s.ValidateConfig(map[string]string{"lines": "red"}))
+	assert.Error(t, s.ValidateConfig(map[string]string{"lines": `["purple"]`}))
+	assert.Error(t, s.ValidateConfig(map[string]string{"lines": `["red", "red"]`}))
+}
+
+func TestDecodeMultiSelect(t *testing.T) {
+	values, err := schema.DecodeMultiSelect(`["a", "b"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, values)
+
+	values, err = schema.DecodeMultiSelect("")
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+
+	_, err = schema.DecodeMultiSelect(`[1, 2]`)
+	assert.Error(t, err)
+
+	assert.Equal(t, "[]", schema.EncodeMultiSelect(nil))
+}
@@ -7,6 +7,11 @@ import (
 	"go.starlark.net/starlark"
 )
 
+// OAuth2 lets users connect an account with an OAuth2 provider. The config UI
+// sends the user through the authorization code flow, with PKCE if pkce is
+// set, or through the device authorization grant if the field has a
+// device_authorization_endpoint. Either way, the handler is called with
+// OAuth2Params and returns the value to store in config.
 type OAuth2 struct {
 	SchemaField
 	starlarkScopes *starlark.List
@@ -27,6 +32,9 @@ func newOAuth2(
 		clientID     starlark.String
 		authEndpoint starlark.String
 		scopes       *starlark.List
+
+		deviceEndpoint starlark.String
+		pkce           starlark.Bool
 	)
 
 	if err := starlark.UnpackArgs(
@@ -38,8 +46,10 @@ func newOAuth2(
 		"icon", &icon,
 		"handler", &handler,
 		"client_id", &clientID,
-		"authorization_endpoint", &authEndpoint,
+		"authorization_endpoint?", &authEndpoint,
 		"scopes", &scopes,
+		"device_authorization_endpoint?", &deviceEndpoint,
+		"pkce?", &pkce,
 	); err != nil {
 		return nil, fmt.Errorf("unpacking arguments for OAuth2: %s", err)
 	}
@@ -54,8 +64,14 @@ func newOAuth2(
 	s.StarlarkHandler = handler
 	s.ClientID = clientID.GoString()
 	s.AuthorizationEndpoint = authEndpoint.GoString()
+	s.DeviceAuthorizationEndpoint = deviceEndpoint.GoString()
+	s.PKCE = bool(pkce)
 	s.starlarkScopes = scopes
 
+	if s.AuthorizationEndpoint == "" && s.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("OAuth2 needs an authorization_endpoint or a device_authorization_endpoint")
+	}
+
 	if s.starlarkScopes != nil {
 		scopesIter := s.starlarkScopes.Iterate()
 		defer scopesIter.Done()
@@ -89,6 +105,7 @@ func (s *OAuth2) AsSchemaField() SchemaField {
 func (s *OAuth2) AttrNames() []string {
 	return []string{
 		"id", "name", "desc", "icon", "handler", "client_id", "authorization_endpoint", "scopes",
+		"device_authorization_endpoint", "pkce",
 	}
 }
 
@@ -119,6 +136,12 @@ func (s *OAuth2) Attr(name string) (starlark.Value, error) {
 	case "scopes":
 		return s.starlarkScopes, nil
 
+	case "device_authorization_endpoint":
+		return starlark.String(s.DeviceAuthorizationEndpoint), nil
+
+	case "pkce":
+		return starlark.Bool(s.PKCE), nil
+
 	default:
 		return nil, nil
 	}
@@ -2,10 +2,14 @@ package schema_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
 )
 
 var oauth2Source = `
@@ -55,3 +59,100 @@ func TestOAuth2(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, screens)
 }
+
+func TestOAuth2PKCEAndDeviceAuthorization(t *testing.T) {
+	code := `
+load("schema.star", "schema")
+
+def assert(success, message = None):
+    if not success:
+        fail(message or "assertion failed")
+
+def oauth_handler(params):
+    return ""
+
+t = schema.OAuth2(
+    id = "auth",
+    name = "Microsoft",
+    desc = "Connect your Microsoft account.",
+    icon = "microsoft",
+    handler = oauth_handler,
+    client_id = "the-client-id",
+    device_authorization_endpoint = "https://example.com/devicecode",
+    scopes = ["calendars.read"],
+    pkce = True,
+)
+
+assert(t.device_authorization_endpoint == "https://example.com/devicecode")
+assert(t.authorization_endpoint == "")
+assert(t.pkce)
+
+def main():
+    return []
+`
+
+	app, err := runtime.NewApplet("oauth2.star", []byte(code))
+	assert.NoError(t, err)
+	_, err = app.Run(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestOAuth2WithoutEndpoint(t *testing.T) {
+	code := `
+load("schema.star", "schema")
+
+t = schema.OAuth2(
+    id = "auth",
+    name = "GitHub",
+    desc = "Connect your GitHub account.",
+    icon = "github",
+    handler = lambda params: "",
+    client_id = "the-client-id",
+    scopes = [],
+)
+
+def main():
+    return []
+`
+
+	_, err := runtime.NewApplet("oauth2.star", []byte(code))
+	assert.ErrorContains(t, err, "authorization_endpoint")
+}
+
+func TestStartDeviceAuthorization(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "the-client-id", r.Form.Get("client_id"))
+		assert.Equal(t, "read write", r.Form.Get("scope"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"device_code": "dev-123",
+			"user_code": "ABCD-EFGH",
+			"verification_uri": "https://example.com/device",
+			"expires_in": 900,
+			"interval": 5
+		}`))
+	}))
+	defer srv.Close()
+
+	auth, err := schema.StartDeviceAuthorization(context.Background(), schema.SchemaField{
+		ID:                          "auth",
+		Type:                        "oauth2",
+		ClientID:                    "the-client-id",
+		DeviceAuthorizationEndpoint: srv.URL,
+		Scopes:                      []string{"read", "write"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "dev-123", auth.DeviceCode)
+	assert.Equal(t, "ABCD-EFGH", auth.UserCode)
+	assert.Equal(t, "https://example.com/device", auth.VerificationURI)
+	assert.Equal(t, 5, auth.Interval)
+	assert.InDelta(t, 900, auth.ExpiresIn, 5)
+
+	_, err = schema.StartDeviceAuthorization(context.Background(), schema.SchemaField{
+		ID:   "auth",
+		Type: "oauth2",
+	})
+	assert.Error(t, err)
+}
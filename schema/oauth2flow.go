@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Grant types passed to OAuth2 handlers in OAuth2Params.
+const (
+	OAuth2AuthorizationCodeGrant = "authorization_code"
+	OAuth2DeviceCodeGrant        = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// OAuth2Params are the parameters an OAuth2 handler is called with, encoded
+// as JSON. They're what the handler needs to request a token from the
+// provider's token endpoint.
+type OAuth2Params struct {
+	GrantType string `json:"grant_type"`
+	ClientID  string `json:"client_id"`
+
+	// Set for the authorization code grant. CodeVerifier is only set if the
+	// field uses PKCE.
+	Code         string `json:"code,omitempty"`
+	RedirectURI  string `json:"redirect_uri,omitempty"`
+	CodeVerifier string `json:"code_verifier,omitempty"`
+
+	// Set for the device code grant.
+	DeviceCode string `json:"device_code,omitempty"`
+}
+
+// DeviceAuthorization is the response to a device authorization request. The
+// config UI shows the user code and verification URI, then calls the field's
+// handler with the device code every Interval seconds until it returns a
+// value or the authorization expires.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in,omitempty"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+// StartDeviceAuthorization requests a device code for an OAuth2 field with a
+// device_authorization_endpoint. This needs to happen on the server, since
+// providers don't allow these requests from browsers.
+func StartDeviceAuthorization(ctx context.Context, field SchemaField) (*DeviceAuthorization, error) {
+	if field.Type != "oauth2" || field.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("field %q doesn't support device authorization", field.ID)
+	}
+
+	conf := &oauth2.Config{
+		ClientID: field.ClientID,
+		Scopes:   field.Scopes,
+		Endpoint: oauth2.Endpoint{
+			DeviceAuthURL: field.DeviceAuthorizationEndpoint,
+		},
+	}
+
+	resp, err := conf.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("requesting device authorization: %w", err)
+	}
+
+	auth := &DeviceAuthorization{
+		DeviceCode:              resp.DeviceCode,
+		UserCode:                resp.UserCode,
+		VerificationURI:         resp.VerificationURI,
+		VerificationURIComplete: resp.VerificationURIComplete,
+		Interval:                int(resp.Interval),
+	}
+	if !resp.Expiry.IsZero() {
+		auth.ExpiresIn = int(time.Until(resp.Expiry).Seconds())
+	}
+	return auth, nil
+}
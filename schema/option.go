@@ -20,6 +20,8 @@ func newOption(
 	var (
 		display starlark.String
 		value   starlark.String
+
+		translations *starlark.Dict
 	)
 
 	if err := starlark.UnpackArgs(
@@ -27,6 +29,7 @@ func newOption(
 		args, kwargs,
 		"display", &display,
 		"value", &value,
+		"translations?", &translations,
 	); err != nil {
 		return nil, fmt.Errorf("unpacking arguments for Option: %s", err)
 	}
@@ -36,6 +39,13 @@ func newOption(
 	s.SchemaOption.Display = display.GoString()
 	s.SchemaOption.Value = value.GoString()
 
+	if translations != nil {
+		var err error
+		if s.SchemaOption.Translations, err = optionTranslationsArg(translations); err != nil {
+			return nil, fmt.Errorf("Option: %w", err)
+		}
+	}
+
 	return s, nil
 }
 
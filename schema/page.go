@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"go.starlark.net/starlark"
+)
+
+// Page is one page of options returned by a paginated Typeahead handler.
+// NextCursor is passed back to the handler to fetch the following page, and
+// is empty on the last one.
+type Page struct {
+	NextCursor      string
+	starlarkOptions *starlark.List
+}
+
+// encodedPage is how a Page is sent to config UIs.
+type encodedPage struct {
+	Options    json.RawMessage `json:"options"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+func newPage(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var (
+		options    *starlark.List
+		nextCursor starlark.String
+	)
+
+	if err := starlark.UnpackArgs(
+		"Page",
+		args, kwargs,
+		"options", &options,
+		"next_cursor?", &nextCursor,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for Page: %s", err)
+	}
+
+	return &Page{
+		NextCursor:      nextCursor.GoString(),
+		starlarkOptions: options,
+	}, nil
+}
+
+// EncodeHandlerOptions serializes what an options handler returned: either a
+// list of options, which is encoded as by EncodeOptions, or a Page, which is
+// encoded as an object holding the options and the next cursor.
+func EncodeHandlerOptions(val starlark.Value) (string, error) {
+	page, ok := val.(*Page)
+	if !ok {
+		return EncodeOptions(val)
+	}
+
+	options, err := EncodeOptions(page.starlarkOptions)
+	if err != nil {
+		return "", err
+	}
+
+	js, err := json.Marshal(encodedPage{
+		Options:    json.RawMessage(options),
+		NextCursor: page.NextCursor,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(js), nil
+}
+
+func (p *Page) AttrNames() []string {
+	return []string{"options", "next_cursor"}
+}
+
+func (p *Page) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "options":
+		return p.starlarkOptions, nil
+
+	case "next_cursor":
+		return starlark.String(p.NextCursor), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (p *Page) String() string       { return "Page(...)" }
+func (p *Page) Type() string         { return "Page" }
+func (p *Page) Freeze()              {}
+func (p *Page) Truth() starlark.Bool { return true }
+
+func (p *Page) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(p, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -30,30 +31,60 @@ type Schema struct {
 	Fields        []SchemaField  `json:"schema" validate:"dive"`
 	Notifications []Notification `json:"notifications,omitempty" validate:"dive"`
 
+	// ConfigVersion is bumped by app authors whenever they change the schema
+	// in a way that needs existing config to be migrated by Migrate.
+	ConfigVersion int                `json:"config_version,omitempty" validate:"min=0"`
+	Migrate       *starlark.Function `json:"-"`
+
 	Handlers map[string]SchemaHandler `json:"-"`
 }
 
 // SchemaField represents an item in the config used to confgure an applet.
 type SchemaField struct {
-	Type        string            `json:"type" validate:"required,oneof=color datetime dropdown generated location locationbased onoff radio text typeahead oauth2 oauth1 png notification"`
+	Type        string            `json:"type" validate:"required,oneof=color date datetime dropdown duration file generated group list location locations locationbased multiselect onoff radio text timerange typeahead oauth2 oauth1 png notification section"`
 	ID          string            `json:"id" validate:"required,excludesall=$"`
-	Name        string            `json:"name,omitempty" validate:"required_for=datetime dropdown location locationbased onoff radio text typeahead png"`
+	Name        string            `json:"name,omitempty" validate:"required_for=date datetime dropdown duration file group list location locations locationbased multiselect onoff radio section text timerange typeahead png"`
 	Description string            `json:"description,omitempty"`
 	Icon        string            `json:"icon,omitempty" validate:"forbidden_for=generated"`
 	Visibility  *SchemaVisibility `json:"visibility,omitempty" validate:"omitempty"`
+	VisibleIf   string            `json:"visible_if,omitempty"`
+
+	Translations map[string]FieldTranslation `json:"translations,omitempty"`
+
+	Required bool     `json:"required,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
 
 	Default string         `json:"default,omitempty" validate:"required_for=dropdown onoff radio"`
-	Options []SchemaOption `json:"options,omitempty" validate:"required_for=dropdown radio,dive"`
+	Options []SchemaOption `json:"options,omitempty" validate:"required_for=dropdown multiselect radio,dive"`
 	Palette []string       `json:"palette,omitempty"`
 	Sounds  []SchemaSound  `json:"sounds,omitempty" validate:"required_for=notification,dive"`
 
+	Timezone string   `json:"timezone,omitempty"`
+	Units    []string `json:"units,omitempty"`
+
+	Labels []string `json:"labels,omitempty"`
+
+	Accept  []string `json:"accept,omitempty"`
+	MaxSize int      `json:"max_size,omitempty"`
+
+	Fields    []SchemaField `json:"fields,omitempty" validate:"required_for=group list section,dive"`
+	MaxItems  int           `json:"max_items,omitempty"`
+	Collapsed bool          `json:"collapsed,omitempty"`
+
 	Source          string             `json:"source,omitempty" validate:"required_for=generated"`
 	Handler         string             `json:"handler,omitempty" validate:"required_for=generated locationbased typeahead oauth2"`
 	StarlarkHandler *starlark.Function `json:"-"`
 
-	ClientID              string   `json:"client_id,omitempty" validate:"required_for=oauth2"`
-	AuthorizationEndpoint string   `json:"authorization_endpoint,omitempty" validate:"required_for=oauth2"`
-	Scopes                []string `json:"scopes,omitempty" validate:"required_for=oauth2"`
+	MinQueryLength int `json:"min_query_length,omitempty"`
+	Debounce       int `json:"debounce_ms,omitempty"`
+
+	ClientID                    string   `json:"client_id,omitempty" validate:"required_for=oauth2"`
+	AuthorizationEndpoint       string   `json:"authorization_endpoint,omitempty"`
+	DeviceAuthorizationEndpoint string   `json:"device_authorization_endpoint,omitempty"`
+	PKCE                        bool     `json:"pkce,omitempty"`
+	Scopes                      []string `json:"scopes,omitempty" validate:"required_for=oauth2"`
 }
 
 // SchemaOption represents an option in a field. For example, an item in a drop
@@ -62,6 +93,8 @@ type SchemaOption struct {
 	Display string `json:"display"`
 	Text    string `json:"text" validate:"required"` // The same as display, for legacy reasons.
 	Value   string `json:"value" validate:"required"`
+
+	Translations map[string]string `json:"translations,omitempty"`
 }
 
 // SchemaSound represents a sound that can be played by the applet.
@@ -90,6 +123,30 @@ type HandlerReturnType int8
 type SchemaHandler struct {
 	Function   *starlark.Function
 	ReturnType HandlerReturnType
+
+	// MinQueryLength is the shortest query a Typeahead handler is called
+	// with. Shorter queries return no options without calling it.
+	MinQueryLength int
+}
+
+// Field returns the field with the given ID, looking inside groups and
+// sections.
+func (s *Schema) Field(id string) (SchemaField, bool) {
+	var find func([]SchemaField) (SchemaField, bool)
+	find = func(fields []SchemaField) (SchemaField, bool) {
+		for _, f := range fields {
+			if f.ID == id {
+				return f, true
+			}
+			if isFieldGroup(f) {
+				if found, ok := find(f.Fields); ok {
+					return found, true
+				}
+			}
+		}
+		return SchemaField{}, false
+	}
+	return find(s.Fields)
 }
 
 func (s Schema) MarshalJSON() ([]byte, error) {
@@ -158,8 +215,32 @@ func FromStarlark(
 		return nil, err
 	}
 
-	for i := range schema.Fields {
-		schemaField := &schema.Fields[i]
+	if err := validateVisibleIf(schema.Fields); err != nil {
+		return nil, err
+	}
+
+	if err := registerHandlers(schema, schema.Fields, globals); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// registerHandlers adds the handlers of fields, and of fields nested in groups,
+// to the schema's handlers.
+func registerHandlers(schema *Schema, fields []SchemaField, globals starlark.StringDict) error {
+	for i := range fields {
+		schemaField := &fields[i]
+
+		if isFieldGroup(*schemaField) {
+			// fields inside groups are copied, so that registering their
+			// handlers doesn't modify the Group they came from
+			schemaField.Fields = slices.Clone(schemaField.Fields)
+			if err := registerHandlers(schema, schemaField.Fields, globals); err != nil {
+				return err
+			}
+			continue
+		}
 
 		var handlerFun *starlark.Function
 		if schemaField.StarlarkHandler != nil {
@@ -169,7 +250,7 @@ func FromStarlark(
 			// a function reference
 			handlerValue, ok := globals[schemaField.Handler]
 			if !ok {
-				return nil, fmt.Errorf(
+				return fmt.Errorf(
 					"field %d references non-existent handler \"%s\"",
 					i,
 					schemaField.Handler)
@@ -177,7 +258,7 @@ func FromStarlark(
 
 			handlerFun, ok = handlerValue.(*starlark.Function)
 			if !ok {
-				return nil, fmt.Errorf(
+				return fmt.Errorf(
 					"field %d references \"%s\" which is not a function",
 					i, schemaField.Handler)
 			}
@@ -197,18 +278,22 @@ func FromStarlark(
 			case "oauth1":
 				handlerType = ReturnString
 			default:
-				return nil, fmt.Errorf(
+				return fmt.Errorf(
 					"field %d of type \"%s\" can't have a handler function",
 					i, schemaField.Type)
 			}
 
 			// prepend the field ID to the handler name to avoid conflicts
 			schemaField.Handler = fmt.Sprintf("%s$%s", schemaField.ID, schemaField.Handler)
-			schema.Handlers[schemaField.Handler] = SchemaHandler{Function: handlerFun, ReturnType: handlerType}
+			schema.Handlers[schemaField.Handler] = SchemaHandler{
+				Function:       handlerFun,
+				ReturnType:     handlerType,
+				MinQueryLength: schemaField.MinQueryLength,
+			}
 		}
 	}
 
-	return schema, nil
+	return nil
 }
 
 // Encodes a list of schema options into validated json.
@@ -0,0 +1,253 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/hashstructure/v2"
+	starlibtime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+)
+
+// TimeOfDayFormat is how the start and end of a TimeRange are serialized.
+const TimeOfDayFormat = "15:04"
+
+// Weekdays are the day names a TimeRange uses, indexed by time.Weekday.
+var Weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// TimeRange lets users pick a daily window of time, such as quiet hours or a
+// commute, optionally limited to some days of the week. It's serialized in
+// config as a JSON object, see TimeRangeValue.
+type TimeRange struct {
+	SchemaField
+	starlarkDefault starlark.Value
+}
+
+func newTimeRange(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var (
+		id   starlark.String
+		name starlark.String
+		desc starlark.String
+		icon starlark.String
+		def  *starlark.Dict
+	)
+
+	if err := starlark.UnpackArgs(
+		"TimeRange",
+		args, kwargs,
+		"id", &id,
+		"name", &name,
+		"desc", &desc,
+		"icon", &icon,
+		"default?", &def,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for TimeRange: %s", err)
+	}
+
+	s := &TimeRange{}
+	s.SchemaField.Type = "timerange"
+	s.ID = id.GoString()
+	s.Name = name.GoString()
+	s.Description = desc.GoString()
+	s.Icon = icon.GoString()
+	s.starlarkDefault = starlark.None
+
+	if def != nil {
+		tree, err := unmarshalStarlark(def)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default for TimeRange: %s", err)
+		}
+
+		js, err := json.Marshal(tree)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default for TimeRange: %s", err)
+		}
+
+		if _, err := ParseTimeRange(string(js)); err != nil {
+			return nil, fmt.Errorf("invalid default for TimeRange: %s", err)
+		}
+
+		s.Default = string(js)
+		s.starlarkDefault = def
+	}
+
+	return s, nil
+}
+
+func (s *TimeRange) AsSchemaField() SchemaField {
+	return s.SchemaField
+}
+
+func (s *TimeRange) AttrNames() []string {
+	return []string{
+		"id", "name", "desc", "icon", "default",
+	}
+}
+
+func (s *TimeRange) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "id":
+		return starlark.String(s.ID), nil
+
+	case "name":
+		return starlark.String(s.Name), nil
+
+	case "desc":
+		return starlark.String(s.Description), nil
+
+	case "icon":
+		return starlark.String(s.Icon), nil
+
+	case "default":
+		return s.starlarkDefault, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (s *TimeRange) String() string       { return "TimeRange(...)" }
+func (s *TimeRange) Type() string         { return "TimeRange" }
+func (s *TimeRange) Freeze()              {}
+func (s *TimeRange) Truth() starlark.Bool { return true }
+
+func (s *TimeRange) Hash() (uint32, error) {
+	sum, err := hashstructure.Hash(s, hashstructure.FormatV2, nil)
+	return uint32(sum), err
+}
+
+// TimeRangeValue is the config value of a TimeRange field, for example
+// {"start": "22:00", "end": "07:00", "days": ["fri", "sat"]}.
+//
+// A range whose end is before its start wraps past midnight, and one whose
+// start and end are equal covers the whole day. Days lists the days the range
+// starts on, with no days meaning every day.
+type TimeRangeValue struct {
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	Days  []string `json:"days,omitempty"`
+
+	start, end time.Duration
+}
+
+// ParseTimeRange parses and validates a TimeRange config value.
+func ParseTimeRange(value string) (*TimeRangeValue, error) {
+	v := &TimeRangeValue{}
+	if err := json.Unmarshal([]byte(value), v); err != nil {
+		return nil, fmt.Errorf("expected a JSON object with start and end: %w", err)
+	}
+
+	var err error
+	if v.start, err = parseTimeOfDay(v.Start); err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	if v.end, err = parseTimeOfDay(v.End); err != nil {
+		return nil, fmt.Errorf("end: %w", err)
+	}
+
+	for _, d := range v.Days {
+		if !slices.Contains(Weekdays, d) {
+			return nil, fmt.Errorf("unknown day %q, expected one of %s", d, strings.Join(Weekdays, ", "))
+		}
+	}
+
+	return v, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse(TimeOfDayFormat, s)
+	if err != nil {
+		return 0, fmt.Errorf("expected a time formatted as HH:MM, got %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t, in its own location, falls within the range.
+func (v *TimeRangeValue) Contains(t time.Time) bool {
+	sinceMidnight := time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	day := t.Weekday()
+
+	switch {
+	case v.start == v.end:
+		// whole day
+	case v.start < v.end:
+		if sinceMidnight < v.start || sinceMidnight >= v.end {
+			return false
+		}
+	default:
+		// wraps past midnight; after midnight, the range started the day before
+		if sinceMidnight < v.end {
+			day = (day + 6) % 7
+		} else if sinceMidnight < v.start {
+			return false
+		}
+	}
+
+	return len(v.Days) == 0 || slices.Contains(v.Days, Weekdays[day])
+}
+
+func (v *TimeRangeValue) String() string       { return fmt.Sprintf("TimeRange(%s-%s)", v.Start, v.End) }
+func (v *TimeRangeValue) Type() string         { return "TimeRange" }
+func (v *TimeRangeValue) Freeze()              {}
+func (v *TimeRangeValue) Truth() starlark.Bool { return true }
+
+func (v *TimeRangeValue) Hash() (uint32, error) {
+	return starlark.String(v.String()).Hash()
+}
+
+func (v *TimeRangeValue) AttrNames() []string {
+	return []string{"start", "end", "days", "contains"}
+}
+
+func (v *TimeRangeValue) Attr(name string) (starlark.Value, error) {
+	switch name {
+
+	case "start":
+		return starlark.String(v.Start), nil
+
+	case "end":
+		return starlark.String(v.End), nil
+
+	case "days":
+		days := make([]starlark.Value, 0, len(v.Days))
+		for _, d := range v.Days {
+			days = append(days, starlark.String(d))
+		}
+		return starlark.NewList(days), nil
+
+	case "contains":
+		return starlark.NewBuiltin("contains", v.contains), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func (v *TimeRangeValue) contains(
+	thread *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var t starlibtime.Time
+
+	if err := starlark.UnpackPositionalArgs(
+		"contains", args, kwargs, 1,
+		&t,
+	); err != nil {
+		return nil, fmt.Errorf("unpacking arguments for TimeRange.contains: %s", err)
+	}
+
+	return starlark.Bool(v.Contains(time.Time(t))), nil
+}
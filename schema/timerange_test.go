@@ -0,0 +1,104 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+)
+
+var timeRangeSource = `
+load("render.star", "render")
+load("schema.star", "schema")
+load("time.star", "time")
+
+def assert(success, message=None):
+    if not success:
+        fail(message or "assertion failed")
+
+r = schema.TimeRange(
+	id = "quiet_hours",
+	name = "Quiet Hours",
+	desc = "When to dim the display.",
+	icon = "moon",
+	default = {"start": "22:00", "end": "07:00"},
+)
+
+assert(r.id == "quiet_hours")
+assert(r.name == "Quiet Hours")
+assert(r.desc == "When to dim the display.")
+assert(r.icon == "moon")
+assert(r.default["start"] == "22:00")
+
+def main(config):
+	quiet = config.time_range("quiet_hours")
+	assert(quiet.start == "22:00")
+	assert(quiet.end == "07:00")
+	assert(quiet.days == ["fri", "sat"])
+
+	loc = "America/New_York"
+	assert(quiet.contains(time.time(year = 2024, month = 3, day = 1, hour = 23, location = loc)))
+	assert(quiet.contains(time.time(year = 2024, month = 3, day = 2, hour = 3, location = loc)))
+	assert(not quiet.contains(time.time(year = 2024, month = 3, day = 2, hour = 12, location = loc)))
+	assert(not quiet.contains(time.time(year = 2024, month = 3, day = 4, hour = 3, location = loc)))
+
+	assert(config.time_range("missing") == None)
+	return []
+`
+
+func TestTimeRange(t *testing.T) {
+	app, err := runtime.NewApplet("time_range.star", []byte(timeRangeSource))
+	require.NoError(t, err)
+
+	screens, err := app.RunWithConfig(context.Background(), map[string]string{
+		"quiet_hours": `{"start": "22:00", "end": "07:00", "days": ["fri", "sat"]}`,
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, screens)
+}
+
+func TestTimeRangeContains(t *testing.T) {
+	at := func(weekday time.Weekday, hour, min int) time.Time {
+		// 2024-03-03 is a Sunday
+		return time.Date(2024, 3, 3+int(weekday), hour, min, 0, 0, time.UTC)
+	}
+
+	commute, err := schema.ParseTimeRange(`{"start": "07:30", "end": "09:00", "days": ["mon", "tue", "wed", "thu", "fri"]}`)
+	require.NoError(t, err)
+	assert.True(t, commute.Contains(at(time.Monday, 7, 30)))
+	assert.True(t, commute.Contains(at(time.Friday, 8, 59)))
+	assert.False(t, commute.Contains(at(time.Friday, 9, 0)))
+	assert.False(t, commute.Contains(at(time.Friday, 7, 29)))
+	assert.False(t, commute.Contains(at(time.Saturday, 8, 0)))
+
+	// ranges that wrap past midnight belong to the day they start on
+	overnight, err := schema.ParseTimeRange(`{"start": "22:00", "end": "06:00", "days": ["sun"]}`)
+	require.NoError(t, err)
+	assert.True(t, overnight.Contains(at(time.Sunday, 23, 0)))
+	assert.True(t, overnight.Contains(at(time.Monday, 5, 59)))
+	assert.False(t, overnight.Contains(at(time.Sunday, 5, 59)))
+	assert.False(t, overnight.Contains(at(time.Monday, 23, 0)))
+
+	allDay, err := schema.ParseTimeRange(`{"start": "00:00", "end": "00:00"}`)
+	require.NoError(t, err)
+	assert.True(t, allDay.Contains(at(time.Wednesday, 13, 37)))
+}
+
+func TestTimeRangeValidateConfig(t *testing.T) {
+	s := &schema.Schema{
+		Version: "1",
+		Fields: []schema.SchemaField{
+			{Type: "timerange", ID: "quiet", Name: "Quiet Hours"},
+		},
+	}
+
+	assert.NoError(t, s.ValidateConfig(map[string]string{"quiet": `{"start": "22:00", "end": "07:00"}`}))
+	assert.Error(t, s.ValidateConfig(map[string]string{"quiet": `{"start": "10pm", "end": "07:00"}`}))
+	assert.Error(t, s.ValidateConfig(map[string]string{"quiet": `{"start": "22:00"}`}))
+	assert.Error(t, s.ValidateConfig(map[string]string{"quiet": `{"start": "22:00", "end": "07:00", "days": ["monday"]}`}))
+	assert.Error(t, s.ValidateConfig(map[string]string{"quiet": "22:00-07:00"}))
+}
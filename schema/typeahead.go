@@ -7,6 +7,10 @@ import (
 	"go.starlark.net/starlark"
 )
 
+// Typeahead lets users search for an option. Its handler is called with what
+// the user typed, and returns a list of options. Handlers that take a second
+// parameter are paginated: they're called with a cursor, empty for the first
+// page, and return a schema.Page.
 type Typeahead struct {
 	SchemaField
 }
@@ -23,6 +27,9 @@ func newTypeahead(
 		desc    starlark.String
 		icon    starlark.String
 		handler *starlark.Function
+
+		minQueryLength = starlark.MakeInt(0)
+		debounce       = starlark.MakeInt(0)
 	)
 
 	if err := starlark.UnpackArgs(
@@ -33,6 +40,8 @@ func newTypeahead(
 		"desc", &desc,
 		"icon", &icon,
 		"handler", &handler,
+		"min_query_length?", &minQueryLength,
+		"debounce_ms?", &debounce,
 	); err != nil {
 		return nil, fmt.Errorf("unpacking arguments for Typeahead: %s", err)
 	}
@@ -46,6 +55,18 @@ func newTypeahead(
 	s.Handler = handler.Name()
 	s.StarlarkHandler = handler
 
+	n, ok := minQueryLength.Int64()
+	if !ok || n < 0 {
+		return nil, fmt.Errorf("min_query_length must be a non-negative integer")
+	}
+	s.MinQueryLength = int(n)
+
+	n, ok = debounce.Int64()
+	if !ok || n < 0 {
+		return nil, fmt.Errorf("debounce_ms must be a non-negative integer")
+	}
+	s.Debounce = int(n)
+
 	return s, nil
 }
 
@@ -55,7 +76,7 @@ func (s *Typeahead) AsSchemaField() SchemaField {
 
 func (s *Typeahead) AttrNames() []string {
 	return []string{
-		"id", "name", "desc", "icon", "handler",
+		"id", "name", "desc", "icon", "handler", "min_query_length", "debounce_ms",
 	}
 }
 
@@ -77,6 +98,12 @@ func (s *Typeahead) Attr(name string) (starlark.Value, error) {
 	case "handler":
 		return s.StarlarkHandler, nil
 
+	case "min_query_length":
+		return starlark.MakeInt(s.MinQueryLength), nil
+
+	case "debounce_ms":
+		return starlark.MakeInt(s.Debounce), nil
+
 	default:
 		return nil, nil
 	}
@@ -54,3 +54,90 @@ func TestTypeahead(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, screens)
 }
+
+func TestTypeaheadPagination(t *testing.T) {
+	code := `
+load("schema.star", "schema")
+
+STATIONS = ["Alewife", "Andrew", "Aquarium", "Arlington", "Ashmont"]
+
+def search(query, cursor):
+    matches = [s for s in STATIONS if s.startswith(query)]
+    start = int(cursor) if cursor else 0
+    end = start + 2
+    return schema.Page(
+        options = [schema.Option(display = s, value = s) for s in matches[start:end]],
+        next_cursor = str(end) if end < len(matches) else "",
+    )
+
+def get_schema():
+    return schema.Schema(
+        version = "1",
+        fields = [
+            schema.Typeahead(
+                id = "station",
+                name = "Station",
+                desc = "The station to show.",
+                icon = "train",
+                handler = search,
+                min_query_length = 1,
+                debounce_ms = 400,
+            ),
+        ],
+    )
+
+def main():
+    return []
+`
+
+	app, err := loadApp(code)
+	assert.NoError(t, err)
+
+	field := app.Schema.Fields[0]
+	assert.Equal(t, 1, field.MinQueryLength)
+	assert.Equal(t, 400, field.Debounce)
+
+	page, err := app.CallSchemaHandlerPage(context.Background(), "station$search", "A", "")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"options": [
+			{"display": "Alewife", "text": "Alewife", "value": "Alewife"},
+			{"display": "Andrew", "text": "Andrew", "value": "Andrew"}
+		],
+		"next_cursor": "2"
+	}`, page)
+
+	page, err = app.CallSchemaHandlerPage(context.Background(), "station$search", "A", "4")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"options": [{"display": "Ashmont", "text": "Ashmont", "value": "Ashmont"}]
+	}`, page)
+
+	// queries shorter than min_query_length don't call the handler
+	page, err = app.CallSchemaHandler(context.Background(), "station$search", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", page)
+}
+
+func TestTypeaheadInvalidOptions(t *testing.T) {
+	for name, args := range map[string]string{
+		"negative min_query_length": "handler = search, min_query_length = -1",
+		"negative debounce_ms":      "handler = search, debounce_ms = -5",
+	} {
+		t.Run(name, func(t *testing.T) {
+			code := `
+load("schema.star", "schema")
+
+def search(query):
+    return []
+
+t = schema.Typeahead(id = "search", name = "Search", desc = "Search.", icon = "gear", ` + args + `)
+
+def main():
+    return []
+`
+			_, err := runtime.NewApplet("typeahead.star", []byte(code))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A visible_if expression hides a field unless it evaluates to true for the
+// current config. Expressions compare the values of other fields by ID:
+//
+//	source == "custom"
+//	units != "metric" and show_wind
+//	not (line in ["red", "blue"] or express)
+//
+// A bare field ID is true when the field is set to anything other than an
+// empty string or "false", which makes it read naturally for toggles. The same
+// expressions are evaluated by the config UI, so keep the two in sync.
+type VisibleIf struct {
+	expr visibleIfExpr
+	ids  []string
+}
+
+// ParseVisibleIf parses a visible_if expression.
+func ParseVisibleIf(src string) (*VisibleIf, error) {
+	p := &visibleIfParser{}
+	if err := p.tokenize(src); err != nil {
+		return nil, fmt.Errorf("parsing visible_if %q: %w", src, err)
+	}
+
+	expr, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing visible_if %q: %w", src, err)
+	}
+
+	return &VisibleIf{expr: expr, ids: p.ids}, nil
+}
+
+// Eval reports whether the expression holds for config.
+func (v *VisibleIf) Eval(config map[string]string) bool {
+	return v.expr.eval(config)
+}
+
+// FieldIDs returns the IDs of the fields the expression refers to.
+func (v *VisibleIf) FieldIDs() []string {
+	return v.ids
+}
+
+// IsVisible reports whether field is shown for config. Fields without a
+// visible_if expression are always visible.
+func (f SchemaField) IsVisible(config map[string]string) (bool, error) {
+	if f.VisibleIf == "" {
+		return true, nil
+	}
+
+	v, err := ParseVisibleIf(f.VisibleIf)
+	if err != nil {
+		return false, err
+	}
+	return v.Eval(config), nil
+}
+
+// validateVisibleIf checks that visible_if expressions only refer to fields
+// that exist in the schema.
+func validateVisibleIf(fields []SchemaField) error {
+	var ids []string
+	var collect func([]SchemaField)
+	collect = func(fields []SchemaField) {
+		for _, f := range fields {
+			ids = append(ids, f.ID)
+			if isFieldGroup(f) {
+				collect(f.Fields)
+			}
+		}
+	}
+	collect(fields)
+
+	var check func([]SchemaField) error
+	check = func(fields []SchemaField) error {
+		for _, f := range fields {
+			if f.VisibleIf != "" {
+				v, err := ParseVisibleIf(f.VisibleIf)
+				if err != nil {
+					return fmt.Errorf("field %q: %w", f.ID, err)
+				}
+				for _, id := range v.FieldIDs() {
+					if !slices.Contains(ids, id) {
+						return fmt.Errorf("field %q: visible_if refers to unknown field %q", f.ID, id)
+					}
+				}
+			}
+			if err := check(f.Fields); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return check(fields)
+}
+
+type visibleIfExpr interface {
+	eval(config map[string]string) bool
+}
+
+type visibleIfOr struct{ left, right visibleIfExpr }
+type visibleIfAnd struct{ left, right visibleIfExpr }
+type visibleIfNot struct{ expr visibleIfExpr }
+type visibleIfTruthy struct{ id string }
+type visibleIfCompare struct {
+	id     string
+	values []string
+	negate bool
+}
+
+func (e visibleIfOr) eval(c map[string]string) bool  { return e.left.eval(c) || e.right.eval(c) }
+func (e visibleIfAnd) eval(c map[string]string) bool { return e.left.eval(c) && e.right.eval(c) }
+func (e visibleIfNot) eval(c map[string]string) bool { return !e.expr.eval(c) }
+
+func (e visibleIfTruthy) eval(c map[string]string) bool {
+	v := c[e.id]
+	return v != "" && v != "false"
+}
+
+func (e visibleIfCompare) eval(c map[string]string) bool {
+	return slices.Contains(e.values, c[e.id]) != e.negate
+}
+
+type visibleIfToken struct {
+	kind string // "ident", "string", or the operator itself
+	text string
+}
+
+type visibleIfParser struct {
+	tokens []visibleIfToken
+	pos    int
+	ids    []string
+}
+
+func (p *visibleIfParser) tokenize(src string) error {
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != src[i] {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return fmt.Errorf("unterminated string")
+			}
+			s, err := strconv.Unquote(`"` + strings.ReplaceAll(src[i+1:end], `"`, `\"`) + `"`)
+			if err != nil {
+				return fmt.Errorf("bad string %s", src[i:end+1])
+			}
+			p.tokens = append(p.tokens, visibleIfToken{"string", s})
+			i = end + 1
+
+		case strings.HasPrefix(src[i:], "==") || strings.HasPrefix(src[i:], "!="):
+			p.tokens = append(p.tokens, visibleIfToken{src[i : i+2], src[i : i+2]})
+			i += 2
+
+		case strings.ContainsRune("()[],", c):
+			p.tokens = append(p.tokens, visibleIfToken{string(c), string(c)})
+			i++
+
+		case c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			end := i
+			for end < len(src) && (src[end] == '_' || src[end] == '-' || unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end]))) {
+				end++
+			}
+			word := src[i:end]
+			switch word {
+			case "and", "or", "not", "in":
+				p.tokens = append(p.tokens, visibleIfToken{word, word})
+			default:
+				p.tokens = append(p.tokens, visibleIfToken{"ident", word})
+			}
+			i = end
+
+		default:
+			return fmt.Errorf("unexpected %q", c)
+		}
+	}
+	return nil
+}
+
+func (p *visibleIfParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].kind
+	}
+	return ""
+}
+
+func (p *visibleIfParser) next(kind string) (visibleIfToken, error) {
+	if p.peek() != kind {
+		if p.pos >= len(p.tokens) {
+			return visibleIfToken{}, fmt.Errorf("expected %s at end of expression", kind)
+		}
+		return visibleIfToken{}, fmt.Errorf("expected %s but found %q", kind, p.tokens[p.pos].text)
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *visibleIfParser) parseOr() (visibleIfExpr, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.peek() == "or" {
+		p.pos++
+		var right visibleIfExpr
+		if right, err = p.parseAnd(); err == nil {
+			left = visibleIfOr{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *visibleIfParser) parseAnd() (visibleIfExpr, error) {
+	left, err := p.parseNot()
+	for err == nil && p.peek() == "and" {
+		p.pos++
+		var right visibleIfExpr
+		if right, err = p.parseNot(); err == nil {
+			left = visibleIfAnd{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *visibleIfParser) parseNot() (visibleIfExpr, error) {
+	if p.peek() == "not" {
+		p.pos++
+		expr, err := p.parseNot()
+		return visibleIfNot{expr}, err
+	}
+	return p.parseComparison()
+}
+
+func (p *visibleIfParser) parseComparison() (visibleIfExpr, error) {
+	if p.peek() == "(" {
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.next(")")
+		return expr, err
+	}
+
+	ident, err := p.next("ident")
+	if err != nil {
+		return nil, err
+	}
+	p.ids = append(p.ids, ident.text)
+
+	switch op := p.peek(); op {
+	case "==", "!=":
+		p.pos++
+		value, err := p.next("string")
+		if err != nil {
+			return nil, err
+		}
+		return visibleIfCompare{id: ident.text, values: []string{value.text}, negate: op == "!="}, nil
+
+	case "not", "in":
+		negate := op == "not"
+		if negate {
+			p.pos++
+			if _, err := p.next("in"); err != nil {
+				return nil, err
+			}
+		} else {
+			p.pos++
+		}
+
+		if _, err := p.next("["); err != nil {
+			return nil, err
+		}
+		var values []string
+		for p.peek() != "]" {
+			value, err := p.next("string")
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value.text)
+			if p.peek() != "," {
+				break
+			}
+			p.pos++
+		}
+		if _, err := p.next("]"); err != nil {
+			return nil, err
+		}
+		return visibleIfCompare{id: ident.text, values: values, negate: negate}, nil
+
+	default:
+		return visibleIfTruthy{id: ident.text}, nil
+	}
+}
@@ -0,0 +1,163 @@
+package schema_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
+)
+
+func TestParseVisibleIf(t *testing.T) {
+	for _, tc := range []struct {
+		expr   string
+		config map[string]string
+		want   bool
+	}{
+		{`source == "custom"`, map[string]string{"source": "custom"}, true},
+		{`source == "custom"`, map[string]string{"source": "builtin"}, false},
+		{`source != 'custom'`, map[string]string{}, true},
+		{`show_wind`, map[string]string{"show_wind": "true"}, true},
+		{`show_wind`, map[string]string{"show_wind": "false"}, false},
+		{`show_wind`, map[string]string{}, false},
+		{`not show_wind`, map[string]string{}, true},
+		{`units != "metric" and show_wind`, map[string]string{"units": "imperial", "show_wind": "true"}, true},
+		{`units != "metric" and show_wind`, map[string]string{"units": "metric", "show_wind": "true"}, false},
+		{`line in ["red", "blue"] or express`, map[string]string{"line": "blue"}, true},
+		{`line in ["red", "blue"] or express`, map[string]string{"line": "green", "express": "true"}, true},
+		{`line not in ["red", "blue"]`, map[string]string{"line": "green"}, true},
+		{`not (a or b) and c`, map[string]string{"c": "true"}, true},
+		{`not (a or b) and c`, map[string]string{"b": "true", "c": "true"}, false},
+	} {
+		v, err := schema.ParseVisibleIf(tc.expr)
+		require.NoError(t, err, tc.expr)
+		assert.Equal(t, tc.want, v.Eval(tc.config), "%s with %v", tc.expr, tc.config)
+	}
+
+	v, err := schema.ParseVisibleIf(`a == "x" or not b`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, v.FieldIDs())
+
+	for _, bad := range []string{
+		``,
+		`source ==`,
+		`source == custom`,
+		`source = "custom"`,
+		`(a or b`,
+		`a b`,
+		`line in "red"`,
+		`"unterminated`,
+	} {
+		_, err := schema.ParseVisibleIf(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+var visibleIfSource = `
+load("schema.star", "schema")
+
+def main(config):
+	return []
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [
+			schema.Dropdown(
+				id = "source",
+				name = "Source",
+				desc = "Where to get data.",
+				icon = "database",
+				default = "builtin",
+				options = [
+					schema.Option(display = "Built in", value = "builtin"),
+					schema.Option(display = "Custom", value = "custom"),
+				],
+			),
+			schema.Text(
+				id = "api_key",
+				name = "API Key",
+				desc = "Key for the custom source.",
+				icon = "key",
+				visible_if = 'source == "custom"',
+			),
+			schema.Date(
+				id = "since",
+				name = "Since",
+				desc = "Show data since.",
+				icon = "calendar",
+				visible_if = 'source == "custom"',
+			),
+		],
+	)
+`
+
+func TestVisibleIf(t *testing.T) {
+	app, err := runtime.NewApplet("visible_if.star", []byte(visibleIfSource))
+	require.NoError(t, err)
+
+	var js struct {
+		Schema []schema.SchemaField `json:"schema"`
+	}
+	require.NoError(t, json.Unmarshal(app.SchemaJSON, &js))
+	assert.Equal(t, `source == "custom"`, js.Schema[1].VisibleIf)
+
+	visible, err := js.Schema[1].IsVisible(map[string]string{"source": "builtin"})
+	assert.NoError(t, err)
+	assert.False(t, visible)
+	visible, err = js.Schema[1].IsVisible(map[string]string{"source": "custom"})
+	assert.NoError(t, err)
+	assert.True(t, visible)
+
+	// values of hidden fields aren't validated
+	assert.NoError(t, app.Schema.ValidateConfig(map[string]string{"source": "builtin", "since": "whenever"}))
+	assert.Error(t, app.Schema.ValidateConfig(map[string]string{"source": "custom", "since": "whenever"}))
+
+	_, err = app.Run(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestVisibleIfErrors(t *testing.T) {
+	src := `
+load("schema.star", "schema")
+
+t = schema.Text(
+	id = "api_key",
+	name = "API Key",
+	desc = "Key for the custom source.",
+	icon = "key",
+	visible_if = 'source ==',
+)
+
+def main():
+	return []
+`
+	_, err := runtime.NewApplet("visible_if.star", []byte(src))
+	assert.ErrorContains(t, err, "parsing visible_if")
+
+	src = `
+load("schema.star", "schema")
+
+def main():
+	return []
+
+def get_schema():
+	return schema.Schema(
+		version = "1",
+		fields = [
+			schema.Text(
+				id = "api_key",
+				name = "API Key",
+				desc = "Key for the custom source.",
+				icon = "key",
+				visible_if = 'source == "custom"',
+			),
+		],
+	)
+`
+	_, err = runtime.NewApplet("visible_if.star", []byte(src))
+	assert.ErrorContains(t, err, `unknown field "source"`)
+}
@@ -0,0 +1,87 @@
+package browser
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"tidbyt.dev/pixlet/icons"
+	"tidbyt.dev/pixlet/render"
+)
+
+type fontData struct {
+	Name   string `json:"name"`
+	Height int    `json:"height"`
+}
+
+type iconData struct {
+	Ref    string `json:"ref"`
+	Format string `json:"format"`
+}
+
+type iconPackData struct {
+	Name  string     `json:"name"`
+	Icons []iconData `json:"icons"`
+}
+
+type iconsData struct {
+	// Packs are the icon packs registered with --icons, which apps use
+	// through the icons module.
+	Packs []iconPackData `json:"packs"`
+
+	// Schema are the names of the icons schema fields can show.
+	Schema []string `json:"schema"`
+}
+
+// fontsHandler lists the fonts apps can render text with.
+func fontsHandler(w http.ResponseWriter, r *http.Request) {
+	fonts := []fontData{}
+	for _, name := range render.GetFontList() {
+		face, err := render.GetFont(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fonts = append(fonts, fontData{Name: name, Height: face.Metrics().Height.Ceil()})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fonts)
+}
+
+// iconsHandler lists the icons in the registered icon packs, and the icons
+// schema fields can show.
+func iconsHandler(w http.ResponseWriter, r *http.Request) {
+	data := iconsData{Packs: []iconPackData{}, Schema: []string{}}
+	for _, p := range icons.Packs() {
+		pack := iconPackData{Name: p.Name, Icons: []iconData{}}
+		for _, name := range p.Names() {
+			icon := p.Icons[name]
+			pack.Icons = append(pack.Icons, iconData{Ref: icon.Ref(), Format: icon.Format})
+		}
+		data.Packs = append(data.Packs, pack)
+	}
+	for name := range icons.IconsMap {
+		data.Schema = append(data.Schema, name)
+	}
+	sort.Strings(data.Schema)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// iconHandler serves an icon from a registered icon pack, by its reference.
+func iconHandler(w http.ResponseWriter, r *http.Request) {
+	icon := icons.Lookup(r.PathValue("ref"))
+	if icon == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if icon.Format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+	} else {
+		w.Header().Set("Content-Type", "image/png")
+	}
+	w.Write(icon.Data)
+}
@@ -6,15 +6,22 @@ import (
 	_ "embed"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/websocket"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/text/language"
 	"tidbyt.dev/pixlet/dist"
+	"tidbyt.dev/pixlet/lib"
+	"tidbyt.dev/pixlet/logging"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/schema"
 	"tidbyt.dev/pixlet/server/fanout"
 	"tidbyt.dev/pixlet/server/loader"
 )
@@ -43,10 +50,43 @@ type previewData struct {
 	ImageType string `json:"img_type"`
 	Watch     bool   `json:"-"`
 	Err       string `json:"error,omitempty"`
+
+	// Field is the ID of the schema field whose config value was
+	// rejected, if that's why rendering failed.
+	Field string `json:"field,omitempty"`
+
+	// Diagnostics is where the time rendering the app went, if the
+	// preview request asked for it.
+	Diagnostics *lib.Diagnostics `json:"diagnostics,omitempty"`
+}
+
+// cacheData is the response of the cache introspection endpoint.
+type cacheData struct {
+	Stats   map[string]runtime.CacheStats `json:"stats"`
+	Total   runtime.CacheStats            `json:"total"`
+	Entries []cacheEntry                  `json:"entries"`
+	Err     string                        `json:"error,omitempty"`
+}
+
+type cacheEntry struct {
+	runtime.CacheEntry
+	TTLSeconds int64 `json:"ttl_seconds"`
 }
+
+// uploadRequest is a file uploaded to a FileUpload field, and the value
+// saved for it in response.
+type uploadRequest struct {
+	Value string `json:"value"`
+}
+
+// maxUploadRequestSize bounds the body of upload requests. The field's own
+// max_size is checked once the file is decoded.
+const maxUploadRequestSize = 16 << 20
+
 type handlerRequest struct {
-	ID    string `json:"id"`
-	Param string `json:"param"`
+	ID     string `json:"id"`
+	Param  string `json:"param"`
+	Cursor string `json:"cursor"`
 }
 
 // NewBrowser sets up a browser structure. Call Run() to kick off the main loops.
@@ -89,9 +129,32 @@ func NewBrowser(addr string, servePath string, title string, watch bool, updateC
 	r.HandleFunc(servePath+"api/v1/preview.webp", b.imageHandler)
 	r.HandleFunc(servePath+"api/v1/preview.gif", b.imageHandler)
 	r.HandleFunc(servePath+"api/v1/push", b.pushHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/frame.png", servePath), b.frameHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/frame.bmp", servePath), b.frameHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/frames", servePath), b.framesHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/frames/{frame}", servePath), b.frameByIndexHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/tree", servePath), b.treeHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/simulate.webp", servePath), b.simulateHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/simulate.gif", servePath), b.simulateHandler)
 	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/schema", servePath), b.schemaHandler)
+	r.HandleFunc(fmt.Sprintf("POST %sapi/v1/schema/resolve", servePath), b.resolveSchemaHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/jsonschema", servePath), b.jsonSchemaHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/config/export", servePath), b.configExportHandler)
+	r.HandleFunc(fmt.Sprintf("POST %sapi/v1/config/import", servePath), b.configImportHandler)
 	r.HandleFunc(fmt.Sprintf("POST %sapi/v1/handlers/{handler}", servePath), b.schemaHandlerHandler)
+	r.HandleFunc(fmt.Sprintf("POST %sapi/v1/oauth2/{field}/device", servePath), b.deviceAuthorizationHandler)
+	r.HandleFunc(fmt.Sprintf("POST %sapi/v1/uploads/{field}", servePath), b.uploadHandler)
 	r.HandleFunc(servePath+"api/v1/ws", b.websocketHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/cache", servePath), b.cacheHandler)
+	r.HandleFunc(fmt.Sprintf("DELETE %sapi/v1/cache", servePath), b.cacheFlushHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/cache/{key...}", servePath), b.cacheEntryHandler)
+	r.HandleFunc(fmt.Sprintf("DELETE %sapi/v1/cache/{key...}", servePath), b.cacheDeleteHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/usage", servePath), usageHandler)
+	r.HandleFunc(fmt.Sprintf("GET %smetrics", servePath), b.metricsHandler)
+	r.HandleFunc(fmt.Sprintf("POST %sapi/v1/diff", servePath), b.diffHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/fonts", servePath), fontsHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/icons", servePath), iconsHandler)
+	r.HandleFunc(fmt.Sprintf("GET %sapi/v1/icons/{ref...}", servePath), iconHandler)
 	b.r = r
 
 	return b, nil
@@ -119,9 +182,92 @@ func (b *Browser) healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(200)
 }
 
+// schemaHandler serves the applet's schema. Pass the locale query parameter,
+// or an Accept-Language header, to have it translated.
 func (b *Browser) schemaHandler(w http.ResponseWriter, r *http.Request) {
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		if tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language")); err == nil && len(tags) > 0 {
+			locale = tags[0].String()
+		}
+	}
+
+	if locale == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b.loader.GetSchema())
+		return
+	}
+
+	js, err := b.loader.GetLocalizedSchema(locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// resolveSchemaHandler takes config, which may be partial, and responds with
+// the schema as it should be shown for it, with Generated fields resolved.
+func (b *Browser) resolveSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	config := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, fmt.Sprintf("decoding config: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	js, err := b.loader.ResolveSchema(r.Context(), config, r.URL.Query().Get("locale"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(b.loader.GetSchema())
+	w.Write(js)
+}
+
+// jsonSchemaHandler serves the applet's schema as a JSON Schema describing
+// its config.
+func (b *Browser) jsonSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	js, err := b.loader.GetJSONSchema()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(js)
+}
+
+// deviceAuthorizationHandler starts the device authorization grant for an
+// OAuth2 field, on behalf of the config UI.
+func (b *Browser) deviceAuthorizationHandler(w http.ResponseWriter, r *http.Request) {
+	auth, err := b.loader.StartDeviceAuthorization(r.Context(), r.PathValue("field"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth)
+}
+
+// uploadHandler checks a file uploaded to a FileUpload field and responds
+// with the value to save in the config, which is scaled down to the display
+// if it's a larger image.
+func (b *Browser) uploadHandler(w http.ResponseWriter, r *http.Request) {
+	var req uploadRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUploadRequestSize)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decoding upload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	value, err := b.loader.PrepareUpload(r.PathValue("field"), req.Value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(uploadRequest{Value: value})
 }
 
 func (b *Browser) schemaHandlerHandler(w http.ResponseWriter, r *http.Request) {
@@ -141,7 +287,7 @@ func (b *Browser) schemaHandlerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := b.loader.CallSchemaHandler(r.Context(), handler, msg.Param)
+	data, err := b.loader.CallSchemaHandler(r.Context(), handler, msg.Param, msg.Cursor)
 	if err != nil {
 		w.WriteHeader(500)
 		fmt.Fprintln(w, err)
@@ -152,20 +298,84 @@ func (b *Browser) schemaHandlerHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(data))
 }
 
-func (b *Browser) imageHandler(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseForm()
+// cacheHandler reports cache statistics and lists the live cache entries.
+// Pass the app query parameter to only include entries and stats for one app.
+func (b *Browser) cacheHandler(w http.ResponseWriter, r *http.Request) {
+	cache := b.loader.Cache()
+	app := r.URL.Query().Get("app")
+
+	data := &cacheData{
+		Stats:   cache.Stats(),
+		Total:   cache.Total(),
+		Entries: []cacheEntry{},
+	}
+	if app != "" {
+		data.Stats = map[string]runtime.CacheStats{app: data.Stats[app]}
+		data.Total = data.Stats[app]
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		data.Err = err.Error()
+	}
+	for _, e := range entries {
+		if app != "" && e.App != app {
+			continue
+		}
+		data.Entries = append(data.Entries, cacheEntry{
+			CacheEntry: e,
+			TTLSeconds: int64(e.TTL().Seconds()),
+		})
+	}
+
+	d, err := json.Marshal(data)
 	if err != nil {
 		w.WriteHeader(500)
-		http.Error(w, "bad form data", http.StatusBadRequest)
+		fmt.Fprintln(w, err)
 		return
 	}
 
-	config := make(map[string]string)
-	for k, val := range r.Form {
-		config[k] = val[0]
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(d)
+}
+
+// usageHandler reports the resources used by each app rendered so far.
+func usageHandler(w http.ResponseWriter, r *http.Request) {
+	d, err := json.Marshal(runtime.DefaultUsageReport.Apps())
+	if err != nil {
+		w.WriteHeader(500)
+		fmt.Fprintln(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(d)
+}
+
+func (b *Browser) imageHandler(w http.ResponseWriter, r *http.Request) {
+	req := &previewRequest{Config: map[string]string{}}
+	if isJSON(r) {
+		var err error
+		if req, err = decodePreviewRequest(w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "bad form data", http.StatusBadRequest)
+			return
+		}
+		for k, val := range r.Form {
+			req.Config[k] = val[0]
+		}
 	}
 
-	img, err := b.loader.LoadApplet(config)
+	img, err := req.render(b.loader)
+	var configErr *schema.ConfigError
+	if errors.As(err, &configErr) {
+		http.Error(w, configErr.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "loading applet", http.StatusInternalServerError)
 		return
@@ -186,19 +396,29 @@ func (b *Browser) imageHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
+// previewHandler renders the app with the config in a form, or in a JSON
+// previewRequest.
 func (b *Browser) previewHandler(w http.ResponseWriter, r *http.Request) {
-	// Parse the request form so we can use it as config values.
-	if err := r.ParseMultipartForm(100); err != nil {
-		log.Printf("form parsing failed: %+v", err)
-		http.Error(w, "bad form data", http.StatusBadRequest)
-		return
-	}
-	config := make(map[string]string)
-	for k, val := range r.Form {
-		config[k] = val[0]
+	req := &previewRequest{Config: map[string]string{}}
+	if isJSON(r) {
+		var err error
+		if req, err = decodePreviewRequest(w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		// Parse the request form so we can use it as config values.
+		if err := r.ParseMultipartForm(100); err != nil {
+			logging.FromContext(r.Context()).Warn("form parsing failed", "error", err)
+			http.Error(w, "bad form data", http.StatusBadRequest)
+			return
+		}
+		for k, val := range r.Form {
+			req.Config[k] = val[0]
+		}
 	}
 
-	img, err := b.loader.LoadApplet(config)
+	img, err := req.render(b.loader)
 	img_type := "webp"
 	if b.serveGif {
 		img_type = "gif"
@@ -211,6 +431,17 @@ func (b *Browser) previewHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		data.Err = err.Error()
 	}
+	var configErr *schema.ConfigError
+	if errors.As(err, &configErr) {
+		data.Field = configErr.FieldID
+	}
+	if err == nil && req.Diagnose {
+		// rendered again, so that profiling doesn't slow down the image
+		data.Diagnostics, err = b.loader.Diagnose(req.context(), req.Config)
+		if err != nil {
+			data.Err = err.Error()
+		}
+	}
 
 	d, err := json.Marshal(data)
 	if err != nil {
@@ -229,16 +460,20 @@ func (b *Browser) websocketHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var upgrader = websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: true,
 	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("error establishing a new connection %v\n", err)
+		logging.FromContext(r.Context()).Error("error establishing a new connection", "error", err)
 		return
 	}
 
-	b.fo.NewClient(conn)
+	// clients that reconnect pick up where they left off
+	query := r.URL.Query()
+	since, _ := strconv.ParseUint(query.Get("since"), 10, 64)
+	b.fo.ResumeClient(conn, query.Get("epoch"), since)
 }
 
 func (b *Browser) updateWatcher() error {
@@ -0,0 +1,116 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+// cacheFlushData is the response of the cache flush endpoint.
+type cacheFlushData struct {
+	Deleted int `json:"deleted"`
+}
+
+// cacheEntryHandler describes a single live cache entry, by its key, so that
+// app developers can see how long a value has left before it's refetched.
+func (b *Browser) cacheEntryHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	entries, err := b.loader.Cache().Entries()
+	if err != nil {
+		cacheError(w, err)
+		return
+	}
+
+	for _, e := range entries {
+		if e.Key != key {
+			continue
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(cacheEntry{
+			CacheEntry: e,
+			TTLSeconds: int64(e.TTL().Seconds()),
+		})
+		return
+	}
+
+	http.Error(w, "no such cache entry", http.StatusNotFound)
+}
+
+// cacheDeleteHandler deletes a single cache entry, by its key, so that the
+// next render fetches it again. Deleting a key that isn't cached succeeds.
+func (b *Browser) cacheDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if err := b.loader.Cache().Delete(r.PathValue("key")); err != nil {
+		cacheError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cacheFlushHandler deletes every cache entry, or only those of one app if
+// the app query parameter is set, and reports how many it deleted.
+func (b *Browser) cacheFlushHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := b.loader.Cache().Flush(r.URL.Query().Get("app"))
+	if err != nil {
+		cacheError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cacheFlushData{Deleted: n})
+}
+
+// metricsHandler serves the cache's counters for each app in the Prometheus
+// text format, so that apps that hammer upstream APIs show up in
+// monitoring. Keys that don't belong to an app are counted under app="".
+func (b *Browser) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	stats := b.loader.Cache().Stats()
+	apps := make([]string, 0, len(stats))
+	for app := range stats {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+
+	var sb strings.Builder
+	metric := func(name, help string, value func(s runtime.CacheStats) int64) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, app := range apps {
+			fmt.Fprintf(&sb, "%s{%s} %s\n", name, label("app", app), strconv.FormatInt(value(stats[app]), 10))
+		}
+	}
+	metric("pixlet_cache_hits_total", "Cache reads that found a value.", func(s runtime.CacheStats) int64 { return s.Hits })
+	metric("pixlet_cache_misses_total", "Cache reads that found nothing.", func(s runtime.CacheStats) int64 { return s.Misses })
+	metric("pixlet_cache_sets_total", "Values written to the cache.", func(s runtime.CacheStats) int64 { return s.Sets })
+	metric("pixlet_cache_evictions_total", "Live values evicted to keep the cache within its limits.", func(s runtime.CacheStats) int64 { return s.Evictions })
+	metric("pixlet_cache_errors_total", "Cache reads and writes that failed.", func(s runtime.CacheStats) int64 { return s.Errors })
+	metric("pixlet_cache_read_bytes_total", "Bytes of values read from the cache.", func(s runtime.CacheStats) int64 { return s.BytesRead })
+	metric("pixlet_cache_written_bytes_total", "Bytes of values written to the cache.", func(s runtime.CacheStats) int64 { return s.BytesWritten })
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	io.WriteString(w, sb.String())
+}
+
+// label formats a Prometheus label, escaping its value.
+func label(name, value string) string {
+	return name + `="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// cacheError reports err, telling caches that can't do what was asked
+// apart from those that failed to.
+func cacheError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, runtime.ErrCacheNotInspectable) || errors.Is(err, runtime.ErrCacheNotDeletable) {
+		status = http.StatusNotImplemented
+	}
+	http.Error(w, err.Error(), status)
+}
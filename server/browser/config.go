@@ -0,0 +1,70 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"tidbyt.dev/pixlet/schema"
+)
+
+// maxConfigImport is the largest config document that can be imported. It's
+// generous, since config can hold uploaded images.
+const maxConfigImport = 16 << 20
+
+// configExportHandler serves the config the app was last rendered with as a
+// schema.ConfigExport, for importing into another pixlet or a server.
+func (b *Browser) configExportHandler(w http.ResponseWriter, r *http.Request) {
+	e, err := schema.NewConfigExport(b.loader.AppID(), b.loader.Config())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="config.json"`)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(e)
+}
+
+// configImportHandler renders the app with config from a schema.ConfigExport
+// posted as the body, migrated to the app's current schema, and responds
+// with the config as it was saved.
+func (b *Browser) configImportHandler(w http.ResponseWriter, r *http.Request) {
+	e, err := schema.DecodeConfigExport(http.MaxBytesReader(w, r.Body, maxConfigImport))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := e.CheckApp(b.loader.AppID()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	config, err := b.loader.MigrateConfig(r.Context(), e.SavedConfig())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("migrating config: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	_, err = b.loader.LoadApplet(config)
+	var configErr *schema.ConfigError
+	if errors.As(err, &configErr) {
+		http.Error(w, configErr.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "loading applet", http.StatusInternalServerError)
+		return
+	}
+
+	imported, err := schema.NewConfigExport(e.AppID, config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(imported)
+}
@@ -0,0 +1,135 @@
+package browser
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"tidbyt.dev/pixlet/render/imagediff"
+	"tidbyt.dev/pixlet/schema"
+)
+
+// maxDiffUpload is the largest render that can be posted to compare
+// against.
+const maxDiffUpload = 16 << 20
+
+type diffSpan struct {
+	Old        int     `json:"old"`
+	New        int     `json:"new"`
+	StartMS    int64   `json:"start_ms"`
+	DurationMS int64   `json:"duration_ms"`
+	Changed    int     `json:"changed_pixels"`
+	MaxDelta   float64 `json:"max_delta"`
+}
+
+type diffData struct {
+	Identical bool       `json:"identical"`
+	Score     float64    `json:"score"`
+	Changed   float64    `json:"changed"`
+	MaxDelta  float64    `json:"max_delta"`
+	Spans     []diffSpan `json:"spans"`
+
+	// Highlight is the old render, the new one and the changes between
+	// them side by side, base64 encoded.
+	Highlight     string `json:"highlight"`
+	HighlightType string `json:"highlight_type"`
+}
+
+// diffHandler compares a render posted as the body, such as one from
+// before a change, with a render of the app with the config in the query.
+// The highlight is magnified by the magnify parameter, if set.
+func (b *Browser) diffHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDiffUpload))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reading render: %v", err), http.StatusBadRequest)
+		return
+	}
+	old, err := imagediff.Decode(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("decoding render: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	magnify := 1
+	if m := query.Get("magnify"); m != "" {
+		if magnify, err = strconv.Atoi(m); err != nil || magnify < 1 || magnify > 16 {
+			http.Error(w, "magnify must be from 1 to 16", http.StatusBadRequest)
+			return
+		}
+		query.Del("magnify")
+	}
+
+	config := make(map[string]string)
+	for k, val := range query {
+		config[k] = val[0]
+	}
+
+	img, err := b.loader.LoadApplet(config)
+	var configErr *schema.ConfigError
+	if errors.As(err, &configErr) {
+		http.Error(w, configErr.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "loading applet", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(img)
+	if err != nil {
+		http.Error(w, "decoding image", http.StatusInternalServerError)
+		return
+	}
+	new, err := imagediff.Decode(data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("decoding render: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := imagediff.Compare(old, new)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	highlight, highlightType, err := result.EncodeHighlights(magnify)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("encoding highlight: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := diffData{
+		Identical:     result.Identical(),
+		Score:         result.Score,
+		Changed:       result.Changed,
+		MaxDelta:      result.MaxDelta,
+		Spans:         []diffSpan{},
+		Highlight:     base64.StdEncoding.EncodeToString(highlight),
+		HighlightType: highlightType,
+	}
+	for _, span := range result.Spans {
+		resp.Spans = append(resp.Spans, diffSpan{
+			Old:        span.Old,
+			New:        span.New,
+			StartMS:    span.Start.Milliseconds(),
+			DurationMS: span.Duration.Milliseconds(),
+			Changed:    span.Changed,
+			MaxDelta:   span.MaxDelta,
+		})
+	}
+
+	d, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(500)
+		fmt.Fprintln(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(d)
+}
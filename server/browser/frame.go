@@ -0,0 +1,79 @@
+package browser
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/image/bmp"
+	"tidbyt.dev/pixlet/schema"
+)
+
+// frameEncoders encode still frames for the formats frameHandler serves.
+var frameEncoders = map[string]struct {
+	contentType string
+	encode      func(*bytes.Buffer, image.Image) error
+}{
+	"png": {"image/png", func(b *bytes.Buffer, img image.Image) error { return png.Encode(b, img) }},
+	"bmp": {"image/bmp", func(b *bytes.Buffer, img image.Image) error { return bmp.Encode(b, img) }},
+}
+
+// renderAtParam is the query parameter with the time to render a frame
+// for. Field IDs can't contain "$", so it never shadows a config field.
+const renderAtParam = "$render_at"
+
+// frameHandler serves the frame of the app that a display would be showing
+// right now, as a PNG or BMP at the app's resolution. It's for devices that
+// can't decode animations and poll for a still image instead, such as
+// ESPHome's online_image component. Query parameters are used as config, and
+// the last config set in the browser is used if there are none, except for
+// renderAtParam, an RFC 3339 time to render the frame for instead of now, so
+// that devices can fetch frames ahead of showing them.
+func (b *Browser) frameHandler(w http.ResponseWriter, r *http.Request) {
+	enc := frameEncoders[strings.TrimPrefix(path.Ext(r.URL.Path), ".")]
+	if enc.encode == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	config := make(map[string]string)
+	for k, val := range r.URL.Query() {
+		config[k] = val[0]
+	}
+
+	at := time.Now()
+	if v, ok := config[renderAtParam]; ok {
+		var err error
+		if at, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "bad "+renderAtParam+", it must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		delete(config, renderAtParam)
+	}
+
+	frame, err := b.loader.RenderFrame(config, at)
+	var configErr *schema.ConfigError
+	if errors.As(err, &configErr) {
+		http.Error(w, configErr.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "rendering applet", http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := enc.encode(&buf, frame); err != nil {
+		http.Error(w, "encoding image", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", enc.contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,82 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// framesJSON describes the frames of the preview.
+type framesJSON struct {
+	Count  int   `json:"count"`
+	Width  int   `json:"width"`
+	Height int   `json:"height"`
+	Delays []int `json:"delays"`
+
+	// Duration is how long the animation lasts, in milliseconds, which is
+	// the sum of its delays.
+	Duration int `json:"duration"`
+}
+
+// framesHandler describes the frames of the animation last rendered for
+// the preview: how many there are, and how long each is shown for in
+// milliseconds. Together with frameByIndexHandler, it lets the web UI and
+// other tools pause, step through and scrub the animation, rather than only
+// watch it loop.
+func (b *Browser) framesHandler(w http.ResponseWriter, r *http.Request) {
+	preview := b.loader.Preview()
+	if preview == nil {
+		http.Error(w, "nothing has been rendered yet", http.StatusNotFound)
+		return
+	}
+
+	resp := framesJSON{Count: len(preview.Frames), Delays: []int{}}
+	if len(preview.Frames) > 0 {
+		bounds := preview.Frames[0].Bounds()
+		resp.Width, resp.Height = bounds.Dx(), bounds.Dy()
+	}
+	for _, d := range preview.Delays {
+		resp.Delays = append(resp.Delays, int(d.Milliseconds()))
+		resp.Duration += int(d.Milliseconds())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// frameByIndexHandler serves a frame of the animation last rendered for the
+// preview, by its index from 0, as a PNG or BMP: frames/3.png is the fourth.
+func (b *Browser) frameByIndexHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("frame")
+	ext := path.Ext(name)
+	enc := frameEncoders[strings.TrimPrefix(ext, ".")]
+	index, err := strconv.Atoi(strings.TrimSuffix(name, ext))
+	if enc.encode == nil || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	preview := b.loader.Preview()
+	if preview == nil {
+		http.Error(w, "nothing has been rendered yet", http.StatusNotFound)
+		return
+	}
+	if index < 0 || index >= len(preview.Frames) {
+		http.Error(w, "no such frame", http.StatusNotFound)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := enc.encode(&buf, preview.Frames[index]); err != nil {
+		http.Error(w, "encoding image", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", enc.contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,88 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/server/loader"
+)
+
+// maxPreviewRequest is the largest JSON preview request, which is mostly
+// the bodies of its mocks.
+const maxPreviewRequest = 8 << 20
+
+// previewRequest is a preview request posted as JSON rather than a form,
+// which can mock the responses to the app's HTTP requests for that render,
+// to show how it copes with an API that's down, empty or rate limiting it,
+// and say what the request module tells it about the render.
+type previewRequest struct {
+	Config    map[string]string    `json:"config"`
+	HTTPMocks runtime.HTTPMocks    `json:"http_mocks"`
+	Request   *runtime.RequestInfo `json:"request"`
+
+	// RenderAt is the time the app sees as now, for previewing how it
+	// looks at another time.
+	RenderAt *time.Time `json:"render_at"`
+
+	// Diagnose asks for how long painting each frame took, and which
+	// widgets the time went to, along with the image.
+	Diagnose bool `json:"diagnose"`
+}
+
+// context returns the context to render the preview with.
+func (req *previewRequest) context() context.Context {
+	ctx := context.Background()
+	if len(req.HTTPMocks) > 0 {
+		ctx = runtime.WithHTTPMocks(ctx, req.HTTPMocks)
+	}
+	if req.Request != nil {
+		ctx = runtime.WithRequestInfo(ctx, req.Request)
+	}
+	if req.RenderAt != nil {
+		ctx = runtime.WithRenderTime(ctx, *req.RenderAt)
+	}
+	return ctx
+}
+
+// overrides reports whether the preview asks for anything besides a
+// config, such as mocked HTTP responses or another time, which only apply
+// to this request.
+func (req *previewRequest) overrides() bool {
+	return len(req.HTTPMocks) > 0 || req.Request != nil || req.RenderAt != nil || req.Diagnose
+}
+
+// render renders the preview. Previews with overrides are only for this
+// request, so they aren't sent to other viewers, and their config isn't
+// kept as the last config.
+func (req *previewRequest) render(l *loader.Loader) (string, error) {
+	if req.overrides() {
+		return l.RenderPreview(req.context(), req.Config)
+	}
+	return l.LoadAppletContext(req.context(), req.Config)
+}
+
+// isJSON reports whether r's body is JSON.
+func isJSON(r *http.Request) bool {
+	t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return t == "application/json"
+}
+
+// decodePreviewRequest reads a JSON preview request.
+func decodePreviewRequest(w http.ResponseWriter, r *http.Request) (*previewRequest, error) {
+	var req previewRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPreviewRequest)).Decode(&req); err != nil {
+		return nil, fmt.Errorf("decoding preview request: %w", err)
+	}
+	if err := req.HTTPMocks.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+	return &req, nil
+}
@@ -3,11 +3,13 @@
 package browser
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+
+	"tidbyt.dev/pixlet/logging"
 )
 
 func (b *Browser) serveHTTP() error {
-	log.Printf("listening at http://%s%s\n", b.addr, b.path)
-	return http.ListenAndServe(b.addr, b.r)
+	slog.Info("listening", "url", "http://"+b.addr+b.path)
+	return http.ListenAndServe(b.addr, logging.Middleware(b.r))
 }
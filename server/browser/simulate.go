@@ -0,0 +1,67 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+
+	"tidbyt.dev/pixlet/device"
+	"tidbyt.dev/pixlet/schema"
+)
+
+// defaultSimulationScale is how many screen pixels wide each LED is drawn
+// in simulations, unless the scale parameter says otherwise.
+const defaultSimulationScale = 10
+
+// simulateHandler serves a render of the app as it would look on a device,
+// with its LEDs, the gaps between them and their bloom drawn, rather than
+// the smoothly scaled pixels of the preview. The profile parameter names the
+// device profile to simulate, and the scale parameter how many screen pixels
+// wide each LED is; other query parameters are used as config, and the last
+// config set in the browser is used if there are none.
+func (b *Browser) simulateHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	name := device.DefaultProfile
+	if p := query.Get("profile"); p != "" {
+		name = p
+		query.Del("profile")
+	}
+	profile, err := device.LookupProfile(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scale := defaultSimulationScale
+	if s := query.Get("scale"); s != "" {
+		if scale, err = strconv.Atoi(s); err != nil || scale < 2 || scale > 20 {
+			http.Error(w, "scale must be from 2 to 20", http.StatusBadRequest)
+			return
+		}
+		query.Del("scale")
+	}
+
+	config := make(map[string]string)
+	for k, val := range query {
+		config[k] = val[0]
+	}
+
+	renderGif := path.Ext(r.URL.Path) == ".gif"
+	img, err := b.loader.RenderImage(config, renderGif, profile.Simulate(scale)...)
+	var configErr *schema.ConfigError
+	if errors.As(err, &configErr) {
+		http.Error(w, configErr.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "rendering applet", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", fmt.Sprintf("image/%s", device.ImageFormat(img)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(img)
+}
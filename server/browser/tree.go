@@ -0,0 +1,47 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"tidbyt.dev/pixlet/schema"
+)
+
+// treeHandler serves where each widget of the app was laid out in a frame,
+// as a JSON tree with the widgets' types, bounds and colors, for debugging
+// layouts and for building inspectors. The frame query parameter picks the
+// frame, 0 by default, and the other query parameters are used as config,
+// with the last config set in the browser used if there are none.
+func (b *Browser) treeHandler(w http.ResponseWriter, r *http.Request) {
+	config := make(map[string]string)
+	for k, val := range r.URL.Query() {
+		config[k] = val[0]
+	}
+
+	frameIdx := 0
+	if f, ok := config["frame"]; ok {
+		var err error
+		if frameIdx, err = strconv.Atoi(f); err != nil || frameIdx < 0 {
+			http.Error(w, "bad frame", http.StatusBadRequest)
+			return
+		}
+		delete(config, "frame")
+	}
+
+	trees, err := b.loader.InspectTree(config, frameIdx)
+	var configErr *schema.ConfigError
+	if errors.As(err, &configErr) {
+		http.Error(w, configErr.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "rendering applet", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(trees)
+}
@@ -1,6 +1,7 @@
 package fanout
 
 import (
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -16,6 +17,10 @@ const (
 	// Send pings to peer with this period. Must be less than pongWait.
 	pingPeriod = (pongWait * 9) / 10
 
+	// Send heartbeat events with this period. Browsers don't see pings, so
+	// heartbeats are how they tell a connection has gone stale.
+	heartbeatPeriod = 15 * time.Second
+
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512
 
@@ -29,7 +34,13 @@ type Client struct {
 	fo   *Fanout
 	conn *websocket.Conn
 	send chan WebsocketEvent
-	quit chan bool
+	quit chan struct{}
+	once sync.Once
+
+	// epoch and since are where the client left off on a previous
+	// connection, if it's resuming.
+	epoch string
+	since uint64
 }
 
 // NewClient instantiates a client with a websocket connection. It spwans off
@@ -37,13 +48,27 @@ type Client struct {
 // is still alive. We're passing a Fanout here so the client can unregister
 // itself on error.
 func (f *Fanout) NewClient(conn *websocket.Conn) *Client {
+	return f.ResumeClient(conn, "", 0)
+}
+
+// ResumeClient is like NewClient, for a client that's reconnecting after
+// having seen the events of the fanout with the given epoch up to sequence
+// number since. The latest events it missed are sent to it first. Clients of
+// another epoch, from before the server restarted, are sent the latest
+// events of every type.
+func (f *Fanout) ResumeClient(conn *websocket.Conn, epoch string, since uint64) *Client {
 	c := &Client{
-		fo:   f,
-		conn: conn,
-		send: make(chan WebsocketEvent, channelSize),
-		quit: make(chan bool, 1),
+		fo:    f,
+		conn:  conn,
+		send:  make(chan WebsocketEvent, channelSize),
+		quit:  make(chan struct{}),
+		epoch: epoch,
+		since: since,
 	}
 
+	// compress if the client negotiated permessage-deflate
+	conn.EnableWriteCompression(true)
+
 	f.RegisterClient(c)
 
 	go c.writer()
@@ -52,16 +77,29 @@ func (f *Fanout) NewClient(conn *websocket.Conn) *Client {
 	return c
 }
 
-// Send is used to send an image message to the client.
+// Send is used to send an image message to the client. Clients that fall
+// too far behind are disconnected rather than holding up other clients, and
+// can resume where they left off when they reconnect.
 func (c *Client) Send(event WebsocketEvent) {
-	c.send <- event
+	select {
+	case c.send <- event:
+	default:
+		go c.Quit()
+	}
 }
 
-// Quit will close the connection and unregiseter it from the Fanout.
+// Quit will close the connection and unregiseter it from the Fanout. It's
+// safe to call more than once.
 func (c *Client) Quit() {
 	c.fo.UnregisterClient(c)
-	c.quit <- true
-	c.conn.Close()
+	c.close()
+}
+
+func (c *Client) close() {
+	c.once.Do(func() {
+		close(c.quit)
+		c.conn.Close()
+	})
 }
 
 // reader reads pong messages off of the connection. Once it recieves a message,
@@ -83,21 +121,30 @@ func (c *Client) reader() {
 }
 
 // writer writes image events over the socket when it recieves messages via
-// Send(). It also sends pings to ensure the connection stays alive.
+// Send(). It also sends pings to ensure the connection stays alive, and
+// heartbeats so the client can tell it does.
 func (c *Client) writer() {
-	ticker := time.NewTicker(pingPeriod)
-	defer ticker.Stop()
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+	heartbeat := time.NewTicker(heartbeatPeriod)
+	defer heartbeat.Stop()
+
+	write := func(event WebsocketEvent) {
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.conn.WriteJSON(event); err != nil {
+			c.Quit()
+		}
+	}
 
 	for {
 		select {
 		case <-c.quit:
 			return
 		case event := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteJSON(event); err != nil {
-				c.Quit()
-			}
-		case <-ticker.C:
+			write(event)
+		case <-heartbeat.C:
+			write(WebsocketEvent{Type: EventTypeHeartbeat})
+		case <-ping.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				c.Quit()
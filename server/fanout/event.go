@@ -12,6 +12,14 @@ const (
 	// EventTypeErr is used to signal there was an error encountered rendering
 	// the image.
 	EventTypeErr = "error"
+
+	// EventTypeHello is sent first on every connection, with the epoch
+	// that clients pass back when they resume.
+	EventTypeHello = "hello"
+
+	// EventTypeHeartbeat is sent periodically so that clients can tell the
+	// connection is still alive, and reconnect if heartbeats stop.
+	EventTypeHeartbeat = "heartbeat"
 )
 
 // WebsocketEvent is a structure used to send messages over the socket.
@@ -24,4 +32,13 @@ type WebsocketEvent struct {
 
 	// Type is the type of message we are sending over the socket.
 	Type string `json:"type"`
+
+	// Seq numbers the events broadcast by a fanout, in order. Clients
+	// resume from the last one they saw when they reconnect.
+	Seq uint64 `json:"seq,omitempty"`
+
+	// Epoch identifies the fanout, so that clients can tell when the server
+	// has restarted and sequence numbers have started over. It's only set
+	// on hello events.
+	Epoch string `json:"epoch,omitempty"`
 }
@@ -1,5 +1,11 @@
 package fanout
 
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+)
+
 // Fanout provides a structure for broadcasting messages to registered clients
 // when an update comes in on a go channel.
 type Fanout struct {
@@ -7,15 +13,23 @@ type Fanout struct {
 	quit       chan bool
 	register   chan *Client
 	unregister chan *Client
+
+	// epoch identifies the fanout to clients that resume, see
+	// ResumeClient.
+	epoch string
 }
 
 // NewFanout creates a new Fanout structure and runs the main loop.
 func NewFanout() *Fanout {
+	b := make([]byte, 8)
+	rand.Read(b)
+
 	fo := &Fanout{
 		broadcast:  make(chan WebsocketEvent, channelSize),
 		register:   make(chan *Client, channelSize),
 		unregister: make(chan *Client, channelSize),
 		quit:       make(chan bool, 1),
+		epoch:      hex.EncodeToString(b),
 	}
 
 	go fo.run()
@@ -23,7 +37,9 @@ func NewFanout() *Fanout {
 	return fo
 }
 
-// Broadcast sends a message to all registered clients.
+// Broadcast sends a message to all registered clients. A message identical
+// to the one broadcast before it is skipped, since clients already have it,
+// so apps that re-render often but rarely change don't use any bandwidth.
 func (fo *Fanout) Broadcast(event WebsocketEvent) {
 	fo.broadcast <- event
 }
@@ -44,27 +60,64 @@ func (fo *Fanout) Quit() {
 }
 
 // run is the main loop. It provides a mechanism to register/unregister clients
-// and will broadcast messages as they come in.
+// and will broadcast messages as they come in. Broadcasts are numbered, and
+// the latest of each type kept for clients that connect or resume later.
 func (fo *Fanout) run() {
 	clients := map[*Client]bool{}
+	latest := map[string]WebsocketEvent{}
+	var seq uint64
+	var last WebsocketEvent
 
 	for {
 		select {
 		case <-fo.quit:
 			for client := range clients {
-				client.Quit()
+				client.close()
 			}
 		case c := <-fo.register:
 			clients[c] = true
-		case c := <-fo.unregister:
-			if _, ok := clients[c]; ok {
-				delete(clients, c)
-				c.Quit()
+			c.Send(WebsocketEvent{Type: EventTypeHello, Epoch: fo.epoch})
+			for _, event := range missed(latest, c, fo.epoch) {
+				c.Send(event)
 			}
+		case c := <-fo.unregister:
+			delete(clients, c)
 		case broadcast := <-fo.broadcast:
+			if seq > 0 && unchanged(last, broadcast) {
+				continue
+			}
+			last = broadcast
+			seq++
+			broadcast.Seq = seq
+			latest[broadcast.Type] = broadcast
 			for client := range clients {
 				client.Send(broadcast)
 			}
 		}
 	}
 }
+
+// missed returns the latest events of each type that a client hasn't seen,
+// in the order they were broadcast.
+func missed(latest map[string]WebsocketEvent, c *Client, epoch string) []WebsocketEvent {
+	since := c.since
+	if c.epoch != epoch {
+		since = 0
+	}
+
+	var events []WebsocketEvent
+	for _, event := range latest {
+		if event.Seq > since {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events
+}
+
+// unchanged reports whether an event is the same as the one before it, apart
+// from its sequence number.
+func unchanged(last, event WebsocketEvent) bool {
+	last.Seq = event.Seq
+	return last == event
+}
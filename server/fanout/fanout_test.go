@@ -0,0 +1,117 @@
+package fanout
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, fo *Fanout) string {
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+		fo.ResumeClient(conn, r.URL.Query().Get("epoch"), since)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial(url, nil)
+	require.NoError(t, err)
+	assert.Contains(t, resp.Header.Get("Sec-Websocket-Extensions"), "permessage-deflate")
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func read(t *testing.T, conn *websocket.Conn) WebsocketEvent {
+	var event WebsocketEvent
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	require.NoError(t, conn.ReadJSON(&event))
+	return event
+}
+
+func TestResume(t *testing.T) {
+	fo := NewFanout()
+	url := newTestServer(t, fo)
+
+	first := dial(t, url)
+	hello := read(t, first)
+	assert.Equal(t, EventTypeHello, hello.Type)
+	require.NotEmpty(t, hello.Epoch)
+
+	fo.Broadcast(WebsocketEvent{Type: EventTypeImage, Message: "a"})
+	fo.Broadcast(WebsocketEvent{Type: EventTypeSchema, Message: "{}"})
+	fo.Broadcast(WebsocketEvent{Type: EventTypeImage, Message: "b"})
+	assert.Equal(t, WebsocketEvent{Type: EventTypeImage, Message: "a", Seq: 1}, read(t, first))
+	assert.Equal(t, WebsocketEvent{Type: EventTypeSchema, Message: "{}", Seq: 2}, read(t, first))
+	assert.Equal(t, WebsocketEvent{Type: EventTypeImage, Message: "b", Seq: 3}, read(t, first))
+
+	// a client that saw the first image is sent the latest of each type
+	// it missed
+	resumed := dial(t, url+"?epoch="+hello.Epoch+"&since=1")
+	assert.Equal(t, EventTypeHello, read(t, resumed).Type)
+	assert.Equal(t, uint64(2), read(t, resumed).Seq)
+	assert.Equal(t, "b", read(t, resumed).Message)
+
+	// a client that's up to date is sent only what's new
+	current := dial(t, url+"?epoch="+hello.Epoch+"&since=3")
+	assert.Equal(t, EventTypeHello, read(t, current).Type)
+	fo.Broadcast(WebsocketEvent{Type: EventTypeImage, Message: "c"})
+	assert.Equal(t, WebsocketEvent{Type: EventTypeImage, Message: "c", Seq: 4}, read(t, current))
+
+	// a client from before a restart is sent everything
+	restarted := dial(t, url+"?epoch=stale&since=3")
+	assert.Equal(t, EventTypeHello, read(t, restarted).Type)
+	assert.Equal(t, uint64(2), read(t, restarted).Seq)
+	assert.Equal(t, "c", read(t, restarted).Message)
+}
+
+func TestClosedClient(t *testing.T) {
+	fo := NewFanout()
+	url := newTestServer(t, fo)
+
+	closed := dial(t, url)
+	read(t, closed)
+	closed.Close()
+
+	// broadcasts carry on for other clients once the closed one is gone
+	other := dial(t, url)
+	read(t, other)
+	for i := 0; i < channelSize; i++ {
+		fo.Broadcast(WebsocketEvent{Type: EventTypeImage, Message: strconv.Itoa(i)})
+		assert.Equal(t, strconv.Itoa(i), read(t, other).Message)
+	}
+}
+
+func TestSkipUnchanged(t *testing.T) {
+	fo := NewFanout()
+	url := newTestServer(t, fo)
+
+	conn := dial(t, url)
+	read(t, conn)
+
+	fo.Broadcast(WebsocketEvent{Type: EventTypeImage, Message: "a"})
+	fo.Broadcast(WebsocketEvent{Type: EventTypeImage, Message: "a"})
+	fo.Broadcast(WebsocketEvent{Type: EventTypeErr, Message: "oops"})
+	fo.Broadcast(WebsocketEvent{Type: EventTypeImage, Message: "a"})
+	fo.Broadcast(WebsocketEvent{Type: EventTypeImage, Message: "a", ImageType: "gif"})
+
+	assert.Equal(t, WebsocketEvent{Type: EventTypeImage, Message: "a", Seq: 1}, read(t, conn))
+	assert.Equal(t, WebsocketEvent{Type: EventTypeErr, Message: "oops", Seq: 2}, read(t, conn))
+
+	// the image is sent again after the error, which the browser clears
+	// when it gets one
+	assert.Equal(t, WebsocketEvent{Type: EventTypeImage, Message: "a", Seq: 3}, read(t, conn))
+	assert.Equal(t, WebsocketEvent{Type: EventTypeImage, Message: "a", ImageType: "gif", Seq: 4}, read(t, conn))
+}
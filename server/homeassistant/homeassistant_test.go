@@ -0,0 +1,82 @@
+package homeassistant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/mqtt"
+	"tidbyt.dev/pixlet/server/loader"
+)
+
+type fakeDisplay struct {
+	config   map[string]string
+	state    loader.DisplayState
+	rendered []map[string]string
+}
+
+func (d *fakeDisplay) Config() map[string]string    { return d.config }
+func (d *fakeDisplay) Display() loader.DisplayState { return d.state }
+
+func (d *fakeDisplay) LoadApplet(config map[string]string) (string, error) {
+	d.rendered = append(d.rendered, config)
+	return "", nil
+}
+
+func (d *fakeDisplay) SetDisplay(s loader.DisplayState) (string, error) {
+	d.state = s
+	return d.LoadApplet(d.config)
+}
+
+func newTestBridge(t *testing.T, brokerURL string) (*Bridge, *fakeDisplay) {
+	d := &fakeDisplay{
+		config: map[string]string{"who": "Grace"},
+		state:  loader.DisplayState{Brightness: 100, On: true},
+	}
+	b, err := NewBridge(brokerURL, "hello.world", d)
+	require.NoError(t, err)
+	return b, d
+}
+
+func TestDiscovery(t *testing.T) {
+	b, _ := newTestBridge(t, "mqtt://localhost")
+	assert.Equal(t, "pixlet_hello_world", b.node)
+
+	entities := b.discovery()
+	assert.Len(t, entities, 4)
+
+	brightness := entities["homeassistant/number/pixlet_hello_world/brightness/config"]
+	require.NotNil(t, brightness)
+	assert.Equal(t, "pixlet/pixlet_hello_world/brightness/set", brightness["command_topic"])
+	assert.Equal(t, "pixlet_hello_world_brightness", brightness["unique_id"])
+	assert.Equal(t, "pixlet/pixlet_hello_world/status", brightness["availability_topic"])
+
+	button := entities["homeassistant/button/pixlet_hello_world/render/config"]
+	require.NotNil(t, button)
+	assert.Equal(t, "hello.world", button["payload_press"])
+
+	b, _ = newTestBridge(t, "mqtt://localhost?node_id=kitchen&discovery_prefix=ha")
+	assert.Contains(t, b.discovery(), "ha/switch/kitchen/screen/config")
+}
+
+func TestHandle(t *testing.T) {
+	b, d := newTestBridge(t, "mqtt://localhost?node_id=kitchen")
+
+	require.NoError(t, b.handle(mqtt.Message{Topic: "pixlet/kitchen/brightness/set", Payload: []byte("40")}))
+	assert.Equal(t, loader.DisplayState{Brightness: 40, On: true}, d.state)
+	assert.Error(t, b.handle(mqtt.Message{Topic: "pixlet/kitchen/brightness/set", Payload: []byte("140")}))
+
+	require.NoError(t, b.handle(mqtt.Message{Topic: "pixlet/kitchen/screen/set", Payload: []byte("OFF")}))
+	assert.False(t, d.state.On)
+	assert.Error(t, b.handle(mqtt.Message{Topic: "pixlet/kitchen/screen/set", Payload: []byte("dim")}))
+
+	d.rendered = nil
+	require.NoError(t, b.handle(mqtt.Message{Topic: "pixlet/kitchen/render", Payload: []byte("hello.world")}))
+	require.NoError(t, b.handle(mqtt.Message{
+		Topic:   "pixlet/kitchen/render",
+		Payload: []byte(`{"app": "hello.world", "config": {"who": "Ada"}}`),
+	}))
+	assert.Equal(t, []map[string]string{{"who": "Grace"}, {"who": "Ada"}}, d.rendered)
+
+	assert.ErrorContains(t, b.handle(mqtt.Message{Topic: "pixlet/kitchen/render", Payload: []byte("clock")}), `no app named "clock"`)
+}
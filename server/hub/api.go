@@ -0,0 +1,733 @@
+package hub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"tidbyt.dev/pixlet/device"
+	"tidbyt.dev/pixlet/lib"
+	"tidbyt.dev/pixlet/schema"
+	"tidbyt.dev/pixlet/server/sink"
+)
+
+// deviceJSON is how Tidbyt's API describes a device.
+type deviceJSON struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	Brightness  int    `json:"brightness"`
+	AutoDim     bool   `json:"autoDim"`
+	PinnedApp   string `json:"pinnedApp,omitempty"`
+
+	Locale   string            `json:"locale,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Profile  string            `json:"profile,omitempty"`
+
+	Telemetry *Telemetry `json:"telemetry,omitempty"`
+
+	// Sinks are how deliveries to the device's push target and sinks
+	// have gone, in that order.
+	Sinks []SinkStatus `json:"sinks,omitempty"`
+}
+
+func (h *Hub) newDeviceJSON(d *Device) deviceJSON {
+	return deviceJSON{
+		ID:          d.ID,
+		DisplayName: d.DisplayName,
+		Brightness:  d.Brightness,
+		AutoDim:     d.AutoDim,
+		PinnedApp:   d.PinnedApp,
+		Locale:      d.Locale,
+		Metadata:    d.Metadata,
+		Profile:     d.Profile,
+		Telemetry:   h.deviceTelemetry(d.ID),
+		Sinks:       h.sinkStatus(d.ID),
+	}
+}
+
+type installationJSON struct {
+	ID        string    `json:"id"`
+	AppID     string    `json:"appID"`
+	Enabled   bool      `json:"enabled"`
+	DwellSecs int       `json:"dwellSecs,omitempty"`
+	Refresh   string    `json:"refresh,omitempty"`
+	Schedule  *Schedule `json:"schedule,omitempty"`
+	Version   string    `json:"version,omitempty"`
+
+	// LatestVersion is the version of the app in the catalog, if it's
+	// newer than the one installed.
+	LatestVersion string `json:"latestVersion,omitempty"`
+}
+
+func (h *Hub) newInstallationJSON(inst *Installation) installationJSON {
+	j := installationJSON{
+		ID:        inst.ID,
+		AppID:     inst.AppID,
+		Enabled:   !inst.Disabled,
+		DwellSecs: inst.DwellSecs,
+		Refresh:   inst.Refresh,
+		Schedule:  inst.Schedule,
+		Version:   inst.Version,
+	}
+	if app, err := h.apps.Applet(inst.AppID); err == nil {
+		if m := app.Manifest(); m != nil && m.IsUpdateOf(inst.Version) {
+			j.LatestVersion = m.Version
+		}
+	}
+	return j
+}
+
+// pushRequest is the body of a push, as in Tidbyt's API.
+type pushRequest struct {
+	DeviceID       string `json:"deviceID"`
+	Image          string `json:"image"`
+	InstallationID string `json:"installationID"`
+	Background     bool   `json:"background"`
+}
+
+// installRequest installs an app from the catalog on a device.
+type installRequest struct {
+	AppID          string            `json:"appID"`
+	InstallationID string            `json:"installationID"`
+	Config         map[string]string `json:"config"`
+	DwellSecs      int               `json:"dwellSecs"`
+	Refresh        string            `json:"refresh"`
+	Schedule       *Schedule         `json:"schedule"`
+}
+
+// patchInstallationRequest changes an installation's place in the playlist.
+// Fields left out aren't changed.
+type patchInstallationRequest struct {
+	Enabled   *bool             `json:"enabled"`
+	DwellSecs *int              `json:"dwellSecs"`
+	Refresh   *string           `json:"refresh"`
+	Config    map[string]string `json:"config"`
+
+	// Schedule replaces the installation's schedule. An empty schedule
+	// removes it.
+	Schedule *Schedule `json:"schedule"`
+}
+
+// playlistRequest reorders a device's installations. Installations that
+// aren't listed keep their order, after the listed ones.
+type playlistRequest struct {
+	Installations []string `json:"installations"`
+}
+
+// patchDeviceRequest changes a device's settings. Fields left out aren't
+// changed.
+type patchDeviceRequest struct {
+	DisplayName *string `json:"displayName"`
+	Brightness  *int    `json:"brightness"`
+	AutoDim     *bool   `json:"autoDim"`
+	PinnedApp   *string `json:"pinnedApp"`
+
+	// Locale and Metadata are passed to apps rendered for the device, see
+	// Device. Metadata replaces what's there; an empty object removes it.
+	Locale   *string            `json:"locale"`
+	Metadata *map[string]string `json:"metadata"`
+
+	// Profile sets the device's profile by name. An empty one makes
+	// apps render with the hub's options again.
+	Profile *string `json:"profile"`
+
+	// Push sets where the hub pushes the device's images. A push target
+	// without a URL makes the device poll again.
+	Push *PushTarget `json:"push"`
+
+	// Sinks replaces the other places the hub delivers the device's
+	// images to. An empty list removes them.
+	Sinks *[]sink.Config `json:"sinks"`
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError responds with err, as a 404 if something wasn't found.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var configErr *schema.ConfigError
+	var capErr *lib.CapabilityError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.As(err, &configErr), errors.As(err, &capErr):
+		status = http.StatusBadRequest
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func (h *Hub) listDevicesHandler(w http.ResponseWriter, r *http.Request) {
+	devices := []deviceJSON{}
+	for _, d := range h.store.Devices() {
+		devices = append(devices, h.newDeviceJSON(d))
+	}
+	writeJSON(w, map[string]any{"devices": devices})
+}
+
+func (h *Hub) getDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	d, err := h.store.Device(r.PathValue("device"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, h.newDeviceJSON(d))
+}
+
+func (h *Hub) patchDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	var req patchDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decoding request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Brightness != nil && (*req.Brightness < 0 || *req.Brightness > 100) {
+		http.Error(w, "brightness must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+	if req.Profile != nil && *req.Profile != "" {
+		if _, err := device.LookupProfile(*req.Profile); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Sinks != nil {
+		for _, cfg := range *req.Sinks {
+			s, err := sink.New(cfg)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			s.Close()
+		}
+	}
+
+	var updated *Device
+	err := h.store.Update(r.PathValue("device"), false, func(d *Device) error {
+		if req.PinnedApp != nil && *req.PinnedApp != "" && d.Installation(*req.PinnedApp) == nil {
+			return fmt.Errorf("installation %q: %w", *req.PinnedApp, ErrNotFound)
+		}
+		if req.DisplayName != nil {
+			d.DisplayName = *req.DisplayName
+		}
+		if req.Brightness != nil {
+			d.Brightness = *req.Brightness
+		}
+		if req.AutoDim != nil {
+			d.AutoDim = *req.AutoDim
+		}
+		if req.PinnedApp != nil {
+			d.PinnedApp = *req.PinnedApp
+		}
+		if req.Locale != nil {
+			d.Locale = *req.Locale
+		}
+		if req.Metadata != nil {
+			d.Metadata = maps.Clone(*req.Metadata)
+			if len(d.Metadata) == 0 {
+				d.Metadata = nil
+			}
+		}
+		if req.Profile != nil {
+			d.Profile = *req.Profile
+		}
+		if req.Push != nil && req.Push.URL == "" {
+			d.Push = nil
+		} else if req.Push != nil {
+			d.Push = req.Push
+		}
+		if req.Sinks != nil {
+			d.Sinks = *req.Sinks
+		}
+		updated = d
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.wake(updated.ID)
+	writeJSON(w, h.newDeviceJSON(updated))
+}
+
+// pushHandler takes an image for a device, which is created if it doesn't
+// exist yet. Images pushed to an installation replace its image in the
+// device's playlist, and those pushed without one are only shown once.
+// Unless the push is in the background, the image is shown next.
+func (h *Hub) pushHandler(w http.ResponseWriter, r *http.Request) {
+	var req pushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decoding request: %v", err), http.StatusBadRequest)
+		return
+	}
+	image, err := base64.StdEncoding.DecodeString(req.Image)
+	if err != nil || len(image) == 0 {
+		http.Error(w, "image must be a base64 encoded WebP or GIF", http.StatusBadRequest)
+		return
+	}
+	if req.Background && req.InstallationID == "" {
+		http.Error(w, "background push won't do anything unless you also specify an installation ID", http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("device")
+	err = h.store.Update(id, true, func(d *Device) error {
+		if !req.Background {
+			d.Interrupt = image
+		}
+		if req.InstallationID == "" {
+			return nil
+		}
+
+		inst := d.Installation(req.InstallationID)
+		if inst == nil {
+			inst = &Installation{ID: req.InstallationID, AppID: req.InstallationID}
+			d.Installations = append(d.Installations, inst)
+		}
+		inst.Image = image
+		inst.Screens = nil
+		inst.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !req.Background {
+		h.wake(id)
+	}
+	writeJSON(w, struct{}{})
+}
+
+func (h *Hub) listInstallationsHandler(w http.ResponseWriter, r *http.Request) {
+	d, err := h.store.Device(r.PathValue("device"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	installations := []installationJSON{}
+	for _, inst := range d.Installations {
+		installations = append(installations, h.newInstallationJSON(inst))
+	}
+	writeJSON(w, map[string]any{"installations": installations})
+}
+
+// installHandler installs an app from the catalog on a device, at the end
+// of its playlist, and renders it. The installation ID defaults to the app's
+// ID, and installing to an existing installation replaces its app and
+// config.
+func (h *Hub) installHandler(w http.ResponseWriter, r *http.Request) {
+	var req installRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decoding request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.InstallationID == "" {
+		req.InstallationID = req.AppID
+	}
+	if req.Refresh != "" {
+		if _, err := ParseRefresh(req.Refresh); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Schedule != nil {
+		if err := req.Schedule.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	app, err := h.apps.Applet(req.AppID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Config == nil {
+		req.Config = app.DefaultConfig()
+	}
+
+	inst := &Installation{
+		ID:        req.InstallationID,
+		AppID:     req.AppID,
+		Config:    req.Config,
+		DwellSecs: req.DwellSecs,
+		Refresh:   req.Refresh,
+		Schedule:  req.Schedule,
+	}
+	if m := app.Manifest(); m != nil {
+		inst.Version = m.Version
+	}
+	deviceID := r.PathValue("device")
+	if inst.Config, err = app.PrepareUploads(inst.Config, h.renderOptions(deviceID, inst)); err != nil {
+		writeError(w, err)
+		return
+	}
+	if d, err := h.store.Device(deviceID); err == nil {
+		if old := d.Installation(inst.ID); old != nil && old.AppID == inst.AppID {
+			// reinstalling an app keeps what it saved
+			inst.State = old.State
+		}
+	}
+
+	imgs, err := h.renderInstallation(r.Context(), deviceID, inst)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	inst.setRender(imgs, time.Now())
+
+	err = h.store.Update(deviceID, true, func(d *Device) error {
+		i := slices.IndexFunc(d.Installations, func(old *Installation) bool { return old.ID == inst.ID })
+		if i < 0 {
+			d.Installations = append(d.Installations, inst)
+			return nil
+		}
+		old := d.Installations[i]
+		inst.Disabled = old.Disabled
+		if inst.Schedule == nil {
+			inst.Schedule = old.Schedule
+		}
+		if inst.DwellSecs == 0 {
+			inst.DwellSecs = old.DwellSecs
+		}
+		d.Installations[i] = inst
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.scheduleRefresh(deviceID, inst, inst.UpdatedAt)
+	writeJSON(w, h.newInstallationJSON(inst))
+}
+
+// patchInstallationHandler enables or disables an installation, or changes
+// when and how long it's shown for, or how often and with what config it's rendered.
+// Installations rendered in the background are rendered again right away if
+// either changes.
+func (h *Hub) patchInstallationHandler(w http.ResponseWriter, r *http.Request) {
+	var req patchInstallationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decoding request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.DwellSecs != nil && *req.DwellSecs < 0 {
+		http.Error(w, "dwellSecs can't be negative", http.StatusBadRequest)
+		return
+	}
+	if req.Refresh != nil && *req.Refresh != "" {
+		if _, err := ParseRefresh(*req.Refresh); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Schedule != nil {
+		if err := req.Schedule.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	deviceID, id := r.PathValue("device"), r.PathValue("installation")
+	if req.Config != nil {
+		config, err := h.prepareUploads(deviceID, id, req.Config)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		req.Config = config
+	}
+
+	var updated *Installation
+	err := h.store.Update(deviceID, false, func(d *Device) error {
+		inst := d.Installation(id)
+		if inst == nil {
+			return fmt.Errorf("installation %q: %w", id, ErrNotFound)
+		}
+		if (req.Config != nil || req.Refresh != nil) && !inst.Rendered() {
+			return fmt.Errorf("installation %q is pushed to, so it isn't rendered", id)
+		}
+		if req.Enabled != nil {
+			inst.Disabled = !*req.Enabled
+		}
+		if req.DwellSecs != nil {
+			inst.DwellSecs = *req.DwellSecs
+		}
+		if req.Refresh != nil {
+			inst.Refresh = *req.Refresh
+		}
+		if req.Schedule != nil && req.Schedule.empty() {
+			inst.Schedule = nil
+		} else if req.Schedule != nil {
+			inst.Schedule = req.Schedule
+		}
+		if req.Config != nil {
+			inst.Config = req.Config
+			// the app's last suggestion was for its old config
+			inst.RefreshAt = time.Time{}
+		}
+		updated = inst
+		return nil
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, err)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Config != nil || req.Refresh != nil {
+		h.renderSoon(deviceID, id)
+	}
+	writeJSON(w, h.newInstallationJSON(updated))
+}
+
+// prepareUploads prepares the files uploaded in config for an installation
+// on a device before it's saved, see lib.Applet.PrepareUploads.
+func (h *Hub) prepareUploads(deviceID, id string, config map[string]string) (map[string]string, error) {
+	d, err := h.store.Device(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	inst := d.Installation(id)
+	if inst == nil {
+		return nil, fmt.Errorf("installation %q: %w", id, ErrNotFound)
+	}
+	if !inst.Rendered() {
+		// pushed installations don't take config
+		return config, nil
+	}
+	app, err := h.apps.Applet(inst.AppID)
+	if err != nil {
+		return nil, err
+	}
+	return app.PrepareUploads(config, h.renderOptions(deviceID, inst))
+}
+
+// exportConfigHandler serves an installation's config as a
+// schema.ConfigExport, for importing into another server or pixlet serve.
+func (h *Hub) exportConfigHandler(w http.ResponseWriter, r *http.Request) {
+	d, err := h.store.Device(r.PathValue("device"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	id := r.PathValue("installation")
+	inst := d.Installation(id)
+	if inst == nil {
+		writeError(w, fmt.Errorf("installation %q: %w", id, ErrNotFound))
+		return
+	}
+
+	e, err := schema.NewConfigExport(inst.AppID, inst.Config)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, e)
+}
+
+// importConfigHandler replaces an installation's config with one from a
+// schema.ConfigExport, migrated to the schema of the installed app, and
+// renders it again.
+func (h *Hub) importConfigHandler(w http.ResponseWriter, r *http.Request) {
+	e, err := schema.DecodeConfigExport(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deviceID, id := r.PathValue("device"), r.PathValue("installation")
+	d, err := h.store.Device(deviceID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	inst := d.Installation(id)
+	if inst == nil {
+		writeError(w, fmt.Errorf("installation %q: %w", id, ErrNotFound))
+		return
+	}
+	if !inst.Rendered() {
+		http.Error(w, fmt.Sprintf("installation %q is pushed to, so it isn't rendered", id), http.StatusBadRequest)
+		return
+	}
+	if err := e.CheckApp(inst.AppID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	app, err := h.apps.Applet(inst.AppID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	config, err := app.MigrateConfig(r.Context(), e.SavedConfig())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("migrating config: %v", err), http.StatusBadRequest)
+		return
+	}
+	if s := app.Schema(); s != nil {
+		if err := s.ValidateConfig(config); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if config, err = app.PrepareUploads(config, h.renderOptions(deviceID, inst)); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var updated *Installation
+	err = h.store.Update(deviceID, false, func(d *Device) error {
+		inst := d.Installation(id)
+		if inst == nil {
+			return fmt.Errorf("installation %q: %w", id, ErrNotFound)
+		}
+		inst.Config = config
+		// the app's last suggestion was for its old config
+		inst.RefreshAt = time.Time{}
+		updated = inst
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.renderSoon(deviceID, id)
+	writeJSON(w, h.newInstallationJSON(updated))
+}
+
+// playlistHandler reorders a device's installations.
+func (h *Hub) playlistHandler(w http.ResponseWriter, r *http.Request) {
+	var req playlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decoding request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var updated *Device
+	err := h.store.Update(r.PathValue("device"), false, func(d *Device) error {
+		order := make([]*Installation, 0, len(d.Installations))
+		for _, id := range req.Installations {
+			inst := d.Installation(id)
+			if inst == nil {
+				return fmt.Errorf("installation %q: %w", id, ErrNotFound)
+			}
+			if !slices.Contains(order, inst) {
+				order = append(order, inst)
+			}
+		}
+		for _, inst := range d.Installations {
+			if !slices.Contains(order, inst) {
+				order = append(order, inst)
+			}
+		}
+		d.Installations = order
+		updated = d
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	installations := []installationJSON{}
+	for _, inst := range updated.Installations {
+		installations = append(installations, h.newInstallationJSON(inst))
+	}
+	writeJSON(w, map[string]any{"installations": installations})
+}
+
+func (h *Hub) deleteInstallationHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("installation")
+	err := h.store.Update(r.PathValue("device"), false, func(d *Device) error {
+		i := slices.IndexFunc(d.Installations, func(inst *Installation) bool { return inst.ID == id })
+		if i < 0 {
+			return fmt.Errorf("installation %q: %w", id, ErrNotFound)
+		}
+		d.Installations = slices.Delete(d.Installations, i, i+1)
+		if d.PinnedApp == id {
+			d.PinnedApp = ""
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, struct{}{})
+}
+
+func (h *Hub) listAppsHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := h.apps.IDs()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	type appJSON struct {
+		ID string `json:"id"`
+	}
+	apps := []appJSON{}
+	for _, id := range ids {
+		apps = append(apps, appJSON{ID: id})
+	}
+	writeJSON(w, map[string]any{"apps": apps})
+}
+
+// schemaHandler serves the schema of an app in the catalog, for building
+// the form its installations are configured with.
+func (h *Hub) schemaHandler(w http.ResponseWriter, r *http.Request) {
+	app, err := h.apps.Applet(r.PathValue("app"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	js := app.SchemaJSON()
+	if js == nil {
+		js = []byte("{}")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// nextHandler serves the image a device should show now. Tronbyt firmware
+// reads its brightness and how long to show the image for from the
+// response's headers. It responds with 204 No Content if there's nothing to
+// show.
+func (h *Hub) nextHandler(w http.ResponseWriter, r *http.Request) {
+	image, dwell, d, err := h.next(r.Context(), r.PathValue("device"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Tronbyt-Brightness", strconv.Itoa(d.Brightness))
+	w.Header().Set("Tronbyt-Dwell-Secs", strconv.Itoa(int(dwell.Seconds())))
+	if image == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	contentType := "image/webp"
+	if device.ImageFormat(image) == "gif" {
+		contentType = "image/gif"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write(image); err != nil {
+		slog.Debug("writing image to device", "device", d.ID, "error", err)
+	}
+}
@@ -0,0 +1,91 @@
+package hub
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"tidbyt.dev/pixlet/lib"
+)
+
+// hostCapabilities is what the hub offers the apps it runs. They can make
+// requests, but not decrypt secrets, since it has no key for them. The
+// display size they require is checked against the size they're rendered
+// at.
+var hostCapabilities = lib.Capabilities{Network: true}
+
+// Catalog is the set of apps the hub can install, one per .star file or
+// directory of Starlark files in its directory. An app's ID is its file or
+// directory name, without the .star extension.
+type Catalog struct {
+	dir string
+
+	mu   sync.Mutex
+	apps map[string]*lib.Applet
+}
+
+// NewCatalog returns a catalog of the apps in dir. Apps are loaded the first
+// time they're used.
+func NewCatalog(dir string) *Catalog {
+	return &Catalog{dir: dir, apps: map[string]*lib.Applet{}}
+}
+
+// IDs returns the IDs of the apps in the catalog, sorted.
+func (c *Catalog) IDs() ([]string, error) {
+	if c.dir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing apps: %w", err)
+	}
+
+	var ids []string
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case strings.HasPrefix(name, "."):
+		case e.IsDir():
+			ids = append(ids, name)
+		case strings.HasSuffix(name, ".star"):
+			ids = append(ids, strings.TrimSuffix(name, ".star"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Applet returns the app with the given ID, loading it if it hasn't been
+// already.
+func (c *Catalog) Applet(id string) (*lib.Applet, error) {
+	if c.dir == "" || id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("app %q: %w", id, ErrNotFound)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if app, ok := c.apps[id]; ok {
+		return app, nil
+	}
+
+	path := filepath.Join(c.dir, id)
+	if _, err := os.Stat(path); err != nil {
+		path += ".star"
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("app %q: %w", id, ErrNotFound)
+		}
+	}
+
+	caps := hostCapabilities
+	app, err := lib.LoadAppletFromPath(path, lib.LoadOptions{SilencePrint: true, Capabilities: &caps})
+	if err != nil {
+		return nil, err
+	}
+	c.apps[id] = app
+	return app, nil
+}
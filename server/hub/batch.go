@@ -0,0 +1,150 @@
+package hub
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"tidbyt.dev/pixlet/lib"
+)
+
+// maxBatchRenders is how many renders a batch may ask for.
+const maxBatchRenders = 100
+
+// batchRequest renders several apps from the catalog at once, such as a
+// device's whole playlist.
+type batchRequest struct {
+	// Device is the ID of the device the renders are for, whose locale and
+	// metadata are passed to the apps. It's optional, and needn't be a
+	// device the hub knows.
+	Device string `json:"device"`
+
+	Renders []batchRender `json:"renders"`
+}
+
+// batchRender is an app in the catalog to render with a config.
+type batchRender struct {
+	App    string            `json:"app"`
+	Config map[string]string `json:"config"`
+}
+
+// batchResult is the outcome of a render in a batch. Image is the first
+// screen of the app; it's an error frame if the render failed and the hub
+// shows them.
+type batchResult struct {
+	App          string `json:"app"`
+	Image        []byte `json:"image,omitempty"`
+	Format       string `json:"format,omitempty"`
+	AppDwellSecs int    `json:"appDwellSecs,omitempty"`
+	Error        string `json:"error,omitempty"`
+
+	// File is the name of the image in a zip archive.
+	File string `json:"file,omitempty"`
+}
+
+// batchRenderHandler renders a list of apps with their configs, in
+// parallel, and responds with their images in the same order, so that hosts
+// can render a playlist in one round trip. Renders that fail don't fail the
+// batch, their results carry the error instead. Requests that accept
+// application/zip get a zip archive of the images, with a results.json
+// describing them, instead of JSON with the images in base64.
+func (h *Hub) batchRenderHandler(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decoding batch: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(req.Renders) == 0 {
+		http.Error(w, "no renders", http.StatusBadRequest)
+		return
+	}
+	if len(req.Renders) > maxBatchRenders {
+		http.Error(w, fmt.Sprintf("too many renders, at most %d are allowed", maxBatchRenders), http.StatusBadRequest)
+		return
+	}
+
+	results := make([]batchResult, len(req.Renders))
+	var g errgroup.Group
+	g.SetLimit(runtime.GOMAXPROCS(0))
+	for i, render := range req.Renders {
+		g.Go(func() error {
+			results[i] = h.renderBatchItem(r, req.Device, render)
+			return nil
+		})
+	}
+	g.Wait()
+
+	if strings.Contains(r.Header.Get("Accept"), "application/zip") {
+		writeBatchZip(w, results)
+		return
+	}
+	writeJSON(w, map[string]any{"results": results})
+}
+
+func (h *Hub) renderBatchItem(r *http.Request, deviceID string, render batchRender) batchResult {
+	result := batchResult{App: render.App}
+
+	inst := &Installation{AppID: render.App, Config: render.Config}
+	imgs, err := h.renderInstallation(r.Context(), deviceID, inst)
+	if err != nil {
+		slog.Warn("rendering app for batch", "app", render.App, "error", err)
+		result.Error = err.Error()
+		if !h.errorFrames {
+			return result
+		}
+		frame, err := lib.RenderError(render.App, err, time.Now(), h.renderOptions(deviceID, inst))
+		if err != nil {
+			return result
+		}
+		imgs = []*lib.EncodedImage{frame}
+	}
+
+	result.Image = imgs[0].Data
+	result.AppDwellSecs = int(imgs[0].ShowFor / time.Second)
+	if len(result.Image) > 0 {
+		result.Format = string(imgs[0].Format)
+	}
+	return result
+}
+
+// writeBatchZip responds with the images of a batch in a zip archive,
+// named by their position in the batch and their app, and a results.json
+// with the rest of the results.
+func writeBatchZip(w http.ResponseWriter, results []batchResult) {
+	w.Header().Set("Content-Type", "application/zip")
+	zw := zip.NewWriter(w)
+
+	for i := range results {
+		result := &results[i]
+		if len(result.Image) == 0 {
+			continue
+		}
+		result.File = fmt.Sprintf("%03d-%s.%s", i, result.App, result.Format)
+		f, err := zw.Create(result.File)
+		if err == nil {
+			_, err = f.Write(result.Image)
+		}
+		if err != nil {
+			slog.Debug("writing batch archive", "error", err)
+			return
+		}
+		result.Image = nil
+	}
+
+	f, err := zw.Create("results.json")
+	if err == nil {
+		err = json.NewEncoder(f).Encode(map[string]any{"results": results})
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		slog.Debug("writing batch archive", "error", err)
+	}
+}
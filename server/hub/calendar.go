@@ -0,0 +1,364 @@
+package hub
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// calendarTTL is how long a calendar feed is used for before it's fetched
+// again.
+const calendarTTL = 15 * time.Minute
+
+// event is a VEVENT in an iCalendar feed.
+type event struct {
+	summary    string
+	start, end time.Time
+	rrule      *rrule
+}
+
+// rrule is the subset of iCalendar's recurrence rules that calendars use
+// for repeating events: a frequency, an interval, an end, and the days of
+// the week for weekly events.
+type rrule struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    []time.Weekday
+}
+
+// parseICS reads the events in an iCalendar feed. Times without a time
+// zone, and in time zones Go doesn't know, are in loc.
+func parseICS(r io.Reader, loc *time.Location) ([]event, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []event
+	var ev *event
+	var duration time.Duration
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(name, ";")
+		name = strings.ToUpper(name)
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			ev = &event{}
+			duration = 0
+		case ev == nil:
+		case name == "END" && value == "VEVENT":
+			if ev.start.IsZero() {
+				return nil, fmt.Errorf("event %q has no start", ev.summary)
+			}
+			if ev.end.IsZero() {
+				ev.end = ev.start.Add(duration)
+			}
+			events = append(events, *ev)
+			ev = nil
+		case name == "SUMMARY":
+			ev.summary = unescapeICS(value)
+		case name == "DTSTART":
+			if ev.start, err = parseICSTime(params, value, loc); err != nil {
+				return nil, err
+			}
+			if len(value) == len("20060102") {
+				// all day events without an end last the day
+				duration = 24 * time.Hour
+			}
+		case name == "DTEND":
+			if ev.end, err = parseICSTime(params, value, loc); err != nil {
+				return nil, err
+			}
+		case name == "DURATION":
+			if duration, err = parseICSDuration(value); err != nil {
+				return nil, err
+			}
+		case name == "RRULE":
+			if ev.rrule, err = parseRRule(value, loc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return events, nil
+}
+
+// unfoldICS reads the lines of an iCalendar feed, joining lines that are
+// continued on the next.
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func unescapeICS(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+func parseICSTime(params, value string, loc *time.Location) (time.Time, error) {
+	for _, p := range strings.Split(params, ";") {
+		if tzid, ok := strings.CutPrefix(p, "TZID="); ok {
+			if l, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+				loc = l
+			}
+		}
+	}
+
+	var t time.Time
+	var err error
+	switch {
+	case len(value) == len("20060102"):
+		t, err = time.ParseInLocation("20060102", value, loc)
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse("20060102T150405Z", value)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+	}
+	if err != nil {
+		return t, fmt.Errorf("invalid calendar time %q", value)
+	}
+	return t, nil
+}
+
+// parseICSDuration parses durations such as P1D or PT1H30M.
+func parseICSDuration(s string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(s, "+"), "P")
+	if !ok {
+		return 0, fmt.Errorf("invalid calendar duration %q", s)
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var d time.Duration
+	n := 0
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == 'T':
+		case c >= '0' && c <= '9':
+			n = n*10 + int(c-'0')
+		case units[c] != 0:
+			d += time.Duration(n) * units[c]
+			n = 0
+		default:
+			return 0, fmt.Errorf("invalid calendar duration %q", s)
+		}
+	}
+	return d, nil
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func parseRRule(s string, loc *time.Location) (*rrule, error) {
+	r := &rrule{interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.freq = strings.ToUpper(v)
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(v)
+			if r.interval < 1 {
+				err = fmt.Errorf("invalid interval")
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(v)
+		case "UNTIL":
+			r.until, err = parseICSTime("", v, loc)
+			if len(v) == len("20060102") {
+				// until the end of the day
+				r.until = r.until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(v, ",") {
+				wd, ok := icsWeekdays[strings.ToUpper(day)]
+				if !ok {
+					// such as 1MO, the first Monday, which isn't
+					// supported
+					return nil, fmt.Errorf("unsupported recurrence %q", s)
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurrence %q: %w", s, err)
+		}
+	}
+
+	switch r.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported recurrence %q", s)
+	}
+	return r, nil
+}
+
+// occurrences calls fn with the start of each occurrence of the event, in
+// order, until fn returns false or the event stops repeating.
+func (e event) occurrences(fn func(start time.Time) bool) {
+	if e.rrule == nil {
+		fn(e.start)
+		return
+	}
+	r := e.rrule
+
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if t.Before(e.start) {
+			return true
+		}
+		if !r.until.IsZero() && t.After(r.until) || r.count > 0 && emitted >= r.count {
+			return false
+		}
+		emitted++
+		return fn(t)
+	}
+
+	y, m, d := e.start.Date()
+	hh, mm, ss := e.start.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, 0, e.start.Location())
+	}
+
+	// repeating events without an end are only followed this far
+	for i := 0; i < 100000; i++ {
+		n := i * r.interval
+		switch r.freq {
+		case "DAILY":
+			if !emit(at(y, m, d+n)) {
+				return
+			}
+		case "WEEKLY":
+			days := r.byDay
+			if len(days) == 0 {
+				days = []time.Weekday{e.start.Weekday()}
+			}
+			// weeks start on Monday
+			monday := d - (int(e.start.Weekday())+6)%7 + 7*n
+			for offset := 0; offset < 7; offset++ {
+				t := at(y, m, monday+offset)
+				if slices.Contains(days, t.Weekday()) && !emit(t) {
+					return
+				}
+			}
+		case "MONTHLY":
+			// months without the day are skipped
+			if t := at(y, m+time.Month(n), d); t.Day() == d && !emit(t) {
+				return
+			}
+		case "YEARLY":
+			if t := at(y+n, m, d); t.Day() == d && !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// happening reports whether an occurrence of the event overlaps from to to.
+func (e event) happening(from, to time.Time) bool {
+	length := e.end.Sub(e.start)
+	found := false
+	e.occurrences(func(start time.Time) bool {
+		if !start.Before(to) {
+			return false
+		}
+		if start.Add(length).After(from) {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// calendars fetches the iCalendar feeds installations are scheduled by,
+// and keeps them for calendarTTL.
+type calendars struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	feeds map[string]*calendarFeed
+}
+
+type calendarFeed struct {
+	events  []event
+	fetched time.Time
+	err     error
+}
+
+func newCalendars() *calendars {
+	return &calendars{
+		client: &http.Client{Timeout: 10 * time.Second},
+		feeds:  map[string]*calendarFeed{},
+	}
+}
+
+// events returns the events in the feed at url. If fetching it again fails,
+// the events fetched last are returned along with the error.
+func (c *calendars) events(ctx context.Context, url string) ([]event, error) {
+	c.mu.Lock()
+	feed, ok := c.feeds[url]
+	c.mu.Unlock()
+	if ok && time.Since(feed.fetched) < calendarTTL {
+		return feed.events, feed.err
+	}
+
+	events, err := c.fetch(ctx, url)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil && ok {
+		events = feed.events
+	}
+	c.feeds[url] = &calendarFeed{events: events, fetched: time.Now(), err: err}
+	return events, err
+}
+
+func (c *calendars) fetch(ctx context.Context, url string) ([]event, error) {
+	if rest, ok := strings.CutPrefix(url, "webcal://"); ok {
+		url = "https://" + rest
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching calendar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching calendar: %s", resp.Status)
+	}
+
+	events, err := parseICS(resp.Body, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("parsing calendar %s: %w", url, err)
+	}
+	return events, nil
+}
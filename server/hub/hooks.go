@@ -0,0 +1,213 @@
+package hub
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+// hookResult is an installation rendered by a webhook.
+type hookResult struct {
+	Device       string `json:"device"`
+	Installation string `json:"installation"`
+	Error        string `json:"error,omitempty"`
+}
+
+// HookToken returns the token the webhook of app takes, or "" if the hub has
+// no API key and webhooks are turned off. It's derived from the API key, so
+// that each app's webhook has its own without any being saved, and changes
+// with the key.
+func (h *Hub) HookToken(app string) string {
+	if h.apiKey == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(h.apiKey))
+	mac.Write([]byte("hook:" + app))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// hookAuthenticated wraps the webhook handler, which needs the token of the
+// app's webhook, as a bearer token or, for senders that can only be given a
+// URL, the token query parameter. The API key isn't accepted.
+func (h *Hub) hookAuthenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = r.URL.Query().Get("token")
+		}
+		if token == "" || !hmac.Equal([]byte(token), []byte(h.HookToken(r.PathValue("app")))) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "invalid webhook token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// getHookHandler returns the path and token of an app's webhook.
+func (h *Hub) getHookHandler(w http.ResponseWriter, r *http.Request) {
+	app := r.PathValue("app")
+	writeJSON(w, map[string]string{
+		"path":  "/api/v1/hooks/" + app,
+		"token": h.HookToken(app),
+	})
+}
+
+// maxHookBodySize bounds the config posted to a webhook, which leaves room
+// for a file uploaded to a FileUpload field.
+const maxHookBodySize = 1 << 20
+
+// decodeHookConfig reads the JSON object posted to a webhook as config.
+// Strings are used as they are, null removes a field, and other values are
+// kept as JSON, since config values are strings. An empty body is no
+// config.
+func decodeHookConfig(body io.Reader) (map[string]*string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&fields); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decoding config: %w", err)
+	}
+
+	config := make(map[string]*string, len(fields))
+	for k, raw := range fields {
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case nil:
+			config[k] = nil
+		case string:
+			config[k] = &v
+		default:
+			s := string(raw)
+			config[k] = &s
+		}
+	}
+	return config, nil
+}
+
+// hookHandler renders every installation of an app again right away, and
+// shows it next on devices where it's enabled, so that CI results,
+// doorbells and alerts reach displays within seconds. A JSON object posted
+// to it is merged into the installations' config first. The device and
+// installation query parameters narrow down which installations are
+// rendered.
+func (h *Hub) hookHandler(w http.ResponseWriter, r *http.Request) {
+	app := r.PathValue("app")
+	onlyDevice := r.URL.Query().Get("device")
+	onlyInstallation := r.URL.Query().Get("installation")
+
+	config, err := decodeHookConfig(http.MaxBytesReader(w, r.Body, maxHookBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results := []hookResult{}
+	for _, d := range h.store.Devices() {
+		if onlyDevice != "" && d.ID != onlyDevice {
+			continue
+		}
+		for _, inst := range d.Installations {
+			if inst.AppID != app || !inst.Rendered() || onlyInstallation != "" && inst.ID != onlyInstallation {
+				continue
+			}
+
+			result := hookResult{Device: d.ID, Installation: inst.ID}
+			if err := h.runHook(r, d.ID, inst, config); err != nil {
+				slog.Warn("rendering installation for webhook", "device", d.ID, "installation", inst.ID, "error", err)
+				result.Error = err.Error()
+			}
+			results = append(results, result)
+		}
+	}
+
+	if len(results) == 0 {
+		http.Error(w, fmt.Sprintf("no installations of %q", app), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]any{"rendered": results})
+}
+
+// runHook merges config into an installation's, renders it, and shows it
+// next if it's enabled. The merged config is only saved once it's valid and
+// renders, so that a bad post doesn't break the installation's later
+// renders.
+func (h *Hub) runHook(r *http.Request, deviceID string, inst *Installation, config map[string]*string) error {
+	if len(config) > 0 {
+		merged, err := h.mergeHookConfig(deviceID, inst, config)
+		if err != nil {
+			return err
+		}
+		withConfig := *inst
+		withConfig.Config = merged
+		inst = &withConfig
+	}
+
+	image, err := h.renderAndSave(r.Context(), deviceID, inst)
+	if err != nil {
+		return err
+	}
+
+	if len(config) > 0 {
+		err := h.store.Update(deviceID, false, func(d *Device) error {
+			saved := d.Installation(inst.ID)
+			if saved == nil {
+				return fmt.Errorf("installation %q: %w", inst.ID, ErrNotFound)
+			}
+			saved.Config = inst.Config
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(image) == 0 || inst.Disabled {
+		return nil
+	}
+
+	err = h.store.Update(deviceID, false, func(d *Device) error {
+		d.Interrupt = image
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	h.wake(deviceID)
+	return nil
+}
+
+// mergeHookConfig returns an installation's config with the config posted to
+// a webhook merged into it, checked against the app's schema.
+func (h *Hub) mergeHookConfig(deviceID string, inst *Installation, config map[string]*string) (map[string]string, error) {
+	merged := maps.Clone(inst.Config)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	for k, v := range config {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = *v
+		}
+	}
+
+	app, err := h.apps.Applet(inst.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if s := app.Schema(); s != nil {
+		if err := s.ValidateConfig(merged); err != nil {
+			return nil, err
+		}
+	}
+	return app.PrepareUploads(merged, h.renderOptions(deviceID, inst))
+}
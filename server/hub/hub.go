@@ -0,0 +1,264 @@
+// Package hub serves many devices from one host, with the subset of
+// Tidbyt's device API that its mobile apps and existing push scripts use, so
+// that they can be pointed at a self-hosted server instead.
+//
+// Images get to the hub by being pushed to it, as they would be to Tidbyt,
+// or by installing an app from the hub's catalog, which the hub renders
+// itself. Each device's installations make up its playlist, which the hub
+// cycles through, showing each for its dwell time. Devices either poll the
+// hub for the image to show next, or the hub pushes images to them.
+package hub
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"tidbyt.dev/pixlet/device"
+	"tidbyt.dev/pixlet/lib"
+)
+
+// DefaultDwell is how long devices show each image for.
+const DefaultDwell = 15 * time.Second
+
+// Options configure a Hub.
+type Options struct {
+	// AppsDir is the directory of apps that can be installed, see Catalog.
+	// Empty means apps can only be pushed.
+	AppsDir string
+
+	// StatePath is the file devices and their installations are saved to.
+	// Empty means they're only kept in memory.
+	StatePath string
+
+	// APIKey is the bearer token API requests must carry. Empty means
+	// requests aren't authenticated, and webhooks are turned off, since
+	// their tokens are derived from it, see Hub.HookToken.
+	APIKey string
+
+	// Dwell is how long devices show each image for. Zero means
+	// DefaultDwell.
+	Dwell time.Duration
+
+	// Render configures the renders of installed apps.
+	Render lib.RenderOptions
+
+	// ErrorFrames makes installations whose render fails show an error
+	// frame, with the app's ID, the error and the time, rather than their
+	// last image.
+	ErrorFrames bool
+}
+
+// Hub is a server that devices pull images from.
+type Hub struct {
+	store  *Store
+	apps   *Catalog
+	cals   *calendars
+	apiKey string
+	dwell  time.Duration
+	render lib.RenderOptions
+
+	errorFrames bool
+
+	mux *http.ServeMux
+
+	// cursors are the installation and screen each device showed last,
+	// due when the hub next pushes to devices it pushes to, and refreshes
+	// when installations are next rendered in the background. They're not
+	// saved, devices just start from the top after a restart.
+	mu        sync.Mutex
+	cursors   map[string]cursor
+	due       map[string]time.Time
+	refreshes map[string]time.Time
+
+	// telemetry is what each device last reported about its health. Like
+	// cursors, it's not saved, so reports don't rewrite the state file.
+	telemetry map[string]Telemetry
+
+	// prefetching are the installations being prefetched, see warmUp.
+	prefetching map[string]bool
+
+	// sinks are those built for each device from its sink configs.
+	sinks map[string][]*deviceSink
+}
+
+// New creates a hub.
+func New(opts Options) (*Hub, error) {
+	store, err := OpenStore(opts.StatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &Hub{
+		store:       store,
+		apps:        NewCatalog(opts.AppsDir),
+		cals:        newCalendars(),
+		apiKey:      opts.APIKey,
+		dwell:       opts.Dwell,
+		render:      opts.Render,
+		errorFrames: opts.ErrorFrames,
+		cursors:     map[string]cursor{},
+		due:         map[string]time.Time{},
+		refreshes:   map[string]time.Time{},
+		telemetry:   map[string]Telemetry{},
+		prefetching: map[string]bool{},
+		sinks:       map[string][]*deviceSink{},
+	}
+	if h.dwell <= 0 {
+		h.dwell = DefaultDwell
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v0/devices", h.authenticated(h.listDevicesHandler))
+	mux.HandleFunc("GET /v0/devices/{device}", h.authenticated(h.getDeviceHandler))
+	mux.HandleFunc("PATCH /v0/devices/{device}", h.authenticated(h.patchDeviceHandler))
+	mux.HandleFunc("POST /v0/devices/{device}/push", h.authenticated(h.pushHandler))
+	mux.HandleFunc("GET /v0/devices/{device}/installations", h.authenticated(h.listInstallationsHandler))
+	mux.HandleFunc("POST /v0/devices/{device}/installations", h.authenticated(h.installHandler))
+	mux.HandleFunc("PATCH /v0/devices/{device}/installations/{installation}", h.authenticated(h.patchInstallationHandler))
+	mux.HandleFunc("DELETE /v0/devices/{device}/installations/{installation}", h.authenticated(h.deleteInstallationHandler))
+	mux.HandleFunc("GET /v0/devices/{device}/installations/{installation}/config", h.authenticated(h.exportConfigHandler))
+	mux.HandleFunc("PUT /v0/devices/{device}/installations/{installation}/config", h.authenticated(h.importConfigHandler))
+	mux.HandleFunc("PUT /v0/devices/{device}/playlist", h.authenticated(h.playlistHandler))
+	mux.HandleFunc("GET /v0/apps", h.authenticated(h.listAppsHandler))
+	mux.HandleFunc("GET /v0/apps/{app}/schema", h.authenticated(h.schemaHandler))
+	mux.HandleFunc("POST /api/v1/render/batch", h.authenticated(h.batchRenderHandler))
+	mux.HandleFunc("GET /metrics", h.authenticated(h.metricsHandler))
+
+	// webhooks carry a token of their own rather than the API key, which
+	// whatever calls them doesn't need to hold. Without an API key there's
+	// nothing to derive it from, and anyone could call them.
+	if h.apiKey != "" {
+		mux.HandleFunc("GET /v0/apps/{app}/hook", h.authenticated(h.getHookHandler))
+		mux.HandleFunc("POST /api/v1/hooks/{app}", h.hookAuthenticated(h.hookHandler))
+	}
+
+	// devices poll and report without credentials, as Tronbyt firmware
+	// does
+	mux.HandleFunc("GET /v0/devices/{device}/next", h.nextHandler)
+	mux.HandleFunc("POST /v0/devices/{device}/telemetry", h.telemetryHandler)
+	mux.HandleFunc("GET /v0/devices/{device}/ws", h.websocketHandler)
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {})
+	h.mux = mux
+
+	return h, nil
+}
+
+// Store returns the hub's devices.
+func (h *Hub) Store() *Store {
+	return h.store
+}
+
+// ServeHTTP serves the hub's API.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Run serves the hub on addr, pushes to devices that don't poll, and renders
+// installations in the background, until it fails.
+func (h *Hub) Run(addr string) error {
+	g, ctx := errgroup.WithContext(context.Background())
+	g.Go(func() error { return h.schedule(ctx) })
+	g.Go(func() error { return h.renderInBackground(ctx) })
+	g.Go(func() error {
+		slog.Info("hub listening", "addr", addr)
+		return http.ListenAndServe(addr, h)
+	})
+	return g.Wait()
+}
+
+// authenticated wraps handlers that need the hub's API key, if it has one.
+func (h *Hub) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.apiKey != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.apiKey)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "invalid API key", http.StatusUnauthorized)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// renderInstallation renders the app of an installation on a device with
+// its config, as an image for each of its screens. The first image's data
+// is empty if the app has nothing to show. inst's State is updated with
+// what the app saved.
+func (h *Hub) renderInstallation(ctx context.Context, deviceID string, inst *Installation) ([]*lib.EncodedImage, error) {
+	app, err := h.apps.Applet(inst.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := h.renderOptions(deviceID, inst)
+	imgs, err := app.RenderScreens(ctx, inst.Config, opts)
+	if err != nil {
+		return nil, fmt.Errorf("rendering %s: %w", inst.AppID, err)
+	}
+	if opts.State.Changed() {
+		inst.State = opts.State.Values()
+	}
+	return imgs, nil
+}
+
+// renderOptions returns the options an installation on a device is rendered
+// with, with its state and what's known about the device. Devices with a
+// profile are rendered at its size, in its format and color depth.
+func (h *Hub) renderOptions(deviceID string, inst *Installation) lib.RenderOptions {
+	opts := h.render
+	opts.State = lib.NewState(inst.State)
+	opts.Request = &lib.RequestInfo{DeviceID: deviceID}
+	if d, err := h.store.Device(deviceID); err == nil {
+		opts.Request.Locale = d.Locale
+		opts.Request.Values = d.Metadata
+		if d.Profile != "" {
+			if p, err := device.LookupProfile(d.Profile); err == nil {
+				applyProfile(&opts, p)
+			}
+		}
+	}
+	opts.Request.Width, opts.Request.Height = opts.Width, opts.Height
+	return opts
+}
+
+// applyProfile sets opts to render for a display with profile p: at its
+// size, in the first of its formats that apps can be rendered in, and
+// reduced to its color depth.
+func applyProfile(opts *lib.RenderOptions, p device.Profile) {
+	opts.Width, opts.Height = p.Width, p.Height
+	for _, f := range p.Formats {
+		if f := lib.Format(f); f == lib.FormatWebP || f == lib.FormatGIF {
+			opts.Format = f
+			break
+		}
+	}
+	opts.Filters = append(slices.Clip(opts.Filters), p.Filters()...)
+}
+
+// setRender sets an installation's images to a render made at now, along
+// with what the app suggested for how long to show them and when to render
+// it again.
+func (inst *Installation) setRender(imgs []*lib.EncodedImage, now time.Time) {
+	inst.Image = imgs[0].Data
+	inst.UpdatedAt = now
+	inst.AppDwellSecs = int(imgs[0].ShowFor / time.Second)
+	inst.RefreshAt = time.Time{}
+	inst.Screens = nil
+	for _, img := range imgs {
+		if img.RefreshIn > 0 && (inst.RefreshAt.IsZero() || now.Add(img.RefreshIn).Before(inst.RefreshAt)) {
+			inst.RefreshAt = now.Add(img.RefreshIn)
+		}
+		if len(imgs) > 1 {
+			inst.Screens = append(inst.Screens, Screen{Image: img.Data, AppDwellSecs: int(img.ShowFor / time.Second)})
+		}
+	}
+}
@@ -0,0 +1,1057 @@
+package hub
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/device"
+	"tidbyt.dev/pixlet/lib"
+)
+
+const greetingSource = `
+load("render.star", "render")
+load("schema.star", "schema")
+
+def main(config):
+    return render.Root(child = render.Text(config.get("who", "world")))
+
+def get_schema():
+    return schema.Schema(
+        version = "1",
+        fields = [
+            schema.Text(id = "who", name = "Who", desc = "Who to greet", icon = "user", default = "world"),
+        ],
+    )
+`
+
+var (
+	webp = []byte("RIFF\x00\x00\x00\x00WEBPVP8L")
+	gif  = []byte("GIF89a")
+)
+
+func newTestHub(t *testing.T, opts Options) *Hub {
+	if opts.AppsDir == "" {
+		opts.AppsDir = t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(opts.AppsDir, "greeting.star"), []byte(greetingSource), 0644))
+	}
+	h, err := New(opts)
+	require.NoError(t, err)
+	return h
+}
+
+func do(t *testing.T, h http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+		r = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func push(t *testing.T, h http.Handler, device string, image []byte, installation string, background bool) {
+	w := do(t, h, "POST", "/v0/devices/"+device+"/push", map[string]any{
+		"deviceID":       device,
+		"image":          base64.StdEncoding.EncodeToString(image),
+		"installationID": installation,
+		"background":     background,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func next(t *testing.T, h http.Handler, device string) []byte {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v0/devices/"+device+"/next", nil))
+	if w.Code == http.StatusNoContent {
+		return nil
+	}
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	return w.Body.Bytes()
+}
+
+func TestAuthentication(t *testing.T) {
+	h := newTestHub(t, Options{APIKey: "secret"})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v0/devices", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = do(t, h, "GET", "/v0/devices", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"devices": []}`, w.Body.String())
+}
+
+func TestPush(t *testing.T) {
+	h := newTestHub(t, Options{APIKey: "secret"})
+
+	// pushing creates the device
+	push(t, h, "kitchen", webp, "", false)
+	w := do(t, h, "GET", "/v0/devices/kitchen", nil)
+	assert.JSONEq(t, `{"id": "kitchen", "displayName": "kitchen", "brightness": 100, "autoDim": false}`, w.Body.String())
+
+	// images pushed without an installation are only shown once
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+	assert.Nil(t, next(t, h, "kitchen"))
+
+	// installations take turns, and background pushes aren't shown right away
+	push(t, h, "kitchen", webp, "weather", true)
+	push(t, h, "kitchen", gif, "clock", true)
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+	assert.Equal(t, gif, next(t, h, "kitchen"))
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+
+	w = do(t, h, "GET", "/v0/devices/kitchen/installations", nil)
+	assert.JSONEq(t, `{"installations": [{"id": "weather", "appID": "weather", "enabled": true}, {"id": "clock", "appID": "clock", "enabled": true}]}`, w.Body.String())
+
+	// pinned installations are shown instead of the rotation
+	w = do(t, h, "PATCH", "/v0/devices/kitchen", map[string]any{"pinnedApp": "clock", "brightness": 40})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, gif, next(t, h, "kitchen"))
+	assert.Equal(t, gif, next(t, h, "kitchen"))
+
+	w = do(t, h, "DELETE", "/v0/devices/kitchen/installations/clock", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+
+	w = do(t, h, "GET", "/v0/devices/kitchen", nil)
+	assert.JSONEq(t, `{"id": "kitchen", "displayName": "kitchen", "brightness": 40, "autoDim": false}`, w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, do(t, h, "GET", "/v0/devices/hallway", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do(t, h, "GET", "/v0/devices/hallway/next", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "PATCH", "/v0/devices/kitchen", map[string]any{"brightness": 101}).Code)
+}
+
+func TestNextHeaders(t *testing.T) {
+	h := newTestHub(t, Options{})
+	push(t, h, "kitchen", gif, "", false)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v0/devices/kitchen/next", nil))
+	assert.Equal(t, "image/gif", w.Header().Get("Content-Type"))
+	assert.Equal(t, "100", w.Header().Get("Tronbyt-Brightness"))
+	assert.Equal(t, "15", w.Header().Get("Tronbyt-Dwell-Secs"))
+}
+
+func TestInstall(t *testing.T) {
+	h := newTestHub(t, Options{APIKey: "secret"})
+
+	w := do(t, h, "GET", "/v0/apps", nil)
+	assert.JSONEq(t, `{"apps": [{"id": "greeting"}]}`, w.Body.String())
+
+	w = do(t, h, "GET", "/v0/apps/greeting/schema", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"who"`)
+	assert.Equal(t, http.StatusNotFound, do(t, h, "GET", "/v0/apps/nope/schema", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do(t, h, "GET", "/v0/apps/..%2Fgreeting/schema", nil).Code)
+
+	w = do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{
+		"appID":  "greeting",
+		"config": map[string]string{"who": "Grace"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"id": "greeting", "appID": "greeting", "enabled": true}`, w.Body.String())
+
+	img := next(t, h, "kitchen")
+	assert.Equal(t, "WEBP", string(img[8:12]))
+
+	d, err := h.Store().Device("kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"who": "Grace"}, d.Installations[0].Config)
+}
+
+func TestInstallationVersion(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "greeting")
+	require.NoError(t, os.Mkdir(app, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(app, "greeting.star"), []byte(greetingSource), 0644))
+	writeManifest := func(version string) {
+		require.NoError(t, os.WriteFile(filepath.Join(app, "manifest.yaml"), []byte("id: greeting\nversion: "+version+"\n"), 0644))
+	}
+	writeManifest("1.0.0")
+	h := newTestHub(t, Options{AppsDir: dir, APIKey: "secret"})
+
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "greeting"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"id": "greeting", "appID": "greeting", "enabled": true, "version": "1.0.0"}`, w.Body.String())
+
+	// once the catalog has a newer version, installations report it
+	writeManifest("1.1.0")
+	h.apps = NewCatalog(dir)
+	w = do(t, h, "GET", "/v0/devices/kitchen/installations", nil)
+	assert.JSONEq(t, `{"installations": [{"id": "greeting", "appID": "greeting", "enabled": true, "version": "1.0.0", "latestVersion": "1.1.0"}]}`, w.Body.String())
+
+	// until they're installed again
+	w = do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "greeting"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"id": "greeting", "appID": "greeting", "enabled": true, "version": "1.1.0"}`, w.Body.String())
+}
+
+func TestInstallRequirements(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vault.star"), []byte(`
+load("render.star", "render")
+
+REQUIRES = ["network", "secrets"]
+
+def main():
+    return render.Root(child = render.Box())
+`), 0644))
+	big := filepath.Join(dir, "big")
+	require.NoError(t, os.Mkdir(big, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(big, "big.star"), []byte(greetingSource), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(big, "manifest.yaml"), []byte("id: big\ncapabilities:\n  min_display_size:\n    width: 128\n    height: 64\n"), 0644))
+	h := newTestHub(t, Options{AppsDir: dir, APIKey: "secret", Render: lib.RenderOptions{Width: 64, Height: 32}})
+
+	// the hub has no key to decrypt secrets with
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "vault"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "vault.star requires secrets, which this host doesn't offer")
+
+	// and its displays are too small for apps that need a bigger one
+	w = do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "big"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "big requires 128x64, which this host doesn't offer")
+}
+
+func TestConfigExportImport(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "greeting.star"), []byte(`
+load("render.star", "render")
+load("schema.star", "schema")
+
+def main(config):
+    return render.Root(child = render.Text(config.get("who", "world")))
+
+def migrate(config, from_version):
+    if from_version < 2:
+        config["who"] = config.pop("name", "world")
+    return config
+
+def get_schema():
+    return schema.Schema(
+        version = "1",
+        config_version = 2,
+        migrate = migrate,
+        fields = [
+            schema.Text(id = "who", name = "Who", desc = "Who to greet", icon = "user", pattern = "[A-Z].*"),
+        ],
+    )
+`), 0644))
+	h := newTestHub(t, Options{AppsDir: dir, APIKey: "secret"})
+
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{
+		"appID":  "greeting",
+		"config": map[string]string{"who": "Grace", "$config_version": "2"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = do(t, h, "GET", "/v0/devices/kitchen/installations/greeting/config", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"format": 1, "app_id": "greeting", "config_version": 2, "config": {"who": "Grace"}}`, w.Body.String())
+
+	// config from an older version of the app is migrated
+	w = do(t, h, "PUT", "/v0/devices/kitchen/installations/greeting/config", map[string]any{
+		"format":         1,
+		"app_id":         "greeting",
+		"config_version": 1,
+		"config":         map[string]string{"name": "Ada"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	d, err := h.Store().Device("kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"who": "Ada", "$config_version": "2"}, d.Installations[0].Config)
+
+	// but config for another app, or that isn't valid, isn't imported
+	w = do(t, h, "PUT", "/v0/devices/kitchen/installations/greeting/config", map[string]any{
+		"format": 1,
+		"app_id": "clock",
+		"config": map[string]string{},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(t, h, "PUT", "/v0/devices/kitchen/installations/greeting/config", map[string]any{
+		"format":         1,
+		"config_version": 2,
+		"config":         map[string]string{"who": "lowercase"},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	d, err = h.Store().Device("kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", d.Installations[0].Config["who"])
+
+	assert.Equal(t, http.StatusNotFound, do(t, h, "GET", "/v0/devices/kitchen/installations/clock/config", nil).Code)
+}
+
+func TestStatePersists(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "hub.json")
+
+	h := newTestHub(t, Options{StatePath: state})
+	push(t, h, "kitchen", webp, "weather", true)
+
+	h = newTestHub(t, Options{StatePath: state})
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+}
+
+func TestPlaylist(t *testing.T) {
+	h := newTestHub(t, Options{APIKey: "secret"})
+
+	push(t, h, "kitchen", webp, "weather", true)
+	push(t, h, "kitchen", gif, "clock", true)
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{
+		"appID":     "greeting",
+		"dwellSecs": 5,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = do(t, h, "PUT", "/v0/devices/kitchen/playlist", map[string]any{"installations": []string{"greeting", "clock"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"installations": [
+		{"id": "greeting", "appID": "greeting", "enabled": true, "dwellSecs": 5},
+		{"id": "clock", "appID": "clock", "enabled": true},
+		{"id": "weather", "appID": "weather", "enabled": true}
+	]}`, w.Body.String())
+
+	// installations the hub renders are rendered as they come up, and
+	// shown for their own dwell time
+	image, dwell, _, err := h.next(context.Background(), "kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, "WEBP", string(image[8:12]))
+	assert.Equal(t, 5*time.Second, dwell)
+
+	image, dwell, _, err = h.next(context.Background(), "kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, gif, image)
+	assert.Equal(t, DefaultDwell, dwell)
+
+	// disabled installations are skipped
+	w = do(t, h, "PATCH", "/v0/devices/kitchen/installations/greeting", map[string]any{"enabled": false})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+	assert.Equal(t, gif, next(t, h, "kitchen"))
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+
+	assert.Equal(t, http.StatusNotFound, do(t, h, "PUT", "/v0/devices/kitchen/playlist", map[string]any{"installations": []string{"nope"}}).Code)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "PATCH", "/v0/devices/kitchen/installations/clock", map[string]any{"config": map[string]string{}}).Code)
+}
+
+func TestSkipWhenEmpty(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.star"), []byte(`
+load("render.star", "render")
+
+def main(config):
+    return []
+`), 0644))
+	h := newTestHub(t, Options{AppsDir: dir})
+
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "empty"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Nil(t, next(t, h, "kitchen"))
+
+	push(t, h, "kitchen", webp, "weather", true)
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+}
+
+func TestSchedulePushes(t *testing.T) {
+	pushed := make(chan string, 10)
+	device := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer device-key", r.Header.Get("Authorization"))
+		var req pushRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		pushed <- r.URL.Path + " " + req.Image
+	}))
+	defer device.Close()
+
+	h := newTestHub(t, Options{APIKey: "secret"})
+	push(t, h, "kitchen", webp, "weather", true)
+	w := do(t, h, "PATCH", "/v0/devices/kitchen", map[string]any{
+		"push": map[string]string{"url": device.URL, "deviceID": "abc123", "apiKey": "device-key"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.schedule(ctx)
+
+	select {
+	case got := <-pushed:
+		assert.Equal(t, "/v0/devices/abc123/push "+base64.StdEncoding.EncodeToString(webp), got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing was pushed")
+	}
+}
+
+func TestSinks(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer hook.Close()
+
+	h := newTestHub(t, Options{APIKey: "secret"})
+	push(t, h, "kitchen", webp, "weather", true)
+
+	// sinks are checked before they're saved
+	w := do(t, h, "PATCH", "/v0/devices/kitchen", map[string]any{
+		"sinks": []map[string]any{{"type": "carrier-pigeon"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	path := filepath.Join(t.TempDir(), "kitchen.webp")
+	w = do(t, h, "PATCH", "/v0/devices/kitchen", map[string]any{
+		"sinks": []map[string]any{
+			{"type": "file", "path": path},
+			{"type": "webhook", "url": hook.URL},
+			{"type": "websocket"},
+		},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	h.pushDue(context.Background(), time.Now())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, webp, data)
+
+	// each sink's deliveries are reported, whether they failed or not
+	var d deviceJSON
+	require.NoError(t, json.NewDecoder(do(t, h, "GET", "/v0/devices/kitchen", nil).Body).Decode(&d))
+	require.Len(t, d.Sinks, 3)
+	assert.Equal(t, "file", d.Sinks[0].Type)
+	assert.Equal(t, 1, d.Sinks[0].Delivered)
+	assert.Equal(t, 1, d.Sinks[1].Failures)
+	assert.Contains(t, d.Sinks[1].LastError, "down for maintenance")
+	assert.Equal(t, 1, d.Sinks[2].Delivered)
+
+	metrics := do(t, h, "GET", "/metrics", nil).Body.String()
+	assert.Contains(t, metrics, `pixlet_sink_deliveries_total{device="kitchen",sink="0",type="file"} 1`)
+	assert.Contains(t, metrics, `pixlet_sink_errors_total{device="kitchen",sink="1",type="webhook"} 1`)
+
+	// devices without a websocket sink can't be streamed
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v0/devices/hallway/ws", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// and sinks that are removed aren't delivered to
+	w = do(t, h, "PATCH", "/v0/devices/kitchen", map[string]any{"sinks": []any{}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, os.Remove(path))
+	h.wake("kitchen")
+	h.pushDue(context.Background(), time.Now())
+	assert.NoFileExists(t, path)
+}
+
+func TestRenderInBackground(t *testing.T) {
+	h := newTestHub(t, Options{APIKey: "secret"})
+
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{
+		"appID":   "greeting",
+		"refresh": "1h",
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "PATCH", "/v0/devices/kitchen/installations/greeting", map[string]any{"refresh": "often"}).Code)
+
+	updatedAt := func() time.Time {
+		d, err := h.Store().Device("kitchen")
+		require.NoError(t, err)
+		return d.Installations[0].UpdatedAt
+	}
+	installed := updatedAt()
+
+	// the image rendered in the background is shown, rather than the app
+	// being rendered when it comes up
+	world := next(t, h, "kitchen")
+	require.NotNil(t, world)
+	assert.Equal(t, installed, updatedAt())
+
+	// and it's not rendered again until it's due
+	h.renderDue(context.Background(), time.Now())
+	assert.Equal(t, installed, updatedAt())
+
+	// unless its config changes
+	w = do(t, h, "PATCH", "/v0/devices/kitchen/installations/greeting", map[string]any{"config": map[string]string{"who": "Ada"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	h.renderDue(context.Background(), time.Now())
+	assert.True(t, updatedAt().After(installed))
+	assert.NotEqual(t, world, next(t, h, "kitchen"))
+
+	refreshed := updatedAt()
+	h.renderDue(context.Background(), time.Now().Add(time.Hour+time.Minute))
+	assert.True(t, updatedAt().After(refreshed))
+}
+
+func TestPrefetch(t *testing.T) {
+	var fetches atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte("Lovelace"))
+	}))
+	defer api.Close()
+
+	h := newTestHub(t, Options{APIKey: "secret"})
+	require.NoError(t, os.WriteFile(filepath.Join(h.apps.dir, "fetcher.star"), []byte(`
+load("http.star", "http")
+load("render.star", "render")
+
+def prefetch(config):
+    http.get(config["url"])
+
+def main(config):
+    return render.Root(child = render.Text("fetched"))
+`), 0644))
+
+	for _, app := range []map[string]any{
+		{"appID": "greeting"},
+		{"appID": "fetcher", "config": map[string]string{"url": api.URL}},
+	} {
+		w := do(t, h, "POST", "/v0/devices/kitchen/installations", app)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	assert.Equal(t, int32(0), fetches.Load())
+
+	// while the greeting is shown, the app after it fetches its data
+	require.NotNil(t, next(t, h, "kitchen"))
+	assert.Eventually(t, func() bool { return fetches.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// the greeting doesn't prefetch anything
+	require.NotNil(t, next(t, h, "kitchen"))
+	require.NotNil(t, next(t, h, "kitchen"))
+	assert.Eventually(t, func() bool { return fetches.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestAppHints(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hinted.star"), []byte(`
+load("render.star", "render")
+
+def main(config):
+    return render.Root(show_for = 10, refresh_in = 120, child = render.Text("hi"))
+`), 0644))
+	h := newTestHub(t, Options{AppsDir: dir, APIKey: "secret"})
+
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "hinted"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	d, err := h.Store().Device("kitchen")
+	require.NoError(t, err)
+	installed := d.Installations[0].UpdatedAt
+	assert.Equal(t, 10, d.Installations[0].AppDwellSecs)
+	assert.Equal(t, installed.Add(2*time.Minute), d.Installations[0].RefreshAt)
+
+	// the app's dwell is used, and it isn't rendered again until it asked
+	// to be
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v0/devices/kitchen/next", nil))
+	assert.Equal(t, "10", w.Header().Get("Tronbyt-Dwell-Secs"))
+	d, err = h.Store().Device("kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, installed, d.Installations[0].UpdatedAt)
+
+	// a dwell set by the user wins
+	w = do(t, h, "PATCH", "/v0/devices/kitchen/installations/hinted", map[string]any{"dwellSecs": 30})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v0/devices/kitchen/next", nil))
+	assert.Equal(t, "30", w.Header().Get("Tronbyt-Dwell-Secs"))
+
+	// installations rendered in the background are rendered again sooner
+	// than their schedule if the app asks
+	w = do(t, h, "PATCH", "/v0/devices/kitchen/installations/hinted", map[string]any{"refresh": "1h"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	now := time.Now()
+	h.renderDue(context.Background(), now)
+	h.mu.Lock()
+	due := h.refreshes[refreshKey("kitchen", "hinted")]
+	h.mu.Unlock()
+	assert.WithinDuration(t, now.Add(2*time.Minute), due, 5*time.Second)
+}
+
+func TestAppState(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "counter.star"), []byte(`
+load("render.star", "render")
+load("state.star", "state")
+
+def main(config):
+    renders = int(state.get("renders", "0")) + 1
+    state.set("renders", str(renders))
+    return render.Root(child = render.Text(str(renders)))
+`), 0644))
+	h := newTestHub(t, Options{AppsDir: dir, APIKey: "secret"})
+
+	renders := func() string {
+		d, err := h.Store().Device("kitchen")
+		require.NoError(t, err)
+		return d.Installations[0].State["renders"]
+	}
+
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "counter"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "1", renders())
+
+	// the state is kept across renders, and when the app is installed again
+	next(t, h, "kitchen")
+	assert.Equal(t, "2", renders())
+	w = do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "counter"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "3", renders())
+
+	// but each installation has its own
+	w = do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "counter", "installationID": "other"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	d, err := h.Store().Device("kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, "1", d.Installations[1].State["renders"])
+}
+
+func TestErrorFrames(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "flaky.star"), []byte(`
+load("render.star", "render")
+load("state.star", "state")
+
+def main(config):
+    if state.get("rendered"):
+        fail("out of coffee")
+    state.set("rendered", "yes")
+    return render.Root(child = render.Text("hi"))
+`), 0644))
+
+	for _, errorFrames := range []bool{false, true} {
+		h := newTestHub(t, Options{AppsDir: dir, APIKey: "secret", ErrorFrames: errorFrames})
+		w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "flaky"})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		d, err := h.Store().Device("kitchen")
+		require.NoError(t, err)
+		rendered := d.Installations[0].Image
+
+		// renders after the first fail, and show the last image or an
+		// error frame in its place
+		image := next(t, h, "kitchen")
+		if !errorFrames {
+			assert.Equal(t, rendered, image)
+			continue
+		}
+		assert.NotEqual(t, rendered, image)
+		assert.Equal(t, "webp", device.ImageFormat(image))
+
+		d, err = h.Store().Device("kitchen")
+		require.NoError(t, err)
+		assert.Equal(t, image, d.Installations[0].Image)
+		assert.True(t, d.Installations[0].RefreshAt.IsZero())
+	}
+}
+
+func TestRequestInfo(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "where.star"), []byte(`
+load("render.star", "render")
+load("request.star", "request")
+load("state.star", "state")
+
+def main(config):
+    state.set("seen", "%s %s %s" % (request.device_id(), request.locale(), request.get("room")))
+    return render.Root(child = render.Text("hi"))
+`), 0644))
+	h := newTestHub(t, Options{AppsDir: dir, APIKey: "secret"})
+
+	seen := func() string {
+		d, err := h.Store().Device("kitchen")
+		require.NoError(t, err)
+		return d.Installations[0].State["seen"]
+	}
+
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "where"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "kitchen None None", seen())
+
+	// apps are told the device's locale and metadata
+	w = do(t, h, "PATCH", "/v0/devices/kitchen", map[string]any{
+		"locale":   "nb-NO",
+		"metadata": map[string]string{"room": "upstairs"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	next(t, h, "kitchen")
+	assert.Equal(t, "kitchen nb-NO upstairs", seen())
+
+	w = do(t, h, "GET", "/v0/devices/kitchen", nil)
+	assert.Contains(t, w.Body.String(), `"locale":"nb-NO"`)
+}
+
+func TestDeviceProfile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "size.star"), []byte(`
+load("render.star", "render")
+load("request.star", "request")
+load("state.star", "state")
+
+def main(config):
+    state.set("size", "%sx%s" % (request.get("width"), request.get("height")))
+    return render.Root(child = render.Text("hi"))
+`), 0644))
+	h := newTestHub(t, Options{AppsDir: dir, APIKey: "secret"})
+
+	size := func() string {
+		d, err := h.Store().Device("kitchen")
+		require.NoError(t, err)
+		return d.Installations[0].State["size"]
+	}
+
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "size"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "64x32", size())
+
+	// devices with a profile are rendered for it
+	w = do(t, h, "PATCH", "/v0/devices/kitchen", map[string]any{"profile": "tronbyt-s3-wide"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"profile":"tronbyt-s3-wide"`)
+	next(t, h, "kitchen")
+	assert.Equal(t, "128x64", size())
+
+	opts := h.renderOptions("kitchen", &Installation{})
+	assert.Equal(t, lib.FormatWebP, opts.Format)
+	assert.Len(t, opts.Filters, len(h.render.Filters))
+
+	w = do(t, h, "PATCH", "/v0/devices/kitchen", map[string]any{"profile": "matrixportal-s3"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	opts = h.renderOptions("kitchen", &Installation{})
+	assert.Equal(t, 64, opts.Width)
+	assert.Len(t, opts.Filters, len(h.render.Filters)+1)
+
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "PATCH", "/v0/devices/kitchen", map[string]any{"profile": "toaster"}).Code)
+}
+
+func TestScreens(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pages.star"), []byte(`
+load("render.star", "render")
+
+def main(config):
+    return [
+        render.Root(child = render.Text("one")),
+        render.Root(child = render.Text("two"), separate = True, show_for = 5),
+    ]
+`), 0644))
+	h := newTestHub(t, Options{AppsDir: dir, APIKey: "secret"})
+
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "pages"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	push(t, h, "kitchen", gif, "photo", true)
+
+	d, err := h.Store().Device("kitchen")
+	require.NoError(t, err)
+	screens := d.Installations[0].Screens
+	require.Len(t, screens, 2)
+
+	// each screen takes its own turn before the playlist moves on
+	ctx := context.Background()
+	image, dwell, _, err := h.next(ctx, "kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, screens[0].Image, image)
+	assert.Equal(t, DefaultDwell, dwell)
+
+	image, dwell, _, err = h.next(ctx, "kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, screens[1].Image, image)
+	assert.Equal(t, 5*time.Second, dwell)
+
+	assert.Equal(t, gif, next(t, h, "kitchen"))
+
+	image, _, _, err = h.next(ctx, "kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, screens[0].Image, image)
+}
+
+// hook posts body to the webhook of app with its token.
+func hook(t *testing.T, h *Hub, app, query string, body any) *httptest.ResponseRecorder {
+	w := do(t, h, "GET", "/v0/apps/"+app+"/hook", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct{ Path, Token string }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "/api/v1/hooks/"+app, resp.Path)
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+		r = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest("POST", resp.Path+query, r)
+	req.Header.Set("Authorization", "Bearer "+resp.Token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestHook(t *testing.T) {
+	h := newTestHub(t, Options{APIKey: "secret"})
+
+	for _, device := range []string{"kitchen", "hallway"} {
+		w := do(t, h, "POST", "/v0/devices/"+device+"/installations", map[string]any{"appID": "greeting"})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		push(t, h, device, gif, "clock", true)
+	}
+	world := next(t, h, "kitchen")
+	assert.Equal(t, gif, next(t, h, "kitchen"))
+
+	w := hook(t, h, "greeting", "?device=kitchen", map[string]any{"who": "Ada"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"rendered": [{"device": "kitchen", "installation": "greeting"}]}`, w.Body.String())
+
+	// the new render is shown right away, and its config kept
+	ada := next(t, h, "kitchen")
+	assert.NotEqual(t, world, ada)
+	d, err := h.Store().Device("kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"who": "Ada"}, d.Installations[0].Config)
+
+	d, err = h.Store().Device("hallway")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"who": "world"}, d.Installations[0].Config)
+
+	// posting nothing renders again with the same config, on every device
+	w = hook(t, h, "greeting", "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"rendered": [{"device": "hallway", "installation": "greeting"}, {"device": "kitchen", "installation": "greeting"}]}`, w.Body.String())
+	assert.Equal(t, ada, next(t, h, "kitchen"))
+	assert.Equal(t, world, next(t, h, "hallway"))
+
+	assert.Equal(t, http.StatusNotFound, hook(t, h, "clock", "", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, hook(t, h, "greeting", "", "Ada").Code)
+}
+
+func TestHookKeepsConfigThatFails(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "greeting.star"), []byte(`
+load("render.star", "render")
+load("schema.star", "schema")
+
+def main(config):
+    if config.get("who") == "nobody":
+        fail("no one to greet")
+    return render.Root(child = render.Text(config.get("who", "world")))
+
+def get_schema():
+    return schema.Schema(
+        version = "1",
+        fields = [
+            schema.Text(id = "who", name = "Who", desc = "Who to greet", icon = "user", pattern = "[a-z]+"),
+        ],
+    )
+`), 0644))
+	h := newTestHub(t, Options{AppsDir: dir, APIKey: "secret"})
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "greeting", "config": map[string]string{"who": "ada"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// neither invalid config nor config the app fails with is saved
+	for _, who := range []string{"Ada!", "nobody"} {
+		w = hook(t, h, "greeting", "", map[string]any{"who": who})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"error"`)
+
+		d, err := h.Store().Device("kitchen")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"who": "ada"}, d.Installations[0].Config)
+	}
+
+	w = hook(t, h, "greeting", "", map[string]any{"who": strings.Repeat("a", maxHookBodySize)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHookAuthentication(t *testing.T) {
+	h := newTestHub(t, Options{APIKey: "secret"})
+	w := do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "greeting"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	post := func(path, token string) int {
+		req := httptest.NewRequest("POST", path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// each app's hook has its own token, and the API key isn't one
+	token := h.HookToken("greeting")
+	assert.NotEmpty(t, token)
+	assert.NotEqual(t, token, h.HookToken("clock"))
+	assert.Equal(t, http.StatusUnauthorized, post("/api/v1/hooks/greeting", ""))
+	assert.Equal(t, http.StatusUnauthorized, post("/api/v1/hooks/greeting", "secret"))
+	assert.Equal(t, http.StatusUnauthorized, post("/api/v1/hooks/greeting", h.HookToken("clock")))
+	assert.Equal(t, http.StatusOK, post("/api/v1/hooks/greeting", token))
+	assert.Equal(t, http.StatusOK, post("/api/v1/hooks/greeting?token="+token, ""))
+
+	// tokens change with the API key
+	other := newTestHub(t, Options{APIKey: "lovelace"})
+	assert.NotEqual(t, token, other.HookToken("greeting"))
+
+	// only the API key gets a hook's token
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v0/apps/greeting/hook", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// a hub without an API key has no hooks, rather than open ones
+	h = newTestHub(t, Options{})
+	w = do(t, h, "POST", "/v0/devices/kitchen/installations", map[string]any{"appID": "greeting"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, h.HookToken("greeting"))
+	assert.Equal(t, http.StatusNotFound, post("/api/v1/hooks/greeting", ""))
+	assert.Equal(t, http.StatusNotFound, do(t, h, "GET", "/v0/apps/greeting/hook", nil).Code)
+}
+
+func TestDecodeHookConfig(t *testing.T) {
+	config, err := decodeHookConfig(bytes.NewReader([]byte(`{"status": "failed", "count": 3, "ok": false, "gone": null, "tags": ["a"]}`)))
+	require.NoError(t, err)
+
+	values := map[string]any{}
+	for k, v := range config {
+		if v == nil {
+			values[k] = nil
+		} else {
+			values[k] = *v
+		}
+	}
+	assert.Equal(t, map[string]any{"status": "failed", "count": "3", "ok": "false", "gone": nil, "tags": `["a"]`}, values)
+}
+
+func TestBatchRender(t *testing.T) {
+	h := newTestHub(t, Options{APIKey: "secret"})
+
+	batch := map[string]any{
+		"renders": []map[string]any{
+			{"app": "greeting", "config": map[string]string{"who": "Ada"}},
+			{"app": "clock"},
+			{"app": "greeting", "config": map[string]string{"who": "Grace"}},
+		},
+	}
+	w := do(t, h, "POST", "/api/v1/render/batch", batch)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Results []batchResult `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 3)
+
+	// results are in the order asked for, and failures don't fail the batch
+	assert.Equal(t, "greeting", resp.Results[0].App)
+	assert.Equal(t, "webp", resp.Results[0].Format)
+	assert.Equal(t, "webp", device.ImageFormat(resp.Results[0].Image))
+	assert.Contains(t, resp.Results[1].Error, "not found")
+	assert.Empty(t, resp.Results[1].Image)
+	assert.NotEqual(t, resp.Results[0].Image, resp.Results[2].Image)
+
+	// the same batch as a zip archive
+	b, err := json.Marshal(batch)
+	require.NoError(t, err)
+	req := httptest.NewRequest("POST", "/api/v1/render/batch", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Accept", "application/zip")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"000-greeting.webp", "002-greeting.webp", "results.json"}, names)
+
+	f, err := zr.Open("000-greeting.webp")
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, resp.Results[0].Image, data)
+
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/api/v1/render/batch", map[string]any{}).Code)
+}
+
+func TestScheduledInstallations(t *testing.T) {
+	h := newTestHub(t, Options{APIKey: "secret"})
+
+	push(t, h, "kitchen", webp, "weather", true)
+	push(t, h, "kitchen", gif, "clock", true)
+
+	// a schedule that's never active today
+	tomorrow := strings.ToLower(time.Now().AddDate(0, 0, 1).Weekday().String()[:3])
+	w := do(t, h, "PATCH", "/v0/devices/kitchen/installations/clock", map[string]any{
+		"schedule": map[string]any{"days": []string{tomorrow}},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"id": "clock", "appID": "clock", "enabled": true, "schedule": {"days": ["`+tomorrow+`"]}}`, w.Body.String())
+
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+	assert.Equal(t, webp, next(t, h, "kitchen"))
+
+	// an empty schedule removes it
+	w = do(t, h, "PATCH", "/v0/devices/kitchen/installations/clock", map[string]any{"schedule": map[string]any{}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, gif, next(t, h, "kitchen"))
+
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "PATCH", "/v0/devices/kitchen/installations/clock", map[string]any{
+		"schedule": map[string]any{"start": "noon"},
+	}).Code)
+}
+
+func TestTelemetry(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "hub.json")
+	h := newTestHub(t, Options{APIKey: "secret", StatePath: statePath})
+	push(t, h, "kitchen", webp, "weather", true)
+	push(t, h, "hallway", webp, "weather", true)
+	state, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+
+	report := func(device, body string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/v0/devices/"+device+"/telemetry", strings.NewReader(body)))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusNoContent, report("kitchen", `{"firmwareVersion": "v1.4.2", "uptimeSecs": 3600, "rssi": -61, "temperature": 41.5}`))
+	assert.Equal(t, http.StatusNotFound, report("garage", `{"rssi": -61}`))
+	assert.Equal(t, http.StatusBadRequest, report("kitchen", `{"rssi": "strong"}`))
+
+	// reports are kept in memory, rather than rewriting the state file
+	after, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, string(state), string(after))
+
+	w := do(t, h, "GET", "/v0/devices/kitchen", nil)
+	var d deviceJSON
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+	require.NotNil(t, d.Telemetry)
+	assert.Equal(t, "v1.4.2", d.Telemetry.FirmwareVersion)
+	assert.Equal(t, -61, *d.Telemetry.RSSI)
+	assert.WithinDuration(t, time.Now(), d.Telemetry.ReportedAt, time.Minute)
+
+	w = do(t, h, "GET", "/metrics", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	metrics := w.Body.String()
+	assert.Contains(t, metrics, `pixlet_device_info{device="hallway",firmware=""} 1`)
+	assert.Contains(t, metrics, `pixlet_device_info{device="kitchen",firmware="v1.4.2"} 1`)
+	assert.Contains(t, metrics, `pixlet_device_uptime_seconds{device="kitchen"} 3600`)
+	assert.Contains(t, metrics, `pixlet_device_wifi_rssi_dbm{device="kitchen"} -61`)
+	assert.Contains(t, metrics, `pixlet_device_temperature_celsius{device="kitchen"} 41.5`)
+	assert.NotContains(t, metrics, `pixlet_device_uptime_seconds{device="hallway"}`)
+	assert.Contains(t, metrics, "# TYPE pixlet_app_renders_total counter")
+}
@@ -0,0 +1,61 @@
+package hub
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// warmUp prefetches the installation that comes up after the one at index
+// shown of a device's playlist, while the device shows it for dwell, so that
+// its app is loaded and the HTTP responses it needs are cached by the time
+// it's rendered. Only installations that will be rendered when they come up
+// are warmed up, and only apps that define a prefetch function fetch
+// anything. It doesn't wait for the prefetch, which is given until the end
+// of dwell.
+func (h *Hub) warmUp(ctx context.Context, deviceID string, installations []*Installation, shown int, dwell time.Duration) {
+	now := time.Now()
+	var inst *Installation
+	for i := 1; i <= len(installations); i++ {
+		candidate := installations[(shown+i)%len(installations)]
+		if h.scheduled(ctx, deviceID, candidate, now.Add(dwell)) {
+			inst = candidate
+			break
+		}
+	}
+	if inst == nil || !inst.Rendered() || inst.Refresh != "" {
+		// installations rendered in the background are warm already
+		return
+	}
+	if len(inst.Image) > 0 && now.Add(dwell).Before(inst.RefreshAt) {
+		return
+	}
+
+	key := refreshKey(deviceID, inst.ID)
+	h.mu.Lock()
+	if h.prefetching[key] {
+		h.mu.Unlock()
+		return
+	}
+	h.prefetching[key] = true
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dwell)
+	go func() {
+		defer cancel()
+		defer func() {
+			h.mu.Lock()
+			delete(h.prefetching, key)
+			h.mu.Unlock()
+		}()
+
+		// loading the app ahead of time warms it up too
+		app, err := h.apps.Applet(inst.AppID)
+		if err != nil {
+			return
+		}
+		if err := app.Prefetch(ctx, inst.Config, h.renderOptions(deviceID, inst)); err != nil {
+			slog.Warn("prefetching installation", "device", deviceID, "installation", inst.ID, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,42 @@
+package hub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRefresh(t *testing.T) {
+	oslo, err := time.LoadLocation("Europe/Oslo")
+	require.NoError(t, err)
+	// a Friday
+	now := time.Date(2024, 6, 21, 9, 7, 30, 0, oslo)
+
+	for _, tc := range []struct {
+		refresh string
+		next    time.Time
+	}{
+		{"5m", now.Add(5 * time.Minute)},
+		{"*/15 * * * *", time.Date(2024, 6, 21, 9, 15, 0, 0, oslo)},
+		{"0 6-22/4 * * *", time.Date(2024, 6, 21, 10, 0, 0, 0, oslo)},
+		{"30 8 * * sat,sun", time.Date(2024, 6, 22, 8, 30, 0, 0, oslo)},
+		{"0 0 * * 7", time.Date(2024, 6, 23, 0, 0, 0, 0, oslo)},
+		{"0 12 1 jan *", time.Date(2025, 1, 1, 12, 0, 0, 0, oslo)},
+		// either day field matches when both are restricted
+		{"0 0 25 * mon", time.Date(2024, 6, 24, 0, 0, 0, 0, oslo)},
+		{"@hourly", time.Date(2024, 6, 21, 10, 0, 0, 0, oslo)},
+		{"@monthly", time.Date(2024, 7, 1, 0, 0, 0, 0, oslo)},
+		{"0 0 30 feb *", time.Time{}},
+	} {
+		r, err := ParseRefresh(tc.refresh)
+		require.NoError(t, err, tc.refresh)
+		assert.Equal(t, tc.next, r.Next(now), tc.refresh)
+	}
+
+	for _, bad := range []string{"", "soon", "100ms", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		_, err := ParseRefresh(bad)
+		assert.Error(t, err, bad)
+	}
+}
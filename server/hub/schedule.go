@@ -0,0 +1,119 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schedule limits when an installation is in its device's playlist. All of
+// its rules must allow it for it to be shown.
+type Schedule struct {
+	// Days are the days of the week the installation is shown on, such as
+	// "mon" or "sat". Empty means every day.
+	Days []string `json:"days,omitempty"`
+
+	// Start and End are the times of day, as HH:MM, it's shown between.
+	// They wrap past midnight if End is before Start. Empty means all day.
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+
+	// Calendar is the URL of an iCalendar feed. The installation is only
+	// shown while one of its events is happening, or on days with events
+	// if WholeDay is set.
+	Calendar string `json:"calendar,omitempty"`
+	WholeDay bool   `json:"wholeDay,omitempty"`
+
+	// Match limits Calendar to events whose summary contains it, ignoring
+	// case.
+	Match string `json:"match,omitempty"`
+}
+
+var scheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// empty reports whether the schedule has no rules.
+func (s *Schedule) empty() bool {
+	return len(s.Days) == 0 && s.Start == "" && s.End == "" && s.Calendar == ""
+}
+
+// Validate returns an error if the schedule's rules can't be understood.
+func (s *Schedule) Validate() error {
+	for _, day := range s.Days {
+		if _, ok := scheduleDays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("invalid day %q, expected one of mon, tue, wed, thu, fri, sat or sun", day)
+		}
+	}
+	if (s.Start == "") != (s.End == "") {
+		return fmt.Errorf("schedule needs both a start and an end")
+	}
+	if s.Start != "" {
+		if _, err := parseTimeOfDay(s.Start); err != nil {
+			return err
+		}
+		if _, err := parseTimeOfDay(s.End); err != nil {
+			return err
+		}
+	}
+	if s.Calendar != "" {
+		u, err := url.Parse(s.Calendar)
+		if err != nil || u.Host == "" || !slices.Contains([]string{"http", "https", "webcal"}, u.Scheme) {
+			return fmt.Errorf("calendar %q isn't an http, https or webcal URL", s.Calendar)
+		}
+	}
+	return nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// active reports whether the schedule allows an installation to be shown
+// at t, in t's location. If its calendar can't be fetched, it's as if
+// nothing's happening.
+func (s *Schedule) active(ctx context.Context, cals *calendars, t time.Time) (bool, error) {
+	if len(s.Days) > 0 && !slices.ContainsFunc(s.Days, func(day string) bool {
+		return scheduleDays[strings.ToLower(day)] == t.Weekday()
+	}) {
+		return false, nil
+	}
+
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	if s.Start != "" {
+		start, _ := parseTimeOfDay(s.Start)
+		end, _ := parseTimeOfDay(s.End)
+		since := t.Sub(midnight)
+		if start <= end && (since < start || since >= end) || start > end && since < start && since >= end {
+			return false, nil
+		}
+	}
+
+	if s.Calendar == "" {
+		return true, nil
+	}
+
+	events, err := cals.events(ctx, s.Calendar)
+	from, to := t, t.Add(time.Nanosecond)
+	if s.WholeDay {
+		from, to = midnight, midnight.AddDate(0, 0, 1)
+	}
+	for _, ev := range events {
+		if s.Match != "" && !strings.Contains(strings.ToLower(ev.summary), strings.ToLower(s.Match)) {
+			continue
+		}
+		if ev.happening(from, to) {
+			return true, err
+		}
+	}
+	return false, err
+}
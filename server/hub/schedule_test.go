@@ -0,0 +1,126 @@
+package hub
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"DTSTART;TZID=Europe/Oslo:20240603T093000\r\n" +
+	"DTEND;TZID=Europe/Oslo:20240603T094500\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR;UNTIL=20240705\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Rosenborg vs\r\n" +
+	"  Molde\r\n" +
+	"DTSTART:20240622T160000Z\r\n" +
+	"DURATION:PT2H\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Midsummer\r\n" +
+	"DTSTART;VALUE=DATE:20240623\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	oslo, err := time.LoadLocation("Europe/Oslo")
+	require.NoError(t, err)
+
+	events, err := parseICS(strings.NewReader(testCalendar), oslo)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, "Standup", events[0].summary)
+	assert.Equal(t, 15*time.Minute, events[0].end.Sub(events[0].start))
+	assert.Equal(t, []time.Weekday{time.Monday, time.Wednesday, time.Friday}, events[0].rrule.byDay)
+	assert.Equal(t, "Rosenborg vs Molde", events[1].summary)
+	assert.Equal(t, 2*time.Hour, events[1].end.Sub(events[1].start))
+	assert.Equal(t, time.Date(2024, 6, 23, 0, 0, 0, 0, oslo), events[2].start)
+
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, oslo)
+	}
+	happening := func(ev event, t time.Time) bool {
+		return ev.happening(t, t.Add(time.Nanosecond))
+	}
+
+	// Friday the 21st
+	assert.True(t, happening(events[0], at(6, 21, 9, 40)))
+	assert.False(t, happening(events[0], at(6, 21, 9, 50)))
+	assert.False(t, happening(events[0], at(6, 20, 9, 40)))
+	// the last one is on the 5th of July
+	assert.True(t, happening(events[0], at(7, 5, 9, 40)))
+	assert.False(t, happening(events[0], at(7, 8, 9, 40)))
+
+	assert.True(t, happening(events[1], at(6, 22, 19, 0)))
+	assert.False(t, happening(events[1], at(6, 22, 20, 0)))
+
+	assert.True(t, happening(events[2], time.Date(2030, 6, 23, 12, 0, 0, 0, oslo)))
+	assert.False(t, happening(events[2], time.Date(2030, 6, 24, 12, 0, 0, 0, oslo)))
+
+	_, err = parseICS(strings.NewReader("BEGIN:VEVENT\nRRULE:FREQ=MONTHLY;BYDAY=1MO\nEND:VEVENT\n"), oslo)
+	assert.Error(t, err)
+}
+
+func TestScheduleActive(t *testing.T) {
+	oslo, err := time.LoadLocation("Europe/Oslo")
+	require.NoError(t, err)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 6, day, hour, minute, 0, 0, oslo)
+	}
+
+	fetches := 0
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write([]byte(testCalendar))
+	}))
+	defer feed.Close()
+	cals := newCalendars()
+
+	active := func(s Schedule, when time.Time) bool {
+		require.NoError(t, s.Validate())
+		ok, err := s.active(context.Background(), cals, when)
+		require.NoError(t, err)
+		return ok
+	}
+
+	workHours := Schedule{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"}
+	assert.True(t, active(workHours, at(21, 12, 0)))
+	assert.False(t, active(workHours, at(21, 17, 0)))
+	assert.False(t, active(workHours, at(22, 12, 0)))
+
+	overnight := Schedule{Start: "22:00", End: "06:00"}
+	assert.True(t, active(overnight, at(21, 23, 0)))
+	assert.True(t, active(overnight, at(21, 5, 0)))
+	assert.False(t, active(overnight, at(21, 12, 0)))
+
+	standup := Schedule{Calendar: feed.URL, Match: "standup"}
+	assert.True(t, active(standup, at(21, 9, 35)))
+	assert.False(t, active(standup, at(21, 10, 0)))
+
+	gameDay := Schedule{Calendar: feed.URL, Match: "rosenborg", WholeDay: true}
+	assert.True(t, active(gameDay, at(22, 8, 0)))
+	assert.False(t, active(gameDay, at(21, 20, 0)))
+
+	assert.Equal(t, 1, fetches)
+
+	for _, bad := range []Schedule{
+		{Days: []string{"someday"}},
+		{Start: "09:00"},
+		{Start: "9am", End: "5pm"},
+		{Calendar: "file:///etc/passwd"},
+	} {
+		assert.Error(t, bad.Validate())
+	}
+}
@@ -0,0 +1,328 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"tidbyt.dev/pixlet/lib"
+)
+
+// scheduleTick is how often the scheduler checks for devices it's due to
+// push to.
+const scheduleTick = time.Second
+
+// cursor is where a device is in its playlist: the installation it showed
+// last, and which of its screens.
+type cursor struct {
+	installation string
+	screen       int
+}
+
+// dwellOf returns how long a screen of an installation is shown for: as
+// long as the user set, or else as the app suggested, or else the hub's
+// default.
+func (h *Hub) dwellOf(inst *Installation, screen int) time.Duration {
+	appDwell := inst.AppDwellSecs
+	if screen < len(inst.Screens) {
+		appDwell = inst.Screens[screen].AppDwellSecs
+	}
+
+	switch {
+	case inst.DwellSecs > 0:
+		return time.Duration(inst.DwellSecs) * time.Second
+	case appDwell > 0:
+		return time.Duration(appDwell) * time.Second
+	default:
+		return h.dwell
+	}
+}
+
+// show records that a device is showing a screen of an installation.
+func (h *Hub) show(deviceID string, inst *Installation, screen int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cursors[deviceID] = cursor{installation: inst.ID, screen: screen}
+}
+
+// next returns the image the device should show now and for how long, and
+// advances its playlist. An image pushed to be shown right away comes first,
+// then the pinned installation, if any, and otherwise the next installation
+// in the playlist. Installations that are disabled, or that their schedule
+// doesn't allow right now, are skipped. Installations the hub renders are
+// rendered as they come up, and those with nothing to show are skipped too.
+// Apps with several screens show each in turn before the playlist moves on.
+// The image is nil if the device has nothing to show.
+func (h *Hub) next(ctx context.Context, id string) ([]byte, time.Duration, *Device, error) {
+	var image []byte
+	var device *Device
+	err := h.store.Update(id, false, func(d *Device) error {
+		device = d
+		if d.Interrupt != nil {
+			image, d.Interrupt = d.Interrupt, nil
+			return nil
+		}
+		return errUnchanged
+	})
+	if err != nil || image != nil {
+		return image, h.dwell, device, err
+	}
+
+	h.mu.Lock()
+	last := h.cursors[id]
+	h.mu.Unlock()
+
+	// carry on through the screens of the installation shown last
+	pinned := device.Installation(device.PinnedApp)
+	if inst := device.Installation(last.installation); inst != nil && last.screen+1 < len(inst.Screens) {
+		if inst == pinned || pinned == nil && h.scheduled(ctx, id, inst, time.Now()) {
+			screen := last.screen + 1
+			h.show(id, inst, screen)
+			return inst.Screens[screen].Image, h.dwellOf(inst, screen), device, nil
+		}
+	}
+
+	if pinned != nil {
+		if image := h.refresh(ctx, id, pinned); len(image) > 0 {
+			h.show(id, pinned, 0)
+			return image, h.dwellOf(pinned, 0), device, nil
+		}
+	}
+
+	// pick up after the installation shown last, by ID, so that the
+	// playlist carries on in order as installations are added, removed
+	// and reordered
+	installations := device.Installations
+	start := slices.IndexFunc(installations, func(inst *Installation) bool { return inst.ID == last.installation }) + 1
+	for i := range installations {
+		inst := installations[(start+i)%len(installations)]
+		if !h.scheduled(ctx, id, inst, time.Now()) {
+			continue
+		}
+		if image := h.refresh(ctx, id, inst); len(image) > 0 {
+			h.show(id, inst, 0)
+			dwell := h.dwellOf(inst, 0)
+			h.warmUp(ctx, id, installations, (start+i)%len(installations), dwell)
+			return image, dwell, device, nil
+		}
+	}
+	return nil, h.dwell, device, nil
+}
+
+// scheduled reports whether an installation is in its device's playlist at
+// t: whether it's enabled, and its schedule allows it.
+func (h *Hub) scheduled(ctx context.Context, deviceID string, inst *Installation, t time.Time) bool {
+	if inst.Disabled {
+		return false
+	}
+	if inst.Schedule == nil {
+		return true
+	}
+
+	active, err := inst.Schedule.active(ctx, h.cals, t)
+	if err != nil {
+		slog.Warn("checking installation's calendar", "device", deviceID, "installation", inst.ID, "error", err)
+	}
+	return active
+}
+
+// refresh returns the image to show for an installation. Installations the
+// hub renders are rendered again, unless they're kept fresh in the
+// background or their app suggested a later time to render them again, and
+// the new image saved; if that fails, an error frame is shown if the hub
+// shows them, and otherwise the last image rather than nothing.
+func (h *Hub) refresh(ctx context.Context, deviceID string, inst *Installation) []byte {
+	if !inst.Rendered() || inst.Refresh != "" {
+		return inst.Image
+	}
+	if len(inst.Image) > 0 && time.Now().Before(inst.RefreshAt) {
+		return inst.Image
+	}
+
+	image, err := h.renderAndSave(ctx, deviceID, inst)
+	if err != nil {
+		slog.Warn("rendering installation", "device", deviceID, "installation", inst.ID, "error", err)
+		if len(image) > 0 {
+			// an error frame
+			return image
+		}
+		return inst.Image
+	}
+	return image
+}
+
+// renderAndSave renders an installation, and saves its images. inst is
+// updated with the render too. If the render fails and the hub shows error
+// frames, an error frame is saved and returned in its place, along with the
+// error; it's rendered again the next time it comes up.
+func (h *Hub) renderAndSave(ctx context.Context, deviceID string, inst *Installation) ([]byte, error) {
+	now := time.Now()
+	imgs, renderErr := h.renderInstallation(ctx, deviceID, inst)
+	if renderErr != nil {
+		if !h.errorFrames {
+			return nil, renderErr
+		}
+		frame, err := lib.RenderError(inst.AppID, renderErr, now, h.renderOptions(deviceID, inst))
+		if err != nil {
+			return nil, errors.Join(renderErr, err)
+		}
+		imgs = []*lib.EncodedImage{frame}
+	}
+
+	inst.setRender(imgs, now)
+	err := h.store.Update(deviceID, false, func(d *Device) error {
+		saved := d.Installation(inst.ID)
+		if saved == nil {
+			// deleted while it was rendering
+			return errUnchanged
+		}
+		saved.setRender(imgs, now)
+		saved.State = inst.State
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(renderErr, fmt.Errorf("saving render: %w", err))
+	}
+	return inst.Image, renderErr
+}
+
+// refreshKey identifies an installation in the hub's refresh schedule.
+func refreshKey(deviceID, installationID string) string {
+	return deviceID + "/" + installationID
+}
+
+// scheduleRefresh sets when an installation is next rendered in the
+// background, after now. Installations without a refresh schedule are
+// dropped from it.
+func (h *Hub) scheduleRefresh(deviceID string, inst *Installation, now time.Time) {
+	key := refreshKey(deviceID, inst.ID)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if inst.Refresh == "" || !inst.Rendered() {
+		delete(h.refreshes, key)
+		return
+	}
+	refresh, err := ParseRefresh(inst.Refresh)
+	if err != nil {
+		slog.Warn("invalid refresh schedule", "device", deviceID, "installation", inst.ID, "error", err)
+		delete(h.refreshes, key)
+		return
+	}
+
+	next := refresh.Next(now)
+	if next.IsZero() {
+		// a cron expression that never matches
+		next = now.AddDate(100, 0, 0)
+	}
+	// the app can ask to be rendered sooner than its schedule
+	if !inst.RefreshAt.IsZero() && inst.RefreshAt.Before(next) {
+		next = inst.RefreshAt
+	}
+	h.refreshes[key] = next
+}
+
+// renderSoon makes the background renderer render an installation at its
+// next tick.
+func (h *Hub) renderSoon(deviceID, installationID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.refreshes, refreshKey(deviceID, installationID))
+}
+
+// renderInBackground renders installations with a refresh schedule as
+// they're due, until ctx is done, so that their freshest image is ready
+// whenever they come up in the playlist.
+func (h *Hub) renderInBackground(ctx context.Context) error {
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+
+	for {
+		h.renderDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Hub) renderDue(ctx context.Context, now time.Time) {
+	for _, d := range h.store.Devices() {
+		for _, inst := range d.Installations {
+			if inst.Refresh == "" || !inst.Rendered() {
+				continue
+			}
+
+			// installations that haven't been scheduled yet, because the
+			// hub just started, are rendered right away
+			h.mu.Lock()
+			due, ok := h.refreshes[refreshKey(d.ID, inst.ID)]
+			h.mu.Unlock()
+			if ok && now.Before(due) {
+				continue
+			}
+
+			if _, err := h.renderAndSave(ctx, d.ID, inst); err != nil {
+				slog.Warn("rendering installation in the background", "device", d.ID, "installation", inst.ID, "error", err)
+			}
+			h.scheduleRefresh(d.ID, inst, now)
+		}
+	}
+}
+
+// wake makes the scheduler push to a device at its next tick, rather than
+// waiting for the current image's dwell to run out.
+func (h *Hub) wake(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.due, id)
+}
+
+// schedule delivers the next image to each device with a push target or
+// sinks as the last one's dwell runs out, until ctx is done.
+func (h *Hub) schedule(ctx context.Context) error {
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+
+	for {
+		h.pushDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Hub) pushDue(ctx context.Context, now time.Time) {
+	for _, d := range h.store.Devices() {
+		if len(d.sinkConfigs()) == 0 {
+			continue
+		}
+
+		h.mu.Lock()
+		due := h.due[d.ID]
+		h.mu.Unlock()
+		if now.Before(due) {
+			continue
+		}
+
+		image, dwell, _, err := h.next(ctx, d.ID)
+		if err != nil {
+			slog.Warn("picking the next image", "device", d.ID, "error", err)
+		} else if image != nil {
+			h.deliver(ctx, d, image, dwell)
+		}
+
+		h.mu.Lock()
+		h.due[d.ID] = now.Add(dwell)
+		h.mu.Unlock()
+	}
+}
@@ -0,0 +1,170 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"tidbyt.dev/pixlet/server/sink"
+)
+
+// sinkTimeout is how long a delivery to a sink can take.
+const sinkTimeout = 30 * time.Second
+
+// SinkStatus is how deliveries to one of a device's sinks have gone since
+// the hub started.
+type SinkStatus struct {
+	Type            string    `json:"type"`
+	Delivered       int       `json:"delivered"`
+	Failures        int       `json:"failures"`
+	LastDeliveredAt time.Time `json:"lastDeliveredAt,omitzero"`
+	LastError       string    `json:"lastError,omitempty"`
+	LastErrorAt     time.Time `json:"lastErrorAt,omitzero"`
+}
+
+// deviceSink is a sink built from one of a device's sink configs.
+type deviceSink struct {
+	// key is the config it was built from, as JSON, so that it's only
+	// built again when its config changes.
+	key string
+
+	// sink is nil if the config isn't valid, with err saying why.
+	sink sink.Sink
+	err  error
+
+	status SinkStatus
+}
+
+// sinkConfigs returns where the hub delivers the device's images: its push
+// target, if it has one, and then its sinks.
+func (d *Device) sinkConfigs() []sink.Config {
+	var configs []sink.Config
+	if d.Push != nil {
+		configs = append(configs, sink.Config{
+			Type:     "http",
+			URL:      d.Push.URL,
+			DeviceID: d.Push.DeviceID,
+			APIKey:   d.Push.APIKey,
+		})
+	}
+	return append(configs, d.Sinks...)
+}
+
+// sinksOf returns the device's sinks, building those whose config changed
+// since they were last built, and closing those it no longer has.
+func (h *Hub) sinksOf(d *Device) []*deviceSink {
+	var unused []*deviceSink
+	defer func() {
+		for _, s := range unused {
+			if s.sink != nil {
+				s.sink.Close()
+			}
+		}
+	}()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	old := h.sinks[d.ID]
+	var sinks []*deviceSink
+	for _, cfg := range d.sinkConfigs() {
+		key, _ := json.Marshal(cfg)
+
+		var s *deviceSink
+		for i, o := range old {
+			if o != nil && o.key == string(key) {
+				s, old[i] = o, nil
+				break
+			}
+		}
+		if s == nil {
+			s = &deviceSink{key: string(key), status: SinkStatus{Type: cfg.Type}}
+			s.sink, s.err = sink.New(cfg)
+		}
+		sinks = append(sinks, s)
+	}
+
+	for _, o := range old {
+		if o != nil {
+			unused = append(unused, o)
+		}
+	}
+	if len(sinks) == 0 {
+		delete(h.sinks, d.ID)
+	} else {
+		h.sinks[d.ID] = sinks
+	}
+	return sinks
+}
+
+// deliver sends an image for a device to all of its sinks at once, and
+// records how each delivery went.
+func (h *Hub) deliver(ctx context.Context, d *Device, image []byte, dwell time.Duration) {
+	img := sink.Image{Data: image, DeviceID: d.ID, Dwell: dwell}
+
+	var wg sync.WaitGroup
+	for _, s := range h.sinksOf(d) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := s.err
+			if s.sink != nil {
+				ctx, cancel := context.WithTimeout(ctx, sinkTimeout)
+				err = sink.Deliver(ctx, s.sink, img)
+				cancel()
+			}
+
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			now := time.Now()
+			if err != nil {
+				slog.Warn("delivering to sink", "device", d.ID, "sink", s.status.Type, "error", err)
+				s.status.Failures++
+				s.status.LastError = err.Error()
+				s.status.LastErrorAt = now
+				return
+			}
+			s.status.Delivered++
+			s.status.LastDeliveredAt = now
+		}()
+	}
+	wg.Wait()
+}
+
+// sinkStatus returns how deliveries to each of a device's sinks have gone,
+// or nil if it has none.
+func (h *Hub) sinkStatus(id string) []SinkStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var statuses []SinkStatus
+	for _, s := range h.sinks[id] {
+		statuses = append(statuses, s.status)
+	}
+	return statuses
+}
+
+// websocketHandler streams a device's images to a WebSocket client, for
+// devices with a websocket sink. Like polling, it doesn't need the API key.
+func (h *Hub) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	d, err := h.store.Device(r.PathValue("device"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	for _, s := range h.sinksOf(d) {
+		if s.sink == nil {
+			continue
+		}
+		if ws, ok := sink.Unwrap(s.sink).(*sink.WebSocket); ok {
+			ws.ServeHTTP(w, r)
+			return
+		}
+	}
+	http.Error(w, "device has no websocket sink", http.StatusNotFound)
+}
@@ -0,0 +1,294 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"tidbyt.dev/pixlet/server/sink"
+)
+
+// ErrNotFound is returned for devices and installations that don't exist.
+var ErrNotFound = errors.New("not found")
+
+// errUnchanged is returned by functions passed to Store.Update that turn out
+// not to change the device, so that it isn't saved.
+var errUnchanged = errors.New("unchanged")
+
+// Device is a display that pulls images from the hub.
+type Device struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	Brightness  int    `json:"brightness"`
+	AutoDim     bool   `json:"autoDim"`
+
+	// PinnedApp is the ID of an installation that's shown instead of
+	// the rotation, if set.
+	PinnedApp string `json:"pinnedApp,omitempty"`
+
+	// Locale is the language tag of the device's user, and Metadata other
+	// details about it, which apps rendered for it read with the request
+	// module.
+	Locale   string            `json:"locale,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Profile is the name of the device's profile, see device.Profiles,
+	// which sets the size, format and color depth apps are rendered with
+	// for it. Empty means the hub's render options are used as they are.
+	Profile string `json:"profile,omitempty"`
+
+	// Installations are the device's playlist, in the order they're shown.
+	Installations []*Installation `json:"installations"`
+
+	// Interrupt is an image pushed to be shown right away, once, ahead of
+	// the rotation.
+	Interrupt []byte `json:"interrupt,omitempty"`
+
+	// Push is where the hub pushes images for the device, if it doesn't
+	// poll for them.
+	Push *PushTarget `json:"push,omitempty"`
+
+	// Sinks are other places the hub delivers the device's images to, such
+	// as MQTT topics, files or webhooks.
+	Sinks []sink.Config `json:"sinks,omitempty"`
+}
+
+// PushTarget is a device the hub pushes images to: a Tronbyt device or
+// server, or a Tidbyt through Tidbyt's API, which take the same pushes.
+type PushTarget struct {
+	URL      string `json:"url"`
+	DeviceID string `json:"deviceID"`
+	APIKey   string `json:"apiKey"`
+}
+
+// Installation is an app in a device's rotation.
+type Installation struct {
+	ID    string `json:"id"`
+	AppID string `json:"appID"`
+
+	// Config is what the hub renders the app with. It's nil for
+	// installations that are only pushed to.
+	Config map[string]string `json:"config"`
+
+	// Version is the version of the app that was installed, from its
+	// manifest. It's empty if the app doesn't have one.
+	Version string `json:"version,omitempty"`
+
+	// Disabled installations are left out of the playlist.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Schedule limits when the installation is in the playlist. Nil means
+	// it always is.
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	// DwellSecs is how long the installation is shown for. Zero means the
+	// hub's default.
+	DwellSecs int `json:"dwellSecs,omitempty"`
+
+	// Refresh is how often the hub renders the installation in the
+	// background, see ParseRefresh. Installations without one are rendered
+	// as they come up in the playlist.
+	Refresh string `json:"refresh,omitempty"`
+
+	// AppDwellSecs and RefreshAt are what the app suggested when it was
+	// last rendered: how long to show it for, and when to render it again.
+	// They're zero if it didn't say.
+	AppDwellSecs int       `json:"appDwellSecs,omitempty"`
+	RefreshAt    time.Time `json:"refreshAt,omitzero"`
+
+	// State is what the app keeps across renders with the state module.
+	State map[string]string `json:"state,omitempty"`
+
+	// Screens are the images of an app that shows several separate
+	// screens, which take turns in the playlist one after another. Image
+	// is then the first of them. It's empty for apps that show one.
+	Screens []Screen `json:"screens,omitempty"`
+
+	// Image is the latest image for the installation, pushed to it or
+	// rendered by the hub.
+	Image     []byte    `json:"image,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Screen is one of the screens of an app that shows several.
+type Screen struct {
+	Image []byte `json:"image"`
+
+	// AppDwellSecs is how long the app suggested showing the screen for.
+	// Zero means it didn't say.
+	AppDwellSecs int `json:"appDwellSecs,omitempty"`
+}
+
+// Rendered reports whether the hub renders the installation's app, rather
+// than it being pushed to.
+func (i *Installation) Rendered() bool {
+	return i.Config != nil
+}
+
+// Installation returns the installation with the given ID.
+func (d *Device) Installation(id string) *Installation {
+	for _, inst := range d.Installations {
+		if inst.ID == id {
+			return inst
+		}
+	}
+	return nil
+}
+
+// clone returns a deep copy of the device, so that it can be read without
+// holding the store's lock.
+func (d *Device) clone() *Device {
+	c := *d
+	if d.Push != nil {
+		push := *d.Push
+		c.Push = &push
+	}
+	c.Metadata = maps.Clone(d.Metadata)
+	c.Sinks = slices.Clone(d.Sinks)
+	for i := range c.Sinks {
+		c.Sinks[i].Formats = slices.Clone(c.Sinks[i].Formats)
+	}
+	c.Installations = make([]*Installation, len(d.Installations))
+	for i, inst := range d.Installations {
+		ic := *inst
+		ic.Config = maps.Clone(inst.Config)
+		ic.State = maps.Clone(inst.State)
+		ic.Screens = slices.Clone(inst.Screens)
+		if inst.Schedule != nil {
+			sc := *inst.Schedule
+			sc.Days = slices.Clone(sc.Days)
+			ic.Schedule = &sc
+		}
+		c.Installations[i] = &ic
+	}
+	return &c
+}
+
+// Store holds the hub's devices, and saves them to a file so that they
+// survive restarts.
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	devices map[string]*Device
+}
+
+// OpenStore loads the devices saved at path. If path is empty, devices are
+// only kept in memory.
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path, devices: map[string]*Device{}}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading hub state: %w", err)
+	}
+
+	var devices []*Device
+	if err := json.Unmarshal(data, &devices); err != nil {
+		return nil, fmt.Errorf("parsing hub state %s: %w", path, err)
+	}
+	for _, d := range devices {
+		s.devices[d.ID] = d
+	}
+	return s, nil
+}
+
+// Devices returns copies of all devices, sorted by ID.
+func (s *Store) Devices() []*Device {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	devices := make([]*Device, 0, len(s.devices))
+	for _, d := range s.devices {
+		devices = append(devices, d.clone())
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
+	return devices
+}
+
+// Device returns a copy of the device with the given ID.
+func (s *Store) Device(id string) (*Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.devices[id]
+	if !ok {
+		return nil, fmt.Errorf("device %q: %w", id, ErrNotFound)
+	}
+	return d.clone(), nil
+}
+
+// Update calls fn with the device with the given ID, and saves the changes
+// fn makes unless it returns an error. If create is set, devices that don't
+// exist yet are created.
+func (s *Store) Update(id string, create bool, fn func(d *Device) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.devices[id]
+	if !ok {
+		if !create {
+			return fmt.Errorf("device %q: %w", id, ErrNotFound)
+		}
+		d = &Device{ID: id, DisplayName: id, Brightness: 100}
+	}
+
+	updated := d.clone()
+	if err := fn(updated); errors.Is(err, errUnchanged) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	s.devices[id] = updated
+	return s.save()
+}
+
+// save writes the devices to the store's file. It's called with s.mu held.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	devices := make([]*Device, 0, len(s.devices))
+	for _, d := range s.devices {
+		devices = append(devices, d)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
+
+	data, err := json.MarshalIndent(devices, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so a crash can't leave the state
+	// half written
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("saving hub state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving hub state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saving hub state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("saving hub state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+// Telemetry is what a device last reported about its health. Fields the
+// device doesn't report are nil.
+type Telemetry struct {
+	FirmwareVersion string   `json:"firmwareVersion,omitempty"`
+	UptimeSecs      *int64   `json:"uptimeSecs,omitempty"`
+	RSSI            *int     `json:"rssi,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+
+	// ReportedAt is when the hub received the report.
+	ReportedAt time.Time `json:"reportedAt"`
+}
+
+// telemetryHandler takes a report from a device about its health. Like
+// polling for images, it doesn't need the API key, but only devices the hub
+// knows about can report. Reports are only kept in memory.
+func (h *Hub) telemetryHandler(w http.ResponseWriter, r *http.Request) {
+	var t Telemetry
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&t); err != nil {
+		http.Error(w, fmt.Sprintf("decoding telemetry: %v", err), http.StatusBadRequest)
+		return
+	}
+	t.ReportedAt = time.Now()
+
+	d, err := h.store.Device(r.PathValue("device"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.mu.Lock()
+	h.telemetry[d.ID] = t
+	h.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deviceTelemetry returns what a device last reported about its health, or
+// nil if it hasn't reported since the hub started.
+func (h *Hub) deviceTelemetry(id string) *Telemetry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.telemetry[id]
+	if !ok {
+		return nil
+	}
+	return &t
+}
+
+// metricsHandler serves the health of devices, of deliveries to them and of
+// app renders in Prometheus' text format.
+func (h *Hub) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	metric := func(name, help, typ string, samples func(sample func(labels string, value float64))) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		samples(func(labels string, value float64) {
+			fmt.Fprintf(&b, "%s{%s} %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+		})
+	}
+
+	devices := h.store.Devices()
+	telemetry := make(map[string]*Telemetry, len(devices))
+	for _, d := range devices {
+		telemetry[d.ID] = h.deviceTelemetry(d.ID)
+	}
+	deviceMetric := func(name, help string, value func(t *Telemetry) (float64, bool)) {
+		metric(name, help, "gauge", func(sample func(string, float64)) {
+			for _, d := range devices {
+				if telemetry[d.ID] == nil {
+					continue
+				}
+				if v, ok := value(telemetry[d.ID]); ok {
+					sample(label("device", d.ID), v)
+				}
+			}
+		})
+	}
+
+	metric("pixlet_device_info", "Devices known to the hub, with the firmware they last reported.", "gauge", func(sample func(string, float64)) {
+		for _, d := range devices {
+			firmware := ""
+			if t := telemetry[d.ID]; t != nil {
+				firmware = t.FirmwareVersion
+			}
+			sample(label("device", d.ID)+","+label("firmware", firmware), 1)
+		}
+	})
+	deviceMetric("pixlet_device_last_report_timestamp_seconds", "When the device last reported its health.", func(t *Telemetry) (float64, bool) {
+		return float64(t.ReportedAt.Unix()), true
+	})
+	deviceMetric("pixlet_device_uptime_seconds", "How long the device had been running when it last reported.", func(t *Telemetry) (float64, bool) {
+		if t.UptimeSecs == nil {
+			return 0, false
+		}
+		return float64(*t.UptimeSecs), true
+	})
+	deviceMetric("pixlet_device_wifi_rssi_dbm", "The strength of the device's Wi-Fi signal.", func(t *Telemetry) (float64, bool) {
+		if t.RSSI == nil {
+			return 0, false
+		}
+		return float64(*t.RSSI), true
+	})
+	deviceMetric("pixlet_device_temperature_celsius", "The temperature the device last reported.", func(t *Telemetry) (float64, bool) {
+		if t.Temperature == nil {
+			return 0, false
+		}
+		return *t.Temperature, true
+	})
+
+	sinkMetric := func(name, help string, value func(s SinkStatus) float64) {
+		metric(name, help, "counter", func(sample func(string, float64)) {
+			for _, d := range devices {
+				for i, s := range h.sinkStatus(d.ID) {
+					sample(label("device", d.ID)+","+label("sink", strconv.Itoa(i))+","+label("type", s.Type), value(s))
+				}
+			}
+		})
+	}
+	sinkMetric("pixlet_sink_deliveries_total", "Images delivered to the device's sink.", func(s SinkStatus) float64 {
+		return float64(s.Delivered)
+	})
+	sinkMetric("pixlet_sink_errors_total", "Images that failed to be delivered to the device's sink.", func(s SinkStatus) float64 {
+		return float64(s.Failures)
+	})
+
+	usage := runtime.DefaultUsageReport.Apps()
+	apps := make([]string, 0, len(usage))
+	for id := range usage {
+		apps = append(apps, id)
+	}
+	sort.Strings(apps)
+	appMetric := func(name, help string, value func(u runtime.AppUsage) float64) {
+		metric(name, help, "counter", func(sample func(string, float64)) {
+			for _, id := range apps {
+				sample(label("app", id), value(usage[id]))
+			}
+		})
+	}
+	appMetric("pixlet_app_renders_total", "Renders of the app.", func(u runtime.AppUsage) float64 {
+		return float64(u.Executions)
+	})
+	appMetric("pixlet_app_render_errors_total", "Renders of the app that failed.", func(u runtime.AppUsage) float64 {
+		return float64(u.Errors)
+	})
+	appMetric("pixlet_app_render_seconds_total", "Time spent running the app's code, including waiting on HTTP requests.", func(u runtime.AppUsage) float64 {
+		return u.Total.WallTime.Seconds()
+	})
+	appMetric("pixlet_app_encode_seconds_total", "Time spent painting and encoding the app's frames.", func(u runtime.AppUsage) float64 {
+		return u.Total.EncodeTime.Seconds()
+	})
+	appMetric("pixlet_app_http_requests_total", "HTTP requests made by the app.", func(u runtime.AppUsage) float64 {
+		return float64(u.Total.HTTPRequests)
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	io.WriteString(w, b.String())
+}
+
+// label formats a Prometheus label, escaping its value.
+func label(name, value string) string {
+	return name + `="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
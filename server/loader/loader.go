@@ -9,35 +9,72 @@ import (
 	"fmt"
 	"image"
 	"io/fs"
-	"log"
 	"os"
-	"path/filepath"
-	"strings"
+	"sync"
 	"time"
 
 	"tidbyt.dev/pixlet/encode"
 	"tidbyt.dev/pixlet/globals"
+	"tidbyt.dev/pixlet/lib"
+	"tidbyt.dev/pixlet/logging"
+	"tidbyt.dev/pixlet/manifest"
+	"tidbyt.dev/pixlet/render"
 	"tidbyt.dev/pixlet/runtime"
 	"tidbyt.dev/pixlet/schema"
-	"tidbyt.dev/pixlet/tools"
+	"tidbyt.dev/pixlet/tracing"
 )
 
+// defaultAppID is the ID applets are loaded with when serving, unless their
+// manifest gives one.
+const defaultAppID = "app-id"
+
 // Loader is a structure to provide applet loading when a file changes or on
 // demand.
 type Loader struct {
-	fs               fs.FS
-	fileChanges      chan bool
-	watch            bool
-	applet           runtime.Applet
-	configChanges    chan map[string]string
-	requestedChanges chan bool
-	updatesChan      chan Update
-	resultsChan      chan Update
-	maxDuration      int
-	initialLoad      chan bool
-	timeout          int
-	renderGif        bool
-	configOutFile    string
+	fs            fs.FS
+	fileChanges   chan bool
+	watch         bool
+	updatesChan   chan Update
+	maxDuration   int
+	initialLoad   chan bool
+	timeout       int
+	renderGif     bool
+	configOutFile string
+	poolSize      int
+	cache         *runtime.StatsCache
+	appletOptions []runtime.AppletOption
+	errorFrames   string
+	onLoad        func(paths []string)
+
+	initialLoadOnce sync.Once
+
+	mutex   sync.RWMutex
+	pool    *runtime.AppletPool
+	config  map[string]string
+	display DisplayState
+	preview *Preview
+
+	// appID is the ID the applet was last loaded with, which its cache
+	// keys, logs, traces and usage are recorded under.
+	appID string
+}
+
+// Preview is the animation last rendered for the preview: its frames, and
+// how long each is shown, for stepping through it frame by frame.
+type Preview struct {
+	Frames []image.Image
+	Delays []time.Duration
+}
+
+// DisplayState is how renders are shown, which hosts such as Home Assistant
+// can control.
+type DisplayState struct {
+	// Brightness is a percentage that the colors of rendered images are
+	// scaled by.
+	Brightness int
+
+	// On is false when the screen is blanked.
+	On bool
 }
 
 type Update struct {
@@ -50,7 +87,8 @@ type Update struct {
 // NewLoader instantiates a new loader structure. The loader will read off of
 // fileChanges channel and write updates to the updatesChan. Updates are base64
 // encoded WebP strings. If watch is enabled, both file changes and on demand
-// requests will send updates over the updatesChan.
+// requests will send updates over the updatesChan. Up to poolSize renders run
+// concurrently. If cache is nil, an in-memory cache is used.
 func NewLoader(
 	fs fs.FS,
 	watch bool,
@@ -60,288 +98,714 @@ func NewLoader(
 	timeout int,
 	renderGif bool,
 	configOutFile string,
+	poolSize int,
+	cache runtime.Cache,
 ) (*Loader, error) {
 	l := &Loader{
-		fs:               fs,
-		fileChanges:      fileChanges,
-		watch:            watch,
-		applet:           runtime.Applet{},
-		updatesChan:      updatesChan,
-		configChanges:    make(chan map[string]string, 100),
-		requestedChanges: make(chan bool, 100),
-		resultsChan:      make(chan Update, 100),
-		maxDuration:      maxDuration,
-		initialLoad:      make(chan bool),
-		timeout:          timeout,
-		renderGif:        renderGif,
-		configOutFile:    configOutFile,
-	}
-
-	cache := runtime.NewInMemoryCache()
-	runtime.InitHTTP(cache)
-	runtime.InitCache(cache)
+		fs:            fs,
+		fileChanges:   fileChanges,
+		watch:         watch,
+		updatesChan:   updatesChan,
+		maxDuration:   maxDuration,
+		initialLoad:   make(chan bool),
+		timeout:       timeout,
+		renderGif:     renderGif,
+		configOutFile: configOutFile,
+		poolSize:      poolSize,
+		config:        make(map[string]string),
+		display:       DisplayState{Brightness: 100, On: true},
+		appID:         defaultAppID,
+	}
+
+	if cache == nil {
+		cache = runtime.NewInMemoryCache()
+	}
+	l.cache = runtime.NewStatsCache(cache)
+	runtime.InitHTTP(l.cache)
+	runtime.InitCache(l.cache)
 
 	if !l.watch {
-		app, err := loadScript("app-id", l.fs)
-		l.markInitialLoadComplete()
-		if err != nil {
+		if _, err := l.reload(); err != nil {
 			return nil, err
-		} else {
-			l.applet = *app
 		}
 	}
 
 	return l, nil
 }
 
-// Run executes the main loop. If there are config changes, those are recorded.
-// If there is an on-demand request, it's processed and sent back to the caller
-// and sent out as an update. If there is a file change, we update the applet
-// and send out the update over the updatesChan.
+// Run executes the main loop. Whenever a file changes, the applet is reloaded
+// and rendered with the most recent config, and the result is sent out over
+// the updatesChan.
 func (l *Loader) Run() error {
-	config := make(map[string]string)
-
-	for {
-		select {
-		case c := <-l.configChanges:
-			config = c
-		case <-l.requestedChanges:
-			up := Update{}
-
-			byteSlice, err := json.Marshal(config)
-			if err != nil {
-				panic(err)
-			}
-
-			if l.configOutFile != "" {
-				// Write the byte slice to the file.
-				//log.Printf("writing to %v",l.configOutFile)
-				err = os.WriteFile(l.configOutFile, byteSlice, 0644)
-				if err != nil {
-					panic(err)
-				}
-			}
-
-			img, err := l.loadApplet(config)
-			if err != nil {
-				log.Printf("error loading applet: %v", err)
-				up.Err = err
-			} else {
-				up.Image = img
-				up.ImageType = "webp"
-				if l.renderGif {
-					up.ImageType = "gif"
-				}
-			}
-
-			l.updatesChan <- up
-			l.resultsChan <- up
-		case <-l.fileChanges:
-			log.Println("detected updates, reloading")
-			up := Update{}
-
-			img, err := l.loadApplet(config)
-			if err != nil {
-				log.Printf("error loading applet: %v", err)
-				up.Err = err
-			} else {
-				up.Image = img
-				up.ImageType = "webp"
-				if l.renderGif {
-					up.ImageType = "gif"
-				}
-				up.Schema = string(l.applet.SchemaJSON)
-			}
-
-			l.updatesChan <- up
+	for range l.fileChanges {
+		logging.ForApp(l.currentAppID()).Info("detected updates, reloading")
+		up := Update{}
+
+		pool, err := l.reload()
+		if err == nil {
+			up.Schema = string(pool.Applet().SchemaJSON)
+			up.Image, err = l.render(context.Background(), pool, l.Config(), true)
+		}
+		if err != nil {
+			logging.ForApp(l.currentAppID()).Error("error loading applet", "error", err)
+			up.Err = err
+			up.Image = l.errorImage(err)
+		}
+		if up.Image != "" {
+			up.ImageType = l.imageType()
 		}
+
+		l.updatesChan <- up
 	}
+
+	return nil
 }
 
-// LoadApplet loads the applet on demand.
-//
-// TODO: This method is thread safe, but has a pretty glaring race condition. If
-// two callers request an update at the same time, they have the potential to
-// get each others update. At the time of writing, this method is only called
-// when you refresh a webpage during app development - so it doesn't seem likely
-// that it's going to cause issues in the short term.
+// LoadApplet renders the applet on demand with the given config, and sends
+// the result out over the updatesChan as well as returning it. It's safe to
+// call concurrently.
 func (l *Loader) LoadApplet(config map[string]string) (string, error) {
-	l.configChanges <- config
-	l.requestedChanges <- true
-	result := <-l.resultsChan
-	return result.Image, result.Err
+	return l.LoadAppletContext(context.Background(), config)
+}
+
+// LoadAppletContext is like LoadApplet, but runs the applet with ctx, so
+// values attached to it apply to this render only, such as HTTP mocks from
+// runtime.WithHTTPMocks or request info from runtime.WithRequestInfo.
+func (l *Loader) LoadAppletContext(ctx context.Context, config map[string]string) (string, error) {
+	l.setConfig(config)
+
+	up := Update{}
+	pool, err := l.currentPool()
+	if err == nil {
+		up.Image, err = l.render(ctx, pool, config, true)
+	}
+	if err != nil {
+		logging.ForApp(l.currentAppID()).Error("error loading applet", "error", err)
+		up.Err = err
+		up.Image = l.errorImage(err)
+	}
+	if up.Image != "" {
+		up.ImageType = l.imageType()
+	}
+
+	l.updatesChan <- up
+	return up.Image, up.Err
+}
+
+// RenderPreview renders the applet with ctx and config for a preview that's
+// only meant for whoever asked for it, such as one with mocked HTTP
+// responses. Unlike LoadAppletContext, it doesn't make config the last
+// config, send an update, or replace the frames Preview returns.
+func (l *Loader) RenderPreview(ctx context.Context, config map[string]string) (string, error) {
+	pool, err := l.currentPool()
+	if err == nil {
+		var img string
+		if img, err = l.render(ctx, pool, config, false); err == nil {
+			return img, nil
+		}
+	}
+	logging.ForApp(l.currentAppID()).Error("error rendering preview", "error", err)
+	return l.errorImage(err), err
 }
 
 func (l *Loader) GetSchema() []byte {
 	<-l.initialLoad
 
-	s := l.applet.SchemaJSON
-	if len(s) > 0 {
-		return s
+	if pool, err := l.currentPool(); err == nil {
+		if s := pool.Applet().SchemaJSON; len(s) > 0 {
+			return s
+		}
 	}
 
 	b, _ := json.Marshal(&schema.Schema{})
 	return b
 }
 
-func (l *Loader) CallSchemaHandler(ctx context.Context, handlerName, parameter string) (string, error) {
+// GetLocalizedSchema returns the schema translated to locale, see
+// schema.Schema.Localize.
+func (l *Loader) GetLocalizedSchema(locale string) ([]byte, error) {
 	<-l.initialLoad
-	return l.applet.CallSchemaHandler(ctx, handlerName, parameter)
+
+	s := &schema.Schema{}
+	if pool, err := l.currentPool(); err == nil && pool.Applet().Schema != nil {
+		s = pool.Applet().Schema
+	}
+
+	localized, err := s.Localize(locale)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(localized)
 }
 
-func (l *Loader) loadApplet(config map[string]string) (string, error) {
-	if l.watch {
-		app, err := loadScript("app-id", l.fs)
-		l.markInitialLoadComplete()
-		if err != nil {
-			return "", err
-		} else {
-			l.applet = *app
+// GetJSONSchema returns the schema converted to JSON Schema, see
+// schema.Schema.JSONSchema.
+func (l *Loader) GetJSONSchema() ([]byte, error) {
+	<-l.initialLoad
+
+	s := &schema.Schema{}
+	if pool, err := l.currentPool(); err == nil && pool.Applet().Schema != nil {
+		s = pool.Applet().Schema
+	}
+	return json.Marshal(s.JSONSchema())
+}
+
+// ResolveSchema returns the schema with Generated fields resolved for config,
+// translated to locale if it isn't empty. See runtime.Applet.ResolveSchema.
+func (l *Loader) ResolveSchema(ctx context.Context, config map[string]string, locale string) ([]byte, error) {
+	<-l.initialLoad
+
+	pool, err := l.currentPool()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := pool.ResolveSchema(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if locale != "" {
+		if s, err = s.Localize(locale); err != nil {
+			return nil, err
 		}
 	}
+	return json.Marshal(s)
+}
 
-	ctx, _ := context.WithTimeoutCause(
-		context.Background(),
-		time.Duration(l.timeout)*time.Millisecond,
-		fmt.Errorf("timeout after %dms", l.timeout),
-	)
+// StartDeviceAuthorization requests a device code for the OAuth2 field with
+// the given ID, see schema.StartDeviceAuthorization.
+func (l *Loader) StartDeviceAuthorization(ctx context.Context, fieldID string) (*schema.DeviceAuthorization, error) {
+	<-l.initialLoad
 
-	roots, err := l.applet.RunWithConfig(ctx, config)
+	pool, err := l.currentPool()
 	if err != nil {
-		return "", fmt.Errorf("error running script: %w", err)
+		return nil, err
 	}
 
-	screens := encode.ScreensFromRoots(roots)
+	s := pool.Applet().Schema
+	if s == nil {
+		return nil, fmt.Errorf("applet has no schema")
+	}
+	field, ok := s.Field(fieldID)
+	if !ok {
+		return nil, fmt.Errorf("no field with ID %q", fieldID)
+	}
+	return schema.StartDeviceAuthorization(ctx, field)
+}
 
-	maxDuration := l.maxDuration
-	if screens.ShowFullAnimation {
-		maxDuration = 0
+// PrepareUpload checks a file uploaded to the FileUpload field with the given
+// ID and scales it down to the display if it's a larger image, see
+// schema.PrepareUpload.
+func (l *Loader) PrepareUpload(fieldID, value string) (string, error) {
+	<-l.initialLoad
+
+	pool, err := l.currentPool()
+	if err != nil {
+		return "", err
 	}
 
-	var img []byte
-	if l.renderGif {
-		img, err = screens.EncodeGIF(maxDuration)
-	} else {
-		img, err = screens.EncodeWebP(maxDuration)
+	s := pool.Applet().Schema
+	if s == nil {
+		return "", fmt.Errorf("applet has no schema")
+	}
+	field, ok := s.Field(fieldID)
+	if !ok {
+		return "", fmt.Errorf("no field with ID %q", fieldID)
 	}
+	return schema.PrepareUpload(field, value, globals.Width, globals.Height)
+}
+
+// Cache returns the cache used by applets run by this loader, which keeps
+// usage statistics.
+func (l *Loader) Cache() *runtime.StatsCache {
+	return l.cache
+}
+
+// CallSchemaHandler calls a schema handler of the current applet. cursor is
+// passed to paginated Typeahead handlers, and is empty for the first page.
+func (l *Loader) CallSchemaHandler(ctx context.Context, handlerName, parameter, cursor string) (string, error) {
+	<-l.initialLoad
+
+	pool, err := l.currentPool()
 	if err != nil {
-		return "", fmt.Errorf("error rendering: %w", err)
+		return "", err
 	}
-	return base64.StdEncoding.EncodeToString(img), nil
+	return pool.CallSchemaHandlerPage(ctx, handlerName, parameter, cursor)
 }
 
-func (l *Loader) markInitialLoadComplete() {
-	// safely close the l.initialLoad channel to signal that the initial load is complete
-	select {
-	case <-l.initialLoad:
-	default:
-		close(l.initialLoad)
+// MigrateConfig brings config saved with an older config version of the
+// applet's schema up to date, see runtime.Applet.MigrateConfig.
+func (l *Loader) MigrateConfig(ctx context.Context, config map[string]string) (map[string]string, error) {
+	<-l.initialLoad
+
+	pool, err := l.currentPool()
+	if err != nil {
+		return nil, err
 	}
+	return pool.MigrateConfig(ctx, config)
 }
 
-func RenderApplet(path string, config map[string]string, width, height, magnify, maxDuration, timeout int, renderGif, silenceOutput bool) ([]byte, error) {
-	// check if path exists, and whether it is a directory or a file
-	info, err := os.Stat(path)
+// AppID returns the ID in the applet's manifest, or an empty string if it
+// doesn't have one.
+func (l *Loader) AppID() string {
+	f, err := l.fs.Open(manifest.ManifestFileName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		return ""
 	}
+	defer f.Close()
 
-	var fs fs.FS
-	if info.IsDir() {
-		fs = os.DirFS(path)
-	} else {
-		if !strings.HasSuffix(path, ".star") {
-			return nil, fmt.Errorf("script file must have suffix .star: %s", path)
-		}
+	m, err := manifest.LoadManifest(f)
+	if err != nil {
+		return ""
+	}
+	return m.ID
+}
 
-		fs = tools.NewSingleFileFS(path)
+// SetAppletOptions sets options the applet is loaded with, such as probes
+// for debugging it. It's loaded again with them when it's next rendered.
+func (l *Loader) SetAppletOptions(opts ...runtime.AppletOption) {
+	l.mutex.Lock()
+	l.appletOptions = opts
+	l.pool = nil
+	l.mutex.Unlock()
+}
+
+// EnableErrorFrames makes renders that fail produce an error frame, showing
+// title, the error and the time, in place of an image. The error is still
+// returned and sent out with it.
+func (l *Loader) EnableErrorFrames(title string) {
+	l.mutex.Lock()
+	l.errorFrames = title
+	l.mutex.Unlock()
+}
+
+// OnLoad calls fn with the files the applet loaded each time it's loaded
+// successfully, such as the libraries it loads with load(), as paths in its
+// filesystem, so that they can be watched for changes too.
+func (l *Loader) OnLoad(fn func(paths []string)) {
+	l.mutex.Lock()
+	l.onLoad = fn
+	l.mutex.Unlock()
+}
+
+// errorImage returns a base64 encoded error frame for err, or nothing if
+// error frames aren't enabled or it can't be rendered.
+func (l *Loader) errorImage(err error) string {
+	l.mutex.RLock()
+	title := l.errorFrames
+	l.mutex.RUnlock()
+	if title == "" {
+		return ""
 	}
 
-	if width > 0 {
-		globals.Width = width
+	format := lib.FormatWebP
+	if l.renderGif {
+		format = lib.FormatGIF
 	}
-	if height > 0 {
-		globals.Height = height
+	img, err := lib.RenderError(title, err, time.Now(), lib.RenderOptions{
+		Format:  format,
+		Filters: l.displayFilters(),
+	})
+	if err != nil {
+		logging.ForApp(l.currentAppID()).Error("error rendering error frame", "error", err)
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// reload loads a fresh pool of applet instances from the filesystem.
+func (l *Loader) reload() (*runtime.AppletPool, error) {
+	l.mutex.RLock()
+	opts := l.appletOptions
+	l.mutex.RUnlock()
+
+	// the ID namespaces what the applet caches, which other apps served
+	// with the same cache mustn't see
+	id := l.AppID()
+	if id == "" {
+		id = defaultAppID
+	}
+	l.mutex.Lock()
+	l.appID = id
+	l.mutex.Unlock()
+
+	_, span := tracing.Start(context.Background(), "applet.load", tracing.AppIDKey.String(id))
+	pool, err := runtime.NewAppletPool(l.poolSize, func() (*runtime.Applet, error) {
+		return loadScript(id, l.fs, opts...)
+	})
+	tracing.End(span, err)
+	defer l.markInitialLoadComplete()
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Applet().CheckCapabilities(hostCapabilities()); err != nil {
+		return nil, err
+	}
+
+	l.mutex.Lock()
+	l.pool = pool
+	onLoad := l.onLoad
+	l.mutex.Unlock()
+
+	if onLoad != nil {
+		onLoad(pool.Applet().PathsForBundle())
+	}
+
+	return pool, nil
+}
+
+// hostCapabilities is what the loader offers the applets it runs. They can
+// make requests, but not decrypt secrets, since it has no key for them, and
+// they're rendered at the size of the display.
+func hostCapabilities() runtime.Capabilities {
+	return runtime.Capabilities{Network: true, Width: globals.Width, Height: globals.Height}
+}
+
+// currentAppID returns the ID the applet was last loaded with.
+func (l *Loader) currentAppID() string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.appID
+}
+
+// currentPool returns the loaded applet pool. If no applet has been loaded
+// successfully yet, it tries loading it again.
+func (l *Loader) currentPool() (*runtime.AppletPool, error) {
+	l.mutex.RLock()
+	pool := l.pool
+	l.mutex.RUnlock()
+
+	if pool != nil {
+		return pool, nil
 	}
-	if magnify == 0 {
-		magnify = 1
+	return l.reload()
+}
+
+func (l *Loader) setConfig(config map[string]string) {
+	l.mutex.Lock()
+	l.config = config
+	l.mutex.Unlock()
+
+	if l.configOutFile != "" {
+		byteSlice, err := json.Marshal(config)
+		if err != nil {
+			panic(err)
+		}
+
+		// Write the byte slice to the file.
+		if err := os.WriteFile(l.configOutFile, byteSlice, 0644); err != nil {
+			logging.ForApp(l.currentAppID()).Error("error saving config", "path", l.configOutFile, "error", err)
+		}
 	}
+}
+
+// Config returns the config the applet was last rendered with.
+func (l *Loader) Config() map[string]string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.config
+}
+
+// Display returns how renders are currently shown.
+func (l *Loader) Display() DisplayState {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.display
+}
+
+// SetDisplay changes how renders are shown, and renders the applet again
+// with the last config so that the change is visible right away.
+func (l *Loader) SetDisplay(d DisplayState) (string, error) {
+	d.Brightness = min(max(d.Brightness, 0), 100)
 
-	// Remove the print function from the starlark thread if the silent flag is
-	// passed.
-	var opts []runtime.AppletOption
-	if silenceOutput {
-		opts = append(opts, runtime.WithPrintDisabled())
+	l.mutex.Lock()
+	l.display = d
+	l.mutex.Unlock()
+
+	return l.LoadApplet(l.Config())
+}
+
+// displayFilters returns the filters that apply the display state to
+// rendered images.
+func (l *Loader) displayFilters() []encode.ImageFilter {
+	d := l.Display()
+	switch {
+	case !d.On:
+		return []encode.ImageFilter{encode.Brightness(0)}
+	case d.Brightness < 100:
+		return []encode.ImageFilter{encode.Brightness(d.Brightness)}
+	default:
+		return nil
 	}
+}
 
-	ctx := context.Background()
-	if timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeoutCause(
-			ctx,
-			time.Duration(timeout)*time.Millisecond,
-			fmt.Errorf("timeout after %d ms", timeout),
-		)
-		defer cancel()
+func (l *Loader) imageType() string {
+	if l.renderGif {
+		return "gif"
 	}
+	return "webp"
+}
 
-	applet, err := runtime.NewAppletFromFS(filepath.Base(path), fs, opts...)
+// render renders the applet with config, and if keep is set, keeps its
+// frames as the preview.
+func (l *Loader) render(ctx context.Context, pool *runtime.AppletPool, config map[string]string, keep bool) (_ string, err error) {
+	var img []byte
+	err = l.run(ctx, pool, config, func(ctx context.Context, screens *encode.Screens, maxDuration int) error {
+		var err error
+		filters := l.displayFilters()
+		img, err = encodeScreens(ctx, screens, l.renderGif, maxDuration, filters...)
+		if err != nil {
+			return fmt.Errorf("error rendering: %w", err)
+		}
+		runtime.UsageMeterFromContext(ctx).AddOutputBytes(len(img))
+		if !keep {
+			return nil
+		}
+
+		// frames were painted for encoding, so this only filters them
+		frames, delays, err := screens.Frames(maxDuration, filters...)
+		if err != nil {
+			return fmt.Errorf("error rendering: %w", err)
+		}
+		l.mutex.Lock()
+		l.preview = &Preview{Frames: frames, Delays: delays}
+		l.mutex.Unlock()
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to load applet: %w", err)
+		return "", err
 	}
+	return base64.StdEncoding.EncodeToString(img), nil
+}
 
-	roots, err := applet.RunWithConfig(ctx, config)
+// Preview returns the animation last rendered for the preview, or nil if
+// there hasn't been one yet.
+func (l *Loader) Preview() *Preview {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.preview
+}
+
+// RenderFrame renders the applet with config, or with the last config if
+// it's empty, as if it were time at, and returns the frame of the animation
+// that a display looping it would show at that time. Unlike LoadApplet, it
+// doesn't send an update.
+func (l *Loader) RenderFrame(config map[string]string, at time.Time) (image.Image, error) {
+	<-l.initialLoad
+
+	pool, err := l.currentPool()
 	if err != nil {
-		return nil, fmt.Errorf("error running script: %w", err)
+		return nil, err
+	}
+	if len(config) == 0 {
+		config = l.Config()
 	}
-	screens := encode.ScreensFromRoots(roots)
 
-	filter := func(input image.Image) (image.Image, error) {
-		if magnify <= 1 {
-			return input, nil
+	var frame image.Image
+	err = l.run(runtime.WithRenderTime(context.Background(), at), pool, config, func(ctx context.Context, screens *encode.Screens, maxDuration int) error {
+		var err error
+		frame, err = screens.FrameAt(time.Duration(at.UnixMilli())*time.Millisecond, maxDuration, l.displayFilters()...)
+		if err != nil {
+			return fmt.Errorf("error rendering: %w", err)
 		}
-		in, ok := input.(*image.RGBA)
-		if !ok {
-			return nil, fmt.Errorf("image not RGBA, very weird")
+		return nil
+	})
+	if err == nil && frame == nil {
+		err = fmt.Errorf("applet rendered nothing")
+	}
+	return frame, err
+}
+
+// InspectTree renders the applet with config, or with the last config if
+// it's empty, and returns where the widgets of each of its roots are laid out
+// in frame frameIdx of the root, for debugging layouts. Unlike LoadApplet, it
+// doesn't send an update.
+func (l *Loader) InspectTree(config map[string]string, frameIdx int) ([]*render.Node, error) {
+	<-l.initialLoad
+
+	pool, err := l.currentPool()
+	if err != nil {
+		return nil, err
+	}
+	if len(config) == 0 {
+		config = l.Config()
+	}
+
+	trees := []*render.Node{}
+	err = l.run(context.Background(), pool, config, func(ctx context.Context, screens *encode.Screens, maxDuration int) error {
+		trees = append(trees, screens.Inspect(frameIdx)...)
+		return nil
+	})
+	return trees, err
+}
+
+// Diagnose renders the applet with ctx and config, or with the last config
+// if it's empty, and reports where the time painting and encoding it went.
+// Unlike LoadApplet, it doesn't send an update.
+func (l *Loader) Diagnose(ctx context.Context, config map[string]string) (*lib.Diagnostics, error) {
+	<-l.initialLoad
+
+	pool, err := l.currentPool()
+	if err != nil {
+		return nil, err
+	}
+	if len(config) == 0 {
+		config = l.Config()
+	}
+
+	var d *lib.Diagnostics
+	err = l.run(ctx, pool, config, func(ctx context.Context, screens *encode.Screens, maxDuration int) error {
+		start := time.Now()
+		if _, err := encodeScreens(ctx, screens, l.renderGif, maxDuration); err != nil {
+			return fmt.Errorf("error rendering: %w", err)
 		}
+		d = &lib.Diagnostics{EncodeTime: time.Since(start), Paint: screens.Profile()}
+		d.FrameCount = len(d.Paint.Frames)
+		return nil
+	})
+	return d, err
+}
+
+// RenderImage renders the applet with config, or with the last config if
+// it's empty, as a GIF or WebP image, applying filters after those of the
+// display state. Unlike LoadApplet, it doesn't send an update.
+func (l *Loader) RenderImage(config map[string]string, renderGif bool, filters ...encode.ImageFilter) ([]byte, error) {
+	<-l.initialLoad
 
-		out := image.NewRGBA(
-			image.Rect(
-				0, 0,
-				in.Bounds().Dx()*magnify,
-				in.Bounds().Dy()*magnify),
-		)
-		for x := range in.Bounds().Dx() {
-			for y := range in.Bounds().Dy() {
-				for xx := range magnify {
-					for yy := 0; yy < magnify; yy++ {
-						out.SetRGBA(
-							x*magnify+xx,
-							y*magnify+yy,
-							in.RGBAAt(x, y),
-						)
-					}
-				}
-			}
+	pool, err := l.currentPool()
+	if err != nil {
+		return nil, err
+	}
+	if len(config) == 0 {
+		config = l.Config()
+	}
+
+	var img []byte
+	err = l.run(context.Background(), pool, config, func(ctx context.Context, screens *encode.Screens, maxDuration int) error {
+		var err error
+		img, err = encodeScreens(ctx, screens, renderGif, maxDuration, append(l.displayFilters(), filters...)...)
+		if err != nil {
+			return fmt.Errorf("error rendering: %w", err)
 		}
+		return nil
+	})
+	return img, err
+}
+
+// run runs the applet with config under the loader's timeout, and passes
+// the resulting screens to fn to be encoded.
+func (l *Loader) run(
+	ctx context.Context,
+	pool *runtime.AppletPool,
+	config map[string]string,
+	fn func(ctx context.Context, screens *encode.Screens, maxDuration int) error,
+) (err error) {
+	appID := l.currentAppID()
+	ctx, span := tracing.Start(ctx, "pixlet.render", tracing.AppIDKey.String(appID))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithTimeoutCause(
+		ctx,
+		time.Duration(l.timeout)*time.Millisecond,
+		fmt.Errorf("timeout after %dms", l.timeout),
+	)
+	defer cancel()
 
-		return out, nil
+	ctx, meter := runtime.MeterUsage(ctx)
+	defer func() { runtime.DefaultUsageReport.Record(appID, meter.Stats(), err) }()
+
+	roots, err := pool.RunWithConfig(ctx, config)
+	if err != nil {
+		return fmt.Errorf("error running script: %w", err)
 	}
 
-	var buf []byte
+	screens := encode.ScreensFromRoots(roots).WithContext(ctx)
 
+	maxDuration := l.maxDuration
 	if screens.ShowFullAnimation {
 		maxDuration = 0
 	}
 
+	if err := fn(ctx, screens, maxDuration); err != nil {
+		return err
+	}
+	if screens.Truncated {
+		logging.ForApp(appID).Warn("render timed out, animation truncated", "error", context.Cause(ctx))
+	}
+	return nil
+}
+
+// encodeScreens encodes screens as a GIF or WebP image, recording the work
+// in a tracing span.
+func encodeScreens(ctx context.Context, screens *encode.Screens, renderGif bool, maxDuration int, filters ...encode.ImageFilter) (buf []byte, err error) {
+	format := "webp"
 	if renderGif {
-		buf, err = screens.EncodeGIF(maxDuration, filter)
-	} else {
-		buf, err = screens.EncodeWebP(maxDuration, filter)
+		format = "gif"
 	}
+
+	start := time.Now()
+	_, span := tracing.Start(ctx, "encode", tracing.FormatKey.String(format))
+	defer func() {
+		runtime.UsageMeterFromContext(ctx).AddEncodeTime(time.Since(start))
+		span.SetAttributes(tracing.BytesKey.Int(len(buf)))
+		tracing.End(span, err)
+	}()
+
+	if renderGif {
+		return screens.EncodeGIF(maxDuration, filters...)
+	}
+	return screens.EncodeWebP(maxDuration, filters...)
+}
+
+func (l *Loader) markInitialLoadComplete() {
+	// safely close the l.initialLoad channel to signal that the initial load is complete
+	l.initialLoadOnce.Do(func() {
+		close(l.initialLoad)
+	})
+}
+
+// RenderApplet loads the applet at path and renders it with config, or with
+// the applet's default config if config is empty, applying filters to every
+// frame, and interpolating frames by a factor of interpolate if it's more
+// than 1. It's a thin wrapper around the lib package for pixlet's own
+// commands.
+func RenderApplet(path string, config map[string]string, width, height, magnify, maxDuration, timeout, interpolate int, renderGif, silenceOutput, deterministic bool, filters ...encode.ImageFilter) ([]byte, error) {
+	format := lib.FormatWebP
+	if renderGif {
+		format = lib.FormatGIF
+	}
+
+	img, err := RenderAppletImage(path, config, lib.LoadOptions{
+		SilencePrint:  silenceOutput,
+		Deterministic: deterministic,
+	}, lib.RenderOptions{
+		Width:       width,
+		Height:      height,
+		Magnify:     magnify,
+		Format:      format,
+		MaxDuration: time.Duration(maxDuration) * time.Millisecond,
+		Timeout:     time.Duration(timeout) * time.Millisecond,
+		Filters:     filters,
+		Interpolate: interpolate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return img.Data, nil
+}
+
+// RenderAppletImage loads the applet at path and renders it like
+// RenderApplet, but with lib's options, and returns the whole image.
+func RenderAppletImage(path string, config map[string]string, load lib.LoadOptions, opts lib.RenderOptions) (*lib.EncodedImage, error) {
+	applet, err := lib.LoadAppletFromPath(path, load)
 	if err != nil {
-		return nil, fmt.Errorf("error rendering: %w", err)
+		return nil, err
+	}
+
+	if len(config) == 0 {
+		config = applet.DefaultConfig()
 	}
 
-	return buf, nil
+	return applet.Render(context.Background(), config, opts)
 }
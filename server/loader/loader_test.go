@@ -0,0 +1,79 @@
+package loader
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func TestLoadRequirements(t *testing.T) {
+	load := func(files fstest.MapFS) error {
+		_, err := NewLoader(files, false, nil, make(chan Update, 1), 15000, 30000, false, "", 1, nil)
+		return err
+	}
+	app := func(requires string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(`
+load("render.star", "render")
+
+REQUIRES = ` + requires + `
+
+def main():
+    return render.Root(child = render.Box())
+`)}
+	}
+
+	require.NoError(t, load(fstest.MapFS{"app.star": app(`["network", "64x32"]`)}))
+
+	// there's no key to decrypt secrets with, and the display is 64x32
+	err := load(fstest.MapFS{"app.star": app(`["secrets", "128x64"]`)})
+	var capErr *runtime.CapabilityError
+	require.ErrorAs(t, err, &capErr)
+	assert.Equal(t, []string{"secrets", "128x64"}, capErr.Missing)
+
+	// requirements can come from the manifest too
+	err = load(fstest.MapFS{
+		"app.star":      app(`[]`),
+		"manifest.yaml": {Data: []byte("id: app\ncapabilities:\n  secrets:\n    - api_key\n")},
+	})
+	require.ErrorAs(t, err, &capErr)
+	assert.Equal(t, []string{"secrets"}, capErr.Missing)
+}
+
+func TestRenderPreview(t *testing.T) {
+	updates := make(chan Update, 1)
+	l, err := NewLoader(fstest.MapFS{"app.star": {Data: []byte(`
+load("render.star", "render")
+
+def main(config):
+    return render.Root(child = render.Text(config.get("who", "world")))
+`)}}, false, nil, updates, 15000, 30000, false, "", 1, nil)
+	require.NoError(t, err)
+
+	img, err := l.RenderPreview(context.Background(), map[string]string{"who": "Mallory"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, img)
+
+	// the preview is neither kept as the last config or frames, nor sent
+	// out
+	assert.Empty(t, l.Config())
+	assert.Nil(t, l.Preview())
+	assert.Empty(t, updates)
+
+	_, err = l.LoadApplet(map[string]string{"who": "Ada"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"who": "Ada"}, l.Config())
+	assert.Len(t, updates, 1)
+	<-updates
+
+	preview := l.Preview()
+	require.NotNil(t, preview)
+
+	_, err = l.RenderPreview(context.Background(), map[string]string{"who": "Mallory"})
+	require.NoError(t, err)
+	assert.Same(t, preview, l.Preview())
+}
@@ -6,6 +6,6 @@ import (
 	"tidbyt.dev/pixlet/runtime"
 )
 
-func loadScript(appID string, fs fs.FS) (*runtime.Applet, error) {
-	return runtime.NewAppletFromFS(appID, fs)
+func loadScript(appID string, fs fs.FS, opts ...runtime.AppletOption) (*runtime.Applet, error) {
+	return runtime.NewAppletFromFS(appID, fs, opts...)
 }
@@ -0,0 +1,287 @@
+// Package mdns advertises pixlet serve on the local network with multicast
+// DNS service discovery, so that devices and companion apps can find it
+// without being told its address.
+package mdns
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ServiceType is the DNS-SD service type pixlet serve advertises itself as.
+const ServiceType = "_pixlet._tcp"
+
+const (
+	domain = "local."
+	ttl    = 120
+
+	// cacheFlush is set in the class of records only this responder
+	// answers for, telling caches to replace what they have.
+	cacheFlush = 1 << 15
+)
+
+var (
+	groupV4        = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+	servicesBrowse = "_services._dns-sd._udp." + domain
+)
+
+// Service describes an instance of pixlet serve.
+type Service struct {
+	// Instance is the name the service is listed under, such as the name
+	// of the app being served.
+	Instance string
+
+	// Port is the port the server listens on.
+	Port int
+
+	// TXT records describe the service, as key=value pairs.
+	TXT []string
+
+	// Host is the host name the server is advertised at, without the
+	// .local suffix. It defaults to the machine's host name.
+	Host string
+
+	// IPs are the addresses the server is advertised at. They default to
+	// the addresses of the machine's network interfaces.
+	IPs []net.IP
+}
+
+// Responder answers mDNS queries for a service.
+type Responder struct {
+	service  Service
+	conn     *net.UDPConn
+	instance dnsmessage.Name
+	typ      dnsmessage.Name
+	host     dnsmessage.Name
+	log      *slog.Logger
+}
+
+// NewResponder starts listening for mDNS queries on the network, to be
+// answered once Run is called.
+func NewResponder(s Service) (*Responder, error) {
+	if s.Host == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("getting host name: %w", err)
+		}
+		s.Host = strings.SplitN(host, ".", 2)[0]
+	}
+	if len(s.IPs) == 0 {
+		s.IPs = interfaceIPs()
+	}
+
+	r, err := newResponder(s)
+	if err != nil {
+		return nil, err
+	}
+
+	r.conn, err = net.ListenMulticastUDP("udp4", nil, groupV4)
+	if err != nil {
+		return nil, fmt.Errorf("listening for mDNS queries: %w", err)
+	}
+	return r, nil
+}
+
+func newResponder(s Service) (*Responder, error) {
+	r := &Responder{
+		service: s,
+		log:     slog.Default().With("component", "mdns", "instance", s.Instance),
+	}
+
+	var err error
+	if r.instance, err = dnsmessage.NewName(label(s.Instance) + "." + ServiceType + "." + domain); err != nil {
+		return nil, fmt.Errorf("invalid instance name %q: %w", s.Instance, err)
+	}
+	if r.host, err = dnsmessage.NewName(label(s.Host) + "." + domain); err != nil {
+		return nil, fmt.Errorf("invalid host name %q: %w", s.Host, err)
+	}
+	r.typ = dnsmessage.MustNewName(ServiceType + "." + domain)
+	return r, nil
+}
+
+// Run announces the service and answers queries for it until ctx is done,
+// when it sends a goodbye so that browsers forget it right away.
+func (r *Responder) Run(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		r.send(r.records(0), nil, groupV4)
+		r.conn.Close()
+	}()
+
+	// announce twice, a second apart, as RFC 6762 section 8.3 asks
+	go func() {
+		for i := 0; i < 2; i++ {
+			r.send(r.records(ttl), nil, groupV4)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("reading mDNS query: %w", err)
+		}
+
+		answers, additionals, unicast := r.answer(buf[:n])
+		if len(answers) == 0 {
+			continue
+		}
+
+		to := groupV4
+		if unicast || from.Port != groupV4.Port {
+			to = from
+		}
+		r.send(answers, additionals, to)
+	}
+}
+
+// answer returns the records that answer the questions in query, the
+// records the querier is likely to ask for next, and whether it asked for a
+// unicast response.
+func (r *Responder) answer(query []byte) (answers, additionals []dnsmessage.Resource, unicast bool) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil || h.Response {
+		return nil, nil, false
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return nil, nil, false
+	}
+
+	all := r.records(ttl)
+	for _, q := range questions {
+		if q.Class&cacheFlush != 0 {
+			// the top bit of the class asks for a unicast response
+			unicast = true
+		}
+
+		if strings.EqualFold(q.Name.String(), servicesBrowse) && (q.Type == dnsmessage.TypePTR || q.Type == dnsmessage.TypeALL) {
+			answers = append(answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{
+					Name:  dnsmessage.MustNewName(servicesBrowse),
+					Type:  dnsmessage.TypePTR,
+					Class: dnsmessage.ClassINET,
+					TTL:   ttl,
+				},
+				Body: &dnsmessage.PTRResource{PTR: r.typ},
+			})
+			continue
+		}
+
+		for _, rr := range all {
+			if strings.EqualFold(rr.Header.Name.String(), q.Name.String()) && (q.Type == rr.Header.Type || q.Type == dnsmessage.TypeALL) {
+				answers = append(answers, rr)
+			}
+		}
+	}
+
+	// browsers that find the instance want its address and port next
+	for _, rr := range all {
+		if !slices.Contains(answers, rr) && (rr.Header.Name == r.instance || rr.Header.Name == r.host) {
+			additionals = append(additionals, rr)
+		}
+	}
+	return answers, additionals, unicast
+}
+
+// records returns all of the service's records, with the given TTL. A TTL
+// of zero says goodbye.
+func (r *Responder) records(ttl uint32) []dnsmessage.Resource {
+	header := func(name dnsmessage.Name, typ dnsmessage.Type, flush bool) dnsmessage.ResourceHeader {
+		class := dnsmessage.ClassINET
+		if flush {
+			class |= cacheFlush
+		}
+		return dnsmessage.ResourceHeader{Name: name, Type: typ, Class: class, TTL: ttl}
+	}
+
+	txt := r.service.TXT
+	if len(txt) == 0 {
+		// a TXT record must have at least one string
+		txt = []string{""}
+	}
+
+	records := []dnsmessage.Resource{
+		{Header: header(r.typ, dnsmessage.TypePTR, false), Body: &dnsmessage.PTRResource{PTR: r.instance}},
+		{Header: header(r.instance, dnsmessage.TypeSRV, true), Body: &dnsmessage.SRVResource{Target: r.host, Port: uint16(r.service.Port)}},
+		{Header: header(r.instance, dnsmessage.TypeTXT, true), Body: &dnsmessage.TXTResource{TXT: txt}},
+	}
+	for _, ip := range r.service.IPs {
+		if ip4 := ip.To4(); ip4 != nil {
+			records = append(records, dnsmessage.Resource{
+				Header: header(r.host, dnsmessage.TypeA, true),
+				Body:   &dnsmessage.AResource{A: [4]byte(ip4)},
+			})
+		} else if ip16 := ip.To16(); ip16 != nil {
+			records = append(records, dnsmessage.Resource{
+				Header: header(r.host, dnsmessage.TypeAAAA, true),
+				Body:   &dnsmessage.AAAAResource{AAAA: [16]byte(ip16)},
+			})
+		}
+	}
+	return records
+}
+
+func (r *Responder) send(answers, additionals []dnsmessage.Resource, to *net.UDPAddr) {
+	msg := dnsmessage.Message{
+		Header:      dnsmessage.Header{Response: true, Authoritative: true},
+		Answers:     answers,
+		Additionals: additionals,
+	}
+	packet, err := msg.Pack()
+	if err != nil {
+		r.log.Error("packing mDNS response", "error", err)
+		return
+	}
+	if _, err := r.conn.WriteToUDP(packet, to); err != nil {
+		r.log.Debug("sending mDNS response", "error", err)
+	}
+}
+
+// label makes s usable as a single DNS label.
+func label(s string) string {
+	return strings.NewReplacer(".", "-", " ", "-").Replace(s)
+}
+
+// interfaceIPs returns the addresses of the network interfaces that are up,
+// other than loopback ones.
+func interfaceIPs() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var ips []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && !n.IP.IsLinkLocalUnicast() {
+				ips = append(ips, n.IP)
+			}
+		}
+	}
+	return ips
+}
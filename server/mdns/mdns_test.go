@@ -0,0 +1,67 @@
+package mdns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func query(t *testing.T, name string, typ dnsmessage.Type, class dnsmessage.Class) []byte {
+	msg := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName(name),
+			Type:  typ,
+			Class: class,
+		}},
+	}
+	b, err := msg.Pack()
+	require.NoError(t, err)
+	return b
+}
+
+func testResponder(t *testing.T) *Responder {
+	r, err := newResponder(Service{
+		Instance: "hello.world",
+		Port:     8080,
+		TXT:      []string{"app=hello.world", "path=/"},
+		Host:     "workshop",
+		IPs:      []net.IP{net.ParseIP("192.168.1.10")},
+	})
+	require.NoError(t, err)
+	return r
+}
+
+func TestBrowse(t *testing.T) {
+	r := testResponder(t)
+
+	answers, additionals, unicast := r.answer(query(t, "_pixlet._tcp.local.", dnsmessage.TypePTR, dnsmessage.ClassINET))
+	assert.False(t, unicast)
+	require.Len(t, answers, 1)
+	assert.Equal(t, "hello-world._pixlet._tcp.local.", answers[0].Body.(*dnsmessage.PTRResource).PTR.String())
+
+	require.Len(t, additionals, 3)
+	srv := additionals[0].Body.(*dnsmessage.SRVResource)
+	assert.Equal(t, uint16(8080), srv.Port)
+	assert.Equal(t, "workshop.local.", srv.Target.String())
+	assert.Equal(t, []string{"app=hello.world", "path=/"}, additionals[1].Body.(*dnsmessage.TXTResource).TXT)
+	assert.Equal(t, [4]byte{192, 168, 1, 10}, additionals[2].Body.(*dnsmessage.AResource).A)
+}
+
+func TestResolve(t *testing.T) {
+	r := testResponder(t)
+
+	answers, _, unicast := r.answer(query(t, "workshop.local.", dnsmessage.TypeA, dnsmessage.ClassINET|cacheFlush))
+	assert.True(t, unicast)
+	require.Len(t, answers, 1)
+	assert.Equal(t, dnsmessage.TypeA, answers[0].Header.Type)
+
+	answers, _, _ = r.answer(query(t, "_services._dns-sd._udp.local.", dnsmessage.TypePTR, dnsmessage.ClassINET))
+	require.Len(t, answers, 1)
+	assert.Equal(t, "_pixlet._tcp.local.", answers[0].Body.(*dnsmessage.PTRResource).PTR.String())
+
+	answers, _, _ = r.answer(query(t, "_http._tcp.local.", dnsmessage.TypePTR, dnsmessage.ClassINET))
+	assert.Empty(t, answers)
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"tidbyt.dev/pixlet/mqtt"
+)
+
+// mqttPublisher publishes renders to an MQTT topic as retained messages, so
+// that subscribers get the latest render as soon as they subscribe.
+type mqttPublisher struct {
+	broker  *url.URL
+	topic   string
+	client  *mqtt.Client
+	pending chan []byte
+	log     *slog.Logger
+}
+
+// newMQTTPublisher returns a publisher for target, an MQTT URL whose path is
+// the topic to publish to, such as mqtt://broker.local/pixlet/clock.
+func newMQTTPublisher(target string) (*mqttPublisher, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("parsing publish URL: %w", err)
+	}
+	if u.Scheme != "mqtt" && u.Scheme != "mqtts" {
+		return nil, fmt.Errorf("unsupported publish URL %q, expected mqtt:// or mqtts://", target)
+	}
+
+	topic := strings.Trim(u.Path, "/")
+	if topic == "" {
+		return nil, fmt.Errorf("publish URL %q has no topic, such as mqtt://broker/pixlet/app", target)
+	}
+
+	return &mqttPublisher{
+		broker:  u,
+		topic:   topic,
+		pending: make(chan []byte, 1),
+		log:     slog.Default().With("component", "publish", "topic", topic),
+	}, nil
+}
+
+// Send queues img to be published. If the previous render hasn't been
+// published yet, it's replaced, since only the latest one is retained
+// anyway. Send must not be called concurrently.
+func (p *mqttPublisher) Send(img []byte) {
+	select {
+	case <-p.pending:
+	default:
+	}
+	p.pending <- img
+}
+
+// Run publishes queued renders forever, connecting to the broker when
+// needed.
+func (p *mqttPublisher) Run() error {
+	for img := range p.pending {
+		if err := p.publish(img); err != nil {
+			p.log.Error("publishing render", "error", err)
+		}
+	}
+	return nil
+}
+
+func (p *mqttPublisher) publish(img []byte) error {
+	if p.client != nil {
+		select {
+		case <-p.client.Done():
+			p.client = nil
+		default:
+		}
+	}
+
+	if p.client == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		c, err := mqtt.Dial(ctx, p.broker, mqtt.Options{})
+		if err != nil {
+			return err
+		}
+		p.client = c
+	}
+
+	return p.client.Publish(p.topic, img, true)
+}
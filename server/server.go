@@ -1,16 +1,25 @@
 package server
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"golang.org/x/sync/errgroup"
+	"tidbyt.dev/pixlet/runtime"
 	"tidbyt.dev/pixlet/server/browser"
+	"tidbyt.dev/pixlet/server/homeassistant"
 	"tidbyt.dev/pixlet/server/loader"
+	"tidbyt.dev/pixlet/server/mdns"
 	"tidbyt.dev/pixlet/tools"
+	"tidbyt.dev/pixlet/tools/dap"
 )
 
 // Server provides functionality to serve Starlark over HTTP. It has
@@ -20,10 +29,30 @@ type Server struct {
 	browser *browser.Browser
 	loader  *loader.Loader
 	watch   bool
+	name    string
+	dir     string
+
+	// renders carries updates from the loader, which are forwarded to
+	// the browser over updates and to the publisher, if any.
+	renders chan loader.Update
+	updates chan loader.Update
+
+	host      string
+	port      int
+	servePath string
+	format    string
+
+	homeAssistant   *homeassistant.Bridge
+	mdns            *mdns.Responder
+	publisher       *mqttPublisher
+	publishInterval time.Duration
+
+	debugger         *dap.Debugger
+	debuggerListener net.Listener
 }
 
 // NewServer creates a new server initialized with the applet.
-func NewServer(host string, port int, servePath string, watch bool, path string, maxDuration int, timeout int, serveGif bool, configOutFile string) (*Server, error) {
+func NewServer(host string, port int, servePath string, watch bool, path string, maxDuration int, timeout int, serveGif bool, configOutFile string, poolSize int, cache runtime.Cache) (*Server, error) {
 	fileChanges := make(chan bool, 100)
 
 	// check if path exists, and whether it is a directory or a file
@@ -34,10 +63,12 @@ func NewServer(host string, port int, servePath string, watch bool, path string,
 
 	var fs fs.FS
 	var w *Watcher
+	dir := path
 	if info.IsDir() {
 		fs = os.DirFS(path)
 		w = NewWatcher(path, fileChanges)
 	} else {
+		dir = filepath.Dir(path)
 		if !strings.HasSuffix(path, ".star") {
 			return nil, fmt.Errorf("script file must have suffix .star: %s", path)
 		}
@@ -46,11 +77,16 @@ func NewServer(host string, port int, servePath string, watch bool, path string,
 		w = NewWatcher(path, fileChanges)
 	}
 
+	renders := make(chan loader.Update, 100)
 	updatesChan := make(chan loader.Update, 100)
-	l, err := loader.NewLoader(fs, watch, fileChanges, updatesChan, maxDuration, timeout, serveGif, configOutFile)
+	l, err := loader.NewLoader(fs, watch, fileChanges, renders, maxDuration, timeout, serveGif, configOutFile, poolSize, cache)
 	if err != nil {
 		return nil, err
 	}
+	if watch {
+		// editing a library the app loads reloads it too
+		l.OnLoad(w.SetDependencies)
+	}
 
 	addr := fmt.Sprintf("%s:%d", host, port)
 	b, err := browser.NewBrowser(addr, servePath, filepath.Base(path), watch, updatesChan, l, false)
@@ -58,20 +94,119 @@ func NewServer(host string, port int, servePath string, watch bool, path string,
 		return nil, err
 	}
 
+	format := "webp"
+	if serveGif {
+		format = "gif"
+	}
+
 	return &Server{
 		watcher: w,
 		browser: b,
 		loader:  l,
 		watch:   watch,
+		name:    strings.TrimSuffix(filepath.Base(path), ".star"),
+		dir:     dir,
+		renders: renders,
+		updates: updatesChan,
+
+		host:      host,
+		port:      port,
+		servePath: servePath,
+		format:    format,
 	}, nil
 }
 
+// EnableMDNS advertises the server on the local network as a
+// mdns.ServiceType service named after the app. TXT records give the app's
+// name, the path it's served on and the image format it's rendered in.
+func (s *Server) EnableMDNS() error {
+	if ip := net.ParseIP(s.host); s.host == "localhost" || ip != nil && ip.IsLoopback() {
+		slog.Warn("advertising over mDNS, but only listening on a loopback address; use --host 0.0.0.0 so that others can connect", "host", s.host)
+	}
+
+	r, err := mdns.NewResponder(mdns.Service{
+		Instance: s.name,
+		Port:     s.port,
+		TXT: []string{
+			"app=" + s.name,
+			"path=" + s.servePath,
+			"format=" + s.format,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	s.mdns = r
+	return nil
+}
+
+// EnablePublish publishes every render to target, an MQTT URL whose path is
+// the topic, as a retained message. If interval isn't zero, the app is also
+// rendered again with the last config that often, so subscribers stay up to
+// date without anyone watching the preview.
+func (s *Server) EnablePublish(target string, interval time.Duration) error {
+	p, err := newMQTTPublisher(target)
+	if err != nil {
+		return err
+	}
+	s.publisher = p
+	s.publishInterval = interval
+	return nil
+}
+
+// EnableHomeAssistant exposes the app to Home Assistant through the MQTT
+// broker at brokerURL, see homeassistant.NewBridge.
+func (s *Server) EnableHomeAssistant(brokerURL string) error {
+	bridge, err := homeassistant.NewBridge(brokerURL, s.name, s.loader)
+	if err != nil {
+		return err
+	}
+	s.homeAssistant = bridge
+	return nil
+}
+
+// EnableErrorFrames shows an error frame, with the app's name, the error and
+// the time, when a render fails, rather than leaving the last image up. It's
+// published like any other render.
+func (s *Server) EnableErrorFrames() {
+	s.loader.EnableErrorFrames(s.name)
+}
+
+// EnableDebugger serves a Debug Adapter Protocol server on addr, for
+// editors to set breakpoints in the app and step through its renders. A
+// render that hits a breakpoint pauses until the editor resumes it.
+func (s *Server) EnableDebugger(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening for debugger: %w", err)
+	}
+
+	s.debugger = dap.NewDebugger(s.dir)
+	s.debuggerListener = l
+	s.loader.SetAppletOptions(runtime.WithProbe(s.debugger))
+	return nil
+}
+
 // Run serves the http server and runs forever in a blocking fashion.
 func (s *Server) Run() error {
 	g := errgroup.Group{}
 
 	g.Go(s.loader.Run)
 	g.Go(s.browser.Run)
+	g.Go(s.forwardUpdates)
+	if s.mdns != nil {
+		g.Go(func() error { return s.mdns.Run(context.Background()) })
+	}
+	if s.publisher != nil {
+		g.Go(s.publisher.Run)
+		g.Go(s.scheduleRenders)
+	}
+	if s.homeAssistant != nil {
+		g.Go(s.homeAssistant.Run)
+	}
+	if s.debugger != nil {
+		g.Go(func() error { return s.debugger.Serve(s.debuggerListener) })
+	}
 	if s.watch {
 		g.Go(s.watcher.Run)
 		s.loader.LoadApplet(make(map[string]string))
@@ -79,3 +214,39 @@ func (s *Server) Run() error {
 
 	return g.Wait()
 }
+
+// forwardUpdates sends renders to the browser, and to the publisher.
+func (s *Server) forwardUpdates() error {
+	for up := range s.renders {
+		s.updates <- up
+
+		if s.publisher == nil || up.Image == "" {
+			continue
+		}
+		img, err := base64.StdEncoding.DecodeString(up.Image)
+		if err != nil {
+			return err
+		}
+		s.publisher.Send(img)
+	}
+	return nil
+}
+
+// scheduleRenders renders the app with the last config every publish
+// interval. Without watch, nothing renders the app on startup, so it also
+// renders it right away.
+func (s *Server) scheduleRenders() error {
+	if !s.watch {
+		s.loader.LoadApplet(s.loader.Config())
+	}
+	if s.publishInterval <= 0 {
+		return nil
+	}
+
+	t := time.NewTicker(s.publishInterval)
+	defer t.Stop()
+	for range t.C {
+		s.loader.LoadApplet(s.loader.Config())
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func init() {
+	Register("file", newFile)
+}
+
+// file writes each image to a file, replacing the last one, for displays
+// and tools that watch a file.
+type file struct {
+	path string
+}
+
+func newFile(cfg Config) (Sink, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	return &file{path: cfg.Path}, nil
+}
+
+func (s *file) Formats() []string {
+	return []string{"webp"}
+}
+
+// Send writes the image to a temporary file first and renames it, so that
+// readers never see half an image.
+func (s *file) Send(ctx context.Context, img Image) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(img.Data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *file) Close() error {
+	return nil
+}
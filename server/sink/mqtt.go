@@ -0,0 +1,78 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"tidbyt.dev/pixlet/mqtt"
+)
+
+func init() {
+	Register("mqtt", newMQTT)
+}
+
+// mqttSink publishes images to an MQTT topic as retained messages, so that
+// subscribers get the latest image as soon as they subscribe. It connects
+// to the broker when it first sends, and again if the connection drops.
+type mqttSink struct {
+	broker *url.URL
+	topic  string
+
+	mutex  sync.Mutex
+	client *mqtt.Client
+}
+
+func newMQTT(cfg Config) (Sink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "mqtt" && u.Scheme != "mqtts") {
+		return nil, fmt.Errorf("url must be an mqtt or mqtts URL, not %q", cfg.URL)
+	}
+
+	topic := strings.Trim(u.Path, "/")
+	if topic == "" {
+		return nil, fmt.Errorf("url %q has no topic, such as mqtt://broker/pixlet/kitchen", cfg.URL)
+	}
+	return &mqttSink{broker: u, topic: topic}, nil
+}
+
+func (s *mqttSink) Formats() []string {
+	return []string{"webp"}
+}
+
+func (s *mqttSink) Send(ctx context.Context, img Image) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.client != nil {
+		select {
+		case <-s.client.Done():
+			s.client = nil
+		default:
+		}
+	}
+
+	if s.client == nil {
+		c, err := mqtt.Dial(ctx, s.broker, mqtt.Options{})
+		if err != nil {
+			return err
+		}
+		s.client = c
+	}
+
+	return s.client.Publish(s.topic, img.Data, true)
+}
+
+func (s *mqttSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Close()
+	s.client = nil
+	return err
+}
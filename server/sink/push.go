@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+
+	"tidbyt.dev/pixlet/tronbyt"
+)
+
+func init() {
+	Register("http", newPush)
+}
+
+// push pushes images to a device through a Tronbyt server or device, or
+// Tidbyt's API, which take the same pushes.
+type push struct {
+	client   *tronbyt.Client
+	deviceID string
+}
+
+func newPush(cfg Config) (Sink, error) {
+	if cfg.URL == "" || cfg.DeviceID == "" {
+		return nil, fmt.Errorf("url and deviceID are required")
+	}
+	return &push{
+		client:   &tronbyt.Client{URL: cfg.URL, APIKey: cfg.APIKey},
+		deviceID: cfg.DeviceID,
+	}, nil
+}
+
+func (s *push) Formats() []string {
+	return []string{"webp", "gif"}
+}
+
+func (s *push) Send(ctx context.Context, img Image) error {
+	return s.client.Push(ctx, s.deviceID, img.Data, tronbyt.PushOptions{})
+}
+
+func (s *push) Close() error {
+	return nil
+}
@@ -0,0 +1,188 @@
+// Package sink delivers the images a scheduler shows to wherever they're
+// needed: pushed to a device or server over HTTP, published over MQTT,
+// written to a file, posted to a webhook, or sent to WebSocket clients.
+//
+// Each kind of sink is registered with a Factory, so new delivery targets
+// can be added without changing the scheduler. Sinks say which image formats
+// they accept, and images are transcoded to one of them before they're sent.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"tidbyt.dev/pixlet/device"
+)
+
+// Sink is somewhere images are delivered to.
+type Sink interface {
+	// Formats returns the image formats the sink accepts, such as "webp",
+	// "gif" or "raw", the one it prefers first.
+	Formats() []string
+
+	// Send delivers an image. It returns an error if it wasn't delivered.
+	Send(ctx context.Context, img Image) error
+
+	// Close releases connections the sink holds.
+	Close() error
+}
+
+// Image is an image to deliver.
+type Image struct {
+	// Data is the encoded image, in Format.
+	Data   []byte
+	Format string
+
+	// DeviceID is the ID of the device the image is for, on the host
+	// delivering it.
+	DeviceID string
+
+	// Dwell is how long the image is meant to be shown for.
+	Dwell time.Duration
+}
+
+// Config configures a sink. Which fields are used depends on its Type.
+type Config struct {
+	// Type is the kind of sink: "http", "mqtt", "file", "webhook",
+	// "websocket", or another registered with Register.
+	Type string `json:"type"`
+
+	// URL is where images are sent: the base URL of a Tronbyt server or
+	// Tidbyt's API for http sinks, the broker and topic for mqtt sinks, such
+	// as mqtt://broker.local/pixlet/kitchen, and the URL posted to for
+	// webhook sinks.
+	URL string `json:"url,omitempty"`
+
+	// DeviceID is the ID of the device on the server http sinks push to.
+	DeviceID string `json:"deviceID,omitempty"`
+
+	// APIKey authenticates http and webhook sinks, as a bearer token.
+	APIKey string `json:"apiKey,omitempty"`
+
+	// Path is the file that file sinks write images to.
+	Path string `json:"path,omitempty"`
+
+	// Formats overrides the image formats the sink accepts, the one it
+	// prefers first.
+	Formats []string `json:"formats,omitempty"`
+}
+
+// Factory creates a sink from its config, returning an error if the config
+// isn't valid. It shouldn't connect to anything yet.
+type Factory func(cfg Config) (Sink, error)
+
+var (
+	factoriesMutex sync.RWMutex
+	factories      = map[string]Factory{}
+)
+
+// Register makes a kind of sink available to New.
+func Register(kind string, f Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+	factories[kind] = f
+}
+
+// Kinds returns the kinds of sink that are registered, sorted.
+func Kinds() []string {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	kinds := make([]string, 0, len(factories))
+	for kind := range factories {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// transcodable are the formats Negotiate can convert images to.
+var transcodable = []string{"webp", "gif", "raw"}
+
+// New creates a sink from its config.
+func New(cfg Config) (Sink, error) {
+	factoriesMutex.RLock()
+	f, ok := factories[cfg.Type]
+	factoriesMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown sink type %q, expected one of %s", cfg.Type, strings.Join(Kinds(), ", "))
+	}
+
+	for _, format := range cfg.Formats {
+		if !slices.Contains(transcodable, format) {
+			return nil, fmt.Errorf("%s sink: unsupported format %q, expected %s", cfg.Type, format, strings.Join(transcodable, ", "))
+		}
+	}
+
+	s, err := f(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s sink: %w", cfg.Type, err)
+	}
+	if len(cfg.Formats) > 0 {
+		s = &withFormats{Sink: s, formats: cfg.Formats}
+	}
+	return s, nil
+}
+
+// withFormats overrides the formats a sink accepts.
+type withFormats struct {
+	Sink
+	formats []string
+}
+
+func (s *withFormats) Formats() []string {
+	return s.formats
+}
+
+// Unwrap returns the sink New created s from, without the formats its
+// config overrides, so that it can be told apart by its type.
+func Unwrap(s Sink) Sink {
+	if w, ok := s.(*withFormats); ok {
+		return w.Sink
+	}
+	return s
+}
+
+// Negotiate returns data in one of formats, with its format: as it is if
+// it's already in one, and otherwise transcoded to the first.
+func Negotiate(data []byte, formats []string) ([]byte, string, error) {
+	format := device.ImageFormat(data)
+	if len(formats) == 0 || slices.Contains(formats, format) {
+		return data, format, nil
+	}
+
+	p := device.Profile{Formats: formats}
+	data, err := p.Transcode(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, p.Format(), nil
+}
+
+// Deliver negotiates a format with s, and sends the image in it.
+func Deliver(ctx context.Context, s Sink, img Image) error {
+	data, format, err := Negotiate(img.Data, s.Formats())
+	if err != nil {
+		return err
+	}
+	img.Data, img.Format = data, format
+	return s.Send(ctx, img)
+}
+
+// contentTypes are the media types of image formats, for sinks that label
+// what they send.
+var contentTypes = map[string]string{
+	"webp": "image/webp",
+	"gif":  "image/gif",
+}
+
+func contentType(format string) string {
+	if t, ok := contentTypes[format]; ok {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,144 @@
+package sink
+
+import (
+	"context"
+	"image"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/device"
+	"tidbyt.dev/pixlet/encode"
+)
+
+func testWebP(t *testing.T) []byte {
+	frame := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	webp, err := encode.ScreensFromFrames([]image.Image{frame}, []time.Duration{time.Second}).EncodeWebP(0)
+	require.NoError(t, err)
+	return webp
+}
+
+func TestNew(t *testing.T) {
+	assert.Equal(t, []string{"file", "http", "mqtt", "webhook", "websocket"}, Kinds())
+
+	_, err := New(Config{Type: "carrier-pigeon"})
+	assert.ErrorContains(t, err, "expected one of file, http, mqtt, webhook, websocket")
+
+	_, err = New(Config{Type: "file", Path: "out.webp", Formats: []string{"png"}})
+	assert.ErrorContains(t, err, `unsupported format "png"`)
+
+	_, err = New(Config{Type: "http", URL: "https://api.tidbyt.com"})
+	assert.ErrorContains(t, err, "http sink: url and deviceID are required")
+
+	_, err = New(Config{Type: "mqtt", URL: "mqtt://broker.local"})
+	assert.ErrorContains(t, err, "has no topic")
+
+	_, err = New(Config{Type: "webhook", URL: "ftp://example.com"})
+	assert.Error(t, err)
+
+	s, err := New(Config{Type: "websocket", Formats: []string{"gif"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gif"}, s.Formats())
+	assert.IsType(t, &WebSocket{}, Unwrap(s))
+}
+
+func TestNegotiate(t *testing.T) {
+	webp := testWebP(t)
+
+	// images in a format the sink accepts are left alone
+	data, format, err := Negotiate(webp, []string{"gif", "webp"})
+	require.NoError(t, err)
+	assert.Equal(t, webp, data)
+	assert.Equal(t, "webp", format)
+
+	// and others are converted to the one it prefers
+	data, format, err = Negotiate(webp, []string{"gif", "raw"})
+	require.NoError(t, err)
+	assert.Equal(t, "gif", format)
+	assert.Equal(t, "gif", device.ImageFormat(data))
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kitchen.gif")
+	s, err := New(Config{Type: "file", Path: path, Formats: []string{"gif"}})
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, Deliver(context.Background(), s, Image{Data: testWebP(t), DeviceID: "kitchen"}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "gif", device.ImageFormat(data))
+
+	// nothing is left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWebhook(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		if r.Header.Get("X-Pixlet-Device") == "attic" {
+			http.Error(w, "no such display", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	s, err := New(Config{Type: "webhook", URL: server.URL + "/frames", APIKey: "hook-key"})
+	require.NoError(t, err)
+
+	webp := testWebP(t)
+	require.NoError(t, Deliver(context.Background(), s, Image{Data: webp, DeviceID: "kitchen", Dwell: 15 * time.Second}))
+	assert.Equal(t, "/frames", got.URL.Path)
+	assert.Equal(t, "image/webp", got.Header.Get("Content-Type"))
+	assert.Equal(t, "kitchen", got.Header.Get("X-Pixlet-Device"))
+	assert.Equal(t, "15", got.Header.Get("X-Pixlet-Dwell-Secs"))
+	assert.Equal(t, "Bearer hook-key", got.Header.Get("Authorization"))
+	assert.Equal(t, webp, body)
+
+	err = Deliver(context.Background(), s, Image{Data: webp, DeviceID: "attic"})
+	assert.ErrorContains(t, err, "404 Not Found: no such display")
+}
+
+func TestWebSocket(t *testing.T) {
+	s := NewWebSocket()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	ctx := context.Background()
+	require.NoError(t, s.Send(ctx, Image{Data: []byte("first")}))
+
+	// clients are sent the latest image when they connect, and then
+	// every one after it
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	typ, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, typ)
+	assert.Equal(t, "first", string(msg))
+
+	require.Eventually(t, func() bool { return s.Clients() == 1 }, time.Second, 10*time.Millisecond)
+	require.NoError(t, s.Send(ctx, Image{Data: []byte("second")}))
+	_, msg, err = conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(msg))
+
+	// closing the sink disconnects them
+	require.NoError(t, s.Close())
+	_, _, err = conn.ReadMessage()
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("webhook", newWebhook)
+}
+
+// webhook posts each image to a URL, with what it's for in headers:
+// X-Pixlet-Device and X-Pixlet-Dwell-Secs.
+type webhook struct {
+	url    string
+	apiKey string
+}
+
+func newWebhook(cfg Config) (Sink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("url must be an http or https URL, not %q", cfg.URL)
+	}
+	return &webhook{url: cfg.URL, apiKey: cfg.APIKey}, nil
+}
+
+func (s *webhook) Formats() []string {
+	return []string{"webp"}
+}
+
+func (s *webhook) Send(ctx context.Context, img Image) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(img.Data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType(img.Format))
+	req.Header.Set("X-Pixlet-Device", img.DeviceID)
+	req.Header.Set("X-Pixlet-Dwell-Secs", strconv.Itoa(int(img.Dwell.Seconds())))
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook responded %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s *webhook) Close() error {
+	return nil
+}
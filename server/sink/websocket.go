@@ -0,0 +1,137 @@
+package sink
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func init() {
+	Register("websocket", func(cfg Config) (Sink, error) {
+		return NewWebSocket(), nil
+	})
+}
+
+// Time allowed to write an image to a WebSocket client.
+const webSocketWriteWait = 10 * time.Second
+
+// WebSocket sends images to the clients connected to it, as binary
+// messages. Clients connect through ServeHTTP, and are sent the latest image
+// straight away.
+type WebSocket struct {
+	mutex   sync.Mutex
+	clients map[*webSocketClient]bool
+	latest  []byte
+	closed  bool
+}
+
+type webSocketClient struct {
+	conn *websocket.Conn
+	send chan []byte
+}
+
+// NewWebSocket returns a WebSocket sink with no clients.
+func NewWebSocket() *WebSocket {
+	return &WebSocket{clients: map[*webSocketClient]bool{}}
+}
+
+func (s *WebSocket) Formats() []string {
+	return []string{"webp"}
+}
+
+// Send queues the image to be sent to every client. Clients that haven't
+// been sent the previous image yet skip it, since only the latest one
+// matters.
+func (s *WebSocket) Send(ctx context.Context, img Image) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.latest = img.Data
+	for c := range s.clients {
+		select {
+		case <-c.send:
+		default:
+		}
+		c.send <- img.Data
+	}
+	return nil
+}
+
+// Close disconnects every client.
+func (s *WebSocket) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	for c := range s.clients {
+		delete(s.clients, c)
+		close(c.send)
+	}
+	return nil
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and sends images
+// to it until it's closed.
+func (s *WebSocket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &webSocketClient{conn: conn, send: make(chan []byte, 1)}
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		conn.Close()
+		return
+	}
+	if s.latest != nil {
+		c.send <- s.latest
+	}
+	s.clients[c] = true
+	s.mutex.Unlock()
+
+	// clients don't send anything, but reading notices when they leave
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				s.remove(c)
+				return
+			}
+		}
+	}()
+
+	for img := range c.send {
+		conn.SetWriteDeadline(time.Now().Add(webSocketWriteWait))
+		if err := conn.WriteMessage(websocket.BinaryMessage, img); err != nil {
+			s.remove(c)
+			break
+		}
+	}
+	conn.Close()
+}
+
+// Clients returns the number of clients connected.
+func (s *WebSocket) Clients() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.clients)
+}
+
+func (s *WebSocket) remove(c *webSocketClient) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.clients[c] {
+		delete(s.clients, c)
+		close(c.send)
+	}
+}
@@ -4,25 +4,80 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/fsnotify/fsnotify"
 )
 
 // Watcher is a structure to watch a file for changes and notify a channel.
+// Files the app depends on, such as the libraries it loads, are watched too
+// once they're set with SetDependencies.
 type Watcher struct {
 	path        string
 	fileChanges chan bool
+
+	mu           sync.Mutex
+	watcher      *fsnotify.Watcher
+	dependencies map[string]bool
+	watchedDirs  map[string]bool
 }
 
 // NewWatcher instantiates a new watcher with the provided filename and changes
 // channel.
 func NewWatcher(filename string, fileChanges chan bool) *Watcher {
 	return &Watcher{
-		path:        filepath.FromSlash(filename),
-		fileChanges: fileChanges,
+		path:         filepath.FromSlash(filename),
+		fileChanges:  fileChanges,
+		dependencies: make(map[string]bool),
+		watchedDirs:  make(map[string]bool),
+	}
+}
+
+// SetDependencies sets the files the app loaded, as slash separated paths
+// relative to its directory, such as shared libraries and modules vendored
+// into subdirectories, so that editing any of them notifies the channel
+// like editing the app does. It replaces the dependencies set before, and
+// is safe to call while the watcher runs.
+func (w *Watcher) SetDependencies(paths []string) {
+	root := w.path
+	if info, err := os.Stat(w.path); err == nil && !info.IsDir() {
+		root = filepath.Dir(w.path)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.dependencies = make(map[string]bool, len(paths))
+	for _, p := range paths {
+		w.dependencies[filepath.Join(root, filepath.FromSlash(p))] = true
+	}
+	w.watchDependencies()
+}
+
+// watchDependencies watches the directories of the dependencies, for the
+// same reason Run watches a directory rather than a file. Directories that
+// are no longer needed stay watched, and their other files are ignored.
+func (w *Watcher) watchDependencies() {
+	if w.watcher == nil {
+		return
+	}
+	for dep := range w.dependencies {
+		dir := filepath.Dir(dep)
+		if w.watchedDirs[dir] {
+			continue
+		}
+		if err := w.watcher.Add(dir); err == nil {
+			w.watchedDirs[dir] = true
+		}
 	}
 }
 
+func (w *Watcher) isDependency(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dependencies[name]
+}
+
 // Run starts the file watcher in a blocking fashion. This watches an entire
 // directory and only notifies the channel when the specified file is changed.
 // If there is an error, it's returned. It's up to the caller to respawn the
@@ -48,11 +103,24 @@ func (w *Watcher) Run() error {
 	}
 	defer watcher.Close()
 
-	if isDir {
-		watcher.Add(w.path)
-	} else {
-		watcher.Add(filepath.Dir(w.path))
+	dir := w.path
+	if !isDir {
+		dir = filepath.Dir(w.path)
 	}
+	watcher.Add(dir)
+
+	w.mu.Lock()
+	w.watcher = watcher
+	w.watchedDirs[dir] = true
+	w.watchDependencies()
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		w.watcher = nil
+		clear(w.watchedDirs)
+		w.mu.Unlock()
+	}()
 
 	for {
 		select {
@@ -61,9 +129,16 @@ func (w *Watcher) Run() error {
 				return fmt.Errorf("watcher events channel closed unexpectedly")
 			}
 
-			if !isDir && event.Name != w.path {
+			switch {
+			case w.isDependency(event.Name):
+				// files the app loads are always of interest
+			case !isDir && event.Name != w.path:
 				// if we're watching a single file, ignore changes to other files
 				continue
+			case isDir && filepath.Dir(event.Name) != w.path:
+				// and changes in subdirectories are only of interest for
+				// files the app loads from them
+				continue
 			}
 
 			if shouldNotify(event.Op) {
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcherDependencies(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.star"), []byte(`load("lib/colors.star", "colors")`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "colors.star"), []byte(`colors = ["#f00"]`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "unused.star"), []byte(`unused = 1`), 0644))
+
+	changes := make(chan bool, 10)
+	w := NewWatcher(dir, changes)
+	w.SetDependencies([]string{"app.star", "lib/colors.star"})
+	go w.Run()
+
+	// editing a library the app loads notifies, once the watcher is up
+	require.Eventually(t, func() bool {
+		os.WriteFile(filepath.Join(dir, "lib", "colors.star"), []byte(`colors = ["#0f0"]`), 0644)
+		select {
+		case <-changes:
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// quiet drains changes, and reports whether there are no more
+	quiet := func() bool {
+		notified := false
+		for {
+			select {
+			case <-changes:
+				notified = true
+			case <-time.After(200 * time.Millisecond):
+				return !notified
+			}
+		}
+	}
+	quiet()
+
+	// files in subdirectories the app doesn't load are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "unused.star"), []byte(`unused = 2`), 0644))
+	require.True(t, quiet())
+
+	// and so are libraries once the app stops loading them
+	w.SetDependencies([]string{"app.star"})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "colors.star"), []byte(`colors = ["#00f"]`), 0644))
+	require.True(t, quiet())
+}
@@ -13,6 +13,7 @@ import Preview from './features/preview/Preview';
 import Schema from './features/schema/Schema';
 import WatcherManager from './features/watcher/WatcherManager';
 import Controls from './features/controls/Controls';
+import CacheStats from './features/cache/CacheStats';
 
 
 export default function Main() {
@@ -37,6 +38,7 @@ export default function Main() {
                         <Grid size={{ xs: 12, lg: size }}>
                             <Preview scale={10} />
                             <Controls />
+                            <CacheStats />
                         </Grid>
                         <Grid size={{ xs: 12, lg: 4 }}>
                             <Schema />
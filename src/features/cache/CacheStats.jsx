@@ -0,0 +1,36 @@
+import { useEffect, useState } from 'react';
+import { useSelector } from 'react-redux';
+import axios from 'axios';
+
+import Stack from '@mui/material/Stack';
+import Typography from '@mui/material/Typography';
+
+// CacheStats shows how the app has used the cache so far, refreshed after
+// every render, so apps that keep missing and hammering an API stand out.
+export default function CacheStats() {
+    const preview = useSelector(state => state.preview);
+    const [total, setTotal] = useState(null);
+
+    useEffect(() => {
+        axios.get('api/v1/cache')
+            .then(res => setTotal(res.data.total))
+            .catch(() => setTotal(null));
+    }, [preview.value]);
+
+    if (!total) {
+        return null;
+    }
+
+    const reads = total.hits + total.misses;
+    const hitRate = reads > 0 ? Math.round(100 * total.hits / reads) : 0;
+
+    return (
+        <Stack sx={{ marginTop: '16px' }} spacing={3} direction="row">
+            <Typography variant="body2" color="text.secondary">Cache hits: {total.hits}</Typography>
+            <Typography variant="body2" color="text.secondary">Misses: {total.misses}</Typography>
+            <Typography variant="body2" color="text.secondary">Hit rate: {hitRate}%</Typography>
+            <Typography variant="body2" color="text.secondary">Sets: {total.sets}</Typography>
+            <Typography variant="body2" color="text.secondary">Evictions: {total.evictions}</Typography>
+        </Stack>
+    );
+}
@@ -1,6 +1,6 @@
 
 import axios from 'axios';
-import { update, loading } from './handlerSlice';
+import { update, updatePage, loading } from './handlerSlice';
 import { updateGenerated } from '../schema/schemaSlice';
 import { set as setError } from '../errors/errorSlice';
 import store from "../../store";
@@ -35,6 +35,36 @@ export function callHandler(id, handler, param) {
         })
 }
 
+// callTypeaheadHandler fetches a page of typeahead options. Paginated handlers
+// return {options, next_cursor}; pass the cursor back to append the next page.
+export function callTypeaheadHandler(id, handler, param, cursor = '') {
+    let data = {
+        id: id,
+        param: param,
+        cursor: cursor
+    }
+
+    store.dispatch(loading(true));
+    axios.post(`api/v1/handlers/` + handler, JSON.stringify(data))
+        .then(res => {
+            const page = Array.isArray(res.data) ? { options: res.data } : res.data;
+            store.dispatch(updatePage({
+                id: id,
+                options: page.options || [],
+                cursor: page.next_cursor || '',
+                append: cursor !== '',
+            }));
+        })
+        .catch(err => {
+            const msg = parseErrorMessage(handler, err);
+            store.dispatch(setError({ id: msg, message: msg }));
+            console.log(err);
+        })
+        .then(() => {
+            store.dispatch(loading(false));
+        })
+}
+
 export function callGeneratedHandler(id, handler, param) {
     let data = {
         id: id,
@@ -77,4 +107,27 @@ export function callHandlerSetValue(id, handler, param, valueHandler) {
         .then(() => {
             store.dispatch(loading(false));
         })
-}
\ No newline at end of file
+}
+
+// startDeviceAuthorization asks the server for a device code for an OAuth2
+// field that uses the device authorization grant.
+export function startDeviceAuthorization(id) {
+    return axios.post(`api/v1/oauth2/${id}/device`).then(res => res.data);
+}
+
+// prepareUpload has the server check a file uploaded to a FileUpload field,
+// and resolves to the value to save for it, scaled down to the display if
+// it's a larger image.
+export function prepareUpload(id, value) {
+    return axios.post(`api/v1/uploads/${id}`, { value: value }).then(res => res.data.value);
+}
+
+// pollHandler calls a handler without touching the store, for callers that
+// call it repeatedly, such as when waiting on a device authorization.
+export function pollHandler(id, handler, param) {
+    let data = {
+        id: id,
+        param: JSON.stringify(param)
+    }
+    return axios.post(`api/v1/handlers/` + handler, JSON.stringify(data)).then(res => res.data);
+}
@@ -4,7 +4,8 @@ export const handlerSlice = createSlice({
     name: 'handler',
     initialState: {
         loading: false,
-        values: {}
+        values: {},
+        cursors: {}
     },
     reducers: {
         update: (state = initialState, action) => {
@@ -12,11 +13,17 @@ export const handlerSlice = createSlice({
             up.values[action.payload.id] = action.payload.value
             return up;
         },
+        updatePage: (state = initialState, action) => {
+            const { id, options, cursor, append } = action.payload;
+            const previous = append ? (state.values[id] || []) : [];
+            state.values[id] = previous.concat(options);
+            state.cursors[id] = cursor;
+        },
         loading: (state = initialState, action) => {
             return { ...state, loading: action.payload }
         },
     },
 });
 
-export const { update, loading } = handlerSlice.actions;
+export const { update, updatePage, loading } = handlerSlice.actions;
 export default handlerSlice.reducer;
\ No newline at end of file
@@ -2,31 +2,52 @@ import OAuth2 from './fields/oauth2/OAuth2';
 import PhotoSelect from './fields/photoselect/PhotoSelect';
 import Toggle from './fields/Toggle';
 import Color from './fields/Color';
+import Date from './fields/Date';
 import DateTime from './fields/DateTime';
 import Dropdown from './fields/Dropdown';
+import Duration from './fields/Duration';
+import FileUpload from './fields/FileUpload';
+import List from './fields/List';
 import LocationBased from './fields/location/LocationBased';
 import LocationForm from './fields/location/LocationForm';
+import Locations from './fields/location/Locations';
+import MultiSelect from './fields/MultiSelect';
 import TextInput from './fields/TextInput';
+import TimeRange from './fields/TimeRange';
 import Typeahead from './fields/Typeahead';
 import Typography from '@mui/material/Typography';
 
 
 export default function FieldDetails({ field }) {
     switch (field.type) {
+        case 'date':
+            return <Date field={field} />
         case 'datetime':
             return <DateTime field={field} />
         case 'dropdown':
             return <Dropdown field={field} />
+        case 'duration':
+            return <Duration field={field} />
+        case 'file':
+            return <FileUpload field={field} />
+        case 'list':
+            return <List field={field} />
         case 'location':
             return <LocationForm field={field} />
+        case 'locations':
+            return <Locations field={field} />
         case 'locationbased':
             return <LocationBased field={field} />
+        case 'multiselect':
+            return <MultiSelect field={field} />
         case 'oauth2':
             return <OAuth2 field={field} />
         case 'png':
             return <PhotoSelect field={field} />
         case 'text':
             return <TextInput field={field} />
+        case 'timerange':
+            return <TimeRange field={field} />
         case 'onoff':
             return <Toggle field={field} />
         case 'typeahead':
@@ -0,0 +1,65 @@
+import { useState } from 'react';
+import { useSelector } from 'react-redux';
+import Accordion from '@mui/material/Accordion';
+import AccordionSummary from '@mui/material/AccordionSummary';
+import AccordionDetails from '@mui/material/AccordionDetails';
+import Box from '@mui/material/Box';
+import Typography from '@mui/material/Typography';
+import ExpandMoreIcon from '@mui/icons-material/ExpandMore';
+
+import Field from './Field';
+import FieldIcon from './FieldIcon';
+import isVisible from './visibleIf';
+
+// SchemaField renders a field of the schema, which may itself be a group or
+// section holding more fields, unless its visible_if expression hides it.
+function SchemaField({ field }) {
+    const config = useSelector(state => state.config);
+
+    if (!isVisible(field, config)) {
+        return null;
+    }
+
+    if (field.type === 'group' || field.type === 'section') {
+        return <FieldGroup field={field} />
+    }
+
+    return <Field field={field} />
+}
+
+export function renderField(field) {
+    return <SchemaField key={field.id} field={field} />
+}
+
+export default function FieldGroup({ field }) {
+    const [expanded, setExpanded] = useState(!field.collapsed);
+
+    if (field.type === 'section') {
+        return (
+            <Box sx={{ mt: 3, mb: 1 }}>
+                <Typography variant="h6">
+                    {field.icon && <FieldIcon icon={field.icon} />} {field.name}
+                </Typography>
+                {field.description && <Typography sx={{ color: 'text.secondary', mb: 1 }}>{field.description}</Typography>}
+                {field.fields.map(renderField)}
+            </Box>
+        );
+    }
+
+    return (
+        <Accordion expanded={expanded} onChange={(event, isExpanded) => setExpanded(isExpanded)}>
+            <AccordionSummary expandIcon={<ExpandMoreIcon />}>
+                <Typography sx={{ width: '10%', flexShrink: 0 }}>
+                    <FieldIcon icon={field.icon} />
+                </Typography>
+                <Typography sx={{ width: '33%', flexShrink: 0 }}>
+                    {field.name}
+                </Typography>
+                <Typography sx={{ color: 'text.secondary' }}>{field.description}</Typography>
+            </AccordionSummary>
+            <AccordionDetails>
+                {field.fields.map(renderField)}
+            </AccordionDetails>
+        </Accordion>
+    );
+}
@@ -3,6 +3,7 @@ import { useSelector } from 'react-redux';
 
 import refreshSchema from './actions';
 import Field from './Field';
+import { renderField } from './FieldGroup';
 import Generated from './fields/Generated';
 
 
@@ -21,7 +22,7 @@ export default function Schema() {
                         return <Generated key={field.id} field={field} />
                     }
 
-                    return <Field key={field.id} field={field} />
+                    return renderField(field);
                 })
             }
             {
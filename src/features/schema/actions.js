@@ -2,6 +2,7 @@ import axios from 'axios';
 import axiosRetry from 'axios-retry';
 
 import { update, loading, error } from './schemaSlice';
+import { set } from '../config/configSlice';
 import store from "../../store";
 
 
@@ -20,6 +21,13 @@ export default function refreshSchema() {
     client.get(`api/v1/schema`)
         .then(res => {
             store.dispatch(update(res.data));
+            if (res.data.config_version) {
+                // config made with this schema doesn't need migrating
+                store.dispatch(set({
+                    id: '$config_version',
+                    value: String(res.data.config_version),
+                }));
+            }
         })
         .catch(err => {
             if (err.response.status == 404) {
@@ -0,0 +1,54 @@
+import { useState, useEffect } from 'react';
+import { useSelector, useDispatch } from 'react-redux';
+import dayjs from 'dayjs';
+
+import { AdapterDayjs } from '@mui/x-date-pickers/AdapterDayjs';
+import { LocalizationProvider } from '@mui/x-date-pickers/LocalizationProvider';
+import { DatePicker } from '@mui/x-date-pickers/DatePicker';
+
+import { set, remove } from '../../config/configSlice'
+
+const DATE_FORMAT = 'YYYY-MM-DD';
+
+
+export default function Date({ field }) {
+    const [date, setDate] = useState(field.default ? dayjs(field.default) : null);
+    const config = useSelector(state => state.config);
+    const dispatch = useDispatch();
+
+    useEffect(() => {
+        if (field.id in config) {
+            setDate(dayjs(config[field.id].value));
+        } else if (field.default) {
+            dispatch(set({
+                id: field.id,
+                value: field.default,
+            }));
+        }
+    }, [config]);
+
+    const onChange = (day) => {
+        if (!day || !day.isValid()) {
+            setDate(null);
+            dispatch(remove(field.id));
+            return;
+        }
+
+        setDate(day);
+        dispatch(set({
+            id: field.id,
+            value: day.format(DATE_FORMAT),
+        }));
+    }
+
+    return (
+        <LocalizationProvider dateAdapter={AdapterDayjs}>
+            <DatePicker
+                label={field.name}
+                value={date}
+                onChange={onChange}
+                onError={console.log}
+            />
+        </LocalizationProvider>
+    );
+}
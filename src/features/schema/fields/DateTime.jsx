@@ -1,6 +1,8 @@
 import { useState, useEffect } from 'react';
 import { useSelector, useDispatch } from 'react-redux';
 import dayjs from 'dayjs';
+import utc from 'dayjs/plugin/utc';
+import timezone from 'dayjs/plugin/timezone';
 
 import { AdapterDayjs } from '@mui/x-date-pickers/AdapterDayjs';
 import { LocalizationProvider } from '@mui/x-date-pickers/LocalizationProvider';
@@ -9,21 +11,34 @@ import TextField from '@mui/material/TextField';
 
 import { set, remove } from '../../config/configSlice'
 
+dayjs.extend(utc);
+dayjs.extend(timezone);
+
 
 export default function DateTime({ field }) {
-    const [dateTime, setDateTime] = useState(dayjs());
+    // when the field has a timezone, the picker shows and edits wall time
+    // in that zone, and the value keeps the zone's offset.
+    const tz = field.timezone || 'default';
+    const parse = (value) => field.timezone ? dayjs(value).tz(field.timezone) : dayjs(value);
+
+    const [dateTime, setDateTime] = useState(parse(field.default || undefined));
     const config = useSelector(state => state.config);
     const dispatch = useDispatch();
 
     useEffect(() => {
         if (field.id in config) {
-            setDateTime(dayjs(config[field.id].value));
+            setDateTime(parse(config[field.id].value));
+        } else if (field.default) {
+            dispatch(set({
+                id: field.id,
+                value: field.default,
+            }));
         }
     }, [config]);
 
     const onChange = (timestamp) => {
         if (!timestamp) {
-            setDateTime(dayjs());
+            setDateTime(parse());
             dispatch(remove(field.id));
             return;
         }
@@ -31,7 +46,7 @@ export default function DateTime({ field }) {
         setDateTime(timestamp);
         dispatch(set({
             id: field.id,
-            value: timestamp.toISOString(),
+            value: field.timezone ? timestamp.tz(field.timezone).format() : timestamp.toISOString(),
         }));
     }
 
@@ -41,9 +56,10 @@ export default function DateTime({ field }) {
                 renderInput={(props) => <TextField {...props} />}
                 label={field.name}
                 value={dateTime}
+                timezone={tz}
                 onChange={onChange}
                 onError={console.log}
             />
         </LocalizationProvider>
     );
-}
\ No newline at end of file
+}
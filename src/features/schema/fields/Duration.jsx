@@ -0,0 +1,117 @@
+import { useState, useEffect } from 'react';
+import { useSelector, useDispatch } from 'react-redux';
+
+import MenuItem from '@mui/material/MenuItem';
+import Stack from '@mui/material/Stack';
+import TextField from '@mui/material/TextField';
+
+import { set, remove } from '../../config/configSlice';
+
+const UNIT_SECONDS = {
+    seconds: 1,
+    minutes: 60,
+    hours: 3600,
+};
+
+// parseDuration parses the subset of Go's duration format that schema.Duration
+// values use, returning a number of seconds.
+export function parseDuration(value) {
+    const match = /^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?$/.exec(value || '');
+    if (!match || value === '') {
+        return null;
+    }
+    const [, h, m, s] = match.map(v => parseInt(v || '0', 10));
+    return h * 3600 + m * 60 + s;
+}
+
+// formatDuration formats seconds the way Go's time.Duration.String() does,
+// which is the canonical format of schema.Duration values.
+export function formatDuration(seconds) {
+    const h = Math.floor(seconds / 3600);
+    const m = Math.floor(seconds % 3600 / 60);
+    const s = seconds % 60;
+    if (h > 0) {
+        return `${h}h${m}m${s}s`;
+    }
+    if (m > 0) {
+        return `${m}m${s}s`;
+    }
+    return `${s}s`;
+}
+
+function splitDuration(seconds, units) {
+    // show the value in the largest unit that represents it exactly
+    for (const unit of [...units].reverse()) {
+        if (seconds % UNIT_SECONDS[unit] === 0) {
+            return { amount: String(seconds / UNIT_SECONDS[unit]), unit };
+        }
+    }
+    return { amount: String(seconds), unit: units[0] };
+}
+
+
+export default function Duration({ field }) {
+    const units = field.units && field.units.length ? field.units : Object.keys(UNIT_SECONDS);
+    const config = useSelector(state => state.config);
+    const dispatch = useDispatch();
+
+    const [amount, setAmount] = useState('');
+    const [unit, setUnit] = useState(units[units.length > 1 ? 1 : 0]);
+
+    useEffect(() => {
+        const value = field.id in config ? config[field.id].value : field.default;
+        const seconds = parseDuration(value);
+        if (seconds === null) {
+            return;
+        }
+
+        const current = parseInt(amount, 10) * UNIT_SECONDS[unit];
+        if (current !== seconds) {
+            const split = splitDuration(seconds, units);
+            setAmount(split.amount);
+            setUnit(split.unit);
+        }
+        if (!(field.id in config)) {
+            dispatch(set({
+                id: field.id,
+                value: field.default,
+            }));
+        }
+    }, [config]);
+
+    const update = (newAmount, newUnit) => {
+        setAmount(newAmount);
+        setUnit(newUnit);
+
+        const n = parseInt(newAmount, 10);
+        if (newAmount === '' || isNaN(n) || n < 0) {
+            dispatch(remove(field.id));
+            return;
+        }
+        dispatch(set({
+            id: field.id,
+            value: formatDuration(n * UNIT_SECONDS[newUnit]),
+        }));
+    }
+
+    return (
+        <Stack spacing={2} direction="row">
+            <TextField
+                fullWidth
+                label={field.name}
+                type="number"
+                inputProps={{ min: 0, step: 1 }}
+                value={amount}
+                onChange={(event) => update(event.target.value, unit)}
+            />
+            <TextField
+                select
+                label="Unit"
+                value={unit}
+                onChange={(event) => update(amount, event.target.value)}
+            >
+                {units.map(u => <MenuItem key={u} value={u}>{u}</MenuItem>)}
+            </TextField>
+        </Stack>
+    );
+}
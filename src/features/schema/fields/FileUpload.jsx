@@ -0,0 +1,99 @@
+import { Fragment, useState, useEffect } from 'react';
+import { useSelector, useDispatch } from 'react-redux';
+
+import Button from '@mui/material/Button';
+import Stack from '@mui/material/Stack';
+import Typography from '@mui/material/Typography';
+import DeleteIcon from '@mui/icons-material/Delete';
+import UploadFileIcon from '@mui/icons-material/UploadFile';
+
+import { set, remove } from '../../config/configSlice';
+import { prepareUpload } from '../../handlers/actions';
+
+// Keep in sync with schema.DefaultMaxUploadSize.
+const DEFAULT_MAX_SIZE = 256 * 1024;
+
+
+export default function FileUpload({ field }) {
+    const [fileName, setFileName] = useState('');
+    const [error, setError] = useState('');
+    const config = useSelector(state => state.config);
+    const dispatch = useDispatch();
+
+    const maxSize = field.max_size || DEFAULT_MAX_SIZE;
+
+    useEffect(() => {
+        if (!(field.id in config)) {
+            setFileName('');
+        }
+    }, [config]);
+
+    const handleCapture = ({ target }) => {
+        const file = target.files[0];
+        target.value = '';
+        if (!file) {
+            return;
+        }
+
+        // the server checks these too, but failing early saves an upload
+        if (file.size > maxSize) {
+            setError(`${file.name} is too large, files can be at most ${maxSize} bytes.`);
+            return;
+        }
+
+        const fileReader = new FileReader();
+        fileReader.readAsDataURL(file);
+        fileReader.onload = (e) => {
+            const value = e.target.result.replace(/^data:[^,]*;base64,/, '');
+            prepareUpload(field.id, value)
+                .then((prepared) => {
+                    setError('');
+                    setFileName(file.name);
+                    dispatch(set({
+                        id: field.id,
+                        value: prepared,
+                    }));
+                })
+                .catch((err) => {
+                    setError(err.response ? err.response.data : err.message);
+                });
+        };
+    }
+
+    const handleClear = () => {
+        setError('');
+        setFileName('');
+        dispatch(remove(field.id));
+    }
+
+    return (
+        <Fragment>
+            <Stack spacing={2} direction="row" alignItems="center">
+                <Button
+                    variant="contained"
+                    component="label"
+                    startIcon={<UploadFileIcon htmlColor='white' />}
+                >
+                    Upload File
+                    <input
+                        accept={field.accept ? field.accept.join(',') : undefined}
+                        type="file"
+                        hidden
+                        onChange={handleCapture}
+                    />
+                </Button>
+                {field.id in config &&
+                    <Button
+                        variant="contained"
+                        onClick={handleClear}
+                        startIcon={<DeleteIcon htmlColor='white' />}
+                    >
+                        Clear File
+                    </Button>
+                }
+                {fileName && <Typography>{fileName}</Typography>}
+            </Stack>
+            {error && <Typography color="error">{error}</Typography>}
+        </Fragment>
+    );
+}
@@ -0,0 +1,133 @@
+import { useState, useEffect } from 'react';
+import { useSelector, useDispatch } from 'react-redux';
+
+import Button from '@mui/material/Button';
+import FormControl from '@mui/material/FormControl';
+import FormControlLabel from '@mui/material/FormControlLabel';
+import IconButton from '@mui/material/IconButton';
+import InputLabel from '@mui/material/InputLabel';
+import MenuItem from '@mui/material/MenuItem';
+import Paper from '@mui/material/Paper';
+import Select from '@mui/material/Select';
+import Stack from '@mui/material/Stack';
+import Switch from '@mui/material/Switch';
+import TextField from '@mui/material/TextField';
+import Typography from '@mui/material/Typography';
+import DeleteIcon from '@mui/icons-material/Delete';
+
+import { set } from '../../config/configSlice';
+
+const parse = (value) => {
+    try {
+        const parsed = JSON.parse(value || '[]');
+        return Array.isArray(parsed) ? parsed : [];
+    } catch {
+        return [];
+    }
+}
+
+// ChildField edits one value of a list entry. Entries are kept in the list's
+// own config value, so children can't use the regular field components,
+// which each own a config key.
+function ChildField({ field, value, onChange }) {
+    switch (field.type) {
+        case 'onoff':
+            return (
+                <FormControlLabel
+                    label={field.name}
+                    control={<Switch
+                        checked={value === 'true'}
+                        onChange={(event) => onChange(event.target.checked ? 'true' : 'false')}
+                    />}
+                />
+            );
+        case 'dropdown':
+            return (
+                <FormControl fullWidth>
+                    <InputLabel>{field.name}</InputLabel>
+                    <Select
+                        value={value}
+                        label={field.name}
+                        onChange={(event) => onChange(event.target.value)}
+                    >
+                        {field.options.map((option) => {
+                            return <MenuItem key={option.value} value={option.value}>{option.display}</MenuItem>
+                        })}
+                    </Select>
+                </FormControl>
+            );
+        default:
+            return (
+                <TextField
+                    fullWidth
+                    label={field.name}
+                    helperText={field.description}
+                    value={value}
+                    onChange={(event) => onChange(event.target.value)}
+                />
+            );
+    }
+}
+
+const newItem = (fields) => Object.fromEntries(
+    fields.filter((f) => f.default).map((f) => [f.id, f.default])
+);
+
+
+export default function List({ field }) {
+    const [items, setItems] = useState([]);
+    const config = useSelector(state => state.config);
+    const dispatch = useDispatch();
+
+    useEffect(() => {
+        if (field.id in config) {
+            setItems(parse(config[field.id].value));
+        }
+    }, [config]);
+
+    const update = (next) => {
+        setItems(next);
+        dispatch(set({
+            id: field.id,
+            value: JSON.stringify(next),
+        }));
+    }
+
+    const onChildChange = (index, id) => (value) => {
+        update(items.map((item, i) => i === index ? { ...item, [id]: value } : item));
+    }
+
+    const full = field.max_items > 0 && items.length >= field.max_items;
+
+    return (
+        <Stack spacing={2}>
+            {items.map((item, index) => {
+                return (
+                    <Paper key={index} variant="outlined" sx={{ p: 2 }}>
+                        <Stack spacing={2}>
+                            <Stack direction="row" justifyContent="space-between" alignItems="center">
+                                <Typography>{field.name} {index + 1}</Typography>
+                                <IconButton onClick={() => update(items.filter((_, i) => i !== index))}>
+                                    <DeleteIcon />
+                                </IconButton>
+                            </Stack>
+                            {field.fields.map((child) => {
+                                return (
+                                    <ChildField
+                                        key={child.id}
+                                        field={child}
+                                        value={item[child.id] || ''}
+                                        onChange={onChildChange(index, child.id)}
+                                    />
+                                );
+                            })}
+                        </Stack>
+                    </Paper>
+                );
+            })}
+            <Button disabled={full} onClick={() => update([...items, newItem(field.fields)])}>
+                Add
+            </Button>
+        </Stack>
+    );
+}
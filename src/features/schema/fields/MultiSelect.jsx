@@ -0,0 +1,76 @@
+import { useState, useEffect } from 'react';
+import { useSelector, useDispatch } from 'react-redux';
+
+import Checkbox from '@mui/material/Checkbox';
+import InputLabel from '@mui/material/InputLabel';
+import ListItemText from '@mui/material/ListItemText';
+import MenuItem from '@mui/material/MenuItem';
+import FormControl from '@mui/material/FormControl';
+import Select from '@mui/material/Select';
+
+import { set } from '../../config/configSlice';
+
+const parse = (value) => {
+    try {
+        const parsed = JSON.parse(value || '[]');
+        return Array.isArray(parsed) ? parsed : [];
+    } catch {
+        return [];
+    }
+}
+
+export default function MultiSelect({ field }) {
+    const [value, setValue] = useState(parse(field.default));
+    const config = useSelector(state => state.config);
+    const dispatch = useDispatch();
+
+    useEffect(() => {
+        if (field.id in config) {
+            setValue(parse(config[field.id].value));
+        } else if (field.default) {
+            dispatch(set({
+                id: field.id,
+                value: field.default,
+            }));
+        }
+    }, [config])
+
+    const onChange = (event) => {
+        // keep the selection in the order the options are listed
+        const selected = field.options
+            .map((option) => option.value)
+            .filter((v) => event.target.value.includes(v));
+        setValue(selected);
+        dispatch(set({
+            id: field.id,
+            value: JSON.stringify(selected),
+        }));
+    }
+
+    const display = (selected) => field.options
+        .filter((option) => selected.includes(option.value))
+        .map((option) => option.display)
+        .join(', ');
+
+    return (
+        <FormControl fullWidth>
+            <InputLabel>{field.name}</InputLabel>
+            <Select
+                multiple
+                value={value}
+                label={field.name}
+                onChange={onChange}
+                renderValue={display}
+            >
+                {field.options.map((option) => {
+                    return (
+                        <MenuItem key={option.value} value={option.value}>
+                            <Checkbox checked={value.includes(option.value)} />
+                            <ListItemText primary={option.display} />
+                        </MenuItem>
+                    );
+                })}
+            </Select>
+        </FormControl>
+    );
+}
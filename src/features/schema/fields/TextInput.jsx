@@ -58,7 +58,41 @@ export default function TextInput({ field }) {
         [value]
     );
 
+    // mirrors the checks in schema/fieldoptions.go, so that mistakes show up
+    // before the preview rejects the config.
+    const validate = (v) => {
+        if (!v) {
+            return field.required ? 'A value is required' : '';
+        }
+        if (field.pattern && !new RegExp(`^(?:${field.pattern})$`).test(v)) {
+            return `Must match ${field.pattern}`;
+        }
+        if (field.min !== undefined || field.max !== undefined) {
+            const n = Number(v);
+            if (isNaN(n)) {
+                return 'Must be a number';
+            }
+            if (field.min !== undefined && n < field.min) {
+                return `Must be at least ${field.min}`;
+            }
+            if (field.max !== undefined && n > field.max) {
+                return `Must be at most ${field.max}`;
+            }
+        }
+        return '';
+    }
+    const error = validate(value);
+
     return (
-        <TextField fullWidth value={value} label={field.name} variant="outlined" onChange={onChange} />
+        <TextField
+            fullWidth
+            value={value}
+            label={field.name}
+            variant="outlined"
+            required={field.required}
+            error={error !== ''}
+            helperText={error}
+            onChange={onChange}
+        />
     )
 }
\ No newline at end of file
@@ -0,0 +1,96 @@
+import { useState, useEffect } from 'react';
+import { useSelector, useDispatch } from 'react-redux';
+import dayjs from 'dayjs';
+
+import { AdapterDayjs } from '@mui/x-date-pickers/AdapterDayjs';
+import { LocalizationProvider } from '@mui/x-date-pickers/LocalizationProvider';
+import { TimePicker } from '@mui/x-date-pickers/TimePicker';
+import Stack from '@mui/material/Stack';
+import ToggleButton from '@mui/material/ToggleButton';
+import ToggleButtonGroup from '@mui/material/ToggleButtonGroup';
+
+import { set } from '../../config/configSlice';
+
+const WEEKDAYS = ['sun', 'mon', 'tue', 'wed', 'thu', 'fri', 'sat'];
+const TIME_FORMAT = 'HH:mm';
+
+const parse = (value) => {
+    try {
+        const parsed = JSON.parse(value);
+        return { start: parsed.start || '', end: parsed.end || '', days: parsed.days || [] };
+    } catch {
+        return { start: '', end: '', days: [] };
+    }
+}
+
+// time pickers work on full dates, so anchor times of day to today.
+const toDayjs = (time) => time ? dayjs(`${dayjs().format('YYYY-MM-DD')}T${time}`) : null;
+
+
+export default function TimeRange({ field }) {
+    const [range, setRange] = useState(parse(field.default));
+    const config = useSelector(state => state.config);
+    const dispatch = useDispatch();
+
+    useEffect(() => {
+        if (field.id in config) {
+            setRange(parse(config[field.id].value));
+        } else if (field.default) {
+            dispatch(set({
+                id: field.id,
+                value: field.default,
+            }));
+        }
+    }, [config]);
+
+    const update = (changes) => {
+        const next = { ...range, ...changes };
+        setRange(next);
+        if (!next.start || !next.end) {
+            return;
+        }
+
+        const value = { start: next.start, end: next.end };
+        if (next.days.length > 0) {
+            value.days = WEEKDAYS.filter((day) => next.days.includes(day));
+        }
+        dispatch(set({
+            id: field.id,
+            value: JSON.stringify(value),
+        }));
+    }
+
+    const onTimeChange = (key) => (time) => {
+        update({ [key]: time && time.isValid() ? time.format(TIME_FORMAT) : '' });
+    }
+
+    return (
+        <LocalizationProvider dateAdapter={AdapterDayjs}>
+            <Stack spacing={2}>
+                <Stack direction="row" spacing={2}>
+                    <TimePicker
+                        label="Start"
+                        ampm={false}
+                        value={toDayjs(range.start)}
+                        onChange={onTimeChange('start')}
+                    />
+                    <TimePicker
+                        label="End"
+                        ampm={false}
+                        value={toDayjs(range.end)}
+                        onChange={onTimeChange('end')}
+                    />
+                </Stack>
+                <ToggleButtonGroup
+                    size="small"
+                    value={range.days}
+                    onChange={(event, days) => update({ days })}
+                >
+                    {WEEKDAYS.map((day) => {
+                        return <ToggleButton key={day} value={day}>{day}</ToggleButton>
+                    })}
+                </ToggleButtonGroup>
+            </Stack>
+        </LocalizationProvider>
+    );
+}
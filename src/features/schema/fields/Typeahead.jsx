@@ -1,18 +1,24 @@
-import { useState, useEffect } from 'react';
+import { useState, useEffect, useRef } from 'react';
 import { useSelector, useDispatch } from 'react-redux';
 
 import Autocomplete from '@mui/material/Autocomplete';
 import TextField from '@mui/material/TextField';
 
 import { set, remove } from '../../config/configSlice';
-import { callHandler } from '../../handlers/actions';
+import { callTypeaheadHandler } from '../../handlers/actions';
 
+const defaultDebounce = 250;
 
 export default function Typeahead({ field }) {
     const [value, setValue] = useState(null);
+    const [query, setQuery] = useState('');
     const config = useSelector(state => state.config);
     const dispatch = useDispatch();
     const handlerResults = useSelector(state => state.handlers)
+    const timer = useRef(null);
+
+    const minQueryLength = field.min_query_length || 0;
+    const debounce = field.debounce_ms || defaultDebounce;
 
     useEffect(() => {
         if (field.id in config) {
@@ -20,6 +26,8 @@ export default function Typeahead({ field }) {
         }
     }, [config])
 
+    useEffect(() => () => clearTimeout(timer.current), []);
+
     const onChange = (event, newValue) => {
         if (newValue) {
             setValue(newValue);
@@ -33,6 +41,28 @@ export default function Typeahead({ field }) {
         }
     }
 
+    const onInputChange = (event, v) => {
+        setQuery(v);
+        clearTimeout(timer.current);
+        if (v.length < minQueryLength) {
+            return;
+        }
+        timer.current = setTimeout(() => {
+            callTypeaheadHandler(field.id, field.handler, v);
+        }, debounce);
+    }
+
+    const cursor = handlerResults.cursors[field.id] || '';
+    const onListboxScroll = (event) => {
+        const list = event.currentTarget;
+        if (!cursor || handlerResults.loading) {
+            return;
+        }
+        if (list.scrollTop + list.clientHeight >= list.scrollHeight - 16) {
+            callTypeaheadHandler(field.id, field.handler, query, cursor);
+        }
+    }
+
     let options = [];
     if (field.id in handlerResults.values) {
         options = handlerResults.values[field.id];
@@ -43,13 +73,15 @@ export default function Typeahead({ field }) {
             fullWidth
             disablePortal
             value={value}
-            onInputChange={(event, v) => {
-                callHandler(field.id, field.handler, v);
-            }}
+            onInputChange={onInputChange}
             onChange={onChange}
             options={options}
+            filterOptions={(x) => x}
+            loading={handlerResults.loading}
+            noOptionsText={query.length < minQueryLength ? `Type at least ${minQueryLength} characters` : 'No options'}
+            ListboxProps={{ onScroll: onListboxScroll }}
             getOptionLabel={(option) => option.display}
             renderInput={(params) => <TextField fullWidth {...params} label={field.name} />}
         />
     )
-}
\ No newline at end of file
+}
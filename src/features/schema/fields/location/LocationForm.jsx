@@ -57,7 +57,7 @@ const getUrlParams = () => {
 };
 
 // Main location picker component that integrates with Geoapify API
-const LocationPicker = ({ onLocationSelect, initialLocation }) => {
+export const LocationPicker = ({ onLocationSelect, initialLocation }) => {
     // State for search input, results, loading status, and dropdown visibility
     const [query, setQuery] = useState(initialLocation?.locality || '');
     const [results, setResults] = useState([]);
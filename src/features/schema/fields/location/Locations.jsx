@@ -0,0 +1,96 @@
+import { useState, useEffect } from 'react';
+import { useSelector, useDispatch } from 'react-redux';
+
+import Autocomplete from '@mui/material/Autocomplete';
+import Button from '@mui/material/Button';
+import IconButton from '@mui/material/IconButton';
+import Paper from '@mui/material/Paper';
+import Stack from '@mui/material/Stack';
+import TextField from '@mui/material/TextField';
+import Typography from '@mui/material/Typography';
+import DeleteIcon from '@mui/icons-material/Delete';
+
+import { set } from '../../../config/configSlice';
+import { LocationPicker } from './LocationForm';
+
+const parse = (value) => {
+    try {
+        const parsed = JSON.parse(value || '[]');
+        return Array.isArray(parsed) ? parsed : [];
+    } catch {
+        return [];
+    }
+}
+
+export default function Locations({ field }) {
+    const [items, setItems] = useState([]);
+    const config = useSelector(state => state.config);
+    const dispatch = useDispatch();
+    const labels = field.labels || [];
+
+    useEffect(() => {
+        if (field.id in config) {
+            setItems(parse(config[field.id].value));
+        }
+    }, [config]);
+
+    const update = (next) => {
+        setItems(next);
+        dispatch(set({
+            id: field.id,
+            value: JSON.stringify(next),
+        }));
+    }
+
+    const onChange = (index, values) => {
+        update(items.map((item, i) => i === index ? { ...item, ...values } : item));
+    }
+
+    // suggest the first label that isn't taken yet
+    const nextLabel = () => labels.find((l) => !items.some((item) => item.label === l)) || '';
+
+    const full = field.max !== undefined && items.length >= field.max;
+
+    return (
+        <Stack spacing={2}>
+            {items.map((item, index) => {
+                return (
+                    <Paper key={index} variant="outlined" sx={{ p: 2 }}>
+                        <Stack spacing={2}>
+                            <Stack direction="row" spacing={1} alignItems="center">
+                                <Autocomplete
+                                    freeSolo
+                                    fullWidth
+                                    options={labels}
+                                    value={item.label || ''}
+                                    onInputChange={(event, v) => onChange(index, { label: v })}
+                                    renderInput={(params) => <TextField {...params} label="Label" />}
+                                />
+                                <IconButton onClick={() => update(items.filter((_, i) => i !== index))}>
+                                    <DeleteIcon />
+                                </IconButton>
+                            </Stack>
+                            <LocationPicker
+                                initialLocation={item}
+                                onLocationSelect={(location) => onChange(index, {
+                                    lat: location.lat.toString(),
+                                    lng: location.lng.toString(),
+                                    locality: location.locality || location.formattedName,
+                                    timezone: location.timezone,
+                                })}
+                            />
+                            {item.lat && (
+                                <Typography variant="body2">
+                                    {item.locality} ({item.lat}, {item.lng})
+                                </Typography>
+                            )}
+                        </Stack>
+                    </Paper>
+                );
+            })}
+            <Button disabled={full} onClick={() => update([...items, { label: nextLabel() }])}>
+                Add Location
+            </Button>
+        </Stack>
+    );
+}
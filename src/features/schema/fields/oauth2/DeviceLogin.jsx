@@ -0,0 +1,74 @@
+import { useState, useEffect, useRef } from 'react';
+import { useDispatch } from 'react-redux';
+
+import Button from '@mui/material/Button';
+import Link from '@mui/material/Link';
+import Typography from '@mui/material/Typography';
+
+import { startDeviceAuthorization, pollHandler } from '../../../handlers/actions';
+import { set as setError } from '../../../errors/errorSlice';
+
+const defaultInterval = 5;
+
+// DeviceLogin signs in through the OAuth2 device authorization grant: it shows
+// the user a code to enter on the provider's site, and calls the field's
+// handler with the device code until it returns a value.
+export default function DeviceLogin({ field, onLogin }) {
+    const [auth, setAuth] = useState(null);
+    const dispatch = useDispatch();
+    const timer = useRef(null);
+
+    useEffect(() => () => clearTimeout(timer.current), []);
+
+    const fail = (err) => {
+        setAuth(null);
+        let msg = `failed login: ${err.response ? err.response.data : err}`;
+        dispatch(setError({ id: msg, message: msg }));
+    }
+
+    const poll = (auth, deadline) => {
+        if (Date.now() > deadline) {
+            return fail("the code expired");
+        }
+        timer.current = setTimeout(() => {
+            pollHandler(field.id, field.handler, {
+                grant_type: "urn:ietf:params:oauth:grant-type:device_code",
+                client_id: field.client_id,
+                device_code: auth.device_code,
+            }).then(value => {
+                if (value) {
+                    setAuth(null);
+                    onLogin(value);
+                } else {
+                    poll(auth, deadline);
+                }
+            }).catch(fail);
+        }, (auth.interval || defaultInterval) * 1000);
+    }
+
+    const start = () => {
+        startDeviceAuthorization(field.id)
+            .then(auth => {
+                setAuth(auth);
+                const deadline = Date.now() + (auth.expires_in || 600) * 1000;
+                poll(auth, deadline);
+            })
+            .catch(fail);
+    }
+
+    if (!auth) {
+        return (
+            <Button variant="contained" onClick={start}>
+                Login
+            </Button>
+        )
+    }
+
+    const uri = auth.verification_uri_complete || auth.verification_uri;
+    return (
+        <Typography>
+            Go to <Link href={uri} target="_blank" rel="noopener">{auth.verification_uri}</Link> and
+            enter the code <strong>{auth.user_code}</strong>.
+        </Typography>
+    )
+}
@@ -4,6 +4,8 @@ import OAuth2Login from 'react-simple-oauth2-login';
 
 import Button from '@mui/material/Button';
 
+import DeviceLogin from './DeviceLogin';
+import { generateVerifier, generateState, challengeFromVerifier } from './pkce';
 import { callHandlerSetValue } from '../../../handlers/actions';
 import { set as setError } from '../../../errors/errorSlice';
 import { set, remove } from '../../../config/configSlice';
@@ -11,6 +13,8 @@ import { set, remove } from '../../../config/configSlice';
 
 export default function OAuth2({ field }) {
     const [loggedIn, setLoggedIn] = useState("");
+    const [pkce, setPKCE] = useState(null);
+    const [state] = useState(generateState);
     const dispatch = useDispatch();
     const config = useSelector(state => state.config);
     const redirectUri = document.location.protocol + "//" + document.location.host + "/oauth-callback"
@@ -21,23 +25,39 @@ export default function OAuth2({ field }) {
         }
     }, [config])
 
+    useEffect(() => {
+        if (!field.pkce || loggedIn) {
+            return;
+        }
+        const verifier = generateVerifier();
+        challengeFromVerifier(verifier).then(challenge => {
+            setPKCE({ verifier, challenge });
+        });
+    }, [field.pkce, loggedIn])
+
+    const onLogin = (value) => {
+        setLoggedIn(value);
+        dispatch(set({
+            id: field.id,
+            value: value,
+        }));
+    }
+
     const onSuccess = (response) => {
         if (!response.code) {
             return onFailure("access was not granted");
         }
 
-        callHandlerSetValue(field.id, field.handler, {
+        let params = {
             code: response.code,
             client_id: field.client_id,
             redirect_uri: redirectUri,
             grant_type: "authorization_code",
-        }, (value) => {
-            setLoggedIn(value);
-            dispatch(set({
-                id: field.id,
-                value: value,
-            }));
-        });
+        };
+        if (pkce) {
+            params.code_verifier = pkce.verifier;
+        }
+        callHandlerSetValue(field.id, field.handler, params, onLogin);
     }
 
     const logout = () => {
@@ -74,6 +94,22 @@ export default function OAuth2({ field }) {
         )
     }
 
+    if (!field.authorization_endpoint) {
+        return <DeviceLogin field={field} onLogin={onLogin} />
+    }
+
+    if (field.pkce && !pkce) {
+        return null;
+    }
+
+    let extraParams = {};
+    if (pkce) {
+        extraParams = {
+            code_challenge: pkce.challenge,
+            code_challenge_method: "S256",
+        };
+    }
+
     let scope = field.scopes.join(" ");
     return (
         <OAuth2Login
@@ -81,7 +117,8 @@ export default function OAuth2({ field }) {
             authorizationUrl={field.authorization_endpoint}
             responseType="code"
             scope={scope}
-            state="abc123"
+            state={state}
+            extraParams={extraParams}
             clientId={field.client_id}
             redirectUri={redirectUri}
             render={renderButton}
@@ -0,0 +1,24 @@
+// PKCE (RFC 7636) lets the config UI use the authorization code flow without
+// a client secret.
+
+function base64url(bytes) {
+    return btoa(String.fromCharCode(...new Uint8Array(bytes)))
+        .replace(/\+/g, '-')
+        .replace(/\//g, '_')
+        .replace(/=+$/, '');
+}
+
+export function generateVerifier() {
+    const bytes = new Uint8Array(32);
+    crypto.getRandomValues(bytes);
+    return base64url(bytes);
+}
+
+export function generateState() {
+    return generateVerifier();
+}
+
+export async function challengeFromVerifier(verifier) {
+    const digest = await crypto.subtle.digest('SHA-256', new TextEncoder().encode(verifier));
+    return base64url(digest);
+}
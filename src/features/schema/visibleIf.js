@@ -0,0 +1,154 @@
+// Evaluates visible_if expressions on schema fields. This mirrors the
+// implementation in schema/visibleif.go, so keep the two in sync.
+
+const tokenize = (src) => {
+    const tokens = [];
+    let i = 0;
+    while (i < src.length) {
+        const c = src[i];
+        if (/\s/.test(c)) {
+            i++;
+        } else if (c === '"' || c === "'") {
+            let end = i + 1;
+            let value = '';
+            while (end < src.length && src[end] !== c) {
+                if (src[end] === '\\') {
+                    end++;
+                }
+                value += src[end];
+                end++;
+            }
+            if (end >= src.length) {
+                throw new Error('unterminated string');
+            }
+            tokens.push({ kind: 'string', text: value });
+            i = end + 1;
+        } else if (src.startsWith('==', i) || src.startsWith('!=', i)) {
+            tokens.push({ kind: src.slice(i, i + 2), text: src.slice(i, i + 2) });
+            i += 2;
+        } else if ('()[],'.includes(c)) {
+            tokens.push({ kind: c, text: c });
+            i++;
+        } else if (/[\w]/.test(c)) {
+            let end = i;
+            while (end < src.length && /[\w-]/.test(src[end])) {
+                end++;
+            }
+            const word = src.slice(i, end);
+            const kind = ['and', 'or', 'not', 'in'].includes(word) ? word : 'ident';
+            tokens.push({ kind, text: word });
+            i = end;
+        } else {
+            throw new Error(`unexpected ${c}`);
+        }
+    }
+    return tokens;
+}
+
+const parse = (src) => {
+    const tokens = tokenize(src);
+    let pos = 0;
+
+    const peek = () => pos < tokens.length ? tokens[pos].kind : '';
+    const next = (kind) => {
+        if (peek() !== kind) {
+            throw new Error(`expected ${kind}`);
+        }
+        return tokens[pos++];
+    }
+
+    const parseOr = () => {
+        let left = parseAnd();
+        while (peek() === 'or') {
+            pos++;
+            const l = left, right = parseAnd();
+            left = (config) => l(config) || right(config);
+        }
+        return left;
+    }
+
+    const parseAnd = () => {
+        let left = parseNot();
+        while (peek() === 'and') {
+            pos++;
+            const l = left, right = parseNot();
+            left = (config) => l(config) && right(config);
+        }
+        return left;
+    }
+
+    const parseNot = () => {
+        if (peek() === 'not') {
+            pos++;
+            const expr = parseNot();
+            return (config) => !expr(config);
+        }
+        return parseComparison();
+    }
+
+    const parseComparison = () => {
+        if (peek() === '(') {
+            pos++;
+            const expr = parseOr();
+            next(')');
+            return expr;
+        }
+
+        const id = next('ident').text;
+        const op = peek();
+        if (op === '==' || op === '!=') {
+            pos++;
+            const value = next('string').text;
+            return (config) => ((config[id] || '') === value) !== (op === '!=');
+        }
+        if (op === 'in' || op === 'not') {
+            pos++;
+            if (op === 'not') {
+                next('in');
+            }
+            next('[');
+            const values = [];
+            while (peek() !== ']') {
+                values.push(next('string').text);
+                if (peek() !== ',') {
+                    break;
+                }
+                pos++;
+            }
+            next(']');
+            return (config) => values.includes(config[id] || '') !== (op === 'not');
+        }
+
+        return (config) => {
+            const value = config[id] || '';
+            return value !== '' && value !== 'false';
+        }
+    }
+
+    const expr = parseOr();
+    if (pos < tokens.length) {
+        throw new Error(`unexpected ${tokens[pos].text}`);
+    }
+    return expr;
+}
+
+// isVisible reports whether field should be shown, given the config from the
+// store. Fields with expressions that don't parse are shown, so they can still
+// be filled in.
+export default function isVisible(field, config) {
+    if (!field.visible_if) {
+        return true;
+    }
+
+    const values = {};
+    for (const id in config) {
+        values[id] = config[id].value;
+    }
+
+    try {
+        return parse(field.visible_if)(values);
+    } catch (err) {
+        console.log(`visible_if of ${field.id}: ${err.message}`);
+        return true;
+    }
+}
@@ -1,33 +1,72 @@
 import store from '../../store';
 import { update } from '../preview/previewSlice';
-import { update as updateSchema } from '../schema/schemaSlice';
+import refreshSchema from '../schema/actions';
 import { set as setError, clear as clearErrors } from '../errors/errorSlice';
 
+// the server sends a heartbeat every 15 seconds, so a connection that's
+// been quiet for longer than this has gone stale
+const staleAfter = 40000;
+
+// reconnects back off from the first delay to the last
+const minReconnectDelay = 1000;
+const maxReconnectDelay = 30000;
+
+const lostConnection = 'lost connection to pixlet, reconnecting';
+
 export default class Watcher {
     constructor() {
+        // where the last connection left off, so that reconnecting resumes
+        // from there rather than missing updates
+        this.epoch = '';
+        this.seq = 0;
+        this.reconnectDelay = minReconnectDelay;
         this.connect();
     }
 
     connect() {
         const proto = document.location.protocol === "https:" ? "wss:" : "ws:";
-        this.conn = new WebSocket(proto + '//' + document.location.host + document.location.pathname + '/api/v1/ws');
-        this.conn.open = this.open.bind(this);
+        const params = new URLSearchParams();
+        if (this.epoch) {
+            params.set('epoch', this.epoch);
+            params.set('since', this.seq);
+        }
+        let url = proto + '//' + document.location.host + document.location.pathname + '/api/v1/ws';
+        if (params.size > 0) {
+            url += '?' + params.toString();
+        }
+
+        this.conn = new WebSocket(url);
+        this.conn.onopen = this.open.bind(this);
         this.conn.onmessage = this.process.bind(this);
         this.conn.onclose = this.close.bind(this);
-        setTimeout(this.check.bind(this), 5000);
+        this.watch();
     }
 
     open(e) {
         console.log('[watcher] connection established');
+        this.reconnectDelay = minReconnectDelay;
         store.dispatch(clearErrors());
     }
 
     process(e) {
-        console.log('[watcher] received new message');
+        this.watch();
         const data = JSON.parse(e.data);
+        if (data.seq) {
+            this.seq = data.seq;
+        }
 
         switch (data.type) {
+            case 'hello':
+                if (data.epoch !== this.epoch) {
+                    // the server restarted, and is sending everything again
+                    this.epoch = data.epoch;
+                    this.seq = 0;
+                }
+                break;
+            case 'heartbeat':
+                break;
             case 'img':
+                console.log('[watcher] received new image');
                 store.dispatch(update({
                     img: data.message,
                     img_type: data.img_type
@@ -35,7 +74,9 @@ export default class Watcher {
                 store.dispatch(clearErrors());
                 break;
             case 'schema':
-                store.dispatch(updateSchema(JSON.parse(data.message)));
+                // fetch the schema rather than using the message, so that
+                // it's translated to the browser's language
+                refreshSchema();
                 break;
             case 'error':
                 store.dispatch(setError({ id: data.message, message: data.message }));
@@ -45,22 +86,29 @@ export default class Watcher {
         }
     }
 
-    check() {
-        if (this.conn.readyState === WebSocket.CONNECTING) {
-            console.log('[watcher] connection timed out');
-            this.reconnect();
-        }
+    // watch reconnects if nothing arrives on the connection for a while,
+    // such as when a proxy or network drops it without closing it.
+    watch() {
+        clearTimeout(this.staleTimer);
+        this.staleTimer = setTimeout(() => {
+            console.log('[watcher] connection went stale');
+            // closing cleanly can take as long as the network is down, so
+            // don't wait for it
+            this.conn.onclose = null;
+            this.conn.close();
+            this.close();
+        }, staleAfter);
     }
 
     close(e) {
-        let msg = `lost connection to pixlet, please refresh page: ${e.code}`;
-        store.dispatch(setError({ id: msg, message: msg }));
-        // TODO: we should in theory be able to reconnect here.
-        // this.reconnect();
+        clearTimeout(this.staleTimer);
+        store.dispatch(setError({ id: lostConnection, message: lostConnection }));
+        this.reconnect();
     }
 
     reconnect() {
-        console.log('[watcher] reestablishing connection');
-        this.connect();
+        console.log(`[watcher] reestablishing connection in ${this.reconnectDelay}ms`);
+        setTimeout(this.connect.bind(this), this.reconnectDelay);
+        this.reconnectDelay = Math.min(this.reconnectDelay * 2, maxReconnectDelay);
     }
-}
\ No newline at end of file
+}
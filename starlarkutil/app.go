@@ -0,0 +1,15 @@
+package starlarkutil
+
+import (
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// AppID returns the ID of the app running on a Starlark thread. Threads are
+// named after their app's ID, which hosts can follow with a slash and more,
+// such as "clock/abc123" for an installation of it.
+func AppID(thread *starlark.Thread) string {
+	id, _, _ := strings.Cut(thread.Name, "/")
+	return id
+}
@@ -0,0 +1,14 @@
+package starlarkutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.starlark.net/starlark"
+)
+
+func TestAppID(t *testing.T) {
+	assert.Equal(t, "clock", AppID(&starlark.Thread{Name: "clock"}))
+	assert.Equal(t, "clock", AppID(&starlark.Thread{Name: "clock/abc123"}))
+	assert.Equal(t, "", AppID(&starlark.Thread{}))
+}
@@ -0,0 +1,190 @@
+// Package appdocs generates documentation for apps from their manifest,
+// schema and renders, so that READMEs stay up to date as apps change.
+package appdocs
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"tidbyt.dev/pixlet/manifest"
+	"tidbyt.dev/pixlet/schema"
+)
+
+// GeneratedMarker starts documents generated by this package, so they can
+// be told apart from hand-written ones before they're overwritten.
+const GeneratedMarker = "<!-- Generated by pixlet docs. Edit the app's manifest and schema instead. -->"
+
+// Example is a render of the app with a config, shown in its docs.
+type Example struct {
+	Title  string
+	Config map[string]string
+
+	// Image is the render, and File the name it's written to, relative
+	// to the docs.
+	Image     []byte
+	ImageType string
+	File      string
+}
+
+// Doc is the documentation of an app.
+type Doc struct {
+	// ID is the app's ID, used as its title when it has no manifest.
+	ID string
+
+	// Manifest and Schema may be nil if the app has none.
+	Manifest *manifest.Manifest
+	Schema   *schema.Schema
+
+	// Examples are renders of the app. The first is shown with the app's
+	// description, and is usually of its default config.
+	Examples []Example
+}
+
+// Title returns the name of the app.
+func (d *Doc) Title() string {
+	if d.Manifest != nil && d.Manifest.Name != "" {
+		return d.Manifest.Name
+	}
+	return d.ID
+}
+
+// ExampleTitle names an example by its config, such as "units=f". An empty
+// config is the default.
+func ExampleTitle(config map[string]string) string {
+	if len(config) == 0 {
+		return "Default settings"
+	}
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+config[key])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseExample parses the config of an example as a query string, such as
+// "units=f&location=home".
+func ParseExample(query string) (map[string]string, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid example %q: %w", query, err)
+	}
+
+	config := map[string]string{}
+	for key, v := range values {
+		config[key] = v[len(v)-1]
+	}
+	return config, nil
+}
+
+// fact is a row of the table of facts about an app, such as its author.
+type fact struct {
+	Name  string
+	Value string
+
+	// Code values are identifiers, shown in a monospace font.
+	Code []string
+}
+
+// facts returns what the manifest says about the app, other than its
+// name and description.
+func (d *Doc) facts() []fact {
+	m := d.Manifest
+	if m == nil {
+		return nil
+	}
+
+	var facts []fact
+	if m.Author != "" {
+		facts = append(facts, fact{Name: "Author", Value: m.Author})
+	}
+	if m.Version != "" {
+		facts = append(facts, fact{Name: "Version", Value: m.Version})
+	}
+	if m.RefreshInterval != "" {
+		facts = append(facts, fact{Name: "Refreshes every", Value: m.RefreshInterval})
+	}
+	if c := m.Capabilities; c != nil {
+		if len(c.Network) > 0 {
+			facts = append(facts, fact{Name: "Network access", Code: c.Network})
+		}
+		if len(c.Secrets) > 0 {
+			facts = append(facts, fact{Name: "Secrets", Code: c.Secrets})
+		}
+		if c.MinDisplaySize != nil {
+			facts = append(facts, fact{Name: "Minimum display size", Value: fmt.Sprintf("%dx%d", c.MinDisplaySize.Width, c.MinDisplaySize.Height)})
+		}
+	}
+	return facts
+}
+
+// setting is a row of the table of an app's settings.
+type setting struct {
+	Name string
+	ID   string
+	Type string
+
+	// Depth is how deeply the setting is nested in groups.
+	Depth int
+
+	// Default is the default value, as it's shown to users, and
+	// DefaultValue as it's stored in config.
+	Default      string
+	DefaultValue string
+
+	Description string
+	Options     []schema.SchemaOption
+}
+
+// settings flattens schema fields into rows, with the fields of groups
+// following the group.
+func settings(fields []schema.SchemaField, depth int) []setting {
+	var rows []setting
+	for _, f := range fields {
+		row := setting{
+			Name:         f.Name,
+			ID:           f.ID,
+			Type:         f.Type,
+			Depth:        depth,
+			DefaultValue: f.Default,
+			Description:  f.Description,
+			Options:      f.Options,
+		}
+
+		row.Default = f.Default
+		for _, o := range f.Options {
+			if o.Value == f.Default {
+				row.Default = optionLabel(o)
+			}
+		}
+
+		switch {
+		case f.Type == "generated":
+			row.Description = fmt.Sprintf("Settings that depend on %s.", f.Source)
+		case f.Required && row.Description != "":
+			row.Description += " Required."
+		case f.Required:
+			row.Description = "Required."
+		}
+
+		rows = append(rows, row)
+		rows = append(rows, settings(f.Fields, depth+1)...)
+	}
+	return rows
+}
+
+// optionLabel returns what users see for an option.
+func optionLabel(o schema.SchemaOption) string {
+	if o.Display != "" {
+		return o.Display
+	}
+	return o.Text
+}
@@ -0,0 +1,131 @@
+package appdocs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/manifest"
+	"tidbyt.dev/pixlet/schema"
+)
+
+func testDoc() *Doc {
+	return &Doc{
+		ID: "weather",
+		Manifest: &manifest.Manifest{
+			Name:            "Weather",
+			Summary:         "Local forecast",
+			Desc:            "The forecast where you are.",
+			Author:          "Grace Hopper",
+			RefreshInterval: "15m",
+			Capabilities: &manifest.Capabilities{
+				Network: []string{"api.weather.gov", "*.example.com"},
+			},
+		},
+		Schema: &schema.Schema{
+			Version: "1",
+			Fields: []schema.SchemaField{
+				{
+					Type:     "location",
+					ID:       "location",
+					Name:     "Location",
+					Required: true,
+				},
+				{
+					Type:        "dropdown",
+					ID:          "units",
+					Name:        "Units",
+					Description: "Celsius | Fahrenheit",
+					Default:     "c",
+					Options: []schema.SchemaOption{
+						{Display: "Celsius", Value: "c"},
+						{Text: "Fahrenheit", Value: "f"},
+					},
+				},
+				{
+					Type: "group",
+					ID:   "advanced",
+					Name: "Advanced",
+					Fields: []schema.SchemaField{
+						{Type: "onoff", ID: "wind", Name: "Show wind", Default: "false"},
+					},
+				},
+				{Type: "generated", ID: "stations", Source: "location", Handler: "stations"},
+			},
+		},
+		Examples: []Example{
+			{Title: "Default settings", Image: []byte("GIF89a"), ImageType: "image/gif", File: "weather.gif"},
+			{Title: "units=f", Image: []byte("GIF89a"), ImageType: "image/gif", File: "weather_2.gif"},
+		},
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testDoc().WriteMarkdown(&buf))
+
+	assert.Equal(t, GeneratedMarker+`
+
+# Weather
+
+Local forecast
+
+![Weather](weather.gif)
+
+The forecast where you are.
+
+| | |
+| --- | --- |
+| Author | Grace Hopper |
+| Refreshes every | 15m |
+| Network access | `+"`api.weather.gov`, `*.example.com`"+` |
+
+## Settings
+
+| Setting | ID | Type | Default | Description |
+| --- | --- | --- | --- | --- |
+| Location | `+"`location`"+` | location |  | Required. |
+| Units | `+"`units`"+` | dropdown | Celsius (`+"`c`"+`) | Celsius \| Fahrenheit Options: Celsius (`+"`c`"+`), Fahrenheit (`+"`f`"+`). |
+| Advanced | `+"`advanced`"+` | group |  |  |
+| &nbsp;&nbsp;↳ Show wind | `+"`wind`"+` | onoff | false |  |
+|  | `+"`stations`"+` | generated |  | Settings that depend on location. |
+
+## Examples
+
+### units=f
+
+![units=f](weather_2.gif)
+`, buf.String())
+}
+
+func TestWriteMarkdownWithoutManifest(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, (&Doc{ID: "clock"}).WriteMarkdown(&buf))
+	assert.Equal(t, GeneratedMarker+"\n\n# clock\n", buf.String())
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testDoc().WriteHTML(&buf))
+	html := buf.String()
+
+	assert.Contains(t, html, GeneratedMarker)
+	assert.Contains(t, html, "<h1>Weather</h1>")
+	assert.Contains(t, html, `<img src="data:image/gif;base64,R0lGODlh" alt="Weather">`)
+	assert.Contains(t, html, "<td>Celsius | Fahrenheit Options: Celsius (<code>c</code>), Fahrenheit (<code>f</code>).</td>")
+	assert.Contains(t, html, "<td>\u00a0\u00a0↳ Show wind</td>")
+	assert.Contains(t, html, "<h3>units=f</h3>")
+}
+
+func TestExamples(t *testing.T) {
+	config, err := ParseExample("units=f&location=home&units=c")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"units": "c", "location": "home"}, config)
+	assert.Equal(t, "location=home, units=c", ExampleTitle(config))
+	assert.Equal(t, "Default settings", ExampleTitle(nil))
+
+	_, err = ParseExample("units=%zz")
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package appdocs
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io"
+	"strings"
+)
+
+var htmlTemplate = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"indent": func(depth int) string { return strings.Repeat("  ", depth) },
+	"image": func(e Example) template.URL {
+		return template.URL("data:" + e.ImageType + ";base64," + base64.StdEncoding.EncodeToString(e.Image))
+	},
+	"label": optionLabel,
+	// html/template drops comments, so the marker is passed as trusted HTML
+	"marker": func() template.HTML { return template.HTML(GeneratedMarker) },
+}).Parse(`<!DOCTYPE html>
+{{marker}}
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; }
+img { image-rendering: pixelated; background: #000; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Manifest}}{{if .Summary}}<p><em>{{.Summary}}</em></p>{{end}}{{end}}
+{{with .Examples}}<p><img src="{{image (index . 0)}}" alt="{{$.Title}}"></p>{{end}}
+{{with .Manifest}}{{if .Desc}}<p>{{.Desc}}</p>{{end}}{{end}}
+{{with .Facts}}<table>
+{{range .}}<tr><th>{{.Name}}</th><td>{{if .Code}}{{range $i, $c := .Code}}{{if $i}}, {{end}}<code>{{$c}}</code>{{end}}{{else}}{{.Value}}{{end}}</td></tr>
+{{end}}</table>{{end}}
+{{with .Settings}}<h2>Settings</h2>
+<table>
+<tr><th>Setting</th><th>ID</th><th>Type</th><th>Default</th><th>Description</th></tr>
+{{range .}}<tr><td>{{if .Depth}}{{indent .Depth}}↳ {{end}}{{.Name}}</td><td><code>{{.ID}}</code></td><td>{{.Type}}</td><td>{{if .DefaultValue}}{{.Default}}{{if ne .Default .DefaultValue}} (<code>{{.DefaultValue}}</code>){{end}}{{end}}</td><td>{{.Description}}{{with .Options}} Options: {{range $i, $o := .}}{{if $i}}, {{end}}{{label $o}} (<code>{{$o.Value}}</code>){{end}}.{{end}}</td></tr>
+{{end}}</table>{{end}}
+{{if gt (len .Examples) 1}}<h2>Examples</h2>
+{{range slice .Examples 1}}<h3>{{.Title}}</h3>
+<p><img src="{{image .}}" alt="{{.Title}}"></p>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the docs as a standalone HTML page, with the examples'
+// images embedded in it.
+func (d *Doc) WriteHTML(w io.Writer) error {
+	data := struct {
+		*Doc
+		Facts    []fact
+		Settings []setting
+	}{Doc: d, Facts: d.facts()}
+	if d.Schema != nil {
+		data.Settings = settings(d.Schema.Fields, 0)
+	}
+	return htmlTemplate.Execute(w, data)
+}
@@ -0,0 +1,99 @@
+package appdocs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown writes the docs as Markdown, such as for an app's README.
+// Examples' images are linked to by their File.
+func (d *Doc) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(GeneratedMarker + "\n\n")
+	fmt.Fprintf(&b, "# %s\n\n", d.Title())
+
+	m := d.Manifest
+	if m != nil && m.Summary != "" {
+		fmt.Fprintf(&b, "%s\n\n", m.Summary)
+	}
+	if len(d.Examples) > 0 {
+		fmt.Fprintf(&b, "![%s](%s)\n\n", d.Title(), d.Examples[0].File)
+	}
+	if m != nil && m.Desc != "" {
+		fmt.Fprintf(&b, "%s\n\n", m.Desc)
+	}
+
+	if facts := d.facts(); len(facts) > 0 {
+		b.WriteString("| | |\n| --- | --- |\n")
+		for _, f := range facts {
+			value := markdownCell(f.Value)
+			if len(f.Code) > 0 {
+				codes := make([]string, len(f.Code))
+				for i, c := range f.Code {
+					codes[i] = markdownCode(c)
+				}
+				value = strings.Join(codes, ", ")
+			}
+			fmt.Fprintf(&b, "| %s | %s |\n", f.Name, value)
+		}
+		b.WriteString("\n")
+	}
+
+	if d.Schema != nil && len(d.Schema.Fields) > 0 {
+		b.WriteString("## Settings\n\n")
+		b.WriteString("| Setting | ID | Type | Default | Description |\n")
+		b.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, s := range settings(d.Schema.Fields, 0) {
+			name := markdownCell(s.Name)
+			if s.Depth > 0 {
+				name = strings.Repeat("&nbsp;&nbsp;", s.Depth) + "↳ " + name
+			}
+
+			def := ""
+			if s.DefaultValue != "" {
+				def = markdownCell(s.Default)
+				if s.Default != s.DefaultValue {
+					def += " (" + markdownCode(s.DefaultValue) + ")"
+				}
+			}
+
+			desc := markdownCell(s.Description)
+			if len(s.Options) > 0 {
+				options := make([]string, len(s.Options))
+				for i, o := range s.Options {
+					options[i] = markdownCell(optionLabel(o)) + " (" + markdownCode(o.Value) + ")"
+				}
+				desc = strings.TrimSpace(desc + " Options: " + strings.Join(options, ", ") + ".")
+			}
+
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", name, markdownCode(s.ID), s.Type, def, desc)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(d.Examples) > 1 {
+		b.WriteString("## Examples\n\n")
+		for _, e := range d.Examples[1:] {
+			fmt.Fprintf(&b, "### %s\n\n![%s](%s)\n\n", markdownCell(e.Title), markdownCell(e.Title), e.File)
+		}
+	}
+
+	_, err := io.WriteString(w, strings.TrimRight(b.String(), "\n")+"\n")
+	return err
+}
+
+// markdownCell escapes text for a cell of a table, which ends at a pipe
+// or a newline.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// markdownCode formats an identifier as code, in a table cell.
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(s, "|", `\|`) + "`"
+}
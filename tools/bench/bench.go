@@ -0,0 +1,198 @@
+// Package bench measures how long applets take to render and how big their
+// output is, and compares the measurements with a baseline so that changes
+// which make applets dramatically slower or bigger can be caught in CI.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"time"
+
+	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+// Options configure how an applet is measured.
+type Options struct {
+	// Runs is how many times the applet is rendered. Zero means 10.
+	Runs int
+
+	// Config is what the applet is run with.
+	Config map[string]string
+
+	// Timeout is how long each render may take. Zero means 30 seconds.
+	Timeout time.Duration
+
+	// MaxDuration caps the length of animations, in milliseconds, as for
+	// pixlet render. Zero means no limit.
+	MaxDuration int
+
+	// Fixtures is a directory of HTTP fixtures, as recorded by pixlet test
+	// --record, that the applet's requests are replayed from, so that the
+	// network doesn't affect measurements.
+	Fixtures string
+}
+
+// Result is what rendering an applet costs.
+type Result struct {
+	// Time is the median time taken to run the applet, paint its frames
+	// and encode them as WebP.
+	Time time.Duration `json:"time_ns"`
+
+	// ExecutionSteps counts the Starlark computation steps of a render.
+	// Unlike time it doesn't depend on the machine or how busy it is, so
+	// it's comparable between CI runners.
+	ExecutionSteps uint64 `json:"execution_steps"`
+
+	// Size is the size of the WebP rendered, in bytes.
+	Size int `json:"size"`
+}
+
+// Measure renders the applet opts.Runs times and returns what it costs.
+// Every render starts with nothing cached, and the applet should be
+// deterministic so that renders do the same work.
+func Measure(ctx context.Context, app *runtime.Applet, opts Options) (*Result, error) {
+	if opts.Runs <= 0 {
+		opts.Runs = 10
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	result := &Result{}
+	times := make([]time.Duration, 0, opts.Runs)
+	for i := 0; i < opts.Runs; i++ {
+		cache := runtime.NewInMemoryCache()
+		runtime.InitHTTPFixtures(cache, &runtime.HTTPFixtures{Dir: opts.Fixtures, Mode: runtime.FixturesReplay})
+		runtime.InitCache(cache)
+
+		elapsed, stats, size, err := renderOnce(ctx, app, opts)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, elapsed)
+		result.ExecutionSteps = stats.ExecutionSteps
+		result.Size = size
+	}
+
+	slices.Sort(times)
+	result.Time = times[len(times)/2]
+	return result, nil
+}
+
+// renderOnce renders the applet, returning how long it took, the resources
+// it used and the size of its output.
+func renderOnce(ctx context.Context, app *runtime.Applet, opts Options) (time.Duration, runtime.UsageStats, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	ctx, meter := runtime.MeterUsage(ctx)
+
+	start := time.Now()
+	roots, err := app.RunWithConfig(ctx, opts.Config)
+	if err != nil {
+		return 0, runtime.UsageStats{}, 0, fmt.Errorf("error running script: %w", err)
+	}
+	webp, err := encode.ScreensFromRoots(roots).EncodeWebP(opts.MaxDuration)
+	if err != nil {
+		return 0, runtime.UsageStats{}, 0, fmt.Errorf("error rendering: %w", err)
+	}
+	return time.Since(start), meter.Stats(), len(webp), nil
+}
+
+// Baseline is the results of a set of applets, keyed by their path.
+type Baseline struct {
+	Apps map[string]*Result `json:"apps"`
+}
+
+// LoadBaseline reads a baseline saved with Save.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Baseline{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("parsing baseline %s: %w", path, err)
+	}
+	if b.Apps == nil {
+		b.Apps = map[string]*Result{}
+	}
+	return b, nil
+}
+
+// LoadBaselineIfExists is like LoadBaseline, but returns an empty baseline
+// if there's no file at path.
+func LoadBaselineIfExists(path string) (*Baseline, error) {
+	b, err := LoadBaseline(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Baseline{Apps: map[string]*Result{}}, nil
+	}
+	return b, err
+}
+
+// Save writes the baseline to path.
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Metric is one of the costs of a render that's compared with a baseline.
+type Metric string
+
+const (
+	MetricTime           Metric = "time"
+	MetricExecutionSteps Metric = "execution_steps"
+	MetricSize           Metric = "size"
+)
+
+// timeSlack is how much slower a render must be before it's a regression,
+// however small the baseline. Timings of fast renders vary by more than
+// any sensible threshold.
+const timeSlack = 2 * time.Millisecond
+
+// Change is how a metric changed from the baseline.
+type Change struct {
+	Metric   Metric
+	Old, New float64
+
+	// Regressed is set if the metric got worse by more than the threshold
+	// passed to Compare.
+	Regressed bool
+}
+
+// Fraction is how much the metric changed relative to the baseline, such
+// as 0.1 for 10% worse. It's infinite if the baseline was zero.
+func (c Change) Fraction() float64 {
+	if c.Old == c.New {
+		return 0
+	}
+	return (c.New - c.Old) / c.Old
+}
+
+// Compare compares a result with the baseline's, reporting a regression for
+// each metric that's worse by more than threshold, a fraction of the
+// baseline.
+func Compare(old, new *Result, threshold float64) []Change {
+	changes := []Change{
+		{Metric: MetricTime, Old: float64(old.Time), New: float64(new.Time)},
+		{Metric: MetricExecutionSteps, Old: float64(old.ExecutionSteps), New: float64(new.ExecutionSteps)},
+		{Metric: MetricSize, Old: float64(old.Size), New: float64(new.Size)},
+	}
+	for i, c := range changes {
+		regressed := c.Fraction() > threshold
+		if c.Metric == MetricTime && c.New-c.Old <= float64(timeSlack) {
+			regressed = false
+		}
+		changes[i].Regressed = regressed
+	}
+	return changes
+}
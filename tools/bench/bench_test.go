@@ -0,0 +1,89 @@
+package bench
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+var benchSrc = `
+load("render.star", "render")
+
+def main(config):
+    n = int(config.get("n", "10"))
+    total = 0
+    for i in range(n):
+        total += i
+    return render.Root(child = render.Text("Ada %d" % total))
+`
+
+func TestMeasure(t *testing.T) {
+	app, err := runtime.NewApplet("bench.star", []byte(benchSrc), runtime.WithDeterministic())
+	require.NoError(t, err)
+
+	small, err := Measure(context.Background(), app, Options{Runs: 3, Config: map[string]string{"n": "10"}})
+	require.NoError(t, err)
+	assert.Greater(t, small.Time, time.Duration(0))
+	assert.Greater(t, small.Size, 0)
+
+	big, err := Measure(context.Background(), app, Options{Runs: 3, Config: map[string]string{"n": "1000"}})
+	require.NoError(t, err)
+	assert.Greater(t, big.ExecutionSteps, small.ExecutionSteps+1000)
+
+	// steps don't vary between runs
+	again, err := Measure(context.Background(), app, Options{Runs: 1, Config: map[string]string{"n": "10"}})
+	require.NoError(t, err)
+	assert.Equal(t, small.ExecutionSteps, again.ExecutionSteps)
+}
+
+func TestMeasureError(t *testing.T) {
+	app, err := runtime.NewApplet("bench.star", []byte(benchSrc))
+	require.NoError(t, err)
+
+	_, err = Measure(context.Background(), app, Options{Config: map[string]string{"n": "lots"}})
+	assert.ErrorContains(t, err, "error running script")
+}
+
+func TestCompare(t *testing.T) {
+	old := &Result{Time: 100 * time.Millisecond, ExecutionSteps: 1000, Size: 2000}
+
+	changes := Compare(old, &Result{Time: 105 * time.Millisecond, ExecutionSteps: 1200, Size: 1800}, 0.1)
+	require.Len(t, changes, 3)
+	assert.Equal(t, MetricTime, changes[0].Metric)
+	assert.InDelta(t, 0.05, changes[0].Fraction(), 1e-9)
+	assert.False(t, changes[0].Regressed)
+	assert.InDelta(t, 0.2, changes[1].Fraction(), 1e-9)
+	assert.True(t, changes[1].Regressed)
+	assert.InDelta(t, -0.1, changes[2].Fraction(), 1e-9)
+	assert.False(t, changes[2].Regressed)
+
+	// fast renders must slow down by more than the clock's noise
+	fast := &Result{Time: time.Millisecond}
+	changes = Compare(fast, &Result{Time: 2 * time.Millisecond}, 0.1)
+	assert.False(t, changes[0].Regressed)
+	changes = Compare(fast, &Result{Time: 4 * time.Millisecond}, 0.1)
+	assert.True(t, changes[0].Regressed)
+}
+
+func TestBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+
+	_, err := LoadBaseline(path)
+	assert.Error(t, err)
+
+	b, err := LoadBaselineIfExists(path)
+	require.NoError(t, err)
+	assert.Empty(t, b.Apps)
+
+	b.Apps["apps/clock"] = &Result{Time: time.Millisecond, ExecutionSteps: 42, Size: 512}
+	require.NoError(t, b.Save(path))
+
+	loaded, err := LoadBaseline(path)
+	require.NoError(t, err)
+	assert.Equal(t, b, loaded)
+}
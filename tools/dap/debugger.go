@@ -0,0 +1,444 @@
+// Package dap implements a Debug Adapter Protocol server for pixlet apps,
+// letting editors set breakpoints in an app, step through it as it renders
+// and inspect its variables.
+package dap
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.starlark.net/starlark"
+)
+
+// stepMode is what a thread that was resumed is doing.
+type stepMode int
+
+const (
+	// running until a breakpoint
+	running stepMode = iota
+
+	// stepping over calls, to the next statement at the same or a
+	// shallower depth
+	stepOver
+
+	// stepping into calls, to the very next statement
+	stepIn
+
+	// stepping out of the current function
+	stepOut
+)
+
+// Debugger pauses the threads of applets at breakpoints and while stepping
+// through them. It's a runtime.Probe, and pauses nothing until a client
+// connects.
+type Debugger struct {
+	// dir is the directory of the app, which the names of its files are
+	// relative to.
+	dir string
+
+	mu sync.Mutex
+
+	// lines are the lines of the probes in each file that's been loaded.
+	lines map[string][]int
+
+	// breakpoints are the IDs of the breakpoints on each line of each file.
+	breakpoints    map[string]map[int]int
+	nextBreakpoint int
+
+	// pausing is set when the client asks for threads to pause, and the
+	// next thread to run a statement pauses.
+	pausing bool
+
+	threads    map[*starlark.Thread]*thread
+	nextThread int
+
+	// refs are the frames, scopes and values the client can refer to while
+	// threads are paused.
+	refs []interface{}
+
+	session *session
+}
+
+// thread is a thread that's paused, or that's stepping.
+type thread struct {
+	id int
+	t  *starlark.Thread
+
+	// the statement the thread is paused at, and how many frames deep in
+	// the call stack it is
+	file  string
+	line  int
+	depth int
+
+	mode   stepMode
+	resume chan struct{}
+}
+
+// frameRef refers to a frame of a paused thread, by its depth in the call
+// stack from the innermost.
+type frameRef struct {
+	thread *thread
+	depth  int
+}
+
+// scopeRef refers to the variables of a frame.
+type scopeRef struct {
+	vars []variable
+}
+
+type variable struct {
+	name  string
+	value starlark.Value
+}
+
+// NewDebugger returns a debugger for the app in dir.
+func NewDebugger(dir string) *Debugger {
+	return &Debugger{
+		dir:         dir,
+		lines:       map[string][]int{},
+		breakpoints: map[string]map[int]int{},
+		threads:     map[*starlark.Thread]*thread{},
+	}
+}
+
+// Instrumented implements runtime.Probe.
+func (d *Debugger) Instrumented(file string, lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lines[file] = lines
+}
+
+// Hit implements runtime.Probe, pausing the thread if it's reached a
+// breakpoint or the end of a step, until the client resumes it.
+func (d *Debugger) Hit(t *starlark.Thread, file string, probe int) {
+	d.mu.Lock()
+	if d.session == nil {
+		d.mu.Unlock()
+		return
+	}
+
+	line := 0
+	if lines := d.lines[file]; probe < len(lines) {
+		line = lines[probe]
+	}
+	// the statement's frame is the one under the probe's
+	depth := t.CallStackDepth() - 1
+
+	th := d.threads[t]
+	var stopped StoppedEvent
+	if id := d.breakpoints[file][line]; id != 0 {
+		stopped = StoppedEvent{Reason: "breakpoint", HitBreakpointIDs: []int{id}}
+	} else if th != nil && th.stepped(depth) {
+		stopped = StoppedEvent{Reason: "step"}
+	} else if d.pausing {
+		stopped = StoppedEvent{Reason: "pause"}
+	} else {
+		d.mu.Unlock()
+		return
+	}
+
+	d.pausing = false
+	if th == nil {
+		d.nextThread++
+		th = &thread{id: d.nextThread, t: t}
+		d.threads[t] = th
+	}
+	th.file, th.line, th.depth = file, line, depth
+	th.mode = running
+	th.resume = make(chan struct{})
+	resume := th.resume
+	s := d.session
+	d.mu.Unlock()
+
+	stopped.ThreadID = th.id
+	s.event("stopped", stopped)
+	<-resume
+}
+
+// stepped reports whether a thread stepping has reached the end of its step
+// at a statement at depth.
+func (th *thread) stepped(depth int) bool {
+	switch th.mode {
+	case stepIn:
+		return true
+	case stepOver:
+		return depth <= th.depth
+	case stepOut:
+		return depth < th.depth
+	}
+	return false
+}
+
+// paused reports whether the thread is paused. It's called with d.mu held.
+func (th *thread) paused() bool {
+	if th.resume == nil {
+		return false
+	}
+	select {
+	case <-th.resume:
+		return false
+	default:
+		return true
+	}
+}
+
+// resume resumes a paused thread, stepping as mode says. It's called with
+// d.mu held.
+func (d *Debugger) resume(th *thread, mode stepMode) {
+	if !th.paused() {
+		return
+	}
+	th.mode = mode
+	if mode == running {
+		delete(d.threads, th.t)
+	}
+	close(th.resume)
+
+	for _, other := range d.threads {
+		if other.paused() {
+			return
+		}
+	}
+	// nothing's paused, so nothing can be referred to
+	d.refs = nil
+}
+
+// resumeAll resumes every paused thread, and stops every step. It's called
+// with d.mu held.
+func (d *Debugger) resumeAll() {
+	for _, th := range d.threads {
+		d.resume(th, running)
+	}
+	d.threads = map[*starlark.Thread]*thread{}
+	d.pausing = false
+}
+
+// thread returns the paused thread with an ID.
+func (d *Debugger) thread(id int) (*thread, error) {
+	for _, th := range d.threads {
+		if th.id == id && th.paused() {
+			return th, nil
+		}
+	}
+	return nil, fmt.Errorf("thread %d isn't paused", id)
+}
+
+// ref returns a reference the client can use to refer to v.
+func (d *Debugger) ref(v interface{}) int {
+	d.refs = append(d.refs, v)
+	return len(d.refs)
+}
+
+func (d *Debugger) deref(ref int) (interface{}, error) {
+	if ref < 1 || ref > len(d.refs) {
+		return nil, fmt.Errorf("no variables with reference %d", ref)
+	}
+	return d.refs[ref-1], nil
+}
+
+// file returns the name a path has in the app, or "" if it's not in it.
+func (d *Debugger) file(path string) string {
+	rel, err := filepath.Rel(d.dir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// source returns the source of a file in the app.
+func (d *Debugger) source(file string) *Source {
+	return &Source{
+		Name: file,
+		Path: filepath.Join(d.dir, filepath.FromSlash(file)),
+	}
+}
+
+// setBreakpoints replaces the breakpoints in a file. Breakpoints on lines
+// without a statement never hit, and aren't verified.
+func (d *Debugger) setBreakpoints(path string, lines []int) []Breakpoint {
+	file := d.file(path)
+	probes, loaded := d.lines[file]
+
+	bps := map[int]int{}
+	result := []Breakpoint{}
+	for _, line := range lines {
+		bp := Breakpoint{Line: line, Source: &Source{Path: path}}
+		switch {
+		case file == "":
+			bp.Message = "not a file of the app"
+		case !loaded:
+			bp.Message = "not loaded by the app"
+		case !slices.Contains(probes, line):
+			bp.Message = "no statement on this line"
+		default:
+			if bps[line] == 0 {
+				d.nextBreakpoint++
+				bps[line] = d.nextBreakpoint
+			}
+			bp.ID = bps[line]
+			bp.Verified = true
+		}
+		result = append(result, bp)
+	}
+
+	if file != "" {
+		d.breakpoints[file] = bps
+	}
+	return result
+}
+
+// stackTrace returns the frames of a paused thread, innermost first.
+func (d *Debugger) stackTrace(th *thread) []StackFrame {
+	// the innermost frame is the probe's, called by the statement's, and
+	// files are executed as "<app ID>/<file>"
+	prefix := strings.TrimSuffix(th.t.CallFrame(1).Pos.Filename(), th.file)
+
+	frames := []StackFrame{}
+	for depth := 1; depth < th.t.CallStackDepth(); depth++ {
+		fr := th.t.DebugFrame(depth)
+		frame := StackFrame{
+			ID:   d.ref(frameRef{thread: th, depth: depth}),
+			Name: fr.Callable().Name(),
+		}
+
+		// probes move the columns of the lines they're on, so frames are
+		// only positioned by line
+		if depth == 1 {
+			frame.Source = d.source(th.file)
+			frame.Line = th.line
+			frame.Column = 1
+		} else if pos := fr.Position(); pos.IsValid() {
+			frame.Source = d.source(strings.TrimPrefix(pos.Filename(), prefix))
+			frame.Line = int(pos.Line)
+			frame.Column = 1
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// scopes returns the variables of a frame: its locals, and the globals of
+// the file its function is in.
+func (d *Debugger) scopes(ref frameRef) []Scope {
+	fr := ref.thread.t.DebugFrame(ref.depth)
+	fn, ok := fr.Callable().(*starlark.Function)
+	if !ok {
+		return []Scope{}
+	}
+
+	var locals []variable
+	for i := 0; i < fr.NumLocals(); i++ {
+		binding, value := fr.Local(i)
+		if value == nil {
+			// not assigned yet
+			continue
+		}
+		locals = append(locals, variable{binding.Name, value})
+	}
+
+	globals := fn.Globals()
+	var globalVars []variable
+	for _, name := range globals.Keys() {
+		globalVars = append(globalVars, variable{name, globals[name]})
+	}
+
+	return []Scope{
+		{Name: "Locals", VariablesReference: d.ref(scopeRef{locals})},
+		{Name: "Globals", VariablesReference: d.ref(scopeRef{globalVars}), Expensive: true},
+	}
+}
+
+// environment returns the names a frame can refer to, for evaluating
+// expressions in it.
+func (d *Debugger) environment(ref frameRef) starlark.StringDict {
+	env := starlark.StringDict{}
+	fr := ref.thread.t.DebugFrame(ref.depth)
+	fn, ok := fr.Callable().(*starlark.Function)
+	if !ok {
+		return env
+	}
+
+	for name, value := range fn.Globals() {
+		env[name] = value
+	}
+	for i := 0; i < fr.NumLocals(); i++ {
+		binding, value := fr.Local(i)
+		if value != nil && value.Type() != "cell" {
+			env[binding.Name] = value
+		}
+	}
+	return env
+}
+
+// variables returns the variables of a scope, or the elements of a value.
+func (d *Debugger) variables(ref interface{}) []Variable {
+	vars := []Variable{}
+	switch r := ref.(type) {
+	case scopeRef:
+		for _, v := range r.vars {
+			vars = append(vars, d.variable(v.name, v.value))
+		}
+
+	case starlark.IterableMapping:
+		for _, item := range r.Items() {
+			vars = append(vars, d.variable(item[0].String(), item[1]))
+		}
+
+	case starlark.Indexable:
+		for i := 0; i < r.Len(); i++ {
+			vars = append(vars, d.variable(fmt.Sprintf("[%d]", i), r.Index(i)))
+		}
+
+	case starlark.HasAttrs:
+		for _, name := range r.AttrNames() {
+			if value, err := r.Attr(name); err == nil && value != nil {
+				vars = append(vars, d.variable(name, value))
+			}
+		}
+
+	case starlark.Iterable:
+		iter := r.Iterate()
+		defer iter.Done()
+		var x starlark.Value
+		for i := 0; iter.Next(&x); i++ {
+			vars = append(vars, d.variable(fmt.Sprintf("[%d]", i), x))
+		}
+	}
+	return vars
+}
+
+// maxValueLength is how long the values of variables are shown before
+// they're truncated, since the elements of long ones can be expanded.
+const maxValueLength = 200
+
+// variable describes a value, with a reference to expand it if it has
+// elements.
+func (d *Debugger) variable(name string, value starlark.Value) Variable {
+	if value.Type() == "cell" {
+		// variables captured by closures are stored in cells, whose values
+		// debuggers can't see
+		return Variable{Name: name, Value: "(captured by a closure)"}
+	}
+
+	v := Variable{Name: name, Type: value.Type(), Value: value.String()}
+	if runes := []rune(v.Value); len(runes) > maxValueLength {
+		v.Value = string(runes[:maxValueLength]) + "…"
+	}
+
+	switch value := value.(type) {
+	case starlark.String, starlark.Bytes:
+		// strings are iterable, but shown in full
+	case starlark.Sequence:
+		if value.Len() > 0 {
+			v.VariablesReference = d.ref(value)
+		}
+	case starlark.HasAttrs:
+		if _, ok := value.(starlark.Callable); !ok && len(value.AttrNames()) > 0 {
+			v.VariablesReference = d.ref(value)
+		}
+	}
+	return v
+}
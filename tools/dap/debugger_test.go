@@ -0,0 +1,264 @@
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+var debugSource = `load("render.star", "render")
+
+def greeting(name):
+    message = "Hello, " + name
+    return message
+
+def main():
+    names = ["Ada", "Grace"]
+    text = greeting(names[0])
+    return render.Root(child = render.Text(text))
+`
+
+// client talks to a debugger over a pipe. Messages from the debugger are
+// read as they arrive, since pipes don't buffer.
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	msgs   chan map[string]json.RawMessage
+	seq    int
+	events []map[string]json.RawMessage
+}
+
+// connect connects a client to d, returning a channel that's sent the
+// result of serving it.
+func connect(t *testing.T, d *Debugger) (*client, chan error) {
+	conn, server := net.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- d.ServeConn(server)
+		server.Close()
+	}()
+
+	c := &client{t: t, conn: conn, msgs: make(chan map[string]json.RawMessage, 100)}
+	go func() {
+		defer close(c.msgs)
+		in := bufio.NewReader(conn)
+		for {
+			msg, err := readRaw(in)
+			if err != nil {
+				return
+			}
+			c.msgs <- msg
+		}
+	}()
+	t.Cleanup(func() { conn.Close() })
+
+	c.request("initialize", map[string]interface{}{"adapterID": "pixlet"}, nil)
+	c.event("initialized", nil)
+	return c, served
+}
+
+// next returns the next message from the debugger.
+func (c *client) next() map[string]json.RawMessage {
+	msg, ok := <-c.msgs
+	require.True(c.t, ok, "debugger closed the connection")
+	return msg
+}
+
+// request sends a request, and unmarshals the body of its response into
+// body, keeping events that arrive first.
+func (c *client) request(command string, args interface{}, body interface{}) {
+	c.seq++
+	require.NoError(c.t, writeMessage(c.conn, map[string]interface{}{
+		"seq":       c.seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": args,
+	}))
+
+	for {
+		resp := c.next()
+		if string(resp["type"]) == `"event"` {
+			c.events = append(c.events, resp)
+			continue
+		}
+
+		var success bool
+		require.NoError(c.t, json.Unmarshal(resp["success"], &success))
+		require.True(c.t, success, "%s failed: %s", command, resp["message"])
+		if body != nil {
+			require.NoError(c.t, json.Unmarshal(resp["body"], body))
+		}
+		return
+	}
+}
+
+// event waits for an event, and unmarshals its body into body.
+func (c *client) event(name string, body interface{}) {
+	for {
+		var ev map[string]json.RawMessage
+		if len(c.events) > 0 {
+			ev, c.events = c.events[0], c.events[1:]
+		} else {
+			ev = c.next()
+		}
+		if string(ev["event"]) != `"`+name+`"` {
+			continue
+		}
+		if body != nil {
+			require.NoError(c.t, json.Unmarshal(ev["body"], body))
+		}
+		return
+	}
+}
+
+// readRaw reads a response or event.
+func readRaw(in *bufio.Reader) (map[string]json.RawMessage, error) {
+	header, err := textproto.NewReader(in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(in, body); err != nil {
+		return nil, err
+	}
+
+	var msg map[string]json.RawMessage
+	err = json.Unmarshal(body, &msg)
+	return msg, err
+}
+
+func TestDebugger(t *testing.T) {
+	d := NewDebugger("/apps/greeting")
+	applet, err := runtime.NewApplet("app", []byte(debugSource), runtime.WithProbe(d))
+	require.NoError(t, err)
+
+	c, served := connect(t, d)
+
+	var bps struct{ Breakpoints []Breakpoint }
+	c.request("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: "/apps/greeting/app.star"},
+		Breakpoints: []SourceBreakpoint{{Line: 9}, {Line: 3}},
+	}, &bps)
+	require.Len(t, bps.Breakpoints, 2)
+	assert.True(t, bps.Breakpoints[0].Verified)
+	assert.False(t, bps.Breakpoints[1].Verified)
+	assert.Equal(t, "no statement on this line", bps.Breakpoints[1].Message)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := applet.Run(context.Background())
+		done <- err
+	}()
+
+	var stopped StoppedEvent
+	c.event("stopped", &stopped)
+	assert.Equal(t, "breakpoint", stopped.Reason)
+	thread := stopped.ThreadID
+
+	var threads struct{ Threads []Thread }
+	c.request("threads", nil, &threads)
+	require.Len(t, threads.Threads, 1)
+	assert.Equal(t, thread, threads.Threads[0].ID)
+
+	var trace struct{ StackFrames []StackFrame }
+	c.request("stackTrace", ThreadArguments{ThreadID: thread}, &trace)
+	require.NotEmpty(t, trace.StackFrames)
+	assert.Equal(t, "main", trace.StackFrames[0].Name)
+	assert.Equal(t, 9, trace.StackFrames[0].Line)
+	assert.Equal(t, "/apps/greeting/app.star", trace.StackFrames[0].Source.Path)
+
+	var scopes struct{ Scopes []Scope }
+	c.request("scopes", ScopesArguments{FrameID: trace.StackFrames[0].ID}, &scopes)
+	require.Len(t, scopes.Scopes, 2)
+	assert.Equal(t, "Locals", scopes.Scopes[0].Name)
+
+	var locals struct{ Variables []Variable }
+	c.request("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}, &locals)
+	require.Len(t, locals.Variables, 1)
+	names := locals.Variables[0]
+	assert.Equal(t, "names", names.Name)
+	assert.Equal(t, `["Ada", "Grace"]`, names.Value)
+	assert.Equal(t, "list", names.Type)
+
+	var elems struct{ Variables []Variable }
+	c.request("variables", VariablesArguments{VariablesReference: names.VariablesReference}, &elems)
+	assert.Equal(t, []Variable{
+		{Name: "[0]", Value: `"Ada"`, Type: "string"},
+		{Name: "[1]", Value: `"Grace"`, Type: "string"},
+	}, elems.Variables)
+
+	var result struct{ Result string }
+	c.request("evaluate", EvaluateArguments{Expression: "names[1] + '!'", FrameID: trace.StackFrames[0].ID}, &result)
+	assert.Equal(t, `"Grace!"`, result.Result)
+
+	// stepping in stops in greeting, called from main
+	c.request("stepIn", ThreadArguments{ThreadID: thread}, nil)
+	c.event("stopped", &stopped)
+	assert.Equal(t, "step", stopped.Reason)
+	c.request("stackTrace", ThreadArguments{ThreadID: thread}, &trace)
+	require.Len(t, trace.StackFrames, 2)
+	assert.Equal(t, "greeting", trace.StackFrames[0].Name)
+	assert.Equal(t, 4, trace.StackFrames[0].Line)
+	assert.Equal(t, "main", trace.StackFrames[1].Name)
+	assert.Equal(t, 9, trace.StackFrames[1].Line)
+
+	// stepping over stays in greeting
+	c.request("next", ThreadArguments{ThreadID: thread}, nil)
+	c.event("stopped", &stopped)
+	c.request("stackTrace", ThreadArguments{ThreadID: thread}, &trace)
+	assert.Equal(t, "greeting", trace.StackFrames[0].Name)
+	assert.Equal(t, 5, trace.StackFrames[0].Line)
+
+	// stepping out stops at the statement after the call
+	c.request("stepOut", ThreadArguments{ThreadID: thread}, nil)
+	c.event("stopped", &stopped)
+	c.request("stackTrace", ThreadArguments{ThreadID: thread}, &trace)
+	assert.Equal(t, "main", trace.StackFrames[0].Name)
+	assert.Equal(t, 10, trace.StackFrames[0].Line)
+
+	c.request("continue", ThreadArguments{ThreadID: thread}, nil)
+	require.NoError(t, <-done)
+
+	c.request("disconnect", nil, nil)
+	assert.NoError(t, <-served)
+}
+
+func TestDebuggerResumesOnDisconnect(t *testing.T) {
+	d := NewDebugger("/apps/greeting")
+	applet, err := runtime.NewApplet("app", []byte(debugSource), runtime.WithProbe(d))
+	require.NoError(t, err)
+
+	c, served := connect(t, d)
+	c.request("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: "/apps/greeting/app.star"},
+		Breakpoints: []SourceBreakpoint{{Line: 4}},
+	}, nil)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := applet.Run(context.Background())
+		done <- err
+	}()
+	c.event("stopped", nil)
+
+	c.request("disconnect", nil, nil)
+	require.NoError(t, <-served)
+	require.NoError(t, <-done)
+
+	// without a client, breakpoints are gone and nothing pauses
+	_, err = applet.Run(context.Background())
+	assert.NoError(t, err)
+}
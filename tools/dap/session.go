@@ -0,0 +1,281 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// fileOptions are the dialect of Starlark that pixlet runs, for evaluating
+// expressions.
+var fileOptions = &syntax.FileOptions{
+	Set:       true,
+	Recursion: true,
+}
+
+// session is a connected client. Its requests are handled one at a time,
+// while events are sent from the threads that pause.
+type session struct {
+	d *Debugger
+
+	out   io.Writer
+	outMu sync.Mutex
+	seq   int
+}
+
+// Serve serves clients that connect to l, one at a time, until l is closed.
+func (d *Debugger) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := d.ServeConn(conn); err != nil {
+			slog.Warn("debugging session ended", "error", err)
+		}
+		conn.Close()
+	}
+}
+
+// ServeConn serves a client that sends requests to rw and reads responses
+// from it, until it disconnects. When it does, its breakpoints are cleared
+// and paused threads resume.
+func (d *Debugger) ServeConn(rw io.ReadWriter) error {
+	s := &session{d: d, out: rw}
+
+	d.mu.Lock()
+	if d.session != nil {
+		d.mu.Unlock()
+		return fmt.Errorf("another client is debugging")
+	}
+	d.session = s
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.session = nil
+		d.breakpoints = map[string]map[int]int{}
+		d.resumeAll()
+	}()
+
+	br := bufio.NewReader(rw)
+	for {
+		msg, err := readMessage(br)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Type != "request" {
+			continue
+		}
+
+		body, err := s.dispatch(msg)
+		resp := response{
+			Type:       "response",
+			RequestSeq: msg.Seq,
+			Command:    msg.Command,
+			Success:    err == nil,
+			Body:       body,
+		}
+		if err != nil {
+			resp.Message = err.Error()
+		}
+		s.respond(resp)
+
+		switch msg.Command {
+		case "initialize":
+			// breakpoints can be set from now on
+			s.event("initialized", nil)
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+func (s *session) dispatch(msg *message) (interface{}, error) {
+	d := s.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch msg.Command {
+	case "initialize":
+		return map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+		}, nil
+
+	case "launch", "attach", "configurationDone", "setExceptionBreakpoints", "disconnect":
+		// the app is already being served, and threads resume once the
+		// client disconnects
+		return nil, nil
+
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := unmarshalArguments(msg, &args); err != nil {
+			return nil, err
+		}
+		lines := make([]int, len(args.Breakpoints))
+		for i, bp := range args.Breakpoints {
+			lines[i] = bp.Line
+		}
+		return map[string]interface{}{
+			"breakpoints": d.setBreakpoints(args.Source.Path, lines),
+		}, nil
+
+	case "threads":
+		threads := []Thread{}
+		for _, th := range d.threads {
+			if th.paused() {
+				threads = append(threads, Thread{ID: th.id, Name: fmt.Sprintf("%s #%d", th.t.Name, th.id)})
+			}
+		}
+		sort.Slice(threads, func(i, j int) bool { return threads[i].ID < threads[j].ID })
+		return map[string]interface{}{"threads": threads}, nil
+
+	case "stackTrace":
+		var args ThreadArguments
+		if err := unmarshalArguments(msg, &args); err != nil {
+			return nil, err
+		}
+		th, err := d.thread(args.ThreadID)
+		if err != nil {
+			return nil, err
+		}
+		frames := d.stackTrace(th)
+		return map[string]interface{}{
+			"stackFrames": frames,
+			"totalFrames": len(frames),
+		}, nil
+
+	case "scopes":
+		var args ScopesArguments
+		if err := unmarshalArguments(msg, &args); err != nil {
+			return nil, err
+		}
+		frame, err := d.frame(args.FrameID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"scopes": d.scopes(frame)}, nil
+
+	case "variables":
+		var args VariablesArguments
+		if err := unmarshalArguments(msg, &args); err != nil {
+			return nil, err
+		}
+		ref, err := d.deref(args.VariablesReference)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"variables": d.variables(ref)}, nil
+
+	case "evaluate":
+		var args EvaluateArguments
+		if err := unmarshalArguments(msg, &args); err != nil {
+			return nil, err
+		}
+		frame, err := d.frame(args.FrameID)
+		if err != nil {
+			return nil, err
+		}
+		thread := &starlark.Thread{Name: "evaluate"}
+		value, err := starlark.EvalOptions(fileOptions, thread, "<evaluate>", args.Expression, d.environment(frame))
+		if err != nil {
+			return nil, err
+		}
+		v := d.variable("", value)
+		return map[string]interface{}{
+			"result":             v.Value,
+			"type":               v.Type,
+			"variablesReference": v.VariablesReference,
+		}, nil
+
+	case "continue", "next", "stepIn", "stepOut":
+		var args ThreadArguments
+		if err := unmarshalArguments(msg, &args); err != nil {
+			return nil, err
+		}
+		th, err := d.thread(args.ThreadID)
+		if err != nil {
+			return nil, err
+		}
+		d.resume(th, map[string]stepMode{
+			"continue": running,
+			"next":     stepOver,
+			"stepIn":   stepIn,
+			"stepOut":  stepOut,
+		}[msg.Command])
+		if msg.Command == "continue" {
+			return map[string]interface{}{"allThreadsContinued": false}, nil
+		}
+		return nil, nil
+
+	case "pause":
+		d.pausing = true
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("command not supported: %s", msg.Command)
+}
+
+// frame returns the frame a reference refers to, if its thread is still
+// paused.
+func (d *Debugger) frame(ref int) (frameRef, error) {
+	v, err := d.deref(ref)
+	if err != nil {
+		return frameRef{}, err
+	}
+	frame, ok := v.(frameRef)
+	if !ok || !frame.thread.paused() {
+		return frameRef{}, fmt.Errorf("no frame with reference %d", ref)
+	}
+	return frame, nil
+}
+
+func unmarshalArguments(msg *message, v interface{}) error {
+	if len(msg.Arguments) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(msg.Arguments, v); err != nil {
+		return fmt.Errorf("invalid arguments to %s: %w", msg.Command, err)
+	}
+	return nil
+}
+
+func (s *session) respond(resp response) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	s.seq++
+	resp.Seq = s.seq
+	s.write(resp)
+}
+
+func (s *session) event(name string, body interface{}) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	s.seq++
+	s.write(event{Seq: s.seq, Type: "event", Event: name, Body: body})
+}
+
+// write writes a message. It's called with s.outMu held.
+func (s *session) write(msg interface{}) {
+	if err := writeMessage(s.out, msg); err != nil {
+		slog.Warn("writing debug adapter message", "error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package fontimport
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+)
+
+// WriteBDF writes the font in BDF, the format of the fonts in pixlet's
+// fonts directory.
+func (f *Font) WriteBDF(w io.Writer) error {
+	var bounds image.Rectangle
+	totalAdvance := 0
+	for _, g := range f.Glyphs {
+		bounds = bounds.Union(g.Bitmap.Rect)
+		totalAdvance += g.Advance
+	}
+	averageWidth := totalAdvance * 10 / len(f.Glyphs)
+
+	// XLFD names are separated by dashes, so names can't contain any
+	xlfdName := strings.NewReplacer("-", " ", "\n", " ").Replace(f.Name)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "STARTFONT 2.1\n")
+	fmt.Fprintf(bw, "FONT -pixlet-%s-Medium-R-Normal--%d-%d-72-72-P-%d-ISO10646-1\n", strings.ReplaceAll(xlfdName, " ", ""), f.Size, f.Size*10, averageWidth)
+	fmt.Fprintf(bw, "SIZE %d 72 72\n", f.Size)
+	fmt.Fprintf(bw, "FONTBOUNDINGBOX %d %d %d %d\n", bounds.Dx(), bounds.Dy(), bounds.Min.X, -bounds.Max.Y)
+	fmt.Fprintf(bw, "STARTPROPERTIES 8\n")
+	fmt.Fprintf(bw, "FAMILY_NAME %q\n", xlfdName)
+	fmt.Fprintf(bw, "PIXEL_SIZE %d\n", f.Size)
+	fmt.Fprintf(bw, "FONT_ASCENT %d\n", f.Ascent)
+	fmt.Fprintf(bw, "FONT_DESCENT %d\n", f.Descent)
+	fmt.Fprintf(bw, "CHARSET_REGISTRY \"ISO10646\"\n")
+	fmt.Fprintf(bw, "CHARSET_ENCODING \"1\"\n")
+	fmt.Fprintf(bw, "DEFAULT_CHAR 32\n")
+	fmt.Fprintf(bw, "AVERAGE_WIDTH %d\n", averageWidth)
+	fmt.Fprintf(bw, "ENDPROPERTIES\n")
+	fmt.Fprintf(bw, "CHARS %d\n", len(f.Glyphs))
+
+	for _, g := range f.Glyphs {
+		b := g.Bitmap.Rect
+		swidth := 0
+		if f.Size > 0 {
+			swidth = g.Advance * 1000 / f.Size
+		}
+
+		fmt.Fprintf(bw, "STARTCHAR U+%04X\n", g.Rune)
+		fmt.Fprintf(bw, "ENCODING %d\n", g.Rune)
+		fmt.Fprintf(bw, "SWIDTH %d 0\n", swidth)
+		fmt.Fprintf(bw, "DWIDTH %d 0\n", g.Advance)
+		fmt.Fprintf(bw, "BBX %d %d %d %d\n", b.Dx(), b.Dy(), b.Min.X, -b.Max.Y)
+		fmt.Fprintf(bw, "BITMAP\n")
+
+		// each row is padded to a whole number of bytes, most significant
+		// bit first
+		row := make([]byte, (b.Dx()+7)/8)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			clear(row)
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if g.Bitmap.AlphaAt(x, y).A != 0 {
+					i := x - b.Min.X
+					row[i/8] |= 0x80 >> (i % 8)
+				}
+			}
+			fmt.Fprintf(bw, "%X\n", row)
+		}
+		fmt.Fprintf(bw, "ENDCHAR\n")
+	}
+
+	fmt.Fprintf(bw, "ENDFONT\n")
+	return bw.Flush()
+}
@@ -0,0 +1,278 @@
+// Package fontimport converts TrueType and OpenType fonts into the BDF
+// bitmap fonts pixlet renders text with, and subsets fonts to the
+// characters that are needed.
+package fontimport
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/zachomedia/go-bdf"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultChars are the characters imported when none are asked for: Basic
+// Latin and the Latin-1 Supplement, which cover most names of people and
+// places in western European languages.
+var DefaultChars = append(runeRange(0x20, 0x7e), runeRange(0xa0, 0xff)...)
+
+// DefaultThreshold is how opaque a pixel of a rasterized glyph must be to
+// be drawn, out of 255.
+const DefaultThreshold = 128
+
+// Options configure how a font is imported.
+type Options struct {
+	// Name of the font, as apps refer to it.
+	Name string
+
+	// Size is the size of an em in pixels, which fonts are rasterized at.
+	// It's ignored for bitmap fonts.
+	Size int
+
+	// Chars are the characters to import. Nil means DefaultChars. The
+	// space character is always imported.
+	Chars []rune
+
+	// Threshold is how opaque a pixel of a rasterized glyph must be to be
+	// drawn, out of 255. Zero means DefaultThreshold.
+	Threshold uint8
+}
+
+// Glyph is the bitmap of a character.
+type Glyph struct {
+	Rune rune
+
+	// Advance is how far the next glyph is drawn to the right, in pixels.
+	Advance int
+
+	// Bitmap is opaque where the glyph is drawn. Its bounds are relative to
+	// the glyph's origin on the baseline, so pixels above the baseline
+	// have negative y.
+	Bitmap *image.Alpha
+}
+
+// Font is an imported bitmap font.
+type Font struct {
+	Name string
+	Size int
+
+	// Ascent and Descent are how far the glyphs reach above and below the
+	// baseline, in pixels.
+	Ascent  int
+	Descent int
+
+	// Glyphs are sorted by rune.
+	Glyphs []*Glyph
+
+	// Missing are the characters asked for that the font doesn't have.
+	Missing []rune
+}
+
+// Import converts a font to a bitmap font. data may be a TrueType or
+// OpenType font, which is rasterized at opts.Size, or a BDF font, which is
+// subset to opts.Chars.
+func Import(data []byte, opts Options) (*Font, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("font needs a name")
+	}
+	if opts.Threshold == 0 {
+		opts.Threshold = DefaultThreshold
+	}
+
+	chars := opts.Chars
+	if chars == nil {
+		chars = DefaultChars
+	}
+	chars = uniqueRunes(append([]rune{' '}, chars...))
+
+	var f *Font
+	var err error
+	if bytes.HasPrefix(data, []byte("STARTFONT")) {
+		f, err = importBDF(data, chars)
+	} else {
+		if opts.Size <= 0 {
+			return nil, fmt.Errorf("size must be positive to rasterize a font")
+		}
+		f, err = rasterize(data, chars, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.Name = opts.Name
+	if len(f.Glyphs) <= 1 {
+		return nil, fmt.Errorf("font has none of the characters asked for")
+	}
+	for _, g := range f.Glyphs {
+		f.Ascent = max(f.Ascent, -g.Bitmap.Rect.Min.Y)
+		f.Descent = max(f.Descent, g.Bitmap.Rect.Max.Y)
+	}
+	return f, nil
+}
+
+// rasterize draws the glyphs of a TrueType or OpenType font.
+func rasterize(data []byte, chars []rune, opts Options) (*Font, error) {
+	sf, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing font: %w", err)
+	}
+
+	// at 72 DPI, a point is a pixel
+	face, err := opentype.NewFace(sf, &opentype.FaceOptions{
+		Size:    float64(opts.Size),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating face: %w", err)
+	}
+	defer face.Close()
+
+	f := &Font{Size: opts.Size}
+	var buf sfnt.Buffer
+	for _, r := range chars {
+		if i, err := sf.GlyphIndex(&buf, r); err != nil || i == 0 {
+			f.Missing = append(f.Missing, r)
+			continue
+		}
+
+		dr, mask, maskp, advance, ok := face.Glyph(fixed.Point26_6{}, r)
+		if !ok {
+			f.Missing = append(f.Missing, r)
+			continue
+		}
+
+		bitmap := image.NewAlpha(dr)
+		for y := dr.Min.Y; y < dr.Max.Y; y++ {
+			for x := dr.Min.X; x < dr.Max.X; x++ {
+				_, _, _, a := mask.At(maskp.X+x-dr.Min.X, maskp.Y+y-dr.Min.Y).RGBA()
+				if uint8(a>>8) >= opts.Threshold {
+					bitmap.Pix[bitmap.PixOffset(x, y)] = 0xff
+				}
+			}
+		}
+
+		f.Glyphs = append(f.Glyphs, &Glyph{
+			Rune:    r,
+			Advance: advance.Round(),
+			Bitmap:  trim(bitmap),
+		})
+	}
+	return f, nil
+}
+
+// importBDF subsets a BDF font.
+func importBDF(data []byte, chars []rune) (*Font, error) {
+	bf, err := bdf.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing BDF: %w", err)
+	}
+
+	f := &Font{Size: bf.PixelSize}
+	if f.Size == 0 {
+		f.Size = bf.Size
+	}
+	for _, r := range chars {
+		c, ok := bf.CharMap[r]
+		if !ok {
+			f.Missing = append(f.Missing, r)
+			continue
+		}
+
+		// BDF bitmaps are positioned by their lower left corner, above the
+		// baseline
+		h := c.Alpha.Rect.Dy()
+		bitmap := image.NewAlpha(image.Rect(0, 0, c.Alpha.Rect.Dx(), h).Add(image.Pt(c.LowerPoint[0], -c.LowerPoint[1]-h)))
+		copy(bitmap.Pix, c.Alpha.Pix)
+
+		f.Glyphs = append(f.Glyphs, &Glyph{
+			Rune:    r,
+			Advance: c.Advance[0],
+			Bitmap:  trim(bitmap),
+		})
+	}
+	return f, nil
+}
+
+// trim returns the part of a bitmap that's drawn.
+func trim(a *image.Alpha) *image.Alpha {
+	var drawn image.Rectangle
+	for y := a.Rect.Min.Y; y < a.Rect.Max.Y; y++ {
+		for x := a.Rect.Min.X; x < a.Rect.Max.X; x++ {
+			if a.AlphaAt(x, y).A != 0 {
+				drawn = drawn.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	trimmed := image.NewAlpha(drawn)
+	for y := drawn.Min.Y; y < drawn.Max.Y; y++ {
+		copy(trimmed.Pix[trimmed.PixOffset(drawn.Min.X, y):], a.Pix[a.PixOffset(drawn.Min.X, y):a.PixOffset(drawn.Max.X, y)])
+	}
+	return trimmed
+}
+
+// ParseRange parses a range of characters, such as "U+0400-U+04FF" for
+// Cyrillic, or a single character, such as "U+20AC".
+func ParseRange(s string) ([]rune, error) {
+	first, last, isRange := strings.Cut(s, "-")
+	lo, err := parseCodePoint(first)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range %q, must be like U+0400-U+04FF", s)
+	}
+	if !isRange {
+		return []rune{lo}, nil
+	}
+
+	hi, err := parseCodePoint(last)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range %q, must be like U+0400-U+04FF", s)
+	}
+	if hi < lo {
+		return nil, fmt.Errorf("range %s ends before it starts", s)
+	}
+	return runeRange(lo, hi), nil
+}
+
+// parseCodePoint parses a code point such as U+20AC.
+func parseCodePoint(s string) (rune, error) {
+	hex, ok := strings.CutPrefix(strings.ToUpper(s), "U+")
+	if !ok {
+		return 0, fmt.Errorf("code point %q doesn't start with U+", s)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || n > unicode.MaxRune {
+		return 0, fmt.Errorf("invalid code point %q", s)
+	}
+	return rune(n), nil
+}
+
+func runeRange(lo, hi rune) []rune {
+	runes := make([]rune, 0, hi-lo+1)
+	for r := lo; r <= hi; r++ {
+		runes = append(runes, r)
+	}
+	return runes
+}
+
+// uniqueRunes sorts runes and removes duplicates and control characters,
+// which can't be drawn.
+func uniqueRunes(runes []rune) []rune {
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	var unique []rune
+	for _, r := range runes {
+		if unicode.IsControl(r) || (len(unique) > 0 && r == unique[len(unique)-1]) {
+			continue
+		}
+		unique = append(unique, r)
+	}
+	return unique
+}
@@ -0,0 +1,123 @@
+package fontimport
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zachomedia/go-bdf"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestImportTrueType(t *testing.T) {
+	f, err := Import(goregular.TTF, Options{Name: "go", Size: 10, Chars: []rune("Ag中\u0007")})
+	require.NoError(t, err)
+
+	// space is always imported, and control characters never are
+	require.Len(t, f.Glyphs, 3)
+	assert.Equal(t, []rune{'中'}, f.Missing)
+	assert.Equal(t, ' ', f.Glyphs[0].Rune)
+	assert.Equal(t, 'A', f.Glyphs[1].Rune)
+	assert.Equal(t, 'g', f.Glyphs[2].Rune)
+
+	// A sits on the baseline, and g descends below it
+	a, g := f.Glyphs[1].Bitmap.Rect, f.Glyphs[2].Bitmap.Rect
+	assert.Equal(t, 0, a.Max.Y)
+	assert.Greater(t, g.Max.Y, 0)
+	assert.Equal(t, -a.Min.Y, f.Ascent)
+	assert.Equal(t, g.Max.Y, f.Descent)
+	assert.Greater(t, f.Glyphs[1].Advance, 0)
+
+	_, err = Import(goregular.TTF, Options{Name: "go"})
+	assert.ErrorContains(t, err, "size must be positive")
+
+	_, err = Import(goregular.TTF, Options{Name: "go", Size: 10, Chars: []rune("中")})
+	assert.ErrorContains(t, err, "none of the characters")
+}
+
+func TestWriteBDF(t *testing.T) {
+	f, err := Import(goregular.TTF, Options{Name: "Go-Regular", Size: 10, Chars: []rune("Ag")})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, f.WriteBDF(&buf))
+	assert.Contains(t, buf.String(), "FONT -pixlet-GoRegular-Medium-R-Normal--10-100-72-72-P-")
+	assert.Contains(t, buf.String(), "FAMILY_NAME \"Go Regular\"\n")
+
+	parsed, err := bdf.Parse(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, f.Ascent, parsed.Ascent)
+	assert.Equal(t, f.Descent, parsed.Descent)
+	require.Len(t, parsed.Characters, 3)
+
+	// glyphs survive the round trip pixel for pixel
+	for i, g := range f.Glyphs {
+		c := parsed.Characters[i]
+		assert.Equal(t, g.Rune, c.Encoding)
+		assert.Equal(t, g.Advance, c.Advance[0])
+		assert.Equal(t, g.Bitmap.Rect.Min.X, c.LowerPoint[0])
+		assert.Equal(t, -g.Bitmap.Rect.Max.Y, c.LowerPoint[1])
+		assert.Equal(t, g.Bitmap.Pix, c.Alpha.Pix)
+	}
+}
+
+func TestImportBDF(t *testing.T) {
+	data, err := os.ReadFile("../../fonts/tb-8.bdf")
+	require.NoError(t, err)
+
+	f, err := Import(data, Options{Name: "digits", Chars: []rune("0123456789")})
+	require.NoError(t, err)
+	assert.Len(t, f.Glyphs, 11)
+	assert.Empty(t, f.Missing)
+	assert.Equal(t, 8, f.Size)
+
+	// tb-8's digits are 5 pixels wide and 6 high, on the baseline
+	for _, g := range f.Glyphs[1:] {
+		assert.Equal(t, 5, g.Advance, string(g.Rune))
+		assert.Equal(t, 0, g.Bitmap.Rect.Max.Y, string(g.Rune))
+	}
+	assert.Equal(t, 6, f.Ascent)
+	assert.Equal(t, 0, f.Descent)
+
+	tb8, err := bdf.Parse(data)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, f.WriteBDF(&buf))
+	subset, err := bdf.Parse(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, tb8.CharMap['7'].Advance, subset.CharMap['7'].Advance)
+}
+
+func TestPreview(t *testing.T) {
+	f, err := Import(goregular.TTF, Options{Name: "go", Size: 10})
+	require.NoError(t, err)
+
+	img, err := f.Preview("Hello, Grace", 1)
+	require.NoError(t, err)
+
+	rows := (len(f.Glyphs) + previewColumns - 1) / previewColumns
+	cellH := f.Ascent + f.Descent
+	assert.Equal(t, rows*(cellH+1)+1+cellH+2, img.Bounds().Dy())
+
+	magnified, err := f.Preview("", 2)
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(img.Bounds().Dx()*2, (rows*(cellH+1)+1)*2), magnified.Bounds().Size())
+}
+
+func TestParseRange(t *testing.T) {
+	r, err := ParseRange("U+0410-U+0412")
+	require.NoError(t, err)
+	assert.Equal(t, []rune("АБВ"), r)
+
+	r, err = ParseRange("u+20ac")
+	require.NoError(t, err)
+	assert.Equal(t, []rune("€"), r)
+
+	for _, bad := range []string{"0410", "U+0412-U+0410", "U+XYZ", "U+0410-", "U+110000"} {
+		_, err := ParseRange(bad)
+		assert.Error(t, err, bad)
+	}
+}
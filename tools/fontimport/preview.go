@@ -0,0 +1,86 @@
+package fontimport
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/zachomedia/go-bdf"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"tidbyt.dev/pixlet/encode"
+)
+
+// previewColumns is how many glyphs are in each row of a preview sheet.
+const previewColumns = 16
+
+var (
+	previewGrid     = color.RGBA{0x40, 0x40, 0x40, 0xff}
+	previewBaseline = color.RGBA{0x00, 0x20, 0x50, 0xff}
+)
+
+// Preview draws a sheet of the font's glyphs, each in a cell with the row
+// under its baseline marked, followed by sample text if there is any. The font is
+// drawn from the BDF that WriteBDF writes, as pixlet would draw it.
+func (f *Font) Preview(sample string, magnify int) (image.Image, error) {
+	var buf bytes.Buffer
+	if err := f.WriteBDF(&buf); err != nil {
+		return nil, err
+	}
+	bf, err := bdf.Parse(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	face := bf.NewFace()
+
+	// cells fit the widest glyph, including any part left of its origin
+	left, right := 0, 1
+	for _, g := range f.Glyphs {
+		left = min(left, g.Bitmap.Rect.Min.X)
+		right = max(right, g.Bitmap.Rect.Max.X, g.Advance)
+	}
+	cellW, cellH := right-left, max(f.Ascent+f.Descent, 1)
+
+	rows := (len(f.Glyphs) + previewColumns - 1) / previewColumns
+	gridW := previewColumns*(cellW+1) + 1
+	gridH := rows*(cellH+1) + 1
+
+	sampleW := font.MeasureString(face, sample).Ceil()
+	height := gridH
+	if sample != "" {
+		height += cellH + 2
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, max(gridW, sampleW+2), height))
+	draw.Draw(img, img.Bounds(), image.Black, image.Point{}, draw.Src)
+
+	for row := 0; row <= rows; row++ {
+		draw.Draw(img, image.Rect(0, row*(cellH+1), gridW, row*(cellH+1)+1), image.NewUniform(previewGrid), image.Point{}, draw.Src)
+	}
+	for col := 0; col <= previewColumns; col++ {
+		draw.Draw(img, image.Rect(col*(cellW+1), 0, col*(cellW+1)+1, gridH), image.NewUniform(previewGrid), image.Point{}, draw.Src)
+	}
+
+	drawer := &font.Drawer{Dst: img, Src: image.White, Face: face}
+	for i, g := range f.Glyphs {
+		x := (i%previewColumns)*(cellW+1) + 1
+		y := (i/previewColumns)*(cellH+1) + 1
+		// the row under the baseline, where descenders are
+		baseline := y + f.Ascent
+		if f.Descent > 0 {
+			draw.Draw(img, image.Rect(x, baseline, x+cellW, baseline+1), image.NewUniform(previewBaseline), image.Point{}, draw.Src)
+		}
+
+		drawer.Dot = fixed.P(x-left, y+f.Ascent)
+		drawer.DrawString(string(g.Rune))
+	}
+
+	if sample != "" {
+		drawer.Dot = fixed.P(1, gridH+1+f.Ascent)
+		drawer.DrawString(sample)
+	}
+
+	return encode.Magnify(magnify)(img)
+}
@@ -0,0 +1,236 @@
+// Package fuzz runs applets over and over with generated config and mutated
+// HTTP responses, to find the inputs that make them crash, time out or
+// fail.
+package fuzz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tidbyt.dev/pixlet/render"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/runtime/modules/starlarkhttp"
+	"tidbyt.dev/pixlet/schema"
+)
+
+// Options configure a fuzzing session.
+type Options struct {
+	// Runs is how many times the applet is run.
+	Runs int
+
+	// Seed seeds the generated inputs. Sessions with the same seed, applet
+	// and fixtures run with the same inputs.
+	Seed int64
+
+	// Timeout is how long each run may take.
+	Timeout time.Duration
+
+	// Fixtures is a directory of HTTP fixtures, as recorded by pixlet test
+	// --record, whose responses are mutated. Requests there's no fixture
+	// for fail as if the network were down.
+	Fixtures string
+}
+
+// Kind is how a run went wrong.
+type Kind string
+
+const (
+	// KindPanic is a Go panic in pixlet, while running or painting.
+	KindPanic Kind = "panic"
+
+	// KindTimeout is a run that took longer than Options.Timeout.
+	KindTimeout Kind = "timeout"
+
+	// KindError is an error in the applet, such as calling fail().
+	KindError Kind = "error"
+)
+
+// Finding is an input that makes the applet go wrong. Runs that go wrong in
+// the same way are reported as one finding, with the input of the first.
+type Finding struct {
+	Kind Kind
+	Err  string
+
+	// Run is the first run it happened in, and Count how many runs it
+	// happened in.
+	Run   int
+	Count int
+
+	// Config is what the applet was run with, and Mutations the changes
+	// made to HTTP responses, such as "GET https://example.com/: status
+	// 503".
+	Config    map[string]string
+	Mutations []string
+}
+
+// Fuzz loads the applet with load and runs it opts.Runs times. It's given
+// config generated from its schema, within the schema's constraints, and
+// responses to its HTTP requests are mutated. The applet's loaded with the
+// HTTP client the fuzzer installs, and should be deterministic so that
+// findings can be reproduced.
+func Fuzz(ctx context.Context, load func() (*runtime.Applet, error), opts Options) ([]*Finding, error) {
+	if opts.Runs <= 0 {
+		opts.Runs = 100
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	transport := &mutator{
+		fixtures: &runtime.HTTPFixtures{Dir: opts.Fixtures, Mode: runtime.FixturesReplay},
+	}
+	starlarkhttp.StarlarkHTTPClient = &http.Client{Transport: transport, Timeout: opts.Timeout}
+
+	// every run starts with nothing cached, rather than depending on what
+	// runs before it stored
+	runtime.InitCache(nil)
+
+	app, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	var s *schema.Schema
+	if app.Schema != nil {
+		s = app.Schema
+	} else {
+		s = &schema.Schema{}
+	}
+	gen := &generator{rng: rng}
+
+	var findings []*Finding
+	seen := map[string]*Finding{}
+	for run := 0; run < opts.Runs; run++ {
+		if err := ctx.Err(); err != nil {
+			return findings, err
+		}
+
+		config := gen.config(s)
+		transport.start(rand.New(rand.NewSource(rng.Int63())))
+
+		kind, msg := runOnce(ctx, app, config, opts.Timeout)
+		mutations := transport.done()
+		if kind == "" {
+			continue
+		}
+
+		key := string(kind) + "\x00" + signature(msg)
+		if f, ok := seen[key]; ok {
+			f.Count++
+			continue
+		}
+		f := &Finding{
+			Kind:      kind,
+			Err:       msg,
+			Run:       run,
+			Count:     1,
+			Config:    config,
+			Mutations: mutations,
+		}
+		seen[key] = f
+		findings = append(findings, f)
+	}
+
+	return findings, nil
+}
+
+// runOnce runs and paints the applet, returning how it went wrong, if it
+// did.
+func runOnce(ctx context.Context, app *runtime.Applet, config map[string]string, timeout time.Duration) (kind Kind, msg string) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			kind, msg = KindPanic, fmt.Sprintf("panic while painting: %v", r)
+		}
+	}()
+
+	roots, err := app.RunWithConfig(ctx, config)
+	if err == nil {
+		_, truncated := render.PaintRootsContext(ctx, true, roots...)
+		if truncated {
+			err = ctx.Err()
+		}
+	}
+
+	switch {
+	case err == nil:
+		return "", ""
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return KindTimeout, fmt.Sprintf("run took longer than %s", timeout)
+	case strings.HasPrefix(err.Error(), "panic while running"):
+		return KindPanic, err.Error()
+	default:
+		return KindError, err.Error()
+	}
+}
+
+// signature is what errors are told apart by: where they happened and what
+// they were, without values that vary from run to run.
+func signature(msg string) string {
+	lines := strings.Split(strings.TrimSpace(msg), "\n")
+	last := lines[len(lines)-1]
+	if i := strings.Index(last, ":"); i >= 0 {
+		last = last[:i]
+	}
+
+	// the innermost frame of a Starlark backtrace
+	for i := len(lines) - 2; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); strings.HasPrefix(line, "at ") || strings.Contains(line, ": in ") {
+			return line + "\n" + last
+		}
+	}
+	return last
+}
+
+// mutator serves HTTP responses from fixtures, mutating some of them.
+type mutator struct {
+	fixtures *runtime.HTTPFixtures
+
+	mu        sync.Mutex
+	rng       *rand.Rand
+	mutations []string
+}
+
+func (m *mutator) start(rng *rand.Rand) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rng = rng
+	m.mutations = nil
+}
+
+func (m *mutator) done() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sort.Strings(m.mutations)
+	return m.mutations
+}
+
+func (m *mutator) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := m.fixtures.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rng.Float64() >= mutationRate {
+		return resp, nil
+	}
+
+	desc, err := mutateResponse(m.rng, resp)
+	if err != nil {
+		return nil, err
+	}
+	m.mutations = append(m.mutations, fmt.Sprintf("%s %s: %s", req.Method, req.URL, desc))
+	return resp, nil
+}
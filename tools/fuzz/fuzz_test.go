@@ -0,0 +1,114 @@
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+var fuzzSrc = `
+load("http.star", "http")
+load("render.star", "render")
+load("schema.star", "schema")
+
+def main(config):
+    who = config.str("who", "world")
+    if who == "Ada Lovelace":
+        for i in range(1000000000):
+            pass
+
+    resp = http.get("%s/forecast")
+    if resp.status_code != 200:
+        fail("forecast unavailable")
+
+    return render.Root(child = render.Text("%%s: %%d" %% (who, resp.json()["temp"])))
+
+def get_schema():
+    return schema.Schema(
+        version = "1",
+        fields = [
+            schema.Text(id = "who", name = "Who", desc = "Who to greet", icon = "user"),
+            schema.Dropdown(
+                id = "units",
+                name = "Units",
+                desc = "Units",
+                icon = "ruler",
+                default = "c",
+                options = [
+                    schema.Option(display = "Celsius", value = "c"),
+                    schema.Option(display = "Fahrenheit", value = "f"),
+                ],
+            ),
+        ],
+    )
+`
+
+func TestFuzz(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"temp": 21, "summary": "sunny"}`)
+	}))
+	defer srv.Close()
+	src := fmt.Sprintf(fuzzSrc, srv.URL)
+
+	// record the fixture the fuzzer mutates
+	fixtures := t.TempDir()
+	client := &http.Client{Transport: &runtime.HTTPFixtures{Dir: fixtures, Mode: runtime.FixturesRecord}}
+	resp, err := client.Get(srv.URL + "/forecast")
+	require.NoError(t, err)
+	resp.Body.Close()
+	srv.Close()
+
+	load := func() (*runtime.Applet, error) {
+		return runtime.NewApplet("fuzz.star", []byte(src), runtime.WithDeterministic(), runtime.WithPrintDisabled())
+	}
+	opts := Options{Runs: 200, Seed: 1, Timeout: 100 * time.Millisecond, Fixtures: fixtures}
+	findings, err := Fuzz(context.Background(), load, opts)
+	require.NoError(t, err)
+
+	kinds := map[Kind]*Finding{}
+	total := 0
+	for _, f := range findings {
+		total += f.Count
+		if kinds[f.Kind] == nil {
+			kinds[f.Kind] = f
+		}
+	}
+	assert.Less(t, total, opts.Runs, "some runs should go fine")
+
+	require.NotNil(t, kinds[KindTimeout])
+	assert.Equal(t, "Ada Lovelace", kinds[KindTimeout].Config["who"])
+
+	require.NotNil(t, kinds[KindError])
+	for _, f := range findings {
+		if f.Kind == KindError {
+			assert.NotEmpty(t, f.Mutations, f.Err)
+			assert.Contains(t, []string{"c", "f"}, f.Config["units"])
+		}
+	}
+
+	// the same seed finds the same things
+	again, err := Fuzz(context.Background(), load, opts)
+	require.NoError(t, err)
+	require.Len(t, again, len(findings))
+	for i := range findings {
+		assert.Equal(t, findings[i].Err, again[i].Err)
+		assert.Equal(t, findings[i].Config, again[i].Config)
+		assert.Equal(t, findings[i].Mutations, again[i].Mutations)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	a := "Traceback (most recent call last):\n  app.star:8:13: in main\nError in fail: fail: too long: 512"
+	b := "Traceback (most recent call last):\n  app.star:8:13: in main\nError in fail: fail: too long: 600"
+	c := "Traceback (most recent call last):\n  app.star:9:13: in main\nError in fail: fail: too long: 512"
+	assert.Equal(t, signature(a), signature(b))
+	assert.NotEqual(t, signature(a), signature(c))
+}
@@ -0,0 +1,203 @@
+package fuzz
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"tidbyt.dev/pixlet/schema"
+)
+
+// texts are the values text fields are tried with, besides ones fitting
+// their min and max: what users type that apps don't expect.
+var texts = []string{
+	"", " ", "0", "-1", "1e308", "NaN", "null", "true",
+	"Ada Lovelace", "O'Brien & \"Sons\"", "<b>bold</b>",
+	"日本語のテキスト", "עברית", "🙂🙃👍🏽", "line\nbreak\ttab",
+	strings.Repeat("W", 512),
+}
+
+// places are the locations location fields are tried with: the extremes of
+// latitude and longitude, and time zones with unusual offsets.
+var places = []map[string]string{
+	{"lat": "40.6781784", "lng": "-73.9441579", "locality": "Brooklyn", "timezone": "America/New_York"},
+	{"lat": "90", "lng": "180", "locality": "North Pole", "timezone": "UTC"},
+	{"lat": "-90", "lng": "-180", "locality": "South Pole", "timezone": "Antarctica/McMurdo"},
+	{"lat": "0", "lng": "0", "locality": "", "timezone": ""},
+	{"lat": "27.7172", "lng": "85.3240", "locality": "Kathmandu", "timezone": "Asia/Kathmandu"},
+	{"lat": "-43.9535", "lng": "-176.5597", "locality": "Chatham Islands", "timezone": "Pacific/Chatham"},
+	{"lat": "1.8721", "lng": "-157.4278", "locality": "Kiritimati", "timezone": "Pacific/Kiritimati"},
+	{"lat": "47.5615", "lng": "-52.7126", "locality": "St. John's", "timezone": "America/St_Johns"},
+}
+
+// times are the values date and datetime fields are tried with: epochs,
+// leap days, daylight saving changes and far off years.
+var times = []string{
+	"1970-01-01T00:00:00Z", "2000-02-29T12:00:00Z", "2024-03-10T07:30:00Z",
+	"2024-11-03T05:59:59Z", "2038-01-19T03:14:08Z", "1900-01-01T00:00:00Z",
+	"9999-12-31T23:59:59Z",
+}
+
+type generator struct {
+	rng *rand.Rand
+}
+
+// config returns config for the schema, with each field either left at its
+// default or given a generated value. Generated values are always valid for
+// their field.
+func (g *generator) config(s *schema.Schema) map[string]string {
+	config := s.DefaultConfig()
+	g.fields(s.Fields, config)
+	return config
+}
+
+func (g *generator) fields(fields []schema.SchemaField, config map[string]string) {
+	for _, f := range fields {
+		if len(f.Fields) > 0 && f.Type != "list" {
+			g.fields(f.Fields, config)
+			continue
+		}
+		if g.rng.Intn(2) == 0 {
+			continue
+		}
+
+		candidates := g.values(f)
+		g.rng.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+		for _, v := range candidates {
+			if valid(f, v) {
+				config[f.ID] = v
+				break
+			}
+		}
+	}
+}
+
+// valid reports whether value is valid for the field on its own. Whether
+// the field is visible isn't considered.
+func valid(f schema.SchemaField, value string) bool {
+	f.VisibleIf = ""
+	f.Visibility = nil
+	s := &schema.Schema{Fields: []schema.SchemaField{f}}
+	return s.ValidateConfig(map[string]string{f.ID: value}) == nil
+}
+
+// values returns candidate values for a field, not all of which are valid.
+func (g *generator) values(f schema.SchemaField) []string {
+	switch f.Type {
+	case "text":
+		values := append([]string{}, texts...)
+		for _, bound := range []*float64{f.Min, f.Max} {
+			if bound != nil {
+				for _, d := range []float64{-1, 0, 0.5, 1} {
+					values = append(values, strconv.FormatFloat(*bound+d, 'g', -1, 64))
+				}
+			}
+		}
+		return values
+
+	case "dropdown", "radio":
+		var values []string
+		for _, o := range f.Options {
+			values = append(values, o.Value)
+		}
+		return values
+
+	case "multiselect":
+		var values []string
+		for i := 0; i < 4; i++ {
+			var picked []string
+			for _, o := range f.Options {
+				if g.rng.Intn(2) == 0 {
+					picked = append(picked, o.Value)
+				}
+			}
+			values = append(values, schema.EncodeMultiSelect(picked))
+		}
+		return values
+
+	case "onoff":
+		return []string{"true", "false"}
+
+	case "color":
+		values := append([]string{"#000", "#fff", "#000000", "#ffffff"}, f.Palette...)
+		for i := 0; i < 4; i++ {
+			values = append(values, fmt.Sprintf("#%06x", g.rng.Intn(1<<24)))
+		}
+		return values
+
+	case "location":
+		var values []string
+		for _, p := range places {
+			b, _ := json.Marshal(p)
+			values = append(values, string(b))
+		}
+		return values
+
+	case "locations":
+		var values []string
+		for n := 0; n < 4; n++ {
+			var locations []map[string]string
+			for i := 0; i < n; i++ {
+				l := map[string]string{"label": places[(i*3+n)%len(places)]["locality"] + strconv.Itoa(i)}
+				for k, v := range places[(i*3+n)%len(places)] {
+					l[k] = v
+				}
+				locations = append(locations, l)
+			}
+			values = append(values, schema.EncodeList(locations))
+		}
+		return values
+
+	case "datetime":
+		return append([]string{}, times...)
+
+	case "date":
+		var values []string
+		for _, t := range times {
+			values = append(values, t[:len("2006-01-02")])
+		}
+		return values
+
+	case "timerange":
+		return []string{
+			`{"start": "00:00", "end": "00:00"}`,
+			`{"start": "22:00", "end": "07:00", "days": ["fri", "sat"]}`,
+			`{"start": "23:59", "end": "00:00", "days": []}`,
+		}
+
+	case "duration":
+		return []string{"0s", "1s", "1m0s", "1h0m0s", "24h0m0s", "8760h0m0s"}
+
+	case "typeahead", "locationbased":
+		var values []string
+		for _, t := range texts {
+			b, _ := json.Marshal(map[string]string{"display": t, "value": t})
+			values = append(values, string(b))
+		}
+		return values
+
+	case "list":
+		var values []string
+		for n := 0; n < 4; n++ {
+			items := make([]map[string]string, n)
+			for i := range items {
+				items[i] = map[string]string{}
+				for _, child := range f.Fields {
+					if candidates := g.values(child); len(candidates) > 0 {
+						items[i][child.ID] = candidates[g.rng.Intn(len(candidates))]
+					}
+				}
+			}
+			values = append(values, schema.EncodeList(items))
+		}
+		return values
+	}
+
+	// fields whose values come from elsewhere, such as uploads and OAuth
+	// tokens, are left at their defaults
+	return nil
+}
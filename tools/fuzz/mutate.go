@@ -0,0 +1,105 @@
+package fuzz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// mutationRate is the share of HTTP responses that are mutated.
+const mutationRate = 0.3
+
+// errorStatuses are what mutated responses fail with: the ways APIs
+// commonly go down, rate limit or reject credentials.
+var errorStatuses = []int{
+	http.StatusBadRequest,
+	http.StatusUnauthorized,
+	http.StatusNotFound,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+}
+
+// mutateResponse changes resp the way a misbehaving API might, returning a
+// description of what it did.
+func mutateResponse(rng *rand.Rand, resp *http.Response) (string, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return "", err
+	}
+
+	desc := ""
+	switch rng.Intn(4) {
+	case 0:
+		status := errorStatuses[rng.Intn(len(errorStatuses))]
+		resp.StatusCode = status
+		resp.Status = fmt.Sprintf("%d %s", status, http.StatusText(status))
+		desc = fmt.Sprintf("status %d", status)
+	case 1:
+		body = nil
+		desc = "empty body"
+	case 2:
+		body = body[:len(body)/2]
+		desc = "truncated body"
+	default:
+		var v interface{}
+		if json.Unmarshal(body, &v) != nil {
+			body = body[:len(body)/2]
+			desc = "truncated body"
+			break
+		}
+		var path string
+		v, path = mutateJSON(rng, v, "$")
+		body, _ = json.Marshal(v)
+		desc = "changed " + path
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Del("Content-Length")
+	return desc, nil
+}
+
+// mutateJSON changes one value in v, picked at random, returning the new v
+// and a JSONPath-like description of what was changed.
+func mutateJSON(rng *rand.Rand, v interface{}, path string) (interface{}, string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 && rng.Intn(4) > 0 {
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			k := keys[rng.Intn(len(keys))]
+
+			if rng.Intn(4) == 0 {
+				delete(v, k)
+				return v, fmt.Sprintf("%s.%s to be missing", path, k)
+			}
+			var desc string
+			v[k], desc = mutateJSON(rng, v[k], path+"."+k)
+			return v, desc
+		}
+
+	case []interface{}:
+		if len(v) > 0 && rng.Intn(4) > 0 {
+			i := rng.Intn(len(v))
+			var desc string
+			v[i], desc = mutateJSON(rng, v[i], path+"["+strconv.Itoa(i)+"]")
+			return v, desc
+		}
+	}
+
+	replacements := []interface{}{nil, "", 0, -1, 1e308, true, "ünïcødé ✓", []interface{}{}, map[string]interface{}{}}
+	r := replacements[rng.Intn(len(replacements))]
+	b, _ := json.Marshal(r)
+	return r, fmt.Sprintf("%s to %s", path, b)
+}
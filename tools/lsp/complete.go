@@ -0,0 +1,230 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+var (
+	// loadPrefix matches the text before the cursor when it's in the
+	// module name of a load statement.
+	loadPrefix = regexp.MustCompile(`\bload\(\s*"([^"]*)$`)
+
+	// attrPrefix matches the text before the cursor when it's after a dot.
+	attrPrefix = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)\.([A-Za-z0-9_]*)$`)
+
+	// calleeSuffix matches the function called by the parenthesis the text
+	// ends before.
+	calleeSuffix = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)\.([A-Za-z_][A-Za-z0-9_]*)\s*$`)
+)
+
+var keywords = []string{
+	"and", "break", "continue", "def", "elif", "else", "for", "if", "in",
+	"lambda", "load", "not", "or", "pass", "return", "while",
+}
+
+// complete returns the completions at a position in the document.
+func (d *document) complete(pos Position) []CompletionItem {
+	offset := d.offset(pos)
+	lineStart := strings.LastIndexByte(d.text[:offset], '\n') + 1
+	line := d.text[lineStart:offset]
+
+	if m := loadPrefix.FindStringSubmatch(line); m != nil {
+		return d.completeLoad()
+	}
+
+	if m := attrPrefix.FindStringSubmatch(line); m != nil {
+		return d.completeAttr(m[1])
+	}
+
+	var items []CompletionItem
+	if module, name, ok := d.enclosingCall(offset); ok {
+		items = append(items, completeParams(module, name)...)
+	}
+	return append(items, d.completeNames()...)
+}
+
+// completeLoad returns the modules that can be loaded: pixlet's, and the
+// Starlark files next to the document.
+func (d *document) completeLoad() []CompletionItem {
+	var items []CompletionItem
+	for _, module := range runtime.BuiltinModules {
+		items = append(items, CompletionItem{Label: module, Kind: KindModule, Detail: "pixlet module"})
+	}
+
+	if d.path != "" {
+		entries, _ := os.ReadDir(filepath.Dir(d.path))
+		for _, e := range entries {
+			if e.IsDir() || filepath.Ext(e.Name()) != ".star" || e.Name() == filepath.Base(d.path) {
+				continue
+			}
+			items = append(items, CompletionItem{Label: e.Name(), Kind: KindFile})
+		}
+	}
+	return items
+}
+
+// completeAttr returns the members of what a name is bound to, if it was
+// loaded from one of pixlet's modules.
+func (d *document) completeAttr(name string) []CompletionItem {
+	value := d.loadedValue(name)
+	if value == nil {
+		return nil
+	}
+
+	var members starlark.StringDict
+	switch v := value.(type) {
+	case *starlarkstruct.Module:
+		members = v.Members
+	case starlark.HasAttrs:
+		members = starlark.StringDict{}
+		for _, attr := range v.AttrNames() {
+			if member, err := v.Attr(attr); err == nil && member != nil {
+				members[attr] = member
+			}
+		}
+	default:
+		return nil
+	}
+
+	var items []CompletionItem
+	for _, attr := range members.Keys() {
+		items = append(items, memberItem(attr, members[attr]))
+	}
+	return items
+}
+
+// loadedValue returns the value a name was loaded as, if it was loaded from
+// one of pixlet's modules.
+func (d *document) loadedValue(name string) starlark.Value {
+	binding, ok := d.loads[name]
+	if !ok {
+		return nil
+	}
+	members, err := runtime.LoadBuiltinModule(binding.module)
+	if err != nil {
+		return nil
+	}
+	return members[binding.name]
+}
+
+func memberItem(name string, value starlark.Value) CompletionItem {
+	item := CompletionItem{Label: name, Detail: value.Type()}
+	switch value.(type) {
+	case starlark.Callable:
+		item.Kind = KindFunction
+	case *starlarkstruct.Module, *starlarkstruct.Struct:
+		item.Kind = KindModule
+	default:
+		item.Kind = KindConstant
+	}
+	return item
+}
+
+// enclosingCall returns the widget whose constructor's arguments offset is
+// in, if any, as the module it's from and its name.
+func (d *document) enclosingCall(offset int) (string, string, bool) {
+	depth := 0
+	for i := offset - 1; i >= 0; i-- {
+		switch d.text[i] {
+		case ')', ']', '}':
+			depth++
+		case '[', '{':
+			if depth == 0 {
+				// in a list or dict, not the call's arguments
+				return "", "", false
+			}
+			depth--
+		case '(':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			m := calleeSuffix.FindStringSubmatch(d.text[:i])
+			if m == nil {
+				return "", "", false
+			}
+			binding, ok := d.loads[m[1]]
+			if !ok {
+				return "", "", false
+			}
+			return binding.module, m[2], true
+		}
+	}
+	return "", "", false
+}
+
+// completeParams returns the parameters of a widget's constructor, required
+// ones first.
+func completeParams(module, name string) []CompletionItem {
+	var items []CompletionItem
+	for _, p := range widgetParams(module, name) {
+		item := CompletionItem{
+			Label:      p.name,
+			Kind:       KindProperty,
+			Detail:     name + " parameter",
+			InsertText: p.name + " = ",
+			SortText:   "1" + p.name,
+		}
+		if p.required {
+			item.Detail = "required " + item.Detail
+			item.SortText = "0" + p.name
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// completeNames returns the names that can be used anywhere in the
+// document: its globals, what it loads, and builtins.
+func (d *document) completeNames() []CompletionItem {
+	seen := map[string]bool{}
+	var items []CompletionItem
+	add := func(item CompletionItem) {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	if d.file != nil {
+		for _, stmt := range d.file.Stmts {
+			switch s := stmt.(type) {
+			case *syntax.DefStmt:
+				add(CompletionItem{Label: s.Name.Name, Kind: KindFunction})
+			case *syntax.AssignStmt:
+				if id, ok := s.LHS.(*syntax.Ident); ok {
+					add(CompletionItem{Label: id.Name, Kind: KindVariable})
+				}
+			}
+		}
+	}
+
+	loaded := make([]string, 0, len(d.loads))
+	for name := range d.loads {
+		loaded = append(loaded, name)
+	}
+	sort.Strings(loaded)
+	for _, name := range loaded {
+		add(CompletionItem{Label: name, Kind: KindModule, Detail: d.loads[name].module})
+	}
+
+	for name := range predeclared {
+		add(CompletionItem{Label: name, Kind: KindFunction, Detail: "builtin"})
+	}
+	for _, name := range starlark.Universe.Keys() {
+		add(memberItem(name, starlark.Universe[name]))
+	}
+	for _, kw := range keywords {
+		add(CompletionItem{Label: kw, Kind: KindKeyword})
+	}
+	return items
+}
@@ -0,0 +1,184 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"go.starlark.net/resolve"
+	"go.starlark.net/syntax"
+)
+
+// maxLoadDepth is how many loads are followed to find where a name is
+// defined, in case files load each other in a cycle.
+const maxLoadDepth = 16
+
+// sourceFunc returns the source of a file: the text of the open document if
+// there is one, or what's on disk.
+type sourceFunc func(path string) (string, error)
+
+// definition returns where the name at a position in the document is
+// defined, following loads into other files.
+func (d *document) definition(pos Position, source sourceFunc) *Location {
+	if d.file == nil {
+		return nil
+	}
+	at := d.syntaxPosition(pos)
+
+	var found *Location
+	syntax.Walk(d.file, func(n syntax.Node) bool {
+		if found != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *syntax.LoadStmt:
+			if contains(n.Module, at) {
+				found = d.loadedFile(n.ModuleName())
+				return false
+			}
+			for i := range n.To {
+				if containsIdent(n.From[i], at) || containsIdent(n.To[i], at) {
+					found = d.loadedDefinition(n.ModuleName(), n.From[i].Name, source, 0)
+					return false
+				}
+			}
+			return false
+
+		case *syntax.Ident:
+			if !containsIdent(n, at) {
+				return true
+			}
+			binding, ok := n.Binding.(*resolve.Binding)
+			if !ok || binding.First == nil {
+				return false
+			}
+			if lb, ok := d.loads[binding.First.Name]; ok && isLoaded(lb.stmt, binding.First) {
+				found = d.loadedDefinition(lb.module, lb.name, source, 0)
+				if found == nil {
+					found = d.location(d.uri, d.text, binding.First)
+				}
+				return false
+			}
+			found = d.location(d.uri, d.text, binding.First)
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// loadedFile returns the location of a file a module name refers to, if it
+// exists.
+func (d *document) loadedFile(module string) *Location {
+	if d.path == "" {
+		return nil
+	}
+	path := d.resolvePath(module)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	return &Location{URI: pathToURI(path)}
+}
+
+// loadedDefinition returns where a name in a loaded file is defined.
+func (d *document) loadedDefinition(module, name string, source sourceFunc, depth int) *Location {
+	if d.path == "" || depth > maxLoadDepth {
+		return nil
+	}
+	path := d.resolvePath(module)
+	text, err := source(path)
+	if err != nil {
+		return nil
+	}
+	f, err := fileOptions.Parse(path, text, 0)
+	if err != nil {
+		return nil
+	}
+
+	for _, stmt := range f.Stmts {
+		var ids []*syntax.Ident
+		switch s := stmt.(type) {
+		case *syntax.DefStmt:
+			ids = []*syntax.Ident{s.Name}
+		case *syntax.AssignStmt:
+			ids = assigned(s.LHS)
+		case *syntax.LoadStmt:
+			for i, to := range s.To {
+				if to.Name == name {
+					loaded := &document{path: path}
+					if loc := loaded.loadedDefinition(s.ModuleName(), s.From[i].Name, source, depth+1); loc != nil {
+						return loc
+					}
+					return d.location(pathToURI(path), text, to)
+				}
+			}
+		}
+		for _, id := range ids {
+			if id.Name == name {
+				return d.location(pathToURI(path), text, id)
+			}
+		}
+	}
+	return nil
+}
+
+// assigned returns the identifiers an assignment binds.
+func assigned(lhs syntax.Expr) []*syntax.Ident {
+	switch lhs := lhs.(type) {
+	case *syntax.Ident:
+		return []*syntax.Ident{lhs}
+	case *syntax.TupleExpr:
+		var ids []*syntax.Ident
+		for _, x := range lhs.List {
+			ids = append(ids, assigned(x)...)
+		}
+		return ids
+	case *syntax.ListExpr:
+		var ids []*syntax.Ident
+		for _, x := range lhs.List {
+			ids = append(ids, assigned(x)...)
+		}
+		return ids
+	case *syntax.ParenExpr:
+		return assigned(lhs.X)
+	}
+	return nil
+}
+
+func (d *document) location(uri, text string, id *syntax.Ident) *Location {
+	start := positionIn(text, id.NamePos)
+	end := start
+	end.Character += len(id.Name)
+	return &Location{URI: uri, Range: Range{Start: start, End: end}}
+}
+
+// isLoaded reports whether id is one of the names a load statement binds.
+func isLoaded(load *syntax.LoadStmt, id *syntax.Ident) bool {
+	for _, to := range load.To {
+		if to == id {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(n syntax.Node, at syntax.Position) bool {
+	start, end := n.Span()
+	return !before(at, start) && !before(end, at)
+}
+
+func before(p, q syntax.Position) bool {
+	return p.Line < q.Line || p.Line == q.Line && p.Col < q.Col
+}
+
+func containsIdent(id *syntax.Ident, at syntax.Position) bool {
+	return at.Line == id.NamePos.Line &&
+		at.Col >= id.NamePos.Col &&
+		at.Col <= id.NamePos.Col+int32(utf8.RuneCountInString(id.Name))
+}
+
+// readSource reads the source of a file from disk.
+func readSource(path string) (string, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	return string(b), err
+}
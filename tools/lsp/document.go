@@ -0,0 +1,243 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+// fileOptions are the dialect of Starlark that pixlet runs.
+var fileOptions = &syntax.FileOptions{
+	Set:       true,
+	Recursion: true,
+}
+
+// predeclared are the names pixlet declares in every file, besides
+// Starlark's universe.
+var predeclared = map[string]bool{
+	"struct": true,
+}
+
+// document is an open source file, and what's known about it.
+type document struct {
+	uri  string
+	path string
+	text string
+
+	// file is the parsed source, and diags what's wrong with it. While
+	// it's being edited, source often doesn't parse, and file and loads are
+	// kept from the last version that did.
+	file  *syntax.File
+	diags []Diagnostic
+
+	// loads are the names bound by load statements.
+	loads map[string]loadBinding
+}
+
+// loadBinding is a name bound by a load statement.
+type loadBinding struct {
+	module string
+	name   string
+	stmt   *syntax.LoadStmt
+}
+
+// newDocument analyzes a version of a document, following prev if there
+// was one.
+func newDocument(uri, text string, prev *document) *document {
+	d := &document{
+		uri:   uri,
+		path:  uriToPath(uri),
+		text:  text,
+		loads: map[string]loadBinding{},
+	}
+	d.analyze()
+
+	if d.file == nil && prev != nil {
+		d.file = prev.file
+		d.loads = prev.loads
+	}
+	return d
+}
+
+// analyze parses and resolves the document, finding its loads and what's
+// wrong with it.
+func (d *document) analyze() {
+	name := d.path
+	if name == "" {
+		name = d.uri
+	}
+
+	f, err := fileOptions.Parse(name, d.text, 0)
+	if err != nil {
+		var serr syntax.Error
+		if errors.As(err, &serr) {
+			d.diags = append(d.diags, d.diagnostic(serr.Pos, serr.Msg, SeverityError))
+		} else {
+			d.diags = append(d.diags, d.diagnostic(syntax.MakePosition(nil, 1, 1), err.Error(), SeverityError))
+		}
+		return
+	}
+	d.file = f
+
+	for _, stmt := range f.Stmts {
+		load, ok := stmt.(*syntax.LoadStmt)
+		if !ok {
+			continue
+		}
+		module := load.ModuleName()
+		for i, to := range load.To {
+			d.loads[to.Name] = loadBinding{module: module, name: load.From[i].Name, stmt: load}
+		}
+		d.checkLoad(load)
+	}
+
+	// resolve sets the bindings of identifiers, for finding definitions,
+	// and reports names that are used but never defined
+	err = resolve.File(f, func(name string) bool { return predeclared[name] }, starlark.Universe.Has)
+	var rerrs resolve.ErrorList
+	if errors.As(err, &rerrs) {
+		for _, e := range rerrs {
+			d.diags = append(d.diags, d.diagnostic(e.Pos, e.Msg, SeverityError))
+		}
+	}
+}
+
+// checkLoad reports loads of modules that don't exist, or of names they
+// don't have.
+func (d *document) checkLoad(load *syntax.LoadStmt) {
+	module := load.ModuleName()
+
+	if slices.Contains(runtime.BuiltinModules, module) {
+		members, err := runtime.LoadBuiltinModule(module)
+		if err != nil {
+			return
+		}
+		for _, from := range load.From {
+			if _, ok := members[from.Name]; !ok {
+				d.diags = append(d.diags, d.diagnostic(from.NamePos, fmt.Sprintf("%s has no %s, only %s", module, from.Name, strings.Join(members.Keys(), ", ")), SeverityError))
+			}
+		}
+		return
+	}
+
+	if d.path == "" {
+		return
+	}
+	if _, err := os.Stat(d.resolvePath(module)); err != nil {
+		d.diags = append(d.diags, d.diagnostic(load.Module.TokenPos, fmt.Sprintf("no module or file named %s", module), SeverityError))
+	}
+}
+
+// resolvePath returns the path of a file loaded by the document.
+func (d *document) resolvePath(module string) string {
+	return filepath.Join(filepath.Dir(d.path), filepath.FromSlash(module))
+}
+
+func (d *document) diagnostic(pos syntax.Position, msg string, severity DiagnosticSeverity) Diagnostic {
+	start := d.position(pos)
+	end := start
+
+	// underline the word at the position
+	offset := d.offset(start)
+	for offset < len(d.text) {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		if !isIdentRune(r) {
+			break
+		}
+		offset += size
+		end.Character += len(utf16.Encode([]rune{r}))
+	}
+
+	return Diagnostic{
+		Range:    Range{Start: start, End: end},
+		Severity: severity,
+		Source:   "pixlet",
+		Message:  msg,
+	}
+}
+
+// position converts a Starlark position, whose columns count runes from
+// one, to an LSP position.
+func (d *document) position(pos syntax.Position) Position {
+	return positionIn(d.text, pos)
+}
+
+func positionIn(text string, pos syntax.Position) Position {
+	line := int(pos.Line) - 1
+	lines := strings.SplitN(text, "\n", line+2)
+	if line < 0 || line >= len(lines) {
+		return Position{}
+	}
+
+	character := 0
+	for i, r := range []rune(lines[line]) {
+		if i >= int(pos.Col)-1 {
+			break
+		}
+		character += len(utf16.Encode([]rune{r}))
+	}
+	return Position{Line: line, Character: character}
+}
+
+// offset converts an LSP position to a byte offset in the text.
+func (d *document) offset(pos Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := strings.IndexByte(d.text[offset:], '\n')
+		if i < 0 {
+			return len(d.text)
+		}
+		offset += i + 1
+	}
+
+	for units := 0; units < pos.Character && offset < len(d.text); {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		if r == '\n' {
+			break
+		}
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+// syntaxPosition converts an LSP position to a Starlark position.
+func (d *document) syntaxPosition(pos Position) syntax.Position {
+	offset := d.offset(pos)
+	lineStart := strings.LastIndexByte(d.text[:offset], '\n') + 1
+	col := utf8.RuneCountInString(d.text[lineStart:offset]) + 1
+	return syntax.MakePosition(nil, int32(pos.Line+1), int32(col))
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// uriToPath returns the path of a file URI, or "" if it's not one.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// pathToURI returns the file URI of a path.
+func pathToURI(path string) string {
+	abs, err := filepath.Abs(path)
+	if err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
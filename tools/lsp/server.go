@@ -0,0 +1,252 @@
+// Package lsp implements a Language Server Protocol server for pixlet apps,
+// giving editors completion of pixlet's modules and widgets, go to
+// definition across load(), and diagnostics as apps are edited.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// Server is the state of a session with a client. Messages are handled one
+// at a time.
+type Server struct {
+	out    io.Writer
+	outMu  sync.Mutex
+	logger *slog.Logger
+
+	docs     map[string]*document
+	shutdown bool
+}
+
+// Serve serves clients that send messages to r and read responses from w,
+// usually an editor connected to stdin and stdout, until the client exits
+// or ctx is done. Problems are logged to logger.
+func Serve(ctx context.Context, r io.Reader, w io.Writer, logger *slog.Logger) error {
+	s := &Server{
+		out:    w,
+		logger: logger,
+		docs:   map[string]*document{},
+	}
+
+	msgs := make(chan *message)
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			msg, err := readMessage(br)
+			var rerr *rpcError
+			if errors.As(err, &rerr) {
+				s.reply(nil, nil, rerr)
+				continue
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case msgs <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case msg := <-msgs:
+			if msg.Method == "exit" {
+				if !s.shutdown {
+					return fmt.Errorf("exited without shutting down")
+				}
+				return nil
+			}
+			s.handle(msg)
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) {
+	result, err := s.dispatch(msg)
+	if msg.ID == nil {
+		// notifications don't get responses
+		if err != nil {
+			s.logger.Error("handling message", "method", msg.Method, "error", err)
+		}
+		return
+	}
+
+	var rerr *rpcError
+	if err != nil && !errors.As(err, &rerr) {
+		rerr = &rpcError{Code: codeInternalError, Message: err.Error()}
+	}
+	s.reply(msg.ID, result, rerr)
+}
+
+func (s *Server) dispatch(msg *message) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				// clients send the whole document when it changes
+				"textDocumentSync": 1,
+				"completionProvider": map[string]any{
+					"triggerCharacters": []string{".", "(", ",", `"`},
+				},
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "pixlet"},
+		}, nil
+
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		s.update(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+
+	case "textDocument/didChange":
+		var params DidChangeParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+
+	case "textDocument/didClose":
+		var params DidCloseParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+		return nil, nil
+
+	case "textDocument/didSave":
+		return nil, nil
+
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return []CompletionItem{}, nil
+		}
+		items := doc.complete(params.Position)
+		if items == nil {
+			items = []CompletionItem{}
+		}
+		return items, nil
+
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		if loc := doc.definition(params.Position, s.source); loc != nil {
+			return loc, nil
+		}
+		return nil, nil
+	}
+
+	if msg.ID == nil {
+		// unknown notifications are ignored
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not supported: %s", msg.Method)}
+}
+
+// update analyzes a new version of a document and publishes its
+// diagnostics.
+func (s *Server) update(uri, text string) {
+	doc := newDocument(uri, text, s.docs[uri])
+	s.docs[uri] = doc
+
+	diags := doc.diags
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diags,
+	})
+}
+
+// source returns the text of a file, from its open document if there is
+// one.
+func (s *Server) source(path string) (string, error) {
+	if doc, ok := s.docs[pathToURI(path)]; ok {
+		return doc.text, nil
+	}
+	return readSource(path)
+}
+
+func unmarshalParams(msg *message, v any) error {
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) reply(id json.RawMessage, result any, err *rpcError) {
+	resp := struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  any             `json:"result"`
+		Error   *rpcError       `json:"error,omitempty"`
+	}{JSONRPC: "2.0", ID: id, Result: result, Error: err}
+	if id == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	s.write(resp)
+}
+
+func (s *Server) notify(method string, params any) {
+	s.write(struct {
+		JSONRPC string `json:"jsonrpc"`
+		Method  string `json:"method"`
+		Params  any    `json:"params"`
+	}{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) write(msg any) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	if err := writeMessage(s.out, msg); err != nil {
+		s.logger.Error("writing message", "error", err)
+	}
+}
@@ -0,0 +1,279 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// client talks to a server over pipes.
+type client struct {
+	t   *testing.T
+	in  io.Writer
+	out *bufio.Reader
+	id  int
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(context.Background(), inR, outW, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		outW.Close()
+	}()
+
+	c := &client{t: t, in: inW, out: bufio.NewReader(outR)}
+	t.Cleanup(func() {
+		c.call("shutdown", nil, nil)
+		c.notify("exit", nil)
+		assert.NoError(t, <-done)
+		inW.Close()
+	})
+
+	c.call("initialize", map[string]any{}, nil)
+	c.notify("initialized", map[string]any{})
+	return c
+}
+
+func (c *client) notify(method string, params any) {
+	require.NoError(c.t, writeMessage(c.in, map[string]any{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	}))
+}
+
+// call sends a request, and unmarshals the result of its response into
+// result, skipping notifications.
+func (c *client) call(method string, params any, result any) {
+	c.id++
+	require.NoError(c.t, writeMessage(c.in, map[string]any{
+		"jsonrpc": "2.0",
+		"id":      c.id,
+		"method":  method,
+		"params":  params,
+	}))
+
+	for {
+		var resp struct {
+			ID     *int            `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *rpcError       `json:"error"`
+		}
+		c.read(&resp)
+		if resp.ID == nil || *resp.ID != c.id {
+			continue
+		}
+		require.Nil(c.t, resp.Error)
+		if result != nil {
+			require.NoError(c.t, json.Unmarshal(resp.Result, result))
+		}
+		return
+	}
+}
+
+// diagnostics returns the next diagnostics published.
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	for {
+		var msg struct {
+			Method string                   `json:"method"`
+			Params PublishDiagnosticsParams `json:"params"`
+		}
+		c.read(&msg)
+		if msg.Method == "textDocument/publishDiagnostics" {
+			return msg.Params
+		}
+	}
+}
+
+func (c *client) read(v any) {
+	msg, err := readRaw(c.out)
+	require.NoError(c.t, err)
+	require.NoError(c.t, json.Unmarshal(msg, v))
+}
+
+func readRaw(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return body, err
+}
+
+func (c *client) open(path, text string) PublishDiagnosticsParams {
+	c.notify("textDocument/didOpen", DidOpenParams{
+		TextDocument: TextDocumentItem{URI: pathToURI(path), Text: text},
+	})
+	return c.diagnostics()
+}
+
+func (c *client) change(path, text string) PublishDiagnosticsParams {
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]string{"uri": pathToURI(path)},
+		"contentChanges": []map[string]string{{"text": text}},
+	})
+	return c.diagnostics()
+}
+
+func (c *client) complete(path string, line, character int) map[string]CompletionItem {
+	var items []CompletionItem
+	c.call("textDocument/completion", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: pathToURI(path)},
+		Position:     Position{Line: line, Character: character},
+	}, &items)
+
+	byLabel := map[string]CompletionItem{}
+	for _, item := range items {
+		byLabel[item.Label] = item
+	}
+	return byLabel
+}
+
+func (c *client) definition(path string, line, character int) *Location {
+	var loc *Location
+	c.call("textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: pathToURI(path)},
+		Position:     Position{Line: line, Character: character},
+	}, &loc)
+	return loc
+}
+
+const libSource = `
+def greeting(name):
+    return "Hi " + name
+`
+
+const appSource = `load("render.star", "render")
+load("lib.star", "greeting")
+
+def main(config):
+    return render.Root(
+        child = render.Text(greeting("Ada")),
+    )
+`
+
+func writeApp(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.star")
+	app := filepath.Join(dir, "app.star")
+	require.NoError(t, os.WriteFile(lib, []byte(libSource), 0644))
+	require.NoError(t, os.WriteFile(app, []byte(appSource), 0644))
+	return app, lib
+}
+
+func TestDiagnostics(t *testing.T) {
+	app, _ := writeApp(t)
+	c := newClient(t)
+
+	diags := c.open(app, appSource)
+	assert.Equal(t, pathToURI(app), diags.URI)
+	assert.Empty(t, diags.Diagnostics)
+
+	diags = c.change(app, `load("render.star", "render", "widget")
+load("missing.star", "nothing")
+
+def main(config):
+    return render.Root(child = undefined)
+`)
+	require.Len(t, diags.Diagnostics, 3)
+	assert.Contains(t, diags.Diagnostics[0].Message, "render.star has no widget")
+	assert.Equal(t, Range{Start: Position{0, 31}, End: Position{0, 37}}, diags.Diagnostics[0].Range)
+	assert.Contains(t, diags.Diagnostics[1].Message, "no module or file named missing.star")
+	assert.Contains(t, diags.Diagnostics[2].Message, "undefined: undefined")
+	assert.Equal(t, Range{Start: Position{4, 31}, End: Position{4, 40}}, diags.Diagnostics[2].Range)
+
+	diags = c.change(app, "def main(config)\n")
+	require.Len(t, diags.Diagnostics, 1)
+	assert.Equal(t, SeverityError, diags.Diagnostics[0].Severity)
+}
+
+func TestCompletion(t *testing.T) {
+	app, _ := writeApp(t)
+	c := newClient(t)
+	c.open(app, appSource)
+
+	// after "render."
+	items := c.complete(app, 4, 18)
+	assert.Contains(t, items, "Root")
+	assert.Contains(t, items, "WrappedText")
+	assert.Contains(t, items, "fonts")
+	assert.Equal(t, KindFunction, items["Text"].Kind)
+	assert.NotContains(t, items, "main")
+
+	// in the arguments of render.Root(
+	items = c.complete(app, 5, 8)
+	assert.Equal(t, "child = ", items["child"].InsertText)
+	assert.Contains(t, items["child"].Detail, "required")
+	assert.Contains(t, items, "delay")
+	assert.Contains(t, items, "greeting")
+	assert.Contains(t, items, "len")
+
+	// while the line being typed doesn't parse
+	c.change(app, appSource+"\nx = render.\n")
+	items = c.complete(app, 8, 11)
+	assert.Contains(t, items, "Box")
+
+	// modules that can be loaded
+	c.change(app, "load(\"\n")
+	items = c.complete(app, 0, 6)
+	assert.Contains(t, items, "http.star")
+	assert.Contains(t, items, "schema.star")
+	assert.Equal(t, KindFile, items["lib.star"].Kind)
+	assert.NotContains(t, items, "app.star")
+}
+
+func TestDefinition(t *testing.T) {
+	app, lib := writeApp(t)
+	c := newClient(t)
+	c.open(app, appSource)
+
+	// greeting("Ada") goes to its def in lib.star
+	loc := c.definition(app, 5, 30)
+	require.NotNil(t, loc)
+	assert.Equal(t, pathToURI(lib), loc.URI)
+	assert.Equal(t, Position{Line: 1, Character: 4}, loc.Range.Start)
+
+	// the name in the load statement does too
+	loc = c.definition(app, 1, 22)
+	require.NotNil(t, loc)
+	assert.Equal(t, pathToURI(lib), loc.URI)
+
+	// and the module name goes to the file
+	loc = c.definition(app, 1, 8)
+	require.NotNil(t, loc)
+	assert.Equal(t, pathToURI(lib), loc.URI)
+
+	// render goes to where it's loaded
+	loc = c.definition(app, 4, 12)
+	require.NotNil(t, loc)
+	assert.Equal(t, pathToURI(app), loc.URI)
+	assert.Equal(t, Position{Line: 0, Character: 21}, loc.Range.Start)
+
+	// config goes to the parameter
+	c.change(app, appSource+"\ndef other(config):\n    return config\n")
+	loc = c.definition(app, 9, 12)
+	require.NotNil(t, loc)
+	assert.Equal(t, Position{Line: 8, Character: 10}, loc.Range.Start)
+
+	// nothing defines len
+	c.change(app, "x = len([])\n")
+	assert.Nil(t, c.definition(app, 0, 5))
+}
@@ -0,0 +1,97 @@
+package lsp
+
+import (
+	"reflect"
+	"strings"
+
+	"tidbyt.dev/pixlet/runtime/modules/animation_runtime"
+	"tidbyt.dev/pixlet/runtime/modules/render_runtime"
+)
+
+// widget is a type whose constructor takes its attributes as parameters.
+type widget interface {
+	Type() string
+	AttrNames() []string
+}
+
+// widgets are the types of the render and animation modules, by module
+// and name.
+var widgets = map[string][]widget{
+	"render.star": {
+		&render_runtime.Animation{},
+		&render_runtime.Box{},
+		&render_runtime.Circle{},
+		&render_runtime.Column{},
+		&render_runtime.Image{},
+		&render_runtime.Marquee{},
+		&render_runtime.Padding{},
+		&render_runtime.PieChart{},
+		&render_runtime.Plot{},
+		&render_runtime.Root{},
+		&render_runtime.Row{},
+		&render_runtime.Sequence{},
+		&render_runtime.Stack{},
+		&render_runtime.Text{},
+		&render_runtime.WrappedText{},
+	},
+	"animation.star": {
+		&animation_runtime.AnimatedPositioned{},
+		&animation_runtime.Keyframe{},
+		&animation_runtime.Origin{},
+		&animation_runtime.Rotate{},
+		&animation_runtime.Scale{},
+		&animation_runtime.Transformation{},
+		&animation_runtime.Translate{},
+	},
+}
+
+// param is a parameter of a widget's constructor.
+type param struct {
+	name     string
+	required bool
+}
+
+// widgetParams returns the parameters of a widget of a module, or nil if
+// there's no such widget.
+func widgetParams(module, name string) []param {
+	for _, w := range widgets[module] {
+		if w.Type() != name {
+			continue
+		}
+
+		required := requiredAttrs(reflect.TypeOf(w).Elem())
+		var params []param
+		for _, attr := range w.AttrNames() {
+			params = append(params, param{name: attr, required: required[attr]})
+		}
+		return params
+	}
+	return nil
+}
+
+// requiredAttrs returns the attributes of a widget type that are tagged
+// `starlark:"<name>,required"`, including those of the types it embeds.
+func requiredAttrs(typ reflect.Type) map[string]bool {
+	required := map[string]bool{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for name := range requiredAttrs(field.Type) {
+				required[name] = true
+			}
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("starlark")
+		if !ok {
+			continue
+		}
+		attrs := strings.Split(tag, ",")
+		for _, attr := range attrs[1:] {
+			if strings.TrimSpace(attr) == "required" {
+				required[strings.TrimSpace(attrs[0])] = true
+			}
+		}
+	}
+	return required
+}
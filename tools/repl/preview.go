@@ -0,0 +1,157 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/render"
+	"tidbyt.dev/pixlet/runtime/modules/render_runtime"
+)
+
+// Preview is how widgets are previewed in the terminal.
+type Preview int
+
+const (
+	PreviewNone Preview = iota
+
+	// PreviewASCII draws widgets with colored half block characters, two
+	// pixels to a character.
+	PreviewASCII
+
+	// PreviewKitty draws widgets as images with the kitty graphics
+	// protocol, which kitty, WezTerm and Ghostty support.
+	PreviewKitty
+)
+
+// kittyMagnify is how much images are scaled up for the kitty graphics
+// protocol, since a pixel of a display is far smaller than a character.
+const kittyMagnify = 8
+
+// kittyChunkSize is the most base64 the kitty graphics protocol takes in
+// one escape sequence.
+const kittyChunkSize = 4096
+
+// ParsePreview parses the name of a way to preview widgets: none, ascii,
+// kitty, or auto, which is kitty in terminals known to support it and
+// ascii in others.
+func ParsePreview(name string) (Preview, error) {
+	switch name {
+	case "none":
+		return PreviewNone, nil
+	case "ascii":
+		return PreviewASCII, nil
+	case "kitty":
+		return PreviewKitty, nil
+	case "auto":
+		if os.Getenv("KITTY_WINDOW_ID") != "" ||
+			os.Getenv("TERM") == "xterm-kitty" ||
+			os.Getenv("TERM_PROGRAM") == "WezTerm" ||
+			os.Getenv("TERM_PROGRAM") == "ghostty" {
+			return PreviewKitty, nil
+		}
+		return PreviewASCII, nil
+	}
+	return PreviewNone, fmt.Errorf("unknown preview %q, must be none, ascii, kitty or auto", name)
+}
+
+// show previews a value, if it's a widget.
+func (s *Session) show(ctx context.Context, v starlark.Value) error {
+	if s.preview == PreviewNone {
+		return nil
+	}
+
+	var root render.Root
+	switch v := v.(type) {
+	case render_runtime.Rootable:
+		root = v.AsRenderRoot()
+	case render_runtime.Widget:
+		root = render.Root{Child: v.AsRenderWidget()}
+	default:
+		return nil
+	}
+
+	frames := root.Paint(true, render.WithContext(ctx), render.WithMaxFrameCount(1))
+	if len(frames) == 0 {
+		return nil
+	}
+
+	switch s.preview {
+	case PreviewASCII:
+		writeASCII(s.out, frames[0])
+	case PreviewKitty:
+		if err := writeKitty(s.out, frames[0]); err != nil {
+			return err
+		}
+	}
+
+	if n := root.Child.FrameCount(); n > 1 {
+		fmt.Fprintf(s.out, "(first of %d frames)\n", n)
+	}
+	return nil
+}
+
+// writeASCII draws an image with half block characters, whose foreground
+// is the upper pixel and background the lower one.
+func writeASCII(w io.Writer, img image.Image) {
+	b := img.Bounds()
+	var sb strings.Builder
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			fmt.Fprintf(&sb, "\x1b[38;2;%d;%d;%dm", r>>8, g>>8, bl>>8)
+			if y+1 < b.Max.Y {
+				r, g, bl, _ = img.At(x, y+1).RGBA()
+				fmt.Fprintf(&sb, "\x1b[48;2;%d;%d;%dm", r>>8, g>>8, bl>>8)
+			}
+			sb.WriteString("▀")
+		}
+		sb.WriteString("\x1b[0m\n")
+	}
+	io.WriteString(w, sb.String())
+}
+
+// writeKitty draws an image with the kitty graphics protocol, as a PNG sent
+// in chunks.
+func writeKitty(w io.Writer, img image.Image) error {
+	img, err := encode.Magnify(kittyMagnify)(img)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	var sb strings.Builder
+	for first := true; first || data != ""; first = false {
+		chunk := data
+		if len(chunk) > kittyChunkSize {
+			chunk = chunk[:kittyChunkSize]
+		}
+		data = data[len(chunk):]
+
+		more := 0
+		if data != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&sb, "\x1b_Gf=100,a=T,m=%d;%s\x1b\\", more, chunk)
+		} else {
+			fmt.Fprintf(&sb, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	sb.WriteString("\n")
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,188 @@
+// Package repl implements an interactive Starlark prompt with pixlet's
+// modules loaded, which previews the widgets that expressions evaluate to.
+package repl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/runtime/modules/random"
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+// Preloaded are the modules that are loaded before the first prompt, so
+// their members can be used without load().
+var Preloaded = []string{
+	"render.star",
+	"animation.star",
+	"schema.star",
+	"http.star",
+	"time.star",
+}
+
+// fileOptions are the dialect of Starlark that pixlet runs. Loads bind
+// globally, so that loaded names can be used at later prompts.
+var fileOptions = &syntax.FileOptions{
+	Set:               true,
+	Recursion:         true,
+	LoadBindsGlobally: true,
+}
+
+// ErrInterrupt is returned by the readLine function passed to Run to
+// abandon the input being entered, as when Control-C is pressed.
+var ErrInterrupt = errors.New("interrupted")
+
+// Session is a sequence of inputs evaluated with the same globals.
+type Session struct {
+	globals starlark.StringDict
+	out     io.Writer
+	preview Preview
+}
+
+// NewSession returns a session that writes results to out, previewing
+// widgets as preview says.
+func NewSession(out io.Writer, preview Preview) (*Session, error) {
+	globals := starlark.StringDict{
+		"struct": starlark.NewBuiltin("struct", starlarkstruct.Make),
+	}
+	for _, module := range Preloaded {
+		members, err := runtime.LoadBuiltinModule(module)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", module, err)
+		}
+		for name, value := range members {
+			globals[name] = value
+		}
+	}
+
+	return &Session{globals: globals, out: out, preview: preview}, nil
+}
+
+// Run reads input with readLine, which is passed the prompt to show and
+// returns lines without their newline, and evaluates it until readLine
+// returns io.EOF. An input that's an expression has its value printed, and
+// previewed if it's a widget; other inputs are executed as statements,
+// which continue until a blank line. Errors are printed, and don't end the
+// session.
+func (s *Session) Run(ctx context.Context, readLine func(prompt string) (string, error)) error {
+	for {
+		prompt := ">>> "
+		eof := false
+		read := func() ([]byte, error) {
+			line, err := readLine(prompt)
+			prompt = "... "
+			if errors.Is(err, io.EOF) {
+				eof = true
+			}
+			if err != nil {
+				return nil, err
+			}
+			return []byte(line + "\n"), nil
+		}
+
+		f, err := fileOptions.ParseCompoundStmt("<stdin>", read)
+		if eof || ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, ErrInterrupt) {
+			continue
+		}
+		if err != nil {
+			s.printError(err)
+			continue
+		}
+
+		if err := s.eval(ctx, f); err != nil {
+			s.printError(err)
+		}
+	}
+}
+
+// Eval evaluates a single input, as if it were entered at the prompt.
+func (s *Session) Eval(ctx context.Context, src string) error {
+	// statements end with a blank line
+	lines := append(strings.Split(strings.TrimRight(src, "\n"), "\n"), "")
+	read := func() ([]byte, error) {
+		if len(lines) == 0 {
+			return nil, io.EOF
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return []byte(line + "\n"), nil
+	}
+
+	f, err := fileOptions.ParseCompoundStmt("<stdin>", read)
+	if err != nil {
+		return err
+	}
+	return s.eval(ctx, f)
+}
+
+func (s *Session) eval(ctx context.Context, f *syntax.File) error {
+	// Control-C interrupts evaluating, rather than ending the session
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	thread := &starlark.Thread{
+		Name: "repl",
+		Load: func(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+			return runtime.LoadBuiltinModule(module)
+		},
+		Print: func(thread *starlark.Thread, msg string) {
+			fmt.Fprintln(s.out, msg)
+		},
+	}
+	starlarkutil.AttachThreadContext(ctx, thread)
+	random.AttachToThread(thread)
+	defer starlarkutil.RunOnExitFuncs(thread)
+	context.AfterFunc(ctx, func() {
+		thread.Cancel(context.Cause(ctx).Error())
+	})
+
+	expr := soleExpr(f)
+	if expr == nil {
+		return starlark.ExecREPLChunk(f, thread, s.globals)
+	}
+
+	v, err := starlark.EvalExprOptions(f.Options, thread, expr, s.globals)
+	if err != nil {
+		return err
+	}
+
+	// like Python, the last value is kept as _
+	s.globals["_"] = v
+	if v == starlark.None {
+		return nil
+	}
+
+	fmt.Fprintln(s.out, v)
+	return s.show(ctx, v)
+}
+
+// soleExpr returns the expression a file consists of, if it's only one.
+func soleExpr(f *syntax.File) syntax.Expr {
+	if len(f.Stmts) == 1 {
+		if stmt, ok := f.Stmts[0].(*syntax.ExprStmt); ok {
+			return stmt.X
+		}
+	}
+	return nil
+}
+
+func (s *Session) printError(err error) {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		fmt.Fprintln(s.out, evalErr.Backtrace())
+		return
+	}
+	fmt.Fprintln(s.out, err)
+}
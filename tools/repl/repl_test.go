@@ -0,0 +1,121 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	var out bytes.Buffer
+	s, err := NewSession(&out, PreviewNone)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, s.Eval(ctx, "1 + 2"))
+	assert.Equal(t, "3\n", out.String())
+
+	// statements define globals for later inputs
+	out.Reset()
+	require.NoError(t, s.Eval(ctx, "def greet(name):\n    return 'Hello, ' + name"))
+	require.NoError(t, s.Eval(ctx, "greet('Ada')"))
+	assert.Equal(t, "\"Hello, Ada\"\n", out.String())
+
+	// so do loads
+	out.Reset()
+	require.NoError(t, s.Eval(ctx, `load("encoding/json.star", "json")`))
+	require.NoError(t, s.Eval(ctx, `json.encode({"name": _})`))
+	assert.Equal(t, `"{\"name\":\"Hello, Ada\"}"`+"\n", out.String())
+
+	// modules are preloaded
+	out.Reset()
+	require.NoError(t, s.Eval(ctx, "schema.Color"))
+	require.NoError(t, s.Eval(ctx, "time.parse_duration('1m')"))
+	assert.Equal(t, "<built-in function Color>\n1m0s\n", out.String())
+
+	assert.Error(t, s.Eval(ctx, "undefined"))
+}
+
+func TestPreview(t *testing.T) {
+	var out bytes.Buffer
+	s, err := NewSession(&out, PreviewASCII)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Eval(context.Background(), `render.Box(width = 4, height = 2, color = "#f00")`))
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+
+	// the widget, then half as many rows as the display is high
+	require.Len(t, lines, 17)
+	assert.True(t, strings.HasPrefix(lines[1], "\x1b[38;2;255;0;0m\x1b[48;2;255;0;0m▀"))
+	assert.Equal(t, 64, strings.Count(lines[1], "▀"))
+	assert.True(t, strings.HasPrefix(lines[2], "\x1b[38;2;0;0;0m"))
+
+	out.Reset()
+	s.preview = PreviewKitty
+	require.NoError(t, s.Eval(context.Background(), `render.Root(child = render.Box(color = "#00f"))`))
+	assert.Contains(t, out.String(), "\x1b_Gf=100,a=T,")
+
+	out.Reset()
+	require.NoError(t, s.Eval(context.Background(), `render.Text("Grace")`))
+	assert.Contains(t, out.String(), "\x1b_G")
+}
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	s, err := NewSession(&out, PreviewNone)
+	require.NoError(t, err)
+
+	input := []string{
+		"x = [1,",
+		"  2]",
+		"",
+		"x[5]",
+		"len(",
+	}
+	var prompts []string
+	err = s.Run(context.Background(), func(prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		if len(input) == 0 {
+			return "", io.EOF
+		}
+		line := input[0]
+		input = input[1:]
+		if line == "len(" {
+			// abandoned, like Control-C
+			return "", ErrInterrupt
+		}
+		return line, nil
+	})
+	require.NoError(t, err)
+
+	// errors are printed, and don't end the session
+	assert.Contains(t, out.String(), "index 5 out of range")
+	assert.Equal(t, []string{">>> ", "... ", ">>> ", ">>> ", ">>> ", ">>> "}, prompts)
+}
+
+func TestParsePreview(t *testing.T) {
+	t.Setenv("KITTY_WINDOW_ID", "")
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv("TERM_PROGRAM", "")
+
+	p, err := ParsePreview("auto")
+	require.NoError(t, err)
+	assert.Equal(t, PreviewASCII, p)
+
+	t.Setenv("TERM", "xterm-kitty")
+	p, err = ParsePreview("auto")
+	require.NoError(t, err)
+	assert.Equal(t, PreviewKitty, p)
+
+	p, err = ParsePreview("none")
+	require.NoError(t, err)
+	assert.Equal(t, PreviewNone, p)
+
+	_, err = ParsePreview("sixel")
+	assert.Error(t, err)
+}
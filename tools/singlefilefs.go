@@ -25,3 +25,22 @@ func (sfs *SingleFileFS) Open(name string) (fs.File, error) {
 
 	return sfs.baseFS.Open(name)
 }
+
+// ReadDir lists only the file in the root directory, so that other files
+// next to it aren't mistaken for part of the same app.
+func (sfs *SingleFileFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		return nil, fs.ErrNotExist
+	}
+
+	entries, err := fs.ReadDir(sfs.baseFS, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.Name() == filepath.Base(sfs.Path) {
+			return []fs.DirEntry{e}, nil
+		}
+	}
+	return nil, fs.ErrNotExist
+}
@@ -0,0 +1,103 @@
+// Package tracing provides OpenTelemetry tracing for the render pipeline.
+//
+// Spans are always created, but they are discarded unless an exporter has
+// been configured with Init, so instrumentation costs next to nothing when
+// tracing is turned off.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// InstrumentationName identifies spans created by pixlet.
+	InstrumentationName = "tidbyt.dev/pixlet"
+
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName = "pixlet"
+)
+
+// Attribute keys used on pixlet spans.
+const (
+	AppIDKey       = attribute.Key("pixlet.app.id")
+	FunctionKey    = attribute.Key("pixlet.function")
+	CacheStatusKey = attribute.Key("pixlet.cache.status")
+	FormatKey      = attribute.Key("pixlet.encode.format")
+	BytesKey       = attribute.Key("pixlet.encode.bytes")
+)
+
+// ShutdownFunc flushes any buffered spans and stops the exporter.
+type ShutdownFunc func(context.Context) error
+
+// Init configures an OTLP/HTTP exporter sending spans to endpoint, such as
+// "http://localhost:4318". Plain host:port endpoints are sent over HTTP. An
+// empty endpoint leaves tracing disabled. The returned function must be
+// called before exiting to flush pending spans.
+func Init(ctx context.Context, endpoint string) (ShutdownFunc, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if u, err := url.Parse(endpoint); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	} else {
+		opts = append(opts, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the tracer used for pixlet spans.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Start starts a span as a child of any span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,73 @@
+package tronbyt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SetupURL is where factory-fresh Tronbyt devices serve their setup page,
+// on the Wi-Fi network they create until they're given one to join.
+const SetupURL = "http://10.10.0.1"
+
+// WiFiConfig is what a device is told when it's provisioned.
+type WiFiConfig struct {
+	// SSID and Password of the Wi-Fi network the device joins.
+	SSID     string
+	Password string
+
+	// ImageURL is where the device polls for the image to show, such as
+	// a pixlet hub's /v0/devices/{device ID}/next.
+	ImageURL string
+}
+
+// Provision configures a device that's in setup mode, through the setup
+// page at setupURL. The device restarts and joins the network once it's
+// accepted the config, so the device's own network goes away.
+func Provision(ctx context.Context, client *http.Client, setupURL string, cfg WiFiConfig) error {
+	if cfg.SSID == "" {
+		return fmt.Errorf("no Wi-Fi network given")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	base := strings.TrimSuffix(setupURL, "/")
+
+	// check the device is there first, for a clearer error than a failed
+	// POST
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("reaching device's setup page at %s, are you connected to its setup Wi-Fi network? %w", base, err)
+	}
+	resp.Body.Close()
+
+	form := url.Values{
+		"ssid":      {cfg.SSID},
+		"password":  {cfg.Password},
+		"image_url": {cfg.ImageURL},
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, base+"/save", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err = client.Do(req)
+	if err != nil {
+		return fmt.Errorf("saving device's config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 399 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("device's setup page returned status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
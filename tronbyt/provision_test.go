@@ -0,0 +1,43 @@
+package tronbyt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvision(t *testing.T) {
+	var saved map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte("<form action=/save></form>"))
+			return
+		}
+		assert.Equal(t, "/save", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		saved = r.PostForm
+	}))
+	defer srv.Close()
+
+	err := Provision(context.Background(), nil, srv.URL, WiFiConfig{
+		SSID:     "Lovelace",
+		Password: "analytical engine",
+		ImageURL: "http://hub.local:8080/v0/devices/kitchen/next",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"ssid":      {"Lovelace"},
+		"password":  {"analytical engine"},
+		"image_url": {"http://hub.local:8080/v0/devices/kitchen/next"},
+	}, saved)
+
+	assert.Error(t, Provision(context.Background(), nil, srv.URL, WiFiConfig{}))
+
+	srv.Close()
+	err = Provision(context.Background(), nil, srv.URL, WiFiConfig{SSID: "Lovelace"})
+	assert.ErrorContains(t, err, "setup Wi-Fi network")
+}
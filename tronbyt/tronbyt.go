@@ -0,0 +1,158 @@
+// Package tronbyt is a client for self-hosted Tronbyt devices. Tronbyt
+// firmware, and the Tronbyt server that devices can be paired with, speak a
+// superset of Tidbyt's device API, authenticated with a per-device API key.
+package tronbyt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// APIKeyEnv is the environment variable the CLI reads a device's API key
+// from.
+const APIKeyEnv = "TRONBYT_API_KEY"
+
+// Client talks to one Tronbyt device, or the Tronbyt server it's paired with.
+type Client struct {
+	// URL is the base URL of the device or server, for example
+	// http://tronbyt.local:8000.
+	URL string
+
+	// APIKey is the device's API key, shown in its settings.
+	APIKey string
+
+	// HTTPClient is used for requests. http.DefaultClient is used if it's
+	// nil.
+	HTTPClient *http.Client
+}
+
+// Device describes a device's display settings.
+type Device struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	Brightness  int    `json:"brightness"`
+	AutoDim     bool   `json:"autoDim"`
+}
+
+// PushOptions control how a pushed image is shown.
+type PushOptions struct {
+	// InstallationID is the app slot to push to. Images pushed to a slot
+	// stay in the device's rotation, and are replaced by later pushes to
+	// the same slot. Images pushed without one are only shown once.
+	InstallationID string
+
+	// Background pushes to the slot without showing the image right away.
+	Background bool
+}
+
+type pushRequest struct {
+	DeviceID       string `json:"deviceID"`
+	Image          string `json:"image"`
+	InstallationID string `json:"installationID,omitempty"`
+	Background     bool   `json:"background"`
+}
+
+// Push uploads a WebP image to a device.
+func (c *Client) Push(ctx context.Context, deviceID string, image []byte, opts PushOptions) error {
+	if opts.Background && opts.InstallationID == "" {
+		return fmt.Errorf("background push won't do anything unless you also specify an installation ID")
+	}
+
+	return c.do(ctx, http.MethodPost, devicePath(deviceID, "push"), pushRequest{
+		DeviceID:       deviceID,
+		Image:          base64.StdEncoding.EncodeToString(image),
+		InstallationID: opts.InstallationID,
+		Background:     opts.Background,
+	}, nil)
+}
+
+// Device fetches a device's display settings.
+func (c *Client) Device(ctx context.Context, deviceID string) (*Device, error) {
+	d := &Device{}
+	if err := c.do(ctx, http.MethodGet, devicePath(deviceID), nil, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// SetBrightness sets a device's brightness, from 0 to 100.
+func (c *Client) SetBrightness(ctx context.Context, deviceID string, brightness int) error {
+	if brightness < 0 || brightness > 100 {
+		return fmt.Errorf("brightness must be between 0 and 100, not %d", brightness)
+	}
+
+	return c.do(ctx, http.MethodPatch, devicePath(deviceID), map[string]any{
+		"brightness": brightness,
+	}, nil)
+}
+
+// PinInstallation pins the app in an installation slot, so that the device
+// shows it instead of rotating through its apps. Pass an empty installation
+// ID to unpin and go back to rotating.
+func (c *Client) PinInstallation(ctx context.Context, deviceID, installationID string) error {
+	return c.do(ctx, http.MethodPatch, devicePath(deviceID), map[string]any{
+		"pinnedApp": installationID,
+	}, nil)
+}
+
+func devicePath(deviceID string, elem ...string) string {
+	parts := append([]string{"v0", "devices", url.PathEscape(deviceID)}, elem...)
+	return "/" + strings.Join(parts, "/")
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	if c.URL == "" {
+		return fmt.Errorf("no Tronbyt URL given")
+	}
+	if c.APIKey == "" {
+		return fmt.Errorf("no Tronbyt API key given")
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshaling request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.URL, "/")+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating %s request: %w", method, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling Tronbyt API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Tronbyt API returned status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("decoding response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package tronbyt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	var requests []string
+	var bodies []map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer the-key", r.Header.Get("Authorization"))
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"id": "dev1", "displayName": "Kitchen", "brightness": 40, "autoDim": true}`))
+			return
+		}
+
+		body := map[string]any{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+
+	c := &Client{URL: srv.URL + "/", APIKey: "the-key"}
+	ctx := context.Background()
+
+	require.NoError(t, c.Push(ctx, "dev1", []byte("webp"), PushOptions{InstallationID: "clock", Background: true}))
+	require.NoError(t, c.SetBrightness(ctx, "dev1", 80))
+	require.NoError(t, c.PinInstallation(ctx, "dev1", "clock"))
+
+	d, err := c.Device(ctx, "dev1")
+	require.NoError(t, err)
+	assert.Equal(t, &Device{ID: "dev1", DisplayName: "Kitchen", Brightness: 40, AutoDim: true}, d)
+
+	assert.Equal(t, []string{
+		"POST /v0/devices/dev1/push",
+		"PATCH /v0/devices/dev1",
+		"PATCH /v0/devices/dev1",
+		"GET /v0/devices/dev1",
+	}, requests)
+	assert.Equal(t, []map[string]any{
+		{"deviceID": "dev1", "image": "d2VicA==", "installationID": "clock", "background": true},
+		{"brightness": float64(80)},
+		{"pinnedApp": "clock"},
+	}, bodies)
+}
+
+func TestClientErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := &Client{URL: srv.URL, APIKey: "wrong"}
+
+	assert.ErrorContains(t, c.Push(ctx, "dev1", []byte("webp"), PushOptions{}), "bad key")
+	assert.Error(t, c.SetBrightness(ctx, "dev1", 101))
+	assert.Error(t, c.Push(ctx, "dev1", []byte("webp"), PushOptions{Background: true}))
+	assert.Error(t, (&Client{URL: srv.URL}).Push(ctx, "dev1", nil, PushOptions{}))
+}